@@ -4,7 +4,10 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/kelda/kelda/api"
@@ -60,10 +63,50 @@ type Client interface {
 	// encrypted and stored in Vault.
 	SetSecret(name, value string) error
 
+	// Logs calls `handle` on each line logged by the container described by
+	// `req` until the logs end, `ctx` is cancelled, or `handle` returns an
+	// error. The daemon proxies the request to the minion at `req.Host`.
+	Logs(ctx context.Context, req pb.LogsRequest,
+		handle func(pb.LogsReply) error) error
+
+	// Exec runs the command described by `req` in a container, and returns its
+	// exit code once it exits. The command reads from `stdin`, which may be
+	// nil, and its terminal is resized to each size received on `resize`. The
+	// daemon proxies the request to the minion at `req.Host`.
+	Exec(ctx context.Context, req pb.ExecRequest, stdin io.Reader,
+		stdout, stderr io.Writer, resize <-chan pb.TerminalSize) (int, error)
+
+	// PortForward tunnels `conn` to the container port described by `req`
+	// until both sides of the connection are closed. The daemon proxies the
+	// request to the minion at `req.Host`.
+	PortForward(ctx context.Context, req pb.PortForwardRequest,
+		conn io.ReadWriter) error
+
 	// Deploy makes a request to the Kelda daemon to deploy the given deployment.
 	// Only defined on the daemon.
 	Deploy(deployment string) error
 
+	// Cordon sets whether new containers may be scheduled on the worker
+	// described by `req`, and whether its containers should be moved to other
+	// workers. Only defined on the daemon.
+	Cordon(req pb.CordonRequest) error
+
+	// Handoff hands the deployment described by `req` off to its cluster,
+	// which manages its own machines from then on.
+	Handoff(req pb.HandoffRequest) error
+
+	// Upgrade rolls the minions of the machines in the namespace described by
+	// `req` to the daemon's version. Only defined on the daemon.
+	Upgrade(req pb.UpgradeRequest) error
+
+	// Snapshot retrieves the latest snapshot of the cluster state in the
+	// namespace described by `req`.
+	Snapshot(req pb.SnapshotRequest) (string, error)
+
+	// Restore restores the IP allocations and container placements in the
+	// snapshot described by `req`.
+	Restore(req pb.RestoreRequest) error
+
 	// Version retrieves the Kelda version of the remote daemon.
 	Version() (string, error)
 }
@@ -74,10 +117,23 @@ type Getter func(string, connection.Credentials) (Client, error)
 type clientImpl struct {
 	pbClient pb.APIClient
 	cc       *grpc.ClientConn
+
+	// The namespace of the deployment that queries are limited to, if any.
+	namespace string
 }
 
 // New creates a new Kelda client connected to `lAddr`.
 func New(lAddr string, creds connection.Credentials) (Client, error) {
+	return NewNamespaced(lAddr, "", creds)
+}
+
+// NewNamespaced creates a new Kelda client connected to `lAddr`, whose requests
+// are limited to the deployment in `namespace`.  The daemon may manage several
+// deployments, and requires a namespace for requests to the cluster when it
+// does.
+func NewNamespaced(lAddr, namespace string, creds connection.Credentials) (
+	Client, error) {
+
 	proto, addr, err := api.ParseListenAddress(lAddr)
 	if err != nil {
 		return nil, err
@@ -96,16 +152,21 @@ func New(lAddr string, creds connection.Credentials) (Client, error) {
 
 	pbClient := pb.NewAPIClient(cc)
 	return clientImpl{
-		pbClient: pbClient,
-		cc:       cc,
+		pbClient:  pbClient,
+		cc:        cc,
+		namespace: namespace,
 	}, nil
 }
 
 // Writes the result into `v` a pointer to a slice of database structs.  For example
 // *[]db.Machine.
-func query(pbClient pb.APIClient, table db.TableType, v interface{}) error {
+func query(pbClient pb.APIClient, namespace string, table db.TableType,
+	v interface{}) error {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
-	reply, err := pbClient.Query(ctx, &pb.DBQuery{Table: string(table)})
+	reply, err := pbClient.Query(ctx, &pb.DBQuery{
+		Table:     string(table),
+		Namespace: namespace,
+	})
 	if err != nil {
 		return err
 	}
@@ -122,44 +183,44 @@ func (c clientImpl) Close() error {
 // QueryMachines retrieves the machines tracked by the Kelda daemon.
 func (c clientImpl) QueryMachines() ([]db.Machine, error) {
 	var rows []db.Machine
-	return rows, query(c.pbClient, db.MachineTable, &rows)
+	return rows, query(c.pbClient, c.namespace, db.MachineTable, &rows)
 }
 
 // QueryContainers retrieves the containers tracked by the Kelda daemon.
 func (c clientImpl) QueryContainers() ([]db.Container, error) {
 	var rows []db.Container
-	return rows, query(c.pbClient, db.ContainerTable, &rows)
+	return rows, query(c.pbClient, c.namespace, db.ContainerTable, &rows)
 }
 
 // QueryEtcd retrieves the etcd information tracked by the Kelda daemon.
 func (c clientImpl) QueryEtcd() ([]db.Etcd, error) {
 	var rows []db.Etcd
-	return rows, query(c.pbClient, db.EtcdTable, &rows)
+	return rows, query(c.pbClient, c.namespace, db.EtcdTable, &rows)
 }
 
 // QueryConnections retrieves the connection information tracked by the Kelda daemon.
 func (c clientImpl) QueryConnections() ([]db.Connection, error) {
 	var rows []db.Connection
-	return rows, query(c.pbClient, db.ConnectionTable, &rows)
+	return rows, query(c.pbClient, c.namespace, db.ConnectionTable, &rows)
 }
 
 // QueryLoadBalancers retrieves the load balancer information tracked by the
 // Kelda daemon.
 func (c clientImpl) QueryLoadBalancers() ([]db.LoadBalancer, error) {
 	var rows []db.LoadBalancer
-	return rows, query(c.pbClient, db.LoadBalancerTable, &rows)
+	return rows, query(c.pbClient, c.namespace, db.LoadBalancerTable, &rows)
 }
 
 // QueryBlueprints retrieves the blueprint information tracked by the Kelda daemon.
 func (c clientImpl) QueryBlueprints() ([]db.Blueprint, error) {
 	var rows []db.Blueprint
-	return rows, query(c.pbClient, db.BlueprintTable, &rows)
+	return rows, query(c.pbClient, c.namespace, db.BlueprintTable, &rows)
 }
 
 // QueryImages retrieves the image information tracked by the Kelda daemon.
 func (c clientImpl) QueryImages() ([]db.Image, error) {
 	var rows []db.Image
-	return rows, query(c.pbClient, db.ImageTable, &rows)
+	return rows, query(c.pbClient, c.namespace, db.ImageTable, &rows)
 }
 
 // QueryCounters retrieves the debugging counters tracked with the Kelda daemon.
@@ -194,10 +255,170 @@ func parseCountersReply(reply *pb.CountersReply) (counters []pb.Counter) {
 
 func (c clientImpl) SetSecret(name, value string) error {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
-	_, err := c.pbClient.SetSecret(ctx, &pb.Secret{Name: name, Value: value})
+	_, err := c.pbClient.SetSecret(ctx, &pb.Secret{
+		Name:      name,
+		Value:     value,
+		Namespace: c.namespace,
+	})
 	return err
 }
 
+// Logs calls `handle` on each line logged by the container described by `req`.
+func (c clientImpl) Logs(ctx context.Context, req pb.LogsRequest,
+	handle func(pb.LogsReply) error) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.pbClient.Logs(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := handle(*reply); err != nil {
+			return err
+		}
+	}
+}
+
+// Exec runs the command described by `req` in a container, and returns its exit
+// code.
+func (c clientImpl) Exec(ctx context.Context, req pb.ExecRequest, stdin io.Reader,
+	stdout, stderr io.Writer, resize <-chan pb.TerminalSize) (int, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.pbClient.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// gRPC streams don't support concurrent calls to Send.
+	var sendLock sync.Mutex
+	send := func(msg *pb.ExecRequest) error {
+		sendLock.Lock()
+		defer sendLock.Unlock()
+		return stream.Send(msg)
+	}
+
+	if err := send(&req); err != nil {
+		return 0, err
+	}
+
+	go func() {
+		if stdin != nil {
+			buf := make([]byte, 32*1024)
+			for {
+				n, err := stdin.Read(buf)
+				if n > 0 {
+					input := append([]byte{}, buf[:n]...)
+					if send(&pb.ExecRequest{Stdin: input}) != nil {
+						return
+					}
+				}
+				if err != nil {
+					break
+				}
+			}
+		}
+		send(&pb.ExecRequest{CloseStdin: true})
+	}()
+
+	go func() {
+		for {
+			select {
+			case size := <-resize:
+				if send(&pb.ExecRequest{Resize: &size}) != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return 0, errors.New("stream ended before the command exited")
+		} else if err != nil {
+			return 0, err
+		}
+
+		if stdout != nil && len(reply.Stdout) > 0 {
+			if _, err := stdout.Write(reply.Stdout); err != nil {
+				return 0, err
+			}
+		}
+		if stderr != nil && len(reply.Stderr) > 0 {
+			if _, err := stderr.Write(reply.Stderr); err != nil {
+				return 0, err
+			}
+		}
+
+		if reply.Exited {
+			return int(reply.ExitCode), nil
+		}
+	}
+}
+
+// PortForward tunnels `conn` to the container port described by `req`.
+func (c clientImpl) PortForward(ctx context.Context, req pb.PortForwardRequest,
+	conn io.ReadWriter) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.pbClient.PortForward(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := stream.Send(&req); err != nil {
+		return err
+	}
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				data := append([]byte{}, buf[:n]...)
+				msg := &pb.PortForwardRequest{Data: data}
+				if stream.Send(msg) != nil {
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		stream.Send(&pb.PortForwardRequest{CloseWrite: true})
+	}()
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := conn.Write(reply.Data); err != nil {
+			return err
+		}
+	}
+}
+
 // Deploy makes a request to the Kelda daemon to deploy the given deployment.
 func (c clientImpl) Deploy(deployment string) error {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
@@ -205,6 +426,47 @@ func (c clientImpl) Deploy(deployment string) error {
 	return err
 }
 
+// Cordon sets whether new containers may be scheduled on a worker, and whether
+// its containers should be moved to other workers.
+func (c clientImpl) Cordon(req pb.CordonRequest) error {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	_, err := c.pbClient.Cordon(ctx, &req)
+	return err
+}
+
+// Handoff hands a deployment off to its cluster, which manages its own machines
+// from then on.
+func (c clientImpl) Handoff(req pb.HandoffRequest) error {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	_, err := c.pbClient.Handoff(ctx, &req)
+	return err
+}
+
+// Upgrade rolls the minions of a deployment's machines to the daemon's version.
+func (c clientImpl) Upgrade(req pb.UpgradeRequest) error {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	_, err := c.pbClient.Upgrade(ctx, &req)
+	return err
+}
+
+// Snapshot retrieves the latest snapshot of a cluster's state.
+func (c clientImpl) Snapshot(req pb.SnapshotRequest) (string, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.Snapshot(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return reply.Snapshot, nil
+}
+
+// Restore restores the IP allocations and container placements in a snapshot
+// of a cluster's state.
+func (c clientImpl) Restore(req pb.RestoreRequest) error {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	_, err := c.pbClient.Restore(ctx, &req)
+	return err
+}
+
 // Version retrieves the Kelda version of the remote daemon.
 func (c clientImpl) Version() (string, error) {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
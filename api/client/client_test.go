@@ -1,7 +1,12 @@
 package client
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,11 +20,20 @@ import (
 type mockAPIClient struct {
 	mockResponse string
 	mockError    error
+	mockLogs     []pb.LogsReply
+	mockExec     *mockExecClient
+	mockForward  *mockPortForwardClient
+
+	// The namespace that queries are expected to be limited to.
+	expNamespace string
 }
 
 func (c mockAPIClient) Query(ctx context.Context, in *pb.DBQuery,
 	opts ...grpc.CallOption) (*pb.QueryReply, error) {
 
+	if in.Namespace != c.expNamespace {
+		return nil, fmt.Errorf("unexpected namespace %q", in.Namespace)
+	}
 	return &pb.QueryReply{TableContents: c.mockResponse}, c.mockError
 }
 
@@ -29,6 +43,36 @@ func (c mockAPIClient) Deploy(ctx context.Context, in *pb.DeployRequest,
 	return &pb.DeployReply{}, nil
 }
 
+func (c mockAPIClient) Cordon(ctx context.Context, in *pb.CordonRequest,
+	opts ...grpc.CallOption) (*pb.CordonReply, error) {
+
+	return &pb.CordonReply{}, c.mockError
+}
+
+func (c mockAPIClient) Handoff(ctx context.Context, in *pb.HandoffRequest,
+	opts ...grpc.CallOption) (*pb.HandoffReply, error) {
+
+	return &pb.HandoffReply{}, c.mockError
+}
+
+func (c mockAPIClient) Upgrade(ctx context.Context, in *pb.UpgradeRequest,
+	opts ...grpc.CallOption) (*pb.UpgradeReply, error) {
+
+	return &pb.UpgradeReply{}, c.mockError
+}
+
+func (c mockAPIClient) Snapshot(ctx context.Context, in *pb.SnapshotRequest,
+	opts ...grpc.CallOption) (*pb.SnapshotReply, error) {
+
+	return &pb.SnapshotReply{Snapshot: c.mockResponse}, c.mockError
+}
+
+func (c mockAPIClient) Restore(ctx context.Context, in *pb.RestoreRequest,
+	opts ...grpc.CallOption) (*pb.RestoreReply, error) {
+
+	return &pb.RestoreReply{}, c.mockError
+}
+
 func (c mockAPIClient) QueryCounters(ctx context.Context, in *pb.CountersRequest,
 	opts ...grpc.CallOption) (*pb.CountersReply, error) {
 
@@ -47,6 +91,111 @@ func (c mockAPIClient) Version(ctx context.Context, in *pb.VersionRequest,
 	return &pb.VersionReply{}, nil
 }
 
+func (c mockAPIClient) Logs(ctx context.Context, in *pb.LogsRequest,
+	opts ...grpc.CallOption) (pb.API_LogsClient, error) {
+
+	return &mockLogsClient{replies: c.mockLogs}, c.mockError
+}
+
+type mockLogsClient struct {
+	grpc.ClientStream
+	replies []pb.LogsReply
+}
+
+func (c *mockLogsClient) Recv() (*pb.LogsReply, error) {
+	if len(c.replies) == 0 {
+		return nil, io.EOF
+	}
+
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return &reply, nil
+}
+
+func (c mockAPIClient) Exec(ctx context.Context, opts ...grpc.CallOption) (
+	pb.API_ExecClient, error) {
+
+	return c.mockExec, c.mockError
+}
+
+type mockExecClient struct {
+	grpc.ClientStream
+
+	sync.Mutex
+	sent        []pb.ExecRequest
+	replies     []pb.ExecReply
+	stdinClosed chan struct{}
+}
+
+func newMockExecClient(replies ...pb.ExecReply) *mockExecClient {
+	return &mockExecClient{replies: replies, stdinClosed: make(chan struct{})}
+}
+
+func (c *mockExecClient) Send(req *pb.ExecRequest) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.sent = append(c.sent, *req)
+	if req.CloseStdin {
+		close(c.stdinClosed)
+	}
+	return nil
+}
+
+// Recv pops the mocked replies. The reply marking the command as exited is only
+// returned once stdin is closed, so that all input is sent by then.
+func (c *mockExecClient) Recv() (*pb.ExecReply, error) {
+	if len(c.replies) == 0 {
+		return nil, io.EOF
+	}
+
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	if reply.Exited {
+		<-c.stdinClosed
+	}
+	return &reply, nil
+}
+
+func (c mockAPIClient) PortForward(ctx context.Context,
+	opts ...grpc.CallOption) (pb.API_PortForwardClient, error) {
+
+	return c.mockForward, c.mockError
+}
+
+type mockPortForwardClient struct {
+	grpc.ClientStream
+
+	sync.Mutex
+	sent        []pb.PortForwardRequest
+	replies     []pb.PortForwardReply
+	writeClosed chan struct{}
+}
+
+func (c *mockPortForwardClient) Send(req *pb.PortForwardRequest) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.sent = append(c.sent, *req)
+	if req.CloseWrite {
+		close(c.writeClosed)
+	}
+	return nil
+}
+
+// Recv pops the mocked replies, and then ends the stream once the client is
+// done writing.
+func (c *mockPortForwardClient) Recv() (*pb.PortForwardReply, error) {
+	if len(c.replies) == 0 {
+		<-c.writeClosed
+		return nil, io.EOF
+	}
+
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return &reply, nil
+}
+
 func (c mockAPIClient) SetSecret(ctx context.Context, in *pb.Secret,
 	opts ...grpc.CallOption) (*pb.SecretReply, error) {
 
@@ -127,6 +276,19 @@ func TestUnmarshalError(t *testing.T) {
 	assert.EqualError(t, err, "unexpected end of JSON input")
 }
 
+func TestQueryNamespace(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{mockResponse: `[]`, expNamespace: "ns"}
+	c := clientImpl{pbClient: apiClient, namespace: "ns"}
+	_, err := c.QueryMachines()
+	assert.NoError(t, err)
+
+	c = clientImpl{pbClient: apiClient}
+	_, err = c.QueryMachines()
+	assert.EqualError(t, err, `unexpected namespace ""`)
+}
+
 func TestGrpcError(t *testing.T) {
 	t.Parallel()
 
@@ -139,3 +301,188 @@ func TestGrpcError(t *testing.T) {
 	_, err := c.QueryMachines()
 	assert.EqualError(t, err, "timeout")
 }
+
+func TestLogs(t *testing.T) {
+	t.Parallel()
+
+	logs := []pb.LogsReply{
+		{Timestamp: 1, Line: "foo"},
+		{Timestamp: 2, Stderr: true, Line: "bar"},
+	}
+	c := clientImpl{pbClient: mockAPIClient{mockLogs: logs}}
+
+	var received []pb.LogsReply
+	err := c.Logs(context.Background(), pb.LogsRequest{Container: "id"},
+		func(reply pb.LogsReply) error {
+			received = append(received, reply)
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, logs, received)
+
+	// Errors returned by the handler should stop the stream.
+	received = nil
+	err = c.Logs(context.Background(), pb.LogsRequest{Container: "id"},
+		func(reply pb.LogsReply) error {
+			received = append(received, reply)
+			return assert.AnError
+		})
+	assert.Equal(t, assert.AnError, err)
+	assert.Len(t, received, 1)
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	err = c.Logs(context.Background(), pb.LogsRequest{Container: "id"},
+		func(pb.LogsReply) error { return nil })
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	stream := newMockExecClient(
+		pb.ExecReply{Stdout: []byte("out")},
+		pb.ExecReply{Stderr: []byte("err")},
+		pb.ExecReply{Exited: true, ExitCode: 2})
+	c := clientImpl{pbClient: mockAPIClient{mockExec: stream}}
+
+	stdinReader, stdinWriter := io.Pipe()
+	resize := make(chan pb.TerminalSize)
+	go func() {
+		resize <- pb.TerminalSize{Height: 24, Width: 80}
+		// The second resize is only received once the first was sent.
+		resize <- pb.TerminalSize{Height: 48, Width: 160}
+		stdinWriter.Write([]byte("in"))
+		stdinWriter.Close()
+	}()
+
+	req := pb.ExecRequest{Host: "host", Container: "id",
+		Command: []string{"sh"}, Tty: true}
+	var stdout, stderr bytes.Buffer
+	exitCode, err := c.Exec(context.Background(), req, stdinReader, &stdout,
+		&stderr, resize)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "out", stdout.String())
+	assert.Equal(t, "err", stderr.String())
+
+	stream.Lock()
+	defer stream.Unlock()
+	assert.Equal(t, req, stream.sent[0])
+	assert.Contains(t, stream.sent, pb.ExecRequest{Stdin: []byte("in")})
+	assert.Contains(t, stream.sent, pb.ExecRequest{CloseStdin: true})
+	assert.Contains(t, stream.sent, pb.ExecRequest{
+		Resize: &pb.TerminalSize{Height: 24, Width: 80}})
+}
+
+func TestExecErrors(t *testing.T) {
+	t.Parallel()
+
+	// Without stdin, the command's input should be closed immediately.
+	stream := newMockExecClient(pb.ExecReply{Exited: true})
+	c := clientImpl{pbClient: mockAPIClient{mockExec: stream}}
+	exitCode, err := c.Exec(context.Background(), pb.ExecRequest{},
+		nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Zero(t, exitCode)
+
+	c = clientImpl{pbClient: mockAPIClient{mockExec: newMockExecClient()}}
+	_, err = c.Exec(context.Background(), pb.ExecRequest{}, nil, nil, nil, nil)
+	assert.EqualError(t, err, "stream ended before the command exited")
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	_, err = c.Exec(context.Background(), pb.ExecRequest{}, nil, nil, nil, nil)
+	assert.Equal(t, assert.AnError, err)
+}
+
+type mockConn struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (c *mockConn) Read(p []byte) (int, error) {
+	return c.Reader.Read(p)
+}
+
+func TestPortForward(t *testing.T) {
+	t.Parallel()
+
+	stream := &mockPortForwardClient{
+		replies: []pb.PortForwardReply{
+			{Data: []byte("po")},
+			{Data: []byte("ng")},
+		},
+		writeClosed: make(chan struct{}),
+	}
+	c := clientImpl{pbClient: mockAPIClient{mockForward: stream}}
+
+	req := pb.PortForwardRequest{Host: "host", IP: "10.0.0.2", Port: 80}
+	conn := &mockConn{Reader: strings.NewReader("ping")}
+	assert.NoError(t, c.PortForward(context.Background(), req, conn))
+	assert.Equal(t, "pong", conn.String())
+
+	stream.Lock()
+	defer stream.Unlock()
+	assert.Equal(t, []pb.PortForwardRequest{
+		req,
+		{Data: []byte("ping")},
+		{CloseWrite: true},
+	}, stream.sent)
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	assert.Equal(t, assert.AnError, c.PortForward(context.Background(), req,
+		&mockConn{}))
+}
+
+func TestCordon(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{}}
+	assert.NoError(t, c.Cordon(pb.CordonRequest{CloudID: "1"}))
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	assert.Equal(t, assert.AnError, c.Cordon(pb.CordonRequest{CloudID: "1"}))
+}
+
+func TestHandoff(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{}}
+	assert.NoError(t, c.Handoff(pb.HandoffRequest{Namespace: "ns"}))
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	assert.Equal(t, assert.AnError, c.Handoff(pb.HandoffRequest{Namespace: "ns"}))
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{}}
+	assert.NoError(t, c.Upgrade(pb.UpgradeRequest{Namespace: "ns"}))
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	assert.Equal(t, assert.AnError, c.Upgrade(pb.UpgradeRequest{Namespace: "ns"}))
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{mockResponse: "snapshot"}}
+	snapshot, err := c.Snapshot(pb.SnapshotRequest{Namespace: "ns"})
+	assert.NoError(t, err)
+	assert.Equal(t, "snapshot", snapshot)
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	_, err = c.Snapshot(pb.SnapshotRequest{Namespace: "ns"})
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestRestore(t *testing.T) {
+	t.Parallel()
+
+	req := pb.RestoreRequest{Namespace: "ns", Snapshot: "snapshot"}
+	c := clientImpl{pbClient: mockAPIClient{}}
+	assert.NoError(t, c.Restore(req))
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	assert.Equal(t, assert.AnError, c.Restore(req))
+}
@@ -2,7 +2,9 @@
 
 package mocks
 
+import context "golang.org/x/net/context"
 import db "github.com/kelda/kelda/db"
+import io "io"
 import mock "github.com/stretchr/testify/mock"
 import pb "github.com/kelda/kelda/api/pb"
 
@@ -25,6 +27,20 @@ func (_m *Client) Close() error {
 	return r0
 }
 
+// Cordon provides a mock function with given fields: req
+func (_m *Client) Cordon(req pb.CordonRequest) error {
+	ret := _m.Called(req)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(pb.CordonRequest) error); ok {
+		r0 = rf(req)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Deploy provides a mock function with given fields: deployment
 func (_m *Client) Deploy(deployment string) error {
 	ret := _m.Called(deployment)
@@ -39,6 +55,69 @@ func (_m *Client) Deploy(deployment string) error {
 	return r0
 }
 
+// Exec provides a mock function with given fields: ctx, req, stdin, stdout, stderr, resize
+func (_m *Client) Exec(ctx context.Context, req pb.ExecRequest, stdin io.Reader, stdout io.Writer, stderr io.Writer, resize <-chan pb.TerminalSize) (int, error) {
+	ret := _m.Called(ctx, req, stdin, stdout, stderr, resize)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, pb.ExecRequest, io.Reader, io.Writer, io.Writer, <-chan pb.TerminalSize) int); ok {
+		r0 = rf(ctx, req, stdin, stdout, stderr, resize)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, pb.ExecRequest, io.Reader, io.Writer, io.Writer, <-chan pb.TerminalSize) error); ok {
+		r1 = rf(ctx, req, stdin, stdout, stderr, resize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Handoff provides a mock function with given fields: req
+func (_m *Client) Handoff(req pb.HandoffRequest) error {
+	ret := _m.Called(req)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(pb.HandoffRequest) error); ok {
+		r0 = rf(req)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Logs provides a mock function with given fields: ctx, req, handle
+func (_m *Client) Logs(ctx context.Context, req pb.LogsRequest, handle func(pb.LogsReply) error) error {
+	ret := _m.Called(ctx, req, handle)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pb.LogsRequest, func(pb.LogsReply) error) error); ok {
+		r0 = rf(ctx, req, handle)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PortForward provides a mock function with given fields: ctx, req, conn
+func (_m *Client) PortForward(ctx context.Context, req pb.PortForwardRequest, conn io.ReadWriter) error {
+	ret := _m.Called(ctx, req, conn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pb.PortForwardRequest, io.ReadWriter) error); ok {
+		r0 = rf(ctx, req, conn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // QueryBlueprints provides a mock function with given fields:
 func (_m *Client) QueryBlueprints() ([]db.Blueprint, error) {
 	ret := _m.Called()
@@ -246,6 +325,20 @@ func (_m *Client) QueryMinionCounters(_a0 string) ([]pb.Counter, error) {
 	return r0, r1
 }
 
+// Restore provides a mock function with given fields: req
+func (_m *Client) Restore(req pb.RestoreRequest) error {
+	ret := _m.Called(req)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(pb.RestoreRequest) error); ok {
+		r0 = rf(req)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetSecret provides a mock function with given fields: name, value
 func (_m *Client) SetSecret(name string, value string) error {
 	ret := _m.Called(name, value)
@@ -260,6 +353,41 @@ func (_m *Client) SetSecret(name string, value string) error {
 	return r0
 }
 
+// Snapshot provides a mock function with given fields: req
+func (_m *Client) Snapshot(req pb.SnapshotRequest) (string, error) {
+	ret := _m.Called(req)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(pb.SnapshotRequest) string); ok {
+		r0 = rf(req)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(pb.SnapshotRequest) error); ok {
+		r1 = rf(req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Upgrade provides a mock function with given fields: req
+func (_m *Client) Upgrade(req pb.UpgradeRequest) error {
+	ret := _m.Called(req)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(pb.UpgradeRequest) error); ok {
+		r0 = rf(req)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Version provides a mock function with given fields:
 func (_m *Client) Version() (string, error) {
 	ret := _m.Called()
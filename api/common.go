@@ -13,6 +13,10 @@ var DefaultSocket = "unix:///tmp/kelda.sock"
 // DefaultRemotePort is the port remote Kelda daemons (the minion) listen on by default.
 const DefaultRemotePort = 9000
 
+// DefaultMetricsPort is the port on which the daemon and minions serve
+// Prometheus metrics by default.
+const DefaultMetricsPort = 9002
+
 // ParseListenAddress validates and parses a socket address into the
 // protocol and address.
 func ParseListenAddress(lAddr string) (string, string, error) {
@@ -14,12 +14,29 @@ It has these top-level messages:
 	QueryReply
 	DeployRequest
 	DeployReply
+	CordonRequest
+	CordonReply
+	HandoffRequest
+	HandoffReply
+	UpgradeRequest
+	UpgradeReply
+	SnapshotRequest
+	SnapshotReply
+	RestoreRequest
+	RestoreReply
 	VersionRequest
 	VersionReply
 	CountersRequest
 	MinionCountersRequest
 	CountersReply
 	Counter
+	LogsRequest
+	LogsReply
+	ExecRequest
+	TerminalSize
+	ExecReply
+	PortForwardRequest
+	PortForwardReply
 */
 package pb
 
@@ -44,8 +61,9 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Secret struct {
-	Name  string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
-	Value string `protobuf:"bytes,2,opt,name=Value" json:"Value,omitempty"`
+	Name      string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
+	Value     string `protobuf:"bytes,2,opt,name=Value" json:"Value,omitempty"`
+	Namespace string `protobuf:"bytes,3,opt,name=Namespace" json:"Namespace,omitempty"`
 }
 
 func (m *Secret) Reset()                    { *m = Secret{} }
@@ -67,6 +85,13 @@ func (m *Secret) GetValue() string {
 	return ""
 }
 
+func (m *Secret) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
 type SecretReply struct {
 }
 
@@ -76,7 +101,8 @@ func (*SecretReply) ProtoMessage()               {}
 func (*SecretReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
 
 type DBQuery struct {
-	Table string `protobuf:"bytes,1,opt,name=Table" json:"Table,omitempty"`
+	Table     string `protobuf:"bytes,1,opt,name=Table" json:"Table,omitempty"`
+	Namespace string `protobuf:"bytes,2,opt,name=Namespace" json:"Namespace,omitempty"`
 }
 
 func (m *DBQuery) Reset()                    { *m = DBQuery{} }
@@ -91,6 +117,13 @@ func (m *DBQuery) GetTable() string {
 	return ""
 }
 
+func (m *DBQuery) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
 type QueryReply struct {
 	TableContents string `protobuf:"bytes,1,opt,name=TableContents" json:"TableContents,omitempty"`
 }
@@ -131,13 +164,175 @@ func (m *DeployReply) String() string            { return proto.CompactTextStrin
 func (*DeployReply) ProtoMessage()               {}
 func (*DeployReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
+type CordonRequest struct {
+	// The cloud ID of the worker.
+	CloudID       string `protobuf:"bytes,1,opt,name=CloudID" json:"CloudID,omitempty"`
+	Unschedulable bool   `protobuf:"varint,2,opt,name=Unschedulable" json:"Unschedulable,omitempty"`
+	Drain         bool   `protobuf:"varint,3,opt,name=Drain" json:"Drain,omitempty"`
+}
+
+func (m *CordonRequest) Reset()                    { *m = CordonRequest{} }
+func (m *CordonRequest) String() string            { return proto.CompactTextString(m) }
+func (*CordonRequest) ProtoMessage()               {}
+func (*CordonRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *CordonRequest) GetCloudID() string {
+	if m != nil {
+		return m.CloudID
+	}
+	return ""
+}
+
+func (m *CordonRequest) GetUnschedulable() bool {
+	if m != nil {
+		return m.Unschedulable
+	}
+	return false
+}
+
+func (m *CordonRequest) GetDrain() bool {
+	if m != nil {
+		return m.Drain
+	}
+	return false
+}
+
+type CordonReply struct {
+}
+
+func (m *CordonReply) Reset()                    { *m = CordonReply{} }
+func (m *CordonReply) String() string            { return proto.CompactTextString(m) }
+func (*CordonReply) ProtoMessage()               {}
+func (*CordonReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+type HandoffRequest struct {
+	Namespace string `protobuf:"bytes,1,opt,name=Namespace" json:"Namespace,omitempty"`
+	// The blueprint of the deployment. Only set by the daemon.
+	Blueprint string `protobuf:"bytes,2,opt,name=Blueprint" json:"Blueprint,omitempty"`
+}
+
+func (m *HandoffRequest) Reset()                    { *m = HandoffRequest{} }
+func (m *HandoffRequest) String() string            { return proto.CompactTextString(m) }
+func (*HandoffRequest) ProtoMessage()               {}
+func (*HandoffRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *HandoffRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *HandoffRequest) GetBlueprint() string {
+	if m != nil {
+		return m.Blueprint
+	}
+	return ""
+}
+
+type HandoffReply struct {
+}
+
+func (m *HandoffReply) Reset()                    { *m = HandoffReply{} }
+func (m *HandoffReply) String() string            { return proto.CompactTextString(m) }
+func (*HandoffReply) ProtoMessage()               {}
+func (*HandoffReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+type UpgradeRequest struct {
+	Namespace string `protobuf:"bytes,1,opt,name=Namespace" json:"Namespace,omitempty"`
+}
+
+func (m *UpgradeRequest) Reset()                    { *m = UpgradeRequest{} }
+func (m *UpgradeRequest) String() string            { return proto.CompactTextString(m) }
+func (*UpgradeRequest) ProtoMessage()               {}
+func (*UpgradeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *UpgradeRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+type UpgradeReply struct {
+}
+
+func (m *UpgradeReply) Reset()                    { *m = UpgradeReply{} }
+func (m *UpgradeReply) String() string            { return proto.CompactTextString(m) }
+func (*UpgradeReply) ProtoMessage()               {}
+func (*UpgradeReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+type SnapshotRequest struct {
+	Namespace string `protobuf:"bytes,1,opt,name=Namespace" json:"Namespace,omitempty"`
+}
+
+func (m *SnapshotRequest) Reset()                    { *m = SnapshotRequest{} }
+func (m *SnapshotRequest) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotRequest) ProtoMessage()               {}
+func (*SnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *SnapshotRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+type SnapshotReply struct {
+	Snapshot string `protobuf:"bytes,1,opt,name=Snapshot" json:"Snapshot,omitempty"`
+}
+
+func (m *SnapshotReply) Reset()                    { *m = SnapshotReply{} }
+func (m *SnapshotReply) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotReply) ProtoMessage()               {}
+func (*SnapshotReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *SnapshotReply) GetSnapshot() string {
+	if m != nil {
+		return m.Snapshot
+	}
+	return ""
+}
+
+type RestoreRequest struct {
+	Namespace string `protobuf:"bytes,1,opt,name=Namespace" json:"Namespace,omitempty"`
+	Snapshot  string `protobuf:"bytes,2,opt,name=Snapshot" json:"Snapshot,omitempty"`
+}
+
+func (m *RestoreRequest) Reset()                    { *m = RestoreRequest{} }
+func (m *RestoreRequest) String() string            { return proto.CompactTextString(m) }
+func (*RestoreRequest) ProtoMessage()               {}
+func (*RestoreRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *RestoreRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *RestoreRequest) GetSnapshot() string {
+	if m != nil {
+		return m.Snapshot
+	}
+	return ""
+}
+
+type RestoreReply struct {
+}
+
+func (m *RestoreReply) Reset()                    { *m = RestoreReply{} }
+func (m *RestoreReply) String() string            { return proto.CompactTextString(m) }
+func (*RestoreReply) ProtoMessage()               {}
+func (*RestoreReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
 type VersionRequest struct {
 }
 
 func (m *VersionRequest) Reset()                    { *m = VersionRequest{} }
 func (m *VersionRequest) String() string            { return proto.CompactTextString(m) }
 func (*VersionRequest) ProtoMessage()               {}
-func (*VersionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*VersionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
 
 type VersionReply struct {
 	Version string `protobuf:"bytes,1,opt,name=Version" json:"Version,omitempty"`
@@ -146,7 +341,7 @@ type VersionReply struct {
 func (m *VersionReply) Reset()                    { *m = VersionReply{} }
 func (m *VersionReply) String() string            { return proto.CompactTextString(m) }
 func (*VersionReply) ProtoMessage()               {}
-func (*VersionReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*VersionReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
 
 func (m *VersionReply) GetVersion() string {
 	if m != nil {
@@ -161,7 +356,7 @@ type CountersRequest struct {
 func (m *CountersRequest) Reset()                    { *m = CountersRequest{} }
 func (m *CountersRequest) String() string            { return proto.CompactTextString(m) }
 func (*CountersRequest) ProtoMessage()               {}
-func (*CountersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*CountersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
 
 type MinionCountersRequest struct {
 	Host string `protobuf:"bytes,1,opt,name=Host" json:"Host,omitempty"`
@@ -170,7 +365,7 @@ type MinionCountersRequest struct {
 func (m *MinionCountersRequest) Reset()                    { *m = MinionCountersRequest{} }
 func (m *MinionCountersRequest) String() string            { return proto.CompactTextString(m) }
 func (*MinionCountersRequest) ProtoMessage()               {}
-func (*MinionCountersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+func (*MinionCountersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
 
 func (m *MinionCountersRequest) GetHost() string {
 	if m != nil {
@@ -186,7 +381,7 @@ type CountersReply struct {
 func (m *CountersReply) Reset()                    { *m = CountersReply{} }
 func (m *CountersReply) String() string            { return proto.CompactTextString(m) }
 func (*CountersReply) ProtoMessage()               {}
-func (*CountersReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+func (*CountersReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
 
 func (m *CountersReply) GetCounters() []*Counter {
 	if m != nil {
@@ -205,7 +400,7 @@ type Counter struct {
 func (m *Counter) Reset()                    { *m = Counter{} }
 func (m *Counter) String() string            { return proto.CompactTextString(m) }
 func (*Counter) ProtoMessage()               {}
-func (*Counter) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (*Counter) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
 
 func (m *Counter) GetPkg() string {
 	if m != nil {
@@ -235,6 +430,309 @@ func (m *Counter) GetPrevValue() uint64 {
 	return 0
 }
 
+type LogsRequest struct {
+	// The public IP of the machine running the container. Only used by the
+	// daemon.
+	Host string `protobuf:"bytes,1,opt,name=Host" json:"Host,omitempty"`
+	// The Docker ID or name of the container.
+	Container string `protobuf:"bytes,2,opt,name=Container" json:"Container,omitempty"`
+	Follow    bool   `protobuf:"varint,3,opt,name=Follow" json:"Follow,omitempty"`
+	// Unix times in nanoseconds. Zero values are ignored.
+	Since int64 `protobuf:"varint,4,opt,name=Since" json:"Since,omitempty"`
+	Until int64 `protobuf:"varint,5,opt,name=Until" json:"Until,omitempty"`
+	// If greater than zero, only the last Tail lines are returned.
+	Tail int64 `protobuf:"varint,6,opt,name=Tail" json:"Tail,omitempty"`
+}
+
+func (m *LogsRequest) Reset()                    { *m = LogsRequest{} }
+func (m *LogsRequest) String() string            { return proto.CompactTextString(m) }
+func (*LogsRequest) ProtoMessage()               {}
+func (*LogsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *LogsRequest) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *LogsRequest) GetContainer() string {
+	if m != nil {
+		return m.Container
+	}
+	return ""
+}
+
+func (m *LogsRequest) GetFollow() bool {
+	if m != nil {
+		return m.Follow
+	}
+	return false
+}
+
+func (m *LogsRequest) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *LogsRequest) GetUntil() int64 {
+	if m != nil {
+		return m.Until
+	}
+	return 0
+}
+
+func (m *LogsRequest) GetTail() int64 {
+	if m != nil {
+		return m.Tail
+	}
+	return 0
+}
+
+type LogsReply struct {
+	// Unix time in nanoseconds.
+	Timestamp int64  `protobuf:"varint,1,opt,name=Timestamp" json:"Timestamp,omitempty"`
+	Stderr    bool   `protobuf:"varint,2,opt,name=Stderr" json:"Stderr,omitempty"`
+	Line      string `protobuf:"bytes,3,opt,name=Line" json:"Line,omitempty"`
+}
+
+func (m *LogsReply) Reset()                    { *m = LogsReply{} }
+func (m *LogsReply) String() string            { return proto.CompactTextString(m) }
+func (*LogsReply) ProtoMessage()               {}
+func (*LogsReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *LogsReply) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *LogsReply) GetStderr() bool {
+	if m != nil {
+		return m.Stderr
+	}
+	return false
+}
+
+func (m *LogsReply) GetLine() string {
+	if m != nil {
+		return m.Line
+	}
+	return ""
+}
+
+// The first ExecRequest in a stream describes the command to run. The rest
+// carry its input, and changes to the size of its terminal.
+type ExecRequest struct {
+	// The public IP of the machine running the container. Only used by the
+	// daemon.
+	Host string `protobuf:"bytes,1,opt,name=Host" json:"Host,omitempty"`
+	// The Docker ID or name of the container.
+	Container string   `protobuf:"bytes,2,opt,name=Container" json:"Container,omitempty"`
+	Command   []string `protobuf:"bytes,3,rep,name=Command" json:"Command,omitempty"`
+	// Whether to run the command in a pseudo-terminal.
+	Tty   bool   `protobuf:"varint,4,opt,name=Tty" json:"Tty,omitempty"`
+	Stdin []byte `protobuf:"bytes,5,opt,name=Stdin,proto3" json:"Stdin,omitempty"`
+	// Set once the client has no more input for the command.
+	CloseStdin bool          `protobuf:"varint,6,opt,name=CloseStdin" json:"CloseStdin,omitempty"`
+	Resize     *TerminalSize `protobuf:"bytes,7,opt,name=Resize" json:"Resize,omitempty"`
+}
+
+func (m *ExecRequest) Reset()                    { *m = ExecRequest{} }
+func (m *ExecRequest) String() string            { return proto.CompactTextString(m) }
+func (*ExecRequest) ProtoMessage()               {}
+func (*ExecRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *ExecRequest) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *ExecRequest) GetContainer() string {
+	if m != nil {
+		return m.Container
+	}
+	return ""
+}
+
+func (m *ExecRequest) GetCommand() []string {
+	if m != nil {
+		return m.Command
+	}
+	return nil
+}
+
+func (m *ExecRequest) GetTty() bool {
+	if m != nil {
+		return m.Tty
+	}
+	return false
+}
+
+func (m *ExecRequest) GetStdin() []byte {
+	if m != nil {
+		return m.Stdin
+	}
+	return nil
+}
+
+func (m *ExecRequest) GetCloseStdin() bool {
+	if m != nil {
+		return m.CloseStdin
+	}
+	return false
+}
+
+func (m *ExecRequest) GetResize() *TerminalSize {
+	if m != nil {
+		return m.Resize
+	}
+	return nil
+}
+
+type TerminalSize struct {
+	Height int32 `protobuf:"varint,1,opt,name=Height" json:"Height,omitempty"`
+	Width  int32 `protobuf:"varint,2,opt,name=Width" json:"Width,omitempty"`
+}
+
+func (m *TerminalSize) Reset()                    { *m = TerminalSize{} }
+func (m *TerminalSize) String() string            { return proto.CompactTextString(m) }
+func (*TerminalSize) ProtoMessage()               {}
+func (*TerminalSize) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *TerminalSize) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *TerminalSize) GetWidth() int32 {
+	if m != nil {
+		return m.Width
+	}
+	return 0
+}
+
+// The last ExecReply in a stream has Exited set, along with the command's exit
+// code.
+type ExecReply struct {
+	Stdout   []byte `protobuf:"bytes,1,opt,name=Stdout,proto3" json:"Stdout,omitempty"`
+	Stderr   []byte `protobuf:"bytes,2,opt,name=Stderr,proto3" json:"Stderr,omitempty"`
+	Exited   bool   `protobuf:"varint,3,opt,name=Exited" json:"Exited,omitempty"`
+	ExitCode int32  `protobuf:"varint,4,opt,name=ExitCode" json:"ExitCode,omitempty"`
+}
+
+func (m *ExecReply) Reset()                    { *m = ExecReply{} }
+func (m *ExecReply) String() string            { return proto.CompactTextString(m) }
+func (*ExecReply) ProtoMessage()               {}
+func (*ExecReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *ExecReply) GetStdout() []byte {
+	if m != nil {
+		return m.Stdout
+	}
+	return nil
+}
+
+func (m *ExecReply) GetStderr() []byte {
+	if m != nil {
+		return m.Stderr
+	}
+	return nil
+}
+
+func (m *ExecReply) GetExited() bool {
+	if m != nil {
+		return m.Exited
+	}
+	return false
+}
+
+func (m *ExecReply) GetExitCode() int32 {
+	if m != nil {
+		return m.ExitCode
+	}
+	return 0
+}
+
+// The first PortForwardRequest in a stream describes the container port to
+// connect to. The rest carry the data sent to it.
+type PortForwardRequest struct {
+	// The public IP of the machine running the container. Only used by the
+	// daemon.
+	Host string `protobuf:"bytes,1,opt,name=Host" json:"Host,omitempty"`
+	// The IP address of the container in the Kelda network.
+	IP   string `protobuf:"bytes,2,opt,name=IP" json:"IP,omitempty"`
+	Port int32  `protobuf:"varint,3,opt,name=Port" json:"Port,omitempty"`
+	Data []byte `protobuf:"bytes,4,opt,name=Data,proto3" json:"Data,omitempty"`
+	// Set once the client has no more data to send.
+	CloseWrite bool `protobuf:"varint,5,opt,name=CloseWrite" json:"CloseWrite,omitempty"`
+}
+
+func (m *PortForwardRequest) Reset()                    { *m = PortForwardRequest{} }
+func (m *PortForwardRequest) String() string            { return proto.CompactTextString(m) }
+func (*PortForwardRequest) ProtoMessage()               {}
+func (*PortForwardRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+func (m *PortForwardRequest) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *PortForwardRequest) GetIP() string {
+	if m != nil {
+		return m.IP
+	}
+	return ""
+}
+
+func (m *PortForwardRequest) GetPort() int32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *PortForwardRequest) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *PortForwardRequest) GetCloseWrite() bool {
+	if m != nil {
+		return m.CloseWrite
+	}
+	return false
+}
+
+// The stream of PortForwardReplies ends once the container closes the
+// connection.
+type PortForwardReply struct {
+	Data []byte `protobuf:"bytes,1,opt,name=Data,proto3" json:"Data,omitempty"`
+}
+
+func (m *PortForwardReply) Reset()                    { *m = PortForwardReply{} }
+func (m *PortForwardReply) String() string            { return proto.CompactTextString(m) }
+func (*PortForwardReply) ProtoMessage()               {}
+func (*PortForwardReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+func (m *PortForwardReply) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Secret)(nil), "Secret")
 	proto.RegisterType((*SecretReply)(nil), "SecretReply")
@@ -242,12 +740,29 @@ func init() {
 	proto.RegisterType((*QueryReply)(nil), "QueryReply")
 	proto.RegisterType((*DeployRequest)(nil), "DeployRequest")
 	proto.RegisterType((*DeployReply)(nil), "DeployReply")
+	proto.RegisterType((*CordonRequest)(nil), "CordonRequest")
+	proto.RegisterType((*CordonReply)(nil), "CordonReply")
+	proto.RegisterType((*HandoffRequest)(nil), "HandoffRequest")
+	proto.RegisterType((*HandoffReply)(nil), "HandoffReply")
+	proto.RegisterType((*UpgradeRequest)(nil), "UpgradeRequest")
+	proto.RegisterType((*UpgradeReply)(nil), "UpgradeReply")
+	proto.RegisterType((*SnapshotRequest)(nil), "SnapshotRequest")
+	proto.RegisterType((*SnapshotReply)(nil), "SnapshotReply")
+	proto.RegisterType((*RestoreRequest)(nil), "RestoreRequest")
+	proto.RegisterType((*RestoreReply)(nil), "RestoreReply")
 	proto.RegisterType((*VersionRequest)(nil), "VersionRequest")
 	proto.RegisterType((*VersionReply)(nil), "VersionReply")
 	proto.RegisterType((*CountersRequest)(nil), "CountersRequest")
 	proto.RegisterType((*MinionCountersRequest)(nil), "MinionCountersRequest")
 	proto.RegisterType((*CountersReply)(nil), "CountersReply")
 	proto.RegisterType((*Counter)(nil), "Counter")
+	proto.RegisterType((*LogsRequest)(nil), "LogsRequest")
+	proto.RegisterType((*LogsReply)(nil), "LogsReply")
+	proto.RegisterType((*ExecRequest)(nil), "ExecRequest")
+	proto.RegisterType((*TerminalSize)(nil), "TerminalSize")
+	proto.RegisterType((*ExecReply)(nil), "ExecReply")
+	proto.RegisterType((*PortForwardRequest)(nil), "PortForwardRequest")
+	proto.RegisterType((*PortForwardReply)(nil), "PortForwardReply")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -266,9 +781,32 @@ type APIClient interface {
 	Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionReply, error)
 	QueryCounters(ctx context.Context, in *CountersRequest, opts ...grpc.CallOption) (*CountersReply, error)
 	SetSecret(ctx context.Context, in *Secret, opts ...grpc.CallOption) (*SecretReply, error)
+	// On the daemon, Logs proxies the request to the minion at LogsRequest.Host.
+	Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (API_LogsClient, error)
+	// On the daemon, Exec proxies the stream to the minion at ExecRequest.Host.
+	Exec(ctx context.Context, opts ...grpc.CallOption) (API_ExecClient, error)
+	// PortForward tunnels a TCP connection to a container. On the daemon, the
+	// stream is proxied to the minion at PortForwardRequest.Host.
+	PortForward(ctx context.Context, opts ...grpc.CallOption) (API_PortForwardClient, error)
 	// Only defined on the daemon.
 	Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error)
 	QueryMinionCounters(ctx context.Context, in *MinionCountersRequest, opts ...grpc.CallOption) (*CountersReply, error)
+	// Cordon sets whether new containers may be scheduled on a worker, and
+	// whether its containers should be moved to other workers.
+	Cordon(ctx context.Context, in *CordonRequest, opts ...grpc.CallOption) (*CordonReply, error)
+	// Handoff hands the deployment in a namespace off to its cluster, which
+	// manages its own machines from then on. On the daemon, the request is
+	// proxied to the cluster's leader with the namespace's blueprint.
+	Handoff(ctx context.Context, in *HandoffRequest, opts ...grpc.CallOption) (*HandoffReply, error)
+	// Upgrade rolls the minions of the machines in a namespace, one at a time,
+	// to the daemon's version.
+	Upgrade(ctx context.Context, in *UpgradeRequest, opts ...grpc.CallOption) (*UpgradeReply, error)
+	// Snapshot returns the latest snapshot of the cluster state taken by the
+	// leader, and Restore restores the IP allocations and container placements
+	// in a snapshot. On the daemon, the requests are proxied to the cluster's
+	// leader.
+	Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotReply, error)
+	Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*RestoreReply, error)
 }
 
 type aPIClient struct {
@@ -315,6 +853,100 @@ func (c *aPIClient) SetSecret(ctx context.Context, in *Secret, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *aPIClient) Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (API_LogsClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[0], c.cc, "/API/Logs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPILogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_LogsClient interface {
+	Recv() (*LogsReply, error)
+	grpc.ClientStream
+}
+
+type aPILogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPILogsClient) Recv() (*LogsReply, error) {
+	m := new(LogsReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) Exec(ctx context.Context, opts ...grpc.CallOption) (API_ExecClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[1], c.cc, "/API/Exec", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIExecClient{stream}
+	return x, nil
+}
+
+type API_ExecClient interface {
+	Send(*ExecRequest) error
+	Recv() (*ExecReply, error)
+	grpc.ClientStream
+}
+
+type aPIExecClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIExecClient) Send(m *ExecRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *aPIExecClient) Recv() (*ExecReply, error) {
+	m := new(ExecReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) PortForward(ctx context.Context, opts ...grpc.CallOption) (API_PortForwardClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[2], c.cc, "/API/PortForward", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIPortForwardClient{stream}
+	return x, nil
+}
+
+type API_PortForwardClient interface {
+	Send(*PortForwardRequest) error
+	Recv() (*PortForwardReply, error)
+	grpc.ClientStream
+}
+
+type aPIPortForwardClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIPortForwardClient) Send(m *PortForwardRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *aPIPortForwardClient) Recv() (*PortForwardReply, error) {
+	m := new(PortForwardReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *aPIClient) Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error) {
 	out := new(DeployReply)
 	err := grpc.Invoke(ctx, "/API/Deploy", in, out, c.cc, opts...)
@@ -333,6 +965,51 @@ func (c *aPIClient) QueryMinionCounters(ctx context.Context, in *MinionCountersR
 	return out, nil
 }
 
+func (c *aPIClient) Cordon(ctx context.Context, in *CordonRequest, opts ...grpc.CallOption) (*CordonReply, error) {
+	out := new(CordonReply)
+	err := grpc.Invoke(ctx, "/API/Cordon", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Handoff(ctx context.Context, in *HandoffRequest, opts ...grpc.CallOption) (*HandoffReply, error) {
+	out := new(HandoffReply)
+	err := grpc.Invoke(ctx, "/API/Handoff", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Upgrade(ctx context.Context, in *UpgradeRequest, opts ...grpc.CallOption) (*UpgradeReply, error) {
+	out := new(UpgradeReply)
+	err := grpc.Invoke(ctx, "/API/Upgrade", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotReply, error) {
+	out := new(SnapshotReply)
+	err := grpc.Invoke(ctx, "/API/Snapshot", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*RestoreReply, error) {
+	out := new(RestoreReply)
+	err := grpc.Invoke(ctx, "/API/Restore", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for API service
 
 type APIServer interface {
@@ -341,9 +1018,32 @@ type APIServer interface {
 	Version(context.Context, *VersionRequest) (*VersionReply, error)
 	QueryCounters(context.Context, *CountersRequest) (*CountersReply, error)
 	SetSecret(context.Context, *Secret) (*SecretReply, error)
+	// On the daemon, Logs proxies the request to the minion at LogsRequest.Host.
+	Logs(*LogsRequest, API_LogsServer) error
+	// On the daemon, Exec proxies the stream to the minion at ExecRequest.Host.
+	Exec(API_ExecServer) error
+	// PortForward tunnels a TCP connection to a container. On the daemon, the
+	// stream is proxied to the minion at PortForwardRequest.Host.
+	PortForward(API_PortForwardServer) error
 	// Only defined on the daemon.
 	Deploy(context.Context, *DeployRequest) (*DeployReply, error)
 	QueryMinionCounters(context.Context, *MinionCountersRequest) (*CountersReply, error)
+	// Cordon sets whether new containers may be scheduled on a worker, and
+	// whether its containers should be moved to other workers.
+	Cordon(context.Context, *CordonRequest) (*CordonReply, error)
+	// Handoff hands the deployment in a namespace off to its cluster, which
+	// manages its own machines from then on. On the daemon, the request is
+	// proxied to the cluster's leader with the namespace's blueprint.
+	Handoff(context.Context, *HandoffRequest) (*HandoffReply, error)
+	// Upgrade rolls the minions of the machines in a namespace, one at a time,
+	// to the daemon's version.
+	Upgrade(context.Context, *UpgradeRequest) (*UpgradeReply, error)
+	// Snapshot returns the latest snapshot of the cluster state taken by the
+	// leader, and Restore restores the IP allocations and container placements
+	// in a snapshot. On the daemon, the requests are proxied to the cluster's
+	// leader.
+	Snapshot(context.Context, *SnapshotRequest) (*SnapshotReply, error)
+	Restore(context.Context, *RestoreRequest) (*RestoreReply, error)
 }
 
 func RegisterAPIServer(s *grpc.Server, srv APIServer) {
@@ -422,6 +1122,79 @@ func _API_SetSecret_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_Logs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).Logs(m, &aPILogsServer{stream})
+}
+
+type API_LogsServer interface {
+	Send(*LogsReply) error
+	grpc.ServerStream
+}
+
+type aPILogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPILogsServer) Send(m *LogsReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _API_Exec_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(APIServer).Exec(&aPIExecServer{stream})
+}
+
+type API_ExecServer interface {
+	Send(*ExecReply) error
+	Recv() (*ExecRequest, error)
+	grpc.ServerStream
+}
+
+type aPIExecServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIExecServer) Send(m *ExecReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *aPIExecServer) Recv() (*ExecRequest, error) {
+	m := new(ExecRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _API_PortForward_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(APIServer).PortForward(&aPIPortForwardServer{stream})
+}
+
+type API_PortForwardServer interface {
+	Send(*PortForwardReply) error
+	Recv() (*PortForwardRequest, error)
+	grpc.ServerStream
+}
+
+type aPIPortForwardServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIPortForwardServer) Send(m *PortForwardReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *aPIPortForwardServer) Recv() (*PortForwardRequest, error) {
+	m := new(PortForwardRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _API_Deploy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeployRequest)
 	if err := dec(in); err != nil {
@@ -458,6 +1231,96 @@ func _API_QueryMinionCounters_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_Cordon_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CordonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Cordon(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Cordon",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Cordon(ctx, req.(*CordonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Handoff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandoffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Handoff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Handoff",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Handoff(ctx, req.(*HandoffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Upgrade_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpgradeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Upgrade(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Upgrade",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Upgrade(ctx, req.(*UpgradeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Snapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Snapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Snapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Snapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Restore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Restore(ctx, req.(*RestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _API_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "API",
 	HandlerType: (*APIServer)(nil),
@@ -486,39 +1349,115 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "QueryMinionCounters",
 			Handler:    _API_QueryMinionCounters_Handler,
 		},
+		{
+			MethodName: "Cordon",
+			Handler:    _API_Cordon_Handler,
+		},
+		{
+			MethodName: "Handoff",
+			Handler:    _API_Handoff_Handler,
+		},
+		{
+			MethodName: "Upgrade",
+			Handler:    _API_Upgrade_Handler,
+		},
+		{
+			MethodName: "Snapshot",
+			Handler:    _API_Snapshot_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _API_Restore_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Logs",
+			Handler:       _API_Logs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Exec",
+			Handler:       _API_Exec_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "PortForward",
+			Handler:       _API_PortForward_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/pb.proto",
 }
 
 func init() { proto.RegisterFile("pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 402 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x53, 0x4d, 0xaf, 0x9a, 0x40,
-	0x14, 0x05, 0xf1, 0x89, 0x5e, 0xc4, 0x67, 0x6f, 0x3f, 0x42, 0x48, 0xd3, 0x92, 0xc9, 0x5b, 0x90,
-	0x98, 0x8c, 0x09, 0xa6, 0xeb, 0xa6, 0xd5, 0x45, 0xbb, 0x68, 0x63, 0xb1, 0x71, 0x2f, 0x66, 0xd2,
-	0x98, 0x22, 0x43, 0x61, 0x68, 0xe2, 0x5f, 0xe9, 0xaf, 0x6d, 0x98, 0x19, 0x10, 0x7c, 0xee, 0xee,
-	0x3d, 0xf7, 0x23, 0x67, 0xce, 0xb9, 0x03, 0x4e, 0x9e, 0x2c, 0xf3, 0x84, 0xe6, 0x05, 0x17, 0x9c,
-	0x44, 0x30, 0xda, 0xb1, 0x63, 0xc1, 0x04, 0x22, 0x0c, 0xbf, 0x1f, 0xce, 0xcc, 0x33, 0x03, 0x33,
-	0x9c, 0xc4, 0x32, 0xc6, 0x57, 0xf0, 0xb0, 0x3f, 0xa4, 0x15, 0xf3, 0x06, 0x12, 0x54, 0x09, 0x71,
-	0xc1, 0x51, 0x33, 0x31, 0xcb, 0xd3, 0x0b, 0x79, 0x0f, 0xf6, 0xe6, 0xf3, 0x8f, 0x8a, 0x15, 0x97,
-	0xba, 0xff, 0xe7, 0x21, 0x49, 0x9b, 0x25, 0x2a, 0x21, 0x11, 0x80, 0x2c, 0xcb, 0x76, 0x7c, 0x02,
-	0x57, 0xc2, 0x6b, 0x9e, 0x09, 0x96, 0x89, 0x52, 0xf7, 0xf6, 0x41, 0xb2, 0x04, 0x77, 0xc3, 0xf2,
-	0x94, 0x5f, 0x62, 0xf6, 0xa7, 0x62, 0xa5, 0xc0, 0x77, 0x00, 0x0a, 0x38, 0xb3, 0x4c, 0xe8, 0x99,
-	0x0e, 0x52, 0x93, 0x6a, 0x06, 0x6a, 0x52, 0x73, 0x98, 0xed, 0x59, 0x51, 0x9e, 0x78, 0xa6, 0x17,
-	0x90, 0x10, 0xa6, 0x2d, 0x52, 0xf3, 0xf0, 0xc0, 0xd6, 0xb9, 0xde, 0xd6, 0xa4, 0xe4, 0x05, 0x3c,
-	0xae, 0x79, 0x95, 0x09, 0x56, 0x94, 0xcd, 0xf0, 0x02, 0x5e, 0x7f, 0x3b, 0x65, 0x27, 0x9e, 0xdd,
-	0x14, 0x6a, 0xd5, 0xbe, 0xf0, 0xb2, 0x21, 0x24, 0x63, 0xf2, 0x01, 0xdc, 0x6b, 0x9b, 0x7a, 0xf2,
-	0xf8, 0xa8, 0x01, 0xcf, 0x0c, 0xac, 0xd0, 0x89, 0xc6, 0x54, 0x77, 0xc4, 0x6d, 0x85, 0x1c, 0xc1,
-	0xd6, 0x20, 0xce, 0xc1, 0xda, 0xfe, 0xfe, 0xa5, 0x97, 0xd6, 0x61, 0xeb, 0xce, 0xe0, 0x9e, 0x3b,
-	0x56, 0x60, 0x86, 0x43, 0xed, 0x0e, 0xbe, 0x85, 0xc9, 0xb6, 0x60, 0x7f, 0x55, 0x65, 0x28, 0x2b,
-	0x57, 0x20, 0xfa, 0x37, 0x00, 0xeb, 0xd3, 0xf6, 0x2b, 0x06, 0xf0, 0xa0, 0x2c, 0x1b, 0x53, 0x6d,
-	0x9e, 0xef, 0xd0, 0xab, 0x4b, 0xc4, 0xc0, 0x45, 0xab, 0x0f, 0x3e, 0xd2, 0xbe, 0x96, 0xbe, 0x4b,
-	0xbb, 0x52, 0x12, 0x03, 0x57, 0xe0, 0xca, 0xe1, 0xe6, 0xdd, 0x38, 0xa7, 0x37, 0x4a, 0xf9, 0x33,
-	0xda, 0x13, 0x85, 0x18, 0xf8, 0x04, 0x93, 0x1d, 0x13, 0xfa, 0xfc, 0x6c, 0xaa, 0x02, 0x7f, 0x4a,
-	0xbb, 0xc7, 0x65, 0x60, 0x08, 0x23, 0x65, 0x2c, 0xce, 0x68, 0xef, 0x24, 0xfc, 0x29, 0xed, 0x3a,
-	0x6e, 0xe0, 0x47, 0x78, 0x29, 0x49, 0xf4, 0x9d, 0xc2, 0x37, 0xf4, 0xae, 0x75, 0xcf, 0x09, 0x25,
-	0x23, 0xf9, 0x27, 0x56, 0xff, 0x03, 0x00, 0x00, 0xff, 0xff, 0x64, 0xa0, 0x22, 0xf6, 0x22, 0x03,
-	0x00, 0x00,
+	// 1017 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0x5f, 0x6f, 0xdb, 0x36,
+	0x10, 0xb7, 0xfc, 0xdf, 0x67, 0x49, 0x76, 0xd9, 0xad, 0x10, 0x84, 0x62, 0x30, 0x88, 0xae, 0x30,
+	0x10, 0x80, 0x29, 0x52, 0xec, 0x69, 0x1b, 0x86, 0xd5, 0x6e, 0x91, 0x0c, 0xd9, 0xe0, 0xc9, 0x4e,
+	0xfb, 0xac, 0x58, 0x6c, 0x22, 0x4c, 0x16, 0x35, 0x89, 0x5e, 0x9b, 0x3e, 0xed, 0x69, 0x5f, 0x61,
+	0x5f, 0x69, 0x1f, 0x6b, 0x38, 0x92, 0x92, 0x25, 0x2f, 0x28, 0x82, 0xbd, 0xdd, 0xfd, 0xc8, 0x3b,
+	0xde, 0x1f, 0xdd, 0xfd, 0x04, 0xe3, 0xec, 0xfa, 0x34, 0xbb, 0x66, 0x59, 0x2e, 0xa4, 0xa0, 0x2b,
+	0xe8, 0xaf, 0xf9, 0x36, 0xe7, 0x92, 0x10, 0xe8, 0xfe, 0x12, 0xee, 0xb8, 0x67, 0xcd, 0xac, 0xf9,
+	0x28, 0x50, 0x32, 0xf9, 0x02, 0x7a, 0x6f, 0xc3, 0x64, 0xcf, 0xbd, 0xb6, 0x02, 0xb5, 0x42, 0x9e,
+	0xc2, 0x08, 0x4f, 0x8b, 0x2c, 0xdc, 0x72, 0xaf, 0xa3, 0x4e, 0x0e, 0x00, 0x75, 0x60, 0xac, 0x3d,
+	0x06, 0x3c, 0x4b, 0xee, 0xe8, 0xf7, 0x30, 0x58, 0xbe, 0xfa, 0x75, 0xcf, 0xf3, 0x3b, 0xf4, 0xb6,
+	0x09, 0xaf, 0x93, 0xf2, 0x09, 0xad, 0x34, 0xbd, 0xb5, 0x8f, 0xbd, 0x9d, 0x01, 0x28, 0x63, 0xe5,
+	0x8c, 0x3c, 0x03, 0x47, 0x19, 0x2d, 0x44, 0x2a, 0x79, 0x2a, 0x0b, 0xe3, 0xa9, 0x09, 0xd2, 0x53,
+	0x70, 0x96, 0x3c, 0x4b, 0xc4, 0x5d, 0xc0, 0x7f, 0xdf, 0xf3, 0x42, 0x92, 0xaf, 0x00, 0x34, 0xb0,
+	0xe3, 0xa9, 0x34, 0x36, 0x35, 0x04, 0x43, 0x2e, 0x0d, 0x30, 0x64, 0x0e, 0xce, 0x42, 0xe4, 0x91,
+	0x48, 0x4b, 0x7b, 0x0f, 0x06, 0x8b, 0x44, 0xec, 0xa3, 0x8b, 0xa5, 0x31, 0x2e, 0x55, 0x0c, 0xe8,
+	0x2a, 0x2d, 0xb6, 0xb7, 0x3c, 0xda, 0x27, 0x2a, 0x35, 0x4c, 0x60, 0x18, 0x34, 0x41, 0x4c, 0x7c,
+	0x99, 0x87, 0x71, 0xaa, 0x8a, 0x35, 0x0c, 0xb4, 0x82, 0xaf, 0x96, 0xcf, 0xe0, 0xab, 0x97, 0xe0,
+	0x9e, 0x87, 0x69, 0x24, 0xde, 0xbf, 0x2f, 0x9f, 0x6d, 0x54, 0xc6, 0x3a, 0xaa, 0x0c, 0x9e, 0xbe,
+	0x4a, 0xf6, 0x3c, 0xcb, 0xe3, 0x54, 0x96, 0x75, 0xab, 0x00, 0xea, 0x82, 0x5d, 0x79, 0x43, 0xef,
+	0x0c, 0xdc, 0xab, 0xec, 0x26, 0x0f, 0x23, 0xfe, 0x20, 0xef, 0x68, 0x5f, 0xdd, 0x47, 0xfb, 0x53,
+	0x98, 0xac, 0xd3, 0x30, 0x2b, 0x6e, 0x85, 0x7c, 0x98, 0x83, 0x13, 0x70, 0x0e, 0x06, 0xd8, 0x3b,
+	0x1f, 0x86, 0x25, 0x60, 0x6e, 0x57, 0x3a, 0xfd, 0x09, 0xdc, 0x80, 0x17, 0x52, 0xe4, 0x0f, 0x8b,
+	0xae, 0xe1, 0xab, 0x7d, 0xe4, 0xcb, 0x05, 0xbb, 0xf2, 0x85, 0x91, 0x4f, 0xc1, 0x7d, 0xcb, 0xf3,
+	0x22, 0xae, 0xda, 0x49, 0xe7, 0x60, 0x57, 0x08, 0x46, 0xe6, 0xc1, 0xc0, 0xe8, 0x65, 0x7b, 0x8d,
+	0x4a, 0x1f, 0xc1, 0x64, 0x21, 0xf6, 0xa9, 0xe4, 0x79, 0x51, 0x1a, 0x9f, 0xc0, 0x97, 0x3f, 0xc7,
+	0x69, 0x2c, 0xd2, 0xa3, 0x03, 0x9c, 0x9f, 0x73, 0x51, 0x94, 0xb9, 0x29, 0x99, 0x7e, 0x03, 0xce,
+	0xe1, 0x9a, 0xfe, 0x80, 0x87, 0x5b, 0x03, 0x78, 0xd6, 0xac, 0x33, 0x1f, 0x9f, 0x0d, 0x99, 0xb9,
+	0x11, 0x54, 0x27, 0x74, 0x0b, 0x03, 0x03, 0x92, 0x29, 0x74, 0x56, 0xbf, 0xdd, 0x18, 0xa7, 0x28,
+	0x56, 0x73, 0xda, 0xbe, 0x6f, 0x4e, 0xf1, 0x03, 0xeb, 0xd6, 0xe6, 0x74, 0x95, 0xf3, 0x3f, 0xf4,
+	0x49, 0x57, 0x9d, 0x1c, 0x00, 0xfa, 0xb7, 0x05, 0xe3, 0x4b, 0x71, 0xf3, 0xb9, 0xf8, 0xd1, 0x03,
+	0x4e, 0x55, 0x18, 0xa7, 0x3c, 0x2f, 0xbf, 0xb1, 0x0a, 0x20, 0x4f, 0xa0, 0xff, 0x46, 0x24, 0x89,
+	0xf8, 0x60, 0xbe, 0x6b, 0xa3, 0x61, 0x34, 0xeb, 0x38, 0xdd, 0xea, 0x37, 0x3b, 0x81, 0x56, 0x10,
+	0xbd, 0x4a, 0x65, 0x9c, 0x78, 0x3d, 0x8d, 0x2a, 0x05, 0x5f, 0xdd, 0x84, 0x71, 0xe2, 0xf5, 0x15,
+	0xa8, 0x64, 0x7a, 0x05, 0x23, 0x1d, 0x18, 0x56, 0xec, 0x29, 0x8c, 0x36, 0xf1, 0x8e, 0x17, 0x32,
+	0xdc, 0x65, 0x2a, 0xb6, 0x4e, 0x70, 0x00, 0x30, 0x84, 0xb5, 0x8c, 0x78, 0x9e, 0x9b, 0xc1, 0x33,
+	0x1a, 0xba, 0xbd, 0x8c, 0xd3, 0x72, 0x3b, 0x29, 0x99, 0xfe, 0x63, 0xc1, 0xf8, 0xf5, 0x47, 0xbe,
+	0xfd, 0xff, 0x09, 0xe3, 0x1e, 0x10, 0xbb, 0x5d, 0x98, 0x46, 0x5e, 0x67, 0xd6, 0x51, 0x7b, 0x40,
+	0xab, 0xd8, 0xa6, 0x8d, 0xbc, 0x53, 0x09, 0x0f, 0x03, 0x14, 0x55, 0x11, 0x64, 0x14, 0xa7, 0x2a,
+	0x5d, 0x3b, 0xd0, 0x0a, 0x6e, 0xa2, 0x45, 0x22, 0x0a, 0xae, 0x8f, 0xfa, 0xea, 0x7a, 0x0d, 0x21,
+	0x5f, 0x43, 0x3f, 0xe0, 0x45, 0xfc, 0x89, 0x7b, 0x83, 0x99, 0x35, 0x1f, 0x9f, 0x39, 0x6c, 0xc3,
+	0xf3, 0x5d, 0x9c, 0x86, 0xc9, 0x3a, 0xfe, 0xc4, 0x03, 0x73, 0x48, 0xbf, 0x03, 0xbb, 0x8e, 0x63,
+	0x19, 0xce, 0x79, 0x7c, 0x73, 0xab, 0x93, 0xe9, 0x05, 0x46, 0xc3, 0x20, 0xde, 0xc5, 0x91, 0xbc,
+	0x55, 0xa9, 0xf4, 0x02, 0xad, 0x50, 0x01, 0x23, 0x5d, 0x07, 0xac, 0xaf, 0xae, 0xa0, 0xd8, 0x6b,
+	0x53, 0x3b, 0x30, 0xda, 0x51, 0x65, 0xed, 0xaa, 0xb2, 0x4f, 0xa0, 0xff, 0xfa, 0x63, 0x2c, 0x79,
+	0x54, 0x36, 0x5d, 0x6b, 0x38, 0x92, 0x28, 0x2d, 0x44, 0xa4, 0xfb, 0xde, 0x0b, 0x2a, 0x9d, 0xfe,
+	0x69, 0x01, 0x59, 0x89, 0x5c, 0xbe, 0x11, 0xf9, 0x87, 0x30, 0x8f, 0x3e, 0xd7, 0x00, 0x17, 0xda,
+	0x17, 0x2b, 0x53, 0xf9, 0xf6, 0xc5, 0x0a, 0xef, 0xa0, 0xa5, 0x7a, 0xac, 0x17, 0x28, 0x19, 0xb1,
+	0x65, 0x28, 0x43, 0xf5, 0x8c, 0x1d, 0x28, 0xb9, 0x2a, 0xec, 0xbb, 0x3c, 0x96, 0xdc, 0xeb, 0xd5,
+	0x0a, 0xab, 0x10, 0xfa, 0x1c, 0xa6, 0x8d, 0x08, 0x30, 0xf5, 0xd2, 0x8f, 0x75, 0xf0, 0x73, 0xf6,
+	0x57, 0x0f, 0x3a, 0x3f, 0xae, 0x2e, 0xc8, 0x0c, 0x7a, 0x9a, 0xb4, 0x86, 0xcc, 0xd0, 0x97, 0x3f,
+	0x66, 0x07, 0x26, 0xa2, 0x2d, 0x72, 0x52, 0x6d, 0x0d, 0x32, 0x61, 0xcd, 0x0d, 0xe3, 0x3b, 0xac,
+	0xbe, 0x60, 0x68, 0x8b, 0xbc, 0x04, 0x47, 0x19, 0x97, 0xdb, 0x80, 0x4c, 0xd9, 0xd1, 0xfe, 0xf0,
+	0x5d, 0xd6, 0x58, 0x15, 0xb4, 0x45, 0x9e, 0xc1, 0x68, 0xcd, 0xa5, 0xa1, 0xe7, 0x01, 0xd3, 0x82,
+	0x6f, 0xb3, 0x3a, 0xbd, 0xe2, 0xad, 0x2e, 0x4e, 0x0b, 0xb1, 0x59, 0x6d, 0x9a, 0x7d, 0x60, 0xd5,
+	0x08, 0xd1, 0xd6, 0x0b, 0x8b, 0x3c, 0x87, 0x2e, 0xf6, 0x9c, 0xd8, 0xac, 0x36, 0x02, 0x3e, 0xb0,
+	0xea, 0x43, 0xa0, 0xad, 0xb9, 0xf5, 0xc2, 0x22, 0xdf, 0xc2, 0xb8, 0x56, 0x27, 0xf2, 0x98, 0xfd,
+	0xb7, 0x6f, 0xfe, 0x23, 0x76, 0x5c, 0x4a, 0x63, 0x3c, 0x87, 0xbe, 0xe6, 0x51, 0xe2, 0xb2, 0x06,
+	0x03, 0xfb, 0x36, 0xab, 0x13, 0x6c, 0x8b, 0xfc, 0x00, 0x8f, 0x55, 0x3d, 0x9a, 0xab, 0x94, 0x3c,
+	0x61, 0xf7, 0xee, 0xd6, 0x7b, 0x6a, 0x33, 0x87, 0xbe, 0x26, 0x4f, 0xe2, 0x32, 0x2d, 0x1c, 0x9e,
+	0xaa, 0xb3, 0xaa, 0xea, 0x93, 0x61, 0x42, 0x32, 0x61, 0x4d, 0x86, 0xf5, 0x1d, 0xd6, 0x20, 0x49,
+	0x75, 0xd9, 0xd0, 0x1e, 0x99, 0xb0, 0x26, 0x61, 0xfa, 0x0e, 0x6b, 0x30, 0x62, 0x8b, 0xb0, 0x03,
+	0x0b, 0x91, 0x29, 0x3b, 0xa2, 0x47, 0xdf, 0x65, 0x0d, 0xfe, 0xd3, 0xce, 0x0d, 0x33, 0x91, 0x09,
+	0x6b, 0xf2, 0x9d, 0xef, 0xb0, 0x06, 0x69, 0xb5, 0xae, 0xfb, 0xea, 0xff, 0xec, 0xe5, 0xbf, 0x03,
+	0x00, 0xf9, 0x3f, 0x72, 0x26, 0xae, 0x09, 0x00, 0x00,
 }
@@ -4,20 +4,31 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/kelda/kelda/api"
 	"github.com/kelda/kelda/api/client"
 	"github.com/kelda/kelda/api/pb"
 	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/cloud"
+	"github.com/kelda/kelda/cloud/acl"
 	"github.com/kelda/kelda/connection"
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/etcd"
+	"github.com/kelda/kelda/minion/ipdef"
 	"github.com/kelda/kelda/minion/vault"
+	"github.com/kelda/kelda/util"
+	"github.com/kelda/kelda/util/str"
 	"github.com/kelda/kelda/version"
 
 	"github.com/docker/distribution/reference"
@@ -60,12 +71,15 @@ func Run(conn db.Conn, listenAddr string, runningOnDaemon bool,
 	go func(c chan os.Signal) {
 		sig := <-c
 		var activeMachinesCount = len(conn.SelectFromMachine(nil))
-		var namespace, _ = conn.GetBlueprintNamespace()
+		var stopCommands []string
+		for _, namespace := range conn.GetBlueprintNamespaces() {
+			stopCommands = append(stopCommands, "`kelda stop "+namespace+"`")
+		}
 		if activeMachinesCount > 0 {
 			log.Warnf("\n%d machines will continue running after the Kelda"+
 				" daemon shuts down. If you'd like to stop them, restart"+
-				" the daemon and run `kelda stop %s`.\n",
-				activeMachinesCount, namespace)
+				" the daemon and run %s.\n",
+				activeMachinesCount, strings.Join(stopCommands, " and "))
 		}
 		log.Printf("Caught signal %s: shutting down.\n", sig)
 		sock.Close()
@@ -85,7 +99,11 @@ func (s server) SetSecret(ctx context.Context, msg *pb.Secret) (*pb.SecretReply,
 	// will get immediate feedback on whether or not the secret was successfully
 	// set.
 	if s.runningOnDaemon {
-		machines := s.conn.SelectFromMachine(nil)
+		machines, err := s.namespaceMachines(msg.Namespace)
+		if err != nil {
+			return &pb.SecretReply{}, err
+		}
+
 		leaderClient, err := newLeaderClient(machines, s.clientCreds)
 		if err != nil {
 			return &pb.SecretReply{}, err
@@ -107,14 +125,15 @@ func (s server) SetSecret(ctx context.Context, msg *pb.Secret) (*pb.SecretReply,
 // returns the requested table from its local database. If in daemon mode,
 // Query proxies certain table requests (e.g. Container and Connection) to the
 // cluster. This is necessary because some tables are only used on the minions,
-// and aren't synced back to the daemon.
+// and aren't synced back to the daemon.  The daemon may manage several
+// deployments, so its queries are limited to the namespace in the query.
 func (s server) Query(cts context.Context, query *pb.DBQuery) (*pb.QueryReply, error) {
 	var rows interface{}
 	var err error
 
 	table := db.TableType(query.Table)
 	if s.runningOnDaemon {
-		rows, err = s.queryFromDaemon(table)
+		rows, err = s.queryFromDaemon(table, query.Namespace)
 	} else {
 		rows, err = s.queryLocal(table)
 	}
@@ -152,16 +171,27 @@ func (s server) queryLocal(table db.TableType) (interface{}, error) {
 	}
 }
 
-func (s server) queryFromDaemon(table db.TableType) (
+func (s server) queryFromDaemon(table db.TableType, namespace string) (
 	interface{}, error) {
 
 	switch table {
-	case db.MachineTable, db.BlueprintTable:
-		return s.queryLocal(table)
+	case db.MachineTable:
+		return s.conn.SelectFromMachine(func(m db.Machine) bool {
+			return namespace == "" || m.Namespace == namespace
+		}), nil
+	case db.BlueprintTable:
+		return s.conn.SelectFromBlueprint(func(bp db.Blueprint) bool {
+			return namespace == "" || bp.Namespace == namespace
+		}), nil
+	}
+
+	machines, err := s.namespaceMachines(namespace)
+	if err != nil {
+		return nil, err
 	}
 
 	var leaderClient client.Client
-	leaderClient, err := newLeaderClient(s.conn.SelectFromMachine(nil), s.clientCreds)
+	leaderClient, err = newLeaderClient(machines, s.clientCreds)
 	if err != nil {
 		return nil, err
 	}
@@ -169,7 +199,7 @@ func (s server) queryFromDaemon(table db.TableType) (
 
 	switch table {
 	case db.ContainerTable:
-		return s.getClusterContainers(leaderClient)
+		return s.getClusterContainers(leaderClient, machines)
 	case db.ConnectionTable:
 		return leaderClient.QueryConnections()
 	case db.LoadBalancerTable:
@@ -181,6 +211,57 @@ func (s server) queryFromDaemon(table db.TableType) (
 	}
 }
 
+// namespaceMachines returns the machines in the deployment in `namespace`.  If
+// no namespace is given, the daemon must manage at most one deployment, whose
+// machines are returned.
+func (s server) namespaceMachines(namespace string) ([]db.Machine, error) {
+	namespace, err := s.resolveNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.conn.SelectFromMachine(func(m db.Machine) bool {
+		return m.Namespace == namespace
+	}), nil
+}
+
+// resolveNamespace returns `namespace` if the daemon manages a deployment in it.
+// If no namespace is given, the daemon must manage at most one deployment, whose
+// namespace is returned.
+func (s server) resolveNamespace(namespace string) (string, error) {
+	namespaces := s.conn.GetBlueprintNamespaces()
+	switch {
+	case namespace != "" && !str.SliceContains(namespaces, namespace):
+		return "", fmt.Errorf("no deployment in namespace %q", namespace)
+	case namespace == "" && len(namespaces) > 1:
+		return "", fmt.Errorf("the daemon manages multiple namespaces (%s); "+
+			"select one with -namespace", strings.Join(namespaces, ", "))
+	case namespace == "" && len(namespaces) == 1:
+		namespace = namespaces[0]
+	}
+	return namespace, nil
+}
+
+// controlPlaneLeader checks that the minion's cluster manages its own machines,
+// in which case the RPCs that are otherwise only defined on the daemon are
+// handled by the cluster's leader.  If this minion isn't the leader, it returns
+// a client connected to the leader, to which the RPC should be forwarded.
+func (s server) controlPlaneLeader() (client.Client, error) {
+	if len(s.conn.SelectFromBlueprint(nil)) == 0 {
+		return nil, errDaemonOnlyRPC
+	}
+
+	if s.conn.EtcdLeader() {
+		return nil, nil
+	}
+
+	etcds := s.conn.SelectFromEtcd(nil)
+	if len(etcds) == 0 || etcds[0].LeaderIP == "" {
+		return nil, errors.New("no leader found")
+	}
+	return newClient(api.RemoteAddress(etcds[0].LeaderIP), s.clientCreds)
+}
+
 func (s server) QueryMinionCounters(ctx context.Context, in *pb.MinionCountersRequest) (
 	*pb.CountersReply, error) {
 	if !s.runningOnDaemon {
@@ -209,11 +290,293 @@ func (s server) QueryCounters(ctx context.Context, in *pb.CountersRequest) (
 	return &pb.CountersReply{Counters: counter.Dump()}, nil
 }
 
+// Logs streams the logs of a container. On the daemon, the request is proxied
+// to the minion at `req.Host`, which reads the logs directly from Docker.
+func (s server) Logs(req *pb.LogsRequest, stream pb.API_LogsServer) error {
+	send := func(reply pb.LogsReply) error {
+		return stream.Send(&reply)
+	}
+
+	if s.runningOnDaemon {
+		if req.Host == "" {
+			return errors.New("no host specified")
+		}
+
+		clnt, err := newClient(api.RemoteAddress(req.Host), s.clientCreds)
+		if err != nil {
+			return err
+		}
+		defer clnt.Close()
+
+		minionReq := *req
+		minionReq.Host = ""
+		return clnt.Logs(stream.Context(), minionReq, send)
+	}
+
+	opts := docker.LogsOptions{
+		Context: stream.Context(),
+		Follow:  req.Follow,
+		Tail:    int(req.Tail),
+	}
+	if req.Since != 0 {
+		opts.Since = time.Unix(0, req.Since)
+	}
+	if req.Until != 0 {
+		opts.Until = time.Unix(0, req.Until)
+	}
+
+	return newDockerClient().Logs(req.Container, opts, func(l docker.LogLine) error {
+		return send(pb.LogsReply{
+			Timestamp: l.Time.UnixNano(),
+			Stderr:    l.Stderr,
+			Line:      l.Text,
+		})
+	})
+}
+
+// Exec runs a command in a container, and streams its input and output. On the
+// daemon, the stream is proxied to the minion at the first request's Host, which
+// runs the command through Docker.
+func (s server) Exec(stream pb.API_ExecServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	// gRPC streams don't support concurrent calls to Send.
+	var sendLock sync.Mutex
+	send := func(reply pb.ExecReply) error {
+		sendLock.Lock()
+		defer sendLock.Unlock()
+		return stream.Send(&reply)
+	}
+	stdout := execWriter{send: send}
+	stderr := execWriter{send: send, stderr: true}
+
+	ctx := stream.Context()
+	stdinReader, stdinWriter := io.Pipe()
+	defer stdinReader.Close()
+
+	var exitCode int
+	if s.runningOnDaemon {
+		if req.Host == "" {
+			return errors.New("no host specified")
+		}
+
+		clnt, err := newClient(api.RemoteAddress(req.Host), s.clientCreds)
+		if err != nil {
+			return err
+		}
+		defer clnt.Close()
+
+		resize := make(chan pb.TerminalSize)
+		go forwardExecInput(req, stream, stdinWriter, func(size pb.TerminalSize) {
+			select {
+			case resize <- size:
+			case <-ctx.Done():
+			}
+		})
+
+		minionReq := *req
+		minionReq.Host = ""
+		exitCode, err = clnt.Exec(ctx, minionReq, stdinReader, stdout, stderr,
+			resize)
+		if err != nil {
+			return err
+		}
+	} else {
+		resize := make(chan docker.TerminalSize)
+		go forwardExecInput(req, stream, stdinWriter, func(size pb.TerminalSize) {
+			dkSize := docker.TerminalSize{
+				Height: int(size.Height),
+				Width:  int(size.Width),
+			}
+			select {
+			case resize <- dkSize:
+			case <-ctx.Done():
+			}
+		})
+
+		exitCode, err = newDockerClient().Exec(req.Container, req.Command,
+			docker.ExecOptions{
+				Context: ctx,
+				Tty:     req.Tty,
+				Stdin:   stdinReader,
+				Stdout:  stdout,
+				Stderr:  stderr,
+				Resize:  resize,
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	return send(pb.ExecReply{Exited: true, ExitCode: int32(exitCode)})
+}
+
+// forwardExecInput writes the input received on `stream`, starting with
+// `first`, to `stdin`, and calls `resize` whenever the client's terminal is
+// resized.  It returns once the stream ends.
+func forwardExecInput(first *pb.ExecRequest, stream pb.API_ExecServer,
+	stdin *io.PipeWriter, resize func(pb.TerminalSize)) {
+
+	defer stdin.Close()
+
+	stdinClosed := false
+	for req := first; ; {
+		if len(req.Stdin) > 0 && !stdinClosed {
+			// Writes fail once the command exits and stops reading its input.
+			if _, err := stdin.Write(req.Stdin); err != nil {
+				stdinClosed = true
+			}
+		}
+
+		if req.CloseStdin && !stdinClosed {
+			stdin.Close()
+			stdinClosed = true
+		}
+
+		if req.Resize != nil {
+			resize(*req.Resize)
+		}
+
+		var err error
+		if req, err = stream.Recv(); err != nil {
+			return
+		}
+	}
+}
+
+// execWriter sends everything written to it to the client running a command.
+type execWriter struct {
+	send   func(pb.ExecReply) error
+	stderr bool
+}
+
+func (w execWriter) Write(p []byte) (int, error) {
+	// `p` may be reused once Write returns, so it must be copied.
+	data := append([]byte{}, p...)
+
+	reply := pb.ExecReply{Stdout: data}
+	if w.stderr {
+		reply = pb.ExecReply{Stderr: data}
+	}
+
+	if err := w.send(reply); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// PortForward tunnels a TCP connection to a container. On the daemon, the
+// stream is proxied to the minion at the first request's Host, which connects to
+// the container.  Minions only connect to containers that they're running.
+func (s server) PortForward(stream pb.API_PortForwardServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	conn := &portForwardConn{
+		stream:      stream,
+		pending:     req.Data,
+		writeClosed: req.CloseWrite,
+	}
+
+	if s.runningOnDaemon {
+		if req.Host == "" {
+			return errors.New("no host specified")
+		}
+
+		clnt, err := newClient(api.RemoteAddress(req.Host), s.clientCreds)
+		if err != nil {
+			return err
+		}
+		defer clnt.Close()
+
+		minionReq := pb.PortForwardRequest{IP: req.IP, Port: req.Port}
+		return clnt.PortForward(stream.Context(), minionReq, conn)
+	}
+
+	self := s.conn.MinionSelf()
+	containers := s.conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.IP == req.IP && dbc.Minion == self.PrivateIP
+	})
+	if len(containers) == 0 {
+		return fmt.Errorf("no container with IP %s on this machine", req.IP)
+	}
+
+	addr := net.JoinHostPort(req.IP, strconv.Itoa(int(req.Port)))
+	containerConn, err := net.DialTimeout("tcp", addr, portForwardDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer containerConn.Close()
+
+	go func() {
+		io.Copy(containerConn, conn)
+		containerConn.(*net.TCPConn).CloseWrite()
+	}()
+
+	_, err = io.Copy(conn, containerConn)
+	return err
+}
+
+const portForwardDialTimeout = 10 * time.Second
+
+// portForwardConn reads the data sent by the client of a PortForward stream,
+// and sends everything written to it back to the client.  Reads return io.EOF
+// once the client is done writing.
+type portForwardConn struct {
+	stream      pb.API_PortForwardServer
+	pending     []byte
+	writeClosed bool
+}
+
+func (c *portForwardConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.writeClosed {
+			return 0, io.EOF
+		}
+
+		req, err := c.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		c.pending, c.writeClosed = req.Data, req.CloseWrite
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *portForwardConn) Write(p []byte) (int, error) {
+	// `p` may be reused once Write returns, so it must be copied.
+	data := append([]byte{}, p...)
+	if err := c.stream.Send(&pb.PortForwardReply{Data: data}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Deploy runs on the daemon, and on the leader of clusters that manage their own
+// machines.  Other minions in such clusters forward the deployment to their
+// leader.
 func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 	*pb.DeployReply, error) {
 
 	if !s.runningOnDaemon {
-		return nil, errDaemonOnlyRPC
+		leaderClient, err := s.controlPlaneLeader()
+		if err != nil {
+			return nil, err
+		}
+
+		if leaderClient != nil {
+			defer leaderClient.Close()
+			err := leaderClient.Deploy(deployReq.Deployment)
+			return &pb.DeployReply{}, err
+		}
 	}
 
 	newBlueprint, err := blueprint.FromJSON(deployReq.Deployment)
@@ -221,46 +584,88 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 		return &pb.DeployReply{}, err
 	}
 
+	// A cluster that manages its own machines only runs the deployment that
+	// was handed off to it.
+	if !s.runningOnDaemon {
+		namespaces := s.conn.GetBlueprintNamespaces()
+		if !str.SliceContains(namespaces, newBlueprint.Namespace) {
+			return &pb.DeployReply{}, fmt.Errorf("this cluster only "+
+				"manages namespace %q", strings.Join(namespaces, ", "))
+		}
+	}
+
 	for _, c := range newBlueprint.Containers {
 		if _, err := reference.ParseAnyReference(c.Image.Name); err != nil {
 			return &pb.DeployReply{}, fmt.Errorf("could not parse "+
 				"container image %s: %s", c.Image.Name, err.Error())
 		}
-	}
 
-	// Ensure that the region is valid
-	if len(newBlueprint.Machines) > 0 {
-		// Since the Javascript code ensures that all machines have the same
-		// region and provider, we only need to check the region of the first
-		// machine is valid for its provider.
-		first := newBlueprint.Machines[0]
-		regionValid := false
-		for _, r := range cloud.ValidRegions(db.ProviderName(first.Provider)) {
-			if r == first.Region {
-				regionValid = true
+		dbc := db.Container{Env: c.Env, FilepathToContent: c.FilepathToContent}
+		for _, name := range dbc.GetReferencedSecrets() {
+			if blueprint.IsReservedSecret(name) {
+				return &pb.DeployReply{}, fmt.Errorf("container %s "+
+					"references reserved secret %q", c.Hostname, name)
 			}
 		}
-		if !regionValid {
+	}
+
+	for _, conn := range newBlueprint.Connections {
+		switch conn.Protocol {
+		case "", blueprint.TCP, blueprint.UDP, blueprint.ICMP:
+		default:
+			return &pb.DeployReply{}, fmt.Errorf("unsupported connection "+
+				"protocol %q (supported protocols: %s, %s, %s)",
+				conn.Protocol, blueprint.TCP, blueprint.UDP,
+				blueprint.ICMP)
+		}
+
+		if conn.Protocol != blueprint.ICMP {
+			continue
+		}
+
+		if conn.MinPort != 0 || conn.MaxPort != 0 {
+			return &pb.DeployReply{}, errors.New(
+				"icmp connections cannot have ports")
+		}
+
+		// Containers' traffic to the public internet is masqueraded by
+		// port, so there's no way to let out ICMP.
+		if str.SliceContains(conn.To, blueprint.PublicInternetLabel) {
+			return &pb.DeployReply{}, errors.New("icmp connections to " +
+				"the public internet are not supported")
+		}
+	}
+
+	if err := s.checkContainerNetwork(newBlueprint); err != nil {
+		return &pb.DeployReply{}, err
+	}
+
+	// Ensure that the regions are valid.
+	for _, m := range newBlueprint.Machines {
+		regions := cloud.ValidRegions(db.ProviderName(m.Provider))
+		if !str.SliceContains(regions, m.Region) {
 			return &pb.DeployReply{}, fmt.Errorf("region: %s is "+
-				"not supported for provider: %s", first.Region,
-				first.Provider)
+				"not supported for provider: %s (supported regions: %s)",
+				m.Region, m.Provider, strings.Join(regions, ", "))
+		}
+
+		// The infrastructure's labels apply to every machine.
+		provider := db.ProviderName(m.Provider)
+		for _, labels := range []map[string]string{newBlueprint.Labels,
+			m.Labels} {
+			if err := cloud.ValidateLabels(provider, labels); err != nil {
+				return &pb.DeployReply{}, fmt.Errorf("invalid labels "+
+					"for provider %s: %s", m.Provider, err)
+			}
 		}
 	}
 
-	s.conn.Txn(db.BlueprintTable, db.MachineTable).Run(func(view db.Database) error {
-		bp, err := view.GetBlueprint()
+	// Each namespace is a separate deployment, so deploying to a new namespace
+	// leaves the deployments in the other namespaces running.
+	s.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp, err := view.GetBlueprint(newBlueprint.Namespace)
 		if err != nil {
 			bp = view.InsertBlueprint()
-		} else {
-			// If the namespace changed, remove all the machines from the
-			// database since those applied to the old namespace, and leaving
-			// them in the database will cause incorrect behavior such as
-			// sending the new blueprint to the old machines.
-			if bp.Namespace != newBlueprint.Namespace {
-				for _, dbm := range view.SelectFromMachine(nil) {
-					view.Remove(dbm)
-				}
-			}
 		}
 
 		bp.Blueprint = newBlueprint
@@ -281,19 +686,287 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 	return &pb.DeployReply{}, nil
 }
 
+// checkContainerNetwork checks that the container network in `bp` is valid.
+// Minions set up the network when they boot, so it may only change once the
+// namespace's machines are gone, or are being stopped.
+func (s server) checkContainerNetwork(bp blueprint.Blueprint) error {
+	network, err := ipdef.ParseContainerNetwork(bp.ContainerSubnet,
+		bp.GatewayIP, bp.LoadBalancerIP, bp.ContainerSubnetIPv6)
+	if err != nil {
+		return err
+	}
+
+	machines := s.conn.SelectFromMachine(func(m db.Machine) bool {
+		return m.Namespace == bp.Namespace
+	})
+	if len(machines) == 0 || len(bp.Machines) == 0 {
+		return nil
+	}
+
+	var currBlueprint db.Blueprint
+	s.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		currBlueprint, err = view.GetBlueprint(bp.Namespace)
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+
+	// The current blueprint was validated when it was deployed.
+	currNetwork, _ := ipdef.ParseContainerNetwork(currBlueprint.ContainerSubnet,
+		currBlueprint.GatewayIP, currBlueprint.LoadBalancerIP,
+		currBlueprint.ContainerSubnetIPv6)
+	curr, next := containerNetworkString(currNetwork),
+		containerNetworkString(network)
+	if curr != next {
+		return fmt.Errorf("the container network can't change while the "+
+			"namespace's machines are running (it's %s, and the blueprint "+
+			"sets %s)", curr, next)
+	}
+	return nil
+}
+
+func containerNetworkString(network ipdef.ContainerNetwork) string {
+	str := fmt.Sprintf("subnet %s with gateway %s and load balancer %s",
+		network.Subnet.String(), network.GatewayIP, network.LoadBalancerIP)
+	if network.Subnet6.IP != nil {
+		str += fmt.Sprintf(" plus IPv6 subnet %s", network.Subnet6.String())
+	}
+	return str
+}
+
+// Cordon sets whether new containers may be scheduled on a worker, and whether
+// its containers should be moved to other workers.  The foreman passes the
+// settings on to the worker's minion.  Like Deploy, it runs on the daemon, and on
+// the leader of clusters that manage their own machines.
+func (s server) Cordon(cts context.Context, req *pb.CordonRequest) (
+	*pb.CordonReply, error) {
+
+	if !s.runningOnDaemon {
+		leaderClient, err := s.controlPlaneLeader()
+		if err != nil {
+			return nil, err
+		}
+
+		if leaderClient != nil {
+			defer leaderClient.Close()
+			if err := leaderClient.Cordon(*req); err != nil {
+				return nil, err
+			}
+			return &pb.CordonReply{}, nil
+		}
+	}
+
+	err := s.conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		machines := view.SelectFromMachine(func(m db.Machine) bool {
+			return m.CloudID == req.CloudID
+		})
+		if len(machines) != 1 {
+			return fmt.Errorf("no machine %q", req.CloudID)
+		}
+
+		m := machines[0]
+		if m.Role == db.Master {
+			return fmt.Errorf("machine %q is a master, and masters "+
+				"don't run containers", req.CloudID)
+		}
+
+		m.Unschedulable = req.Unschedulable || req.Drain
+		m.Draining = req.Drain
+		view.Commit(m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CordonReply{}, nil
+}
+
+// Handoff hands the deployment in a namespace off to its cluster, which manages
+// its own machines from then on.  The daemon sends the blueprint to the
+// cluster's leader, and then forgets about the namespace.  The leader stores the
+// blueprint, which starts its control plane.
+func (s server) Handoff(cts context.Context, req *pb.HandoffRequest) (
+	*pb.HandoffReply, error) {
+
+	if !s.runningOnDaemon {
+		if !s.conn.EtcdLeader() {
+			return nil, errors.New("only the leader may take over the " +
+				"deployment")
+		}
+
+		bp, err := blueprint.FromJSON(req.Blueprint)
+		if err != nil {
+			return nil, err
+		}
+
+		s.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+			for _, dbBlueprint := range view.SelectFromBlueprint(nil) {
+				view.Remove(dbBlueprint)
+			}
+
+			dbBlueprint := view.InsertBlueprint()
+			dbBlueprint.Blueprint = bp
+			view.Commit(dbBlueprint)
+			return nil
+		})
+		return &pb.HandoffReply{}, nil
+	}
+
+	namespace, err := s.resolveNamespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var bp db.Blueprint
+	s.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp, err = view.GetBlueprint(namespace)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The leader resolves the "local" admin ACL to its own IP, so the daemon's
+	// IP must be added for its host to keep access to the cluster.
+	ip, err := myIP()
+	if err != nil {
+		return nil, fmt.Errorf("get local IP: %s", err)
+	}
+	bp.AdminACL = append(bp.AdminACL, acl.HostCIDR(ip))
+
+	machines, err := s.namespaceMachines(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderClient, err := newLeaderClient(machines, s.clientCreds)
+	if err != nil {
+		return nil, err
+	}
+	defer leaderClient.Close()
+
+	if err := leaderClient.Handoff(pb.HandoffRequest{
+		Namespace: namespace, Blueprint: bp.Blueprint.String()}); err != nil {
+		return nil, err
+	}
+
+	// The daemon stops managing the namespace's machines once they're removed
+	// from its database.
+	s.conn.Txn(db.BlueprintTable, db.MachineTable).Run(
+		func(view db.Database) error {
+			view.Remove(bp)
+			for _, m := range view.SelectFromMachine(nil) {
+				if m.Namespace == namespace {
+					view.Remove(m)
+				}
+			}
+			return nil
+		})
+	return &pb.HandoffReply{}, nil
+}
+
+// Upgrade marks the machines in a namespace whose minions run a different
+// version than the daemon, so that they're upgraded one at a time by
+// cloud.SyncUpgrades.
+func (s server) Upgrade(cts context.Context, req *pb.UpgradeRequest) (
+	*pb.UpgradeReply, error) {
+
+	if !s.runningOnDaemon {
+		return nil, errDaemonOnlyRPC
+	}
+
+	namespace, err := s.resolveNamespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	s.conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for _, m := range view.SelectFromMachine(func(m db.Machine) bool {
+			return m.Namespace == namespace && m.Version != "" &&
+				m.Version != version.Version
+		}) {
+			m.Upgrading = true
+			view.Commit(m)
+		}
+		return nil
+	})
+	return &pb.UpgradeReply{}, nil
+}
+
+// Snapshot returns the latest snapshot of the cluster state.  The daemon gets it
+// from the leader of the namespace's cluster, which reads the copy that it keeps
+// on its disk.
+func (s server) Snapshot(cts context.Context, req *pb.SnapshotRequest) (
+	*pb.SnapshotReply, error) {
+
+	if !s.runningOnDaemon {
+		snapshot, err := etcd.ReadSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		return &pb.SnapshotReply{Snapshot: snapshot}, nil
+	}
+
+	machines, err := s.namespaceMachines(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderClient, err := newLeaderClient(machines, s.clientCreds)
+	if err != nil {
+		return nil, err
+	}
+	defer leaderClient.Close()
+
+	snapshot, err := leaderClient.Snapshot(pb.SnapshotRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SnapshotReply{Snapshot: snapshot}, nil
+}
+
+// Restore restores the IP allocations and container placements in a snapshot
+// into the leader's database, which then writes them to etcd.  The daemon
+// forwards the snapshot to the leader of the namespace's cluster.
+func (s server) Restore(cts context.Context, req *pb.RestoreRequest) (
+	*pb.RestoreReply, error) {
+
+	if !s.runningOnDaemon {
+		if !s.conn.EtcdLeader() {
+			return nil, errors.New("only the leader may restore a snapshot")
+		}
+		return &pb.RestoreReply{}, etcd.RestoreSnapshot(s.conn, req.Snapshot)
+	}
+
+	machines, err := s.namespaceMachines(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderClient, err := newLeaderClient(machines, s.clientCreds)
+	if err != nil {
+		return nil, err
+	}
+	defer leaderClient.Close()
+
+	return &pb.RestoreReply{}, leaderClient.Restore(pb.RestoreRequest{
+		Snapshot: req.Snapshot})
+}
+
 func (s server) Version(_ context.Context, _ *pb.VersionRequest) (
 	*pb.VersionReply, error) {
 	return &pb.VersionReply{Version: version.Version}, nil
 }
 
-func (s server) getClusterContainers(leaderClient client.Client) (interface{}, error) {
+func (s server) getClusterContainers(leaderClient client.Client,
+	machines []db.Machine) (interface{}, error) {
 	leaderContainers, err := leaderClient.QueryContainers()
 	if err != nil {
 		return nil, err
 	}
 
-	workerContainers, err := queryWorkers(s.conn.SelectFromMachine(nil),
-		s.clientCreds)
+	workerContainers, err := queryWorkers(machines, s.clientCreds)
 	if err != nil {
 		return nil, err
 	}
@@ -368,8 +1041,12 @@ func updateLeaderContainerAttrs(lContainers []db.Container, wContainers []db.Con
 	return allContainers
 }
 
-// client.New, client.Leader, and vault.New are saved in variables to
-// facilitate injecting test clients for unit testing.
+// client.New, client.Leader, vault.New, and the Docker client are saved in
+// variables to facilitate injecting test clients for unit testing.
 var newClient = client.New
 var newLeaderClient = client.Leader
 var newVaultClient = vault.New
+var myIP = util.MyIP
+var newDockerClient = func() docker.Client {
+	return docker.New("unix:///var/run/docker.sock")
+}
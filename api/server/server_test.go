@@ -3,6 +3,12 @@ package server
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,11 +19,19 @@ import (
 	"github.com/kelda/kelda/api/client/mocks"
 	"github.com/kelda/kelda/api/pb"
 	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/cloud/amazon"
 	"github.com/kelda/kelda/connection"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/etcd"
 	"github.com/kelda/kelda/minion/vault"
 	vaultMocks "github.com/kelda/kelda/minion/vault/mocks"
+	"github.com/kelda/kelda/util"
+	"github.com/kelda/kelda/version"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
 )
 
 func checkQuery(t *testing.T, s server, table db.TableType, exp string) {
@@ -51,6 +65,7 @@ func TestQueryMachinesDaemon(t *testing.T) {
 	conn := db.New()
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		m := view.InsertMachine()
+		m.Namespace = "ns"
 		m.Role = db.Master
 		m.Provider = db.Amazon
 		m.Size = "size"
@@ -59,15 +74,79 @@ func TestQueryMachinesDaemon(t *testing.T) {
 		m.Status = db.Connected
 		view.Commit(m)
 
+		m = view.InsertMachine()
+		m.Namespace = "other"
+		view.Commit(m)
+
 		return nil
 	})
 
-	exp := `[{"ID":1,"Role":"Master","Provider":"Amazon",` +
+	exp := `[{"ID":1,"Namespace":"ns","Role":"Master","Provider":"Amazon",` +
 		`"Region":"","Size":"size","DiskSize":0,"SSHKeys":null,"FloatingIP":"",` +
-		`"Preemptible":false,"CloudID":"","PublicIP":"8.8.8.8",` +
-		`"PrivateIP":"9.9.9.9","Status":"connected","PublicKey":""}]`
+		`"Preemptible":false,"MaxPrice":0,"Image":"","OS":"","Labels":null,` +
+		`"CloudID":"","PublicIP":"8.8.8.8",` +
+		`"PrivateIP":"9.9.9.9","Status":"connected","CloudError":"",` +
+		`"PublicKey":"","Unschedulable":false,"Draining":false,"Version":"",` +
+		`"Upgrading":false}]`
+
+	reply, err := server{conn, true, nil}.Query(context.Background(),
+		&pb.DBQuery{Table: string(db.MachineTable), Namespace: "ns"})
+	assert.NoError(t, err)
+	assert.Equal(t, exp, reply.TableContents)
+
+	// Without a namespace, the machines in every namespace are returned.
+	reply, err = server{conn, true, nil}.Query(context.Background(),
+		&pb.DBQuery{Table: string(db.MachineTable)})
+	assert.NoError(t, err)
+	assert.Contains(t, reply.TableContents, `"Namespace":"other"`)
+}
+
+func TestQueryNamespaces(t *testing.T) {
+	var leaderMachines []db.Machine
+	newLeaderClient = func(machines []db.Machine, _ connection.Credentials) (
+		client.Client, error) {
+		leaderMachines = machines
+		mc := new(mocks.Client)
+		mc.On("QueryConnections").Return(nil, nil)
+		mc.On("Close").Return(nil)
+		return mc, nil
+	}
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, ns := range []string{"a", "b"} {
+			bp := view.InsertBlueprint()
+			bp.Namespace = ns
+			view.Commit(bp)
+
+			m := view.InsertMachine()
+			m.Namespace = ns
+			view.Commit(m)
+		}
+		return nil
+	})
+	s := server{conn, true, nil}
+
+	_, err := s.Query(context.Background(),
+		&pb.DBQuery{Table: string(db.ConnectionTable)})
+	assert.EqualError(t, err, "the daemon manages multiple namespaces (a, b); "+
+		"select one with -namespace")
+
+	_, err = s.Query(context.Background(),
+		&pb.DBQuery{Table: string(db.ConnectionTable), Namespace: "c"})
+	assert.EqualError(t, err, `no deployment in namespace "c"`)
 
-	checkQuery(t, server{conn, true, nil}, db.MachineTable, exp)
+	_, err = s.Query(context.Background(),
+		&pb.DBQuery{Table: string(db.ConnectionTable), Namespace: "b"})
+	assert.NoError(t, err)
+	assert.Len(t, leaderMachines, 1)
+	assert.Equal(t, "b", leaderMachines[0].Namespace)
+
+	reply, err := s.Query(context.Background(),
+		&pb.DBQuery{Table: string(db.BlueprintTable), Namespace: "a"})
+	assert.NoError(t, err)
+	assert.Contains(t, reply.TableContents, `"Namespace":"a"`)
+	assert.NotContains(t, reply.TableContents, `"Namespace":"b"`)
 }
 
 func TestQueryContainersCluster(t *testing.T) {
@@ -209,7 +288,7 @@ func TestDeploy(t *testing.T) {
 
 	var bp db.Blueprint
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
-		bp, err = view.GetBlueprint()
+		bp, err = view.GetBlueprint("")
 		assert.NoError(t, err)
 		return nil
 	})
@@ -219,6 +298,121 @@ func TestDeploy(t *testing.T) {
 	assert.Equal(t, exp, bp.Blueprint)
 }
 
+func TestDeployConnectionProtocol(t *testing.T) {
+	s := server{conn: db.New(), runningOnDaemon: true}
+
+	_, err := s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: `{"Connections":[{"From":["a"],"To":["b"],` +
+			`"MinPort":80,"MaxPort":80,"Protocol":"sctp"}]}`})
+	assert.EqualError(t, err, `unsupported connection protocol "sctp" `+
+		"(supported protocols: tcp, udp, icmp)")
+
+	_, err = s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: `{"Connections":[{"From":["a"],"To":["b"],` +
+			`"Protocol":"icmp"}]}`})
+	assert.NoError(t, err)
+
+	_, err = s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: `{"Connections":[{"From":["a"],"To":["b"],` +
+			`"MinPort":80,"MaxPort":80,"Protocol":"icmp"}]}`})
+	assert.EqualError(t, err, "icmp connections cannot have ports")
+
+	_, err = s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: `{"Connections":[{"From":["public"],"To":["b"],` +
+			`"Protocol":"icmp"}]}`})
+	assert.NoError(t, err)
+
+	_, err = s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: `{"Connections":[{"From":["a"],"To":["public"],` +
+			`"Protocol":"icmp"}]}`})
+	assert.EqualError(t, err,
+		"icmp connections to the public internet are not supported")
+}
+
+func TestDeployReservedSecret(t *testing.T) {
+	s := server{conn: db.New(), runningOnDaemon: true}
+
+	deploy := func(secret string) error {
+		_, err := s.Deploy(context.Background(), &pb.DeployRequest{
+			Deployment: fmt.Sprintf(`{"Containers":[{"Hostname":"web",`+
+				`"Image":{"Name":"nginx"},"Env":{"KEY":`+
+				`{"NameOfSecret":%q}}}]}`, secret)})
+		return err
+	}
+
+	assert.EqualError(t, deploy("kelda-control-plane/ca-key"), "container web "+
+		`references reserved secret "kelda-control-plane/ca-key"`)
+	assert.EqualError(t, deploy("../kelda-control-plane/ca-key"), "container "+
+		`web references reserved secret "../kelda-control-plane/ca-key"`)
+	assert.NoError(t, deploy("password"))
+}
+
+func TestDeployContainerNetwork(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: true}
+	deploy := func(bp blueprint.Blueprint) error {
+		_, err := s.Deploy(context.Background(),
+			&pb.DeployRequest{Deployment: bp.String()})
+		return err
+	}
+
+	bp := blueprint.Blueprint{
+		Namespace:       "ns",
+		ContainerSubnet: "10.1.0.0/8",
+		Machines: []blueprint.Machine{{
+			Provider: "Amazon", Role: "Worker", Region: "us-west-1"}},
+	}
+	assert.EqualError(t, deploy(bp), `container subnet "10.1.0.0/8" has `+
+		"host bits set (did you mean 10.0.0.0/8?)")
+
+	bp.ContainerSubnet = "172.20.0.0/16"
+	assert.NoError(t, deploy(bp))
+
+	// The network may change until the namespace has machines.
+	bp.GatewayIP = "172.20.0.10"
+	assert.NoError(t, deploy(bp))
+
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.Namespace = "ns"
+		view.Commit(m)
+		return nil
+	})
+	assert.NoError(t, deploy(bp))
+
+	bp.ContainerSubnet = ""
+	bp.GatewayIP = ""
+	assert.EqualError(t, deploy(bp), "the container network can't change "+
+		"while the namespace's machines are running (it's subnet "+
+		"172.20.0.0/16 with gateway 172.20.0.10 and load balancer "+
+		"172.20.0.2, and the blueprint sets subnet 10.0.0.0/8 with gateway "+
+		"10.0.0.1 and load balancer 10.0.0.2)")
+
+	bp.ContainerSubnet = "172.20.0.0/16"
+	bp.GatewayIP = "172.20.0.10"
+	bp.ContainerSubnetIPv6 = "fd00::/64"
+	assert.EqualError(t, deploy(bp), "the container network can't change "+
+		"while the namespace's machines are running (it's subnet "+
+		"172.20.0.0/16 with gateway 172.20.0.10 and load balancer "+
+		"172.20.0.2, and the blueprint sets subnet 172.20.0.0/16 with "+
+		"gateway 172.20.0.10 and load balancer 172.20.0.2 plus IPv6 subnet "+
+		"fd00::/64)")
+
+	bp.ContainerSubnetIPv6 = "fd00::1/64"
+	assert.EqualError(t, deploy(bp), `IPv6 container subnet "fd00::1/64" `+
+		"has host bits set (did you mean fd00::/64?)")
+
+	// Other namespaces have their own networks.
+	bp.ContainerSubnetIPv6 = "fd00::/64"
+	bp.Namespace = "other"
+	assert.NoError(t, deploy(bp))
+
+	// The machines may be stopped.
+	assert.NoError(t, deploy(blueprint.Blueprint{Namespace: "ns"}))
+}
+
 func TestDeployUnsupportedRegion(t *testing.T) {
 	conn := db.New()
 	s := server{conn: conn, runningOnDaemon: true}
@@ -239,10 +433,68 @@ func TestDeployUnsupportedRegion(t *testing.T) {
 		&pb.DeployRequest{Deployment: createMachineDeployment})
 
 	assert.EqualError(t, err, "region: FakeRegion is not supported "+
-		"for provider: Amazon")
+		"for provider: Amazon (supported regions: "+
+		strings.Join(amazon.Regions, ", ")+")")
+
+	// Every machine's region should be checked, not just the first.
+	createMachineDeployment = `
+	{"Machines":[
+		{"Provider":"Amazon",
+		"Role":"Master",
+		"Size":"m4.large",
+		"Region":"eu-west-1"
+	}, {"Provider":"Vagrant",
+		"Role":"Worker",
+		"Region":"FakeRegion"
+	}]}`
+
+	_, err = s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: createMachineDeployment})
+	assert.EqualError(t, err, "region: FakeRegion is not supported "+
+		"for provider: Vagrant (supported regions: )")
 }
 
-func TestDeployChangeNamespace(t *testing.T) {
+func TestDeployLabels(t *testing.T) {
+	t.Parallel()
+
+	s := server{conn: db.New(), runningOnDaemon: true}
+	deploy := func(bp blueprint.Blueprint) error {
+		_, err := s.Deploy(context.Background(),
+			&pb.DeployRequest{Deployment: bp.String()})
+		return err
+	}
+
+	bp := blueprint.Blueprint{
+		Labels: map[string]string{"Team": "infra"},
+		Machines: []blueprint.Machine{{
+			Provider: "Amazon", Role: "Worker", Region: "us-west-1"}},
+	}
+	assert.NoError(t, deploy(bp))
+
+	// Google only accepts lowercase labels, including those inherited from
+	// the infrastructure.
+	bp.Machines = append(bp.Machines, blueprint.Machine{
+		Provider: "Google", Role: "Master", Region: "us-east1-b"})
+	assert.EqualError(t, deploy(bp), `invalid labels for provider Google: `+
+		`label key "Team" must start with a lowercase letter, and may `+
+		`only contain up to 63 lowercase letters, numbers, dashes, and `+
+		`underscores`)
+
+	bp.Labels = map[string]string{"team": "infra"}
+	assert.NoError(t, deploy(bp))
+
+	bp.Machines[1].Labels = map[string]string{"env": "Prod"}
+	assert.Error(t, deploy(bp))
+
+	bp.Machines[1] = blueprint.Machine{Provider: "DigitalOcean",
+		Role: "Master", Region: "sfo1",
+		Labels: map[string]string{"owner": "jane doe"}}
+	assert.EqualError(t, deploy(bp), `invalid labels for provider `+
+		`DigitalOcean: label value "jane doe" may only contain letters, `+
+		`numbers, colons, dashes, and underscores`)
+}
+
+func TestDeployNamespaces(t *testing.T) {
 	t.Parallel()
 
 	conn := db.New()
@@ -252,18 +504,32 @@ func TestDeployChangeNamespace(t *testing.T) {
 		view.Commit(bp)
 
 		dbm := view.InsertMachine()
+		dbm.Namespace = "old"
 		view.Commit(dbm)
 		return nil
 	})
 	s := server{conn: conn, runningOnDaemon: true}
 
-	newNamespaceBlueprint := `{"Namespace":"new"}`
 	_, err := s.Deploy(context.Background(),
-		&pb.DeployRequest{Deployment: newNamespaceBlueprint})
+		&pb.DeployRequest{Deployment: `{"Namespace":"new"}`})
+	assert.NoError(t, err)
+
+	// Deploying to a new namespace shouldn't affect the old deployment.
+	assert.Len(t, conn.SelectFromMachine(nil), 1)
+	assert.Equal(t, []string{"new", "old"}, conn.GetBlueprintNamespaces())
+
+	// Redeploying a namespace should replace its blueprint.
+	_, err = s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: `{"Namespace":"new","AdminACL":["1.2.3.4/32"]}`})
 	assert.NoError(t, err)
+	assert.Equal(t, []string{"new", "old"}, conn.GetBlueprintNamespaces())
 
-	// The machines in the database should be removed.
-	assert.Empty(t, conn.SelectFromMachine(nil))
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp, err := view.GetBlueprint("new")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1.2.3.4/32"}, bp.AdminACL)
+		return nil
+	})
 }
 
 func TestVagrantDeployment(t *testing.T) {
@@ -290,7 +556,7 @@ func TestVagrantDeployment(t *testing.T) {
 
 	var bp db.Blueprint
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
-		bp, err = view.GetBlueprint()
+		bp, err = view.GetBlueprint("")
 		assert.NoError(t, err)
 		return nil
 	})
@@ -359,11 +625,342 @@ func TestUpdateLeaderContainerAttrs(t *testing.T) {
 func TestDaemonOnlyEndpoints(t *testing.T) {
 	t.Parallel()
 
-	_, err := server{runningOnDaemon: false}.QueryMinionCounters(nil, nil)
+	s := server{conn: db.New(), runningOnDaemon: false}
+	_, err := s.QueryMinionCounters(nil, nil)
 	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
 
-	_, err = server{runningOnDaemon: false}.Deploy(nil, nil)
+	// Deploy and Cordon are only defined on minions once the cluster manages
+	// its own machines.
+	_, err = s.Deploy(nil, nil)
 	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
+
+	_, err = s.Cordon(nil, nil)
+	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
+
+	_, err = s.Upgrade(nil, nil)
+	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
+}
+
+func TestCordon(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.CloudID = "worker"
+		m.Role = db.Worker
+		view.Commit(m)
+
+		m = view.InsertMachine()
+		m.CloudID = "master"
+		m.Role = db.Master
+		view.Commit(m)
+		return nil
+	})
+	s := server{conn: conn, runningOnDaemon: true}
+
+	getWorker := func() db.Machine {
+		return conn.SelectFromMachine(func(m db.Machine) bool {
+			return m.CloudID == "worker"
+		})[0]
+	}
+
+	_, err := s.Cordon(context.Background(), &pb.CordonRequest{
+		CloudID: "worker", Unschedulable: true})
+	assert.NoError(t, err)
+	assert.True(t, getWorker().Unschedulable)
+	assert.False(t, getWorker().Draining)
+
+	// Draining machines are unschedulable as well.
+	_, err = s.Cordon(context.Background(), &pb.CordonRequest{
+		CloudID: "worker", Drain: true})
+	assert.NoError(t, err)
+	assert.True(t, getWorker().Unschedulable)
+	assert.True(t, getWorker().Draining)
+
+	_, err = s.Cordon(context.Background(), &pb.CordonRequest{
+		CloudID: "worker"})
+	assert.NoError(t, err)
+	assert.False(t, getWorker().Unschedulable)
+	assert.False(t, getWorker().Draining)
+
+	_, err = s.Cordon(context.Background(), &pb.CordonRequest{
+		CloudID: "master", Unschedulable: true})
+	assert.EqualError(t, err, `machine "master" is a master, and masters `+
+		`don't run containers`)
+
+	_, err = s.Cordon(context.Background(), &pb.CordonRequest{
+		CloudID: "missing", Unschedulable: true})
+	assert.EqualError(t, err, `no machine "missing"`)
+}
+
+func TestControlPlaneRPCs(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		view.Commit(bp)
+
+		etcd := view.InsertEtcd()
+		etcd.LeaderIP = "10.0.0.1"
+		view.Commit(etcd)
+
+		m := view.InsertMachine()
+		m.Namespace = "ns"
+		m.CloudID = "worker"
+		m.Role = db.Worker
+		view.Commit(m)
+		return nil
+	})
+	s := server{conn: conn, runningOnDaemon: false}
+
+	// Minions that aren't the leader forward the RPCs to it.
+	deployment := `{"Namespace":"ns"}`
+	mc := new(mocks.Client)
+	mc.On("Deploy", deployment).Return(nil).Once()
+	mc.On("Cordon", pb.CordonRequest{CloudID: "worker", Drain: true}).
+		Return(nil).Once()
+	mc.On("Close").Return(nil)
+	newClient = func(addr string, _ connection.Credentials) (client.Client, error) {
+		assert.Equal(t, api.RemoteAddress("10.0.0.1"), addr)
+		return mc, nil
+	}
+
+	_, err := s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.NoError(t, err)
+	_, err = s.Cordon(context.Background(),
+		&pb.CordonRequest{CloudID: "worker", Drain: true})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+
+	// The leader handles them itself.
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.Leader = true
+		view.Commit(etcd)
+		return nil
+	})
+
+	_, err = s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: `{"Namespace":"ns","AdminACL":["1.2.3.4/32"]}`})
+	assert.NoError(t, err)
+	bps := conn.SelectFromBlueprint(nil)
+	assert.Len(t, bps, 1)
+	assert.Equal(t, []string{"1.2.3.4/32"}, bps[0].AdminACL)
+
+	_, err = s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: `{"Namespace":"other"}`})
+	assert.EqualError(t, err, `this cluster only manages namespace "ns"`)
+
+	_, err = s.Cordon(context.Background(),
+		&pb.CordonRequest{CloudID: "worker", Unschedulable: true})
+	assert.NoError(t, err)
+	assert.True(t, conn.SelectFromMachine(nil)[0].Unschedulable)
+}
+
+func TestHandoffDaemon(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.BlueprintTable, db.MachineTable).Run(func(view db.Database) error {
+		for _, ns := range []string{"ns", "other"} {
+			bp := view.InsertBlueprint()
+			bp.Namespace = ns
+			bp.AdminACL = []string{"5.6.7.8/32"}
+			view.Commit(bp)
+
+			m := view.InsertMachine()
+			m.Namespace = ns
+			m.CloudID = ns
+			view.Commit(m)
+		}
+		return nil
+	})
+	s := server{conn: conn, runningOnDaemon: true}
+
+	_, err := s.Handoff(context.Background(), &pb.HandoffRequest{})
+	assert.EqualError(t, err, "the daemon manages multiple namespaces "+
+		"(ns, other); select one with -namespace")
+
+	myIP = func() (string, error) {
+		return "1.2.3.4", nil
+	}
+
+	mc := new(mocks.Client)
+	expBlueprint := blueprint.Blueprint{
+		Namespace: "ns",
+		AdminACL:  []string{"5.6.7.8/32", "1.2.3.4/32"},
+	}
+	mc.On("Handoff", pb.HandoffRequest{
+		Namespace: "ns", Blueprint: expBlueprint.String()}).Return(nil).Once()
+	mc.On("Close").Return(nil)
+	newLeaderClient = func(machines []db.Machine, _ connection.Credentials) (
+		client.Client, error) {
+		assert.Len(t, machines, 1)
+		return mc, nil
+	}
+
+	_, err = s.Handoff(context.Background(), &pb.HandoffRequest{Namespace: "ns"})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+
+	// The daemon no longer manages the namespace.
+	assert.Equal(t, []string{"other"}, conn.GetBlueprintNamespaces())
+	machines := conn.SelectFromMachine(nil)
+	assert.Len(t, machines, 1)
+	assert.Equal(t, "other", machines[0].Namespace)
+
+	// If the leader fails to take over the deployment, the daemon keeps
+	// managing it.
+	mc.On("Handoff", mock.Anything).Return(assert.AnError).Once()
+	_, err = s.Handoff(context.Background(), &pb.HandoffRequest{})
+	assert.Equal(t, assert.AnError, err)
+	assert.Equal(t, []string{"other"}, conn.GetBlueprintNamespaces())
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.BlueprintTable, db.MachineTable).Run(func(view db.Database) error {
+		for _, ns := range []string{"ns", "other"} {
+			bp := view.InsertBlueprint()
+			bp.Namespace = ns
+			view.Commit(bp)
+		}
+
+		for _, m := range []db.Machine{
+			{CloudID: "old", Namespace: "ns", Version: "0.1.0"},
+			{CloudID: "current", Namespace: "ns", Version: version.Version},
+			{CloudID: "unknown", Namespace: "ns"},
+			{CloudID: "other", Namespace: "other", Version: "0.1.0"},
+		} {
+			dbm := view.InsertMachine()
+			m.ID = dbm.ID
+			view.Commit(m)
+		}
+		return nil
+	})
+	s := server{conn: conn, runningOnDaemon: true}
+
+	_, err := s.Upgrade(context.Background(), &pb.UpgradeRequest{})
+	assert.EqualError(t, err, "the daemon manages multiple namespaces "+
+		"(ns, other); select one with -namespace")
+
+	_, err = s.Upgrade(context.Background(), &pb.UpgradeRequest{Namespace: "ns"})
+	assert.NoError(t, err)
+
+	var upgrading []string
+	for _, m := range conn.SelectFromMachine(nil) {
+		if m.Upgrading {
+			upgrading = append(upgrading, m.CloudID)
+		}
+	}
+	assert.Equal(t, []string{"old"}, upgrading)
+}
+
+func TestHandoffMinion(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: false}
+
+	req := &pb.HandoffRequest{Namespace: "ns", Blueprint: `{"Namespace":"ns"}`}
+	_, err := s.Handoff(context.Background(), req)
+	assert.EqualError(t, err, "only the leader may take over the deployment")
+
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+		return nil
+	})
+
+	_, err = s.Handoff(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ns"}, conn.GetBlueprintNamespaces())
+
+	_, err = s.Handoff(context.Background(), &pb.HandoffRequest{Blueprint: "bad"})
+	assert.Error(t, err)
+}
+
+func TestSnapshotDaemon(t *testing.T) {
+	mc := new(mocks.Client)
+	mc.On("Snapshot", pb.SnapshotRequest{}).Return("snapshot", nil).Once()
+	mc.On("Close").Return(nil)
+	newLeaderClient = func(_ []db.Machine, _ connection.Credentials) (
+		client.Client, error) {
+		return mc, nil
+	}
+
+	s := server{conn: db.New(), runningOnDaemon: true}
+	reply, err := s.Snapshot(context.Background(), &pb.SnapshotRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "snapshot", reply.Snapshot)
+
+	mc.On("Snapshot", pb.SnapshotRequest{}).Return("", assert.AnError).Once()
+	_, err = s.Snapshot(context.Background(), &pb.SnapshotRequest{})
+	assert.Equal(t, assert.AnError, err)
+	mc.AssertExpectations(t)
+}
+
+func TestSnapshotMinion(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	s := server{conn: db.New(), runningOnDaemon: false}
+	_, err := s.Snapshot(context.Background(), &pb.SnapshotRequest{})
+	assert.EqualError(t, err, "no snapshot has been taken yet")
+
+	util.WriteFile(etcd.SnapshotPath, []byte("snapshot"), 0600)
+	reply, err := s.Snapshot(context.Background(), &pb.SnapshotRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "snapshot", reply.Snapshot)
+}
+
+func TestRestoreDaemon(t *testing.T) {
+	mc := new(mocks.Client)
+	mc.On("Restore", pb.RestoreRequest{Snapshot: "snapshot"}).Return(nil)
+	mc.On("Close").Return(nil)
+	newLeaderClient = func(_ []db.Machine, _ connection.Credentials) (
+		client.Client, error) {
+		return mc, nil
+	}
+
+	s := server{conn: db.New(), runningOnDaemon: true}
+	_, err := s.Restore(context.Background(), &pb.RestoreRequest{
+		Snapshot: "snapshot"})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+}
+
+func TestRestoreMinion(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: false}
+
+	snapshot := `{"Keys":{"/containers":` +
+		`"[{\"BlueprintID\":\"a\",\"IP\":\"10.0.0.1\"}]"}}`
+	req := &pb.RestoreRequest{Snapshot: snapshot}
+	_, err := s.Restore(context.Background(), req)
+	assert.EqualError(t, err, "only the leader may restore a snapshot")
+
+	conn.Txn(db.ContainerTable, db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+
+		dbc := view.InsertContainer()
+		dbc.BlueprintID = "a"
+		view.Commit(dbc)
+		return nil
+	})
+
+	_, err = s.Restore(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", conn.SelectFromContainer(nil)[0].IP)
+
+	_, err = s.Restore(context.Background(), &pb.RestoreRequest{Snapshot: "bad"})
+	assert.Error(t, err)
 }
 
 func TestQueryImagesCluster(t *testing.T) {
@@ -446,3 +1043,376 @@ func TestSetSecretCluster(t *testing.T) {
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
 }
+
+type mockLogsServer struct {
+	grpc.ServerStream
+	replies []pb.LogsReply
+}
+
+func (s *mockLogsServer) Send(reply *pb.LogsReply) error {
+	s.replies = append(s.replies, *reply)
+	return nil
+}
+
+func (s *mockLogsServer) Context() context.Context {
+	return context.Background()
+}
+
+// The daemon should proxy the request to the minion running the container.
+func TestLogsDaemon(t *testing.T) {
+	host := "8.8.8.8"
+	req := pb.LogsRequest{Host: host, Container: "id", Follow: true, Tail: 5}
+	minionReq := req
+	minionReq.Host = ""
+
+	mc := new(mocks.Client)
+	mc.On("Logs", mock.Anything, minionReq, mock.Anything).Return(
+		func(_ context.Context, _ pb.LogsRequest,
+			handle func(pb.LogsReply) error) error {
+			return handle(pb.LogsReply{Timestamp: 1, Line: "foo"})
+		})
+	mc.On("Close").Return(nil)
+	newClient = func(addr string, _ connection.Credentials) (client.Client, error) {
+		assert.Equal(t, api.RemoteAddress(host), addr)
+		return mc, nil
+	}
+
+	stream := &mockLogsServer{}
+	err := server{runningOnDaemon: true}.Logs(&req, stream)
+	assert.NoError(t, err)
+	assert.Equal(t, []pb.LogsReply{{Timestamp: 1, Line: "foo"}}, stream.replies)
+	mc.AssertExpectations(t)
+
+	err = server{runningOnDaemon: true}.Logs(&pb.LogsRequest{Container: "id"},
+		&mockLogsServer{})
+	assert.EqualError(t, err, "no host specified")
+
+	newClient = func(_ string, _ connection.Credentials) (client.Client, error) {
+		return nil, assert.AnError
+	}
+	assert.Equal(t, assert.AnError, server{runningOnDaemon: true}.Logs(&req,
+		&mockLogsServer{}))
+}
+
+// The minion should read the logs directly from Docker.
+func TestLogsCluster(t *testing.T) {
+	md, dk := docker.NewMock()
+	newDockerClient = func() docker.Client { return dk }
+
+	md.StdoutLogs["id"] = "2017-10-18T17:55:00Z foo\n"
+	md.StderrLogs["id"] = "2017-10-18T17:56:00Z bar\n"
+
+	since := time.Date(2017, 10, 18, 17, 50, 0, 0, time.UTC)
+	stream := &mockLogsServer{}
+	err := server{}.Logs(&pb.LogsRequest{
+		Container: "id",
+		Follow:    true,
+		Since:     since.UnixNano(),
+		Tail:      10,
+	}, stream)
+	assert.NoError(t, err)
+
+	assert.Len(t, stream.replies, 2)
+	assert.Contains(t, stream.replies, pb.LogsReply{
+		Timestamp: time.Date(2017, 10, 18, 17, 55, 0, 0, time.UTC).UnixNano(),
+		Line:      "foo",
+	})
+	assert.Contains(t, stream.replies, pb.LogsReply{
+		Timestamp: time.Date(2017, 10, 18, 17, 56, 0, 0, time.UTC).UnixNano(),
+		Stderr:    true,
+		Line:      "bar",
+	})
+
+	dockerReq := md.LogRequests["id"]
+	assert.True(t, dockerReq.Follow)
+	assert.Equal(t, since.Unix(), dockerReq.Since)
+	assert.Equal(t, "10", dockerReq.Tail)
+
+	md.LogsError = true
+	assert.Error(t, server{}.Logs(&pb.LogsRequest{Container: "id"},
+		&mockLogsServer{}))
+}
+
+type mockExecServer struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	sync.Mutex
+	requests []pb.ExecRequest
+	replies  []pb.ExecReply
+}
+
+func newMockExecServer(ctx context.Context,
+	requests ...pb.ExecRequest) *mockExecServer {
+	return &mockExecServer{ctx: ctx, requests: requests}
+}
+
+// Recv pops the mocked requests, and then blocks until the stream's context is
+// cancelled.
+func (s *mockExecServer) Recv() (*pb.ExecRequest, error) {
+	s.Lock()
+	if len(s.requests) == 0 {
+		s.Unlock()
+		<-s.ctx.Done()
+		return nil, s.ctx.Err()
+	}
+
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	s.Unlock()
+	return &req, nil
+}
+
+func (s *mockExecServer) Send(reply *pb.ExecReply) error {
+	s.Lock()
+	defer s.Unlock()
+	s.replies = append(s.replies, *reply)
+	return nil
+}
+
+func (s *mockExecServer) Context() context.Context {
+	return s.ctx
+}
+
+// getOutput returns the stdout and stderr sent by the server, and the final
+// reply.
+func (s *mockExecServer) getOutput() (string, string, pb.ExecReply) {
+	s.Lock()
+	defer s.Unlock()
+
+	var stdout, stderr string
+	for _, reply := range s.replies[:len(s.replies)-1] {
+		stdout += string(reply.Stdout)
+		stderr += string(reply.Stderr)
+	}
+	return stdout, stderr, s.replies[len(s.replies)-1]
+}
+
+// The daemon should proxy the stream to the minion running the container.
+func TestExecDaemon(t *testing.T) {
+	host := "8.8.8.8"
+	req := pb.ExecRequest{Host: host, Container: "id",
+		Command: []string{"sh"}, Tty: true}
+	minionReq := req
+	minionReq.Host = ""
+
+	var stdin string
+	var size pb.TerminalSize
+	mc := new(mocks.Client)
+	mc.On("Exec", mock.Anything, minionReq, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything).Return(
+		func(_ context.Context, _ pb.ExecRequest, in io.Reader,
+			out, errOut io.Writer, resize <-chan pb.TerminalSize) int {
+			size = <-resize
+			input, _ := ioutil.ReadAll(in)
+			stdin = string(input)
+			out.Write([]byte("out"))
+			errOut.Write([]byte("err"))
+			return 3
+		}, nil)
+	mc.On("Close").Return(nil)
+	newClient = func(addr string, _ connection.Credentials) (client.Client, error) {
+		assert.Equal(t, api.RemoteAddress(host), addr)
+		return mc, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := newMockExecServer(ctx, req,
+		pb.ExecRequest{Resize: &pb.TerminalSize{Height: 24, Width: 80}},
+		pb.ExecRequest{Stdin: []byte("in")},
+		pb.ExecRequest{CloseStdin: true})
+	assert.NoError(t, server{runningOnDaemon: true}.Exec(stream))
+	mc.AssertExpectations(t)
+
+	stdout, stderr, last := stream.getOutput()
+	assert.Equal(t, "out", stdout)
+	assert.Equal(t, "err", stderr)
+	assert.Equal(t, pb.ExecReply{Exited: true, ExitCode: 3}, last)
+	assert.Equal(t, "in", stdin)
+	assert.Equal(t, pb.TerminalSize{Height: 24, Width: 80}, size)
+
+	err := server{runningOnDaemon: true}.Exec(newMockExecServer(ctx,
+		pb.ExecRequest{Container: "id"}))
+	assert.EqualError(t, err, "no host specified")
+
+	newClient = func(_ string, _ connection.Credentials) (client.Client, error) {
+		return nil, assert.AnError
+	}
+	assert.Equal(t, assert.AnError, server{runningOnDaemon: true}.Exec(
+		newMockExecServer(ctx, req)))
+}
+
+// The minion should run the command through Docker.
+func TestExecCluster(t *testing.T) {
+	md, dk := docker.NewMock()
+	newDockerClient = func() docker.Client { return dk }
+
+	id, err := dk.Run(docker.RunOptions{Name: "name"})
+	assert.NoError(t, err)
+	md.ExecStdout[id] = "out"
+	md.ExecStderr[id] = "err"
+	md.ExecExitCodes[id] = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := newMockExecServer(ctx,
+		pb.ExecRequest{Container: id, Command: []string{"ls", "/"}},
+		pb.ExecRequest{Stdin: []byte("in")},
+		pb.ExecRequest{Resize: &pb.TerminalSize{Height: 24, Width: 80}},
+		// The second resize is only received once the first was handled.
+		pb.ExecRequest{Resize: &pb.TerminalSize{Height: 48, Width: 160}},
+		pb.ExecRequest{CloseStdin: true})
+	assert.NoError(t, server{}.Exec(stream))
+
+	stdout, stderr, last := stream.getOutput()
+	assert.Equal(t, "out", stdout)
+	assert.Equal(t, "err", stderr)
+	assert.Equal(t, pb.ExecReply{Exited: true, ExitCode: 1}, last)
+
+	md.Lock()
+	assert.Equal(t, []string{"ls /"}, md.Executions[id])
+	assert.Equal(t, "in", md.ExecStdin[id])
+	assert.Contains(t, md.ExecResizes[id], docker.TerminalSize{
+		Height: 24, Width: 80})
+	md.StartExecError = true
+	md.Unlock()
+
+	assert.Error(t, server{}.Exec(newMockExecServer(ctx,
+		pb.ExecRequest{Container: id, Command: []string{"ls"}})))
+}
+
+type mockPortForwardServer struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	sync.Mutex
+	requests []pb.PortForwardRequest
+	replies  []byte
+}
+
+// Recv pops the mocked requests, and then blocks until the stream's context is
+// cancelled.
+func (s *mockPortForwardServer) Recv() (*pb.PortForwardRequest, error) {
+	s.Lock()
+	if len(s.requests) == 0 {
+		s.Unlock()
+		<-s.ctx.Done()
+		return nil, s.ctx.Err()
+	}
+
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	s.Unlock()
+	return &req, nil
+}
+
+func (s *mockPortForwardServer) Send(reply *pb.PortForwardReply) error {
+	s.Lock()
+	defer s.Unlock()
+	s.replies = append(s.replies, reply.Data...)
+	return nil
+}
+
+func (s *mockPortForwardServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockPortForwardServer) received() string {
+	s.Lock()
+	defer s.Unlock()
+	return string(s.replies)
+}
+
+// The daemon should proxy the stream to the minion running the container.
+func TestPortForwardDaemon(t *testing.T) {
+	host := "8.8.8.8"
+	mc := new(mocks.Client)
+	mc.On("PortForward", mock.Anything,
+		pb.PortForwardRequest{IP: "10.0.0.2", Port: 80}, mock.Anything).Return(
+		func(_ context.Context, _ pb.PortForwardRequest,
+			conn io.ReadWriter) error {
+			data, err := ioutil.ReadAll(conn)
+			if err != nil {
+				return err
+			}
+			_, err = conn.Write(append([]byte("pong:"), data...))
+			return err
+		})
+	mc.On("Close").Return(nil)
+	newClient = func(addr string, _ connection.Credentials) (client.Client, error) {
+		assert.Equal(t, api.RemoteAddress(host), addr)
+		return mc, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := pb.PortForwardRequest{Host: host, IP: "10.0.0.2", Port: 80,
+		Data: []byte("pi")}
+	stream := &mockPortForwardServer{ctx: ctx, requests: []pb.PortForwardRequest{
+		req, {Data: []byte("ng")}, {CloseWrite: true}}}
+	assert.NoError(t, server{runningOnDaemon: true}.PortForward(stream))
+	assert.Equal(t, "pong:ping", stream.received())
+	mc.AssertExpectations(t)
+
+	stream = &mockPortForwardServer{ctx: ctx,
+		requests: []pb.PortForwardRequest{{IP: "10.0.0.2", Port: 80}}}
+	assert.EqualError(t, server{runningOnDaemon: true}.PortForward(stream),
+		"no host specified")
+}
+
+// The minion should connect to the container, but only if it's running locally.
+func TestPortForwardCluster(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := ioutil.ReadAll(conn)
+		conn.Write(append([]byte("pong:"), data...))
+	}()
+
+	_, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.PrivateIP = "priv"
+		view.Commit(self)
+
+		dbc := view.InsertContainer()
+		dbc.IP = "127.0.0.1"
+		dbc.Minion = "priv"
+		view.Commit(dbc)
+
+		dbc = view.InsertContainer()
+		dbc.IP = "10.0.0.3"
+		dbc.Minion = "other"
+		view.Commit(dbc)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := &mockPortForwardServer{ctx: ctx, requests: []pb.PortForwardRequest{
+		{IP: "127.0.0.1", Port: int32(port)},
+		{Data: []byte("ping")},
+		{CloseWrite: true}}}
+	assert.NoError(t, server{conn: conn}.PortForward(stream))
+	assert.Equal(t, "pong:ping", stream.received())
+
+	stream = &mockPortForwardServer{ctx: ctx, requests: []pb.PortForwardRequest{
+		{IP: "10.0.0.3", Port: int32(port)}}}
+	assert.EqualError(t, server{conn: conn}.PortForward(stream),
+		"no container with IP 10.0.0.3 on this machine")
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/kelda/kelda/util"
 )
@@ -22,6 +23,55 @@ type Blueprint struct {
 
 	AdminACL  []string `json:",omitempty"`
 	Namespace string   `json:",omitempty"`
+
+	// Labels applied to all of the cloud resources in the namespace.  Each
+	// machine's own labels take precedence.
+	Labels map[string]string `json:",omitempty"`
+
+	LogSink *LogSink `json:",omitempty"`
+
+	// The subnet in which containers and load balancers are given IP
+	// addresses, and the addresses of the gateway and load balancer router in
+	// it.  Empty fields take their default values, which are 10.0.0.0/8 and
+	// the first and second addresses in the subnet.
+	ContainerSubnet string `json:",omitempty"`
+	GatewayIP       string `json:",omitempty"`
+	LoadBalancerIP  string `json:",omitempty"`
+
+	// The IPv6 subnet in which containers and load balancers are given
+	// addresses, in addition to their IPv4 addresses.  IPv6 is disabled if
+	// it's empty.
+	ContainerSubnetIPv6 string `json:",omitempty"`
+}
+
+// The types of LogSinks supported by the minions.
+const (
+	// SyslogSink sends each line to the syslog server at Address.
+	SyslogSink = "syslog"
+
+	// FileSink appends each line to File on the lead master.
+	FileSink = "file"
+
+	// HTTPSink POSTs batches of lines to the URL at Address.
+	HTTPSink = "http"
+)
+
+// A LogSink describes where the minions should forward the logs written by
+// containers.
+type LogSink struct {
+	Type string `json:",omitempty"`
+
+	// The address of the syslog server (e.g. "udp://10.0.0.1:514"), or the URL
+	// of the HTTP endpoint.
+	Address string `json:",omitempty"`
+
+	// The name of the file written by the FileSink.  It's relative to the log
+	// directory on the master.
+	File string `json:",omitempty"`
+
+	// The maximum number of lines that each minion buffers before it stops
+	// reading logs from Docker.
+	BufferSize int `json:",omitempty"`
 }
 
 // A Placement constraint guides on what type of machine a container can be
@@ -68,6 +118,27 @@ type Secret struct {
 	NameOfSecret string
 }
 
+// ControlPlaneSecretPrefix prefixes the names of the secrets that a cluster
+// needs to manage its own machines.  They're stored apart from the secrets used
+// by containers.
+const ControlPlaneSecretPrefix = "kelda-control-plane/"
+
+// IsReservedSecret returns whether containers are forbidden from referencing the
+// secret `name`, either because it's a control plane secret, or because its
+// name escapes the path under which container secrets are stored.
+func IsReservedSecret(name string) bool {
+	if strings.HasPrefix(name, ControlPlaneSecretPrefix) {
+		return true
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return true
+		}
+	}
+	return false
+}
+
 // ContainerPubIPKey is the RuntimeValue resource key for the public IP of the
 // machine running the container. In other words, it's the IP at which the
 // container can be reached from the public internet.
@@ -88,11 +159,57 @@ type LoadBalancer struct {
 
 // A Connection allows any container whose hostname appears in `From` to speak with any
 // container whose hostname appears in `To` using ports in the range [MinPort, MaxPort]
+// and `Protocol`.  Connections without a protocol allow both TCP and UDP.
 type Connection struct {
-	From    []string `json:",omitempty"`
-	To      []string `json:",omitempty"`
-	MinPort int      `json:",omitempty"`
-	MaxPort int      `json:",omitempty"`
+	From     []string `json:",omitempty"`
+	To       []string `json:",omitempty"`
+	MinPort  int      `json:",omitempty"`
+	MaxPort  int      `json:",omitempty"`
+	Protocol string   `json:",omitempty"`
+}
+
+// The protocols that a Connection may be restricted to.
+const (
+	TCP = "tcp"
+	UDP = "udp"
+
+	// ICMP connections don't have ports.
+	ICMP = "icmp"
+)
+
+// PortProtocols returns the protocols that a connection with `protocol` allows on
+// its port range.
+func PortProtocols(protocol string) []string {
+	switch protocol {
+	case "":
+		return []string{TCP, UDP}
+	case ICMP:
+		return nil
+	default:
+		return []string{protocol}
+	}
+}
+
+// PortsString returns the ports and protocol of a connection as they're shown to
+// users, e.g. "80-81/tcp", or "icmp" for ICMP connections, which don't have
+// ports.
+func PortsString(minPort, maxPort int, protocol string) string {
+	switch protocol {
+	case "":
+	case ICMP:
+		return protocol
+	default:
+		return fmt.Sprintf("%s/%s", portRangeString(minPort, maxPort),
+			protocol)
+	}
+	return portRangeString(minPort, maxPort)
+}
+
+func portRangeString(minPort, maxPort int) string {
+	if minPort == maxPort {
+		return fmt.Sprintf("%d", minPort)
+	}
+	return fmt.Sprintf("%d-%d", minPort, maxPort)
 }
 
 // A ConnectionSlice allows for slices of Collections to be used in joins
@@ -108,6 +225,11 @@ type Machine struct {
 	SSHKeys     []string `json:",omitempty"`
 	FloatingIP  string   `json:",omitempty"`
 	Preemptible bool     `json:",omitempty"`
+	MaxPrice    float64  `json:",omitempty"`
+	Image       string   `json:",omitempty"`
+	OS          string   `json:",omitempty"`
+
+	Labels map[string]string `json:",omitempty"`
 }
 
 // PublicInternetLabel is a magic label that allows connections to or from the public
@@ -51,6 +51,15 @@ func TestSecretJSON(t *testing.T) {
 }
 
 // TestStringJSON tests marshalling and unmarshalling raw strings.
+func TestIsReservedSecret(t *testing.T) {
+	assert.False(t, IsReservedSecret("password"))
+	assert.False(t, IsReservedSecret("db/password"))
+	assert.False(t, IsReservedSecret("kelda-control-plane"))
+	assert.True(t, IsReservedSecret(ControlPlaneSecretPrefix+"ssh-key"))
+	assert.True(t, IsReservedSecret("../kelda-control-plane/ssh-key"))
+	assert.True(t, IsReservedSecret("db/../../password"))
+}
+
 func TestStringJSON(t *testing.T) {
 	t.Parallel()
 
@@ -97,3 +106,33 @@ func checkMarshalAndUnmarshal(t *testing.T, toMarshal ContainerValue) {
 	assert.NoError(t, json.Unmarshal(jsonBytes, &unmarshalled))
 	assert.Equal(t, toMarshal, unmarshalled)
 }
+
+func TestLogSinkJSON(t *testing.T) {
+	t.Parallel()
+
+	bp, err := FromJSON(`{"logSink": {"type": "file", "file": "a.log"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, &LogSink{Type: FileSink, File: "a.log"}, bp.LogSink)
+
+	// The sink should be omitted when it's not set.
+	assert.Equal(t, "{}", Blueprint{}.String())
+}
+
+func TestPortProtocols(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"tcp", "udp"}, PortProtocols(""))
+	assert.Equal(t, []string{"tcp"}, PortProtocols(TCP))
+	assert.Equal(t, []string{"udp"}, PortProtocols(UDP))
+	assert.Empty(t, PortProtocols(ICMP))
+}
+
+func TestPortsString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "80", PortsString(80, 80, ""))
+	assert.Equal(t, "80-81", PortsString(80, 81, ""))
+	assert.Equal(t, "53/udp", PortsString(53, 53, UDP))
+	assert.Equal(t, "80-81/tcp", PortsString(80, 81, TCP))
+	assert.Equal(t, "icmp", PortsString(0, 0, ICMP))
+}
@@ -13,18 +13,29 @@ import (
 
 // Note the `minion` command is in cli_posix.go as it only runs on posix systems.
 var commands = map[string]command.SubCommand{
+	"cluster": command.NewClusterCommand(),
+	"cost":    command.NewCostCommand(),
 	"daemon":  command.NewDaemonCommand(),
+	"handoff": command.NewHandoffCommand(),
 	"inspect": &inspect.Inspect{},
 	"logs":    command.NewLogCommand(),
 
+	"cordon":   command.NewCordonCommand(),
+	"drain":    command.NewDrainCommand(),
+	"uncordon": command.NewUncordonCommand(),
+
 	"ps":   command.NewShowCommand(),
 	"show": command.NewShowCommand(),
 
+	"port-forward": command.NewPortForwardCommand(),
+
 	"secret":     &command.Secret{},
 	"run":        command.NewRunCommand(),
 	"init":       &command.Init{},
+	"exec":       command.NewExecCommand(),
 	"ssh":        command.NewSSHCommand(),
 	"stop":       command.NewStopCommand(),
+	"upgrade":    command.NewUpgradeCommand(),
 	"version":    command.NewVersionCommand(),
 	"debug-logs": command.NewDebugCommand(),
 	"counters":   &command.Counters{},
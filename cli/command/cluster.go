@@ -0,0 +1,103 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/util"
+)
+
+// Cluster contains the options for backing up and restoring the state of a
+// cluster.
+type Cluster struct {
+	action string
+	path   string
+
+	connectionHelper
+}
+
+// NewClusterCommand creates a new Cluster command instance.
+func NewClusterCommand() *Cluster {
+	return &Cluster{}
+}
+
+var clusterCommands = "kelda cluster [OPTIONS] backup|restore FILE"
+var clusterExplanation = `Back up or restore the state of a deployment's cluster.
+
+The leader of the cluster snapshots its state every ten minutes, and the masters
+keep a copy of the latest snapshot.  ` + "`kelda cluster backup`" + ` saves the
+latest snapshot to FILE.
+
+If all of the masters are lost, the replacement masters start with an empty
+state, so containers are allocated new IP addresses and may be placed on other
+workers.  Once the cluster has a leader, ` + "`kelda cluster restore`" + `
+restores the IP addresses and placements of the containers and load balancers
+in the snapshot in FILE.  Containers that aren't in the snapshot are allocated
+new IP addresses if theirs were restored to other containers.`
+
+// InstallFlags sets up parsing for command line flags.
+func (cCmd *Cluster) InstallFlags(flags *flag.FlagSet) {
+	cCmd.connectionHelper.InstallFlags(flags)
+	cCmd.installNamespaceFlag(flags)
+	flags.Usage = func() {
+		util.PrintUsageString(clusterCommands, clusterExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the cluster command.
+func (cCmd *Cluster) Parse(args []string) error {
+	if len(args) != 2 {
+		return errors.New("must specify an action and a file")
+	}
+
+	cCmd.action, cCmd.path = args[0], args[1]
+	if cCmd.action != "backup" && cCmd.action != "restore" {
+		return fmt.Errorf("unknown action %q, must be backup or restore",
+			cCmd.action)
+	}
+	return nil
+}
+
+// Run backs up or restores the cluster's state.
+func (cCmd *Cluster) Run() int {
+	if err := cCmd.run(os.Stdout); err != nil {
+		log.WithError(err).Errorf("Failed to %s the cluster state", cCmd.action)
+		return 1
+	}
+	return 0
+}
+
+func (cCmd *Cluster) run(out io.Writer) error {
+	if cCmd.action == "backup" {
+		snapshot, err := cCmd.client.Snapshot(pb.SnapshotRequest{
+			Namespace: cCmd.namespace})
+		if err != nil {
+			return err
+		}
+
+		if err := util.WriteFile(cCmd.path, []byte(snapshot), 0600); err != nil {
+			return fmt.Errorf("write snapshot: %s", err)
+		}
+		fmt.Fprintf(out, "Saved the cluster state to %s.\n", cCmd.path)
+		return nil
+	}
+
+	snapshot, err := util.ReadFile(cCmd.path)
+	if err != nil {
+		return fmt.Errorf("read snapshot: %s", err)
+	}
+
+	err = cCmd.client.Restore(pb.RestoreRequest{
+		Namespace: cCmd.namespace, Snapshot: snapshot})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Restored the cluster state from %s.\n", cCmd.path)
+	return nil
+}
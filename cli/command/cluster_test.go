@@ -0,0 +1,66 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/util"
+)
+
+func TestClusterFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewClusterCommand()
+	err := parseHelper(cmd, []string{"-namespace", "ns", "backup", "file"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ns", cmd.namespace)
+	assert.Equal(t, "backup", cmd.action)
+	assert.Equal(t, "file", cmd.path)
+
+	assert.EqualError(t, parseHelper(NewClusterCommand(), []string{"backup"}),
+		"must specify an action and a file")
+	assert.EqualError(t, parseHelper(NewClusterCommand(),
+		[]string{"delete", "file"}),
+		`unknown action "delete", must be backup or restore`)
+}
+
+func TestClusterBackupRestore(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	c := new(mocks.Client)
+	c.On("Snapshot", pb.SnapshotRequest{Namespace: "ns"}).Return(
+		"snapshot", nil).Once()
+	cmd := NewClusterCommand()
+	cmd.client = c
+	cmd.namespace = "ns"
+	cmd.action = "backup"
+	cmd.path = "/backup.json"
+
+	var out bytes.Buffer
+	assert.NoError(t, cmd.run(&out))
+	assert.Equal(t, "Saved the cluster state to /backup.json.\n", out.String())
+	contents, err := util.ReadFile("/backup.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "snapshot", contents)
+
+	c.On("Snapshot", pb.SnapshotRequest{Namespace: "ns"}).Return(
+		"", assert.AnError).Once()
+	assert.Equal(t, assert.AnError, cmd.run(&out))
+
+	c.On("Restore", pb.RestoreRequest{Namespace: "ns", Snapshot: "snapshot"}).
+		Return(nil).Once()
+	cmd.action = "restore"
+	out.Reset()
+	assert.NoError(t, cmd.run(&out))
+	assert.Equal(t, "Restored the cluster state from /backup.json.\n",
+		out.String())
+
+	cmd.path = "/missing.json"
+	assert.Error(t, cmd.run(&out))
+	c.AssertExpectations(t)
+}
@@ -30,16 +30,30 @@ type connectionHelper struct {
 	creds  connection.Credentials
 	client client.Client
 
+	// The namespace of the deployment that the client's requests are limited
+	// to.  It's only set by commands that install the namespace flag.
+	namespace string
+
 	connectionFlags
 }
 
+// installNamespaceFlag sets up parsing for the flag that selects which of the
+// daemon's deployments the command applies to.
+func (ch *connectionHelper) installNamespaceFlag(flags *flag.FlagSet) {
+	flags.StringVar(&ch.namespace, "namespace", "", "the namespace of the "+
+		"deployment to use. Required if the daemon manages multiple namespaces.")
+}
+
 func (ch *connectionHelper) BeforeRun() (err error) {
 	// Load the credentials that will be used by Kelda clients and servers.
 	ch.creds, err = tlsIO.ReadCredentials(cliPath.DefaultTLSDir)
 	if err != nil {
 		return err
 	}
-	return ch.setupClient(client.New)
+	return ch.setupClient(func(host string, creds connection.Credentials) (
+		client.Client, error) {
+		return client.NewNamespaced(host, ch.namespace, creds)
+	})
 }
 
 func (ch *connectionHelper) AfterRun() error {
@@ -88,3 +88,13 @@ func TestConnectionFlagsHostEnv(t *testing.T) {
 		os.Setenv("KELDA_HOST", "")
 	}
 }
+
+func TestNamespaceFlag(t *testing.T) {
+	t.Parallel()
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	ch := connectionHelper{}
+	ch.installNamespaceFlag(flags)
+	assert.NoError(t, flags.Parse([]string{"-namespace", "ns"}))
+	assert.Equal(t, "ns", ch.namespace)
+}
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/api/pb"
+	apiUtil "github.com/kelda/kelda/api/util"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+// Cordon contains the options for changing whether containers may be scheduled
+// on a worker.  It implements `kelda cordon`, `kelda drain`, and
+// `kelda uncordon`.
+type Cordon struct {
+	machine string
+
+	// The scheduling settings that the command applies to the machine.
+	unschedulable bool
+	drain         bool
+
+	commands    string
+	explanation string
+
+	connectionHelper
+}
+
+// NewCordonCommand creates a new Cordon command instance that keeps new
+// containers from being scheduled on a worker.
+func NewCordonCommand() *Cordon {
+	return &Cordon{
+		unschedulable: true,
+		commands:      "kelda cordon [OPTIONS] MACHINE",
+		explanation: `Stop scheduling new containers on a worker.
+
+The containers already running on the worker keep running.  Use
+` + "`kelda uncordon`" + ` to schedule containers on the worker again.
+
+To cordon machine 09ed35808a0b:
+kelda cordon 09ed35808a0b`,
+	}
+}
+
+// NewDrainCommand creates a new Cordon command instance that moves the
+// containers on a worker to other workers.
+func NewDrainCommand() *Cordon {
+	return &Cordon{
+		unschedulable: true,
+		drain:         true,
+		commands:      "kelda drain [OPTIONS] MACHINE",
+		explanation: `Move the containers on a worker to other workers, e.g.
+before maintaining it.
+
+New containers aren't scheduled on the worker until ` + "`kelda uncordon`" + `
+is run.  The daemon drains workers automatically before stopping them.
+
+To drain machine 09ed35808a0b:
+kelda drain 09ed35808a0b`,
+	}
+}
+
+// NewUncordonCommand creates a new Cordon command instance that allows
+// containers to be scheduled on a cordoned or drained worker again.
+func NewUncordonCommand() *Cordon {
+	return &Cordon{
+		commands: "kelda uncordon [OPTIONS] MACHINE",
+		explanation: `Schedule containers on a cordoned or drained worker again.
+
+To uncordon machine 09ed35808a0b:
+kelda uncordon 09ed35808a0b`,
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (cCmd *Cordon) InstallFlags(flags *flag.FlagSet) {
+	cCmd.connectionHelper.InstallFlags(flags)
+	cCmd.installNamespaceFlag(flags)
+	flags.Usage = func() {
+		util.PrintUsageString(cCmd.commands, cCmd.explanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the command.
+func (cCmd *Cordon) Parse(args []string) error {
+	if len(args) != 1 {
+		return errors.New("must specify exactly one machine")
+	}
+
+	cCmd.machine = args[0]
+	return nil
+}
+
+// Run applies the scheduling settings to the machine.
+func (cCmd *Cordon) Run() int {
+	if err := cCmd.run(os.Stdout); err != nil {
+		log.WithError(err).Errorf("Failed to update machine %s", cCmd.machine)
+		return 1
+	}
+	return 0
+}
+
+func (cCmd *Cordon) run(out io.Writer) error {
+	i, _, err := apiUtil.FuzzyLookup(cCmd.client, cCmd.machine)
+	if err != nil {
+		return err
+	}
+
+	m, ok := i.(db.Machine)
+	if !ok {
+		return fmt.Errorf("%s is a container, not a machine", cCmd.machine)
+	}
+
+	err = cCmd.client.Cordon(pb.CordonRequest{
+		CloudID:       m.CloudID,
+		Unschedulable: cCmd.unschedulable,
+		Drain:         cCmd.drain,
+	})
+	if err != nil {
+		return err
+	}
+
+	id := util.ShortUUID(m.CloudID)
+	switch {
+	case cCmd.drain:
+		fmt.Fprintf(out, "Draining machine %s. Its containers will be "+
+			"moved to other workers.\n", id)
+	case cCmd.unschedulable:
+		fmt.Fprintf(out, "Cordoned machine %s. New containers won't be "+
+			"scheduled on it.\n", id)
+	default:
+		fmt.Fprintf(out, "Uncordoned machine %s. Containers may be "+
+			"scheduled on it again.\n", id)
+	}
+	return nil
+}
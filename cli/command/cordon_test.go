@@ -0,0 +1,92 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/db"
+)
+
+func TestCordonFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewDrainCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"machine"}))
+	assert.Equal(t, "machine", cmd.machine)
+
+	assert.EqualError(t, parseHelper(NewCordonCommand(), nil),
+		"must specify exactly one machine")
+	assert.EqualError(t, parseHelper(NewUncordonCommand(), []string{"a", "b"}),
+		"must specify exactly one machine")
+}
+
+func TestCordon(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		cmd *Cordon
+		req pb.CordonRequest
+		exp string
+	}{
+		{NewCordonCommand(),
+			pb.CordonRequest{CloudID: "0123456789abcdef",
+				Unschedulable: true},
+			"Cordoned machine 0123456789ab. New containers won't be " +
+				"scheduled on it.\n"},
+		{NewDrainCommand(),
+			pb.CordonRequest{CloudID: "0123456789abcdef", Unschedulable: true,
+				Drain: true},
+			"Draining machine 0123456789ab. Its containers will be moved " +
+				"to other workers.\n"},
+		{NewUncordonCommand(),
+			pb.CordonRequest{CloudID: "0123456789abcdef"},
+			"Uncordoned machine 0123456789ab. Containers may be scheduled " +
+				"on it again.\n"},
+	}
+	for _, test := range tests {
+		c := new(mocks.Client)
+		c.On("QueryMachines").Return([]db.Machine{
+			{CloudID: "0123456789abcdef", Role: db.Worker}}, nil)
+		c.On("QueryContainers").Return(nil, nil)
+		c.On("Cordon", test.req).Return(nil).Once()
+
+		test.cmd.client = c
+		test.cmd.machine = "0123"
+
+		var out bytes.Buffer
+		assert.NoError(t, test.cmd.run(&out))
+		assert.Equal(t, test.exp, out.String())
+		c.AssertExpectations(t)
+	}
+}
+
+func TestCordonErrors(t *testing.T) {
+	t.Parallel()
+
+	c := new(mocks.Client)
+	c.On("QueryMachines").Return([]db.Machine{{CloudID: "machine"}}, nil)
+	c.On("QueryContainers").Return([]db.Container{
+		{BlueprintID: "container"}}, nil)
+	c.On("Cordon", pb.CordonRequest{CloudID: "machine", Unschedulable: true}).
+		Return(errors.New("cordon error"))
+
+	cmd := NewCordonCommand()
+	cmd.client = c
+
+	var out bytes.Buffer
+	cmd.machine = "container"
+	assert.EqualError(t, cmd.run(&out), "container is a container, not a machine")
+
+	cmd.machine = "missing"
+	assert.EqualError(t, cmd.run(&out),
+		`no machine "missing", no container "missing"`)
+
+	cmd.machine = "machine"
+	assert.EqualError(t, cmd.run(&out), "cordon error")
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,196 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/cloud/cost"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+// Cost contains the options for estimating the cost of deployments.
+type Cost struct {
+	blueprint     string
+	blueprintArgs []string
+
+	connectionHelper
+}
+
+// NewCostCommand creates a new Cost command instance.
+func NewCostCommand() *Cost {
+	return &Cost{}
+}
+
+var costCommands = `kelda cost [OPTIONS] [BLUEPRINT [BLUEPRINT_ARGS...]]`
+var costExplanation = `Estimate what a deployment costs to run.
+
+Without a blueprint, estimate the cost of the machines managed by the daemon,
+or of the machines in the -namespace deployment.  With a blueprint, estimate the
+cost of the machines that it describes, and how that differs from the cost of
+the deployment currently running in its namespace.
+
+Estimates are based on each provider's on-demand prices, and include Amazon's
+disks. Spot instances are estimated at their maximum price.`
+
+// InstallFlags sets up parsing for command line flags.
+func (cCmd *Cost) InstallFlags(flags *flag.FlagSet) {
+	cCmd.connectionHelper.InstallFlags(flags)
+	cCmd.installNamespaceFlag(flags)
+	flags.Usage = func() {
+		util.PrintUsageString(costCommands, costExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the cost command.
+func (cCmd *Cost) Parse(args []string) error {
+	if len(args) > 0 {
+		cCmd.blueprint = args[0]
+		cCmd.blueprintArgs = args[1:]
+	}
+	return nil
+}
+
+// BeforeRun connects to the daemon.  Blueprints can be estimated without a
+// daemon, in which case there's no current deployment to compare them to.
+func (cCmd *Cost) BeforeRun() error {
+	err := cCmd.connectionHelper.BeforeRun()
+	if err != nil && cCmd.blueprint != "" {
+		log.WithError(err).Debug("Unable to connect to the daemon.")
+		cCmd.client = nil
+		return nil
+	}
+	return err
+}
+
+// AfterRun closes the connection to the daemon, if there is one.
+func (cCmd *Cost) AfterRun() error {
+	if cCmd.client == nil {
+		return nil
+	}
+	return cCmd.connectionHelper.AfterRun()
+}
+
+// Run prints the cost estimate.
+func (cCmd *Cost) Run() int {
+	if err := cCmd.run(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (cCmd *Cost) run(out io.Writer) error {
+	var running []db.Machine
+	if cCmd.client != nil {
+		var err error
+		running, err = cCmd.client.QueryMachines()
+		if err != nil {
+			return fmt.Errorf("unable to query machines: %s", err)
+		}
+	}
+
+	if cCmd.blueprint == "" {
+		writeCosts(out, running)
+		fmt.Fprintln(out)
+		writeTotalCost(out, cost.Machines(running))
+		return nil
+	}
+
+	compiled, err := compile(cCmd.blueprint, cCmd.blueprintArgs)
+	if err != nil {
+		return err
+	}
+
+	proposed := cost.BlueprintMachines(compiled)
+	writeCosts(out, proposed)
+	fmt.Fprintln(out)
+	if cCmd.client == nil {
+		writeTotalCost(out, cost.Machines(proposed))
+	} else {
+		curr := namespaceMachines(running, compiled.Namespace)
+		writeCostChange(out, cost.Machines(curr), cost.Machines(proposed))
+	}
+	return nil
+}
+
+// namespaceMachines returns the machines in `machines` that belong to the
+// deployment in `namespace`.
+func namespaceMachines(machines []db.Machine, namespace string) []db.Machine {
+	var filtered []db.Machine
+	for _, m := range machines {
+		if m.Namespace == namespace {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+func writeCosts(fd io.Writer, machines []db.Machine) {
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "MACHINE\tROLE\tPROVIDER\tREGION\tSIZE\tHOURLY\tMONTHLY")
+
+	for _, m := range db.SortMachines(machines) {
+		hourly, monthly := "unknown", "unknown"
+		if price, ok := cost.Hourly(m); ok {
+			hourly = dollars(price, 4)
+			monthly = dollars(price*cost.HoursPerMonth, 2)
+		}
+
+		size := m.Size
+		if m.Preemptible {
+			size += " (preemptible)"
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			util.ShortUUID(m.CloudID), m.Role, m.Provider, m.Region,
+			size, hourly, monthly)
+	}
+}
+
+func writeTotalCost(w io.Writer, est cost.Estimate) {
+	fmt.Fprintf(w, "Total cost: %s/hour, %s/month\n",
+		dollars(est.Hourly, 4), dollars(est.Monthly(), 2))
+	writeUnknownCost(w, est.Unknown)
+}
+
+// writeCostChange describes how the cost of the deployment changes from `curr`
+// to `proposed`.
+func writeCostChange(w io.Writer, curr, proposed cost.Estimate) {
+	fmt.Fprintf(w, "Hourly cost: %s -> %s (%s)\n", dollars(curr.Hourly, 4),
+		dollars(proposed.Hourly, 4),
+		signedDollars(proposed.Hourly-curr.Hourly, 4))
+	fmt.Fprintf(w, "Monthly cost: %s -> %s (%s)\n", dollars(curr.Monthly(), 2),
+		dollars(proposed.Monthly(), 2),
+		signedDollars(proposed.Monthly()-curr.Monthly(), 2))
+	writeUnknownCost(w, curr.Unknown+proposed.Unknown)
+}
+
+func writeUnknownCost(w io.Writer, unknown int) {
+	if unknown > 0 {
+		fmt.Fprintln(w, "Machines whose prices are unknown aren't included.")
+	}
+}
+
+func dollars(amount float64, precision int) string {
+	return fmt.Sprintf("$%.*f", precision, amount)
+}
+
+func signedDollars(amount float64, precision int) string {
+	// Ignore the rounding errors from subtracting equal costs.
+	if math.Abs(amount) < 1e-9 {
+		amount = 0
+	}
+
+	if amount < 0 {
+		return "-" + dollars(-amount, precision)
+	}
+	return "+" + dollars(amount, precision)
+}
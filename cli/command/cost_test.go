@@ -0,0 +1,135 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	clientMock "github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/cloud/cost"
+	"github.com/kelda/kelda/db"
+)
+
+func TestCostFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewCostCommand()
+	assert.NoError(t, parseHelper(cmd, nil))
+	assert.Empty(t, cmd.blueprint)
+
+	cmd = NewCostCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"bp.js", "arg"}))
+	assert.Equal(t, "bp.js", cmd.blueprint)
+	assert.Equal(t, []string{"arg"}, cmd.blueprintArgs)
+}
+
+func TestCostRunning(t *testing.T) {
+	t.Parallel()
+
+	c := new(clientMock.Client)
+	c.On("QueryMachines").Return([]db.Machine{{
+		CloudID:  "1",
+		Role:     db.Master,
+		Provider: db.Google,
+		Region:   "us-east1-b",
+		Size:     "n1-standard-1",
+	}, {
+		CloudID:  "2",
+		Role:     db.Worker,
+		Provider: db.OpenStack,
+		Region:   "RegionOne",
+		Size:     "m1.small",
+	}}, nil).Once()
+
+	cmd := &Cost{connectionHelper: connectionHelper{client: c}}
+	var out bytes.Buffer
+	assert.NoError(t, cmd.run(&out))
+	exp := `MACHINE    ROLE      PROVIDER     REGION        SIZE             ` +
+		`HOURLY     MONTHLY
+1          Master    Google       us-east1-b    n1-standard-1    $0.0475    $34.67
+2          Worker    OpenStack    RegionOne     m1.small         unknown    unknown
+
+Total cost: $0.0475/hour, $34.67/month
+Machines whose prices are unknown aren't included.
+`
+	assert.Equal(t, exp, out.String())
+
+	c.On("QueryMachines").Return(nil, errors.New("err"))
+	assert.EqualError(t, cmd.run(&out), "unable to query machines: err")
+}
+
+func TestCostBlueprint(t *testing.T) {
+	oldCompile := compile
+	defer func() {
+		compile = oldCompile
+	}()
+
+	compile = func(path string, args []string) (blueprint.Blueprint, error) {
+		assert.Equal(t, "bp.js", path)
+		return blueprint.Blueprint{
+			Namespace: "ns",
+			Machines: []blueprint.Machine{{
+				Role:     "Worker",
+				Provider: "Google",
+				Region:   "us-east1-b",
+				Size:     "n1-standard-2",
+			}},
+		}, nil
+	}
+
+	// Without a daemon, only the blueprint's cost is shown.
+	cmd := &Cost{blueprint: "bp.js"}
+	var out bytes.Buffer
+	assert.NoError(t, cmd.run(&out))
+	assert.True(t, strings.HasSuffix(out.String(),
+		"\nTotal cost: $0.0950/hour, $69.35/month\n"), out.String())
+
+	// Only the machines in the blueprint's namespace are compared to it.
+	c := new(clientMock.Client)
+	c.On("QueryMachines").Return([]db.Machine{{
+		Namespace: "ns",
+		Provider:  db.Google,
+		Region:    "us-east1-b",
+		Size:      "n1-standard-1",
+	}, {
+		Namespace: "other",
+		Provider:  db.Google,
+		Region:    "us-east1-b",
+		Size:      "n1-standard-2",
+	}}, nil)
+
+	out.Reset()
+	cmd.client = c
+	assert.NoError(t, cmd.run(&out))
+	assert.True(t, strings.HasSuffix(out.String(), `
+Hourly cost: $0.0475 -> $0.0950 (+$0.0475)
+Monthly cost: $34.67 -> $69.35 (+$34.67)
+`), out.String())
+
+	compile = func(path string, args []string) (blueprint.Blueprint, error) {
+		return blueprint.Blueprint{}, errors.New("compile error")
+	}
+	assert.EqualError(t, cmd.run(&out), "compile error")
+}
+
+func TestCostChange(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	writeCostChange(&out, cost.Estimate{Hourly: 1}, cost.Estimate{Hourly: 0.5,
+		Unknown: 1})
+	assert.Equal(t, `Hourly cost: $1.0000 -> $0.5000 (-$0.5000)
+Monthly cost: $730.00 -> $365.00 (-$365.00)
+Machines whose prices are unknown aren't included.
+`, out.String())
+
+	out.Reset()
+	writeCostChange(&out, cost.Estimate{Hourly: 0.3}, cost.Estimate{Hourly: 0.3})
+	assert.Equal(t, `Hourly cost: $0.3000 -> $0.3000 (+$0.0000)
+Monthly cost: $219.00 -> $219.00 (+$0.00)
+`, out.String())
+}
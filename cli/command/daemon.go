@@ -13,12 +13,14 @@ import (
 
 	"golang.org/x/crypto/ssh"
 
+	"github.com/kelda/kelda/api"
 	"github.com/kelda/kelda/api/server"
 	cliPath "github.com/kelda/kelda/cli/path"
 	"github.com/kelda/kelda/cloud"
 	"github.com/kelda/kelda/cloud/foreman"
 	tlsIO "github.com/kelda/kelda/connection/tls/io"
 	"github.com/kelda/kelda/connection/tls/rsa"
+	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/util"
 	"github.com/kelda/kelda/version"
@@ -28,6 +30,10 @@ import (
 
 // Daemon contains the options for running the Kelda daemon.
 type Daemon struct {
+	metricsAddr  string
+	healthPolicy cloud.HealthPolicy
+	backupDir    string
+
 	*connectionFlags
 }
 
@@ -44,6 +50,20 @@ var daemonExplanation = "Start the kelda daemon, which listens for kelda API req
 // InstallFlags sets up parsing for command line flags
 func (dCmd *Daemon) InstallFlags(flags *flag.FlagSet) {
 	dCmd.connectionFlags.InstallFlags(flags)
+	flags.StringVar(&dCmd.metricsAddr, "metrics",
+		fmt.Sprintf("127.0.0.1:%d", api.DefaultMetricsPort),
+		"the TCP address on which to serve Prometheus metrics, or the empty "+
+			"string to disable the metrics endpoint")
+	flags.DurationVar(&dCmd.healthPolicy.GracePeriod, "replace-after",
+		cloud.DefaultHealthPolicy.GracePeriod,
+		"how long a machine may be unreachable before it's replaced, or 0 "+
+			"to never replace machines")
+	flags.IntVar(&dCmd.healthPolicy.MaxReplacements, "max-replacements",
+		cloud.DefaultHealthPolicy.MaxReplacements,
+		"the most unreachable machines to replace at once")
+	flags.StringVar(&dCmd.backupDir, "backup-dir", "",
+		"the directory in which to keep a copy of each cluster's latest "+
+			"snapshot, or the empty string to not keep copies")
 	flags.Usage = func() {
 		util.PrintUsageString(daemonCommands, daemonExplanation, flags)
 	}
@@ -112,9 +132,23 @@ func (dCmd *Daemon) Run() int {
 		return 1
 	}
 
-	go foreman.Run(conn, creds)
-	go cloud.SyncCredentials(conn, sshKey, ca)
-	cloud.Run(conn, getPublicKey(sshKey))
+	if dCmd.metricsAddr != "" {
+		go func() {
+			if err := counter.ServeMetrics(dCmd.metricsAddr); err != nil {
+				log.WithError(err).WithField("address", dCmd.metricsAddr).
+					Error("Failed to serve metrics")
+			}
+		}()
+	}
+
+	go foreman.Run(conn, creds, nil)
+	go cloud.SyncMetrics(conn)
+	go cloud.SyncCredentials(conn, sshKey, ca, nil)
+	go cloud.SyncUpgrades(conn, sshKey, nil)
+	if dCmd.backupDir != "" {
+		go cloud.SyncBackups(conn, creds, dCmd.backupDir, nil)
+	}
+	cloud.Run(conn, getPublicKey(sshKey), dCmd.healthPolicy, nil)
 	return 0
 }
 
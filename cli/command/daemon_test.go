@@ -1,15 +1,38 @@
 package command
 
 import (
+	"flag"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/kelda/kelda/cloud"
 	tlsIO "github.com/kelda/kelda/connection/tls/io"
 	"github.com/kelda/kelda/util"
 )
 
+func TestDaemonFlags(t *testing.T) {
+	t.Parallel()
+
+	dCmd := NewDaemonCommand()
+	flags := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	dCmd.InstallFlags(flags)
+	assert.NoError(t, flags.Parse(nil))
+	assert.Equal(t, cloud.DefaultHealthPolicy, dCmd.healthPolicy)
+
+	dCmd = NewDaemonCommand()
+	flags = flag.NewFlagSet("daemon", flag.ContinueOnError)
+	dCmd.InstallFlags(flags)
+	assert.NoError(t, flags.Parse([]string{"-replace-after", "1h",
+		"-max-replacements", "3"}))
+	assert.Equal(t, cloud.HealthPolicy{
+		GracePeriod:     time.Hour,
+		MaxReplacements: 3,
+	}, dCmd.healthPolicy)
+}
+
 func TestParsePrivateKey(t *testing.T) {
 	util.AppFs = afero.NewMemMapFs()
 
@@ -0,0 +1,178 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/net/context"
+
+	"github.com/kelda/kelda/api/client"
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/api/util"
+	"github.com/kelda/kelda/db"
+	keldaUtil "github.com/kelda/kelda/util"
+)
+
+// Exec contains the options for executing commands in containers.
+type Exec struct {
+	target      string
+	allocatePTY bool
+	command     []string
+
+	connectionHelper
+}
+
+// NewExecCommand creates a new Exec command instance.
+func NewExecCommand() *Exec {
+	return &Exec{}
+}
+
+var execCommands = "kelda exec [OPTIONS] ID [--] COMMAND..."
+var execExplanation = `Execute a command in a container.
+
+The command is run through the Kelda API, so no SSH access to the machine
+running the container is required.
+
+To list the files in the root directory of container 8879fd2dbcee:
+kelda exec 8879fd2dbcee ls /
+
+To start an interactive shell in container 8879fd2dbcee:
+kelda exec -t 8879fd2dbcee -- sh -l`
+
+// InstallFlags sets up parsing for command line flags.
+func (eCmd *Exec) InstallFlags(flags *flag.FlagSet) {
+	eCmd.connectionHelper.InstallFlags(flags)
+	eCmd.installNamespaceFlag(flags)
+	flags.BoolVar(&eCmd.allocatePTY, "t", false,
+		"attempt to allocate a pseudo-terminal")
+
+	flags.Usage = func() {
+		keldaUtil.PrintUsageString(execCommands, execExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the exec command.
+func (eCmd *Exec) Parse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("must specify a target container")
+	}
+
+	eCmd.target = args[0]
+	eCmd.command = args[1:]
+	if len(eCmd.command) > 0 && eCmd.command[0] == "--" {
+		eCmd.command = eCmd.command[1:]
+	}
+
+	if len(eCmd.command) == 0 {
+		return errors.New("must specify a command")
+	}
+	return nil
+}
+
+// Run executes the command in the given container.
+func (eCmd Exec) Run() int {
+	if eCmd.allocatePTY && !isTerminal() {
+		log.Error("Cannot allocate pseudo-terminal without a terminal")
+		return 1
+	}
+
+	i, host, err := util.FuzzyLookup(eCmd.client, eCmd.target)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to lookup %s", eCmd.target)
+		return 1
+	}
+
+	dbc, ok := i.(db.Container)
+	if !ok {
+		log.Errorf("%s is a machine. Use `kelda ssh` to run commands on "+
+			"machines", eCmd.target)
+		return 1
+	}
+
+	if dbc.DockerID == "" {
+		log.Error("Container not yet running")
+		return 1
+	}
+
+	exitCode, err := containerExec(eCmd.client, host, dbc.DockerID,
+		eCmd.allocatePTY, eCmd.command)
+	if err != nil {
+		log.WithError(err).Error("Error running command")
+		return 1
+	}
+	return exitCode
+}
+
+// containerExec runs `cmd` in the container with the given Docker ID on the
+// machine at `host`, and returns its exit code.  The command reads from stdin,
+// and writes to stdout and stderr.  If `allocatePTY` is true, the local
+// terminal is put in raw mode while the command runs, and changes to its size
+// are forwarded to the command's pseudo-terminal.
+func containerExec(c client.Client, host, dockerID string, allocatePTY bool,
+	cmd []string) (int, error) {
+
+	var resize chan pb.TerminalSize
+	if allocatePTY {
+		resize = make(chan pb.TerminalSize, 1)
+		restore, err := setupTerminal(resize)
+		if err != nil {
+			return 0, err
+		}
+		defer restore()
+	}
+
+	req := pb.ExecRequest{
+		Host:      host,
+		Container: dockerID,
+		Command:   cmd,
+		Tty:       allocatePTY,
+	}
+	return c.Exec(context.Background(), req, os.Stdin, os.Stdout, os.Stderr,
+		resize)
+}
+
+// setupTerminal puts the local terminal in raw mode, and sends its size to
+// `resize` whenever it changes.  The returned function restores the terminal
+// to its original state.  It's stored in a variable so that it can be mocked
+// out for unit tests.
+var setupTerminal = func(resize chan pb.TerminalSize) (func(), error) {
+	stdinFd, stdoutFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	originalState, err := terminal.MakeRaw(stdinFd)
+	if err != nil {
+		return nil, err
+	}
+
+	sendSize := func() {
+		width, height, err := terminal.GetSize(stdoutFd)
+		if err != nil {
+			log.WithError(err).Warn("Error getting terminal window size")
+			return
+		}
+
+		// Replace the pending size, if any, so that sending never blocks.
+		select {
+		case <-resize:
+		default:
+		}
+		resize <- pb.TerminalSize{Height: int32(height), Width: int32(width)}
+	}
+	sendSize()
+
+	resizeSignal := make(chan os.Signal, 1)
+	setupResizeSignal(resizeSignal)
+	go func() {
+		for range resizeSignal {
+			sendSize()
+		}
+	}()
+
+	return func() {
+		signal.Stop(resizeSignal)
+		close(resizeSignal)
+		terminal.Restore(stdinFd, originalState)
+	}, nil
+}
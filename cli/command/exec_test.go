@@ -0,0 +1,125 @@
+package command
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/db"
+)
+
+func checkExecParsing(t *testing.T, args []string, exp Exec, expErr error) {
+	execCmd := NewExecCommand()
+	err := parseHelper(execCmd, args)
+
+	assert.Equal(t, expErr, err)
+	assert.Equal(t, exp.target, execCmd.target)
+	assert.Equal(t, exp.command, execCmd.command)
+	assert.Equal(t, exp.allocatePTY, execCmd.allocatePTY)
+}
+
+func TestExecFlags(t *testing.T) {
+	t.Parallel()
+
+	checkExecParsing(t, []string{"1", "ls", "/"}, Exec{
+		target:  "1",
+		command: []string{"ls", "/"},
+	}, nil)
+	checkExecParsing(t, []string{"-t", "1", "--", "sh", "-l"}, Exec{
+		target:      "1",
+		command:     []string{"sh", "-l"},
+		allocatePTY: true,
+	}, nil)
+	checkExecParsing(t, []string{"1", "--"}, Exec{
+		target:  "1",
+		command: []string{},
+	}, errors.New("must specify a command"))
+	checkExecParsing(t, []string{}, Exec{},
+		errors.New("must specify a target container"))
+}
+
+// mockTerminal replaces setupTerminal with a function that doesn't touch the
+// local terminal, and reports a fixed terminal size.
+func mockTerminal() {
+	setupTerminal = func(resize chan pb.TerminalSize) (func(), error) {
+		resize <- pb.TerminalSize{Height: 24, Width: 80}
+		return func() {}, nil
+	}
+}
+
+func execMockClient() *mocks.Client {
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return([]db.Machine{{
+		CloudID:   "machine",
+		PublicIP:  "host",
+		PrivateIP: "priv",
+	}}, nil)
+	mockClient.On("QueryContainers").Return([]db.Container{{
+		BlueprintID: "running",
+		DockerID:    "dockerID",
+		Minion:      "priv",
+	}, {
+		BlueprintID: "scheduled",
+	}}, nil)
+	return mockClient
+}
+
+func TestExec(t *testing.T) {
+	isTerminal = func() bool { return true }
+	mockTerminal()
+
+	var resize <-chan pb.TerminalSize
+	mockClient := execMockClient()
+	mockClient.On("Exec", mock.Anything, pb.ExecRequest{
+		Host:      "host",
+		Container: "dockerID",
+		Command:   []string{"sh", "-l"},
+		Tty:       true,
+	}, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(3, nil).
+		Run(func(args mock.Arguments) {
+			resize = args.Get(5).(<-chan pb.TerminalSize)
+		})
+
+	testCmd := Exec{
+		target:           "running",
+		command:          []string{"sh", "-l"},
+		allocatePTY:      true,
+		connectionHelper: connectionHelper{client: mockClient},
+	}
+	assert.Equal(t, 3, testCmd.Run())
+	mockClient.AssertExpectations(t)
+
+	// The size of the local terminal should be forwarded.
+	assert.Equal(t, pb.TerminalSize{Height: 24, Width: 80}, <-resize)
+}
+
+func TestExecErrors(t *testing.T) {
+	isTerminal = func() bool { return false }
+	mockTerminal()
+
+	mockClient := execMockClient()
+	mockClient.On("Exec", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).Return(0, assert.AnError)
+
+	// A pseudo-terminal can't be allocated without a local terminal.
+	testCmd := Exec{
+		target:           "running",
+		command:          []string{"sh"},
+		allocatePTY:      true,
+		connectionHelper: connectionHelper{client: mockClient},
+	}
+	assert.Equal(t, 1, testCmd.Run())
+
+	for _, target := range []string{"machine", "scheduled", "missing", "running"} {
+		testCmd := Exec{
+			target:           target,
+			command:          []string{"ls"},
+			connectionHelper: connectionHelper{client: mockClient},
+		}
+		assert.Equal(t, 1, testCmd.Run())
+	}
+}
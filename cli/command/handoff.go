@@ -0,0 +1,146 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/api"
+	"github.com/kelda/kelda/api/pb"
+	cliPath "github.com/kelda/kelda/cli/path"
+	"github.com/kelda/kelda/cloud"
+	tlsIO "github.com/kelda/kelda/connection/tls/io"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+// Handoff contains the options for handing a deployment off to its cluster.
+type Handoff struct {
+	connectionHelper
+}
+
+// NewHandoffCommand creates a new Handoff command instance.
+func NewHandoffCommand() *Handoff {
+	return &Handoff{}
+}
+
+var handoffCommands = "kelda handoff [OPTIONS]"
+var handoffExplanation = `Hand a deployment off to its cluster, so that the
+cluster manages its own machines without the daemon.
+
+The cloud provider credentials, the SSH key, and the certificate authority used
+by the daemon are stored as secrets in the cluster, and the cluster's leader
+takes over replacing and booting machines.  The daemon then stops managing the
+deployment, and may be shut down.  Afterwards, connect to the cluster directly
+with ` + "`kelda -H tcp://<master-ip>:9000`."
+
+// InstallFlags sets up parsing for command line flags.
+func (hCmd *Handoff) InstallFlags(flags *flag.FlagSet) {
+	hCmd.connectionHelper.InstallFlags(flags)
+	hCmd.installNamespaceFlag(flags)
+	flags.Usage = func() {
+		util.PrintUsageString(handoffCommands, handoffExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the handoff command.
+func (hCmd *Handoff) Parse(args []string) error {
+	if len(args) > 0 {
+		return errors.New("handoff takes no arguments")
+	}
+	return nil
+}
+
+// Run hands the deployment off to its cluster.
+func (hCmd *Handoff) Run() int {
+	if err := hCmd.run(os.Stdout); err != nil {
+		log.WithError(err).Error("Failed to hand off the deployment")
+		return 1
+	}
+	return 0
+}
+
+func (hCmd *Handoff) run(out io.Writer) error {
+	machines, err := hCmd.client.QueryMachines()
+	if err != nil {
+		return fmt.Errorf("query machines: %s", err)
+	}
+
+	var masters []string
+	for _, m := range machines {
+		if m.Role == db.Master && m.PublicIP != "" {
+			masters = append(masters, api.RemoteAddress(m.PublicIP))
+		}
+	}
+	if len(masters) == 0 {
+		return errors.New("the deployment has no booted masters")
+	}
+	sort.Strings(masters)
+
+	secrets, err := readControlPlaneSecrets()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := hCmd.client.SetSecret(name, secrets[name]); err != nil {
+			return fmt.Errorf("set secret %s: %s", name, err)
+		}
+	}
+
+	err = hCmd.client.Handoff(pb.HandoffRequest{Namespace: hCmd.namespace})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "The cluster now manages its own machines.\n"+
+		"Connect to it through any of its masters:")
+	for _, master := range masters {
+		fmt.Fprintf(out, "    kelda -H %s show\n", master)
+	}
+	return nil
+}
+
+// readControlPlaneSecrets reads the files that the cluster needs to manage its
+// own machines, keyed by the names of the secrets they're stored in.  Cloud
+// provider credentials that don't exist are skipped, because the deployment
+// might not use the provider.
+func readControlPlaneSecrets() (map[string]string, error) {
+	secrets := map[string]string{}
+	for name, file := range cloud.CredentialFiles {
+		path := filepath.Join(os.Getenv("HOME"), file)
+		value, err := util.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("read %s: %s", path, err)
+		}
+		secrets[name] = value
+	}
+
+	files := map[string]string{
+		cloud.SSHKeySecret: cliPath.DefaultSSHKeyPath,
+		cloud.CACertSecret: tlsIO.CACertPath(cliPath.DefaultTLSDir),
+		cloud.CAKeySecret:  tlsIO.CAKeyPath(cliPath.DefaultTLSDir),
+	}
+	for name, path := range files {
+		value, err := util.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %s", path, err)
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
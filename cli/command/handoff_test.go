@@ -0,0 +1,94 @@
+package command
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/api/pb"
+	cliPath "github.com/kelda/kelda/cli/path"
+	"github.com/kelda/kelda/cloud"
+	tlsIO "github.com/kelda/kelda/connection/tls/io"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+func TestHandoffFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewHandoffCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-namespace", "ns"}))
+	assert.Equal(t, "ns", cmd.namespace)
+
+	assert.EqualError(t, parseHelper(NewHandoffCommand(), []string{"arg"}),
+		"handoff takes no arguments")
+}
+
+func TestHandoff(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	c := new(mocks.Client)
+	c.On("QueryMachines").Return([]db.Machine{
+		{Role: db.Worker, PublicIP: "3.3.3.3"},
+		{Role: db.Master, PublicIP: "2.2.2.2"},
+		{Role: db.Master, PublicIP: "1.1.1.1"},
+		{Role: db.Master},
+	}, nil)
+	cmd := NewHandoffCommand()
+	cmd.client = c
+	cmd.namespace = "ns"
+
+	// The daemon's SSH key and certificate authority are required.
+	var out bytes.Buffer
+	assert.Error(t, cmd.run(&out))
+
+	files := map[string]string{
+		filepath.Join(os.Getenv("HOME"), ".aws/credentials"): "aws",
+		cliPath.DefaultSSHKeyPath:                            "ssh",
+		tlsIO.CACertPath(cliPath.DefaultTLSDir):              "cert",
+		tlsIO.CAKeyPath(cliPath.DefaultTLSDir):               "key",
+	}
+	for path, content := range files {
+		assert.NoError(t, util.WriteFile(path, []byte(content), 0600))
+	}
+
+	c.On("SetSecret", "kelda-control-plane/amazon-credentials", "aws").Return(
+		nil).Once()
+	c.On("SetSecret", cloud.SSHKeySecret, "ssh").Return(nil).Once()
+	c.On("SetSecret", cloud.CACertSecret, "cert").Return(nil).Once()
+	c.On("SetSecret", cloud.CAKeySecret, "key").Return(nil).Once()
+	c.On("Handoff", pb.HandoffRequest{Namespace: "ns"}).Return(nil).Once()
+
+	out.Reset()
+	assert.NoError(t, cmd.run(&out))
+	c.AssertExpectations(t)
+	assert.Equal(t, "The cluster now manages its own machines.\n"+
+		"Connect to it through any of its masters:\n"+
+		"    kelda -H tcp://1.1.1.1:9000 show\n"+
+		"    kelda -H tcp://2.2.2.2:9000 show\n", out.String())
+
+	// Secrets are set before the handoff, so a failure leaves the daemon
+	// managing the deployment.
+	c.On("SetSecret", "kelda-control-plane/amazon-credentials", "aws").Return(
+		assert.AnError).Once()
+	assert.EqualError(t, cmd.run(&out),
+		"set secret kelda-control-plane/amazon-credentials: "+
+			assert.AnError.Error())
+	c.AssertNumberOfCalls(t, "Handoff", 1)
+}
+
+func TestHandoffNoMasters(t *testing.T) {
+	t.Parallel()
+
+	c := new(mocks.Client)
+	c.On("QueryMachines").Return([]db.Machine{{Role: db.Master}}, nil)
+	cmd := NewHandoffCommand()
+	cmd.client = c
+	assert.EqualError(t, cmd.run(&bytes.Buffer{}),
+		"the deployment has no booted masters")
+}
@@ -3,50 +3,94 @@ package command
 import (
 	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/kelda/kelda/api/pb"
 	apiUtil "github.com/kelda/kelda/api/util"
-	"github.com/kelda/kelda/cli/ssh"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/util"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
 )
 
 // Log is the structure for the `kelda logs` command.
 type Log struct {
-	privateKey string
-	shouldTail bool
+	shouldTail     bool
+	showTimestamps bool
+	matchPrefix    bool
+	sinceString    string
+	untilString    string
+	tail           int
 
 	target string
+	since  time.Time
+	until  time.Time
 
-	sshGetter ssh.Getter
+	out    io.Writer
+	errOut io.Writer
 
 	connectionHelper
 }
 
+// A logSource is a single container whose logs are fetched.
+type logSource struct {
+	// The name printed before each line when logs from multiple sources are
+	// interleaved.
+	name string
+
+	// The public IP of the machine running the container.
+	host string
+
+	// The Docker ID of the container.
+	container string
+}
+
 // NewLogCommand creates a new Log command instance.
 func NewLogCommand() *Log {
-	return &Log{sshGetter: ssh.New}
+	return &Log{out: os.Stdout, errOut: os.Stderr}
 }
 
 var logCommands = `kelda logs [OPTIONS] ID`
 var logExplanation = `Fetch the logs of a container or machine minion. Either a container
 or machine ID must be supplied.
 
-To get the logs of container 8879fd2dbcee with a specific private key:
-kelda logs -i ~/.ssh/kelda 8879fd2dbcee
+To get the logs of container 8879fd2dbcee:
+kelda logs 8879fd2dbcee
 
 To follow the logs of the minion on machine 09ed35808a0b:
-kelda logs -f 09ed35808a0b`
+kelda logs -f 09ed35808a0b
+
+To show the last 10 lines logged by container 8879fd2dbcee in the past hour,
+with timestamps:
+kelda logs -t -tail 10 -since 1h 8879fd2dbcee
+
+To interleave the logs of every container whose hostname starts with "spark":
+kelda logs -prefix spark`
 
 // InstallFlags sets up parsing for command line flags.
 func (lCmd *Log) InstallFlags(flags *flag.FlagSet) {
 	lCmd.connectionHelper.InstallFlags(flags)
+	lCmd.installNamespaceFlag(flags)
 
-	flags.StringVar(&lCmd.privateKey, "i", "",
-		"path to the private key to use when connecting to the host")
 	flags.BoolVar(&lCmd.shouldTail, "f", false, "follow log output")
+	flags.BoolVar(&lCmd.showTimestamps, "t", false, "show timestamps")
+	flags.BoolVar(&lCmd.matchPrefix, "prefix", false, "interleave the logs of "+
+		"all containers whose hostname starts with ID")
+	flags.StringVar(&lCmd.sinceString, "since", "", "only show logs after this "+
+		"time, given as an RFC 3339 timestamp or a duration relative to now "+
+		"(e.g. 10m)")
+	flags.StringVar(&lCmd.untilString, "until", "", "only show logs before "+
+		"this time, given as an RFC 3339 timestamp or a duration relative to "+
+		"now (e.g. 10m)")
+	flags.IntVar(&lCmd.tail, "tail", 0, "only show this many lines from the "+
+		"end of the logs of each container")
 
 	flags.Usage = func() {
 		util.PrintUsageString(logCommands, logExplanation, flags)
@@ -54,52 +98,214 @@ func (lCmd *Log) InstallFlags(flags *flag.FlagSet) {
 }
 
 // Parse parses the command line arguments for the `logs` command.
-func (lCmd *Log) Parse(args []string) error {
+func (lCmd *Log) Parse(args []string) (err error) {
 	if len(args) == 0 {
 		return errors.New("must specify a target container or machine")
 	}
 
 	lCmd.target = args[0]
+
+	now := time.Now()
+	if lCmd.since, err = parseLogTime(lCmd.sinceString, now); err != nil {
+		return fmt.Errorf("invalid since: %s", err)
+	}
+	if lCmd.until, err = parseLogTime(lCmd.untilString, now); err != nil {
+		return fmt.Errorf("invalid until: %s", err)
+	}
 	return nil
 }
 
-// Run finds the target container or machine minion and outputs logs.
+// parseLogTime parses either an RFC 3339 timestamp, or a duration that's
+// subtracted from `now`.  The empty string is parsed as the zero time.
+func parseLogTime(str string, now time.Time) (time.Time, error) {
+	if str == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		return t, nil
+	}
+
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a timestamp nor a "+
+			"duration", str)
+	}
+	return now.Add(-d), nil
+}
+
+// Run finds the target containers or machine minion and outputs their logs.
 func (lCmd *Log) Run() int {
-	i, host, err := apiUtil.FuzzyLookup(lCmd.client, lCmd.target)
+	var sources []logSource
+	var err error
+	if lCmd.matchPrefix {
+		sources, err = lCmd.prefixSources()
+	} else {
+		sources, err = lCmd.fuzzySource()
+	}
+
 	if err != nil {
 		log.WithError(err).Errorf("Failed to lookup %s", lCmd.target)
 		return 1
 	}
 
-	cmd := []string{"docker", "logs"}
-	if lCmd.shouldTail {
-		cmd = append(cmd, "--follow")
+	if err := lCmd.printLogs(sources); err != nil {
+		log.WithError(err).Error("Failed to get logs")
+		return 1
+	}
+	return 0
+}
+
+func (lCmd *Log) fuzzySource() ([]logSource, error) {
+	i, host, err := apiUtil.FuzzyLookup(lCmd.client, lCmd.target)
+	if err != nil {
+		return nil, err
 	}
 
+	source := logSource{name: lCmd.target, host: host}
 	switch t := i.(type) {
 	case db.Machine:
-		cmd = append(cmd, "minion")
+		source.container = "minion"
 	case db.Container:
 		if t.DockerID == "" {
-			log.Error("Container not yet running")
-			return 1
+			return nil, errors.New("container not yet running")
 		}
-		cmd = append(cmd, t.DockerID)
+		source.container = t.DockerID
 	default:
 		panic("Not Reached")
 	}
+	return []logSource{source}, nil
+}
 
-	sshClient, err := lCmd.sshGetter(host, lCmd.privateKey)
+// prefixSources returns all running containers whose hostname starts with the
+// source.
+func (lCmd *Log) prefixSources() ([]logSource, error) {
+	machines, err := lCmd.client.QueryMachines()
 	if err != nil {
-		log.WithError(err).Info("Error opening SSH connection")
-		return 1
+		return nil, err
 	}
-	defer sshClient.Close()
 
-	if err = sshClient.Run(false, strings.Join(cmd, " ")); err != nil {
-		log.WithError(err).Info("Error running command over SSH")
-		return 1
+	containers, err := lCmd.client.QueryContainers()
+	if err != nil {
+		return nil, err
 	}
 
-	return 0
+	publicIPs := map[string]string{}
+	for _, m := range machines {
+		publicIPs[m.PrivateIP] = m.PublicIP
+	}
+
+	var sources []logSource
+	for _, c := range containers {
+		if c.Hostname == "" || !strings.HasPrefix(c.Hostname, lCmd.target) {
+			continue
+		}
+
+		host := publicIPs[c.Minion]
+		if c.DockerID == "" || host == "" {
+			log.Warnf("Skipping %s: container not yet running", c.Hostname)
+			continue
+		}
+
+		sources = append(sources, logSource{
+			name:      c.Hostname,
+			host:      host,
+			container: c.DockerID,
+		})
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no running containers with hostname "+
+			"prefix %q", lCmd.target)
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].name < sources[j].name
+	})
+	return sources, nil
+}
+
+type logLine struct {
+	source logSource
+	pb.LogsReply
+}
+
+// printLogs fetches the logs of all sources in parallel.  When following, lines
+// are printed as they arrive.  Otherwise, the lines are sorted by timestamp
+// before being printed so that the logs of multiple sources are interleaved.
+func (lCmd *Log) printLogs(sources []logSource) error {
+	var lines []logLine
+	var linesLock sync.Mutex
+	handle := func(source logSource) func(pb.LogsReply) error {
+		return func(reply pb.LogsReply) error {
+			linesLock.Lock()
+			defer linesLock.Unlock()
+
+			line := logLine{source, reply}
+			if lCmd.shouldTail {
+				lCmd.printLine(line, len(sources) > 1)
+			} else {
+				lines = append(lines, line)
+			}
+			return nil
+		}
+	}
+
+	req := pb.LogsRequest{Follow: lCmd.shouldTail, Tail: int64(lCmd.tail)}
+	if !lCmd.since.IsZero() {
+		req.Since = lCmd.since.UnixNano()
+	}
+	if !lCmd.until.IsZero() {
+		req.Until = lCmd.until.UnixNano()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, len(sources))
+	for _, source := range sources {
+		go func(source logSource) {
+			sourceReq := req
+			sourceReq.Host = source.host
+			sourceReq.Container = source.container
+			err := lCmd.client.Logs(ctx, sourceReq, handle(source))
+			if err != nil {
+				err = fmt.Errorf("%s: %s", source.name, err)
+			}
+			errs <- err
+		}(source)
+	}
+
+	var err error
+	for range sources {
+		if sourceErr := <-errs; sourceErr != nil && err == nil {
+			err = sourceErr
+			cancel()
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp < lines[j].Timestamp
+	})
+	for _, line := range lines {
+		lCmd.printLine(line, len(sources) > 1)
+	}
+	return err
+}
+
+func (lCmd *Log) printLine(line logLine, showName bool) {
+	var prefix string
+	if showName {
+		prefix += line.source.name + " | "
+	}
+	if lCmd.showTimestamps {
+		prefix += time.Unix(0, line.Timestamp).UTC().Format(time.RFC3339Nano) +
+			" "
+	}
+
+	out := lCmd.out
+	if line.Stderr {
+		out = lCmd.errOut
+	}
+	fmt.Fprintln(out, prefix+line.Line)
 }
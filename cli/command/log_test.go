@@ -1,14 +1,17 @@
 package command
 
 import (
+	"bytes"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/context"
 
 	"github.com/kelda/kelda/api/client/mocks"
-	"github.com/kelda/kelda/cli/ssh"
-	mockSSH "github.com/kelda/kelda/cli/ssh/mocks"
+	"github.com/kelda/kelda/api/pb"
 	"github.com/kelda/kelda/db"
 )
 
@@ -18,8 +21,11 @@ func checkLogParsing(t *testing.T, args []string, exp Log, expErr error) {
 
 	assert.Equal(t, expErr, err)
 	assert.Equal(t, exp.target, logsCmd.target)
-	assert.Equal(t, exp.privateKey, logsCmd.privateKey)
 	assert.Equal(t, exp.shouldTail, logsCmd.shouldTail)
+	assert.Equal(t, exp.showTimestamps, logsCmd.showTimestamps)
+	assert.Equal(t, exp.matchPrefix, logsCmd.matchPrefix)
+	assert.Equal(t, exp.tail, logsCmd.tail)
+	assert.Equal(t, exp.since, logsCmd.since)
 }
 
 func TestLogFlags(t *testing.T) {
@@ -28,88 +34,222 @@ func TestLogFlags(t *testing.T) {
 	checkLogParsing(t, []string{"1"}, Log{
 		target: "1",
 	}, nil)
-	checkLogParsing(t, []string{"-i", "key", "1"}, Log{
-		target:     "1",
-		privateKey: "key",
-	}, nil)
 	checkLogParsing(t, []string{"-f", "1"}, Log{
 		target:     "1",
 		shouldTail: true,
 	}, nil)
+	checkLogParsing(t, []string{"-t", "-prefix", "-tail", "10", "1"}, Log{
+		target:         "1",
+		showTimestamps: true,
+		matchPrefix:    true,
+		tail:           10,
+	}, nil)
+	checkLogParsing(t, []string{"-since", "2017-10-18T17:55:00Z", "1"}, Log{
+		target: "1",
+		since:  time.Date(2017, 10, 18, 17, 55, 0, 0, time.UTC),
+	}, nil)
+	checkLogParsing(t, []string{"-until", "yesterday", "1"}, Log{
+		target: "1",
+	}, errors.New(`invalid until: "yesterday" is neither a timestamp `+
+		`nor a duration`))
 	checkLogParsing(t, []string{}, Log{},
 		errors.New("must specify a target container or machine"))
 }
 
-type logTest struct {
-	cmd           Log
-	expHost       string
-	expSSHCommand string
+func TestParseLogTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2017, 10, 18, 17, 55, 0, 0, time.UTC)
+
+	parsed, err := parseLogTime("", now)
+	assert.NoError(t, err)
+	assert.True(t, parsed.IsZero())
+
+	parsed, err = parseLogTime("10m", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-10*time.Minute), parsed)
+
+	parsed, err = parseLogTime("2017-10-18T17:00:00.5Z", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2017, 10, 18, 17, 0, 0, 5e8, time.UTC), parsed)
+
+	_, err = parseLogTime("foo", now)
+	assert.EqualError(t, err, `"foo" is neither a timestamp nor a duration`)
+}
+
+func logsMockClient() *mocks.Client {
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return([]db.Machine{{
+		CloudID:  "a",
+		PublicIP: "machine",
+	}, {
+		PublicIP:  "container",
+		PrivateIP: "containerPriv",
+	}}, nil)
+	mockClient.On("QueryContainers").Return([]db.Container{{
+		BlueprintID: "1",
+		DockerID:    "foo",
+		Hostname:    "spark-ms",
+		Minion:      "containerPriv",
+	}, {
+		BlueprintID: "2",
+		DockerID:    "bar",
+		Hostname:    "spark-wk",
+		Minion:      "containerPriv",
+	}, {
+		BlueprintID: "3",
+		Hostname:    "spark-scheduled",
+	}, {
+		BlueprintID: "4",
+		DockerID:    "baz",
+		Hostname:    "zookeeper",
+		Minion:      "containerPriv",
+	}}, nil)
+	return mockClient
+}
+
+// mockLogs returns a function that can be used as the return value of the
+// mocked Logs call, and replies with `replies`.
+func mockLogs(replies ...pb.LogsReply) func(context.Context, pb.LogsRequest,
+	func(pb.LogsReply) error) error {
+
+	return func(_ context.Context, _ pb.LogsRequest,
+		handle func(pb.LogsReply) error) error {
+		for _, reply := range replies {
+			if err := handle(reply); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 }
 
 func TestLog(t *testing.T) {
 	t.Parallel()
 
-	targetContainer := "1"
-	targetMachine := "a"
-
-	tests := []logTest{
+	tests := []struct {
+		cmd       Log
+		expReq    pb.LogsRequest
+		expOut    string
+		expErrOut string
+	}{
 		// Target container.
 		{
-			cmd:           Log{target: targetContainer},
-			expHost:       "container",
-			expSSHCommand: "docker logs foo",
+			cmd: Log{target: "1"},
+			expReq: pb.LogsRequest{
+				Host:      "container",
+				Container: "foo",
+			},
+			expOut:    "out\n",
+			expErrOut: "err\n",
 		},
 		// Target machine.
 		{
-			cmd:           Log{target: targetMachine},
-			expHost:       "machine",
-			expSSHCommand: "docker logs minion",
+			cmd: Log{target: "a"},
+			expReq: pb.LogsRequest{
+				Host:      "machine",
+				Container: "minion",
+			},
+			expOut:    "out\n",
+			expErrOut: "err\n",
 		},
-		// Tail flag
+		// Options are passed through, and timestamps are printed.
 		{
 			cmd: Log{
-				target:     targetContainer,
-				shouldTail: true,
+				target:         "1",
+				shouldTail:     true,
+				showTimestamps: true,
+				tail:           5,
+				since:          time.Unix(0, 10),
+				until:          time.Unix(0, 20),
 			},
-			expHost:       "container",
-			expSSHCommand: "docker logs --follow foo",
+			expReq: pb.LogsRequest{
+				Host:      "container",
+				Container: "foo",
+				Follow:    true,
+				Tail:      5,
+				Since:     10,
+				Until:     20,
+			},
+			expOut:    "1970-01-01T00:00:00.000000001Z out\n",
+			expErrOut: "1970-01-01T00:00:00.000000002Z err\n",
 		},
 	}
 
-	mockLocalClient := new(mocks.Client)
-	mockLocalClient.On("QueryMachines").Return([]db.Machine{{
-		CloudID:  targetMachine,
-		PublicIP: "machine",
-	}, {
-		PublicIP:  "container",
-		PrivateIP: "containerPriv",
-	}}, nil)
-	mockLocalClient.On("QueryContainers").Return([]db.Container{{
-		BlueprintID: targetContainer,
-		DockerID:    "foo",
-		Minion:      "containerPriv",
-	}}, nil)
-	mockLocalClient.On("Close").Return(nil)
-
 	for _, test := range tests {
 		testCmd := test.cmd
 
-		mockSSHClient := new(mockSSH.Client)
-		testCmd.sshGetter = func(host, key string) (ssh.Client, error) {
-			assert.Equal(t, test.expHost, host)
-			assert.Equal(t, "key", key)
-			return mockSSHClient, nil
-		}
-		testCmd.privateKey = "key"
-		testCmd.connectionHelper = connectionHelper{client: mockLocalClient}
+		mockClient := logsMockClient()
+		mockClient.On("Logs", mock.Anything, test.expReq, mock.Anything).Return(
+			mockLogs(pb.LogsReply{Timestamp: 1, Line: "out"},
+				pb.LogsReply{Timestamp: 2, Stderr: true, Line: "err"}))
+
+		var out, errOut bytes.Buffer
+		testCmd.out = &out
+		testCmd.errOut = &errOut
+		testCmd.connectionHelper = connectionHelper{client: mockClient}
+
+		assert.Equal(t, 0, testCmd.Run())
+		assert.Equal(t, test.expOut, out.String())
+		assert.Equal(t, test.expErrOut, errOut.String())
+		mockClient.AssertExpectations(t)
+	}
+}
 
-		mockSSHClient.On("Run", false, test.expSSHCommand).Return(nil)
-		mockSSHClient.On("Close").Return(nil)
+func TestLogPrefix(t *testing.T) {
+	t.Parallel()
 
-		testCmd.Run()
+	mockClient := logsMockClient()
+	mockClient.On("Logs", mock.Anything, pb.LogsRequest{
+		Host:      "container",
+		Container: "foo",
+	}, mock.Anything).Return(mockLogs(
+		pb.LogsReply{Timestamp: 1, Line: "ms1"},
+		pb.LogsReply{Timestamp: 3, Line: "ms2"}))
+	mockClient.On("Logs", mock.Anything, pb.LogsRequest{
+		Host:      "container",
+		Container: "bar",
+	}, mock.Anything).Return(mockLogs(
+		pb.LogsReply{Timestamp: 2, Line: "wk1"},
+		pb.LogsReply{Timestamp: 4, Stderr: true, Line: "wk2"}))
 
-		mockSSHClient.AssertExpectations(t)
+	var out, errOut bytes.Buffer
+	testCmd := Log{
+		target:           "spark",
+		matchPrefix:      true,
+		out:              &out,
+		errOut:           &errOut,
+		connectionHelper: connectionHelper{client: mockClient},
 	}
+	assert.Equal(t, 0, testCmd.Run())
+	assert.Equal(t, "spark-ms | ms1\nspark-wk | wk1\nspark-ms | ms2\n",
+		out.String())
+	assert.Equal(t, "spark-wk | wk2\n", errOut.String())
+	mockClient.AssertExpectations(t)
+
+	// The command should fail if no running containers match.
+	testCmd = Log{
+		target:           "spark-sch",
+		matchPrefix:      true,
+		connectionHelper: connectionHelper{client: mockClient},
+	}
+	assert.Equal(t, 1, testCmd.Run())
+}
+
+func TestLogError(t *testing.T) {
+	t.Parallel()
+
+	mockClient := logsMockClient()
+	mockClient.On("Logs", mock.Anything, mock.Anything, mock.Anything).Return(
+		errors.New("logs error"))
+
+	testCmd := Log{
+		target:           "1",
+		out:              &bytes.Buffer{},
+		errOut:           &bytes.Buffer{},
+		connectionHelper: connectionHelper{client: mockClient},
+	}
+	assert.Equal(t, 1, testCmd.Run())
 }
 
 func TestLogAmbiguousID(t *testing.T) {
@@ -0,0 +1,221 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/db"
+	keldaUtil "github.com/kelda/kelda/util"
+)
+
+// PortForward contains the options for forwarding local ports to containers.
+type PortForward struct {
+	target     string
+	address    string
+	localPort  int
+	remotePort int
+
+	connectionHelper
+}
+
+// NewPortForwardCommand creates a new PortForward command instance.
+func NewPortForwardCommand() *PortForward {
+	return &PortForward{}
+}
+
+var portForwardCommands = "kelda port-forward [OPTIONS] TARGET [LOCAL_PORT:]REMOTE_PORT"
+var portForwardExplanation = `Forward a local port to a container.
+
+TARGET is the hostname of a container, or the name of a load balancer.  Each
+connection to a load balancer is forwarded to one of its containers, chosen at
+random.
+
+Connections are tunneled through the Kelda daemon and the minion running the
+container, so the container's port doesn't need to be exposed to the public
+internet.
+
+To connect to port 5432 of the postgres container at localhost:8000:
+kelda port-forward postgres 8000:5432`
+
+// InstallFlags sets up parsing for command line flags.
+func (pCmd *PortForward) InstallFlags(flags *flag.FlagSet) {
+	pCmd.connectionHelper.InstallFlags(flags)
+	pCmd.installNamespaceFlag(flags)
+	flags.StringVar(&pCmd.address, "address", "127.0.0.1",
+		"the local address to listen on")
+
+	flags.Usage = func() {
+		keldaUtil.PrintUsageString(portForwardCommands,
+			portForwardExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the port-forward command.
+func (pCmd *PortForward) Parse(args []string) error {
+	if len(args) != 2 {
+		return errors.New("must specify a target and a port")
+	}
+
+	pCmd.target = args[0]
+
+	ports := strings.SplitN(args[1], ":", 2)
+	remotePort, err := parsePort(ports[len(ports)-1])
+	if err != nil {
+		return err
+	}
+
+	localPort := remotePort
+	if len(ports) == 2 {
+		if localPort, err = parsePort(ports[0]); err != nil {
+			return err
+		}
+	}
+
+	pCmd.localPort, pCmd.remotePort = localPort, remotePort
+	return nil
+}
+
+func parsePort(str string) (int, error) {
+	port, err := strconv.Atoi(str)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port: %q", str)
+	}
+	return port, nil
+}
+
+// A forwardBackend is a container that connections can be forwarded to.
+type forwardBackend struct {
+	host string
+	ip   string
+}
+
+// Run forwards connections to the local port until the command is killed.
+func (pCmd PortForward) Run() int {
+	if _, err := pCmd.backends(); err != nil {
+		log.WithError(err).Errorf("Failed to lookup %s", pCmd.target)
+		return 1
+	}
+
+	addr := net.JoinHostPort(pCmd.address, strconv.Itoa(pCmd.localPort))
+	listener, err := listen("tcp", addr)
+	if err != nil {
+		log.WithError(err).Error("Failed to listen")
+		return 1
+	}
+	defer listener.Close()
+
+	log.Infof("Forwarding %s to %s:%d", listener.Addr(), pCmd.target,
+		pCmd.remotePort)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.WithError(err).Error("Failed to accept connection")
+			return 1
+		}
+		go pCmd.forward(conn)
+	}
+}
+
+// forward tunnels `conn` to one of the target's containers.  The containers
+// are looked up for each connection, so that connections follow containers
+// as they're rescheduled.
+func (pCmd PortForward) forward(conn net.Conn) {
+	defer conn.Close()
+
+	backends, err := pCmd.backends()
+	if err != nil {
+		log.WithError(err).Errorf("Failed to lookup %s", pCmd.target)
+		return
+	}
+
+	backend := backends[rand.Intn(len(backends))]
+	req := pb.PortForwardRequest{
+		Host: backend.host,
+		IP:   backend.ip,
+		Port: int32(pCmd.remotePort),
+	}
+	err = pCmd.client.PortForward(context.Background(), req, conn)
+	if err != nil {
+		log.WithError(err).Warn("Failed to forward connection")
+	}
+}
+
+// backends returns the running containers that connections to the target may
+// be forwarded to.
+func (pCmd PortForward) backends() ([]forwardBackend, error) {
+	containers, err := pCmd.client.QueryContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	machines, err := pCmd.client.QueryMachines()
+	if err != nil {
+		return nil, err
+	}
+
+	hostnames := map[string]struct{}{}
+	for _, dbc := range containers {
+		if dbc.Hostname == pCmd.target {
+			hostnames[dbc.Hostname] = struct{}{}
+		}
+	}
+
+	if len(hostnames) == 0 {
+		loadBalancers, err := pCmd.client.QueryLoadBalancers()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lb := range loadBalancers {
+			if lb.Name != pCmd.target {
+				continue
+			}
+
+			for _, hostname := range lb.Hostnames {
+				hostnames[hostname] = struct{}{}
+			}
+		}
+	}
+
+	if len(hostnames) == 0 {
+		return nil, errors.New("no container or load balancer with that name")
+	}
+
+	publicIPs := map[string]string{}
+	for _, m := range machines {
+		publicIPs[m.PrivateIP] = m.PublicIP
+	}
+
+	var backends []forwardBackend
+	for _, dbc := range containers {
+		if _, ok := hostnames[dbc.Hostname]; !ok || !isRunning(dbc) {
+			continue
+		}
+
+		if host := publicIPs[dbc.Minion]; host != "" {
+			backends = append(backends,
+				forwardBackend{host: host, ip: dbc.IP})
+		}
+	}
+
+	if len(backends) == 0 {
+		return nil, errors.New("no running containers")
+	}
+	return backends, nil
+}
+
+func isRunning(dbc db.Container) bool {
+	return dbc.DockerID != "" && dbc.IP != "" && dbc.Minion != ""
+}
+
+// Stored in a variable so that it can be mocked out for unit tests.
+var listen = net.Listen
@@ -0,0 +1,160 @@
+package command
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/context"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/db"
+)
+
+func checkPortForwardParsing(t *testing.T, args []string, exp PortForward,
+	expErr error) {
+
+	pfCmd := NewPortForwardCommand()
+	err := parseHelper(pfCmd, args)
+
+	assert.Equal(t, expErr, err)
+	assert.Equal(t, exp.target, pfCmd.target)
+	assert.Equal(t, exp.localPort, pfCmd.localPort)
+	assert.Equal(t, exp.remotePort, pfCmd.remotePort)
+}
+
+func TestPortForwardFlags(t *testing.T) {
+	t.Parallel()
+
+	checkPortForwardParsing(t, []string{"postgres", "8000:5432"}, PortForward{
+		target:     "postgres",
+		localPort:  8000,
+		remotePort: 5432,
+	}, nil)
+	checkPortForwardParsing(t, []string{"postgres", "5432"}, PortForward{
+		target:     "postgres",
+		localPort:  5432,
+		remotePort: 5432,
+	}, nil)
+	checkPortForwardParsing(t, []string{"postgres", "0:5432"}, PortForward{
+		target: "postgres",
+	}, errors.New(`invalid port: "0"`))
+	checkPortForwardParsing(t, []string{"postgres", "8000:http"}, PortForward{
+		target: "postgres",
+	}, errors.New(`invalid port: "http"`))
+	checkPortForwardParsing(t, []string{"postgres"}, PortForward{},
+		errors.New("must specify a target and a port"))
+}
+
+func portForwardMockClient() *mocks.Client {
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return([]db.Machine{
+		{PublicIP: "host1", PrivateIP: "priv1"},
+		{PublicIP: "host2", PrivateIP: "priv2"},
+	}, nil)
+	mockClient.On("QueryContainers").Return([]db.Container{
+		{Hostname: "postgres", DockerID: "a", IP: "10.0.0.2",
+			Minion: "priv1"},
+		{Hostname: "web1", DockerID: "b", IP: "10.0.0.3", Minion: "priv2"},
+		{Hostname: "web2", DockerID: "c", IP: "10.0.0.4", Minion: "priv1"},
+		{Hostname: "web3", IP: "10.0.0.5"},
+		{Hostname: "scheduled", IP: "10.0.0.6"},
+	}, nil)
+	mockClient.On("QueryLoadBalancers").Return([]db.LoadBalancer{
+		{Name: "web", Hostnames: []string{"web1", "web2", "web3"}},
+	}, nil)
+	return mockClient
+}
+
+func TestPortForwardBackends(t *testing.T) {
+	t.Parallel()
+
+	mockClient := portForwardMockClient()
+	getBackends := func(target string) ([]forwardBackend, error) {
+		return PortForward{
+			target:           target,
+			connectionHelper: connectionHelper{client: mockClient},
+		}.backends()
+	}
+
+	backends, err := getBackends("postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, []forwardBackend{{host: "host1", ip: "10.0.0.2"}}, backends)
+
+	// Containers of load balancers that aren't running should be skipped.
+	backends, err = getBackends("web")
+	assert.NoError(t, err)
+	assert.Equal(t, []forwardBackend{
+		{host: "host2", ip: "10.0.0.3"},
+		{host: "host1", ip: "10.0.0.4"},
+	}, backends)
+
+	_, err = getBackends("scheduled")
+	assert.EqualError(t, err, "no running containers")
+
+	_, err = getBackends("missing")
+	assert.EqualError(t, err, "no container or load balancer with that name")
+}
+
+func TestPortForward(t *testing.T) {
+	var listener net.Listener
+	listening := make(chan struct{})
+	listen = func(network, addr string) (net.Listener, error) {
+		assert.Equal(t, "127.0.0.1:8000", addr)
+
+		var err error
+		listener, err = net.Listen(network, "127.0.0.1:0")
+		close(listening)
+		return listener, err
+	}
+	defer func() { listen = net.Listen }()
+
+	mockClient := portForwardMockClient()
+	mockClient.On("PortForward", mock.Anything, pb.PortForwardRequest{
+		Host: "host1",
+		IP:   "10.0.0.2",
+		Port: 5432,
+	}, mock.Anything).Return(
+		func(_ context.Context, _ pb.PortForwardRequest,
+			conn io.ReadWriter) error {
+			data, err := ioutil.ReadAll(conn)
+			if err != nil {
+				return err
+			}
+			_, err = conn.Write(append([]byte("pong:"), data...))
+			return err
+		})
+
+	done := make(chan int)
+	go func() {
+		done <- PortForward{
+			target:           "postgres",
+			address:          "127.0.0.1",
+			localPort:        8000,
+			remotePort:       5432,
+			connectionHelper: connectionHelper{client: mockClient},
+		}.Run()
+	}()
+
+	<-listening
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+
+	_, err = conn.Write([]byte("ping"))
+	assert.NoError(t, err)
+	conn.(*net.TCPConn).CloseWrite()
+
+	reply, err := ioutil.ReadAll(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, "pong:ping", string(reply))
+	conn.Close()
+
+	// The command should exit if the listener fails.
+	listener.Close()
+	assert.Equal(t, 1, <-done)
+}
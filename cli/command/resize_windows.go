@@ -0,0 +1,7 @@
+package command
+
+import "os"
+
+func setupResizeSignal(sig chan os.Signal) {
+	// Unimplemented
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/kelda/kelda/api/client"
 	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/cloud/cost"
 	"github.com/kelda/kelda/util"
 )
 
@@ -83,7 +85,7 @@ func (rCmd *Run) Run() int {
 	}
 	deployment := compiled.String()
 
-	curr, err := getCurrentDeployment(rCmd.client)
+	curr, err := getCurrentDeployment(rCmd.client, compiled.Namespace)
 	if err != nil && err != errNoBlueprint {
 		log.WithError(err).Error("Unable to get current deployment.")
 		return 1
@@ -100,6 +102,7 @@ func (rCmd *Run) Run() int {
 			fmt.Println("No change.")
 		} else {
 			fmt.Println(colorizeDiff(diff))
+			printCostChange(rCmd.client, compiled)
 		}
 		shouldDeploy, err := confirm(os.Stdin, "Continue with deployment?")
 		if err != nil {
@@ -125,18 +128,48 @@ func (rCmd *Run) Run() int {
 	return 0
 }
 
-func getCurrentDeployment(c client.Client) (blueprint.Blueprint, error) {
+// printCostChange prints how deploying `bp` would change the cost of the
+// deployment.  The estimate is only informational, so failures are ignored.
+func printCostChange(c client.Client, bp blueprint.Blueprint) {
+	machines, err := c.QueryMachines()
+	if err != nil {
+		log.WithError(err).Debug("Unable to estimate the deployment's cost.")
+		return
+	}
+
+	writeCostChange(os.Stdout, cost.Machines(namespaceMachines(machines,
+		bp.Namespace)), cost.Blueprint(bp))
+	fmt.Println()
+}
+
+// getCurrentDeployment returns the blueprint deployed in `namespace`.  If no
+// namespace is given, the daemon must manage at most one deployment.
+func getCurrentDeployment(c client.Client, namespace string) (
+	blueprint.Blueprint, error) {
 	blueprints, err := c.QueryBlueprints()
 	if err != nil {
 		return blueprint.Blueprint{}, err
 	}
-	switch len(blueprints) {
+
+	var namespaces []string
+	var matching []blueprint.Blueprint
+	for _, bp := range blueprints {
+		namespaces = append(namespaces, bp.Namespace)
+		if namespace == "" || bp.Namespace == namespace {
+			matching = append(matching, bp.Blueprint)
+		}
+	}
+
+	switch len(matching) {
 	case 0:
 		return blueprint.Blueprint{}, errNoBlueprint
 	case 1:
-		return blueprints[0].Blueprint, nil
+		return matching[0], nil
 	default:
-		panic("unreached")
+		sort.Strings(namespaces)
+		return blueprint.Blueprint{}, fmt.Errorf("the daemon manages "+
+			"multiple namespaces (%s); select one with -namespace",
+			strings.Join(namespaces, ", "))
 	}
 }
 
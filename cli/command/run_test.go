@@ -202,6 +202,7 @@ func TestPromptsUser(t *testing.T) {
 			Blueprint: blueprint.Blueprint{Namespace: "old"},
 		}}, nil)
 		c.On("Deploy", "{}").Return(nil)
+		c.On("QueryMachines").Return(nil, nil)
 
 		util.WriteFile("test.js", []byte(""), 0644)
 		runCmd := &Run{
@@ -218,6 +219,31 @@ func TestPromptsUser(t *testing.T) {
 	}
 }
 
+func TestGetCurrentDeployment(t *testing.T) {
+	t.Parallel()
+
+	c := new(clientMock.Client)
+	c.On("QueryBlueprints").Return(nil, nil).Once()
+	_, err := getCurrentDeployment(c, "")
+	assert.Equal(t, errNoBlueprint, err)
+
+	c.On("QueryBlueprints").Return([]db.Blueprint{
+		{Blueprint: blueprint.Blueprint{Namespace: "b"}},
+		{Blueprint: blueprint.Blueprint{Namespace: "a"}},
+	}, nil)
+
+	_, err = getCurrentDeployment(c, "")
+	assert.EqualError(t, err, "the daemon manages multiple namespaces (a, b); "+
+		"select one with -namespace")
+
+	bp, err := getCurrentDeployment(c, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", bp.Namespace)
+
+	_, err = getCurrentDeployment(c, "c")
+	assert.Equal(t, errNoBlueprint, err)
+}
+
 func TestRunFlags(t *testing.T) {
 	t.Parallel()
 
@@ -11,10 +11,13 @@ import (
 	"time"
 
 	units "github.com/docker/go-units"
+	log "github.com/sirupsen/logrus"
+
 	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/util"
 	"github.com/kelda/kelda/util/str"
+	"github.com/kelda/kelda/version"
 )
 
 // An arbitrary length to truncate container commands to.
@@ -38,6 +41,7 @@ var showExplanation = "Display the status of kelda-managed machines and containe
 // InstallFlags sets up parsing for command line flags
 func (pCmd *Show) InstallFlags(flags *flag.FlagSet) {
 	pCmd.connectionHelper.InstallFlags(flags)
+	pCmd.installNamespaceFlag(flags)
 	flags.BoolVar(&pCmd.noTruncate, "no-trunc", false, "do not truncate container"+
 		" command output")
 	flags.Usage = func() {
@@ -65,7 +69,14 @@ func (pCmd *Show) run() (err error) {
 		return fmt.Errorf("unable to query machines: %s", err)
 	}
 
-	writeMachines(os.Stdout, machines)
+	// The daemon's version is only used to report version skew, so the machines
+	// are still shown if it can't be retrieved.
+	daemonVersion, err := pCmd.client.Version()
+	if err != nil {
+		log.WithError(err).Debug("Failed to get the daemon's version")
+	}
+
+	writeMachines(os.Stdout, machines, daemonVersion)
 	fmt.Println()
 
 	clusterUp := false
@@ -121,10 +132,11 @@ func (pCmd *Show) run() (err error) {
 	return nil
 }
 
-func writeMachines(fd io.Writer, machines []db.Machine) {
+func writeMachines(fd io.Writer, machines []db.Machine, daemonVersion string) {
 	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
 	defer w.Flush()
-	fmt.Fprintln(w, "MACHINE\tROLE\tPROVIDER\tREGION\tSIZE\tPUBLIC IP\tSTATUS")
+	fmt.Fprintln(w, "MACHINE\tROLE\tPROVIDER\tREGION\tSIZE\tPUBLIC IP"+
+		"\tLABELS\tSTATUS")
 
 	for _, m := range db.SortMachines(machines) {
 		// Prefer the floating IP over the public IP if it's defined.
@@ -133,9 +145,58 @@ func writeMachines(fd io.Writer, machines []db.Machine) {
 			pubIP = m.FloatingIP
 		}
 
-		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
 			util.ShortUUID(m.CloudID), m.Role, m.Provider, m.Region,
-			m.Size, pubIP, m.Status)
+			m.Size, pubIP, machineLabels(m), machineStatus(m, daemonVersion))
+	}
+}
+
+// machineLabels returns the labels of `m` as a comma-separated list of
+// "key=value" pairs, sorted by key.
+func machineLabels(m db.Machine) string {
+	var labels []string
+	for key, value := range m.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
+
+// machineStatus returns the status of `m`, along with whether it's cordoned or
+// drained, whether its minion runs a different version than `daemonVersion`, and
+// the errors from its cloud provider if there are any.
+func machineStatus(m db.Machine, daemonVersion string) string {
+	var statuses []string
+	if m.Status != "" {
+		statuses = append(statuses, m.Status)
+	}
+
+	switch {
+	case m.Draining:
+		statuses = append(statuses, "drained")
+	case m.Unschedulable:
+		statuses = append(statuses, "cordoned")
+	}
+
+	switch {
+	case m.Upgrading:
+		statuses = append(statuses, "upgrading")
+	case m.Version == "" || daemonVersion == "" || m.Version == daemonVersion:
+	case version.Compatible(daemonVersion, m.Version):
+		statuses = append(statuses, "version "+m.Version)
+	default:
+		statuses = append(statuses, "incompatible version "+m.Version)
+	}
+
+	status := strings.Join(statuses, ", ")
+
+	switch {
+	case m.CloudError == "":
+		return status
+	case status == "":
+		return m.CloudError
+	default:
+		return fmt.Sprintf("%s (%s)", status, m.CloudError)
 	}
 }
 
@@ -222,11 +283,8 @@ func connToPorts(connections []db.Connection) map[string][]string {
 			continue
 		}
 
+		portStr := blueprint.PortsString(c.MinPort, c.MaxPort, c.Protocol)
 		for _, to := range c.To {
-			portStr := fmt.Sprintf("%d", c.MinPort)
-			if c.MinPort != c.MaxPort {
-				portStr += fmt.Sprintf("-%d", c.MaxPort)
-			}
 			hostnamePublicPorts[to] = append(hostnamePublicPorts[to],
 				portStr)
 		}
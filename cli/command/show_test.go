@@ -13,6 +13,7 @@ import (
 
 	"github.com/kelda/kelda/api/client/mocks"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/version"
 )
 
 func TestShowFlags(t *testing.T) {
@@ -44,6 +45,7 @@ func TestShowErrors(t *testing.T) {
 	mockClient.On("QueryMachines").Return([]db.Machine{{Status: db.Connected}}, nil)
 	mockClient.On("QueryContainers").Return(nil, mockErr)
 	mockClient.On("QueryImages").Return(nil, nil)
+	mockClient.On("Version").Return(version.Version, nil)
 	cmd := &Show{false, connectionHelper{client: mockClient}}
 	assert.EqualError(t, cmd.run(), "unable to query containers: error")
 
@@ -53,6 +55,7 @@ func TestShowErrors(t *testing.T) {
 	mockClient.On("QueryMachines").Return([]db.Machine{{Status: db.Connected}}, nil)
 	mockClient.On("QueryConnections").Return(nil, mockErr)
 	mockClient.On("QueryImages").Return(nil, nil)
+	mockClient.On("Version").Return(version.Version, nil)
 	cmd = &Show{false, connectionHelper{client: mockClient}}
 	assert.EqualError(t, cmd.run(), "unable to query connections: error")
 }
@@ -62,6 +65,7 @@ func TestMachineOnly(t *testing.T) {
 	t.Parallel()
 
 	mockClient := new(mocks.Client)
+	mockClient.On("Version").Return("", assert.AnError)
 	cmd := &Show{false, connectionHelper{client: mockClient}}
 
 	// Test failing to query machines.
@@ -89,6 +93,7 @@ func TestShowSuccess(t *testing.T) {
 	mockClient.On("QueryMachines").Return(nil, nil)
 	mockClient.On("QueryConnections").Return(nil, nil)
 	mockClient.On("QueryImages").Return(nil, nil)
+	mockClient.On("Version").Return(version.Version, nil)
 	cmd := &Show{false, connectionHelper{client: mockClient}}
 	assert.Equal(t, 0, cmd.Run())
 }
@@ -113,12 +118,14 @@ func TestMachineOutput(t *testing.T) {
 			Size:       "2gb",
 			PublicIP:   "9.9.9.9",
 			FloatingIP: "10.10.10.10",
+			Labels:     map[string]string{"team": "infra", "env": "prod"},
 			Status:     db.Connected,
+			Version:    "0.6.0",
 		},
 	}
 
 	var b bytes.Buffer
-	writeMachines(&b, machines)
+	writeMachines(&b, machines, "0.7.0")
 	result := string(b.Bytes())
 
 	/* By replacing space with underscore, we make the spaces explicit and whitespace
@@ -126,14 +133,48 @@ func TestMachineOutput(t *testing.T) {
 	result = strings.Replace(result, " ", "_", -1)
 
 	exp := `MACHINE____ROLE______PROVIDER________REGION_______SIZE` +
-		`________PUBLIC_IP______STATUS
-1__________Master____Amazon__________us-west-1____m4.large____8.8.8.8________connected
-2__________Worker____DigitalOcean____sfo1_________2gb_________10.10.10.10____connected
+		`________PUBLIC_IP______LABELS_________________STATUS
+1__________Master____Amazon__________us-west-1____m4.large____8.8.8.8________` +
+		`_______________________connected
+2__________Worker____DigitalOcean____sfo1_________2gb_________10.10.10.10____` +
+		`env=prod,team=infra____connected,_incompatible_version_0.6.0
 `
 
 	assert.Equal(t, exp, result)
 }
 
+func TestMachineStatus(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, db.Connected, machineStatus(db.Machine{Status: db.Connected}, ""))
+	assert.Equal(t, "booting (boot: insufficient capacity)",
+		machineStatus(db.Machine{
+			Status:     db.Booting,
+			CloudError: "boot: insufficient capacity",
+		}, ""))
+	assert.Equal(t, "list: auth failure",
+		machineStatus(db.Machine{CloudError: "list: auth failure"}, ""))
+
+	assert.Equal(t, "connected, cordoned", machineStatus(db.Machine{
+		Status: db.Connected, Unschedulable: true}, ""))
+	assert.Equal(t, "connected, drained", machineStatus(db.Machine{
+		Status: db.Connected, Unschedulable: true, Draining: true}, ""))
+	assert.Equal(t, "cordoned (list: auth failure)", machineStatus(db.Machine{
+		Unschedulable: true, CloudError: "list: auth failure"}, ""))
+
+	assert.Equal(t, "connected", machineStatus(db.Machine{
+		Status: db.Connected, Version: "0.7.0"}, "0.7.0"))
+	assert.Equal(t, "connected, version 0.7.1", machineStatus(db.Machine{
+		Status: db.Connected, Version: "0.7.1"}, "0.7.0"))
+	assert.Equal(t, "connected, incompatible version 0.6.0", machineStatus(
+		db.Machine{Status: db.Connected, Version: "0.6.0"}, "0.7.0"))
+	assert.Equal(t, "connected, cordoned, upgrading", machineStatus(db.Machine{
+		Status: db.Connected, Unschedulable: true, Version: "0.6.0",
+		Upgrading: true}, "0.7.0"))
+	assert.Equal(t, "connected", machineStatus(db.Machine{
+		Status: db.Connected, Version: "0.6.0"}, ""))
+}
+
 func checkContainerOutput(t *testing.T, containers []db.Container,
 	machines []db.Machine, connections []db.Connection, images []db.Image,
 	truncate bool, exp string) {
@@ -287,6 +328,21 @@ ________________________________________________________________________________
 		`CREATED____PUBLIC_IP
 3____________5__________image1_____frompub_____scheduled_______________` +
 		`7.7.7.7:[80,100-101]
+`
+	checkContainerOutput(t, containers, machines, connections, nil, true, expected)
+
+	// Connections with a protocol show it alongside their ports.
+	connections = []db.Connection{
+		{ID: 1, From: []string{"public"}, To: []string{"frompub"},
+			MinPort: 53, MaxPort: 53, Protocol: "udp"},
+		{ID: 2, From: []string{"public"}, To: []string{"frompub"},
+			Protocol: "icmp"},
+	}
+
+	expected = `CONTAINER____MACHINE____COMMAND____HOSTNAME____STATUS_______` +
+		`CREATED____PUBLIC_IP
+3____________5__________image1_____frompub_____scheduled_______________` +
+		`7.7.7.7:[53/udp,icmp]
 `
 	checkContainerOutput(t, containers, machines, connections, nil, true, expected)
 }
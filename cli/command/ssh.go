@@ -35,7 +35,8 @@ func NewSSHCommand() *SSH {
 var sshCommands = "kelda ssh [OPTIONS] ID [COMMAND]"
 var sshExplanation = `SSH into or execute a command in a machine or container.
 
-If no command is supplied, a login shell is created.
+If no command is supplied, a login shell is created.  Commands are run in
+containers through the Kelda API, so the private key is only used for machines.
 
 To login to machine 09ed35808a0b with a specific private key:
 kelda ssh -i ~/.ssh/kelda 09ed35808a0b
@@ -46,8 +47,9 @@ kelda ssh 8879fd2dbcee echo foo`
 // InstallFlags sets up parsing for command line flags.
 func (sCmd *SSH) InstallFlags(flags *flag.FlagSet) {
 	sCmd.connectionHelper.InstallFlags(flags)
+	sCmd.installNamespaceFlag(flags)
 	flags.StringVar(&sCmd.privateKey, "i", "",
-		"path to the private key to use when connecting to the host")
+		"path to the private key to use when connecting to a machine")
 	flags.BoolVar(&sCmd.allocatePTY, "t", false,
 		"attempt to allocate a pseudo-terminal")
 
@@ -81,37 +83,45 @@ func (sCmd SSH) Run() int {
 		return 1
 	}
 
-	sshClient, err := sCmd.sshGetter(host, sCmd.privateKey)
-	if err != nil {
-		log.WithError(err).Error("Failed to set up SSH connection")
-		return 1
-	}
-	defer sshClient.Close()
-
-	cmd := strings.Join(sCmd.args, " ")
-	shouldLogin := cmd == ""
-
 	switch t := i.(type) {
 	case db.Machine:
-		if shouldLogin {
-			err = sshClient.Shell()
-		} else {
-			err = sshClient.Run(sCmd.allocatePTY, cmd)
-		}
+		return sCmd.runOnMachine(host)
 	case db.Container:
 		if t.DockerID == "" {
 			log.Error("Container not yet running")
 			return 1
 		}
 
-		if shouldLogin {
-			err = containerExec(sshClient, t.DockerID, true, "sh")
-		} else {
-			err = containerExec(sshClient, t.DockerID, sCmd.allocatePTY, cmd)
+		cmd := sCmd.args
+		if len(cmd) == 0 {
+			cmd = []string{"sh"}
+		}
+
+		exitCode, err := containerExec(sCmd.client, host, t.DockerID,
+			allocatePTY, cmd)
+		if err != nil {
+			log.WithError(err).Error("Error running command")
+			return 1
 		}
+		return exitCode
 	default:
 		panic("Not Reached")
 	}
+}
+
+func (sCmd SSH) runOnMachine(host string) int {
+	sshClient, err := sCmd.sshGetter(host, sCmd.privateKey)
+	if err != nil {
+		log.WithError(err).Error("Failed to set up SSH connection")
+		return 1
+	}
+	defer sshClient.Close()
+
+	if len(sCmd.args) == 0 {
+		err = sshClient.Shell()
+	} else {
+		err = sshClient.Run(sCmd.allocatePTY, strings.Join(sCmd.args, " "))
+	}
 
 	if err != nil {
 		if exitErr, ok := err.(exitError); ok {
@@ -127,16 +137,6 @@ func (sCmd SSH) Run() int {
 	return 0
 }
 
-func containerExec(c ssh.Client, dockerID string, allocatePTY bool, cmd string) error {
-	var flags string
-	if allocatePTY {
-		flags = "-it"
-	}
-
-	command := strings.Join([]string{"docker exec", flags, dockerID, cmd}, " ")
-	return c.Run(allocatePTY, command)
-}
-
 var isTerminal = func() bool {
 	return terminal.IsTerminal(int(os.Stdout.Fd()))
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/api/pb"
 	"github.com/kelda/kelda/cli/ssh"
 	mockSSH "github.com/kelda/kelda/cli/ssh/mocks"
 	"github.com/kelda/kelda/db"
@@ -84,7 +85,6 @@ func TestSSHPTY(t *testing.T) {
 type sshTest struct {
 	cmd            SSH
 	machines       []db.Machine
-	containers     []db.Container
 	expHost        string
 	expUseShell    bool
 	expRunArgs     string
@@ -115,56 +115,6 @@ func TestSSH(t *testing.T) {
 			expHost:    "host",
 			expRunArgs: "foo bar",
 		},
-		// Container with login shell.
-		{
-			cmd: SSH{
-				privateKey: "key",
-				target:     "tgt",
-			},
-			machines: []db.Machine{{PrivateIP: "priv", PublicIP: "host"}},
-			containers: []db.Container{{
-				Minion:      "priv",
-				BlueprintID: "tgt",
-				DockerID:    "dockerID",
-			}},
-			expAllocatePTY: true,
-			expHost:        "host",
-			expRunArgs:     "docker exec -it dockerID sh",
-		},
-		// Container with exec.
-		{
-			cmd: SSH{
-				privateKey: "key",
-				target:     "tgt",
-				args:       []string{"foo", "bar"},
-			},
-			machines: []db.Machine{{PrivateIP: "priv", PublicIP: "host"}},
-			containers: []db.Container{{
-				Minion:      "priv",
-				BlueprintID: "tgt",
-				DockerID:    "dockerID",
-			}},
-			expHost:    "host",
-			expRunArgs: "docker exec  dockerID foo bar",
-		},
-		// Container with exec and PTY.
-		{
-			cmd: SSH{
-				privateKey:  "key",
-				target:      "tgt",
-				args:        []string{"foo", "bar"},
-				allocatePTY: true,
-			},
-			machines: []db.Machine{{PrivateIP: "priv", PublicIP: "host"}},
-			containers: []db.Container{{
-				Minion:      "priv",
-				BlueprintID: "tgt",
-				DockerID:    "dockerID",
-			}},
-			expAllocatePTY: true,
-			expHost:        "host",
-			expRunArgs:     "docker exec -it dockerID foo bar",
-		},
 	}
 	for _, test := range tests {
 		testCmd := test.cmd
@@ -186,7 +136,7 @@ func TestSSH(t *testing.T) {
 
 		mockClient := new(mocks.Client)
 		mockClient.On("QueryMachines").Return(test.machines, nil)
-		mockClient.On("QueryContainers").Return(test.containers, nil)
+		mockClient.On("QueryContainers").Return(nil, nil)
 		mockClient.On("Close").Return(nil)
 
 		testCmd.connectionHelper = connectionHelper{client: mockClient}
@@ -196,6 +146,99 @@ func TestSSH(t *testing.T) {
 	}
 }
 
+// Commands should be run in containers through the API rather than SSH.
+func TestSSHContainer(t *testing.T) {
+	isTerminal = func() bool { return true }
+	mockTerminal()
+
+	tests := []struct {
+		cmd    SSH
+		expReq pb.ExecRequest
+	}{
+		// Login shell.
+		{
+			cmd: SSH{target: "tgt"},
+			expReq: pb.ExecRequest{
+				Host:      "host",
+				Container: "dockerID",
+				Command:   []string{"sh"},
+				Tty:       true,
+			},
+		},
+		// Exec command.
+		{
+			cmd: SSH{target: "tgt", args: []string{"foo", "bar"}},
+			expReq: pb.ExecRequest{
+				Host:      "host",
+				Container: "dockerID",
+				Command:   []string{"foo", "bar"},
+			},
+		},
+		// Exec command with PTY.
+		{
+			cmd: SSH{
+				target:      "tgt",
+				args:        []string{"foo", "bar"},
+				allocatePTY: true,
+			},
+			expReq: pb.ExecRequest{
+				Host:      "host",
+				Container: "dockerID",
+				Command:   []string{"foo", "bar"},
+				Tty:       true,
+			},
+		},
+	}
+	for _, test := range tests {
+		testCmd := test.cmd
+		testCmd.sshGetter = func(string, string) (ssh.Client, error) {
+			t.Fatal("SSH shouldn't be used for containers")
+			return nil, nil
+		}
+
+		mockClient := new(mocks.Client)
+		mockClient.On("QueryMachines").Return([]db.Machine{{
+			PrivateIP: "priv",
+			PublicIP:  "host",
+		}}, nil)
+		mockClient.On("QueryContainers").Return([]db.Container{{
+			Minion:      "priv",
+			BlueprintID: "tgt",
+			DockerID:    "dockerID",
+		}}, nil)
+		mockClient.On("Exec", mock.Anything, test.expReq, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything).Return(0, nil)
+		testCmd.connectionHelper = connectionHelper{client: mockClient}
+
+		assert.Equal(t, 0, testCmd.Run())
+		mockClient.AssertExpectations(t)
+	}
+
+	// The command's exit code should be returned.
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return([]db.Machine{{
+		PrivateIP: "priv",
+		PublicIP:  "host",
+	}}, nil)
+	mockClient.On("QueryContainers").Return([]db.Container{{
+		Minion:      "priv",
+		BlueprintID: "tgt",
+		DockerID:    "dockerID",
+	}}, nil)
+	mockClient.On("Exec", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).Return(5, nil).Once()
+	mockClient.On("Exec", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).Return(0, assert.AnError)
+
+	testCmd := SSH{
+		connectionHelper: connectionHelper{client: mockClient},
+		target:           "tgt",
+		args:             []string{"false"},
+	}
+	assert.Equal(t, 5, testCmd.Run())
+	assert.Equal(t, 1, testCmd.Run())
+}
+
 func TestAmbiguousID(t *testing.T) {
 	mockClient := new(mocks.Client)
 	mockClient.On("QueryMachines").Return([]db.Machine{{CloudID: "foo"}}, nil)
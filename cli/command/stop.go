@@ -13,7 +13,6 @@ import (
 
 // Stop contains the options for stopping namespaces.
 type Stop struct {
-	namespace      string
 	onlyContainers bool
 	force          bool
 
@@ -31,8 +30,8 @@ var stopExplanation = `Stop a deployment.
 
 This will free all resources (e.g. VMs) associated with the deployment.
 
-If no namespace is specified, stop the deployment running in the namespace that is
-currently tracked by the daemon.
+If no namespace is specified, stop the deployment tracked by the daemon.  A
+namespace is required if the daemon manages multiple deployments.
 
 Confirmation is required, but can be skipped with the -f flag.`
 
@@ -65,7 +64,7 @@ func (sCmd *Stop) Run() int {
 		Namespace: sCmd.namespace,
 	}
 
-	currDepl, err := getCurrentDeployment(sCmd.client)
+	currDepl, err := getCurrentDeployment(sCmd.client, sCmd.namespace)
 	if err != nil && err != errNoBlueprint {
 		log.WithError(err).Error("Failed to get current cluster")
 		return 1
@@ -81,6 +80,12 @@ func (sCmd *Stop) Run() int {
 			return 1
 		}
 		newCluster.Machines = currDepl.Machines
+
+		// The machines keep running, so they keep their container network.
+		newCluster.ContainerSubnet = currDepl.ContainerSubnet
+		newCluster.GatewayIP = currDepl.GatewayIP
+		newCluster.LoadBalancerIP = currDepl.LoadBalancerIP
+		newCluster.ContainerSubnetIPv6 = currDepl.ContainerSubnetIPv6
 	}
 
 	// If the user is stopping the currently tracked namespace, inform the user of
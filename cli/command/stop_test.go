@@ -48,6 +48,23 @@ func TestStopNamespace(t *testing.T) {
 	c.AssertCalled(t, "Deploy", blueprint.Blueprint{Namespace: "namespace"}.String())
 }
 
+func TestStopMultipleNamespaces(t *testing.T) {
+	t.Parallel()
+
+	c := &clientMock.Client{}
+	c.On("QueryBlueprints").Return([]db.Blueprint{
+		{Blueprint: blueprint.Blueprint{Namespace: "b"}},
+		{Blueprint: blueprint.Blueprint{Namespace: "a"}},
+	}, nil)
+
+	// Without a namespace, it's unclear which deployment to stop.
+	stopCmd := NewStopCommand()
+	stopCmd.client = c
+	stopCmd.force = true
+	assert.Equal(t, 1, stopCmd.Run())
+	c.AssertNotCalled(t, "Deploy", mock.Anything)
+}
+
 func TestStopContainers(t *testing.T) {
 	t.Parallel()
 
@@ -58,7 +75,9 @@ func TestStopContainers(t *testing.T) {
 			Machines: []blueprint.Machine{
 				{Provider: "Amazon"},
 				{Provider: "Google"}},
-			Containers: []blueprint.Container{{}, {}}},
+			Containers:          []blueprint.Container{{}, {}},
+			ContainerSubnet:     "172.20.0.0/16",
+			ContainerSubnetIPv6: "fd00::/64"},
 	}}, nil)
 
 	c.On("Deploy", mock.Anything).Return(nil)
@@ -75,7 +94,9 @@ func TestStopContainers(t *testing.T) {
 			Provider: "Amazon",
 		}, {
 			Provider: "Google",
-		}}}.String())
+		}},
+		ContainerSubnet:     "172.20.0.0/16",
+		ContainerSubnetIPv6: "fd00::/64"}.String())
 
 }
 
@@ -84,21 +105,21 @@ func TestStopFlags(t *testing.T) {
 
 	expNamespace := "namespace"
 	checkStopParsing(t, []string{"-namespace", expNamespace},
-		Stop{namespace: expNamespace}, nil)
-	checkStopParsing(t, []string{"-f"}, Stop{force: true}, nil)
-	checkStopParsing(t, []string{"-f", expNamespace},
-		Stop{force: true, namespace: expNamespace}, nil)
-	checkStopParsing(t, []string{expNamespace}, Stop{namespace: expNamespace}, nil)
-	checkStopParsing(t, []string{}, Stop{}, nil)
+		expNamespace, false, nil)
+	checkStopParsing(t, []string{"-f"}, "", true, nil)
+	checkStopParsing(t, []string{"-f", expNamespace}, expNamespace, true, nil)
+	checkStopParsing(t, []string{expNamespace}, expNamespace, false, nil)
+	checkStopParsing(t, []string{}, "", false, nil)
 }
 
-func checkStopParsing(t *testing.T, args []string, expFlags Stop, expErr error) {
+func checkStopParsing(t *testing.T, args []string, expNamespace string,
+	expForce bool, expErr error) {
 	stopCmd := NewStopCommand()
 	err := parseHelper(stopCmd, args)
 
 	assert.Equal(t, expErr, err)
-	assert.Equal(t, expFlags.namespace, stopCmd.namespace)
-	assert.Equal(t, expFlags.force, stopCmd.force)
+	assert.Equal(t, expNamespace, stopCmd.namespace)
+	assert.Equal(t, expForce, stopCmd.force)
 }
 
 func TestStopPromptsUser(t *testing.T) {
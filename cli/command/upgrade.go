@@ -0,0 +1,92 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/util"
+)
+
+// Upgrade contains the options for upgrading the minions of a deployment.
+type Upgrade struct {
+	connectionHelper
+}
+
+// NewUpgradeCommand creates a new Upgrade command instance.
+func NewUpgradeCommand() *Upgrade {
+	return &Upgrade{}
+}
+
+var upgradeCommands = "kelda upgrade [OPTIONS]"
+var upgradeExplanation = `Upgrade the minions of a deployment to the daemon's
+version.
+
+Machines are upgraded one at a time, masters first.  Each machine's minion is
+restarted with the daemon's image, and the next machine isn't upgraded until
+the minion reconnects with the new version.  Containers keep running while
+their minion restarts.  Use ` + "`kelda show`" + ` to follow the progress of the
+upgrade.`
+
+// InstallFlags sets up parsing for command line flags.
+func (uCmd *Upgrade) InstallFlags(flags *flag.FlagSet) {
+	uCmd.connectionHelper.InstallFlags(flags)
+	uCmd.installNamespaceFlag(flags)
+	flags.Usage = func() {
+		util.PrintUsageString(upgradeCommands, upgradeExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the upgrade command.
+func (uCmd *Upgrade) Parse(args []string) error {
+	if len(args) > 0 {
+		return errors.New("upgrade takes no arguments")
+	}
+	return nil
+}
+
+// Run starts upgrading the deployment's minions.
+func (uCmd *Upgrade) Run() int {
+	if err := uCmd.run(os.Stdout); err != nil {
+		log.WithError(err).Error("Failed to upgrade the deployment")
+		return 1
+	}
+	return 0
+}
+
+func (uCmd *Upgrade) run(out io.Writer) error {
+	daemonVersion, err := uCmd.client.Version()
+	if err != nil {
+		return fmt.Errorf("get daemon version: %s", err)
+	}
+
+	machines, err := uCmd.client.QueryMachines()
+	if err != nil {
+		return fmt.Errorf("query machines: %s", err)
+	}
+
+	var outdated int
+	for _, m := range machines {
+		if m.Version != "" && m.Version != daemonVersion {
+			outdated++
+		}
+	}
+	if outdated == 0 {
+		fmt.Fprintf(out, "All minions already run version %s.\n", daemonVersion)
+		return nil
+	}
+
+	err = uCmd.client.Upgrade(pb.UpgradeRequest{Namespace: uCmd.namespace})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Upgrading %d machines to version %s, one at a time.\n"+
+		"Run `kelda show` to follow the upgrade.\n", outdated, daemonVersion)
+	return nil
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/db"
+)
+
+func TestUpgradeFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewUpgradeCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-namespace", "ns"}))
+	assert.Equal(t, "ns", cmd.namespace)
+
+	assert.EqualError(t, parseHelper(NewUpgradeCommand(), []string{"arg"}),
+		"upgrade takes no arguments")
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Parallel()
+
+	c := new(mocks.Client)
+	c.On("Version").Return("0.7.0", nil)
+	c.On("QueryMachines").Return([]db.Machine{
+		{Version: "0.6.0"},
+		{Version: "0.7.0"},
+		{Version: "0.7.1"},
+		{},
+	}, nil).Once()
+	c.On("Upgrade", pb.UpgradeRequest{Namespace: "ns"}).Return(nil).Once()
+	cmd := NewUpgradeCommand()
+	cmd.client = c
+	cmd.namespace = "ns"
+
+	var out bytes.Buffer
+	assert.NoError(t, cmd.run(&out))
+	c.AssertExpectations(t)
+	assert.Equal(t, "Upgrading 2 machines to version 0.7.0, one at a time.\n"+
+		"Run `kelda show` to follow the upgrade.\n", out.String())
+
+	// Nothing needs to be upgraded.
+	c.On("QueryMachines").Return([]db.Machine{{Version: "0.7.0"}}, nil).Once()
+	out.Reset()
+	assert.NoError(t, cmd.run(&out))
+	c.AssertNumberOfCalls(t, "Upgrade", 1)
+	assert.Equal(t, "All minions already run version 0.7.0.\n", out.String())
+
+	c.On("QueryMachines").Return([]db.Machine{{Version: "0.6.0"}}, nil).Once()
+	c.On("Upgrade", pb.UpgradeRequest{Namespace: "ns"}).Return(
+		assert.AnError).Once()
+	assert.Equal(t, assert.AnError, cmd.run(&out))
+}
@@ -1,10 +1,24 @@
 package acl
 
-// ACL represents allowed traffic to a machine.
+import (
+	"strings"
+
+	"github.com/kelda/kelda/blueprint"
+)
+
+// ACL represents allowed traffic to a machine.  ACLs without a protocol allow both
+// TCP and UDP on their port range.  ICMP is always allowed from CidrIP so that
+// health pings work.
 type ACL struct {
-	CidrIP  string
-	MinPort int
-	MaxPort int
+	CidrIP   string
+	MinPort  int
+	MaxPort  int
+	Protocol string
+}
+
+// PortProtocols returns the protocols that the ACL allows on its port range.
+func (acl ACL) PortProtocols() []string {
+	return blueprint.PortProtocols(acl.Protocol)
 }
 
 // Slice is an alias for []ACL to allow for joins
@@ -19,3 +33,16 @@ func (slc Slice) Get(ii int) interface{} {
 func (slc Slice) Len() int {
 	return len(slc)
 }
+
+// IsIPv6 returns whether the given CIDR is an IPv6 range.
+func IsIPv6(cidr string) bool {
+	return strings.Contains(cidr, ":")
+}
+
+// HostCIDR returns the CIDR that matches exactly the given IP address.
+func HostCIDR(ip string) string {
+	if IsIPv6(ip) {
+		return ip + "/128"
+	}
+	return ip + "/32"
+}
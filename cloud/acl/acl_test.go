@@ -7,9 +7,27 @@ import (
 )
 
 func TestSlice(t *testing.T) {
-	acl := ACL{"1.2.3.4", 1, 2}
+	acl := ACL{CidrIP: "1.2.3.4", MinPort: 1, MaxPort: 2}
 	slice := Slice([]ACL{acl})
 
 	assert.Equal(t, slice.Len(), 1)
 	assert.Equal(t, slice.Get(0), acl)
 }
+
+func TestPortProtocols(t *testing.T) {
+	assert.Equal(t, []string{"tcp", "udp"}, ACL{}.PortProtocols())
+	assert.Equal(t, []string{"udp"}, ACL{Protocol: "udp"}.PortProtocols())
+	assert.Empty(t, ACL{Protocol: "icmp"}.PortProtocols())
+}
+
+func TestIsIPv6(t *testing.T) {
+	assert.False(t, IsIPv6("1.2.3.4/32"))
+	assert.False(t, IsIPv6("0.0.0.0/0"))
+	assert.True(t, IsIPv6("::/0"))
+	assert.True(t, IsIPv6("2001:db8::/32"))
+}
+
+func TestHostCIDR(t *testing.T) {
+	assert.Equal(t, "1.2.3.4/32", HostCIDR("1.2.3.4"))
+	assert.Equal(t, "2001:db8::1/128", HostCIDR("2001:db8::1"))
+}
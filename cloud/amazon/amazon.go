@@ -2,8 +2,11 @@ package amazon
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,9 +15,11 @@ import (
 	"github.com/kelda/kelda/cloud/cfg"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/join"
+	"github.com/kelda/kelda/util/str"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
 	log "github.com/sirupsen/logrus"
@@ -26,11 +31,17 @@ type Provider struct {
 
 	namespace string
 	region    string
+
+	// The Ubuntu image that machines boot by default, and when it should be
+	// looked up again.
+	ubuntuImage       string
+	ubuntuImageExpiry time.Time
 }
 
 type awsMachine struct {
 	instanceID string
 	spotID     string
+	volumeID   string
 
 	machine db.Machine
 }
@@ -39,19 +50,22 @@ const (
 	// DefaultRegion is the preferred location for machines that don't have a
 	// user specified region preference.
 	DefaultRegion = "us-west-1"
-
-	spotPrice = "0.5"
 )
 
-// Regions is the list of supported AWS regions.
-var Regions = []string{"ap-southeast-2", "us-west-1", "us-west-2"}
+// Regions is the list of supported AWS regions: every region in the standard
+// AWS partition that offers EC2.
+var Regions = ec2Regions()
 
-// Ubuntu 16.04, 64-bit hvm:ebs-ssd
-var amis = map[string]string{
-	"ap-southeast-2": "ami-943d3bf7",
-	"us-west-1":      "ami-79df8219",
-	"us-west-2":      "ami-d206bdb2",
-}
+// The account that publishes the official Ubuntu images, and the name of the
+// Ubuntu 16.04, 64-bit hvm:ebs-ssd images.
+const (
+	canonicalOwner  = "099720109477"
+	ubuntuImageName = "ubuntu/images/hvm-ssd/ubuntu-xenial-16.04-amd64-server-*"
+)
+
+// How long the Ubuntu image of a region is cached.  Canonical regularly
+// publishes images with updated packages, which new machines should pick up.
+const ubuntuImageTTL = 24 * time.Hour
 
 var timeout = 5 * time.Minute
 
@@ -95,9 +109,14 @@ func newAmazon(namespace, region string) *Provider {
 type bootReq struct {
 	groupID     string
 	cfg         string
+	image       string
 	size        string
 	diskSize    int
 	preemptible bool
+	maxPrice    float64
+
+	// The JSON encoding of the machine's labels, as bootReq is a map key.
+	labels string
 }
 
 // Boot creates instances in the `prvdr` configured according to the `bootSet`.
@@ -113,12 +132,31 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) ([]string, error) {
 
 	bootReqMap := make(map[bootReq]int64) // From boot request to an instance count.
 	for _, m := range bootSet {
+		image := m.Image
+		if image == "" {
+			image, err = prvdr.getUbuntuImage()
+			if err != nil {
+				return nil, fmt.Errorf("find Ubuntu image: %s", err)
+			}
+		}
+
+		var labels []byte
+		if len(m.Labels) > 0 {
+			labels, err = json.Marshal(m.Labels)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		br := bootReq{
 			groupID:     groupID,
-			cfg:         cfg.Ubuntu(m, ""),
+			cfg:         cfg.BootScript(m, ""),
+			image:       image,
 			size:        m.Size,
 			diskSize:    m.DiskSize,
 			preemptible: m.Preemptible,
+			maxPrice:    m.MaxPrice,
+			labels:      string(labels),
 		}
 		bootReqMap[br] = bootReqMap[br] + 1
 	}
@@ -142,10 +180,60 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) ([]string, error) {
 	return ids, nil
 }
 
+// tags returns the tags that should be applied to the resources booted for
+// `br`.
+func (br bootReq) tags() ([]*ec2.Tag, error) {
+	if br.labels == "" {
+		return nil, nil
+	}
+
+	var labels map[string]string
+	if err := json.Unmarshal([]byte(br.labels), &labels); err != nil {
+		return nil, fmt.Errorf("parse labels: %s", err)
+	}
+	return ec2Tags(labels), nil
+}
+
+// getUbuntuImage returns the newest Ubuntu 16.04 image in the region.
+func (prvdr *Provider) getUbuntuImage() (string, error) {
+	if prvdr.ubuntuImage != "" && now().Before(prvdr.ubuntuImageExpiry) {
+		return prvdr.ubuntuImage, nil
+	}
+
+	images, err := prvdr.DescribeImages([]string{canonicalOwner}, []*ec2.Filter{
+		{
+			Name:   aws.String("name"),
+			Values: []*string{aws.String(ubuntuImageName)},
+		}, {
+			Name:   aws.String("state"),
+			Values: []*string{aws.String(ec2.ImageStateAvailable)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Creation dates are in ISO 8601 format, so they sort lexicographically.
+	var newest *ec2.Image
+	for _, image := range images {
+		if newest == nil || resolveString(image.CreationDate) >
+			resolveString(newest.CreationDate) {
+			newest = image
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("no images match %s", ubuntuImageName)
+	}
+
+	prvdr.ubuntuImage = resolveString(newest.ImageId)
+	prvdr.ubuntuImageExpiry = now().Add(ubuntuImageTTL)
+	return prvdr.ubuntuImage, nil
+}
+
 func (prvdr *Provider) bootReserved(br bootReq, count int64) ([]string, error) {
 	cloudConfig64 := base64.StdEncoding.EncodeToString([]byte(br.cfg))
-	resp, err := prvdr.RunInstances(&ec2.RunInstancesInput{
-		ImageId:          aws.String(amis[prvdr.region]),
+	input := &ec2.RunInstancesInput{
+		ImageId:          aws.String(br.image),
 		InstanceType:     aws.String(br.size),
 		UserData:         &cloudConfig64,
 		SecurityGroupIds: []*string{aws.String(br.groupID)},
@@ -153,7 +241,23 @@ func (prvdr *Provider) bootReserved(br bootReq, count int64) ([]string, error) {
 			blockDevice(br.diskSize)},
 		MaxCount: &count,
 		MinCount: &count,
-	})
+	}
+
+	tags, err := br.tags()
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		input.TagSpecifications = []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags:         tags,
+		}, {
+			ResourceType: aws.String(ec2.ResourceTypeVolume),
+			Tags:         tags,
+		}}
+	}
+
+	resp, err := prvdr.RunInstances(input)
 	if err != nil {
 		return nil, err
 	}
@@ -166,10 +270,21 @@ func (prvdr *Provider) bootReserved(br bootReq, count int64) ([]string, error) {
 }
 
 func (prvdr *Provider) bootSpot(br bootReq, count int64) ([]string, error) {
+	tags, err := br.tags()
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a max price, Amazon caps the bid at the on-demand price.
+	var spotPrice string
+	if br.maxPrice != 0 {
+		spotPrice = strconv.FormatFloat(br.maxPrice, 'f', -1, 64)
+	}
+
 	cloudConfig64 := base64.StdEncoding.EncodeToString([]byte(br.cfg))
 	spots, err := prvdr.RequestSpotInstances(spotPrice, count,
 		&ec2.RequestSpotLaunchSpecification{
-			ImageId:          aws.String(amis[prvdr.region]),
+			ImageId:          aws.String(br.image),
 			InstanceType:     aws.String(br.size),
 			UserData:         &cloudConfig64,
 			SecurityGroupIds: []*string{aws.String(br.groupID)},
@@ -183,6 +298,14 @@ func (prvdr *Provider) bootSpot(br bootReq, count int64) ([]string, error) {
 	for _, request := range spots {
 		ids = append(ids, *request.SpotInstanceRequestId)
 	}
+
+	// Spot requests can't be tagged when they're made.  Their instances are
+	// tagged by List once they launch.
+	if len(tags) > 0 {
+		if err := prvdr.CreateTags(ids, tags); err != nil {
+			return nil, fmt.Errorf("tag spot requests: %s", err)
+		}
+	}
 	return ids, nil
 }
 
@@ -262,11 +385,23 @@ func (prvdr *Provider) listSpots() (machines []awsMachine, err error) {
 	}
 
 	for _, spot := range spots {
+		var maxPrice float64
+		if spot.SpotPrice != nil {
+			maxPrice, err = strconv.ParseFloat(*spot.SpotPrice, 64)
+			if err != nil {
+				log.WithError(err).Warn("Failed to parse spot price.")
+			}
+		}
+
 		machines = append(machines, awsMachine{
 			spotID: resolveString(spot.SpotInstanceRequestId),
 			machine: db.Machine{
 				Size: resolveString(spot.LaunchSpecification.
 					InstanceType),
+				Image: resolveString(spot.LaunchSpecification.
+					ImageId),
+				MaxPrice: maxPrice,
+				Labels:   parseTags(spot.Tags),
 			},
 		})
 	}
@@ -337,16 +472,26 @@ func (prvdr *Provider) listInstances() (instances []awsMachine, err error) {
 				floatingIP = *ip.PublicIp
 			}
 
+			var volumeID string
+			if len(inst.BlockDeviceMappings) > 0 &&
+				inst.BlockDeviceMappings[0].Ebs != nil {
+				volumeID = resolveString(
+					inst.BlockDeviceMappings[0].Ebs.VolumeId)
+			}
+
 			instances = append(instances, awsMachine{
 				instanceID: resolveString(inst.InstanceId),
 				spotID: resolveString(
 					inst.SpotInstanceRequestId),
+				volumeID: volumeID,
 				machine: db.Machine{
 					PublicIP:   resolveString(inst.PublicIpAddress),
 					PrivateIP:  resolveString(inst.PrivateIpAddress),
 					FloatingIP: floatingIP,
 					Size:       resolveString(inst.InstanceType),
 					DiskSize:   diskSize,
+					Image:      resolveString(inst.ImageId),
+					Labels:     parseTags(inst.Tags),
 				},
 			})
 		}
@@ -377,7 +522,15 @@ func (prvdr *Provider) List() (machines []db.Machine, err error) {
 		awsMachines = append(awsMachines, mIntf.(awsMachine))
 	}
 	for _, pair := range bootedSpots {
-		awsMachines = append(awsMachines, pair.R.(awsMachine))
+		// Only the spot request knows the price it was bid at.
+		spot, awsm := pair.L.(awsMachine), pair.R.(awsMachine)
+		awsm.machine.MaxPrice = spot.machine.MaxPrice
+
+		if !str.MapContains(awsm.machine.Labels, spot.machine.Labels) {
+			prvdr.tagSpotInstance(awsm, spot.machine.Labels)
+		}
+		awsm.machine.Labels = spot.machine.Labels
+		awsMachines = append(awsMachines, awsm)
 	}
 	for _, mIntf := range nonbootedSpots {
 		awsMachines = append(awsMachines, mIntf.(awsMachine))
@@ -397,6 +550,20 @@ func (prvdr *Provider) List() (machines []db.Machine, err error) {
 	return machines, nil
 }
 
+// tagSpotInstance applies the labels of a spot request to the instance that it
+// launched, and the instance's disk.
+func (prvdr *Provider) tagSpotInstance(awsm awsMachine, labels map[string]string) {
+	ids := []string{awsm.instanceID}
+	if awsm.volumeID != "" {
+		ids = append(ids, awsm.volumeID)
+	}
+
+	if err := prvdr.CreateTags(ids, ec2Tags(labels)); err != nil {
+		log.WithError(err).WithField("instance", awsm.instanceID).Warn(
+			"Failed to tag spot instance.")
+	}
+}
+
 // UpdateFloatingIPs updates Elastic IPs <> EC2 instance associations.
 func (prvdr *Provider) UpdateFloatingIPs(machines []db.Machine) error {
 	addrs, err := prvdr.DescribeAddresses()
@@ -443,12 +610,58 @@ func (prvdr *Provider) UpdateFloatingIPs(machines []db.Machine) error {
 			if err != nil {
 				return err
 			}
+
+			if len(machine.Labels) > 0 {
+				err := prvdr.CreateTags([]string{allocationID},
+					ec2Tags(machine.Labels))
+				if err != nil {
+					return fmt.Errorf("tag %s: %s",
+						machine.FloatingIP, err)
+				}
+			}
 		}
 	}
 
 	return nil
 }
 
+// UpdateLabels tags the instances of `machines`, and their volumes, with the
+// machines' labels.  Preemptible machines are identified by their spot requests,
+// so the spot requests are tagged instead, and List copies the tags to their
+// instances.
+func (prvdr *Provider) UpdateLabels(machines []db.Machine) error {
+	var volumeIDs map[string]string
+	for _, m := range machines {
+		if len(m.Labels) == 0 {
+			continue
+		}
+
+		ids := []string{m.CloudID}
+		if !m.Preemptible {
+			if volumeIDs == nil {
+				instances, err := prvdr.listInstances()
+				if err != nil {
+					return err
+				}
+
+				volumeIDs = map[string]string{}
+				for _, awsm := range instances {
+					volumeIDs[awsm.instanceID] = awsm.volumeID
+				}
+			}
+
+			if volumeID := volumeIDs[m.CloudID]; volumeID != "" {
+				ids = append(ids, volumeID)
+			}
+		}
+
+		if err := prvdr.CreateTags(ids, ec2Tags(m.Labels)); err != nil {
+			return fmt.Errorf("tag %s: %s", m.CloudID, err)
+		}
+	}
+	return nil
+}
+
 func (prvdr Provider) getInstanceID(spotID string) (string, error) {
 	spots, err := prvdr.DescribeSpotInstanceRequests([]string{spotID}, nil)
 	if err != nil {
@@ -500,6 +713,31 @@ func (prvdr *Provider) SetACLs(acls []acl.ACL) error {
 	return nil
 }
 
+// SetLabels tags the namespace's security group with `labels`.  Tags that aren't
+// in `labels` are left alone, as they may have been added outside of Kelda.
+func (prvdr *Provider) SetLabels(labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	groups, err := prvdr.DescribeSecurityGroup(prvdr.namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		if str.MapContains(parseTags(group.Tags), labels) {
+			continue
+		}
+
+		err := prvdr.CreateTags([]string{*group.GroupId}, ec2Tags(labels))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (prvdr *Provider) getCreateSecurityGroup() (
 	string, []*ec2.IpPermission, error) {
 
@@ -520,7 +758,8 @@ func (prvdr *Provider) getCreateSecurityGroup() (
 
 // syncACLs returns the permissions that need to be removed and added in order
 // for the cloud ACLs to match the policy.
-// rangesToAdd is guaranteed to always have exactly one item in the IpRanges slice.
+// Each permission in rangesToAdd is guaranteed to have exactly one item in either
+// the IpRanges or the Ipv6Ranges slice.
 func syncACLs(desiredACLs []acl.ACL, desiredGroupID string,
 	current []*ec2.IpPermission) (rangesToAdd []*ec2.IpPermission, foundGroup bool,
 	toRemove []*ec2.IpPermission) {
@@ -537,6 +776,16 @@ func syncACLs(desiredACLs []acl.ACL, desiredGroupID string,
 				},
 			})
 		}
+		for _, ipRange := range perm.Ipv6Ranges {
+			currRangeRules = append(currRangeRules, &ec2.IpPermission{
+				IpProtocol: perm.IpProtocol,
+				FromPort:   perm.FromPort,
+				ToPort:     perm.ToPort,
+				Ipv6Ranges: []*ec2.Ipv6Range{
+					ipRange,
+				},
+			})
+		}
 		for _, pair := range perm.UserIdGroupPairs {
 			if *pair.GroupId != desiredGroupID {
 				toRemove = append(toRemove, &ec2.IpPermission{
@@ -551,35 +800,22 @@ func syncACLs(desiredACLs []acl.ACL, desiredGroupID string,
 	}
 
 	var desiredRangeRules []*ec2.IpPermission
-	for _, acl := range desiredACLs {
-		desiredRangeRules = append(desiredRangeRules, &ec2.IpPermission{
-			FromPort: aws.Int64(int64(acl.MinPort)),
-			ToPort:   aws.Int64(int64(acl.MaxPort)),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String(acl.CidrIP),
-				},
-			},
-			IpProtocol: aws.String("tcp"),
-		}, &ec2.IpPermission{
-			FromPort: aws.Int64(int64(acl.MinPort)),
-			ToPort:   aws.Int64(int64(acl.MaxPort)),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String(acl.CidrIP),
-				},
-			},
-			IpProtocol: aws.String("udp"),
-		}, &ec2.IpPermission{
-			FromPort: aws.Int64(-1),
-			ToPort:   aws.Int64(-1),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String(acl.CidrIP),
-				},
-			},
-			IpProtocol: aws.String("icmp"),
-		})
+	for _, rule := range desiredACLs {
+		minPort, maxPort := int64(rule.MinPort), int64(rule.MaxPort)
+		icmp := "icmp"
+		if acl.IsIPv6(rule.CidrIP) {
+			icmp = "icmpv6"
+		}
+
+		for _, protocol := range rule.PortProtocols() {
+			desiredRangeRules = append(desiredRangeRules,
+				rangePermission(rule.CidrIP, protocol, minPort, maxPort))
+		}
+
+		// Rules shared by several ACLs, such as ICMP, are deduplicated by
+		// the join below.
+		desiredRangeRules = append(desiredRangeRules,
+			rangePermission(rule.CidrIP, icmp, -1, -1))
 	}
 
 	_, toAdd, rangesToRemove := join.HashJoin(ipPermSlice(desiredRangeRules),
@@ -594,6 +830,34 @@ func syncACLs(desiredACLs []acl.ACL, desiredGroupID string,
 	return rangesToAdd, foundGroup, toRemove
 }
 
+// rangePermission returns a permission allowing `protocol` traffic from `cidr`.
+// IPv6 CIDRs are placed in the Ipv6Ranges slice, as EC2 requires.
+func rangePermission(cidr, protocol string, minPort, maxPort int64) *ec2.IpPermission {
+	perm := &ec2.IpPermission{
+		FromPort:   aws.Int64(minPort),
+		ToPort:     aws.Int64(maxPort),
+		IpProtocol: aws.String(protocol),
+	}
+	if acl.IsIPv6(cidr) {
+		perm.Ipv6Ranges = []*ec2.Ipv6Range{{CidrIpv6: aws.String(cidr)}}
+	} else {
+		perm.IpRanges = []*ec2.IpRange{{CidrIp: aws.String(cidr)}}
+	}
+	return perm
+}
+
+// permissionCIDR returns the IP range of a permission created by syncACLs, or
+// the empty string if it refers to a security group instead.
+func permissionCIDR(perm *ec2.IpPermission) string {
+	if len(perm.IpRanges) != 0 && perm.IpRanges[0].CidrIp != nil {
+		return *perm.IpRanges[0].CidrIp
+	}
+	if len(perm.Ipv6Ranges) != 0 && perm.Ipv6Ranges[0].CidrIpv6 != nil {
+		return *perm.Ipv6Ranges[0].CidrIpv6
+	}
+	return ""
+}
+
 func logACLs(add bool, perms []*ec2.IpPermission) {
 	action := "Remove"
 	if add {
@@ -601,7 +865,7 @@ func logACLs(add bool, perms []*ec2.IpPermission) {
 	}
 
 	for _, perm := range perms {
-		if len(perm.IpRanges) != 0 {
+		if cidrIP := permissionCIDR(perm); cidrIP != "" {
 			// Each rule has three variants (TCP, UDP, and ICMP), but
 			// we only want to log once.
 			protocol := *perm.IpProtocol
@@ -609,7 +873,6 @@ func logACLs(add bool, perms []*ec2.IpPermission) {
 				continue
 			}
 
-			cidrIP := *perm.IpRanges[0].CidrIp
 			ports := fmt.Sprintf("%d", *perm.FromPort)
 			if *perm.FromPort != *perm.ToPort {
 				ports += fmt.Sprintf("-%d", *perm.ToPort)
@@ -660,6 +923,42 @@ func blockDevice(diskSize int) *ec2.BlockDeviceMapping {
 	}
 }
 
+// ec2Tags converts `labels` into tags, sorted by key.
+func ec2Tags(labels map[string]string) []*ec2.Tag {
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tags []*ec2.Tag
+	for _, key := range keys {
+		tags = append(tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(labels[key]),
+		})
+	}
+	return tags
+}
+
+// parseTags converts `tags` into labels, or returns nil if there are none.  Tags
+// reserved by Amazon are ignored.
+func parseTags(tags []*ec2.Tag) map[string]string {
+	var labels map[string]string
+	for _, tag := range tags {
+		key := resolveString(tag.Key)
+		if strings.HasPrefix(key, "aws:") {
+			continue
+		}
+
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[key] = resolveString(tag.Value)
+	}
+	return labels
+}
+
 func resolveString(ptr *string) string {
 	if ptr == nil {
 		return ""
@@ -701,10 +1000,13 @@ func permToACLKey(permIntf interface{}) interface{} {
 		key.protocol = *perm.IpProtocol
 	}
 
-	if perm.IpRanges[0].CidrIp != nil {
-		key.ipRange = *perm.IpRanges[0].CidrIp
+	// EC2 may report ICMPv6 rules by their protocol number.
+	if key.protocol == "58" {
+		key.protocol = "icmpv6"
 	}
 
+	key.ipRange = permissionCIDR(perm)
+
 	return key
 }
 
@@ -725,3 +1027,16 @@ func (slc ipPermSlice) Less(i, j int) bool {
 func (slc ipPermSlice) Swap(i, j int) {
 	slc[i], slc[j] = slc[j], slc[i]
 }
+
+func ec2Regions() []string {
+	partition := endpoints.AwsPartition()
+	var regions []string
+	for region := range partition.Services()[endpoints.Ec2ServiceID].Regions() {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// Stored in a variable so it may be mocked out for unit tests.
+var now = time.Now
@@ -2,9 +2,11 @@ package amazon
 
 import (
 	"encoding/base64"
+	"errors"
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -48,6 +50,7 @@ func TestList(t *testing.T) {
 		{
 			InstanceId:   aws.String("inst3"),
 			InstanceType: aws.String("size2"),
+			ImageId:      aws.String("ami-custom"),
 			State: &ec2.InstanceState{
 				Name: aws.String(ec2.InstanceStateNameRunning),
 			},
@@ -77,6 +80,7 @@ func TestList(t *testing.T) {
 			// A spot request and a corresponding instance.
 			{
 				SpotInstanceRequestId: aws.String("spot1"),
+				SpotPrice:             aws.String("0.050000"),
 				State: aws.String(
 					ec2.SpotInstanceStateActive),
 				InstanceId: aws.String("inst1"),
@@ -95,9 +99,11 @@ func TestList(t *testing.T) {
 			// A spot request that hasn't been booted yet.
 			{
 				SpotInstanceRequestId: aws.String("spot3"),
+				SpotPrice:             aws.String("0.1"),
 				State: aws.String(ec2.SpotInstanceStateOpen),
 				LaunchSpecification: &ec2.LaunchSpecification{
 					InstanceType: aws.String("size3"),
+					ImageId:      aws.String("ami-spot"),
 				},
 			}}, nil)
 
@@ -123,6 +129,7 @@ func TestList(t *testing.T) {
 			DiskSize:    32,
 			FloatingIP:  "8.8.8.8",
 			Preemptible: false,
+			Image:       "ami-custom",
 		},
 		{
 			Provider:    "Amazon",
@@ -132,6 +139,7 @@ func TestList(t *testing.T) {
 			PrivateIP:   "privateIP",
 			Size:        "size",
 			Preemptible: true,
+			MaxPrice:    0.05,
 		},
 		{
 			Provider:    "Amazon",
@@ -147,6 +155,8 @@ func TestList(t *testing.T) {
 			CloudID:     "spot3",
 			Size:        "size3",
 			Preemptible: true,
+			MaxPrice:    0.1,
+			Image:       "ami-spot",
 		},
 	}, machines)
 }
@@ -284,6 +294,91 @@ func TestNewACLs(t *testing.T) {
 	}
 }
 
+func TestSyncACLsProtocol(t *testing.T) {
+	t.Parallel()
+
+	perm := func(protocol string, min, max int64) *ec2.IpPermission {
+		return &ec2.IpPermission{
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("1.2.3.4/32")}},
+			FromPort:   aws.Int64(min),
+			ToPort:     aws.Int64(max),
+			IpProtocol: aws.String(protocol),
+		}
+	}
+
+	desired := []acl.ACL{
+		{CidrIP: "1.2.3.4/32", MinPort: 53, MaxPort: 53, Protocol: "udp"},
+		{CidrIP: "1.2.3.4/32", Protocol: "icmp"},
+	}
+	toAdd, _, toRemove := syncACLs(desired, "", []*ec2.IpPermission{
+		perm("tcp", 53, 53)})
+	sort.Sort(ipPermSlice(toAdd))
+	assert.Equal(t, []*ec2.IpPermission{
+		perm("icmp", -1, -1),
+		perm("udp", 53, 53),
+	}, toAdd)
+	assert.Equal(t, []*ec2.IpPermission{perm("tcp", 53, 53)}, toRemove)
+}
+
+func TestSyncACLsIPv6(t *testing.T) {
+	t.Parallel()
+
+	v6Perm := func(cidr, protocol string, min, max int64) *ec2.IpPermission {
+		return &ec2.IpPermission{
+			Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: aws.String(cidr)}},
+			FromPort:   aws.Int64(min),
+			ToPort:     aws.Int64(max),
+			IpProtocol: aws.String(protocol),
+		}
+	}
+
+	current := []*ec2.IpPermission{
+		v6Perm("::/0", "tcp", 80, 80),
+		v6Perm("::/0", "udp", 80, 80),
+		// EC2 reports ICMPv6 by its protocol number.
+		v6Perm("::/0", "58", -1, -1),
+		v6Perm("2001:db8::/32", "tcp", 22, 22),
+	}
+	desired := []acl.ACL{
+		{CidrIP: "::/0", MinPort: 80, MaxPort: 80},
+		{CidrIP: "1.2.3.4/32", MinPort: 80, MaxPort: 80},
+	}
+
+	toAdd, _, toRemove := syncACLs(desired, "", current)
+	sort.Sort(ipPermSlice(toAdd))
+	assert.Equal(t, []*ec2.IpPermission{
+		{
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("1.2.3.4/32")}},
+			FromPort:   aws.Int64(-1),
+			ToPort:     aws.Int64(-1),
+			IpProtocol: aws.String("icmp"),
+		},
+		{
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("1.2.3.4/32")}},
+			FromPort:   aws.Int64(80),
+			ToPort:     aws.Int64(80),
+			IpProtocol: aws.String("tcp"),
+		},
+		{
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("1.2.3.4/32")}},
+			FromPort:   aws.Int64(80),
+			ToPort:     aws.Int64(80),
+			IpProtocol: aws.String("udp"),
+		},
+	}, toAdd)
+	assert.Equal(t, []*ec2.IpPermission{
+		v6Perm("2001:db8::/32", "tcp", 22, 22)}, toRemove)
+
+	toAdd, _, _ = syncACLs([]acl.ACL{{CidrIP: "::/0", MinPort: 1, MaxPort: 2}},
+		"", nil)
+	sort.Sort(ipPermSlice(toAdd))
+	assert.Equal(t, []*ec2.IpPermission{
+		v6Perm("::/0", "icmpv6", -1, -1),
+		v6Perm("::/0", "tcp", 1, 2),
+		v6Perm("::/0", "udp", 1, 2),
+	}, toAdd)
+}
+
 func TestBoot(t *testing.T) {
 	t.Parallel()
 
@@ -291,6 +386,8 @@ func TestBoot(t *testing.T) {
 	mc.On("DescribeSecurityGroup", mock.Anything).Return([]*ec2.SecurityGroup{{
 		GroupId: aws.String("groupId")}}, nil)
 
+	mc.On("DescribeImages", mock.Anything, mock.Anything).Return(
+		[]*ec2.Image{{ImageId: aws.String("ami-ubuntu")}}, nil).Once()
 	mc.On("RequestSpotInstances", mock.Anything, mock.Anything,
 		mock.Anything).Return([]*ec2.SpotInstanceRequest{{
 		SpotInstanceRequestId: aws.String("spot1"),
@@ -345,10 +442,10 @@ func TestBoot(t *testing.T) {
 	assert.Subset(t, []string{"spot1", "spot2", "reserved1", "reserved2"}, ids)
 	assert.Len(t, ids, 4)
 
-	cfg := cfg.Ubuntu(db.Machine{Role: db.Master}, "")
-	mc.AssertCalled(t, "RequestSpotInstances", spotPrice, int64(2),
+	cfg := cfg.BootScript(db.Machine{Role: db.Master}, "")
+	mc.AssertCalled(t, "RequestSpotInstances", "", int64(2),
 		&ec2.RequestSpotLaunchSpecification{
-			ImageId:      aws.String(amis[DefaultRegion]),
+			ImageId:      aws.String("ami-ubuntu"),
 			InstanceType: aws.String("m4.large"),
 			UserData: aws.String(base64.StdEncoding.EncodeToString(
 				[]byte(cfg))),
@@ -356,7 +453,7 @@ func TestBoot(t *testing.T) {
 			BlockDeviceMappings: []*ec2.BlockDeviceMapping{
 				blockDevice(32)}})
 	mc.AssertCalled(t, "RunInstances", &ec2.RunInstancesInput{
-		ImageId:      aws.String(amis[DefaultRegion]),
+		ImageId:      aws.String("ami-ubuntu"),
 		InstanceType: aws.String("m4.large"),
 		UserData: aws.String(base64.StdEncoding.EncodeToString(
 			[]byte(cfg))),
@@ -369,6 +466,357 @@ func TestBoot(t *testing.T) {
 	mc.AssertExpectations(t)
 }
 
+func TestGetUbuntuImage(t *testing.T) {
+	mc := new(mocks.Client)
+	amazonProvider := newAmazon(testNamespace, DefaultRegion)
+	amazonProvider.Client = mc
+
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	filters := []*ec2.Filter{{
+		Name:   aws.String("name"),
+		Values: []*string{aws.String(ubuntuImageName)},
+	}, {
+		Name:   aws.String("state"),
+		Values: []*string{aws.String(ec2.ImageStateAvailable)},
+	}}
+	mc.On("DescribeImages", []string{canonicalOwner}, filters).Return(
+		[]*ec2.Image{{
+			ImageId:      aws.String("ami-old"),
+			CreationDate: aws.String("2018-01-01T00:00:00.000Z"),
+		}, {
+			ImageId:      aws.String("ami-new"),
+			CreationDate: aws.String("2018-02-01T00:00:00.000Z"),
+		}, {
+			ImageId:      aws.String("ami-older"),
+			CreationDate: aws.String("2017-12-01T00:00:00.000Z"),
+		}}, nil).Once()
+
+	image, err := amazonProvider.getUbuntuImage()
+	assert.NoError(t, err)
+	assert.Equal(t, "ami-new", image)
+
+	// The image should be cached until it expires.
+	current = current.Add(ubuntuImageTTL - time.Second)
+	image, err = amazonProvider.getUbuntuImage()
+	assert.NoError(t, err)
+	assert.Equal(t, "ami-new", image)
+	mc.AssertExpectations(t)
+
+	current = current.Add(time.Second)
+	mc.On("DescribeImages", mock.Anything, mock.Anything).Return(
+		nil, errors.New("err")).Once()
+	_, err = amazonProvider.getUbuntuImage()
+	assert.EqualError(t, err, "err")
+
+	mc.On("DescribeImages", mock.Anything, mock.Anything).Return(
+		nil, nil).Once()
+	_, err = amazonProvider.getUbuntuImage()
+	assert.EqualError(t, err, "no images match "+ubuntuImageName)
+
+	mc.On("DescribeSecurityGroup", mock.Anything).Return(
+		[]*ec2.SecurityGroup{{GroupId: aws.String("groupId")}}, nil)
+	mc.On("DescribeImages", mock.Anything, mock.Anything).Return(
+		nil, errors.New("err")).Once()
+	_, err = amazonProvider.Boot([]db.Machine{{Size: "m4.large"}})
+	assert.EqualError(t, err, "find Ubuntu image: err")
+}
+
+func TestRegions(t *testing.T) {
+	t.Parallel()
+
+	// Regions should include both the original regions, and newer ones.
+	for _, region := range []string{"us-west-1", "eu-west-1", "ca-central-1"} {
+		assert.Contains(t, Regions, region)
+	}
+	assert.NotContains(t, Regions, "cn-north-1")
+	assert.True(t, sort.StringsAreSorted(Regions))
+}
+
+func TestBootCustomImage(t *testing.T) {
+	t.Parallel()
+
+	mc := new(mocks.Client)
+	mc.On("DescribeSecurityGroup", mock.Anything).Return([]*ec2.SecurityGroup{{
+		GroupId: aws.String("groupId")}}, nil)
+	mc.On("RunInstances", mock.Anything).Return(&ec2.Reservation{
+		Instances: []*ec2.Instance{{InstanceId: aws.String("reserved1")}},
+	}, nil)
+
+	amazonProvider := newAmazon(testNamespace, DefaultRegion)
+	amazonProvider.Client = mc
+
+	m := db.Machine{
+		Role:     db.Master,
+		Size:     "m4.large",
+		DiskSize: 32,
+		Image:    "ami-custom",
+		OS:       db.Prebaked,
+	}
+	ids, err := amazonProvider.Boot([]db.Machine{m})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reserved1"}, ids)
+
+	mc.AssertCalled(t, "RunInstances", &ec2.RunInstancesInput{
+		ImageId:      aws.String("ami-custom"),
+		InstanceType: aws.String("m4.large"),
+		UserData: aws.String(base64.StdEncoding.EncodeToString(
+			[]byte(cfg.BootScript(m, "")))),
+		SecurityGroupIds: aws.StringSlice([]string{"groupId"}),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			blockDevice(32)},
+		MaxCount: aws.Int64(1),
+		MinCount: aws.Int64(1),
+	})
+}
+
+func TestBootMaxPrice(t *testing.T) {
+	t.Parallel()
+
+	mc := new(mocks.Client)
+	mc.On("DescribeSecurityGroup", mock.Anything).Return([]*ec2.SecurityGroup{{
+		GroupId: aws.String("groupId")}}, nil)
+	mc.On("RequestSpotInstances", mock.Anything, mock.Anything,
+		mock.Anything).Return([]*ec2.SpotInstanceRequest{{
+		SpotInstanceRequestId: aws.String("spot1"),
+	}}, nil)
+
+	amazonProvider := newAmazon(testNamespace, DefaultRegion)
+	amazonProvider.Client = mc
+
+	m := db.Machine{
+		Role:        db.Worker,
+		Size:        "m4.large",
+		DiskSize:    32,
+		Preemptible: true,
+		MaxPrice:    0.025,
+		Image:       "ami-custom",
+	}
+	ids, err := amazonProvider.Boot([]db.Machine{m})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spot1"}, ids)
+
+	mc.AssertCalled(t, "RequestSpotInstances", "0.025", int64(1),
+		&ec2.RequestSpotLaunchSpecification{
+			ImageId:      aws.String("ami-custom"),
+			InstanceType: aws.String("m4.large"),
+			UserData: aws.String(base64.StdEncoding.EncodeToString(
+				[]byte(cfg.BootScript(m, "")))),
+			SecurityGroupIds: aws.StringSlice([]string{"groupId"}),
+			BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+				blockDevice(32)}})
+}
+
+func TestBootLabels(t *testing.T) {
+	t.Parallel()
+
+	mc := new(mocks.Client)
+	mc.On("DescribeSecurityGroup", mock.Anything).Return([]*ec2.SecurityGroup{{
+		GroupId: aws.String("groupId")}}, nil)
+	mc.On("RequestSpotInstances", mock.Anything, mock.Anything,
+		mock.Anything).Return([]*ec2.SpotInstanceRequest{{
+		SpotInstanceRequestId: aws.String("spot1"),
+	}}, nil)
+	mc.On("RunInstances", mock.Anything).Return(&ec2.Reservation{
+		Instances: []*ec2.Instance{{InstanceId: aws.String("reserved1")}},
+	}, nil)
+
+	tags := []*ec2.Tag{
+		{Key: aws.String("env"), Value: aws.String("prod")},
+		{Key: aws.String("team"), Value: aws.String("infra")},
+	}
+	mc.On("CreateTags", []string{"spot1"}, tags).Return(nil).Once()
+
+	amazonProvider := newAmazon(testNamespace, DefaultRegion)
+	amazonProvider.Client = mc
+
+	labels := map[string]string{"team": "infra", "env": "prod"}
+	reserved := db.Machine{
+		Role:   db.Worker,
+		Size:   "m4.large",
+		Image:  "ami-custom",
+		Labels: labels,
+	}
+	spot := reserved
+	spot.Preemptible = true
+
+	ids, err := amazonProvider.Boot([]db.Machine{reserved, spot})
+	assert.NoError(t, err)
+	assert.Len(t, ids, 2)
+
+	// Reserved instances and their disks are tagged when they're created, but
+	// spot requests have to be tagged afterwards.
+	mc.AssertCalled(t, "RunInstances", &ec2.RunInstancesInput{
+		ImageId:      aws.String("ami-custom"),
+		InstanceType: aws.String("m4.large"),
+		UserData: aws.String(base64.StdEncoding.EncodeToString(
+			[]byte(cfg.BootScript(reserved, "")))),
+		SecurityGroupIds: aws.StringSlice([]string{"groupId"}),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			blockDevice(0)},
+		MaxCount: aws.Int64(1),
+		MinCount: aws.Int64(1),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags:         tags,
+		}, {
+			ResourceType: aws.String(ec2.ResourceTypeVolume),
+			Tags:         tags,
+		}},
+	})
+	mc.AssertExpectations(t)
+
+	// A failure to tag the spot requests is reported.
+	mc.On("CreateTags", mock.Anything, mock.Anything).Return(errors.New("err"))
+	_, err = amazonProvider.Boot([]db.Machine{spot})
+	assert.EqualError(t, err, "tag spot requests: err")
+}
+
+func TestListLabels(t *testing.T) {
+	t.Parallel()
+
+	mc := new(mocks.Client)
+	mc.On("DescribeInstances", mock.Anything).Return(
+		&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{
+			Instances: []*ec2.Instance{{
+				InstanceId:            aws.String("inst1"),
+				SpotInstanceRequestId: aws.String("spot1"),
+				State: &ec2.InstanceState{
+					Name: aws.String(
+						ec2.InstanceStateNameRunning),
+				},
+				BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{{
+					Ebs: &ec2.EbsInstanceBlockDevice{
+						VolumeId: aws.String("vol1"),
+					},
+				}},
+			}, {
+				InstanceId: aws.String("inst2"),
+				State: &ec2.InstanceState{
+					Name: aws.String(
+						ec2.InstanceStateNameRunning),
+				},
+				Tags: []*ec2.Tag{{
+					Key:   aws.String("team"),
+					Value: aws.String("infra"),
+				}, {
+					Key:   aws.String("aws:cloudformation:stack-id"),
+					Value: aws.String("stack"),
+				}},
+			}},
+		}}}, nil)
+	mc.On("DescribeVolumes").Return([]*ec2.Volume{{
+		VolumeId: aws.String("vol1"),
+		Size:     aws.Int64(32)}}, nil)
+	mc.On("DescribeAddresses").Return(nil, nil)
+	mc.On("DescribeSpotInstanceRequests", mock.Anything, mock.Anything).Return(
+		[]*ec2.SpotInstanceRequest{{
+			SpotInstanceRequestId: aws.String("spot1"),
+			State: aws.String(
+				ec2.SpotInstanceStateActive),
+			InstanceId: aws.String("inst1"),
+			LaunchSpecification: &ec2.LaunchSpecification{
+				InstanceType: aws.String("size"),
+			},
+			Tags: []*ec2.Tag{{
+				Key:   aws.String("team"),
+				Value: aws.String("web"),
+			}},
+		}}, nil)
+
+	// The spot instance doesn't have its request's tags yet.
+	mc.On("CreateTags", []string{"inst1", "vol1"}, []*ec2.Tag{{
+		Key: aws.String("team"), Value: aws.String("web")}}).Return(nil).Once()
+
+	amazonProvider := newAmazon(testNamespace, DefaultRegion)
+	amazonProvider.Client = mc
+
+	machines, err := amazonProvider.List()
+	assert.NoError(t, err)
+	assert.Len(t, machines, 2)
+	assert.Equal(t, "inst2", machines[0].CloudID)
+	assert.Equal(t, map[string]string{"team": "infra"}, machines[0].Labels)
+	assert.Equal(t, "spot1", machines[1].CloudID)
+	assert.Equal(t, map[string]string{"team": "web"}, machines[1].Labels)
+	mc.AssertExpectations(t)
+}
+
+func TestSetLabels(t *testing.T) {
+	t.Parallel()
+
+	mc := new(mocks.Client)
+	amazonProvider := newAmazon(testNamespace, DefaultRegion)
+	amazonProvider.Client = mc
+
+	// Without labels, there's nothing to do.
+	assert.NoError(t, amazonProvider.SetLabels(nil))
+
+	mc.On("DescribeSecurityGroup", testNamespace).Return(
+		[]*ec2.SecurityGroup{{
+			GroupId: aws.String("group1"),
+			Tags: []*ec2.Tag{{
+				Key:   aws.String("team"),
+				Value: aws.String("infra"),
+			}},
+		}, {
+			GroupId: aws.String("group2"),
+		}}, nil)
+	mc.On("CreateTags", []string{"group2"}, []*ec2.Tag{{
+		Key: aws.String("team"), Value: aws.String("infra")}}).Return(nil)
+
+	labels := map[string]string{"team": "infra"}
+	assert.NoError(t, amazonProvider.SetLabels(labels))
+	mc.AssertExpectations(t)
+	mc.AssertNumberOfCalls(t, "CreateTags", 1)
+}
+
+func TestUpdateLabels(t *testing.T) {
+	t.Parallel()
+
+	mc := new(mocks.Client)
+	mc.On("DescribeInstances", mock.Anything).Return(
+		&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{
+			Instances: []*ec2.Instance{{
+				InstanceId: aws.String("inst1"),
+				BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{{
+					Ebs: &ec2.EbsInstanceBlockDevice{
+						VolumeId: aws.String("vol1"),
+					},
+				}},
+			}},
+		}}}, nil)
+	mc.On("DescribeVolumes").Return([]*ec2.Volume{{
+		VolumeId: aws.String("vol1"),
+		Size:     aws.Int64(32)}}, nil)
+	mc.On("DescribeAddresses").Return(nil, nil)
+
+	tags := []*ec2.Tag{{Key: aws.String("team"), Value: aws.String("web")}}
+	mc.On("CreateTags", []string{"inst1", "vol1"}, tags).Return(nil).Once()
+
+	// Spot instances get the tags of their requests when they're listed.
+	mc.On("CreateTags", []string{"spot1"}, tags).Return(nil).Once()
+
+	amazonProvider := newAmazon(testNamespace, DefaultRegion)
+	amazonProvider.Client = mc
+
+	labels := map[string]string{"team": "web"}
+	err := amazonProvider.UpdateLabels([]db.Machine{
+		{CloudID: "inst1", Labels: labels},
+		{CloudID: "spot1", Preemptible: true, Labels: labels},
+		{CloudID: "inst2"},
+	})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+
+	mc.On("CreateTags", mock.Anything, mock.Anything).Return(
+		errors.New("tag error"))
+	err = amazonProvider.UpdateLabels([]db.Machine{
+		{CloudID: "spot1", Preemptible: true, Labels: labels}})
+	assert.EqualError(t, err, "tag spot1: tag error")
+}
+
 func TestStop(t *testing.T) {
 	t.Parallel()
 
@@ -451,11 +899,13 @@ func TestUpdateFloatingIPs(t *testing.T) {
 			FloatingIP:  "",
 			Preemptible: true,
 		},
-		// Kelda should assign "x.x.x.x" to reserved-1.
+		// Kelda should assign "x.x.x.x" to reserved-1, and tag it with the
+		// machine's labels.
 		{
 			CloudID:     "reserved-1",
 			FloatingIP:  "reservedAdd",
 			Preemptible: false,
+			Labels:      map[string]string{"team": "infra"},
 		},
 		// Kelda should disassociate all floating IPs from reserved-2.
 		{
@@ -541,11 +991,15 @@ func TestUpdateFloatingIPs(t *testing.T) {
 	mockClient.On("DisassociateAddress", "assoc-2").Return(nil)
 
 	mockClient.On("AssociateAddress", "reserved-1", "alloc-reservedAdd").Return(nil)
+	mockClient.On("CreateTags", []string{"alloc-reservedAdd"}, []*ec2.Tag{{
+		Key: aws.String("team"), Value: aws.String("infra")}}).Return(nil)
 
 	mockClient.On("DisassociateAddress", "assoc-reservedRemove").Return(nil)
 
 	err := amazonProvider.UpdateFloatingIPs(mockMachines)
 	assert.Nil(t, err)
+	mockClient.AssertCalled(t, "CreateTags", []string{"alloc-reservedAdd"},
+		[]*ec2.Tag{{Key: aws.String("team"), Value: aws.String("infra")}})
 }
 
 func TestCleanup(t *testing.T) {
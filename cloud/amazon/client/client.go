@@ -11,6 +11,7 @@ import (
 
 // A Client to an Amazon EC2 region.
 type Client interface {
+	DescribeImages(owners []string, filters []*ec2.Filter) ([]*ec2.Image, error)
 	DescribeInstances([]*ec2.Filter) (*ec2.DescribeInstancesOutput, error)
 	RunInstances(*ec2.RunInstancesInput) (*ec2.Reservation, error)
 	TerminateInstances(ids []string) error
@@ -32,6 +33,8 @@ type Client interface {
 	DisassociateAddress(associationID string) error
 
 	DescribeVolumes() ([]*ec2.Volume, error)
+
+	CreateTags(ids []string, tags []*ec2.Tag) error
 }
 
 type awsClient struct {
@@ -40,6 +43,18 @@ type awsClient struct {
 
 var c = counter.New("Amazon")
 
+func (ac awsClient) DescribeImages(owners []string, filters []*ec2.Filter) (
+	[]*ec2.Image, error) {
+	c.Inc("List Images")
+	resp, err := ac.client.DescribeImages(&ec2.DescribeImagesInput{
+		Owners:  stringSlice(owners),
+		Filters: filters})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Images, nil
+}
+
 func (ac awsClient) DescribeInstances(filters []*ec2.Filter) (
 	*ec2.DescribeInstancesOutput, error) {
 	c.Inc("List Instances")
@@ -73,8 +88,14 @@ func (ac awsClient) RequestSpotInstances(spotPrice string, count int64,
 	[]*ec2.SpotInstanceRequest, error) {
 	c.Inc("Request Spots")
 
+	// An empty price lets Amazon default to the on-demand price.
+	var price *string
+	if spotPrice != "" {
+		price = &spotPrice
+	}
+
 	resp, err := ac.client.RequestSpotInstances(&ec2.RequestSpotInstancesInput{
-		SpotPrice:           &spotPrice,
+		SpotPrice:           price,
 		InstanceCount:       &count,
 		LaunchSpecification: launchSpec})
 	if err != nil {
@@ -179,6 +200,14 @@ func (ac awsClient) DescribeVolumes() ([]*ec2.Volume, error) {
 	return resp.Volumes, err
 }
 
+func (ac awsClient) CreateTags(ids []string, tags []*ec2.Tag) error {
+	c.Inc("Create Tags")
+	_, err := ac.client.CreateTags(&ec2.CreateTagsInput{
+		Resources: stringSlice(ids),
+		Tags:      tags})
+	return err
+}
+
 // New creates a new Client.
 func New(region string) Client {
 	c.Inc("New Client")
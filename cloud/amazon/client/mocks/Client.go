@@ -73,6 +73,20 @@ func (_m *Client) CreateSecurityGroup(name string, description string) (string,
 	return r0, r1
 }
 
+// CreateTags provides a mock function with given fields: ids, tags
+func (_m *Client) CreateTags(ids []string, tags []*ec2.Tag) error {
+	ret := _m.Called(ids, tags)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string, []*ec2.Tag) error); ok {
+		r0 = rf(ids, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteSecurityGroup provides a mock function with given fields: id
 func (_m *Client) DeleteSecurityGroup(id string) error {
 	ret := _m.Called(id)
@@ -110,6 +124,29 @@ func (_m *Client) DescribeAddresses() ([]*ec2.Address, error) {
 	return r0, r1
 }
 
+// DescribeImages provides a mock function with given fields: owners, filters
+func (_m *Client) DescribeImages(owners []string, filters []*ec2.Filter) ([]*ec2.Image, error) {
+	ret := _m.Called(owners, filters)
+
+	var r0 []*ec2.Image
+	if rf, ok := ret.Get(0).(func([]string, []*ec2.Filter) []*ec2.Image); ok {
+		r0 = rf(owners, filters)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ec2.Image)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string, []*ec2.Filter) error); ok {
+		r1 = rf(owners, filters)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DescribeInstances provides a mock function with given fields: _a0
 func (_m *Client) DescribeInstances(_a0 []*ec2.Filter) (*ec2.DescribeInstancesOutput, error) {
 	ret := _m.Called(_a0)
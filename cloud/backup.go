@@ -0,0 +1,74 @@
+package cloud
+
+import (
+	"fmt"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/api/client"
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+// How often, in seconds, the daemon copies the clusters' snapshots.  The
+// leaders take a snapshot every ten minutes.
+const backupInterval = 10 * 60
+
+// SyncBackups copies the latest snapshot of the state of each namespace's
+// cluster to `<dir>/<namespace>.json`, until `stop` is closed.  The copies allow
+// a cluster to be restored with `kelda cluster restore` even if all of its
+// masters, and so all of their snapshots, are lost.
+func SyncBackups(conn db.Conn, creds connection.Credentials, dir string,
+	stop <-chan struct{}) {
+
+	trigg := conn.TriggerTick(backupInterval)
+	defer trigg.Stop()
+
+	for {
+		select {
+		case <-trigg.C:
+		case <-stop:
+			return
+		}
+
+		for _, namespace := range conn.GetBlueprintNamespaces() {
+			if err := backup(conn, creds, dir, namespace); err != nil {
+				log.WithError(err).WithField("namespace", namespace).Warn(
+					"Failed to back up the cluster state")
+			}
+		}
+	}
+}
+
+func backup(conn db.Conn, creds connection.Credentials, dir,
+	namespace string) error {
+
+	machines := conn.SelectFromMachine(func(m db.Machine) bool {
+		return m.Namespace == namespace && m.Status == db.Connected
+	})
+	if len(machines) == 0 {
+		return nil
+	}
+
+	leaderClient, err := newLeaderClient(machines, creds)
+	if err != nil {
+		return err
+	}
+	defer leaderClient.Close()
+
+	snapshot, err := leaderClient.Snapshot(pb.SnapshotRequest{})
+	if err != nil {
+		return fmt.Errorf("get snapshot: %s", err)
+	}
+
+	if err := util.AppFs.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("create backup directory: %s", err)
+	}
+	path := filepath.Join(dir, namespace+".json")
+	return util.WriteFile(path, []byte(snapshot), 0600)
+}
+
+var newLeaderClient = client.Leader
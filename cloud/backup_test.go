@@ -0,0 +1,60 @@
+package cloud
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/api/client"
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/api/pb"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+func TestBackup(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	conn := db.New()
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for _, m := range []db.Machine{
+			{CloudID: "a", Namespace: "ns", Status: db.Connected},
+			{CloudID: "b", Namespace: "ns", Status: db.Booting},
+			{CloudID: "c", Namespace: "down", Status: db.Booting},
+		} {
+			m.ID = view.InsertMachine().ID
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	mc := new(mocks.Client)
+	mc.On("Snapshot", pb.SnapshotRequest{}).Return("snapshot", nil).Once()
+	mc.On("Close").Return(nil)
+	newLeaderClient = func(machines []db.Machine, _ connection.Credentials) (
+		client.Client, error) {
+		assert.Len(t, machines, 1)
+		assert.Equal(t, "a", machines[0].CloudID)
+		return mc, nil
+	}
+
+	assert.NoError(t, backup(conn, nil, "/backups", "ns"))
+	contents, err := util.ReadFile("/backups/ns.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "snapshot", contents)
+
+	// Namespaces without connected machines are skipped.
+	assert.NoError(t, backup(conn, nil, "/backups", "down"))
+	_, err = util.ReadFile("/backups/down.json")
+	assert.Error(t, err)
+
+	// The previous backup is kept if the snapshot can't be retrieved.
+	mc.On("Snapshot", pb.SnapshotRequest{}).Return("", assert.AnError).Once()
+	assert.Error(t, backup(conn, nil, "/backups", "ns"))
+	contents, err = util.ReadFile("/backups/ns.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "snapshot", contents)
+	mc.AssertExpectations(t)
+}
@@ -8,6 +8,7 @@ import (
 
 	tlsIO "github.com/kelda/kelda/connection/tls/io"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/ipdef"
 	"github.com/kelda/kelda/version"
 
 	log "github.com/sirupsen/logrus"
@@ -20,32 +21,88 @@ const (
 // Allow mocking out for the unit tests.
 var ver = version.Version
 
-// Ubuntu generates a cloud config file for the Ubuntu operating system with the
-// corresponding `version`.
-func Ubuntu(m db.Machine, inboundPublic string) string {
-	t := template.Must(template.New("cloudConfig").Parse(cfgTemplate))
-
-	img := fmt.Sprintf("%s:%s", keldaImage, ver)
-
-	var cloudConfigBytes bytes.Buffer
-	err := t.Execute(&cloudConfigBytes, struct {
-		KeldaImage string
-		SSHKeys    string
-		LogLevel   string
-		MinionOpts string
-		TLSDir     string
-	}{
-		KeldaImage: img,
-		SSHKeys:    strings.Join(m.SSHKeys, "\n"),
-		LogLevel:   log.GetLevel().String(),
-		MinionOpts: minionOptions(m.Role, inboundPublic),
-		TLSDir:     tlsIO.MinionTLSDir,
-	})
-	if err != nil {
-		panic(err)
+// The private address ranges that machines get their private IPs from.  Images
+// built by Kelda are pulled from the registry at the leader's private IP.
+var privateSubnets = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+
+// A Generator generates the script that boots a machine.
+type Generator func(m db.Machine, inboundPublic string) string
+
+// generators maps each supported OS to the Generator for its images.
+var generators = map[db.OS]Generator{
+	db.Ubuntu:   systemd(ubuntuDocker),
+	db.Debian:   systemd(debianDocker),
+	db.Prebaked: systemd(""),
+}
+
+// BootScript generates the script that boots `m`, based on its OS.
+func BootScript(m db.Machine, inboundPublic string) string {
+	os := m.OS
+	if os == "" {
+		os = db.Ubuntu
 	}
 
-	return cloudConfigBytes.String()
+	gen, ok := generators[os]
+	if !ok {
+		panic(fmt.Sprintf("unsupported OS: %s", os))
+	}
+	return gen(m, inboundPublic)
+}
+
+// systemd returns a Generator for systemd based distributions that installs
+// Docker with the `installDocker` script.  If `installDocker` is empty, the
+// image must already have Docker installed.
+func systemd(installDocker string) Generator {
+	return func(m db.Machine, inboundPublic string) string {
+		t := template.Must(template.New("cloudConfig").Parse(cfgTemplate))
+
+		var cloudConfigBytes bytes.Buffer
+		err := t.Execute(&cloudConfigBytes, struct {
+			KeldaImage         string
+			SSHKeys            string
+			LogLevel           string
+			MinionOpts         string
+			TLSDir             string
+			InstallDocker      string
+			InsecureRegistries []string
+		}{
+			KeldaImage:         Image(),
+			SSHKeys:            strings.Join(m.SSHKeys, "\n"),
+			LogLevel:           log.GetLevel().String(),
+			MinionOpts:         minionOptions(m.Role, inboundPublic),
+			TLSDir:             tlsIO.MinionTLSDir,
+			InstallDocker:      installDocker,
+			InsecureRegistries: InsecureRegistries(m),
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		return cloudConfigBytes.String()
+	}
+}
+
+// Image returns the Kelda image that minions run.
+func Image() string {
+	return fmt.Sprintf("%s:%s", keldaImage, ver)
+}
+
+// InsecureRegistries returns the subnets in which the Docker daemon of `m`
+// trusts registries without TLS: the namespace's container subnet, and the
+// private address ranges.
+func InsecureRegistries(m db.Machine) []string {
+	subnet := m.ContainerSubnet
+	if subnet == "" {
+		subnet = ipdef.DefaultSubnet
+	}
+
+	registries := []string{subnet}
+	for _, private := range privateSubnets {
+		if private != subnet {
+			registries = append(registries, private)
+		}
+	}
+	return registries
 }
 
 func minionOptions(role db.Role, inboundPublic string) string {
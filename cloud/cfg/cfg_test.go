@@ -3,6 +3,8 @@ package cfg
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/kelda/kelda/db"
 
 	log "github.com/sirupsen/logrus"
@@ -14,7 +16,7 @@ func TestCloudConfig(t *testing.T) {
 
 	log.SetLevel(log.InfoLevel)
 	ver = "master"
-	res := Ubuntu(db.Machine{
+	res := BootScript(db.Machine{
 		SSHKeys: []string{"a", "b"},
 		Role:    db.Master,
 	}, "")
@@ -26,7 +28,7 @@ func TestCloudConfig(t *testing.T) {
 
 	log.SetLevel(log.DebugLevel)
 	ver = "1.2.3"
-	res = Ubuntu(db.Machine{
+	res = BootScript(db.Machine{
 		SSHKeys: []string{"a", "b"},
 		Role:    db.Worker,
 	}, "ib")
@@ -37,3 +39,29 @@ func TestCloudConfig(t *testing.T) {
 		t.Errorf("res: %s\nexp: %s", res, exp)
 	}
 }
+
+func TestInsecureRegistries(t *testing.T) {
+	assert.Equal(t, []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"},
+		InsecureRegistries(db.Machine{}))
+	assert.Equal(t, []string{"172.16.0.0/12", "10.0.0.0/8", "192.168.0.0/16"},
+		InsecureRegistries(db.Machine{ContainerSubnet: "172.16.0.0/12"}))
+	assert.Equal(t, []string{"100.64.0.0/10", "10.0.0.0/8", "172.16.0.0/12",
+		"192.168.0.0/16"},
+		InsecureRegistries(db.Machine{ContainerSubnet: "100.64.0.0/10"}))
+
+	cfgTemplate = "{{range .InsecureRegistries}}--insecure-registry {{.}} {{end}}"
+	assert.Equal(t, "--insecure-registry 100.64.0.0/10 "+
+		"--insecure-registry 10.0.0.0/8 --insecure-registry 172.16.0.0/12 "+
+		"--insecure-registry 192.168.0.0/16 ",
+		BootScript(db.Machine{ContainerSubnet: "100.64.0.0/10"}, ""))
+}
+
+func TestBootScriptOS(t *testing.T) {
+	cfgTemplate = "{{.InstallDocker}}"
+
+	assert.Equal(t, ubuntuDocker, BootScript(db.Machine{}, ""))
+	assert.Equal(t, ubuntuDocker, BootScript(db.Machine{OS: db.Ubuntu}, ""))
+	assert.Equal(t, debianDocker, BootScript(db.Machine{OS: db.Debian}, ""))
+	assert.Empty(t, BootScript(db.Machine{OS: db.Prebaked}, ""))
+	assert.Panics(t, func() { BootScript(db.Machine{OS: "Windows"}, "") })
+}
@@ -4,6 +4,7 @@ var cfgTemplate = `#!/bin/bash
 
 initialize_ovs() {
 	echo "net.ipv4.ip_forward=1" >> /etc/sysctl.conf
+	echo "net.ipv6.conf.all.forwarding=1" >> /etc/sysctl.conf
 	sysctl --system
 
 	cat <<- EOF > /etc/systemd/system/ovs.service
@@ -41,7 +42,7 @@ initialize_docker() {
 	# The below empty ExecStart deletes the official one installed by docker daemon.
 	ExecStart=
 	ExecStart=/usr/bin/dockerd --ip-forward=false --bridge=none \
-	--insecure-registry 10.0.0.0/8 --insecure-registry 172.16.0.0/12 --insecure-registry 192.168.0.0/16 \
+	{{range .InsecureRegistries}}--insecure-registry {{.}} {{end}}\
 	-H unix:///var/run/docker.sock
 
 
@@ -58,8 +59,9 @@ initialize_minion() {
 	# unable to write to the directory.
 	install -d -o kelda -m 755 {{.TLSDir}}
 
-	# Create the etcd data directory.
-	mkdir -p /var/lib/etcd
+	# Create the etcd data directory, and the directory in which masters store
+	# snapshots of the cluster state.
+	mkdir -p /var/lib/etcd /var/lib/kelda
 
 	cat <<- EOF > /etc/systemd/system/minion.service
 	[Unit]
@@ -76,6 +78,8 @@ initialize_minion() {
 	-v /var/run/docker.sock:/var/run/docker.sock \
 	-v /etc/ssl/certs/ca-certificates.crt:/etc/ssl/certs/ca-certificates.crt \
 	-v /home/kelda/.ssh:/home/kelda/.ssh:rw \
+	-v /var/log/kelda:/var/log/kelda:rw \
+	-v /var/lib/kelda:/var/lib/kelda:rw \
 	-v {{.TLSDir}}:{{.TLSDir}}:ro \
 	-v /run/docker:/run/docker:rw {{.KeldaImage}} \
 	kelda -l {{.LogLevel}} minion {{.MinionOpts}}
@@ -86,6 +90,7 @@ initialize_minion() {
 	EOF
 }
 
+{{- if .InstallDocker}}
 install_docker() (
 	# Fail immediately if any of commands error. If this flag were not set,
 	# every command would have to check whether it failed in order to
@@ -93,23 +98,10 @@ install_docker() (
 	# within a subshell, so commands outside this function will not cause
 	# the shell to exit on failure.
 	set -e
-
-	# The expected key is documented by Docker here:
-	# https://docs.docker.com/engine/installation/linux/docker-ce/ubuntu/#install-using-the-repository
-	curl -fsSL https://download.docker.com/linux/ubuntu/gpg | apt-key add -
-	expKey="9DC858229FC7DD38854AE2D88D81803C0EBFCD88"
-	actualKey=$(apt-key adv --with-colons --fingerprint 0EBFCD88 | grep ^fpr: | cut -d ':' -f 10)
-	if [ $actualKey != $expKey ] ; then
-	    echo "ERROR Failed to verify Docker's GPG key."
-	    echo "This could mean that an attacker is injecting a malicious version of docker-engine. Bailing."
-	    exit 1
-	fi
-
-	add-apt-repository "deb [arch=amd64] https://download.docker.com/linux/ubuntu $(lsb_release -cs) stable"
-	apt-get update
-	apt-get install docker-ce=17.09.1~ce-0~ubuntu -y
+{{.InstallDocker}}
 	systemctl stop docker.service
 )
+{{- end}}
 
 setup_user() {
 	user=$1
@@ -136,12 +128,14 @@ export DEBIAN_FRONTEND=noninteractive
 ssh_keys="{{.SSHKeys}}"
 setup_user kelda "$ssh_keys"
 
+{{- if .InstallDocker}}
 # Docker sometimes fails to install because of temporary network issues
 # connecting to the Docker apt server.
 while ! install_docker ; do
   echo "Docker failed to install. Retrying in 30 seconds."
   sleep 30
 done
+{{- end}}
 
 initialize_ovs
 initialize_docker
@@ -162,3 +156,41 @@ systemctl restart {docker,ovs,minion}.service
 echo -n "Completed Boot Script: " >> /var/log/bootscript.log
 date >> /var/log/bootscript.log
     `
+
+// The expected key is documented by Docker here:
+// https://docs.docker.com/engine/installation/linux/docker-ce/ubuntu/#install-using-the-repository
+var ubuntuDocker = `
+	curl -fsSL https://download.docker.com/linux/ubuntu/gpg | apt-key add -
+	expKey="9DC858229FC7DD38854AE2D88D81803C0EBFCD88"
+	actualKey=$(apt-key adv --with-colons --fingerprint 0EBFCD88 | grep ^fpr: | cut -d ':' -f 10)
+	if [ $actualKey != $expKey ] ; then
+	    echo "ERROR Failed to verify Docker's GPG key."
+	    echo "This could mean that an attacker is injecting a malicious version of docker-engine. Bailing."
+	    exit 1
+	fi
+
+	add-apt-repository "deb [arch=amd64] https://download.docker.com/linux/ubuntu $(lsb_release -cs) stable"
+	apt-get update
+	apt-get install docker-ce=17.09.1~ce-0~ubuntu -y`
+
+// Debian's cloud images don't include the tools needed to add an HTTPS apt
+// repository, so they're installed first.  Docker signs the Debian packages
+// with the same key as the Ubuntu ones.
+var debianDocker = `
+	apt-get update
+	apt-get install -y apt-transport-https ca-certificates curl gnupg2
+
+	curl -fsSL https://download.docker.com/linux/debian/gpg | apt-key add -
+	expKey="9DC858229FC7DD38854AE2D88D81803C0EBFCD88"
+	actualKey=$(apt-key adv --with-colons --fingerprint 0EBFCD88 | grep ^fpr: | cut -d ':' -f 10)
+	if [ $actualKey != $expKey ] ; then
+	    echo "ERROR Failed to verify Docker's GPG key."
+	    echo "This could mean that an attacker is injecting a malicious version of docker-engine. Bailing."
+	    exit 1
+	fi
+
+	codename=$(. /etc/os-release && echo $VERSION_CODENAME)
+	echo "deb [arch=amd64] https://download.docker.com/linux/debian $codename stable" \
+	    > /etc/apt/sources.list.d/docker.list
+	apt-get update
+	apt-get install docker-ce=17.09.1~ce-0~debian -y`
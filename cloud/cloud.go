@@ -2,6 +2,7 @@ package cloud
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/kelda/kelda/blueprint"
@@ -10,10 +11,14 @@ import (
 	"github.com/kelda/kelda/cloud/digitalocean"
 	"github.com/kelda/kelda/cloud/foreman"
 	"github.com/kelda/kelda/cloud/google"
+	"github.com/kelda/kelda/cloud/local"
+	"github.com/kelda/kelda/cloud/openstack"
+	"github.com/kelda/kelda/cloud/static"
 	"github.com/kelda/kelda/cloud/vagrant"
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/util"
+	"github.com/kelda/kelda/util/str"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -30,6 +35,16 @@ type provider interface {
 
 	UpdateFloatingIPs([]db.Machine) error
 
+	// UpdateLabels applies the Labels of each machine to the running machine
+	// identified by its CloudID, and to the machine's disk.  Labels that the
+	// machine already has but that aren't in Labels are left alone.
+	UpdateLabels([]db.Machine) error
+
+	// SetLabels applies `labels` to the resources shared by all of the
+	// namespace's machines in the region, such as security groups.  Labels on
+	// the machines themselves are applied by Boot, and by UpdateLabels.
+	SetLabels(labels map[string]string) error
+
 	// The Cleanup() function will be called occaisionally in those regions that have
 	// no machines running, and no machines expected to be running in the future.
 	// The provider may use this method to free up resources that are only necessary
@@ -49,38 +64,145 @@ type cloud struct {
 	providerName db.ProviderName
 	region       string
 	provider     provider
+
+	// The labels most recently applied by SetLabels, or nil if they haven't
+	// been applied since the provider last cleaned up.
+	labels map[string]string
+}
+
+// The providers that apply labels to machines.  The labels of machines on
+// other providers are ignored, so that they aren't replaced because of labels
+// that their provider never reports.
+var labeledProviders = map[db.ProviderName]bool{
+	db.Amazon:       true,
+	db.Google:       true,
+	db.DigitalOcean: true,
 }
 
 var myIP = util.MyIP
 var sleep = time.Sleep
 var adminKey string
 
-const defaultDiskSize = 32
+// Run continually checks 'conn' for new namespaces, and starts the clouds that
+// manage each namespace's machines.  Machines that can't be reached are
+// replaced according to `healthPolicy`.  Run returns, after stopping all of
+// its clouds, once `stop` is closed.
+func Run(conn db.Conn, adminSSHKey string, healthPolicy HealthPolicy,
+	stop <-chan struct{}) {
 
-// Run continually checks 'conn' for cloud changes and recreates the cloud as
-// needed.
-func Run(conn db.Conn, adminSSHKey string) {
 	adminKey = adminSSHKey
+	health = newHealthTracker(healthPolicy)
 
-	var ns string
-	stop := make(chan struct{})
-	for range conn.TriggerTick(60, db.BlueprintTable, db.MachineTable).C {
-		newns, _ := conn.GetBlueprintNamespace()
-		if newns == ns {
-			continue
+	trigg := conn.TriggerTick(60, db.BlueprintTable, db.MachineTable)
+	defer trigg.Stop()
+
+	stops := map[string]chan struct{}{}
+	for {
+		select {
+		case <-trigg.C:
+		case <-stop:
+			for _, nsStop := range stops {
+				close(nsStop)
+			}
+			return
 		}
 
-		log.Debugf("Namespace change from \"%s\", to \"%s\".", ns, newns)
-		ns = newns
+		removeStoppedBlueprints(conn)
 
-		if ns != "" {
-			close(stop)
-			stop = make(chan struct{})
-			startClouds(conn, ns, stop)
+		namespaces := map[string]bool{}
+		for _, ns := range conn.GetBlueprintNamespaces() {
+			namespaces[ns] = true
+			if _, ok := stops[ns]; ok || ns == "" {
+				continue
+			}
+
+			log.Debugf("Start managing namespace \"%s\".", ns)
+			stops[ns] = make(chan struct{})
+			startClouds(conn, ns, stops[ns])
+		}
+
+		for ns, nsStop := range stops {
+			if !namespaces[ns] {
+				log.Debugf("Stop managing namespace \"%s\".", ns)
+				close(nsStop)
+				delete(stops, ns)
+			}
 		}
 	}
 }
 
+// A cloud within a namespace.
+type cloudKey struct {
+	provider db.ProviderName
+	region   string
+}
+
+// emptyClouds records whether each cloud, keyed by namespace, found any
+// machines the last time it checked.  Machines only have rows in the database
+// once their cloud has listed them, so after the daemon restarts, a stopped
+// namespace may still have machines that nothing knows about yet.
+var emptyClouds = struct {
+	sync.Mutex
+	byNamespace map[string]map[cloudKey]bool
+}{byNamespace: map[string]map[cloudKey]bool{}}
+
+// setEmpty records whether the cloud found any machines.
+func (cld *cloud) setEmpty(empty bool) {
+	emptyClouds.Lock()
+	defer emptyClouds.Unlock()
+
+	clouds, ok := emptyClouds.byNamespace[cld.namespace]
+	if !ok {
+		clouds = map[cloudKey]bool{}
+		emptyClouds.byNamespace[cld.namespace] = clouds
+	}
+	clouds[cloudKey{cld.providerName, cld.region}] = empty
+}
+
+// namespaceEmpty returns whether every cloud of `ns`, in every provider and
+// region, has checked for machines and found none.
+func namespaceEmpty(ns string) bool {
+	emptyClouds.Lock()
+	defer emptyClouds.Unlock()
+
+	clouds := emptyClouds.byNamespace[ns]
+	for _, p := range db.AllProviders {
+		for _, r := range ValidRegions(p) {
+			if !clouds[cloudKey{p, r}] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// removeStoppedBlueprints removes the blueprints of the namespaces that were
+// stopped, once all of their machines are gone, so that the daemon no longer
+// manages them.  A namespace's machines are only known to be gone once all of
+// its clouds have found none.
+func removeStoppedBlueprints(conn db.Conn) {
+	conn.Txn(db.BlueprintTable, db.MachineTable).Run(func(view db.Database) error {
+		namespaces := map[string]bool{}
+		for _, m := range view.SelectFromMachine(nil) {
+			namespaces[m.Namespace] = true
+		}
+
+		for _, bp := range view.SelectFromBlueprint(nil) {
+			if bp.Namespace != "" && !namespaces[bp.Namespace] &&
+				len(bp.Machines) == 0 && len(bp.Containers) == 0 &&
+				namespaceEmpty(bp.Namespace) {
+				log.Debugf("Namespace \"%s\" was stopped.", bp.Namespace)
+				view.Remove(bp)
+
+				emptyClouds.Lock()
+				delete(emptyClouds.byNamespace, bp.Namespace)
+				emptyClouds.Unlock()
+			}
+		}
+		return nil
+	})
+}
+
 func startClouds(conn db.Conn, ns string, stop chan struct{}) {
 	for _, p := range db.AllProviders {
 		for _, r := range ValidRegions(p) {
@@ -166,12 +288,21 @@ func (cld *cloud) runOnce() (active bool) {
 				logger.Errorf(message, "used by the current blueprint ")
 				return true
 			}
+
+			// Kelda can't have booted machines with a provider that it
+			// can't use at all, e.g. because it has no credentials for it.
+			if classifyError(err) == permanent {
+				cld.setEmpty(true)
+			}
 			logger.Debugf(message, "")
 			return false
 		}
-		cld.provider = provider
+		cld.provider = newRetryProvider(cld.providerName, provider)
 	}
 
+	var jr joinResult
+	defer func() { cld.syncCloudErrors(jr) }()
+
 	jr, err := cloudJoin(cld)
 	if err != nil {
 		// Could have failed due to a misconfiguration (bad keys, network
@@ -185,18 +316,22 @@ func (cld *cloud) runOnce() (active bool) {
 		if err := cld.provider.Cleanup(); err != nil {
 			log.WithError(err).WithField("region", cld.String()).Debug(
 				"Failed to clean up region")
+		} else {
+			cld.labels = nil
 		}
 		return jr.isActive
 	}
 
 	if len(jr.boot) == 0 &&
 		len(jr.terminate) == 0 &&
-		len(jr.updateIPs) == 0 {
+		len(jr.updateIPs) == 0 &&
+		len(jr.updateLabels) == 0 {
 		// ACLs must be processed after Kelda learns about what machines
 		// are in the cloud.  If we didn't, inter-machine ACLs could get
 		// removed when the Kelda controller restarts, even if there are
 		// running cloud machines that still need to communicate.
 		cld.syncACLs(jr.acls)
+		cld.syncLabels(jr.labels)
 	} else {
 		cld.updateCloud(jr)
 	}
@@ -204,6 +339,40 @@ func (cld *cloud) runOnce() (active bool) {
 	return jr.isActive
 }
 
+// syncCloudErrors records the provider calls that failed during the sync pass
+// that produced `jr` in this cloud's machines, so that users can see why their
+// machines aren't booting or stopping.  The failures of calls that act on
+// particular machines, such as boots, are only recorded in those machines.
+func (cld *cloud) syncCloudErrors(jr joinResult) {
+	rp, ok := cld.provider.(*retryProvider)
+	if !ok {
+		return
+	}
+	defer rp.resetFailures()
+
+	machineCalls := map[int][]string{}
+	addCalls := func(method string, dbms []db.Machine) {
+		for _, dbm := range dbms {
+			machineCalls[dbm.ID] = append(machineCalls[dbm.ID], method)
+		}
+	}
+	addCalls("boot", jr.boot)
+	addCalls("stop", jr.terminate)
+	addCalls("update floating IPs", jr.updateIPs)
+	addCalls("update labels", jr.updateLabels)
+
+	cld.conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for _, dbm := range cld.selectMachines(view) {
+			failure := rp.failure(machineCalls[dbm.ID]...)
+			if dbm.CloudError != failure {
+				dbm.CloudError = failure
+				view.Commit(dbm)
+			}
+		}
+		return nil
+	})
+}
+
 // usedByCurrentBlueprint returns whether this cloud provider is used by machines
 // in the blueprint that is currently active.
 func (cld *cloud) usedByCurrentBlueprint() bool {
@@ -211,7 +380,7 @@ func (cld *cloud) usedByCurrentBlueprint() bool {
 	var err error
 	cld.conn.Txn(db.BlueprintTable).Run(
 		func(view db.Database) error {
-			bp, err = view.GetBlueprint()
+			bp, err = view.GetBlueprint(cld.namespace)
 			return nil
 		})
 	if err != nil {
@@ -224,15 +393,14 @@ func (cld *cloud) usedByCurrentBlueprint() bool {
 		// conservatively assume that it is.
 		return true
 	}
-	return len(cld.desiredMachines(bp.Blueprint.Machines)) > 0
+	return len(cld.desiredMachines(bp.Blueprint)) > 0
 }
 
-// desiredMachines takes a list of all machines specified by a blueprint, and returns
-// a list of database machines that includes only the machines for this cloud's
-// provider and region.
-func (cld *cloud) desiredMachines(bpms []blueprint.Machine) []db.Machine {
+// desiredMachines takes a blueprint, and returns a list of database machines that
+// includes only the machines for this cloud's provider and region.
+func (cld *cloud) desiredMachines(bp blueprint.Blueprint) []db.Machine {
 	var dbms []db.Machine
-	for _, bpm := range bpms {
+	for _, bpm := range bp.Machines {
 		region := bpm.Region
 		if bpm.Provider != string(cld.providerName) || region != cld.region {
 			continue
@@ -244,19 +412,35 @@ func (cld *cloud) desiredMachines(bpms []blueprint.Machine) []db.Machine {
 			continue
 		}
 
+		os, err := db.ParseOS(bpm.OS)
+		if err != nil {
+			log.WithError(err).Error("Parse error: ", bpm.OS)
+			continue
+		}
+
 		dbm := db.Machine{
+			Namespace:   cld.namespace,
 			Region:      region,
 			FloatingIP:  bpm.FloatingIP,
 			Role:        role,
 			Provider:    db.ProviderName(bpm.Provider),
 			Preemptible: bpm.Preemptible,
+			MaxPrice:    bpm.MaxPrice,
 			Size:        bpm.Size,
 			DiskSize:    bpm.DiskSize,
 			SSHKeys:     bpm.SSHKeys,
+			Image:       bpm.Image,
+			OS:          os,
+
+			ContainerSubnet: bp.ContainerSubnet,
 		}
 
 		if dbm.DiskSize == 0 {
-			dbm.DiskSize = defaultDiskSize
+			dbm.DiskSize = db.DefaultDiskSize
+		}
+
+		if labeledProviders[dbm.Provider] {
+			dbm.Labels = mergeLabels(bp.Labels, bpm.Labels)
 		}
 
 		if adminKey != "" {
@@ -268,6 +452,23 @@ func (cld *cloud) desiredMachines(bpms []blueprint.Machine) []db.Machine {
 	return dbms
 }
 
+// mergeLabels returns the labels in `infra`, overridden by those in `machine`, or
+// nil if there are none.
+func mergeLabels(infra, machine map[string]string) map[string]string {
+	if len(infra) == 0 && len(machine) == 0 {
+		return nil
+	}
+
+	labels := map[string]string{}
+	for key, value := range infra {
+		labels[key] = value
+	}
+	for key, value := range machine {
+		labels[key] = value
+	}
+	return labels
+}
+
 func sanitizeMachines(machines []db.Machine) []db.Machine {
 	// As a defensive measure, we only copy over the fields that the underlying
 	// provider should care about instead of passing `machines` to updateCloud
@@ -279,11 +480,17 @@ func sanitizeMachines(machines []db.Machine) []db.Machine {
 			Size:        m.Size,
 			DiskSize:    m.DiskSize,
 			Preemptible: m.Preemptible,
+			MaxPrice:    m.MaxPrice,
 			SSHKeys:     m.SSHKeys,
 			Role:        m.Role,
 			Provider:    m.Provider,
 			Region:      m.Region,
 			FloatingIP:  m.FloatingIP,
+			Image:       m.Image,
+			OS:          m.OS,
+			Labels:      m.Labels,
+
+			ContainerSubnet: m.ContainerSubnet,
 		})
 	}
 	return cloudMachines
@@ -308,12 +515,16 @@ func (cld *cloud) updateCloud(jr joinResult) {
 	var bootIDs []string
 	if len(jr.boot) > 0 {
 		var err error
+		start := time.Now()
 		bootIDs, err = cld.provider.Boot(sanitizeMachines(jr.boot))
+		cld.observe("Boot", start)
 		logAttempt(len(jr.boot), "boot", err)
 	}
 
 	if len(jr.terminate) > 0 {
+		start := time.Now()
 		err := cld.provider.Stop(sanitizeMachines(jr.terminate))
+		cld.observe("Stop", start)
 		logAttempt(len(jr.terminate), "stop", err)
 		if err != nil {
 			jr.terminate = nil // Don't wait if we errored.
@@ -321,13 +532,25 @@ func (cld *cloud) updateCloud(jr joinResult) {
 	}
 
 	if len(jr.updateIPs) > 0 {
+		start := time.Now()
 		err := cld.provider.UpdateFloatingIPs(sanitizeMachines(jr.updateIPs))
+		cld.observe("Update Floating IPs", start)
 		logAttempt(len(jr.updateIPs), "update floating IPs", err)
 		if err != nil {
 			jr.updateIPs = nil // Don't wait if we errored.
 		}
 	}
 
+	if len(jr.updateLabels) > 0 {
+		start := time.Now()
+		err := cld.provider.UpdateLabels(sanitizeMachines(jr.updateLabels))
+		cld.observe("Update Labels", start)
+		logAttempt(len(jr.updateLabels), "update labels", err)
+		if err != nil {
+			jr.updateLabels = nil // Don't wait if we errored.
+		}
+	}
+
 	pred := func() bool {
 		machines, err := cld.provider.List()
 		if err != nil {
@@ -359,6 +582,13 @@ func (cld *cloud) updateCloud(jr joinResult) {
 			}
 		}
 
+		for _, jrm := range jr.updateLabels {
+			m, ok := ids[jrm.CloudID]
+			if ok && !str.MapContains(m.Labels, jrm.Labels) {
+				return false
+			}
+		}
+
 		return true
 	}
 
@@ -370,24 +600,49 @@ func (cld *cloud) updateCloud(jr joinResult) {
 
 func (cld *cloud) syncACLs(unresolvedACLs []acl.ACL) {
 	var acls []acl.ACL
-	for _, acl := range unresolvedACLs {
-		if acl.CidrIP == "local" {
+	for _, rule := range unresolvedACLs {
+		if rule.CidrIP == "local" {
 			ip, err := myIP()
 			if err != nil {
 				log.WithError(err).Error("Failed to retrive local IP.")
 				return
 			}
-			acl.CidrIP = ip + "/32"
+			rule.CidrIP = acl.HostCIDR(ip)
 		}
-		acls = append(acls, acl)
+		acls = append(acls, rule)
 	}
 
 	c.Inc("SetACLs")
-	if err := cld.provider.SetACLs(acls); err != nil {
+	start := time.Now()
+	err := cld.provider.SetACLs(acls)
+	cld.observe("SetACLs", start)
+	if err != nil {
 		log.WithError(err).Warnf("Could not update ACLs in %s.", cld)
 	}
 }
 
+// syncLabels applies the infrastructure's labels to the resources shared by the
+// region's machines, if they've changed since they were last applied.
+func (cld *cloud) syncLabels(labels map[string]string) {
+	if cld.labels != nil && str.MapEq(cld.labels, labels) {
+		return
+	}
+
+	c.Inc("SetLabels")
+	start := time.Now()
+	err := cld.provider.SetLabels(labels)
+	cld.observe("SetLabels", start)
+	if err != nil {
+		log.WithError(err).Warnf("Could not update labels in %s.", cld)
+		return
+	}
+
+	cld.labels = labels
+	if cld.labels == nil {
+		cld.labels = map[string]string{}
+	}
+}
+
 func newProviderImpl(p db.ProviderName, namespace, region string) (provider, error) {
 	switch p {
 	case db.Amazon:
@@ -396,8 +651,14 @@ func newProviderImpl(p db.ProviderName, namespace, region string) (provider, err
 		return google.New(namespace, region)
 	case db.DigitalOcean:
 		return digitalocean.New(namespace, region)
+	case db.OpenStack:
+		return openstack.New(namespace, region)
 	case db.Vagrant:
 		return vagrant.New(namespace)
+	case db.Local:
+		return local.New(namespace)
+	case db.Static:
+		return static.New(namespace)
 	default:
 		panic("Unimplemented")
 	}
@@ -411,13 +672,24 @@ func validRegionsImpl(p db.ProviderName) []string {
 		return google.Zones
 	case db.DigitalOcean:
 		return digitalocean.Regions
+	case db.OpenStack:
+		return openstack.Regions()
 	case db.Vagrant:
 		return []string{""} // Vagrant has no regions
+	case db.Local:
+		return []string{""} // Local machines have no regions
+	case db.Static:
+		return []string{""} // Static machines have no regions
 	default:
 		panic("Unimplemented")
 	}
 }
 
+// observe records the latency of a call to the cloud provider API.
+func (cld *cloud) observe(action string, start time.Time) {
+	c.ObserveSince(fmt.Sprintf("%s %s", cld.providerName, action), start)
+}
+
 func (cld *cloud) String() string {
 	return fmt.Sprintf("%s-%s-%s", cld.providerName, cld.region, cld.namespace)
 }
@@ -428,3 +700,16 @@ var getMachineRole = foreman.GetMachineRole
 
 // ValidRegions returns a list of supported regions for a given cloud provider
 var ValidRegions = validRegionsImpl
+
+// ValidateLabels returns an error if the provider can't apply `labels` to its
+// machines.  Providers that ignore labels accept any labels.
+func ValidateLabels(providerName db.ProviderName, labels map[string]string) error {
+	switch providerName {
+	case db.Google:
+		return google.ValidateLabels(labels)
+	case db.DigitalOcean:
+		return digitalocean.ValidateLabels(labels)
+	default:
+		return nil
+	}
+}
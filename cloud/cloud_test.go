@@ -37,6 +37,10 @@ type fakeProvider struct {
 	updatedIPs   []db.Machine
 	aclRequests  []acl.ACL
 
+	updatedLabels []db.Machine
+
+	labelRequests []map[string]string
+
 	listError error
 }
 
@@ -48,7 +52,9 @@ func (p *fakeProvider) clearLogs() {
 	p.bootRequests = nil
 	p.stopRequests = nil
 	p.aclRequests = nil
+	p.labelRequests = nil
 	p.updatedIPs = nil
+	p.updatedLabels = nil
 }
 
 func (p *fakeProvider) List() ([]db.Machine, error) {
@@ -113,6 +119,28 @@ func (p *fakeProvider) UpdateFloatingIPs(machines []db.Machine) error {
 	return nil
 }
 
+func (p *fakeProvider) UpdateLabels(machines []db.Machine) error {
+	for _, desired := range machines {
+		curr := p.machines[desired.CloudID]
+		labels := map[string]string{}
+		for key, value := range curr.Labels {
+			labels[key] = value
+		}
+		for key, value := range desired.Labels {
+			labels[key] = value
+		}
+		curr.Labels = labels
+		p.machines[desired.CloudID] = curr
+	}
+	p.updatedLabels = append(p.updatedLabels, machines...)
+	return nil
+}
+
+func (p *fakeProvider) SetLabels(labels map[string]string) error {
+	p.labelRequests = append(p.labelRequests, labels)
+	return nil
+}
+
 func (p *fakeProvider) Cleanup() error {
 	return nil
 }
@@ -161,7 +189,8 @@ func TestCloudRunOnceInitializesProvider(t *testing.T) {
 	cld.runOnce()
 
 	assert.NotNil(t, cld.provider)
-	assert.Equal(t, provider, cld.provider.(*fakeProvider).providerName)
+	wrapped := cld.provider.(*retryProvider).wrapped
+	assert.Equal(t, provider, wrapped.(*fakeProvider).providerName)
 }
 
 func TestCloudRunOnceProviderInitializationFailure(t *testing.T) {
@@ -184,6 +213,7 @@ func TestCloudRunOnceProviderInitializationFailure(t *testing.T) {
 	// as the test cloud.
 	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
 		bp := view.InsertBlueprint()
+		bp.Namespace = "test"
 		bp.Blueprint.Machines = []blueprint.Machine{{
 			Provider: string(providerName),
 			Region:   testRegion,
@@ -224,7 +254,7 @@ func TestCloudRunOnceProviderInitializationFailure(t *testing.T) {
 	// is logged at debug instead of error level.
 	hook.Reset()
 	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
-		bp, _ := view.GetBlueprint()
+		bp, _ := view.GetBlueprint("test")
 		bp.Blueprint.Machines = []blueprint.Machine{{
 			Provider: string(FakeVagrant),
 			Region:   testRegion,
@@ -319,6 +349,81 @@ func TestACLs(t *testing.T) {
 	assert.Equal(t, exp, actual)
 }
 
+func TestSyncLabels(t *testing.T) {
+	clst := newTestCloud(FakeAmazon, testRegion, "ns")
+	prvdr := clst.provider.(*fakeProvider)
+
+	// The labels are always applied once, even if there aren't any.
+	clst.syncLabels(nil)
+	clst.syncLabels(map[string]string{})
+	assert.Equal(t, []map[string]string{nil}, prvdr.labelRequests)
+
+	prvdr.clearLogs()
+	labels := map[string]string{"team": "infra"}
+	clst.syncLabels(labels)
+	clst.syncLabels(map[string]string{"team": "infra"})
+	assert.Equal(t, []map[string]string{labels}, prvdr.labelRequests)
+
+	prvdr.clearLogs()
+	clst.syncLabels(map[string]string{"team": "web"})
+	assert.Equal(t, []map[string]string{{"team": "web"}}, prvdr.labelRequests)
+}
+
+func TestUpdateLabels(t *testing.T) {
+	cloudJoin = joinImpl
+	clst := newTestCloud(FakeAmazon, testRegion, "ns")
+	prvdr := clst.provider.(*fakeProvider)
+
+	labeledProviders[FakeAmazon] = true
+	defer delete(labeledProviders, FakeAmazon)
+
+	setLabels := func(labels map[string]string) {
+		clst.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+			bp, err := view.GetBlueprint("ns")
+			if err != nil {
+				bp = view.InsertBlueprint()
+				bp.Namespace = "ns"
+			}
+			bp.Blueprint.Labels = labels
+			bp.Blueprint.Machines = []blueprint.Machine{{
+				Provider: string(FakeAmazon),
+				Region:   testRegion,
+				Size:     "m4.large",
+			}}
+			view.Commit(bp)
+			return nil
+		})
+	}
+
+	setLabels(map[string]string{"team": "infra"})
+	clst.runOnce()
+	clst.runOnce()
+	assert.Len(t, prvdr.bootRequests, 1)
+	machines := clst.conn.SelectFromMachine(nil)
+	assert.Len(t, machines, 1)
+	cloudID := machines[0].CloudID
+	assert.NotEmpty(t, cloudID)
+
+	// Changing the labels relabels the machine rather than replacing it.
+	prvdr.clearLogs()
+	setLabels(map[string]string{"team": "web", "env": "prod"})
+	clst.runOnce()
+	assert.Empty(t, prvdr.bootRequests)
+	assert.Empty(t, prvdr.stopRequests)
+	assert.Len(t, prvdr.updatedLabels, 1)
+	assert.Equal(t, cloudID, prvdr.updatedLabels[0].CloudID)
+	assert.Equal(t, map[string]string{"team": "web", "env": "prod"},
+		prvdr.machines[cloudID].Labels)
+
+	// Removing a label leaves it on the machine.
+	prvdr.clearLogs()
+	setLabels(map[string]string{"team": "web"})
+	clst.runOnce()
+	assert.Empty(t, prvdr.bootRequests)
+	assert.Empty(t, prvdr.stopRequests)
+	assert.Empty(t, prvdr.updatedLabels)
+}
+
 func TestStartClouds(t *testing.T) {
 	stop := make(chan struct{})
 	startClouds(db.New(), "ns", stop)
@@ -343,6 +448,124 @@ func TestStartClouds(t *testing.T) {
 	close(stop)
 }
 
+func TestRunStop(t *testing.T) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		Run(db.New(), "key", HealthPolicy{}, stop)
+		close(done)
+	}()
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return")
+	}
+}
+
+func TestRemoveStoppedBlueprints(t *testing.T) {
+	mock()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		// A stopped namespace whose machines are still being stopped.
+		bp := view.InsertBlueprint()
+		bp.Namespace = "stopping"
+		view.Commit(bp)
+
+		m := view.InsertMachine()
+		m.Namespace = "stopping"
+		view.Commit(m)
+
+		// A stopped namespace without any machines.
+		bp = view.InsertBlueprint()
+		bp.Namespace = "stopped"
+		view.Commit(bp)
+
+		// A running namespace whose machines haven't booted yet.
+		bp = view.InsertBlueprint()
+		bp.Namespace = "running"
+		bp.Machines = []blueprint.Machine{{Provider: "Amazon"}}
+		view.Commit(bp)
+		return nil
+	})
+
+	// Stopped namespaces are kept until all of their clouds have checked for
+	// machines, as the daemon may have just restarted.
+	removeStoppedBlueprints(conn)
+	assert.Equal(t, []string{"running", "stopped", "stopping"},
+		conn.GetBlueprintNamespaces())
+
+	for _, ns := range []string{"running", "stopped", "stopping"} {
+		amazon := cloud{namespace: ns, providerName: FakeAmazon,
+			region: testRegion}
+		amazon.setEmpty(true)
+	}
+	removeStoppedBlueprints(conn)
+	assert.Equal(t, []string{"running", "stopped", "stopping"},
+		conn.GetBlueprintNamespaces())
+
+	for _, ns := range []string{"running", "stopped", "stopping"} {
+		vagrant := cloud{namespace: ns, providerName: FakeVagrant,
+			region: testRegion}
+		vagrant.setEmpty(true)
+	}
+	removeStoppedBlueprints(conn)
+	assert.Equal(t, []string{"running", "stopping"},
+		conn.GetBlueprintNamespaces())
+
+	// Clouds that find machines keep the namespace around.
+	stopping := cloud{namespace: "stopping", providerName: FakeAmazon,
+		region: testRegion}
+	stopping.setEmpty(false)
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		view.Remove(view.SelectFromMachine(nil)[0])
+		return nil
+	})
+	removeStoppedBlueprints(conn)
+	assert.Equal(t, []string{"running", "stopping"},
+		conn.GetBlueprintNamespaces())
+
+	stopping.setEmpty(true)
+	removeStoppedBlueprints(conn)
+	assert.Equal(t, []string{"running"}, conn.GetBlueprintNamespaces())
+}
+
+func TestInitFailureEmpty(t *testing.T) {
+	mock()
+	delete(emptyClouds.byNamespace, "uninitialized")
+
+	var initErr error
+	newProvider = func(p db.ProviderName, namespace,
+		region string) (provider, error) {
+		return nil, initErr
+	}
+
+	conn := db.New()
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Namespace = "uninitialized"
+		view.Commit(bp)
+		return nil
+	})
+
+	for _, p := range db.AllProviders {
+		cld := cloud{conn: conn, namespace: "uninitialized", providerName: p,
+			region: testRegion}
+
+		// Transient errors may hide machines.
+		initErr = errors.New("dial tcp: i/o timeout")
+		cld.runOnce()
+		assert.False(t, namespaceEmpty("uninitialized"))
+
+		// Providers that can't be used at all don't have any machines.
+		initErr = errors.New("no credentials")
+		cld.runOnce()
+	}
+	assert.True(t, namespaceEmpty("uninitialized"))
+}
+
 func TestNewProviderFailure(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -356,31 +579,62 @@ func TestDesiredMachines(t *testing.T) {
 	cld := newTestCloud(FakeAmazon, testRegion, "ns")
 	adminKey = "bar"
 
-	res := cld.desiredMachines([]blueprint.Machine{{
-		Provider: "Google", // Wrong Provider
-		Region:   "zone-1",
-	}, {
-		Provider: string(FakeAmazon),
-		Region:   testRegion,
-		Role:     "invalid",
-	}, {
-		Provider:    string(FakeAmazon),
-		Region:      testRegion,
-		Size:        "m4.lage",
-		Preemptible: true,
-		FloatingIP:  "1.2.3.4",
-		Role:        db.Worker,
-		SSHKeys:     []string{"foo"},
-	}})
+	labeledProviders[FakeAmazon] = true
+	defer delete(labeledProviders, FakeAmazon)
+
+	res := cld.desiredMachines(blueprint.Blueprint{
+		Labels:          map[string]string{"team": "infra", "env": "prod"},
+		ContainerSubnet: "172.20.0.0/16",
+		Machines: []blueprint.Machine{{
+			Provider: "Google", // Wrong Provider
+			Region:   "zone-1",
+		}, {
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			Role:     "invalid",
+		}, {
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			OS:       "invalid",
+		}, {
+			Provider:    string(FakeAmazon),
+			Region:      testRegion,
+			Size:        "m4.lage",
+			Preemptible: true,
+			FloatingIP:  "1.2.3.4",
+			Role:        db.Worker,
+			SSHKeys:     []string{"foo"},
+			Image:       "ami-1234",
+			OS:          "Debian",
+			Labels:      map[string]string{"env": "dev"},
+		}}})
 	assert.Equal(t, []db.Machine{{
+		Namespace:   "ns",
 		Provider:    FakeAmazon,
 		Region:      testRegion,
 		Size:        "m4.lage",
 		Preemptible: true,
 		FloatingIP:  "1.2.3.4",
 		Role:        db.Worker,
-		DiskSize:    defaultDiskSize,
-		SSHKeys:     []string{"foo", "bar"}}}, res)
+		DiskSize:    db.DefaultDiskSize,
+		SSHKeys:     []string{"foo", "bar"},
+		Image:       "ami-1234",
+		OS:          db.Debian,
+		Labels:      map[string]string{"team": "infra", "env": "dev"},
+
+		ContainerSubnet: "172.20.0.0/16"}}, res)
+
+	// Labels are ignored on providers that don't apply them.
+	delete(labeledProviders, FakeAmazon)
+	res = cld.desiredMachines(blueprint.Blueprint{
+		Labels: map[string]string{"team": "infra"},
+		Machines: []blueprint.Machine{{
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			Labels:   map[string]string{"env": "dev"},
+		}}})
+	assert.Len(t, res, 1)
+	assert.Nil(t, res[0].Labels)
 }
 
 var instantiatedProviders []fakeProvider
@@ -0,0 +1,30 @@
+package cloud
+
+import "github.com/kelda/kelda/blueprint"
+
+// The names of the secrets that `kelda handoff` writes into a cluster's Vault so
+// that the cluster's leader can manage its own machines.  They're control plane
+// secrets, so containers can't read them.
+const (
+	controlPlanePrefix = blueprint.ControlPlaneSecretPrefix
+
+	// SSHKeySecret holds the private key used to install TLS credentials on
+	// new machines.
+	SSHKeySecret = controlPlanePrefix + "ssh-key"
+
+	// CACertSecret and CAKeySecret hold the certificate authority that signs
+	// the TLS credentials of new machines.
+	CACertSecret = controlPlanePrefix + "ca-certificate"
+	CAKeySecret  = controlPlanePrefix + "ca-key"
+)
+
+// CredentialFiles maps the names of the secrets that hold each provider's
+// credentials to the file, relative to the home directory, that the provider
+// reads them from.
+var CredentialFiles = map[string]string{
+	controlPlanePrefix + "amazon-credentials":       ".aws/credentials",
+	controlPlanePrefix + "google-credentials":       ".gce/kelda.json",
+	controlPlanePrefix + "digitalocean-credentials": ".digitalocean/key",
+	controlPlanePrefix + "openstack-credentials":    ".openstack/kelda.json",
+	controlPlanePrefix + "static-hosts":             ".kelda/static_hosts.json",
+}
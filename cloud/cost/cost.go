@@ -0,0 +1,123 @@
+//go:generate go run gen.go
+
+// Package cost estimates what machines cost to run, based on the on-demand
+// prices that the blueprint bindings use to choose machine sizes.
+package cost
+
+import (
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+)
+
+// HoursPerMonth is the average number of hours in a month.  Cloud providers use
+// it to convert hourly prices into monthly ones.
+const HoursPerMonth = 730
+
+// The price in US dollars per GB-month of an EBS General Purpose SSD volume.
+// Google and DigitalOcean ignore the disk size, and their boot disks are
+// included in the machine's price.
+const amazonDiskPrice = 0.10
+
+// Google's preemptible VMs cost a fixed fraction of the on-demand price.
+const googlePreemptibleFactor = 0.21
+
+// The providers that run on hardware that the user already has.
+var freeProviders = map[db.ProviderName]bool{
+	db.Vagrant: true,
+	db.Local:   true,
+	db.Static:  true,
+}
+
+// Estimate is the estimated cost of running a set of machines.
+type Estimate struct {
+	// The cost per hour in US dollars of the machines whose prices are known.
+	Hourly float64
+
+	// The number of machines whose prices are unknown, and so aren't included
+	// in `Hourly`.
+	Unknown int
+}
+
+// Monthly returns the estimated cost per month in US dollars.
+func (e Estimate) Monthly() float64 {
+	return e.Hourly * HoursPerMonth
+}
+
+// Machines estimates the cost of running `machines`.
+func Machines(machines []db.Machine) Estimate {
+	var est Estimate
+	for _, m := range machines {
+		hourly, ok := Hourly(m)
+		if !ok {
+			est.Unknown++
+		}
+		est.Hourly += hourly
+	}
+	return est
+}
+
+// Blueprint estimates the cost of running the machines in `bp`.
+func Blueprint(bp blueprint.Blueprint) Estimate {
+	return Machines(BlueprintMachines(bp))
+}
+
+// BlueprintMachines converts the machines in `bp` into database machines with the
+// attributes that determine their cost.
+func BlueprintMachines(bp blueprint.Blueprint) []db.Machine {
+	var machines []db.Machine
+	for _, bpm := range bp.Machines {
+		role, _ := db.ParseRole(bpm.Role)
+		machines = append(machines, db.Machine{
+			Role:        role,
+			Provider:    db.ProviderName(bpm.Provider),
+			Region:      bpm.Region,
+			Size:        bpm.Size,
+			DiskSize:    bpm.DiskSize,
+			Preemptible: bpm.Preemptible,
+			MaxPrice:    bpm.MaxPrice,
+		})
+	}
+	return machines
+}
+
+// Hourly returns the cost per hour in US dollars of running `m`, and whether its
+// price is known.  Spot instances are estimated at their maximum price, as the
+// price they're charged varies with demand.
+func Hourly(m db.Machine) (float64, bool) {
+	if freeProviders[m.Provider] {
+		return 0, true
+	}
+
+	price, ok := onDemandPrice(m.Provider, m.Region, m.Size)
+	if m.Preemptible {
+		switch {
+		case m.Provider == db.Google && ok:
+			price *= googlePreemptibleFactor
+		case m.MaxPrice != 0 && (!ok || m.MaxPrice < price):
+			price, ok = m.MaxPrice, true
+		}
+	}
+
+	if !ok {
+		return 0, false
+	}
+
+	if m.Provider == db.Amazon {
+		diskSize := m.DiskSize
+		if diskSize == 0 {
+			diskSize = db.DefaultDiskSize
+		}
+		price += float64(diskSize) * amazonDiskPrice / HoursPerMonth
+	}
+	return price, true
+}
+
+func onDemandPrice(provider db.ProviderName, region, size string) (float64, bool) {
+	if price, ok := prices[provider][region][size]; ok {
+		return price, true
+	}
+
+	// Some providers price sizes the same in every region.
+	price, ok := prices[provider][""][size]
+	return price, ok
+}
@@ -0,0 +1,136 @@
+package cost
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+)
+
+func TestHourly(t *testing.T) {
+	t.Parallel()
+
+	amazonDisk := db.DefaultDiskSize * amazonDiskPrice / HoursPerMonth
+	tests := []struct {
+		machine db.Machine
+		hourly  float64
+		known   bool
+	}{
+		{db.Machine{Provider: db.Amazon, Region: "us-west-1",
+			Size: "m4.large"}, 0.14 + amazonDisk, true},
+		{db.Machine{Provider: db.Amazon, Region: "us-west-1",
+			Size: "m4.large", DiskSize: 73}, 0.15, true},
+
+		// Spot instances are estimated at their maximum price.
+		{db.Machine{Provider: db.Amazon, Region: "us-west-1",
+			Size: "m4.large", Preemptible: true},
+			0.14 + amazonDisk, true},
+		{db.Machine{Provider: db.Amazon, Region: "us-west-1",
+			Size: "m4.large", Preemptible: true, MaxPrice: 0.05},
+			0.05 + amazonDisk, true},
+		{db.Machine{Provider: db.Amazon, Region: "us-west-1",
+			Size: "m4.large", Preemptible: true, MaxPrice: 1},
+			0.14 + amazonDisk, true},
+		{db.Machine{Provider: db.Amazon, Region: "us-west-1",
+			Size: "unknown", Preemptible: true, MaxPrice: 0.05},
+			0.05 + amazonDisk, true},
+
+		// Google prices sizes the same in every zone.
+		{db.Machine{Provider: db.Google, Region: "us-east1-b",
+			Size: "n1-standard-1"}, 0.0475, true},
+		{db.Machine{Provider: db.Google, Region: "us-east1-b",
+			Size: "n1-standard-1", Preemptible: true},
+			0.0475 * googlePreemptibleFactor, true},
+
+		{db.Machine{Provider: db.DigitalOcean, Region: "sfo2",
+			Size: "512mb", DiskSize: 100}, 0.00744, true},
+
+		{db.Machine{Provider: db.Vagrant, Size: "1,1"}, 0, true},
+		{db.Machine{Provider: db.OpenStack, Region: "RegionOne",
+			Size: "m1.small"}, 0, false},
+		{db.Machine{Provider: db.Amazon, Region: "us-west-1",
+			Size: "unknown"}, 0, false},
+	}
+	for _, test := range tests {
+		hourly, known := Hourly(test.machine)
+		assert.InDelta(t, test.hourly, hourly, 1e-9, "%v", test.machine)
+		assert.Equal(t, test.known, known, "%v", test.machine)
+	}
+}
+
+func TestBlueprint(t *testing.T) {
+	t.Parallel()
+
+	bp := blueprint.Blueprint{Machines: []blueprint.Machine{{
+		Role:     "Master",
+		Provider: "Google",
+		Region:   "us-east1-b",
+		Size:     "n1-standard-1",
+	}, {
+		Role:     "Worker",
+		Provider: "Google",
+		Region:   "us-east1-b",
+		Size:     "n1-standard-2",
+	}, {
+		Role:     "Worker",
+		Provider: "OpenStack",
+		Region:   "RegionOne",
+		Size:     "m1.small",
+	}}}
+
+	assert.Equal(t, db.Role(db.Master), BlueprintMachines(bp)[0].Role)
+
+	est := Blueprint(bp)
+	assert.InDelta(t, 0.1425, est.Hourly, 1e-9)
+	assert.InDelta(t, 104.025, est.Monthly(), 1e-9)
+	assert.Equal(t, 1, est.Unknown)
+
+	assert.Equal(t, Estimate{}, Blueprint(blueprint.Blueprint{}))
+}
+
+// The generated prices must match the descriptions used by the bindings.
+func TestPricesUpToDate(t *testing.T) {
+	t.Parallel()
+
+	files := map[db.ProviderName]string{
+		db.Amazon:       "amazonDescriptions.json",
+		db.DigitalOcean: "digitalOceanDescriptions.json",
+		db.Google:       "googleDescriptions.json",
+	}
+	for provider, file := range files {
+		data, err := ioutil.ReadFile("../../js/bindings/" + file)
+		assert.NoError(t, err)
+
+		var descriptions struct {
+			Descriptions []struct {
+				Size   string
+				Region string
+				Price  float64
+			}
+		}
+		assert.NoError(t, json.Unmarshal(data, &descriptions))
+
+		count := 0
+		for _, d := range descriptions.Descriptions {
+			if d.Price == 0 {
+				continue
+			}
+			count++
+
+			price, ok := onDemandPrice(provider, d.Region, d.Size)
+			assert.True(t, ok, "%s %s %s", provider, d.Region, d.Size)
+			assert.Equal(t, d.Price, price,
+				"%s %s %s", provider, d.Region, d.Size)
+		}
+
+		actual := 0
+		for _, sizes := range prices[provider] {
+			actual += len(sizes)
+		}
+		assert.Equal(t, count, actual, "Run `go generate` in cloud/cost.")
+	}
+}
@@ -0,0 +1,118 @@
+// +build ignore
+
+// gen generates prices.go from the machine descriptions used by the blueprint
+// bindings, so that the CLI and daemon estimate costs with the same prices that
+// the bindings use to choose machine sizes.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const bindingsDir = "../../js/bindings"
+
+// The description files of the providers that have prices, keyed by the name of
+// the provider's db.ProviderName constant.
+var descriptionFiles = map[string]string{
+	"Amazon":       "amazonDescriptions.json",
+	"DigitalOcean": "digitalOceanDescriptions.json",
+	"Google":       "googleDescriptions.json",
+}
+
+type description struct {
+	Size   string
+	Region string
+	Price  float64
+}
+
+func main() {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen.go. DO NOT EDIT.\n\n" +
+		"package cost\n\n" +
+		"import \"github.com/kelda/kelda/db\"\n\n" +
+		"// The on-demand price in US dollars per hour of each machine size, " +
+		"keyed by\n// provider, region, and size.  Sizes that are priced " +
+		"the same in every region\n// are under the empty region.\n" +
+		"var prices = map[db.ProviderName]map[string]map[string]float64{\n")
+
+	var providers []string
+	for provider := range descriptionFiles {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	for _, provider := range providers {
+		prices, err := readPrices(descriptionFiles[provider])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read %s prices: %s\n", provider, err)
+			os.Exit(1)
+		}
+
+		var regions []string
+		for region := range prices {
+			regions = append(regions, region)
+		}
+		sort.Strings(regions)
+
+		fmt.Fprintf(&buf, "db.%s: {\n", provider)
+		for _, region := range regions {
+			var sizes []string
+			for size := range prices[region] {
+				sizes = append(sizes, size)
+			}
+			sort.Strings(sizes)
+
+			fmt.Fprintf(&buf, "%q: {\n", region)
+			for _, size := range sizes {
+				fmt.Fprintf(&buf, "%q: %v,\n", size, prices[region][size])
+			}
+			buf.WriteString("},\n")
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "format: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := ioutil.WriteFile("prices.go", src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "write: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func readPrices(file string) (map[string]map[string]float64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(bindingsDir, file))
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptions struct{ Descriptions []description }
+	if err := json.Unmarshal(data, &descriptions); err != nil {
+		return nil, err
+	}
+
+	prices := map[string]map[string]float64{}
+	for _, d := range descriptions.Descriptions {
+		// Sizes without a price are ones whose price Kelda doesn't know.
+		if d.Price == 0 {
+			continue
+		}
+
+		if _, ok := prices[d.Region]; !ok {
+			prices[d.Region] = map[string]float64{}
+		}
+		prices[d.Region][d.Size] = d.Price
+	}
+	return prices, nil
+}
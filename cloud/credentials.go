@@ -26,9 +26,19 @@ var credentialsCounter = counter.New("Cloud Credentials")
 // over using the given ssh key. It only installs certificates once -- once
 // certificates are in place on a machine, they are left alone. SyncCredentials
 // also writes the installed signed certificate for each machine into the
-// database.
-func SyncCredentials(conn db.Conn, sshKey ssh.Signer, ca rsa.KeyPair) {
-	for range conn.TriggerTick(30, db.MachineTable).C {
+// database.  It returns once `stop` is closed.
+func SyncCredentials(conn db.Conn, sshKey ssh.Signer, ca rsa.KeyPair,
+	stop <-chan struct{}) {
+
+	trigg := conn.TriggerTick(30, db.MachineTable)
+	defer trigg.Stop()
+
+	for {
+		select {
+		case <-trigg.C:
+		case <-stop:
+			return
+		}
 		syncCredentialsOnce(conn, sshKey, ca)
 	}
 }
@@ -64,8 +74,11 @@ func syncCredentialsOnce(conn db.Conn, sshKey ssh.Signer, ca rsa.KeyPair) {
 
 // generateAndInstallCerts attempts to generate a certificate key pair and install
 // it onto the given machine. If a certificate was already installed, it simply
-// returns the contents of the previously installed certificate. Returns the
-// public key of the installed certificate, and whether it was successful.
+// returns the contents of the previously installed certificate.  etcd's
+// certificate is installed if it's missing, even if the machine already has its
+// minion certificate, because machines booted by earlier releases only have the
+// latter.  Returns the public key of the installed certificate, and whether it
+// was successful.
 func generateAndInstallCerts(machine db.Machine, sshKey ssh.Signer,
 	ca rsa.KeyPair) (string, bool) {
 	fs, err := getSftpFs(machine.PublicIP, sshKey)
@@ -78,6 +91,8 @@ func generateAndInstallCerts(machine db.Machine, sshKey ssh.Signer,
 	}
 	defer fs.Close()
 
+	var publicKey string
+	var files []tlsIO.File
 	certPath := tlsIO.SignedCertPath(tlsIO.MinionTLSDir)
 	if _, err := fs.Stat(certPath); err == nil {
 		existingCert, err := afero.Afero{Fs: fs}.ReadFile(certPath)
@@ -86,16 +101,35 @@ func generateAndInstallCerts(machine db.Machine, sshKey ssh.Signer,
 				"Failed to read existing certificate")
 			return "", false
 		}
-		return string(existingCert), true
+		publicKey = string(existingCert)
+	} else {
+		// Generate new certificates signed by the CA for use by the minion
+		// for all communication.
+		signed, err := rsa.NewSigned(ca, net.ParseIP(machine.PrivateIP))
+		if err != nil {
+			log.WithError(err).WithField("host", machine.PublicIP).
+				Error("Failed to generate certs. Retrying.")
+			return "", false
+		}
+		publicKey = signed.CertString()
+		files = tlsIO.MinionFiles(tlsIO.MinionTLSDir, ca, signed)
 	}
 
-	// Generate new certificates signed by the CA for use by the minion for all
-	// communication.
-	signed, err := rsa.NewSigned(ca, net.ParseIP(machine.PrivateIP))
-	if err != nil {
-		log.WithError(err).WithField("host", machine.PublicIP).
-			Error("Failed to generate certs. Retrying.")
-		return "", false
+	if _, err := fs.Stat(tlsIO.EtcdKeyPath(tlsIO.MinionTLSDir)); err != nil {
+		// etcd's certificate also covers the loopback address, because
+		// minions connect to their local etcd.
+		etcd, err := rsa.NewSigned(ca, net.ParseIP(machine.PrivateIP),
+			net.ParseIP("127.0.0.1"))
+		if err != nil {
+			log.WithError(err).WithField("host", machine.PublicIP).
+				Error("Failed to generate etcd certs. Retrying.")
+			return "", false
+		}
+		files = append(files, tlsIO.EtcdFiles(tlsIO.MinionTLSDir, etcd)...)
+	}
+
+	if len(files) == 0 {
+		return publicKey, true
 	}
 
 	// Create the directory in which the credentials will be installed. This is
@@ -107,7 +141,7 @@ func generateAndInstallCerts(machine db.Machine, sshKey ssh.Signer,
 		return "", false
 	}
 
-	for _, f := range tlsIO.MinionFiles(tlsIO.MinionTLSDir, ca, signed) {
+	for _, f := range files {
 		if err := write(fs, f.Path, f.Content, f.Mode); err != nil {
 			log.WithFields(log.Fields{
 				"error": err,
@@ -118,7 +152,7 @@ func generateAndInstallCerts(machine db.Machine, sshKey ssh.Signer,
 		}
 	}
 
-	return signed.CertString(), true
+	return publicKey, true
 }
 
 func write(fs afero.Fs, path, contents string, mode os.FileMode) error {
@@ -3,6 +3,8 @@ package cloud
 import (
 	"crypto/rand"
 	goRSA "crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -58,6 +60,20 @@ func TestSyncCredentials(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, caBytes)
 
+	// etcd's certificate covers both the machine's private IP and the loopback
+	// address.
+	etcdCertBytes, err := aferoFs.ReadFile(tlsIO.EtcdCertPath(tlsIO.MinionTLSDir))
+	assert.NoError(t, err)
+	etcdKeyBytes, err := aferoFs.ReadFile(tlsIO.EtcdKeyPath(tlsIO.MinionTLSDir))
+	assert.NoError(t, err)
+	etcdCert, err := tls.X509KeyPair(etcdCertBytes, etcdKeyBytes)
+	assert.NoError(t, err)
+	etcdX509, err := x509.ParseCertificate(etcdCert.Certificate[0])
+	assert.NoError(t, err)
+	assert.Len(t, etcdX509.IPAddresses, 2)
+	assert.Equal(t, "9.9.9.9", etcdX509.IPAddresses[0].String())
+	assert.Equal(t, "127.0.0.1", etcdX509.IPAddresses[1].String())
+
 	// Ensure that the machine's public key got written to the database.
 	dbm := conn.SelectFromMachine(nil)[0]
 	assert.Equal(t, string(certBytes), dbm.PublicKey)
@@ -83,8 +99,8 @@ func TestExistingCredentialsDontGetOverwritten(t *testing.T) {
 
 	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
 		dbm := view.InsertMachine()
-		dbm.PublicIP = "foo"
-		dbm.PrivateIP = "bar"
+		dbm.PublicIP = "8.8.8.8"
+		dbm.PrivateIP = "9.9.9.9"
 		view.Commit(dbm)
 		return nil
 	})
@@ -99,6 +115,24 @@ func TestExistingCredentialsDontGetOverwritten(t *testing.T) {
 		tlsIO.SignedCertPath(tlsIO.MinionTLSDir))
 	assert.NoError(t, err)
 	assert.Equal(t, existingCert, string(certOnMachine))
+
+	// The machine was booted without an etcd certificate, so it's installed.
+	etcdCert, err := aferoFs.ReadFile(tlsIO.EtcdCertPath(tlsIO.MinionTLSDir))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, etcdCert)
+
+	// The etcd certificate isn't overwritten either.
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		dbm := view.SelectFromMachine(nil)[0]
+		dbm.PublicKey = ""
+		view.Commit(dbm)
+		return nil
+	})
+	syncCredentialsOnce(conn, nil, ca)
+	etcdCertAfter, err := aferoFs.ReadFile(
+		tlsIO.EtcdCertPath(tlsIO.MinionTLSDir))
+	assert.NoError(t, err)
+	assert.Equal(t, etcdCert, etcdCertAfter)
 }
 
 func TestFailedToSSH(t *testing.T) {
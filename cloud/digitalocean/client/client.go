@@ -5,6 +5,7 @@ package client
 import (
 	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/digitalocean/godo"
 	"github.com/kelda/kelda/counter"
@@ -19,6 +20,8 @@ type Client interface {
 	ListDroplets(*godo.ListOptions, string) ([]godo.Droplet, *godo.Response, error)
 
 	CreateTag(string) (*godo.Tag, *godo.Response, error)
+	TagDroplet(string, int) (*godo.Response, error)
+	UntagDroplet(string, int) (*godo.Response, error)
 
 	ListFloatingIPs(*godo.ListOptions) ([]godo.FloatingIP, *godo.Response, error)
 	AssignFloatingIP(string, int) (*godo.Action, *godo.Response, error)
@@ -73,6 +76,25 @@ func (client client) CreateTag(name string) (*godo.Tag, *godo.Response, error) {
 	)
 }
 
+func (client client) TagDroplet(tag string, id int) (*godo.Response, error) {
+	c.Inc("Tag Droplet")
+	return client.tags.TagResources(context.Background(), tag,
+		&godo.TagResourcesRequest{Resources: dropletResources(id)})
+}
+
+func (client client) UntagDroplet(tag string, id int) (*godo.Response, error) {
+	c.Inc("Untag Droplet")
+	return client.tags.UntagResources(context.Background(), tag,
+		&godo.UntagResourcesRequest{Resources: dropletResources(id)})
+}
+
+func dropletResources(id int) []godo.Resource {
+	return []godo.Resource{{
+		ID:   strconv.Itoa(id),
+		Type: godo.DropletResourceType,
+	}}
+}
+
 func (client client) ListFloatingIPs(opt *godo.ListOptions) ([]godo.FloatingIP,
 	*godo.Response, error) {
 	c.Inc("List Floating IPs")
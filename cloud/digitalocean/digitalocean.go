@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/join"
 	"github.com/kelda/kelda/util"
+	"github.com/kelda/kelda/util/str"
 	log "github.com/sirupsen/logrus"
 
 	"golang.org/x/oauth2"
@@ -33,13 +36,6 @@ var c = counter.New("Digital Ocean")
 var apiKeyPath = ".digitalocean/key"
 
 var (
-	// When creating firewall rules, the API requires that each rule have a protocol
-	// associated with it. It accepts the ones listed below, and we want to allow
-	// traffic only based on IP and port, so allow them all.
-	//
-	// https://developers.digitalocean.com/documentation/v2/#add-rules-to-a-firewall
-	protocols = []string{"tcp", "udp", "icmp"}
-
 	allIPs = &godo.Destinations{
 		Addresses: []string{"0.0.0.0/0", "::/0"},
 	}
@@ -64,6 +60,16 @@ var (
 	}
 )
 
+// The labels that can be turned into tags of the form "key:value".  The key
+// can't contain colons so that the tag can be parsed, and DigitalOcean limits
+// tags to 255 characters.
+var (
+	tagKeyPattern   = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	tagValuePattern = regexp.MustCompile(`^[a-zA-Z0-9_:-]*$`)
+)
+
+const maxTagLength = 255
+
 // 16.04.1 x64 created at 2017-02-03.
 var imageID = 22601368
 
@@ -143,6 +149,8 @@ func (prvdr Provider) List() (machines []db.Machine, err error) {
 				FloatingIP:  floatingIPs[d.ID],
 				Size:        d.SizeSlug,
 				Preemptible: false,
+				Image:       dropletImage(d),
+				Labels:      parseTags(d.Tags),
 			}
 			machines = append(machines, machine)
 		}
@@ -185,7 +193,12 @@ func (prvdr Provider) getFloatingIPs() (map[int]string, error) {
 func (prvdr Provider) Boot(machines []db.Machine) ([]string, error) {
 	type bootRequest struct {
 		size     string
+		image    string
 		userData string
+
+		// The droplet's tags, joined by commas, as bootRequest is a map
+		// key.  Commas aren't allowed in tags.
+		tags string
 	}
 
 	bootSet := map[bootRequest]int{}
@@ -195,7 +208,13 @@ func (prvdr Provider) Boot(machines []db.Machine) ([]string, error) {
 			return nil, err
 		}
 
-		br := bootRequest{size: m.Size, userData: cfg.Ubuntu(m, "")}
+		br := bootRequest{
+			size:     m.Size,
+			image:    m.Image,
+			userData: cfg.BootScript(m, ""),
+			tags: strings.Join(append([]string{prvdr.getTag()},
+				labelTags(m.Labels)...), ","),
+		}
 		bootSet[br] = bootSet[br] + 1
 	}
 
@@ -219,10 +238,10 @@ func (prvdr Provider) Boot(machines []db.Machine) ([]string, error) {
 				Names:             names,
 				Region:            prvdr.region,
 				Size:              br.size,
-				Image:             godo.DropletCreateImage{ID: imageID},
+				Image:             createImage(br.image),
 				PrivateNetworking: true,
 				UserData:          br.userData,
-				Tags:              []string{prvdr.getTag()}})
+				Tags:              strings.Split(br.tags, ",")})
 		}
 	}
 
@@ -253,11 +272,142 @@ func (prvdr Provider) Boot(machines []db.Machine) ([]string, error) {
 	return ids, nil
 }
 
+// createImage converts an image, given as either a slug or an ID, into a
+// request to boot it.  The empty string is the default Ubuntu image.
+func createImage(image string) godo.DropletCreateImage {
+	if image == "" {
+		return godo.DropletCreateImage{ID: imageID}
+	}
+
+	if id, err := strconv.Atoi(image); err == nil {
+		return godo.DropletCreateImage{ID: id}
+	}
+	return godo.DropletCreateImage{Slug: image}
+}
+
+// dropletImage returns the image that `d` booted, in the form users refer to
+// it: the slug for public images, and the ID for snapshots, which don't have
+// slugs.
+func dropletImage(d godo.Droplet) string {
+	switch {
+	case d.Image == nil:
+		return ""
+	case d.Image.Slug != "":
+		return d.Image.Slug
+	default:
+		return strconv.Itoa(d.Image.ID)
+	}
+}
+
 // Returns a unique tag to use for all entities in this namespace and region.
 func (prvdr Provider) getTag() string {
 	return fmt.Sprintf("%s-%s", prvdr.namespace, prvdr.region)
 }
 
+// labelTags converts `labels` into tags of the form "key:value", sorted by key.
+// DigitalOcean tags don't have values of their own.
+func labelTags(labels map[string]string) []string {
+	var tags []string
+	for key, value := range labels {
+		tags = append(tags, key+":"+value)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// parseTags converts the tags created by labelTags back into labels, or returns
+// nil if there are none.
+func parseTags(tags []string) map[string]string {
+	var labels map[string]string
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels
+}
+
+// ValidateLabels returns an error if `labels` can't be turned into DigitalOcean
+// tags, so that invalid labels are rejected when they're deployed rather than
+// when the machines boot.
+func ValidateLabels(labels map[string]string) error {
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := labels[key]
+		switch {
+		case !tagKeyPattern.MatchString(key):
+			return fmt.Errorf("label key %q may only contain letters, "+
+				"numbers, dashes, and underscores", key)
+		case !tagValuePattern.MatchString(value):
+			return fmt.Errorf("label value %q may only contain letters, "+
+				"numbers, colons, dashes, and underscores", value)
+		case len(key)+len(":")+len(value) > maxTagLength:
+			return fmt.Errorf("label %s:%s is longer than %d characters",
+				key, value, maxTagLength)
+		}
+	}
+	return nil
+}
+
+// UpdateLabels tags the droplets of `machines` with their labels.  The tags for
+// the old values of the labels are removed, but tags for other keys are left
+// alone.
+func (prvdr Provider) UpdateLabels(machines []db.Machine) error {
+	for _, m := range machines {
+		id, err := strconv.Atoi(m.CloudID)
+		if err != nil {
+			return err
+		}
+
+		droplet, _, err := prvdr.GetDroplet(id)
+		if err != nil {
+			return fmt.Errorf("get droplet %d: %s", id, err)
+		}
+
+		for _, tag := range labelTags(m.Labels) {
+			if str.SliceContains(droplet.Tags, tag) {
+				continue
+			}
+
+			if _, _, err := prvdr.CreateTag(tag); err != nil {
+				return fmt.Errorf("create tag %s: %s", tag, err)
+			}
+
+			if _, err := prvdr.TagDroplet(tag, id); err != nil {
+				return fmt.Errorf("tag droplet %d: %s", id, err)
+			}
+		}
+
+		for _, tag := range droplet.Tags {
+			parts := strings.SplitN(tag, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			desired, ok := m.Labels[parts[0]]
+			if !ok || desired == parts[1] {
+				continue
+			}
+
+			if _, err := prvdr.UntagDroplet(tag, id); err != nil {
+				return fmt.Errorf("untag droplet %d: %s", id, err)
+			}
+		}
+	}
+	return nil
+}
+
 // UpdateFloatingIPs updates Droplet to Floating IP associations.
 func (prvdr Provider) UpdateFloatingIPs(desired []db.Machine) error {
 	curr, err := prvdr.List()
@@ -363,6 +513,12 @@ func (prvdr Provider) SetACLs(acls []acl.ACL) error {
 	return nil
 }
 
+// SetLabels is a noop because DigitalOcean's firewalls and floating IPs can't be
+// tagged.  The tags of a firewall select the droplets that it applies to.
+func (prvdr Provider) SetLabels(map[string]string) error {
+	return nil
+}
+
 // Cleanup removes unnecessary detritus from this provider.  It's intended to be called
 // when there are no VMS running or expected to be running soon.
 func (prvdr Provider) Cleanup() error {
@@ -485,7 +641,7 @@ func toRules(acls []acl.ACL) (rules []godo.InboundRule) {
 	icmpSources := map[string]struct{}{}
 
 	for _, acl := range acls {
-		for _, proto := range protocols {
+		for _, proto := range append(acl.PortProtocols(), "icmp") {
 			portRange := fmt.Sprintf("%d-%d", acl.MinPort, acl.MaxPort)
 			if acl.MinPort == acl.MaxPort {
 				portRange = fmt.Sprintf("%d", acl.MinPort)
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -71,6 +72,7 @@ func TestList(t *testing.T) {
 			ID:        125,
 			Networks:  network,
 			SizeSlug:  "size",
+			Image:     &godo.Image{ID: 1, Slug: "ubuntu-16-04-x64"},
 			VolumeIDs: []string{"foo"},
 			Region:    sfo}}
 
@@ -138,7 +140,8 @@ func TestList(t *testing.T) {
 			PrivateIP:   "privateIP",
 			FloatingIP:  "floatingIP",
 			Size:        "size",
-			Preemptible: false}}, machines)
+			Preemptible: false,
+			Image:       "ubuntu-16-04-x64"}}, machines)
 
 	// Error ListDroplets.
 	mc.On("ListFloatingIPs", mock.Anything).Return(nil, &godo.Response{}, nil).Once()
@@ -189,9 +192,14 @@ func TestBoot(t *testing.T) {
 	for i := 0; i < 11; i++ {
 		bootSet = append(bootSet, db.Machine{Size: "size1"})
 	}
-	bootSet = append(bootSet, db.Machine{Size: "size2"}, db.Machine{Size: "size2"})
-
-	userData := cfg.Ubuntu(bootSet[0], "")
+	labels := map[string]string{"team": "infra", "env": "prod"}
+	bootSet = append(bootSet,
+		db.Machine{Size: "size2", Image: "debian-9-x64", OS: db.Debian,
+			Labels: labels},
+		db.Machine{Size: "size2", Image: "debian-9-x64", OS: db.Debian,
+			Labels: labels})
+
+	userData := cfg.BootScript(bootSet[0], "")
 	mc.On("CreateDroplets", &godo.DropletMultiCreateRequest{
 		Names: []string{"Kelda", "Kelda", "Kelda", "Kelda", "Kelda",
 			"Kelda", "Kelda", "Kelda", "Kelda", "Kelda"},
@@ -218,10 +226,11 @@ func TestBoot(t *testing.T) {
 		Names:             []string{"Kelda", "Kelda"},
 		Region:            DefaultRegion,
 		Size:              "size2",
-		Image:             godo.DropletCreateImage{ID: imageID},
+		Image:             godo.DropletCreateImage{Slug: "debian-9-x64"},
 		PrivateNetworking: true,
-		UserData:          userData,
-		Tags:              []string{doPrvdr.getTag()},
+		UserData:          cfg.BootScript(bootSet[11], ""),
+		Tags: []string{doPrvdr.getTag(), "env:prod",
+			"team:infra"},
 	}).Return([]godo.Droplet{{ID: 12}, {ID: 13}}, nil, nil).Once()
 
 	ids, err = doPrvdr.Boot(bootSet)
@@ -237,6 +246,82 @@ func TestBoot(t *testing.T) {
 	assert.Nil(t, ids)
 }
 
+func TestTags(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, labelTags(nil))
+	assert.Equal(t, []string{"env:prod", "team:infra"},
+		labelTags(map[string]string{"team": "infra", "env": "prod"}))
+
+	assert.Nil(t, parseTags([]string{"namespace-sfo2"}))
+	assert.Equal(t, map[string]string{"team": "infra", "url": "a:b"},
+		parseTags([]string{"namespace-sfo2", "team:infra", "url:a:b"}))
+}
+
+func TestValidateLabels(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidateLabels(nil))
+	assert.NoError(t, ValidateLabels(map[string]string{
+		"Team": "infra", "url": "a:b", "empty": ""}))
+
+	assert.EqualError(t, ValidateLabels(map[string]string{"a:b": "c"}),
+		`label key "a:b" may only contain letters, numbers, dashes, `+
+			`and underscores`)
+	assert.EqualError(t, ValidateLabels(map[string]string{"team": "a b"}),
+		`label value "a b" may only contain letters, numbers, colons, `+
+			`dashes, and underscores`)
+	assert.EqualError(t, ValidateLabels(map[string]string{
+		"team": strings.Repeat("a", 251)}),
+		"label team:"+strings.Repeat("a", 251)+" is longer than 255 "+
+			"characters")
+}
+
+func TestUpdateLabels(t *testing.T) {
+	mc := new(mocks.Client)
+	doPrvdr, err := newDigitalOcean(testNamespace, DefaultRegion)
+	assert.Nil(t, err)
+	doPrvdr.Client = mc
+
+	mc.On("GetDroplet", 123).Return(&godo.Droplet{
+		ID:   123,
+		Tags: []string{"namespace-sfo1", "team:web", "env:prod", "owner:me"},
+	}, nil, nil)
+	mc.On("CreateTag", "team:infra").Return(nil, nil, nil).Once()
+	mc.On("TagDroplet", "team:infra", 123).Return(nil, nil).Once()
+	mc.On("UntagDroplet", "team:web", 123).Return(nil, nil).Once()
+
+	// Tags that already match, and tags for other keys, are left alone.
+	err = doPrvdr.UpdateLabels([]db.Machine{{
+		CloudID: "123",
+		Labels:  map[string]string{"team": "infra", "env": "prod"},
+	}})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+
+	mc.On("CreateTag", "team:infra").Return(nil, nil, errMock)
+	err = doPrvdr.UpdateLabels([]db.Machine{{
+		CloudID: "123",
+		Labels:  map[string]string{"team": "infra"},
+	}})
+	assert.EqualError(t, err, "create tag team:infra: error")
+}
+
+func TestImages(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, godo.DropletCreateImage{ID: imageID}, createImage(""))
+	assert.Equal(t, godo.DropletCreateImage{ID: 123}, createImage("123"))
+	assert.Equal(t, godo.DropletCreateImage{Slug: "debian-9-x64"},
+		createImage("debian-9-x64"))
+
+	assert.Equal(t, "", dropletImage(godo.Droplet{}))
+	assert.Equal(t, "123", dropletImage(godo.Droplet{
+		Image: &godo.Image{ID: 123}}))
+	assert.Equal(t, "debian-9-x64", dropletImage(godo.Droplet{
+		Image: &godo.Image{ID: 123, Slug: "debian-9-x64"}}))
+}
+
 func TestBootPreemptible(t *testing.T) {
 	t.Parallel()
 
@@ -473,6 +558,8 @@ func TestToRules(t *testing.T) {
 		{CidrIP: "3.0.0.0/8", MinPort: 0, MaxPort: 100},
 		{CidrIP: "1.0.0.0/8", MinPort: 4000, MaxPort: 4000},
 		{CidrIP: "1.0.0.0/8", MinPort: 500, MaxPort: 600},
+		{CidrIP: "4.0.0.0/8", MinPort: 53, MaxPort: 53, Protocol: "udp"},
+		{CidrIP: "5.0.0.0/8", Protocol: "icmp"},
 	}
 	srcForIP := func(ip string) *godo.Sources {
 		return &godo.Sources{Addresses: []string{ip}}
@@ -497,6 +584,12 @@ func TestToRules(t *testing.T) {
 		{Protocol: "tcp", PortRange: "500-600", Sources: srcForIP("1.0.0.0/8")},
 		{Protocol: "udp", PortRange: "500-600", Sources: srcForIP("1.0.0.0/8")},
 		// Nor do we want one here.
+
+		// ACLs with a protocol only allow that protocol, and ICMP.
+		{Protocol: "udp", PortRange: "53", Sources: srcForIP("4.0.0.0/8")},
+		{Protocol: "icmp", Sources: srcForIP("4.0.0.0/8")},
+
+		{Protocol: "icmp", Sources: srcForIP("5.0.0.0/8")},
 	}
 	assert.Equal(t, godoRules, toRules(acls))
 }
@@ -0,0 +1,93 @@
+package cloud
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kelda/kelda/cloud/foreman"
+	"github.com/kelda/kelda/db"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// How long the daemon waits for the containers on a worker that it's about to
+// stop to move to other workers, before stopping the worker anyways.
+var drainTimeout = 5 * time.Minute
+
+var isDrained = foreman.IsDrained
+var isSettled = foreman.IsSettled
+
+// drainTracker tracks when the daemon started draining each of the workers
+// that it's about to stop.  It's shared by all the cloud threads.
+type drainTracker struct {
+	sync.Mutex
+
+	// When each machine, keyed by cloud ID, started draining.
+	since map[string]time.Time
+}
+
+var drains = &drainTracker{since: map[string]time.Time{}}
+
+// filterDraining splits `dbms` into the machines that are being drained before
+// they're stopped, and the rest.  Draining machines are left out of the join
+// with the blueprint, so that the same machines are stopped once they drain.
+func filterDraining(dbms []db.Machine) (live, draining []db.Machine) {
+	for _, dbm := range dbms {
+		if dbm.Status == db.Draining {
+			draining = append(draining, dbm)
+		} else {
+			live = append(live, dbm)
+		}
+	}
+	return live, draining
+}
+
+// needsDrain returns whether `dbm`, which is no longer in the blueprint, should
+// be drained before it's stopped.  Only connected workers run containers that
+// can be moved to other workers.
+func needsDrain(dbm db.Machine) bool {
+	return dbm.Role == db.Worker && dbm.Status != db.Stopping &&
+		isConnected(dbm.CloudID)
+}
+
+// drained returns whether the draining machine `dbm` may be stopped, either
+// because its containers moved to other workers, its minion disconnected, or it
+// didn't drain within the drain timeout.  Containers have only moved once Docker
+// stopped them on `dbm`, and the connected machines in `cluster` report that
+// the containers are scheduled elsewhere and running.
+func (d *drainTracker) drained(dbm db.Machine, cluster []db.Machine) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	since, ok := d.since[dbm.CloudID]
+	if !ok {
+		since = now()
+		d.since[dbm.CloudID] = since
+	}
+
+	switch {
+	case isDrained(dbm.CloudID) && settled(dbm, cluster):
+	case !isConnected(dbm.CloudID):
+	case now().Sub(since) >= drainTimeout:
+		c.Inc("Drain Timeout")
+		log.WithField("machine", dbm).Warn("Machine didn't drain within " +
+			"the timeout. Stopping it anyways.")
+	default:
+		return false
+	}
+
+	delete(d.since, dbm.CloudID)
+	return true
+}
+
+// settled returns whether every connected machine in `cluster` other than `dbm`
+// reports that the containers it's responsible for are where they should be.
+func settled(dbm db.Machine, cluster []db.Machine) bool {
+	for _, m := range cluster {
+		if m.CloudID != dbm.CloudID && isConnected(m.CloudID) &&
+			!isSettled(m.CloudID) {
+			return false
+		}
+	}
+	return true
+}
@@ -9,10 +9,14 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/kelda/kelda/api"
+	apiClient "github.com/kelda/kelda/api/client"
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/connection"
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/pb"
+	"github.com/kelda/kelda/version"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -23,18 +27,24 @@ var credentials connection.Credentials
 type client interface {
 	setMinion(pb.MinionConfig) error
 	getMinion() (pb.MinionConfig, error)
+	getVersion() (string, error)
 	Close()
 }
 
 type clientImpl struct {
 	pb.MinionClient
 	cc *grpc.ClientConn
+	ip string
 }
 
 // The minion information that is shared between threads.
 type minionStatus struct {
-	connected bool
-	role      db.Role
+	connected   bool
+	role        db.Role
+	terminating bool
+	drained     bool
+	settled     bool
+	version     string
 }
 
 // A map from cloud ID to the corresponding `minionStatus`. This map is shared
@@ -45,13 +55,23 @@ var statusLock = &sync.Mutex{}
 var c = counter.New("Foreman")
 
 // Run checks for updates to the machine table and starts and stops minion threads
-// in response.
-func Run(conn db.Conn, creds connection.Credentials) {
+// in response, until `stop` is closed.
+func Run(conn db.Conn, creds connection.Credentials, stop <-chan struct{}) {
 	credentials = creds
 	// A map from cloud ID to the stop channel for the corresponding minion thread.
 	minionChans := make(map[string]chan struct{})
 
-	for range conn.Trigger(db.MachineTable).C {
+	trigg := conn.Trigger(db.MachineTable)
+	defer trigg.Stop()
+
+	for {
+		select {
+		case <-trigg.C:
+		case <-stop:
+			updateMinions(conn, nil, minionChans)
+			return
+		}
+
 		machines := conn.SelectFromMachine(func(m db.Machine) bool {
 			return m.PublicIP != "" && m.PrivateIP != "" &&
 				m.CloudID != "" && m.Status != db.Stopping
@@ -120,37 +140,46 @@ func newMinionImpl(conn db.Conn, cloudID string, stop chan struct{}) {
 		}
 
 		var currConfig pb.MinionConfig
-		currConfig, connected = runOnce(waitForMachinesCutoff, conn, cloudID)
-		setMinionStatus(cloudID, currConfig, connected)
+		var minionVersion string
+		currConfig, minionVersion, connected = runOnce(waitForMachinesCutoff,
+			conn, cloudID)
+		setMinionStatus(cloudID, currConfig, minionVersion, connected)
 	}
 }
 
 func runOnce(waitForMachinesCutoff time.Time, conn db.Conn, cloudID string) (
-	currConfig pb.MinionConfig, connected bool) {
+	currConfig pb.MinionConfig, minionVersion string, connected bool) {
 	currConfig = pb.MinionConfig{}
 	connected = false
 
-	var blueprint string
+	var bp blueprint.Blueprint
 	var machines []db.Machine
+	var minionMachine db.Machine
+	var found bool
 	conn.Txn(db.BlueprintTable, db.MachineTable).Run(func(view db.Database) error {
-		bp, _ := view.GetBlueprint()
-		blueprint = bp.Blueprint.String()
-
 		machines = view.SelectFromMachine(func(m db.Machine) bool {
 			return m.CloudID != "" && m.PublicIP != "" &&
 				m.PrivateIP != "" && m.Status != db.Stopping
 		})
+
+		for _, m := range machines {
+			if m.CloudID == cloudID {
+				minionMachine = m
+				found = true
+			}
+		}
+		if !found {
+			return nil
+		}
+
+		// Each namespace is a separate cluster, so minions are only configured
+		// with the machines in their own namespace.
+		machines = filterNamespace(machines, minionMachine.Namespace)
+		dbBlueprint, _ := view.GetBlueprint(minionMachine.Namespace)
+		bp = dbBlueprint.Blueprint
 		return nil
 	})
 
-	var minionMachine db.Machine
-	var found bool
-	for _, m := range machines {
-		if m.CloudID == cloudID {
-			minionMachine = m
-			found = true
-		}
-	}
 	if !found {
 		log.Debugf("Failed to get machine with ID %s", cloudID)
 		return
@@ -173,6 +202,25 @@ func runOnce(waitForMachinesCutoff time.Time, conn db.Conn, cloudID string) (
 		return
 	}
 
+	// The minion may have been upgraded and restarted since the last poll
+	// without the foreman noticing the disconnect, so the version is read every
+	// time.  If it can't be read, the last known version is used.
+	minionVersion, err = cli.getVersion()
+	if err != nil {
+		log.WithError(err).Debug("Failed to get minion version")
+		minionVersion = getMinionStatus(cloudID).version
+	}
+
+	// Minions running an incompatible version of Kelda might misinterpret
+	// their config, so they're left alone until they're upgraded.
+	if minionVersion != "" && !version.Compatible(version.Version, minionVersion) {
+		log.WithFields(log.Fields{
+			"machine": cloudID,
+			"version": minionVersion,
+		}).Debug("Not configuring minion with incompatible version")
+		return
+	}
+
 	// If there isn't enough information to generate a complete minion config
 	// yet, then don't try to set it. However, if enough time has elapsed, then
 	// go ahead and use the limited information we have to set the config. This
@@ -182,7 +230,15 @@ func runOnce(waitForMachinesCutoff time.Time, conn db.Conn, cloudID string) (
 		return
 	}
 
-	newConfig := makeConfig(machines, minionMachine, blueprint)
+	newConfig := makeConfig(machines, minionMachine, bp)
+	newConfig.EtcdTLS = currConfig.EtcdTLS ||
+		etcdTLSReady(machines, cloudID, minionVersion)
+
+	// Termination notices and the state of the containers are reported by
+	// the minion rather than configured.
+	newConfig.Terminating = currConfig.Terminating
+	newConfig.Drained = currConfig.Drained
+	newConfig.Settled = currConfig.Settled
 	if !reflect.DeepEqual(currConfig, newConfig) {
 		err = cli.setMinion(newConfig)
 		if err != nil {
@@ -192,6 +248,17 @@ func runOnce(waitForMachinesCutoff time.Time, conn db.Conn, cloudID string) (
 	return
 }
 
+// filterNamespace returns the machines in `machines` that belong to `namespace`.
+func filterNamespace(machines []db.Machine, namespace string) []db.Machine {
+	var filtered []db.Machine
+	for _, m := range machines {
+		if m.Namespace == namespace {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
 // clusterReady returns whether we have enough information to generate a minion
 // config. For example, if there are machines with an unknown role, they might
 // cause an etcd restart once they connect since the EtcdMembers will change.
@@ -204,8 +271,31 @@ func clusterReady(machines []db.Machine) bool {
 	return true
 }
 
+// etcdTLSReady returns whether every master has an etcd certificate, and runs a
+// minion that's compatible with the daemon, so that etcd can switch to TLS
+// without cutting any of them off.  A machine's PublicKey is only set once all
+// of its certificates are installed.
+func etcdTLSReady(machines []db.Machine, cloudID, minionVersion string) bool {
+	for _, m := range machines {
+		if m.Role == db.Worker {
+			continue
+		}
+
+		mVersion := getMinionStatus(m.CloudID).version
+		if m.CloudID == cloudID {
+			mVersion = minionVersion
+		}
+
+		if m.Role != db.Master || m.PublicKey == "" || mVersion == "" ||
+			!version.Compatible(version.Version, mVersion) {
+			return false
+		}
+	}
+	return true
+}
+
 func makeConfig(machines []db.Machine, minionMachine db.Machine,
-	blueprint string) pb.MinionConfig {
+	bp blueprint.Blueprint) pb.MinionConfig {
 
 	minionIPToPublicKey := map[string]string{}
 	var etcdIPs []string
@@ -223,25 +313,45 @@ func makeConfig(machines []db.Machine, minionMachine db.Machine,
 		FloatingIP:          minionMachine.FloatingIP,
 		PrivateIP:           minionMachine.PrivateIP,
 		PublicIP:            minionMachine.PublicIP,
-		Blueprint:           blueprint,
+		Blueprint:           bp.String(),
 		Provider:            string(minionMachine.Provider),
 		Size:                minionMachine.Size,
 		Region:              minionMachine.Region,
 		EtcdMembers:         etcdIPs,
 		AuthorizedKeys:      minionMachine.SSHKeys,
 		MinionIPToPublicKey: minionIPToPublicKey,
+		Unschedulable:       minionMachine.Unschedulable,
+		Draining: minionMachine.Draining ||
+			minionMachine.Status == db.Draining,
+		ContainerSubnet: bp.ContainerSubnet,
+		GatewayIP:       bp.GatewayIP,
+		LoadBalancerIP:  bp.LoadBalancerIP,
+
+		ContainerSubnetIPv6: bp.ContainerSubnetIPv6,
 	}
 }
 
-func setMinionStatus(cloudID string, config pb.MinionConfig, isConnected bool) {
+func setMinionStatus(cloudID string, config pb.MinionConfig, version string,
+	isConnected bool) {
+
 	statusLock.Lock()
 	defer statusLock.Unlock()
 	minionStatuses[cloudID] = minionStatus{
-		connected: isConnected,
-		role:      db.PBToRole(config.Role),
+		connected:   isConnected,
+		role:        db.PBToRole(config.Role),
+		terminating: config.Terminating,
+		drained:     config.Drained,
+		settled:     config.Settled,
+		version:     version,
 	}
 }
 
+func getMinionStatus(cloudID string) minionStatus {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	return minionStatuses[cloudID]
+}
+
 // GetMachineRole uses the minionStatuses map to find the minion associated with
 // the given cloud ID, according to the minion thread's last update cycle.
 func GetMachineRole(cloudID string) db.Role {
@@ -253,6 +363,14 @@ func GetMachineRole(cloudID string) db.Role {
 	return db.None
 }
 
+// GetMachineVersion returns the Kelda version of the minion running on the
+// machine with the given cloud ID, or the empty string if it's unknown.
+func GetMachineVersion(cloudID string) string {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	return minionStatuses[cloudID].version
+}
+
 // IsConnected returns whether the foreman is connected to the minion running
 // on the machine with the given cloud ID.
 func IsConnected(cloudID string) bool {
@@ -262,6 +380,36 @@ func IsConnected(cloudID string) bool {
 	return ok && minion.connected
 }
 
+// IsTerminating returns whether the minion running on the machine with the
+// given cloud ID reported that the cloud provider is about to reclaim it.
+func IsTerminating(cloudID string) bool {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	minion, ok := minionStatuses[cloudID]
+	return ok && minion.terminating
+}
+
+// IsDrained returns whether the minion running on the machine with the given
+// cloud ID reported that it's draining, and Docker no longer runs any of its
+// containers.  The containers may not be running elsewhere yet, see IsSettled.
+func IsDrained(cloudID string) bool {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	minion, ok := minionStatuses[cloudID]
+	return ok && minion.drained
+}
+
+// IsSettled returns whether the minion running on the machine with the given
+// cloud ID reported that the containers it's responsible for are where they
+// should be.  Workers are settled once their containers are running, and masters
+// once every container is scheduled on a minion that isn't draining.
+func IsSettled(cloudID string) bool {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	minion, ok := minionStatuses[cloudID]
+	return ok && minion.settled
+}
+
 func newClientImpl(ip string) (client, error) {
 	c.Inc("New Minion Client")
 	cc, err := connection.Client("tcp", ip+":9999", credentials.ClientOpts())
@@ -270,11 +418,12 @@ func newClientImpl(ip string) (client, error) {
 		return nil, err
 	}
 
-	return clientImpl{pb.NewMinionClient(cc), cc}, nil
+	return clientImpl{pb.NewMinionClient(cc), cc, ip}, nil
 }
 
 // Storing in a variable allows us to mock it out for unit tests
 var newClient = newClientImpl
+var newAPIClient = apiClient.New
 
 func (cl clientImpl) getMinion() (pb.MinionConfig, error) {
 	c.Inc("Get Minion")
@@ -298,6 +447,18 @@ func (cl clientImpl) setMinion(cfg pb.MinionConfig) error {
 	return err
 }
 
+// getVersion reads the minion's version from its API server.
+func (cl clientImpl) getVersion() (string, error) {
+	c.Inc("Get Version")
+	cli, err := newAPIClient(api.RemoteAddress(cl.ip), credentials)
+	if err != nil {
+		c.Inc("Get Version Error")
+		return "", err
+	}
+	defer cli.Close()
+	return cli.Version()
+}
+
 func (cl clientImpl) Close() {
 	c.Inc("Close Client")
 	cl.cc.Close()
@@ -7,14 +7,18 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/pb"
+	"github.com/kelda/kelda/version"
 )
 
 type clients struct {
 	clients        map[string]*fakeClient
 	newClientError bool
 	getMinionError bool
+	version        string
+	versionError   bool
 }
 
 func TestUpdateMinions(t *testing.T) {
@@ -83,6 +87,47 @@ func TestUpdateMinions(t *testing.T) {
 	assert.Equal(t, 4, newMinionCalls)
 }
 
+func TestRunStop(t *testing.T) {
+	minionStops := make(chan chan struct{}, 1)
+	newMinion = func(conn db.Conn, cloudID string, stop chan struct{}) {
+		minionStops <- stop
+	}
+
+	conn := db.New()
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.PublicIP = "1.1.1.1"
+		m.PrivateIP = "2.2.2.2"
+		m.CloudID = "ID1"
+		view.Commit(m)
+		return nil
+	})
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		Run(conn, nil, stop)
+		close(done)
+	}()
+
+	var minionStop chan struct{}
+	select {
+	case minionStop = <-minionStops:
+	case <-time.After(10 * time.Second):
+		t.Fatal("minion thread not started")
+	}
+
+	// Closing `stop` should stop the minion threads before returning.
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	_, more := <-minionStop
+	assert.False(t, more)
+}
+
 func TestMakeConfig(t *testing.T) {
 	machine1 := db.Machine{
 		PublicIP:  "1.1.1.1",
@@ -99,8 +144,9 @@ func TestMakeConfig(t *testing.T) {
 		PublicKey: "pubKey",
 	}
 	allMachines := []db.Machine{machine1, machine2}
+	bp := blueprint.Blueprint{Namespace: "ns"}
 
-	config := makeConfig(allMachines, machine1, `{"Namespace":"ns"}`)
+	config := makeConfig(allMachines, machine1, bp)
 	assert.Equal(t, "10.10.10.10", config.PrivateIP)
 	assert.Equal(t, `{"Namespace":"ns"}`, config.Blueprint)
 	assert.Len(t, config.EtcdMembers, 1)
@@ -109,7 +155,7 @@ func TestMakeConfig(t *testing.T) {
 	assert.Contains(t, config.MinionIPToPublicKey, "20.20.20.20")
 	assert.Equal(t, "pubKey", config.MinionIPToPublicKey["20.20.20.20"])
 
-	config = makeConfig(allMachines, machine2, `{"Namespace":"ns"}`)
+	config = makeConfig(allMachines, machine2, bp)
 	assert.Equal(t, "20.20.20.20", config.PrivateIP)
 	assert.Equal(t, `{"Namespace":"ns"}`, config.Blueprint)
 	assert.Len(t, config.EtcdMembers, 1)
@@ -127,7 +173,7 @@ func TestMakeConfig(t *testing.T) {
 
 	allMachines = append(allMachines, machine3)
 
-	config = makeConfig(allMachines, machine1, `{"Namespace":"ns"}`)
+	config = makeConfig(allMachines, machine1, bp)
 	assert.Equal(t, "10.10.10.10", config.PrivateIP)
 	assert.Equal(t, `{"Namespace":"ns"}`, config.Blueprint)
 	assert.Len(t, config.EtcdMembers, 2)
@@ -139,12 +185,43 @@ func TestMakeConfig(t *testing.T) {
 
 	allMachines = []db.Machine{machine1, machine3}
 
-	config = makeConfig(allMachines, machine1, `{"Namespace":"ns"}`)
+	config = makeConfig(allMachines, machine1, bp)
 	assert.Equal(t, "10.10.10.10", config.PrivateIP)
 	assert.Equal(t, `{"Namespace":"ns"}`, config.Blueprint)
 	assert.Len(t, config.EtcdMembers, 1)
 	assert.Contains(t, config.EtcdMembers, "30.30.30.30")
 	assert.Len(t, config.MinionIPToPublicKey, 0)
+	assert.False(t, config.Unschedulable)
+	assert.False(t, config.Draining)
+
+	machine1.Unschedulable = true
+	config = makeConfig(allMachines, machine1, bp)
+	assert.True(t, config.Unschedulable)
+	assert.False(t, config.Draining)
+
+	// Machines are drained by the user, or by the daemon before it stops them.
+	machine1.Draining = true
+	config = makeConfig(allMachines, machine1, bp)
+	assert.True(t, config.Draining)
+
+	machine1.Unschedulable = false
+	machine1.Draining = false
+	machine1.Status = db.Draining
+	config = makeConfig(allMachines, machine1, bp)
+	assert.False(t, config.Unschedulable)
+	assert.True(t, config.Draining)
+	assert.Empty(t, config.ContainerSubnet)
+
+	// The minions are configured with the blueprint's container network.
+	bp.ContainerSubnet = "172.20.0.0/16"
+	bp.GatewayIP = "172.20.0.10"
+	bp.LoadBalancerIP = "172.20.0.11"
+	bp.ContainerSubnetIPv6 = "fd00::/64"
+	config = makeConfig(allMachines, machine1, bp)
+	assert.Equal(t, "172.20.0.0/16", config.ContainerSubnet)
+	assert.Equal(t, "172.20.0.10", config.GatewayIP)
+	assert.Equal(t, "172.20.0.11", config.LoadBalancerIP)
+	assert.Equal(t, "fd00::/64", config.ContainerSubnetIPv6)
 }
 
 func TestClusterReady(t *testing.T) {
@@ -177,43 +254,139 @@ func TestForemanRunOnce(t *testing.T) {
 		"1.1.1.1": pb.MinionConfig_WORKER,
 	})
 
-	config, connected := runOnce(time.Time{}, conn, "ID1")
+	config, _, connected := runOnce(time.Time{}, conn, "ID1")
 	assert.False(t, connected)
 	assert.Equal(t, db.Role(db.None), db.PBToRole(config.Role))
 
 	conn.Txn(db.MachineTable, db.BlueprintTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		view.Commit(bp)
+
+		bp = view.InsertBlueprint()
+		bp.Namespace = "other"
+		view.Commit(bp)
+
 		m := view.InsertMachine()
+		m.Namespace = "ns"
 		m.PublicIP = "1.1.1.1"
 		m.Role = db.Worker
 		m.Size = "size1"
 		m.PrivateIP = "10.10.10.10"
 		m.CloudID = "ID1"
 		view.Commit(m)
+
+		// Machines in other namespaces belong to other clusters.
+		m = view.InsertMachine()
+		m.Namespace = "other"
+		m.PublicIP = "2.2.2.2"
+		m.Role = db.Master
+		m.PrivateIP = "10.10.10.11"
+		m.CloudID = "ID2"
+		view.Commit(m)
 		return nil
 	})
 
 	clients.newClientError = true
-	config, connected = runOnce(time.Time{}, conn, "ID1")
+	config, _, connected = runOnce(time.Time{}, conn, "ID1")
 	assert.False(t, connected)
 	assert.Equal(t, db.Role(db.None), db.PBToRole(config.Role))
 
 	clients.newClientError = false
-	config, connected = runOnce(time.Time{}, conn, "ID1")
+	config, minionVersion, connected := runOnce(time.Time{}, conn, "ID1")
 	assert.True(t, connected)
 	assert.Equal(t, db.Role(db.Worker), db.PBToRole(config.Role))
+	assert.Equal(t, version.Version, minionVersion)
 
 	minionConf := clients.clients["1.1.1.1"].mc
 	assert.Equal(t, "10.10.10.10", minionConf.PrivateIP)
 	assert.Equal(t, "size1", minionConf.Size)
+	assert.Empty(t, minionConf.EtcdMembers)
+	assert.Equal(t, `{"Namespace":"ns"}`, minionConf.Blueprint)
 
 	clients.getMinionError = true
-	config, connected = runOnce(time.Time{}, conn, "ID1")
+	config, _, connected = runOnce(time.Time{}, conn, "ID1")
 	assert.False(t, connected)
 	assert.Equal(t, db.Role(db.None), db.PBToRole(config.Role))
 }
 
+func TestForemanRunOnceIncompatibleVersion(t *testing.T) {
+	conn := db.New()
+	clients := mock(t, map[string]pb.MinionConfig_Role{
+		"1.1.1.1": pb.MinionConfig_WORKER,
+	})
+	clients.version = "0.1.0"
+
+	conn.Txn(db.MachineTable, db.BlueprintTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		view.Commit(bp)
+
+		m := view.InsertMachine()
+		m.PublicIP = "1.1.1.1"
+		m.Role = db.Worker
+		m.PrivateIP = "10.10.10.10"
+		m.CloudID = "incompatible"
+		view.Commit(m)
+		return nil
+	})
+
+	// The minion's version is reported, but it isn't configured.
+	_, minionVersion, connected := runOnce(time.Time{}, conn, "incompatible")
+	assert.True(t, connected)
+	assert.Equal(t, "0.1.0", minionVersion)
+	assert.Equal(t, pb.MinionConfig{}, clients.clients["1.1.1.1"].mc)
+
+	// If the version can't be read, the last known version is used.
+	setMinionStatus("incompatible", pb.MinionConfig{}, minionVersion, true)
+	clients.versionError = true
+	_, minionVersion, _ = runOnce(time.Time{}, conn, "incompatible")
+	assert.Equal(t, "0.1.0", minionVersion)
+	assert.Equal(t, pb.MinionConfig{}, clients.clients["1.1.1.1"].mc)
+
+	// The version is read on every poll, so an upgrade is noticed even if the
+	// minion never appeared disconnected.
+	clients.versionError = false
+	clients.version = version.Version
+	_, minionVersion, _ = runOnce(time.Time{}, conn, "incompatible")
+	assert.Equal(t, version.Version, minionVersion)
+	assert.Equal(t, "10.10.10.10", clients.clients["1.1.1.1"].mc.PrivateIP)
+}
+
+func TestEtcdTLSReady(t *testing.T) {
+	machines := []db.Machine{
+		{CloudID: "master1", Role: db.Master, PublicKey: "key"},
+		{CloudID: "master2", Role: db.Master, PublicKey: "key"},
+		{CloudID: "worker", Role: db.Worker},
+	}
+	setMinionStatus("master1", pb.MinionConfig{}, version.Version, true)
+	setMinionStatus("master2", pb.MinionConfig{}, "", true)
+
+	// The version of the minion being configured was just read.
+	assert.True(t, etcdTLSReady(machines, "master2", version.Version))
+
+	// Every master's version must be known and compatible.
+	assert.False(t, etcdTLSReady(machines, "master1", version.Version))
+	assert.False(t, etcdTLSReady(machines, "master2", "0.1.0"))
+
+	// Every master must have its certificates.
+	machines[0].PublicKey = ""
+	assert.False(t, etcdTLSReady(machines, "master2", version.Version))
+
+	// Machines whose role isn't known yet might be masters.
+	machines[0].PublicKey = "key"
+	machines = append(machines, db.Machine{CloudID: "unknown"})
+	assert.False(t, etcdTLSReady(machines, "master2", version.Version))
+}
+
+func TestGetMachineVersion(t *testing.T) {
+	setMinionStatus("version", pb.MinionConfig{}, "0.7.0", true)
+
+	assert.Equal(t, "0.7.0", GetMachineVersion("version"))
+	assert.Equal(t, "", GetMachineVersion("none"))
+}
+
 func TestGetMachineRole(t *testing.T) {
-	setMinionStatus("ID1", pb.MinionConfig{Role: pb.MinionConfig_WORKER}, false)
+	setMinionStatus("ID1", pb.MinionConfig{Role: pb.MinionConfig_WORKER}, "", false)
 
 	assert.Equal(t, db.Role(db.Worker), GetMachineRole("ID1"))
 	assert.Equal(t, db.Role(db.None), GetMachineRole("none"))
@@ -222,15 +395,47 @@ func TestGetMachineRole(t *testing.T) {
 func TestIsConnected(t *testing.T) {
 	assert.False(t, IsConnected("host"))
 
-	setMinionStatus("host", pb.MinionConfig{Role: pb.MinionConfig_WORKER}, false)
+	setMinionStatus("host", pb.MinionConfig{Role: pb.MinionConfig_WORKER}, "", false)
 	assert.False(t, IsConnected("host"))
 
-	setMinionStatus("host", pb.MinionConfig{Role: pb.MinionConfig_WORKER}, true)
+	setMinionStatus("host", pb.MinionConfig{Role: pb.MinionConfig_WORKER}, "", true)
 	assert.True(t, IsConnected("host"))
 }
 
+func TestIsTerminating(t *testing.T) {
+	assert.False(t, IsTerminating("terminating"))
+
+	setMinionStatus("terminating", pb.MinionConfig{}, "", true)
+	assert.False(t, IsTerminating("terminating"))
+
+	setMinionStatus("terminating", pb.MinionConfig{Terminating: true}, "", true)
+	assert.True(t, IsTerminating("terminating"))
+}
+
+func TestIsDrained(t *testing.T) {
+	assert.False(t, IsDrained("drained"))
+
+	setMinionStatus("drained", pb.MinionConfig{Draining: true}, "", true)
+	assert.False(t, IsDrained("drained"))
+
+	setMinionStatus("drained", pb.MinionConfig{Draining: true, Drained: true}, "",
+		true)
+	assert.True(t, IsDrained("drained"))
+}
+
+func TestIsSettled(t *testing.T) {
+	assert.False(t, IsSettled("settled"))
+
+	setMinionStatus("settled", pb.MinionConfig{}, "", true)
+	assert.False(t, IsSettled("settled"))
+
+	setMinionStatus("settled", pb.MinionConfig{Settled: true}, "", true)
+	assert.True(t, IsSettled("settled"))
+}
+
 func mock(t *testing.T, roles map[string]pb.MinionConfig_Role) *clients {
-	clients := &clients{make(map[string]*fakeClient), false, false}
+	clients := &clients{clients: make(map[string]*fakeClient),
+		version: version.Version}
 	newClient = func(ip string) (client, error) {
 		if clients.newClientError {
 			return nil, errors.New("newMinion error")
@@ -278,6 +483,13 @@ func (fc *fakeClient) getMinion() (pb.MinionConfig, error) {
 	return mc, nil
 }
 
+func (fc *fakeClient) getVersion() (string, error) {
+	if fc.clients.versionError {
+		return "", assert.AnError
+	}
+	return fc.clients.version, nil
+}
+
 func (fc *fakeClient) Close() {
 	fc.clients.clients[fc.ip].closed = true
 }
@@ -38,6 +38,10 @@ type Client interface {
 	ListNetworks(name string) (*compute.NetworkList, error)
 	InsertNetwork(network *compute.Network) (*compute.Operation, error)
 	DeleteNetwork(name string) (*compute.Operation, error)
+	SetDiskLabels(zone, disk string, labels map[string]string) (
+		*compute.Operation, error)
+	SetInstanceLabels(zone, instance string, labels map[string]string) (
+		*compute.Operation, error)
 }
 
 type client struct {
@@ -186,6 +190,54 @@ func (ci *client) DeleteNetwork(network string) (*compute.Operation, error) {
 	return ci.gce.Networks.Delete(ci.projID, network).Do()
 }
 
+// SetDiskLabels applies `labels` to `disk`, and leaves its other labels alone.
+// Google requires the disk's current label fingerprint, so it's looked up
+// first.
+func (ci *client) SetDiskLabels(zone, disk string, labels map[string]string) (
+	*compute.Operation, error) {
+	c.Inc("Get Disk")
+	current, err := ci.gce.Disks.Get(ci.projID, zone, disk).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	c.Inc("Set Disk Labels")
+	return ci.gce.Disks.SetLabels(ci.projID, zone, disk,
+		&compute.ZoneSetLabelsRequest{
+			Labels:           mergeLabels(current.Labels, labels),
+			LabelFingerprint: current.LabelFingerprint,
+		}).Do()
+}
+
+// SetInstanceLabels applies `labels` to `instance`, and leaves its other labels
+// alone.  Like SetDiskLabels, it looks up the current label fingerprint first.
+func (ci *client) SetInstanceLabels(zone, instance string,
+	labels map[string]string) (*compute.Operation, error) {
+	current, err := ci.GetInstance(zone, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Inc("Set Instance Labels")
+	return ci.gce.Instances.SetLabels(ci.projID, zone, instance,
+		&compute.InstancesSetLabelsRequest{
+			Labels:           mergeLabels(current.Labels, labels),
+			LabelFingerprint: current.LabelFingerprint,
+		}).Do()
+}
+
+// mergeLabels returns `current` with `labels` added to it.
+func mergeLabels(current, labels map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	return merged
+}
+
 func descFilter(desc string) string {
 	return fmt.Sprintf("description eq %s", desc)
 }
@@ -377,3 +377,49 @@ func (_m *Client) ListNetworks(name string) (*compute.NetworkList, error) {
 
 	return r0, r1
 }
+
+// SetDiskLabels provides a mock function with given fields: zone, disk, labels
+func (_m *Client) SetDiskLabels(zone string, disk string, labels map[string]string) (*compute.Operation, error) {
+	ret := _m.Called(zone, disk, labels)
+
+	var r0 *compute.Operation
+	if rf, ok := ret.Get(0).(func(string, string, map[string]string) *compute.Operation); ok {
+		r0 = rf(zone, disk, labels)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*compute.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, map[string]string) error); ok {
+		r1 = rf(zone, disk, labels)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetInstanceLabels provides a mock function with given fields: zone, instance, labels
+func (_m *Client) SetInstanceLabels(zone string, instance string, labels map[string]string) (*compute.Operation, error) {
+	ret := _m.Called(zone, instance, labels)
+
+	var r0 *compute.Operation
+	if rf, ok := ret.Get(0).(func(string, string, map[string]string) *compute.Operation); ok {
+		r0 = rf(zone, instance, labels)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*compute.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, map[string]string) error); ok {
+		r1 = rf(zone, instance, labels)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
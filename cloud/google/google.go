@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/cloud/acl"
 	"github.com/kelda/kelda/cloud/cfg"
 	"github.com/kelda/kelda/cloud/google/client"
@@ -34,11 +37,24 @@ const ephemeralIPName = "External NAT"
 // floatingIPName is a constant for what we label NATs with floating IPs in GCE.
 const floatingIPName = "Floating IP"
 
-const image = "https://www.googleapis.com/compute/v1/projects/" +
+const defaultImage = "https://www.googleapis.com/compute/v1/projects/" +
 	"ubuntu-os-cloud/global/images/ubuntu-1604-xenial-v20170202"
 
+// imageKey is the metadata key under which instances record the image they
+// booted, as Google only reports the boot disk.
+const imageKey = "kelda-image"
+
 const ipv4Range string = "172.16.0.0/12"
 
+// Google firewalls refer to ICMPv6 by its protocol number.
+const icmpv6 = "58"
+
+// The labels that Google accepts.  Keys must start with a lowercase letter.
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	labelValuePattern = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+)
+
 // The Provider objects represents a connection to GCE.
 type Provider struct {
 	client.Client
@@ -132,6 +148,15 @@ func (prvdr *Provider) List() ([]db.Machine, error) {
 			log.WithError(err).Warn("Failed to get machine IP")
 		}
 
+		var image string
+		if instance.Metadata != nil {
+			for _, item := range instance.Metadata.Items {
+				if item.Key == imageKey && item.Value != nil {
+					image = *item.Value
+				}
+			}
+		}
+
 		machines = append(machines, db.Machine{
 			Provider:    db.Google,
 			Region:      prvdr.zone,
@@ -141,6 +166,8 @@ func (prvdr *Provider) List() ([]db.Machine, error) {
 			PrivateIP:   privateIP,
 			Size:        mtype,
 			Preemptible: instance.Scheduling.Preemptible,
+			Image:       image,
+			Labels:      instance.Labels,
 		})
 	}
 	return machines, nil
@@ -148,6 +175,14 @@ func (prvdr *Provider) List() ([]db.Machine, error) {
 
 // Boot blocks while creating instances.
 func (prvdr *Provider) Boot(bootSet []db.Machine) ([]string, error) {
+	// Preemptible instances have a fixed price, so there's nothing to bid.
+	for _, m := range bootSet {
+		if m.MaxPrice != 0 {
+			return nil, errors.New("google does not support max " +
+				"prices for preemptible instances")
+		}
+	}
+
 	if err := prvdr.createNetwork(); err != nil {
 		return nil, err
 	}
@@ -160,9 +195,18 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) ([]string, error) {
 		names = append(names, name)
 
 		go func(m db.Machine) {
-			icfg := prvdr.instanceConfig(name, m.Size, cfg.Ubuntu(m, ""),
-				m.Preemptible)
-			_, err := prvdr.InsertInstance(prvdr.zone, icfg)
+			image := m.Image
+			if image == "" {
+				image = defaultImage
+			}
+
+			icfg := prvdr.instanceConfig(name, m.Size, image,
+				cfg.BootScript(m, ""), m.Preemptible)
+			icfg.Labels = m.Labels
+			op, err := prvdr.InsertInstance(prvdr.zone, icfg)
+			if err == nil && len(m.Labels) > 0 {
+				err = prvdr.labelDisk(op, name, m.Labels)
+			}
 			errChan <- err
 		}(m)
 	}
@@ -176,6 +220,69 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) ([]string, error) {
 	return names, nil
 }
 
+// labelDisk applies `labels` to the boot disk of the instance `name` once the
+// instance insertion `op` creates it.  Boot disks are named after their
+// instance, and can't be labeled when the instance is inserted.
+func (prvdr *Provider) labelDisk(op *compute.Operation, name string,
+	labels map[string]string) error {
+
+	if err := prvdr.operationWait(op); err != nil {
+		return fmt.Errorf("wait for instance %s: %s", name, err)
+	}
+
+	if _, err := prvdr.SetDiskLabels(prvdr.zone, name, labels); err != nil {
+		return fmt.Errorf("label disk %s: %s", name, err)
+	}
+	return nil
+}
+
+// ValidateLabels returns an error if Google won't accept `labels`, so that
+// invalid labels are rejected when they're deployed rather than when the
+// machines boot.
+func ValidateLabels(labels map[string]string) error {
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("label key %q must start with a lowercase "+
+				"letter, and may only contain up to 63 lowercase "+
+				"letters, numbers, dashes, and underscores", key)
+		}
+
+		if value := labels[key]; !labelValuePattern.MatchString(value) {
+			return fmt.Errorf("label value %q may only contain up to 63 "+
+				"lowercase letters, numbers, dashes, and underscores",
+				value)
+		}
+	}
+	return nil
+}
+
+// UpdateLabels applies the labels of `machines` to their instances and boot
+// disks.
+func (prvdr *Provider) UpdateLabels(machines []db.Machine) error {
+	for _, m := range machines {
+		if len(m.Labels) == 0 {
+			continue
+		}
+
+		_, err := prvdr.SetInstanceLabels(prvdr.zone, m.CloudID, m.Labels)
+		if err != nil {
+			return fmt.Errorf("label instance %s: %s", m.CloudID, err)
+		}
+
+		_, err = prvdr.SetDiskLabels(prvdr.zone, m.CloudID, m.Labels)
+		if err != nil {
+			return fmt.Errorf("label disk %s: %s", m.CloudID, err)
+		}
+	}
+	return nil
+}
+
 // Stop blocks while deleting the instances.
 //
 // If an error occurs while deleting, it will finish the ones that have
@@ -221,7 +328,7 @@ func (prvdr *Provider) operationWait(ops ...*compute.Operation) (err error) {
 	}, 10*time.Second, 3*time.Minute)
 }
 
-func (prvdr Provider) instanceConfig(name, size, cloudConfig string,
+func (prvdr Provider) instanceConfig(name, size, image, cloudConfig string,
 	preemptible bool) *compute.Instance {
 	return &compute.Instance{
 		Name:        name,
@@ -248,25 +355,35 @@ func (prvdr Provider) instanceConfig(name, size, cloudConfig string,
 			Items: []*compute.MetadataItems{{
 				Key:   "startup-script",
 				Value: &cloudConfig,
+			}, {
+				Key:   imageKey,
+				Value: &image,
 			}},
 		},
 	}
 }
 
+// SetLabels is a noop because Google's firewalls and networks can't be labeled.
+func (prvdr *Provider) SetLabels(map[string]string) error {
+	return nil
+}
+
 func (prvdr *Provider) parseACL(fw *compute.Firewall) (gACL, error) {
-	if len(fw.SourceRanges) != 1 || len(fw.Allowed) != 3 {
+	if len(fw.SourceRanges) != 1 || len(fw.Allowed) == 0 || len(fw.Allowed) > 3 {
 		return gACL{}, errors.New("malformed firewall")
 	}
 
 	var portsStr string
+	var protocols []string
 	for _, allowed := range fw.Allowed {
-		if allowed.IPProtocol == "icmp" {
+		if allowed.IPProtocol == "icmp" || allowed.IPProtocol == icmpv6 {
 			continue
 		}
 
 		if len(allowed.Ports) != 1 {
 			return gACL{}, errors.New("malformed firewall")
 		}
+		protocols = append(protocols, allowed.IPProtocol)
 
 		if portsStr == "" {
 			portsStr = allowed.Ports[0]
@@ -275,6 +392,18 @@ func (prvdr *Provider) parseACL(fw *compute.Firewall) (gACL, error) {
 		}
 	}
 
+	acl := gACL{name: fw.Name}
+	acl.CidrIP = fw.SourceRanges[0]
+
+	// Firewalls that allow both TCP and UDP are for ACLs without a protocol.
+	switch len(protocols) {
+	case 0:
+		acl.Protocol = blueprint.ICMP
+		return acl, nil
+	case 1:
+		acl.Protocol = protocols[0]
+	}
+
 	var ports []int
 	for _, p := range strings.Split(portsStr, "-") {
 		portInt, err := strconv.Atoi(p)
@@ -284,9 +413,6 @@ func (prvdr *Provider) parseACL(fw *compute.Firewall) (gACL, error) {
 		ports = append(ports, portInt)
 	}
 
-	acl := gACL{name: fw.Name}
-	acl.CidrIP = fw.SourceRanges[0]
-
 	switch len(ports) {
 	case 1:
 		acl.MinPort, acl.MaxPort = ports[0], ports[0]
@@ -349,10 +475,19 @@ func (prvdr *Provider) planSetACLs(cloudFWs []*compute.Firewall, acls []acl.ACL)
 
 	var gacls []gACL
 	for _, a := range acls {
-		ip := strings.Replace(a.CidrIP, ".", "-", -1)
-		ip = strings.Replace(ip, "/", "-", -1)
+		// ICMP firewalls don't have ports, so parseACL always parses them
+		// with zero ports.  The name must match, or they'd be recreated.
+		if a.Protocol == blueprint.ICMP {
+			a.MinPort, a.MaxPort = 0, 0
+		}
+
+		ip := strings.NewReplacer(".", "-", ":", "-", "/", "-").Replace(
+			strings.ToLower(a.CidrIP))
 		name := fmt.Sprintf("%s-%s-%d-%d", prvdr.network, ip,
 			a.MinPort, a.MaxPort)
+		if a.Protocol != "" {
+			name += "-" + a.Protocol
+		}
 		gacls = append(gacls, gACL{name: name, ACL: a})
 	}
 
@@ -365,25 +500,37 @@ func (prvdr *Provider) planSetACLs(cloudFWs []*compute.Firewall, acls []acl.ACL)
 	for _, a := range adds {
 		acl := a.(gACL)
 		ports := fmt.Sprintf("%d-%d", acl.MinPort, acl.MaxPort)
+		var allowed []*compute.FirewallAllowed
+		for _, protocol := range acl.PortProtocols() {
+			allowed = append(allowed, &compute.FirewallAllowed{
+				IPProtocol: protocol,
+				Ports:      []string{ports},
+			})
+		}
+
+		allowed = append(allowed, &compute.FirewallAllowed{
+			IPProtocol: icmpProtocol(acl.CidrIP),
+		})
+
 		add = append(add, &compute.Firewall{
 			Name:         acl.name,
 			Network:      prvdr.networkURL(),
 			Description:  prvdr.network,
 			SourceRanges: []string{acl.CidrIP},
-			Allowed: []*compute.FirewallAllowed{{
-				IPProtocol: "tcp",
-				Ports:      []string{ports},
-			}, {
-				IPProtocol: "udp",
-				Ports:      []string{ports},
-			}, {
-				IPProtocol: "icmp",
-			}}})
+			Allowed:      allowed})
 	}
 
 	return
 }
 
+// icmpProtocol returns the ICMP protocol that should be allowed from `cidr`.
+func icmpProtocol(cidr string) string {
+	if acl.IsIPv6(cidr) {
+		return icmpv6
+	}
+	return "icmp"
+}
+
 // UpdateFloatingIPs updates IPs of machines by recreating their network interfaces.
 func (prvdr *Provider) UpdateFloatingIPs(machines []db.Machine) error {
 	allIPs, err := prvdr.ListFloatingIPs(prvdr.region)
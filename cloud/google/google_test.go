@@ -3,6 +3,8 @@ package google
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -35,6 +37,7 @@ func getProvider() (*mocks.Client, Provider) {
 
 func TestList(t *testing.T) {
 	mc, gce := getProvider()
+	image := "custom-image"
 	mc.On("ListInstances", "zone-1", gce.network).Return(&compute.InstanceList{
 		Items: []*compute.Instance{
 			{
@@ -53,6 +56,13 @@ func TestList(t *testing.T) {
 				Scheduling: &compute.Scheduling{
 					Preemptible: false,
 				},
+				Labels: map[string]string{"team": "infra"},
+				Metadata: &compute.Metadata{
+					Items: []*compute.MetadataItems{{
+						Key:   imageKey,
+						Value: &image,
+					}},
+				},
 			},
 		},
 	}, nil)
@@ -68,6 +78,8 @@ func TestList(t *testing.T) {
 		PrivateIP:   "y.y.y.y",
 		Size:        "type-1",
 		Preemptible: false,
+		Image:       image,
+		Labels:      map[string]string{"team": "infra"},
 	})
 }
 
@@ -204,6 +216,31 @@ func TestParseACL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, gACL{"name", acl.ACL{CidrIP: "1.2.3.4/32",
 		MinPort: 1, MaxPort: 2}}, gacl)
+
+	// Single Protocol
+	gacl, err = gce.parseACL(&compute.Firewall{
+		Name:         "name",
+		SourceRanges: []string{"1.2.3.4/32"},
+		Allowed: []*compute.FirewallAllowed{{
+			IPProtocol: "tcp",
+			Ports:      []string{"80-80"},
+		}, {
+			IPProtocol: "icmp",
+		}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, gACL{"name", acl.ACL{CidrIP: "1.2.3.4/32",
+		MinPort: 80, MaxPort: 80, Protocol: "tcp"}}, gacl)
+
+	// ICMP Only
+	gacl, err = gce.parseACL(&compute.Firewall{
+		Name:         "name",
+		SourceRanges: []string{"1.2.3.4/32"},
+		Allowed:      []*compute.FirewallAllowed{{IPProtocol: "icmp"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, gACL{"name", acl.ACL{CidrIP: "1.2.3.4/32",
+		Protocol: "icmp"}}, gacl)
 }
 
 func TestSetACLs(t *testing.T) {
@@ -319,11 +356,120 @@ func TestPlanSetACLs(t *testing.T) {
 	}}, add)
 }
 
+func TestPlanSetACLsProtocol(t *testing.T) {
+	_, gce := getProvider()
+	add, remove := gce.planSetACLs([]*compute.Firewall{{
+		// The protocol of the ACL for port 53 changed from UDP to TCP.
+		Name:         "network-5-6-7-8-32-53-53-udp",
+		SourceRanges: []string{"5.6.7.8/32"},
+		Allowed: []*compute.FirewallAllowed{{
+			IPProtocol: "udp",
+			Ports:      []string{"53-53"},
+		}, {
+			IPProtocol: "icmp",
+		}},
+	}}, []acl.ACL{{
+		CidrIP:   "5.6.7.8/32",
+		MinPort:  53,
+		MaxPort:  53,
+		Protocol: "tcp",
+	}, {
+		CidrIP:   "5.6.7.8/32",
+		Protocol: "icmp",
+	}})
+	assert.Equal(t, []string{"network-5-6-7-8-32-53-53-udp"}, remove)
+
+	sort.Slice(add, func(i, j int) bool { return add[i].Name < add[j].Name })
+	assert.Equal(t, []*compute.Firewall{{
+		Name:         "network-5-6-7-8-32-0-0-icmp",
+		Network:      gce.networkURL(),
+		Description:  gce.network,
+		SourceRanges: []string{"5.6.7.8/32"},
+		Allowed: []*compute.FirewallAllowed{{
+			IPProtocol: "icmp",
+		}},
+	}, {
+		Name:         "network-5-6-7-8-32-53-53-tcp",
+		Network:      gce.networkURL(),
+		Description:  gce.network,
+		SourceRanges: []string{"5.6.7.8/32"},
+		Allowed: []*compute.FirewallAllowed{{
+			IPProtocol: "tcp",
+			Ports:      []string{"53-53"},
+		}, {
+			IPProtocol: "icmp",
+		}},
+	}}, add)
+}
+
+func TestPlanSetACLsRoundTrip(t *testing.T) {
+	_, gce := getProvider()
+	acls := []acl.ACL{
+		{CidrIP: "5.6.7.8/32", MinPort: 1, MaxPort: 2},
+		{CidrIP: "5.6.7.8/32", MinPort: 53, MaxPort: 53, Protocol: "udp"},
+		{CidrIP: "0.0.0.0/0", Protocol: "icmp"},
+		{CidrIP: "::/0", MinPort: 80, MaxPort: 80, Protocol: "icmp"},
+	}
+	add, remove := gce.planSetACLs(nil, acls)
+	assert.Len(t, add, len(acls))
+	assert.Empty(t, remove)
+
+	// Once created, the firewalls match the ACLs they were created for.
+	add, remove = gce.planSetACLs(add, acls)
+	assert.Empty(t, add)
+	assert.Empty(t, remove)
+}
+
+func TestPlanSetACLsIPv6(t *testing.T) {
+	_, gce := getProvider()
+	add, remove := gce.planSetACLs([]*compute.Firewall{{
+		Name:         "network----0-1-2",
+		SourceRanges: []string{"::/0"},
+		Allowed: []*compute.FirewallAllowed{{
+			IPProtocol: "58",
+		}, {
+			IPProtocol: "udp",
+			Ports:      []string{"1-2"},
+		}, {
+			IPProtocol: "tcp",
+			Ports:      []string{"1-2"},
+		}},
+	}}, []acl.ACL{{
+		CidrIP:  "::/0",
+		MinPort: 1,
+		MaxPort: 2,
+	}, {
+		CidrIP:  "2001:DB8::/32",
+		MinPort: 3,
+		MaxPort: 4,
+	}})
+	assert.Empty(t, remove)
+	assert.Equal(t, []*compute.Firewall{{
+		Name:         "network-2001-db8---32-3-4",
+		Network:      gce.networkURL(),
+		Description:  gce.network,
+		SourceRanges: []string{"2001:DB8::/32"},
+		Allowed: []*compute.FirewallAllowed{{
+			IPProtocol: "tcp",
+			Ports:      []string{"3-4"},
+		}, {
+			IPProtocol: "udp",
+			Ports:      []string{"3-4"},
+		}, {
+			IPProtocol: "58",
+		}},
+	}}, add)
+}
+
 func TestBoot(t *testing.T) {
 	mc, gce := getProvider()
 
+	_, err := gce.Boot([]db.Machine{{Preemptible: true, MaxPrice: 0.1}})
+	assert.EqualError(t, err,
+		"google does not support max prices for preemptible instances")
+
 	mc.On("ListNetworks", mock.Anything).Return(nil, errors.New("list err")).Once()
-	_, err := gce.Boot(nil)
+	_, err = gce.Boot(nil)
 	assert.EqualError(t, err, "list err")
 
 	mc.On("ListNetworks", mock.Anything).Return(&compute.NetworkList{
@@ -342,12 +488,15 @@ func TestBoot(t *testing.T) {
 		return fmt.Sprintf("%d", name)
 	}
 
-	machines := []db.Machine{{Size: "size1", Preemptible: true}, {Size: "size2"}}
+	machines := []db.Machine{{Size: "size1", Preemptible: true},
+		{Size: "size2", Image: "image", OS: db.Debian}}
 
-	cfg1 := gce.instanceConfig("1", "size1", cfg.Ubuntu(machines[0], ""), true)
+	cfg1 := gce.instanceConfig("1", "size1", defaultImage,
+		cfg.BootScript(machines[0], ""), true)
 	mc.On("InsertInstance", "zone-1", cfg1).Return(nil, nil)
 
-	cfg2 := gce.instanceConfig("2", "size2", cfg.Ubuntu(machines[1], ""), false)
+	cfg2 := gce.instanceConfig("2", "size2", "image",
+		cfg.BootScript(machines[1], ""), false)
 	mc.On("InsertInstance", "zone-1", cfg2).Return(nil, nil)
 
 	ids, err := gce.Boot(machines)
@@ -357,6 +506,72 @@ func TestBoot(t *testing.T) {
 	mc.AssertExpectations(t)
 }
 
+func TestBootLabels(t *testing.T) {
+	mc, gce := getProvider()
+	mc.On("ListNetworks", mock.Anything).Return(&compute.NetworkList{
+		Items: []*compute.Network{{Name: gce.network}},
+	}, nil)
+
+	randName = func() string { return "name" }
+
+	labels := map[string]string{"team": "infra"}
+	m := db.Machine{Size: "size1", Labels: labels}
+	icfg := gce.instanceConfig("name", "size1", defaultImage,
+		cfg.BootScript(m, ""), false)
+	icfg.Labels = labels
+
+	op := &compute.Operation{Name: "op", Zone: "zone-1"}
+	mc.On("InsertInstance", "zone-1", icfg).Return(op, nil)
+	mc.On("SetDiskLabels", "zone-1", "name", labels).Return(
+		nil, errors.New("err")).Once()
+
+	_, err := gce.Boot([]db.Machine{m})
+	assert.EqualError(t, err, "label disk name: err")
+
+	mc.On("SetDiskLabels", "zone-1", "name", labels).Return(op, nil)
+	ids, err := gce.Boot([]db.Machine{m})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name"}, ids)
+
+	mc.AssertExpectations(t)
+}
+
+func TestValidateLabels(t *testing.T) {
+	assert.NoError(t, ValidateLabels(nil))
+	assert.NoError(t, ValidateLabels(map[string]string{
+		"team": "infra", "cost-center": "1234", "empty": ""}))
+
+	assert.EqualError(t, ValidateLabels(map[string]string{"Team": "infra"}),
+		`label key "Team" must start with a lowercase letter, and may `+
+			`only contain up to 63 lowercase letters, numbers, dashes, `+
+			`and underscores`)
+	assert.Error(t, ValidateLabels(map[string]string{"1team": "infra"}))
+	assert.Error(t, ValidateLabels(map[string]string{
+		strings.Repeat("a", 64): "infra"}))
+	assert.EqualError(t, ValidateLabels(map[string]string{"team": "a.b"}),
+		`label value "a.b" may only contain up to 63 lowercase letters, `+
+			`numbers, dashes, and underscores`)
+}
+
+func TestUpdateLabels(t *testing.T) {
+	mc, gce := getProvider()
+
+	labels := map[string]string{"team": "infra"}
+	mc.On("SetInstanceLabels", "zone-1", "name", labels).Return(
+		nil, errors.New("err")).Once()
+	err := gce.UpdateLabels([]db.Machine{{CloudID: "name", Labels: labels}})
+	assert.EqualError(t, err, "label instance name: err")
+
+	mc.On("SetInstanceLabels", "zone-1", "name", labels).Return(nil, nil)
+	mc.On("SetDiskLabels", "zone-1", "name", labels).Return(nil, nil)
+	err = gce.UpdateLabels([]db.Machine{
+		{CloudID: "name", Labels: labels},
+		{CloudID: "unlabeled"},
+	})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+}
+
 func TestStop(t *testing.T) {
 	mc, gce := getProvider()
 
@@ -374,7 +589,8 @@ func TestStop(t *testing.T) {
 func TestInstanceConfig(t *testing.T) {
 	_, gce := getProvider()
 	cloudConfig := "cloudConfig"
-	res := gce.instanceConfig("name", "size", cloudConfig, true)
+	image := "image"
+	res := gce.instanceConfig("name", "size", image, cloudConfig, true)
 	exp := &compute.Instance{
 		Name:        "name",
 		Description: gce.network,
@@ -400,6 +616,9 @@ func TestInstanceConfig(t *testing.T) {
 			Items: []*compute.MetadataItems{{
 				Key:   "startup-script",
 				Value: &cloudConfig,
+			}, {
+				Key:   imageKey,
+				Value: &image,
 			}},
 		},
 	}
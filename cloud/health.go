@@ -0,0 +1,127 @@
+package cloud
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kelda/kelda/db"
+)
+
+// HealthPolicy determines when the daemon replaces machines whose minions it
+// can't reach.
+type HealthPolicy struct {
+	// How long a machine may go without connecting, either after it boots or
+	// after it loses its connection, before it's replaced.  Machines whose
+	// boot script fails never connect, so they're replaced as well.  Zero
+	// disables replacement.
+	GracePeriod time.Duration
+
+	// The most machines that may be replaced at once.  Other unhealthy
+	// machines are left alone until the replacements connect or are stopped,
+	// so that a problem with the daemon's own network doesn't replace the
+	// whole cluster.
+	MaxReplacements int
+}
+
+// DefaultHealthPolicy is the HealthPolicy used by the daemon unless
+// configured otherwise.
+var DefaultHealthPolicy = HealthPolicy{
+	GracePeriod:     15 * time.Minute,
+	MaxReplacements: 1,
+}
+
+// healthTracker tracks the machines that can't be reached, and the
+// replacements booted for them.  It's shared by all the cloud threads.
+type healthTracker struct {
+	sync.Mutex
+	policy HealthPolicy
+
+	// When each machine, keyed by cloud ID, was first seen disconnected.
+	unhealthySince map[string]time.Time
+
+	// The database IDs of the replacement machines that haven't connected
+	// yet.  Replacements that don't connect within the grace period are
+	// replaced themselves, so that a broken replacement doesn't disable
+	// replacement altogether, but they keep counting against MaxReplacements
+	// until they're stopped.
+	replacements map[int]struct{}
+}
+
+var health = newHealthTracker(DefaultHealthPolicy)
+
+// Stored in a variable so it may be mocked out.
+var now = time.Now
+
+func newHealthTracker(policy HealthPolicy) *healthTracker {
+	return &healthTracker{
+		policy:         policy,
+		unhealthySince: map[string]time.Time{},
+		replacements:   map[int]struct{}{},
+	}
+}
+
+// shouldReplace returns whether `m`, whose connection status is up to date,
+// has been unhealthy for long enough that it should be replaced.
+func (h *healthTracker) shouldReplace(m db.Machine) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	unhealthy := m.Status == db.Connecting || m.Status == db.Reconnecting
+	if !unhealthy || m.CloudID == "" {
+		delete(h.unhealthySince, m.CloudID)
+		return false
+	}
+
+	since, ok := h.unhealthySince[m.CloudID]
+	if !ok {
+		h.unhealthySince[m.CloudID] = now()
+		return false
+	}
+
+	if h.policy.GracePeriod == 0 || now().Sub(since) < h.policy.GracePeriod {
+		return false
+	}
+
+	// A replacement that's replaced gives up its place to its own
+	// replacement.
+	inFlight := len(h.replacements)
+	if _, ok := h.replacements[m.ID]; ok {
+		inFlight--
+	}
+
+	if inFlight >= h.policy.MaxReplacements {
+		c.Inc("Replacement Deferred")
+		return false
+	}
+
+	delete(h.unhealthySince, m.CloudID)
+	return true
+}
+
+// replacing records that the machine with database ID `id` was booted to
+// replace an unhealthy machine.
+func (h *healthTracker) replacing(id int) {
+	h.Lock()
+	defer h.Unlock()
+	h.replacements[id] = struct{}{}
+}
+
+// syncReplacements stops tracking the replacements that have connected, or
+// are no longer in `machines`.
+func (h *healthTracker) syncReplacements(machines []db.Machine) {
+	h.Lock()
+	defer h.Unlock()
+
+	booting := map[int]struct{}{}
+	for _, m := range machines {
+		if m.Status != db.Connected {
+			booting[m.ID] = struct{}{}
+		}
+	}
+
+	for id := range h.replacements {
+		if _, ok := booting[id]; !ok {
+			delete(h.replacements, id)
+		}
+	}
+}
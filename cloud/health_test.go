@@ -0,0 +1,91 @@
+package cloud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/db"
+)
+
+func TestShouldReplace(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	h := newHealthTracker(HealthPolicy{
+		GracePeriod:     time.Minute,
+		MaxReplacements: 1,
+	})
+
+	m := db.Machine{ID: 1, CloudID: "1", Status: db.Reconnecting}
+	assert.False(t, h.shouldReplace(m))
+
+	current = current.Add(30 * time.Second)
+	assert.False(t, h.shouldReplace(m))
+
+	// Connecting again resets the grace period.
+	m.Status = db.Connected
+	assert.False(t, h.shouldReplace(m))
+	m.Status = db.Reconnecting
+	assert.False(t, h.shouldReplace(m))
+	current = current.Add(59 * time.Second)
+	assert.False(t, h.shouldReplace(m))
+
+	current = current.Add(time.Second)
+	assert.True(t, h.shouldReplace(m))
+
+	// Machines that never connected are replaced as well, but only one
+	// replacement may be in flight.
+	booting := db.Machine{ID: 3, CloudID: "3", Status: db.Connecting}
+	assert.False(t, h.shouldReplace(booting))
+	current = current.Add(time.Minute)
+	h.replacing(2)
+	assert.False(t, h.shouldReplace(booting))
+
+	h.syncReplacements([]db.Machine{
+		{ID: 2, Status: db.Connecting}, booting})
+	assert.False(t, h.shouldReplace(booting))
+
+	h.syncReplacements([]db.Machine{{ID: 2, Status: db.Connected}, booting})
+	assert.True(t, h.shouldReplace(booting))
+
+	// Replacements that don't connect within the grace period are replaced
+	// themselves, but keep blocking other replacements until they're stopped.
+	h.replacing(4)
+	stuck := db.Machine{ID: 4, CloudID: "4", Status: db.Connecting}
+	other := db.Machine{ID: 5, CloudID: "5", Status: db.Reconnecting}
+	assert.False(t, h.shouldReplace(stuck))
+	assert.False(t, h.shouldReplace(other))
+	current = current.Add(time.Minute)
+	h.syncReplacements([]db.Machine{stuck, other})
+	assert.False(t, h.shouldReplace(other))
+	assert.True(t, h.shouldReplace(stuck))
+
+	stuck.Status = db.Stopping
+	h.replacing(6)
+	h.syncReplacements([]db.Machine{stuck, other, {ID: 6}})
+	assert.False(t, h.shouldReplace(other))
+
+	h.syncReplacements([]db.Machine{other, {ID: 6, Status: db.Connected}})
+	assert.Empty(t, h.replacements)
+	assert.True(t, h.shouldReplace(other))
+
+	// Machines without cloud IDs haven't booted yet.
+	assert.False(t, h.shouldReplace(db.Machine{Status: db.Connecting}))
+	current = current.Add(time.Hour)
+	assert.False(t, h.shouldReplace(db.Machine{Status: db.Connecting}))
+}
+
+func TestShouldReplaceDisabled(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	h := newHealthTracker(HealthPolicy{MaxReplacements: 1})
+	m := db.Machine{ID: 1, CloudID: "1", Status: db.Reconnecting}
+	assert.False(t, h.shouldReplace(m))
+	current = current.Add(24 * time.Hour)
+	assert.False(t, h.shouldReplace(m))
+}
@@ -1,8 +1,8 @@
 package cloud
 
 import (
-	"errors"
 	"fmt"
+	"time"
 
 	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/cloud/acl"
@@ -15,13 +15,17 @@ import (
 )
 
 var isConnected = foreman.IsConnected
+var getMachineVersion = foreman.GetMachineVersion
+var isTerminating = foreman.IsTerminating
 
 type joinResult struct {
-	acls []acl.ACL
+	acls   []acl.ACL
+	labels map[string]string
 
-	boot      []db.Machine
-	terminate []db.Machine
-	updateIPs []db.Machine
+	boot         []db.Machine
+	terminate    []db.Machine
+	updateIPs    []db.Machine
+	updateLabels []db.Machine
 
 	// True if there's things going on in this join that warrant frequent polls.
 	isActive bool
@@ -30,24 +34,21 @@ type joinResult struct {
 var cloudJoin = joinImpl
 
 func joinImpl(cld *cloud) (joinResult, error) {
+	start := time.Now()
 	machines, err := cld.provider.List()
+	cld.observe("List", start)
 	if err != nil {
 		log.WithError(err).Error("Failed to list machines")
 		return joinResult{}, err
 	}
+	cld.setEmpty(len(machines) == 0)
 	machines = getMachineRoles(machines)
 
 	var res joinResult
 	err = cld.conn.Txn(db.BlueprintTable,
 		db.MachineTable).Run(func(view db.Database) error {
-		bp, err := view.GetBlueprint()
+		bp, err := view.GetBlueprint(cld.namespace)
 		if err != nil {
-			log.WithError(err).Error("Failed to get blueprint")
-			return err
-		}
-
-		if cld.namespace != bp.Namespace {
-			err := errors.New("namespace change during a cloud run")
 			log.WithError(err).Debug("Cloud run abort")
 			return err
 		}
@@ -61,6 +62,7 @@ func joinImpl(cld *cloud) (joinResult, error) {
 				res.acls = append(res.acls, acl)
 			}
 		}
+		res.labels = bp.Blueprint.Labels
 
 		return nil
 	})
@@ -89,8 +91,14 @@ func (cld *cloud) syncDBWithCloud(view db.Database, cloudMachines []db.Machine)
 		// Providers don't know about some fields, so we don't overwrite them.
 		cm.ID = dbm.ID
 		cm.Status = dbm.Status
+		cm.CloudError = dbm.CloudError
 		cm.SSHKeys = dbm.SSHKeys
 		cm.PublicKey = dbm.PublicKey
+		cm.Unschedulable = dbm.Unschedulable
+		cm.Draining = dbm.Draining
+		cm.Version = dbm.Version
+		cm.Upgrading = dbm.Upgrading
+		cm.Namespace = cld.namespace
 		view.Commit(cm)
 	}
 }
@@ -98,7 +106,7 @@ func (cld *cloud) syncDBWithCloud(view db.Database, cloudMachines []db.Machine)
 func (cld *cloud) syncDBWithBlueprint(view db.Database) joinResult {
 	var res joinResult
 
-	bp, err := view.GetBlueprint()
+	bp, err := view.GetBlueprint(cld.namespace)
 	if err != nil {
 		// Already got the blueprint earlier in this transaction.
 		panic(fmt.Sprintf("Unreachable error: %v", err))
@@ -106,11 +114,16 @@ func (cld *cloud) syncDBWithBlueprint(view db.Database) joinResult {
 
 	dbms := cld.selectMachines(view)
 
-	bpms := cld.desiredMachines(bp.Blueprint.Machines)
+	bpms := cld.desiredMachines(bp.Blueprint)
 	if len(bpms) > 0 || len(dbms) > 0 {
 		res.isActive = true
 	}
 
+	health.syncReplacements(view.SelectFromMachine(nil))
+
+	dbms, reclaimed := cld.filterTerminating(view, dbms)
+	res.terminate = append(res.terminate, reclaimed...)
+	dbms, draining := filterDraining(dbms)
 	pairs, missingBPMs, extraDBMs := join.Join(bpms, dbms, machineScore)
 
 	for _, p := range pairs {
@@ -127,6 +140,24 @@ func (cld *cloud) syncDBWithBlueprint(view db.Database) joinResult {
 		if status != "" {
 			dbm.Status = status
 		}
+
+		if version := getMachineVersion(dbm.CloudID); version != "" {
+			dbm.Version = version
+		}
+
+		if health.shouldReplace(dbm) {
+			c.Inc("Replace Unhealthy Machine")
+			log.WithField("machine", dbm).Warn("Machine hasn't connected " +
+				"within the grace period. Replacing it.")
+			dbm.Status = db.Stopping
+			view.Commit(dbm)
+			res.terminate = append(res.terminate, dbm)
+
+			replacement := bootMachine(view, bpm)
+			health.replacing(replacement.ID)
+			res.boot = append(res.boot, replacement)
+			continue
+		}
 		view.Commit(dbm)
 
 		// Only update IPs once the roles are set. This way, we avoid assigning
@@ -139,35 +170,104 @@ func (cld *cloud) syncDBWithBlueprint(view db.Database) joinResult {
 			dbm.FloatingIP = bpm.FloatingIP
 			res.updateIPs = append(res.updateIPs, dbm)
 		}
+
+		// Labels are applied in place, rather than by replacing the
+		// machine, so that changing the infrastructure's labels doesn't
+		// reboot the whole cluster.  Labels added outside of Kelda are
+		// left alone.
+		if !str.MapContains(dbm.Labels, bpm.Labels) {
+			relabel := dbm
+			relabel.Labels = bpm.Labels
+			res.updateLabels = append(res.updateLabels, relabel)
+		}
 	}
 
-	for _, extraDBM := range extraDBMs {
-		dbm := extraDBM.(db.Machine)
+	stop := func(dbm db.Machine) {
 		dbm.Status = db.Stopping
 		view.Commit(dbm)
 
 		res.terminate = append(res.terminate, dbm)
 	}
 
+	for _, extraDBM := range extraDBMs {
+		dbm := extraDBM.(db.Machine)
+		if !needsDrain(dbm) {
+			stop(dbm)
+			continue
+		}
+
+		c.Inc("Drain Machine")
+		log.WithField("machine", dbm).Info("Draining machine before " +
+			"stopping it.")
+		dbm.Status = db.Draining
+		view.Commit(dbm)
+		draining = append(draining, dbm)
+	}
+
+	cluster := view.SelectFromMachine(func(m db.Machine) bool {
+		return m.Namespace == cld.namespace
+	})
+	for _, dbm := range draining {
+		if drains.drained(dbm, cluster) {
+			stop(dbm)
+		}
+	}
+
 	for _, missingBPM := range missingBPMs {
-		bpm := missingBPM.(db.Machine)
-		dbm := view.InsertMachine()
-		bpm.ID = dbm.ID
-		bpm.Status = db.Booting
-
-		res.boot = append(res.boot, bpm)
-
-		// Don't bother assigning the role to the database, as the foreman will
-		// just unassign it in the next run loop.  We can't be sure which machine
-		// in the database gets which role until we actually connect to it
-		// anyways.
-		bpm.Role = db.None
-		view.Commit(bpm)
+		res.boot = append(res.boot, bootMachine(view, missingBPM.(db.Machine)))
 	}
 
 	return res
 }
 
+// bootMachine inserts a database machine for the blueprint machine `bpm`, and
+// returns the machine that should be booted.
+func bootMachine(view db.Database, bpm db.Machine) db.Machine {
+	dbm := view.InsertMachine()
+	bpm.ID = dbm.ID
+	bpm.Status = db.Booting
+	boot := bpm
+
+	// Don't bother assigning the role to the database, as the foreman will
+	// just unassign it in the next run loop.  We can't be sure which machine
+	// in the database gets which role until we actually connect to it
+	// anyways.
+	bpm.Role = db.None
+	view.Commit(bpm)
+	return boot
+}
+
+// filterTerminating returns the machines in `dbms` that the cloud provider
+// isn't about to reclaim.  The rest are marked as terminating and left out of
+// the join with the blueprint, so that their replacements boot while their
+// containers drain.  Once their minions disconnect, they're returned as
+// `reclaimed` so that they're stopped.  Some providers, such as Google, only
+// shut down reclaimed machines, which would otherwise keep their disks and
+// addresses forever.
+func (cld *cloud) filterTerminating(view db.Database,
+	dbms []db.Machine) (live, reclaimed []db.Machine) {
+
+	for _, dbm := range dbms {
+		if dbm.Status != db.Terminating && !isTerminating(dbm.CloudID) {
+			live = append(live, dbm)
+			continue
+		}
+
+		if dbm.Status != db.Terminating {
+			c.Inc("Terminating Machine")
+			log.WithField("machine", dbm).Info("Cloud provider is " +
+				"reclaiming machine. Booting a replacement.")
+			dbm.Status = db.Terminating
+			view.Commit(dbm)
+		}
+
+		if !isConnected(dbm.CloudID) {
+			reclaimed = append(reclaimed, dbm)
+		}
+	}
+	return live, reclaimed
+}
+
 func machineScore(left, right interface{}) int {
 	l := left.(db.Machine)
 	r := right.(db.Machine)
@@ -178,10 +278,14 @@ func machineScore(left, right interface{}) int {
 		panic("Invalid Provider or Region")
 	case l.Preemptible != r.Preemptible:
 		return -1
+	case l.MaxPrice != 0 && r.MaxPrice != 0 && l.MaxPrice != r.MaxPrice:
+		return -1
 	case l.Size != r.Size:
 		return -1
 	case l.DiskSize != 0 && r.DiskSize != 0 && l.DiskSize != r.DiskSize:
 		return -1
+	case l.Image != "" && r.Image != "" && l.Image != r.Image:
+		return -1
 	case l.Role != db.None && r.Role != db.None && l.Role != r.Role:
 		return -1
 	case l.CloudID != "" && r.CloudID != "" && l.CloudID == r.CloudID:
@@ -238,12 +342,22 @@ func (cld *cloud) desiredACLs(bp db.Blueprint) map[acl.ACL]struct{} {
 		aclSet[acl] = struct{}{}
 	}
 
+	publicCIDRs := []string{"0.0.0.0/0"}
+	if bp.ContainerSubnetIPv6 != "" {
+		publicCIDRs = append(publicCIDRs, "::/0")
+	}
+
 	for _, conn := range bp.Connections {
-		if str.SliceContains(conn.From, blueprint.PublicInternetLabel) {
+		if !str.SliceContains(conn.From, blueprint.PublicInternetLabel) {
+			continue
+		}
+
+		for _, cidr := range publicCIDRs {
 			acl := acl.ACL{
-				CidrIP:  "0.0.0.0/0",
-				MinPort: conn.MinPort,
-				MaxPort: conn.MaxPort,
+				CidrIP:   cidr,
+				MinPort:  conn.MinPort,
+				MaxPort:  conn.MaxPort,
+				Protocol: conn.Protocol,
 			}
 			aclSet[acl] = struct{}{}
 		}
@@ -254,7 +368,8 @@ func (cld *cloud) desiredACLs(bp db.Blueprint) map[acl.ACL]struct{} {
 
 func (cld *cloud) selectMachines(view db.Database) []db.Machine {
 	return view.SelectFromMachine(func(dbm db.Machine) bool {
-		return dbm.Provider == cld.providerName && dbm.Region == cld.region
+		return dbm.Namespace == cld.namespace &&
+			dbm.Provider == cld.providerName && dbm.Region == cld.region
 	})
 }
 
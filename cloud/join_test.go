@@ -3,9 +3,11 @@ package cloud
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/cloud/acl"
+	"github.com/kelda/kelda/cloud/foreman"
 	"github.com/kelda/kelda/db"
 
 	"github.com/stretchr/testify/assert"
@@ -19,17 +21,19 @@ func TestJoin(t *testing.T) {
 	cld.provider.(*fakeProvider).listError = nil
 
 	_, err = joinImpl(cld)
-	assert.EqualError(t, err, "no blueprints found")
+	assert.EqualError(t, err, `no blueprint in namespace "ns"`)
 
 	cld.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
-		view.InsertBlueprint()
+		bp := view.InsertBlueprint()
+		bp.Namespace = "other"
+		view.Commit(bp)
 		return nil
 	})
 	_, err = joinImpl(cld)
-	assert.EqualError(t, err, "namespace change during a cloud run")
+	assert.EqualError(t, err, `no blueprint in namespace "ns"`)
 
 	cld.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
-		bp, _ := view.GetBlueprint()
+		bp := view.InsertBlueprint()
 		bp.Blueprint.Machines = []blueprint.Machine{{
 			Provider: string(FakeAmazon),
 			Region:   testRegion,
@@ -47,16 +51,30 @@ func TestSyncDBWithCloud(t *testing.T) {
 	cld := newTestCloud(FakeAmazon, testRegion, "ns")
 	cld.conn.Txn(db.MachineTable).Run(func(view db.Database) error {
 		m := view.InsertMachine()
+		m.Namespace = "ns"
 		m.Provider = FakeAmazon
 		m.Region = testRegion
 		m.Size = "1"
 		view.Commit(m)
 
 		m = view.InsertMachine()
+		m.Namespace = "ns"
 		m.Provider = FakeAmazon
 		m.Region = testRegion
 		m.Size = "2"
 		m.Status = db.Reconnecting
+		m.Unschedulable = true
+		m.Draining = true
+		m.Version = "0.7.0"
+		m.Upgrading = true
+		view.Commit(m)
+
+		// Machines in other namespaces are managed by other clouds.
+		m = view.InsertMachine()
+		m.Namespace = "other"
+		m.Provider = FakeAmazon
+		m.Region = testRegion
+		m.Size = "2"
 		view.Commit(m)
 
 		cloudMachines := []db.Machine{{
@@ -75,16 +93,27 @@ func TestSyncDBWithCloud(t *testing.T) {
 
 		dbms := scrubID(db.SortMachines(view.SelectFromMachine(nil)))
 		assert.Equal(t, []db.Machine{{
-			Provider: FakeAmazon,
-			Region:   testRegion,
-			PublicIP: "1.2.3.4",
-			Status:   db.Reconnecting,
-			Size:     "2",
+			Namespace:     "ns",
+			Provider:      FakeAmazon,
+			Region:        testRegion,
+			PublicIP:      "1.2.3.4",
+			Status:        db.Reconnecting,
+			Size:          "2",
+			Unschedulable: true,
+			Draining:      true,
+			Version:       "0.7.0",
+			Upgrading:     true,
 		}, {
-			Provider: FakeAmazon,
-			Region:   testRegion,
-			PublicIP: "5.6.7.8",
-			Size:     "3",
+			Namespace: "other",
+			Provider:  FakeAmazon,
+			Region:    testRegion,
+			Size:      "2",
+		}, {
+			Namespace: "ns",
+			Provider:  FakeAmazon,
+			Region:    testRegion,
+			PublicIP:  "5.6.7.8",
+			Size:      "3",
 		}}, dbms)
 
 		return nil
@@ -96,11 +125,14 @@ func TestSyncDBWithBlueprint(t *testing.T) {
 	adminKey = ""
 
 	isConnected = func(s string) bool { return true }
+	getMachineVersion = func(s string) string { return "0.7.0" }
+	defer func() { getMachineVersion = foreman.GetMachineVersion }()
 
 	cld.conn.Txn(db.BlueprintTable,
 		db.MachineTable).Run(func(view db.Database) error {
 
 		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
 		bp.Blueprint.Machines = []blueprint.Machine{{
 			Provider: string(FakeAmazon),
 			Region:   testRegion,
@@ -115,12 +147,14 @@ func TestSyncDBWithBlueprint(t *testing.T) {
 		view.Commit(bp)
 
 		m := view.InsertMachine()
+		m.Namespace = "ns"
 		m.Provider = FakeAmazon
 		m.Region = testRegion
 		m.Size = "2"
 		view.Commit(m)
 
 		m = view.InsertMachine()
+		m.Namespace = "ns"
 		m.Provider = FakeAmazon
 		m.Region = testRegion
 		m.Size = "3"
@@ -130,25 +164,256 @@ func TestSyncDBWithBlueprint(t *testing.T) {
 
 		res := cld.syncDBWithBlueprint(view)
 		assert.Equal(t, []db.Machine{{
-			Provider: FakeAmazon,
-			Region:   testRegion,
-			DiskSize: 32,
-			Size:     "1",
-			Status:   db.Booting}}, scrubID(res.boot))
+			Namespace: "ns",
+			Provider:  FakeAmazon,
+			Region:    testRegion,
+			DiskSize:  32,
+			Size:      "1",
+			Status:    db.Booting}}, scrubID(res.boot))
 		assert.Equal(t, []db.Machine{{
-			Provider: FakeAmazon,
-			Region:   testRegion,
-			Size:     "2",
-			Status:   db.Stopping}}, scrubID(res.terminate))
+			Namespace: "ns",
+			Provider:  FakeAmazon,
+			Region:    testRegion,
+			Size:      "2",
+			Status:    db.Stopping}}, scrubID(res.terminate))
 		assert.Equal(t, []db.Machine{{
+			Namespace:  "ns",
 			Provider:   FakeAmazon,
 			Region:     testRegion,
 			Role:       db.Worker,
 			Size:       "3",
 			PublicIP:   "1.2.3.4",
 			FloatingIP: "5.6.7.8",
-			Status:     db.Connected}}, scrubID(res.updateIPs))
+			Status:     db.Connected,
+			Version:    "0.7.0"}}, scrubID(res.updateIPs))
+
+		return nil
+	})
+}
+
+func TestSyncDBWithBlueprintTerminating(t *testing.T) {
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	adminKey = ""
+
+	isConnected = func(s string) bool { return true }
+	isTerminating = func(id string) bool { return id == "reclaimed" }
+	defer func() { isTerminating = foreman.IsTerminating }()
+
+	cld.conn.Txn(db.BlueprintTable,
+		db.MachineTable).Run(func(view db.Database) error {
+
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		bp.Blueprint.Machines = []blueprint.Machine{{
+			Provider:    string(FakeAmazon),
+			Region:      testRegion,
+			Size:        "1",
+			Preemptible: true,
+		}}
+		view.Commit(bp)
+
+		m := view.InsertMachine()
+		m.Namespace = "ns"
+		m.Provider = FakeAmazon
+		m.Region = testRegion
+		m.Size = "1"
+		m.DiskSize = 32
+		m.Preemptible = true
+		m.CloudID = "reclaimed"
+		m.Status = db.Connected
+		view.Commit(m)
+
+		// The reclaimed machine should be replaced, but not stopped.
+		res := cld.syncDBWithBlueprint(view)
+		assert.Equal(t, []db.Machine{{
+			Namespace:   "ns",
+			Provider:    FakeAmazon,
+			Region:      testRegion,
+			DiskSize:    32,
+			Size:        "1",
+			Preemptible: true,
+			Status:      db.Booting}}, scrubID(res.boot))
+		assert.Empty(t, res.terminate)
+
+		reclaimed := view.SelectFromMachine(func(m db.Machine) bool {
+			return m.CloudID == "reclaimed"
+		})
+		assert.Len(t, reclaimed, 1)
+		assert.Equal(t, db.Terminating, reclaimed[0].Status)
+
+		// Once the replacement is booting, nothing else should change, even
+		// if the minion stops reporting the termination notice.
+		isTerminating = func(id string) bool { return false }
+		res = cld.syncDBWithBlueprint(view)
+		assert.Empty(t, res.boot)
+		assert.Empty(t, res.terminate)
+
+		// Once the machine is reclaimed, its minion disconnects.  Google
+		// leaves preempted instances shut down rather than deleting them, so
+		// the machine should be stopped.
+		isConnected = func(s string) bool { return false }
+		res = cld.syncDBWithBlueprint(view)
+		assert.Empty(t, res.boot)
+		assert.Len(t, res.terminate, 1)
+		assert.Equal(t, "reclaimed", res.terminate[0].CloudID)
 
+		reclaimed = view.SelectFromMachine(func(m db.Machine) bool {
+			return m.CloudID == "reclaimed"
+		})
+		assert.Len(t, reclaimed, 1)
+		assert.Equal(t, db.Terminating, reclaimed[0].Status)
+		return nil
+	})
+}
+
+func TestSyncDBWithBlueprintDrain(t *testing.T) {
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	adminKey = ""
+
+	current := time.Now()
+	now = func() time.Time { return current }
+	drained := map[string]bool{}
+	isDrained = func(id string) bool { return drained[id] }
+	settled := map[string]bool{"timeout": true}
+	isSettled = func(id string) bool { return settled[id] }
+	isConnected = func(s string) bool { return true }
+	defer func() {
+		now = time.Now
+		isDrained = foreman.IsDrained
+		isSettled = foreman.IsSettled
+	}()
+
+	cld.conn.Txn(db.BlueprintTable,
+		db.MachineTable).Run(func(view db.Database) error {
+
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		bp.Blueprint.Machines = []blueprint.Machine{{
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			Role:     db.Master,
+			Size:     "1",
+		}}
+		view.Commit(bp)
+
+		for _, id := range []string{"master", "drain", "timeout"} {
+			m := view.InsertMachine()
+			m.Namespace = "ns"
+			m.Provider = FakeAmazon
+			m.Region = testRegion
+			m.Role = db.Worker
+			m.Size = "1"
+			m.DiskSize = 32
+			m.CloudID = id
+			m.Status = db.Connected
+			if id == "master" {
+				m.Role = db.Master
+			}
+			view.Commit(m)
+		}
+
+		// The workers should be drained before they're stopped.
+		res := cld.syncDBWithBlueprint(view)
+		assert.Empty(t, res.boot)
+		assert.Empty(t, res.terminate)
+
+		workers := view.SelectFromMachine(func(m db.Machine) bool {
+			return m.Role == db.Worker
+		})
+		assert.Len(t, workers, 2)
+		for _, m := range workers {
+			assert.Equal(t, db.Draining, m.Status)
+		}
+
+		// Workers whose containers have stopped aren't drained until the
+		// rest of the cluster reports that the containers are running
+		// elsewhere.
+		drained["drain"] = true
+		res = cld.syncDBWithBlueprint(view)
+		assert.Empty(t, res.terminate)
+
+		// Workers that drain are stopped.
+		settled["master"] = true
+		res = cld.syncDBWithBlueprint(view)
+		assert.Len(t, res.terminate, 1)
+		assert.Equal(t, "drain", res.terminate[0].CloudID)
+		assert.Equal(t, db.Stopping, res.terminate[0].Status)
+
+		// Workers that don't drain are stopped after the timeout.
+		current = current.Add(drainTimeout)
+		res = cld.syncDBWithBlueprint(view)
+		assert.Len(t, res.terminate, 2)
+		assert.Equal(t, "timeout", res.terminate[1].CloudID)
+		return nil
+	})
+}
+
+func TestSyncDBWithBlueprintUnhealthy(t *testing.T) {
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	adminKey = ""
+
+	current := time.Now()
+	now = func() time.Time { return current }
+	health = newHealthTracker(HealthPolicy{
+		GracePeriod:     time.Minute,
+		MaxReplacements: 1,
+	})
+	isConnected = func(s string) bool { return false }
+	defer func() {
+		now = time.Now
+		health = newHealthTracker(DefaultHealthPolicy)
+	}()
+
+	cld.conn.Txn(db.BlueprintTable,
+		db.MachineTable).Run(func(view db.Database) error {
+
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		bp.Blueprint.Machines = []blueprint.Machine{{
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			Role:     db.Worker,
+			Size:     "1",
+		}, {
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			Role:     db.Worker,
+			Size:     "1",
+		}}
+		view.Commit(bp)
+
+		for _, id := range []string{"a", "b"} {
+			m := view.InsertMachine()
+			m.Namespace = "ns"
+			m.Provider = FakeAmazon
+			m.Region = testRegion
+			m.Role = db.Worker
+			m.Size = "1"
+			m.DiskSize = 32
+			m.CloudID = id
+			m.PublicIP = "1.2.3.4"
+			m.Status = db.Connected
+			view.Commit(m)
+		}
+
+		res := cld.syncDBWithBlueprint(view)
+		assert.Empty(t, res.boot)
+		assert.Empty(t, res.terminate)
+
+		// Once the grace period passes, only one of the machines should be
+		// replaced at a time.
+		current = current.Add(time.Minute)
+		res = cld.syncDBWithBlueprint(view)
+		assert.Len(t, res.terminate, 1)
+		assert.Equal(t, db.Stopping, res.terminate[0].Status)
+		assert.Equal(t, []db.Machine{{
+			Namespace: "ns",
+			Provider:  FakeAmazon,
+			Region:    testRegion,
+			Role:      db.Worker,
+			DiskSize:  32,
+			Size:      "1",
+			Status:    db.Booting}}, scrubID(res.boot))
 		return nil
 	})
 }
@@ -163,6 +428,7 @@ func TestSyncDBWithBlueprintFloatingIP(t *testing.T) {
 		db.MachineTable).Run(func(view db.Database) error {
 
 		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
 		bp.Blueprint.Machines = []blueprint.Machine{{
 			Role:     db.Master,
 			Provider: string(FakeAmazon),
@@ -178,12 +444,14 @@ func TestSyncDBWithBlueprintFloatingIP(t *testing.T) {
 		// When the machine has not yet connected, don't attempt to update
 		// floating IPs.
 		master := view.InsertMachine()
+		master.Namespace = "ns"
 		master.Provider = FakeAmazon
 		master.Role = db.Master
 		master.Region = testRegion
 		view.Commit(master)
 
 		worker := view.InsertMachine()
+		worker.Namespace = "ns"
 		worker.Provider = FakeAmazon
 		worker.Region = testRegion
 		view.Commit(worker)
@@ -199,6 +467,7 @@ func TestSyncDBWithBlueprintFloatingIP(t *testing.T) {
 		res = cld.syncDBWithBlueprint(view)
 		assert.Subset(t, scrubID(res.updateIPs), []db.Machine{
 			{
+				Namespace:  "ns",
 				Provider:   FakeAmazon,
 				Region:     testRegion,
 				Role:       db.Worker,
@@ -257,6 +526,31 @@ func TestMachineScore(t *testing.T) {
 	m1.DiskSize = 64
 	assert.Equal(t, -1, machineScore(m, m1))
 
+	// Image
+	m1 = m
+	m1.Image = "image"
+	assert.Equal(t, 0, machineScore(m, m1))
+	m2 = m1
+	m2.Image = "other"
+	assert.Equal(t, -1, machineScore(m1, m2))
+
+	// MaxPrice
+	m1 = m
+	m1.MaxPrice = 0.1
+	assert.Equal(t, 0, machineScore(m, m1))
+	m2 = m1
+	m2.MaxPrice = 0.2
+	assert.Equal(t, -1, machineScore(m1, m2))
+
+	// Labels don't affect the score, as they're updated in place.
+	m1 = m
+	m1.Labels = map[string]string{"team": "infra"}
+	assert.Equal(t, 0, machineScore(m, m1))
+	assert.Equal(t, 0, machineScore(m1, m))
+	m2 = m1
+	m2.Labels = map[string]string{"team": "web"}
+	assert.Equal(t, 0, machineScore(m1, m2))
+
 	// Size
 	m1 = m
 	m1.Size = "wrong"
@@ -346,6 +640,36 @@ func TestDesiredACLs(t *testing.T) {
 	})
 	exp[acl.ACL{CidrIP: "0.0.0.0/0", MinPort: 1, MaxPort: 2}] = struct{}{}
 	assert.Equal(t, exp, acls)
+
+	// Blueprints with IPv6 enabled also accept public IPv6 traffic.
+	acls = cld.desiredACLs(db.Blueprint{
+		Blueprint: blueprint.Blueprint{
+			ContainerSubnetIPv6: "fd00::/64",
+			Connections: []blueprint.Connection{{
+				From:    []string{blueprint.PublicInternetLabel},
+				To:      []string{"bar"},
+				MinPort: 1,
+				MaxPort: 2,
+			}},
+		},
+	})
+	exp[acl.ACL{CidrIP: "::/0", MinPort: 1, MaxPort: 2}] = struct{}{}
+	assert.Equal(t, exp, acls)
+
+	// The ACLs of public connections keep their protocol.
+	acls = cld.desiredACLs(db.Blueprint{
+		Blueprint: blueprint.Blueprint{
+			Connections: []blueprint.Connection{{
+				From:     []string{blueprint.PublicInternetLabel},
+				To:       []string{"bar"},
+				Protocol: blueprint.ICMP,
+			}},
+		},
+	})
+	assert.Equal(t, map[acl.ACL]struct{}{
+		{CidrIP: "local", MinPort: 1, MaxPort: 65535}:   {},
+		{CidrIP: "0.0.0.0/0", Protocol: blueprint.ICMP}: {},
+	}, acls)
 }
 
 // Test that syncDBWithBlueprint properly syncs the SSH key information to the database.
@@ -358,6 +682,7 @@ func TestJoinSSHKeys(t *testing.T) {
 		db.MachineTable).Run(func(view db.Database) error {
 
 		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
 		bp.Blueprint.Machines = []blueprint.Machine{{
 			Provider: string(FakeAmazon),
 			Region:   testRegion,
@@ -367,6 +692,7 @@ func TestJoinSSHKeys(t *testing.T) {
 		view.Commit(bp)
 
 		m := view.InsertMachine()
+		m.Namespace = "ns"
 		m.Provider = FakeAmazon
 		m.Region = testRegion
 		m.Size = "1"
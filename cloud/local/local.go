@@ -0,0 +1,420 @@
+package local
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kelda/kelda/cloud/acl"
+	"github.com/kelda/kelda/cloud/cfg"
+	tlsIO "github.com/kelda/kelda/connection/tls/io"
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/db"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	namespaceLabel = "kelda.namespace"
+	sizeLabel      = "kelda.size"
+)
+
+// The subset of the Docker client used by the provider.
+type client interface {
+	InspectImage(name string) (*dkc.Image, error)
+	BuildImage(opts dkc.BuildImageOptions) error
+	ExportImage(opts dkc.ExportImageOptions) error
+	CreateNetwork(opts dkc.CreateNetworkOptions) (*dkc.Network, error)
+	FilteredListNetworks(opts dkc.NetworkFilterOpts) ([]dkc.Network, error)
+	RemoveNetwork(id string) error
+	CreateContainer(opts dkc.CreateContainerOptions) (*dkc.Container, error)
+	StartContainer(id string, hostConfig *dkc.HostConfig) error
+	ListContainers(opts dkc.ListContainersOptions) ([]dkc.APIContainers, error)
+	RemoveContainer(opts dkc.RemoveContainerOptions) error
+	CreateExec(opts dkc.CreateExecOptions) (*dkc.Exec, error)
+	StartExec(id string, opts dkc.StartExecOptions) error
+	InspectExec(id string) (*dkc.ExecInspect, error)
+}
+
+// The Provider object represents a connection to the local Docker daemon.
+// Each machine is a privileged container running its own Docker daemon, and
+// machines in the same namespace share a private Docker network.
+type Provider struct {
+	client
+
+	namespace string
+}
+
+var c = counter.New("Local")
+
+// New creates a new local provider that uses the Docker daemon configured by
+// the DOCKER_HOST environment variable, or the local socket by default.
+func New(namespace string) (*Provider, error) {
+	dk, err := newDockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dk.FilteredListNetworks(nil); err != nil {
+		return nil, fmt.Errorf("connect to docker: %s", err)
+	}
+	return &Provider{client: dk, namespace: namespace}, nil
+}
+
+// Boot creates a machine container for each machine in `bootSet`.
+func (prvdr Provider) Boot(bootSet []db.Machine) ([]string, error) {
+	for _, m := range bootSet {
+		if m.Preemptible {
+			return nil, errors.New(
+				"local provider does not support preemptible instances")
+		}
+		if m.Image != "" {
+			return nil, errors.New(
+				"local provider does not support custom images")
+		}
+	}
+
+	if err := prvdr.buildMachineImage(); err != nil {
+		return nil, fmt.Errorf("build machine image: %s", err)
+	}
+
+	if err := prvdr.createNetwork(); err != nil {
+		return nil, fmt.Errorf("create network: %s", err)
+	}
+
+	// If any of the bootMachine() calls fail, errChan will contain exactly
+	// one error for this function to return.
+	errChan := make(chan error, 1)
+
+	var ids []string
+	var idsLock sync.Mutex
+	var wg sync.WaitGroup
+	for _, m := range bootSet {
+		wg.Add(1)
+		go func(m db.Machine) {
+			defer wg.Done()
+			id, err := prvdr.bootMachine(m)
+			if err != nil {
+				select {
+				case errChan <- err:
+				default:
+				}
+				return
+			}
+
+			idsLock.Lock()
+			ids = append(ids, id)
+			idsLock.Unlock()
+		}(m)
+	}
+	wg.Wait()
+
+	var err error
+	select {
+	case err = <-errChan:
+	default:
+	}
+
+	return ids, err
+}
+
+func (prvdr Provider) bootMachine(m db.Machine) (string, error) {
+	c.Inc("Create Container")
+	hostConfig := &dkc.HostConfig{
+		Privileged:    true,
+		RestartPolicy: dkc.RestartUnlessStopped(),
+	}
+	if ram, cpu, ok := parseSize(m.Size); ok {
+		hostConfig.Memory = int64(ram * (1 << 30))
+		hostConfig.CPUPeriod = cpuPeriod
+		hostConfig.CPUQuota = int64(cpu * cpuPeriod)
+	}
+
+	container, err := prvdr.CreateContainer(dkc.CreateContainerOptions{
+		Config: &dkc.Config{
+			Image: machineImage(),
+			Env: []string{
+				"SSH_KEYS=" + strings.Join(m.SSHKeys, "\n"),
+				"KELDA_IMAGE=" + cfg.Image(),
+				"LOG_LEVEL=" + log.GetLevel().String(),
+				"ROLE=" + string(m.Role),
+				"TLS_DIR=" + tlsIO.MinionTLSDir,
+				"INSECURE_REGISTRIES=" + strings.Join(
+					cfg.InsecureRegistries(m), " "),
+			},
+			Labels: map[string]string{
+				namespaceLabel: prvdr.namespace,
+				sizeLabel:      m.Size,
+			},
+		},
+		HostConfig: hostConfig,
+		NetworkingConfig: &dkc.NetworkingConfig{
+			EndpointsConfig: map[string]*dkc.EndpointConfig{
+				prvdr.networkName(): {},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create machine: %s", err)
+	}
+
+	c.Inc("Start Container")
+	if err := prvdr.StartContainer(container.ID, nil); err != nil {
+		prvdr.removeMachine(container.ID)
+		return "", fmt.Errorf("start machine: %s", err)
+	}
+
+	// Copy the Kelda image into the machine if the host has it, so that
+	// machines boot without access to a registry.  Otherwise, the machine
+	// pulls it.
+	if _, err := prvdr.InspectImage(cfg.Image()); err == nil {
+		if err := prvdr.loadImage(container.ID, cfg.Image()); err != nil {
+			log.WithError(err).WithField("machine", container.ID).Warn(
+				"Failed to copy the Kelda image into machine")
+		}
+	}
+
+	return container.ID, nil
+}
+
+// loadImage streams `image` from the host's Docker daemon to the Docker daemon
+// in the machine container `id`.
+func (prvdr Provider) loadImage(id, image string) error {
+	c.Inc("Load Image")
+	exec, err := prvdr.CreateExec(dkc.CreateExecOptions{
+		Container:    id,
+		Cmd:          loadImageCmd,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+	exportErr := make(chan error, 1)
+	go func() {
+		err := prvdr.ExportImage(dkc.ExportImageOptions{
+			Name:         image,
+			OutputStream: writer,
+		})
+		writer.CloseWithError(err)
+		exportErr <- err
+	}()
+
+	var output bytes.Buffer
+	err = prvdr.StartExec(exec.ID, dkc.StartExecOptions{
+		InputStream:  reader,
+		OutputStream: &output,
+		ErrorStream:  &output,
+	})
+	reader.Close()
+	if err := <-exportErr; err != nil {
+		return fmt.Errorf("export: %s", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	inspect, err := prvdr.InspectExec(exec.ID)
+	if err != nil {
+		return err
+	}
+
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("docker load: %s", strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// List returns the machine containers in the namespace.
+func (prvdr Provider) List() ([]db.Machine, error) {
+	c.Inc("List")
+	containers, err := prvdr.ListContainers(dkc.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {prvdr.namespaceFilter()}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	machines := []db.Machine{}
+	for _, container := range containers {
+		var ip string
+		if network, ok := container.Networks.Networks[prvdr.networkName()]; ok {
+			ip = network.IPAddress
+		}
+
+		machines = append(machines, db.Machine{
+			Provider:  db.Local,
+			CloudID:   container.ID,
+			PublicIP:  ip,
+			PrivateIP: ip,
+			Size:      container.Labels[sizeLabel],
+		})
+	}
+	return machines, nil
+}
+
+// Stop removes the machine containers of `machines`.
+func (prvdr Provider) Stop(machines []db.Machine) error {
+	for _, m := range machines {
+		if err := prvdr.removeMachine(m.CloudID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (prvdr Provider) removeMachine(id string) error {
+	c.Inc("Remove Container")
+	return prvdr.RemoveContainer(dkc.RemoveContainerOptions{
+		ID:            id,
+		Force:         true,
+		RemoveVolumes: true,
+	})
+}
+
+// SetACLs is a noop because machine containers are only reachable from the
+// host.
+func (prvdr Provider) SetACLs(acls []acl.ACL) error {
+	return nil
+}
+
+// UpdateFloatingIPs is not supported.
+func (prvdr *Provider) UpdateFloatingIPs([]db.Machine) error {
+	return errors.New("local provider does not support floating IPs")
+}
+
+// UpdateLabels is a noop because local machines aren't labeled.
+func (prvdr *Provider) UpdateLabels([]db.Machine) error {
+	return nil
+}
+
+// SetLabels is a noop because local machines aren't labeled.
+func (prvdr *Provider) SetLabels(map[string]string) error {
+	return nil
+}
+
+// Cleanup removes the namespace's network.  It's intended to be called when
+// there are no machines running or expected to be running soon.
+func (prvdr *Provider) Cleanup() error {
+	networks, err := prvdr.namespaceNetworks()
+	if err != nil {
+		return err
+	}
+
+	for _, network := range networks {
+		c.Inc("Remove Network")
+		if err := prvdr.RemoveNetwork(network.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (prvdr Provider) createNetwork() error {
+	networks, err := prvdr.namespaceNetworks()
+	if err != nil || len(networks) > 0 {
+		return err
+	}
+
+	c.Inc("Create Network")
+	_, err = prvdr.CreateNetwork(dkc.CreateNetworkOptions{
+		Name:           prvdr.networkName(),
+		Driver:         "bridge",
+		CheckDuplicate: true,
+		Labels:         map[string]string{namespaceLabel: prvdr.namespace},
+	})
+	return err
+}
+
+func (prvdr Provider) namespaceNetworks() ([]dkc.Network, error) {
+	c.Inc("List Networks")
+	return prvdr.FilteredListNetworks(dkc.NetworkFilterOpts{
+		"label": {prvdr.namespaceFilter(): true},
+	})
+}
+
+// buildMachineImage builds the machine image unless it already exists.
+func (prvdr Provider) buildMachineImage() error {
+	if _, err := prvdr.InspectImage(machineImage()); err == nil {
+		return nil
+	} else if err != dkc.ErrNoSuchImage {
+		return err
+	}
+
+	var context bytes.Buffer
+	tw := tar.NewWriter(&context)
+	for name, contents := range map[string]string{
+		"Dockerfile": dockerfile,
+		"boot.sh":    bootScript,
+	} {
+		hdr := &tar.Header{Name: name, Mode: 0755, Size: int64(len(contents))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	log.Info("Building the local machine image. This may take a few minutes.")
+	c.Inc("Build Image")
+	return prvdr.BuildImage(dkc.BuildImageOptions{
+		Name:         machineImage(),
+		InputStream:  &context,
+		OutputStream: ioutil.Discard,
+	})
+}
+
+func (prvdr Provider) networkName() string {
+	return "kelda-" + prvdr.namespace
+}
+
+func (prvdr Provider) namespaceFilter() string {
+	return namespaceLabel + "=" + prvdr.namespace
+}
+
+// machineImage returns the name of the machine image.  The tag is derived from
+// the image's contents so that changes to them cause the image to be rebuilt.
+func machineImage() string {
+	hash := sha256.Sum256([]byte(dockerfile + bootScript))
+	return fmt.Sprintf("kelda-local-machine:%x", hash[:6])
+}
+
+// The length of the CFS scheduling period used to limit machines' CPU usage, in
+// microseconds.
+const cpuPeriod = 100000
+
+// parseSize parses sizes of the form "RAM,CPU", where RAM is in GiB.
+func parseSize(size string) (ram, cpu float64, ok bool) {
+	parts := strings.Split(size, ",")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	ram, ramErr := strconv.ParseFloat(parts[0], 64)
+	cpu, cpuErr := strconv.ParseFloat(parts[1], 64)
+	if ramErr != nil || cpuErr != nil || ram <= 0 || cpu <= 0 {
+		return 0, 0, false
+	}
+	return ram, cpu, true
+}
+
+// Stored in a variable so it may be mocked out for unit tests.
+var newDockerClient = func() (client, error) {
+	return dkc.NewClientFromEnv()
+}
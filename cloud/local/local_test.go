@@ -0,0 +1,318 @@
+package local
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/cloud/cfg"
+	"github.com/kelda/kelda/db"
+)
+
+// fakeClient simulates the subset of the Docker daemon used by the provider.
+type fakeClient struct {
+	images     map[string]bool
+	built      map[string]map[string]string
+	networks   map[string]dkc.Network
+	containers map[string]dkc.APIContainers
+	configs    map[string]dkc.CreateContainerOptions
+	loaded     map[string]string
+	execs      map[string]string
+	nextID     int
+
+	startErr error
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		images:     map[string]bool{},
+		built:      map[string]map[string]string{},
+		networks:   map[string]dkc.Network{},
+		containers: map[string]dkc.APIContainers{},
+		configs:    map[string]dkc.CreateContainerOptions{},
+		loaded:     map[string]string{},
+		execs:      map[string]string{},
+	}
+}
+
+func (fc *fakeClient) newID() string {
+	fc.nextID++
+	return fmt.Sprintf("%d", fc.nextID)
+}
+
+func (fc *fakeClient) InspectImage(name string) (*dkc.Image, error) {
+	if !fc.images[name] {
+		return nil, dkc.ErrNoSuchImage
+	}
+	return &dkc.Image{}, nil
+}
+
+func (fc *fakeClient) BuildImage(opts dkc.BuildImageOptions) error {
+	files := map[string]string{}
+	tr := tar.NewReader(opts.InputStream)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		files[hdr.Name] = string(contents)
+	}
+
+	fc.images[opts.Name] = true
+	fc.built[opts.Name] = files
+	return nil
+}
+
+func (fc *fakeClient) ExportImage(opts dkc.ExportImageOptions) error {
+	_, err := io.WriteString(opts.OutputStream, "image:"+opts.Name)
+	return err
+}
+
+func (fc *fakeClient) CreateNetwork(opts dkc.CreateNetworkOptions) (
+	*dkc.Network, error) {
+	network := dkc.Network{ID: fc.newID(), Name: opts.Name, Labels: opts.Labels}
+	fc.networks[network.ID] = network
+	return &network, nil
+}
+
+func (fc *fakeClient) FilteredListNetworks(opts dkc.NetworkFilterOpts) (
+	[]dkc.Network, error) {
+	var networks []dkc.Network
+	for _, network := range fc.networks {
+		if matchesLabels(network.Labels, opts["label"]) {
+			networks = append(networks, network)
+		}
+	}
+	return networks, nil
+}
+
+func (fc *fakeClient) RemoveNetwork(id string) error {
+	delete(fc.networks, id)
+	return nil
+}
+
+func (fc *fakeClient) CreateContainer(opts dkc.CreateContainerOptions) (
+	*dkc.Container, error) {
+	id := fc.newID()
+	networks := map[string]dkc.ContainerNetwork{}
+	for name := range opts.NetworkingConfig.EndpointsConfig {
+		networks[name] = dkc.ContainerNetwork{IPAddress: "172.18.0." + id}
+	}
+
+	fc.containers[id] = dkc.APIContainers{
+		ID:       id,
+		Labels:   opts.Config.Labels,
+		Networks: dkc.NetworkList{Networks: networks},
+	}
+	fc.configs[id] = opts
+	return &dkc.Container{ID: id}, nil
+}
+
+func (fc *fakeClient) StartContainer(id string, _ *dkc.HostConfig) error {
+	return fc.startErr
+}
+
+func (fc *fakeClient) ListContainers(opts dkc.ListContainersOptions) (
+	[]dkc.APIContainers, error) {
+	labels := map[string]bool{}
+	for _, label := range opts.Filters["label"] {
+		labels[label] = true
+	}
+
+	var containers []dkc.APIContainers
+	for i := 1; i <= fc.nextID; i++ {
+		container, ok := fc.containers[fmt.Sprintf("%d", i)]
+		if ok && matchesLabels(container.Labels, labels) {
+			containers = append(containers, container)
+		}
+	}
+	return containers, nil
+}
+
+func (fc *fakeClient) RemoveContainer(opts dkc.RemoveContainerOptions) error {
+	if _, ok := fc.containers[opts.ID]; !ok {
+		return &dkc.NoSuchContainer{ID: opts.ID}
+	}
+	delete(fc.containers, opts.ID)
+	return nil
+}
+
+func (fc *fakeClient) CreateExec(opts dkc.CreateExecOptions) (*dkc.Exec, error) {
+	id := fc.newID()
+	fc.execs[id] = opts.Container
+	return &dkc.Exec{ID: id}, nil
+}
+
+func (fc *fakeClient) StartExec(id string, opts dkc.StartExecOptions) error {
+	input, err := ioutil.ReadAll(opts.InputStream)
+	if err != nil {
+		return err
+	}
+	fc.loaded[fc.execs[id]] = string(input)
+	return nil
+}
+
+func (fc *fakeClient) InspectExec(id string) (*dkc.ExecInspect, error) {
+	return &dkc.ExecInspect{}, nil
+}
+
+func matchesLabels(labels map[string]string, filters map[string]bool) bool {
+	for filter := range filters {
+		kv := strings.SplitN(filter, "=", 2)
+		if len(kv) != 2 || labels[kv[0]] != kv[1] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNew(t *testing.T) {
+	fc := newFakeClient()
+	newDockerClient = func() (client, error) { return fc, nil }
+	prvdr, err := New("ns")
+	assert.NoError(t, err)
+	assert.Equal(t, &Provider{client: fc, namespace: "ns"}, prvdr)
+
+	newDockerClient = func() (client, error) { return nil, errors.New("err") }
+	_, err = New("ns")
+	assert.EqualError(t, err, "err")
+}
+
+func TestBootListStop(t *testing.T) {
+	fc := newFakeClient()
+	fc.images[cfg.Image()] = true
+	prvdr := Provider{client: fc, namespace: "ns"}
+	otherNs := Provider{client: fc, namespace: "other"}
+
+	ids, err := prvdr.Boot([]db.Machine{
+		{Role: db.Master, Size: "2,1", SSHKeys: []string{"a", "b"},
+			ContainerSubnet: "172.20.0.0/16"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, ids, 1)
+
+	// The machine image should be built from the embedded Dockerfile.
+	assert.Equal(t, map[string]map[string]string{
+		machineImage(): {"Dockerfile": dockerfile, "boot.sh": bootScript},
+	}, fc.built)
+
+	networks, err := prvdr.namespaceNetworks()
+	assert.NoError(t, err)
+	assert.Len(t, networks, 1)
+	assert.Equal(t, "kelda-ns", networks[0].Name)
+
+	opts := fc.configs[ids[0]]
+	assert.Equal(t, machineImage(), opts.Config.Image)
+	assert.Contains(t, opts.Config.Env, "SSH_KEYS=a\nb")
+	assert.Contains(t, opts.Config.Env, "ROLE=Master")
+	assert.Contains(t, opts.Config.Env, "KELDA_IMAGE="+cfg.Image())
+	assert.Contains(t, opts.Config.Env, "INSECURE_REGISTRIES=172.20.0.0/16 "+
+		"10.0.0.0/8 172.16.0.0/12 192.168.0.0/16")
+	assert.True(t, opts.HostConfig.Privileged)
+	assert.Equal(t, int64(2<<30), opts.HostConfig.Memory)
+	assert.Equal(t, int64(cpuPeriod), opts.HostConfig.CPUQuota)
+	assert.Equal(t, "image:"+cfg.Image(), fc.loaded[ids[0]])
+
+	_, err = otherNs.Boot([]db.Machine{{Role: db.Worker, Size: "1,1"}})
+	assert.NoError(t, err)
+
+	// The image and network should be reused.
+	workerIDs, err := prvdr.Boot([]db.Machine{{Role: db.Worker, Size: "1,1"}})
+	assert.NoError(t, err)
+	assert.Len(t, fc.built, 1)
+	networks, err = prvdr.namespaceNetworks()
+	assert.NoError(t, err)
+	assert.Len(t, networks, 1)
+
+	machines, err := prvdr.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []db.Machine{
+		{Provider: db.Local, CloudID: ids[0], PublicIP: "172.18.0." + ids[0],
+			PrivateIP: "172.18.0." + ids[0], Size: "2,1"},
+		{Provider: db.Local, CloudID: workerIDs[0],
+			PublicIP:  "172.18.0." + workerIDs[0],
+			PrivateIP: "172.18.0." + workerIDs[0], Size: "1,1"},
+	}, machines)
+
+	assert.NoError(t, prvdr.Stop(machines[:1]))
+	machines, err = prvdr.List()
+	assert.NoError(t, err)
+	assert.Len(t, machines, 1)
+	assert.Equal(t, workerIDs[0], machines[0].CloudID)
+
+	assert.NoError(t, prvdr.Stop(machines))
+	assert.NoError(t, prvdr.Cleanup())
+	networks, err = prvdr.namespaceNetworks()
+	assert.NoError(t, err)
+	assert.Empty(t, networks)
+
+	// The other namespace's machines and network should be untouched.
+	machines, err = otherNs.List()
+	assert.NoError(t, err)
+	assert.Len(t, machines, 1)
+	networks, err = otherNs.namespaceNetworks()
+	assert.NoError(t, err)
+	assert.Len(t, networks, 1)
+}
+
+func TestBootErrors(t *testing.T) {
+	fc := newFakeClient()
+	prvdr := Provider{client: fc, namespace: "ns"}
+
+	_, err := prvdr.Boot([]db.Machine{{Preemptible: true}})
+	assert.EqualError(t, err, "local provider does not support preemptible "+
+		"instances")
+
+	_, err = prvdr.Boot([]db.Machine{{Image: "image"}})
+	assert.EqualError(t, err, "local provider does not support custom images")
+
+	// Machines that fail to start should be removed.
+	fc.startErr = errors.New("start")
+	ids, err := prvdr.Boot([]db.Machine{{}})
+	assert.EqualError(t, err, "start machine: start")
+	assert.Empty(t, ids)
+	assert.Empty(t, fc.containers)
+
+	// The Kelda image should only be copied into machines if the host has it.
+	fc.startErr = nil
+	ids, err = prvdr.Boot([]db.Machine{{}})
+	assert.NoError(t, err)
+	assert.Empty(t, fc.loaded)
+
+	// Machines without a valid size aren't limited.
+	assert.Equal(t, int64(0), fc.configs[ids[0]].HostConfig.Memory)
+}
+
+func TestParseSize(t *testing.T) {
+	ram, cpu, ok := parseSize("0.5,2")
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, ram)
+	assert.Equal(t, 2.0, cpu)
+
+	for _, size := range []string{"", "1", "a,1", "1,b", "0,1", "1,2,3"} {
+		_, _, ok := parseSize(size)
+		assert.False(t, ok, size)
+	}
+}
+
+func TestBootScriptLoadsOVS(t *testing.T) {
+	// The Open vSwitch modules must be loaded before the minion starts.
+	ovs := strings.Index(bootScript, "insmod $modules/$module.ko")
+	minion := strings.Index(bootScript, "docker run --net=host --name=minion")
+	assert.True(t, ovs > 0 && ovs < minion)
+	assert.Contains(t, bootScript, "for module in openvswitch vport-geneve vport-stt")
+}
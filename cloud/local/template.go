@@ -0,0 +1,89 @@
+package local
+
+// The image that local machines run.  It's a Docker-in-Docker image with an
+// SSH server, so that machine containers look like any other Kelda machine to
+// the daemon.
+var dockerfile = `FROM docker:17.09.1-ce-dind
+
+RUN apk add --no-cache bash openssh openssh-sftp-server sudo \
+    && ssh-keygen -A \
+    && addgroup -S docker \
+    && adduser -D -s /bin/bash kelda \
+    && addgroup kelda docker \
+    && echo 'kelda:*' | chpasswd -e \
+    && echo 'kelda ALL=(ALL) NOPASSWD: ALL' > /etc/sudoers.d/kelda
+
+COPY boot.sh /usr/local/bin/kelda-boot
+ENTRYPOINT ["kelda-boot"]
+`
+
+// bootScript starts the machine's SSH server and Docker daemon, and then runs
+// the minion.  It's configured by the environment variables set on the
+// machine container.
+var bootScript = `#!/bin/sh
+set -e
+
+install -d -o kelda -m 700 /home/kelda/.ssh
+printf "%s\n" "$SSH_KEYS" > /home/kelda/.ssh/authorized_keys
+chown kelda /home/kelda/.ssh/authorized_keys
+
+# Create the TLS directory now so that it's owned by the kelda user when the
+# daemon installs the minion's credentials.
+install -d -o kelda -m 755 "$TLS_DIR"
+mkdir -p /var/lib/etcd /var/lib/kelda /var/log/kelda
+
+/usr/sbin/sshd
+
+registries=""
+for subnet in $INSECURE_REGISTRIES; do
+	registries="$registries --insecure-registry $subnet"
+done
+
+dind dockerd --host=unix:///var/run/docker.sock --ip-forward=false \
+	--bridge=none $registries &
+until docker info >/dev/null 2>&1 ; do
+	sleep 1
+done
+
+# Machines share the host's kernel, so the Open vSwitch kernel modules are
+# loaded into it like the ovs.service of other machines does, unless another
+# machine already loaded them.
+docker run --rm --privileged --net=host "$KELDA_IMAGE" bash -c '
+	modules=/modules/$(uname -r)
+	if [ ! -d $modules ]; then
+		echo WARN No usable pre-built kernel module. Building now... >&2
+		/bin/bootstrap kernel_modules $(uname -r)
+	fi
+	for module in openvswitch vport-geneve vport-stt; do
+		if [ ! -d /sys/module/$(echo $module | tr - _) ]; then
+			insmod $modules/$module.ko
+		fi
+	done'
+
+# Like minion.service on other machines, the minion is restarted whenever it
+# exits.  It runs the image named in $MINION_IMAGE, which "kelda upgrade"
+# rewrites before removing the minion container.
+MINION_IMAGE=/var/lib/kelda/minion-image
+if [ ! -f $MINION_IMAGE ]; then
+	echo "$KELDA_IMAGE" > $MINION_IMAGE
+fi
+
+while true; do
+	docker rm -f minion >/dev/null 2>&1 || true
+	docker run --net=host --name=minion --privileged \
+		-v /var/run/docker.sock:/var/run/docker.sock \
+		-v /etc/ssl/certs/ca-certificates.crt:/etc/ssl/certs/ca-certificates.crt \
+		-v /home/kelda/.ssh:/home/kelda/.ssh:rw \
+		-v /var/log/kelda:/var/log/kelda:rw \
+		-v /var/lib/kelda:/var/lib/kelda:rw \
+		-v "$TLS_DIR:$TLS_DIR:ro" \
+		-v /run/docker:/run/docker:rw "$(cat $MINION_IMAGE)" \
+		kelda -l "$LOG_LEVEL" minion --role "$ROLE" || true
+	sleep 10
+done
+`
+
+// The command run in machines to load the Kelda image exported from the host,
+// once the machine's Docker daemon is ready.
+var loadImageCmd = []string{"sh", "-c",
+	"until docker info >/dev/null 2>&1 ; do sleep 1 ; done ; docker load"}
@@ -0,0 +1,32 @@
+package cloud
+
+import (
+	"github.com/kelda/kelda/cloud/cost"
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/db"
+)
+
+var clusterC = counter.New("Cluster")
+
+// SyncMetrics keeps the machine gauges up to date with the machine table.  The
+// estimated cost only includes the machines whose prices are known.
+func SyncMetrics(conn db.Conn) {
+	for range conn.Trigger(db.MachineTable).C {
+		machines := conn.SelectFromMachine(nil)
+		clusterC.SetLabeledGauge("Machines", "Status",
+			machinesByStatus(machines))
+		clusterC.SetGauge("Hourly Cost", cost.Machines(machines).Hourly)
+	}
+}
+
+func machinesByStatus(machines []db.Machine) map[string]float64 {
+	statuses := map[string]float64{}
+	for _, m := range machines {
+		status := m.Status
+		if status == "" {
+			status = "unknown"
+		}
+		statuses[status]++
+	}
+	return statuses
+}
@@ -0,0 +1,25 @@
+package cloud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/db"
+)
+
+func TestMachinesByStatus(t *testing.T) {
+	assert.Empty(t, machinesByStatus(nil))
+
+	machines := []db.Machine{
+		{Status: db.Connected},
+		{Status: db.Connected},
+		{Status: db.Booting},
+		{},
+	}
+	assert.Equal(t, map[string]float64{
+		db.Connected: 2,
+		db.Booting:   1,
+		"unknown":    1,
+	}, machinesByStatus(machines))
+}
@@ -0,0 +1,520 @@
+//go:generate mockery -name=Client
+
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/util"
+)
+
+// A Client for the OpenStack compute (Nova) and networking (Neutron) APIs.
+// Used for unit testing.
+type Client interface {
+	ListServers() ([]Server, error)
+	CreateServer(opts CreateServerOpts) (*Server, error)
+	DeleteServer(id string) error
+
+	ListSecurityGroups(name string) ([]SecurityGroup, error)
+	CreateSecurityGroup(name, description string) (*SecurityGroup, error)
+	DeleteSecurityGroup(id string) error
+	CreateSecurityGroupRule(rule SecurityGroupRule) (*SecurityGroupRule, error)
+	DeleteSecurityGroupRule(id string) error
+
+	ListFloatingIPs() ([]FloatingIP, error)
+	UpdateFloatingIP(id, portID string) error
+	ListPorts(deviceID string) ([]Port, error)
+}
+
+// Config describes how to connect to an OpenStack cloud.  It's read from
+// ~/.openstack/kelda.json.
+type Config struct {
+	AuthURL     string
+	Username    string
+	Password    string
+	ProjectName string
+	DomainName  string
+
+	// The IDs of the Ubuntu image that machines boot, and of the network
+	// they're attached to.
+	Image   string
+	Network string
+
+	// The regions that Kelda may boot machines in.
+	Regions []string
+}
+
+// A Server is a Nova instance.
+type Server struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Status    string               `json:"status"`
+	Metadata  map[string]string    `json:"metadata"`
+	Addresses map[string][]Address `json:"addresses"`
+
+	// The ID of the image the server booted.  Nova reports the image as an
+	// object, or as the empty string for servers booted from volumes, so
+	// it's decoded by ListServers.
+	Image string `json:"-"`
+}
+
+// An Address is an IP address of a Server.  Its Type is either "fixed" or
+// "floating".
+type Address struct {
+	Addr string `json:"addr"`
+	Type string `json:"OS-EXT-IPS:type"`
+}
+
+// CreateServerOpts are the options for booting a Server.
+type CreateServerOpts struct {
+	Name          string
+	Flavor        string
+	Image         string // Defaults to the configured image.
+	UserData      string
+	SecurityGroup string
+	Metadata      map[string]string
+}
+
+// A SecurityGroup is a Neutron security group.
+type SecurityGroup struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Rules       []SecurityGroupRule `json:"security_group_rules"`
+}
+
+// A SecurityGroupRule is a rule of a SecurityGroup.  An empty Protocol matches
+// all protocols.
+type SecurityGroupRule struct {
+	ID              string `json:"id,omitempty"`
+	SecurityGroupID string `json:"security_group_id"`
+	Direction       string `json:"direction"`
+	EtherType       string `json:"ethertype"`
+	Protocol        string `json:"protocol,omitempty"`
+	PortRangeMin    int    `json:"port_range_min,omitempty"`
+	PortRangeMax    int    `json:"port_range_max,omitempty"`
+	RemoteIPPrefix  string `json:"remote_ip_prefix,omitempty"`
+	RemoteGroupID   string `json:"remote_group_id,omitempty"`
+}
+
+// A FloatingIP is a Neutron floating IP.  It's associated with the port
+// PortID, if any.
+type FloatingIP struct {
+	ID                string `json:"id"`
+	FloatingIPAddress string `json:"floating_ip_address"`
+	PortID            string `json:"port_id"`
+}
+
+// A Port is a Neutron port.  DeviceID is the ID of the Server that the port is
+// attached to.
+type Port struct {
+	ID       string `json:"id"`
+	DeviceID string `json:"device_id"`
+}
+
+type client struct {
+	config Config
+	region string
+	http   *http.Client
+
+	// Protects the fields below, which are refreshed when the token expires.
+	lock       sync.Mutex
+	token      string
+	expires    time.Time
+	computeURL string
+	networkURL string
+	flavors    map[string]string
+}
+
+var c = counter.New("OpenStack")
+
+// ReadConfig reads the OpenStack configuration from ~/.openstack/kelda.json.
+func ReadConfig() (Config, error) {
+	configPath := filepath.Join(os.Getenv("HOME"), ".openstack", "kelda.json")
+	configStr, err := util.ReadFile(configPath)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	if err := json.Unmarshal([]byte(configStr), &config); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %s", configPath, err)
+	}
+	return config, nil
+}
+
+// New creates a new OpenStack client for `region`.
+func New(region string) (Client, error) {
+	c.Inc("New Client")
+
+	config, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	clnt := &client{
+		config: config,
+		region: region,
+		http:   &http.Client{Timeout: time.Minute},
+	}
+	if _, _, err := clnt.authenticate(); err != nil {
+		return nil, err
+	}
+	return clnt, nil
+}
+
+func (clnt *client) ListServers() ([]Server, error) {
+	c.Inc("List Servers")
+	var resp struct {
+		Servers []struct {
+			Server
+			Image json.RawMessage `json:"image"`
+		} `json:"servers"`
+	}
+	if err := clnt.do("GET", compute, "/servers/detail", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var servers []Server
+	for _, s := range resp.Servers {
+		var image struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(s.Image, &image) == nil {
+			s.Server.Image = image.ID
+		}
+		servers = append(servers, s.Server)
+	}
+	return servers, nil
+}
+
+func (clnt *client) CreateServer(opts CreateServerOpts) (*Server, error) {
+	flavor, err := clnt.flavorID(opts.Flavor)
+	if err != nil {
+		return nil, err
+	}
+
+	image := opts.Image
+	if image == "" {
+		image = clnt.config.Image
+	}
+
+	req := map[string]interface{}{"server": map[string]interface{}{
+		"name":            opts.Name,
+		"imageRef":        image,
+		"flavorRef":       flavor,
+		"networks":        []map[string]string{{"uuid": clnt.config.Network}},
+		"security_groups": []map[string]string{{"name": opts.SecurityGroup}},
+		"user_data": base64.StdEncoding.EncodeToString(
+			[]byte(opts.UserData)),
+		"metadata": opts.Metadata,
+	}}
+
+	c.Inc("Create Server")
+	var resp struct {
+		Server Server `json:"server"`
+	}
+	if err := clnt.do("POST", compute, "/servers", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Server, nil
+}
+
+func (clnt *client) DeleteServer(id string) error {
+	c.Inc("Delete Server")
+	return clnt.do("DELETE", compute, "/servers/"+id, nil, nil)
+}
+
+func (clnt *client) ListSecurityGroups(name string) ([]SecurityGroup, error) {
+	c.Inc("List Security Groups")
+	var resp struct {
+		SecurityGroups []SecurityGroup `json:"security_groups"`
+	}
+	err := clnt.do("GET", network, "/v2.0/security-groups?name="+
+		url.QueryEscape(name), nil, &resp)
+	return resp.SecurityGroups, err
+}
+
+func (clnt *client) CreateSecurityGroup(name, description string) (
+	*SecurityGroup, error) {
+	c.Inc("Create Security Group")
+	req := map[string]interface{}{"security_group": map[string]string{
+		"name":        name,
+		"description": description,
+	}}
+
+	var resp struct {
+		SecurityGroup SecurityGroup `json:"security_group"`
+	}
+	err := clnt.do("POST", network, "/v2.0/security-groups", req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.SecurityGroup, nil
+}
+
+func (clnt *client) DeleteSecurityGroup(id string) error {
+	c.Inc("Delete Security Group")
+	return clnt.do("DELETE", network, "/v2.0/security-groups/"+id, nil, nil)
+}
+
+func (clnt *client) CreateSecurityGroupRule(rule SecurityGroupRule) (
+	*SecurityGroupRule, error) {
+	c.Inc("Create Security Group Rule")
+	req := map[string]interface{}{"security_group_rule": rule}
+
+	var resp struct {
+		Rule SecurityGroupRule `json:"security_group_rule"`
+	}
+	err := clnt.do("POST", network, "/v2.0/security-group-rules", req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Rule, nil
+}
+
+func (clnt *client) DeleteSecurityGroupRule(id string) error {
+	c.Inc("Delete Security Group Rule")
+	return clnt.do("DELETE", network, "/v2.0/security-group-rules/"+id, nil, nil)
+}
+
+func (clnt *client) ListFloatingIPs() ([]FloatingIP, error) {
+	c.Inc("List Floating IPs")
+	var resp struct {
+		FloatingIPs []FloatingIP `json:"floatingips"`
+	}
+	err := clnt.do("GET", network, "/v2.0/floatingips", nil, &resp)
+	return resp.FloatingIPs, err
+}
+
+// UpdateFloatingIP associates the floating IP `id` with the port `portID`, or
+// disassociates it if `portID` is empty.
+func (clnt *client) UpdateFloatingIP(id, portID string) error {
+	c.Inc("Update Floating IP")
+	var port interface{}
+	if portID != "" {
+		port = portID
+	}
+
+	req := map[string]interface{}{"floatingip": map[string]interface{}{
+		"port_id": port,
+	}}
+	return clnt.do("PUT", network, "/v2.0/floatingips/"+id, req, nil)
+}
+
+func (clnt *client) ListPorts(deviceID string) ([]Port, error) {
+	c.Inc("List Ports")
+	var resp struct {
+		Ports []Port `json:"ports"`
+	}
+	err := clnt.do("GET", network, "/v2.0/ports?device_id="+
+		url.QueryEscape(deviceID), nil, &resp)
+	return resp.Ports, err
+}
+
+// flavorID returns the ID of the flavor called `name`.
+func (clnt *client) flavorID(name string) (string, error) {
+	clnt.lock.Lock()
+	id, ok := clnt.flavors[name]
+	clnt.lock.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	c.Inc("List Flavors")
+	var resp struct {
+		Flavors []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"flavors"`
+	}
+	if err := clnt.do("GET", compute, "/flavors", nil, &resp); err != nil {
+		return "", err
+	}
+
+	flavors := map[string]string{}
+	for _, flavor := range resp.Flavors {
+		flavors[flavor.Name] = flavor.ID
+	}
+
+	clnt.lock.Lock()
+	clnt.flavors = flavors
+	clnt.lock.Unlock()
+
+	if id, ok := flavors[name]; ok {
+		return id, nil
+	}
+	return "", fmt.Errorf("unknown flavor: %s", name)
+}
+
+type service int
+
+const (
+	compute service = iota
+	network
+)
+
+// do sends a request to `svc`, and decodes the JSON response into `respBody`
+// if it's not nil.
+func (clnt *client) do(method string, svc service, path string,
+	reqBody, respBody interface{}) error {
+
+	token, urls, err := clnt.authenticate()
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if reqBody != nil {
+		if err := json.NewEncoder(&body).Encode(reqBody); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, urls[svc]+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Auth-Token", token)
+
+	resp, err := clnt.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status,
+			strings.TrimSpace(string(respBytes)))
+	}
+
+	if respBody == nil || len(respBytes) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBytes, respBody)
+}
+
+// authenticate returns the token and service URLs used to access the cloud,
+// requesting a new token from Keystone if the current one is about to expire.
+func (clnt *client) authenticate() (string, map[service]string, error) {
+	clnt.lock.Lock()
+	defer clnt.lock.Unlock()
+
+	if clnt.token == "" || time.Now().Add(time.Minute).After(clnt.expires) {
+		if err := clnt.requestToken(); err != nil {
+			return "", nil, fmt.Errorf("authenticate: %s", err)
+		}
+	}
+
+	return clnt.token, map[service]string{
+		compute: clnt.computeURL,
+		network: clnt.networkURL,
+	}, nil
+}
+
+// requestToken requests a new token from Keystone, and looks up the region's
+// service URLs in the token's catalog.
+func (clnt *client) requestToken() error {
+
+	domain := map[string]string{"name": clnt.config.DomainName}
+	req := map[string]interface{}{"auth": map[string]interface{}{
+		"identity": map[string]interface{}{
+			"methods": []string{"password"},
+			"password": map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":     clnt.config.Username,
+					"password": clnt.config.Password,
+					"domain":   domain,
+				},
+			},
+		},
+		"scope": map[string]interface{}{
+			"project": map[string]interface{}{
+				"name":   clnt.config.ProjectName,
+				"domain": domain,
+			},
+		},
+	}}
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	c.Inc("Authenticate")
+	authURL := strings.TrimSuffix(clnt.config.AuthURL, "/") + "/auth/tokens"
+	resp, err := clnt.http.Post(authURL, "application/json",
+		bytes.NewReader(reqBytes))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%s: %s", resp.Status,
+			strings.TrimSpace(string(respBytes)))
+	}
+
+	var tokenResp struct {
+		Token struct {
+			ExpiresAt time.Time `json:"expires_at"`
+			Catalog   []struct {
+				Type      string `json:"type"`
+				Endpoints []struct {
+					Interface string `json:"interface"`
+					Region    string `json:"region"`
+					URL       string `json:"url"`
+				} `json:"endpoints"`
+			} `json:"catalog"`
+		} `json:"token"`
+	}
+	if err := json.Unmarshal(respBytes, &tokenResp); err != nil {
+		return err
+	}
+
+	urls := map[string]string{}
+	for _, svc := range tokenResp.Token.Catalog {
+		for _, ep := range svc.Endpoints {
+			if ep.Interface == "public" && ep.Region == clnt.region {
+				urls[svc.Type] = strings.TrimSuffix(ep.URL, "/")
+			}
+		}
+	}
+
+	for _, svcType := range []string{"compute", "network"} {
+		if urls[svcType] == "" {
+			return fmt.Errorf("no %s endpoint in region %s", svcType,
+				clnt.region)
+		}
+	}
+
+	clnt.token = resp.Header.Get("X-Subject-Token")
+	clnt.expires = tokenResp.Token.ExpiresAt
+	clnt.computeURL = urls["compute"]
+	clnt.networkURL = urls["network"]
+	return nil
+}
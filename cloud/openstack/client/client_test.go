@@ -0,0 +1,200 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/util"
+)
+
+// newTestServer returns a server that simulates Keystone, Nova, and Neutron.
+// Requests to Nova and Neutron are recorded in `requests`.
+func newTestServer(t *testing.T, requests *[]string, expires time.Time) (
+	*httptest.Server, *int) {
+
+	var srv *httptest.Server
+	var tokens int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/identity/auth/tokens", func(w http.ResponseWriter,
+		r *http.Request) {
+		var req struct {
+			Auth struct {
+				Identity struct {
+					Password struct {
+						User struct {
+							Password string
+						}
+					}
+				}
+			}
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.Auth.Identity.Password.User.Password != "secret" {
+			http.Error(w, `{"error": "bad password"}`,
+				http.StatusUnauthorized)
+			return
+		}
+
+		tokens++
+		w.Header().Set("X-Subject-Token", fmt.Sprintf("token%d", tokens))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": {"expires_at": %q, "catalog": [
+			{"type": "compute", "endpoints": [
+				{"interface": "public", "region": "RegionOne",
+				 "url": "%[2]s/compute/"},
+				{"interface": "internal", "region": "RegionOne",
+				 "url": "http://internal"},
+				{"interface": "public", "region": "RegionTwo",
+				 "url": "http://region-two"}]},
+			{"type": "network", "endpoints": [
+				{"interface": "public", "region": "RegionOne",
+				 "url": "%[2]s/network"}]}]}}`,
+			expires.Format(time.RFC3339), srv.URL)
+	})
+
+	handle := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*requests = append(*requests, fmt.Sprintf("%s %s %s %s", r.Method,
+			r.URL.RequestURI(), r.Header.Get("X-Auth-Token"), body))
+
+		switch r.URL.Path {
+		case "/compute/servers/detail":
+			fmt.Fprint(w, `{"servers": [{"id": "1", "name": "a",
+				"metadata": {"kelda": "ns"}, "addresses": {"net": [{
+					"addr": "10.0.0.2",
+					"OS-EXT-IPS:type": "fixed"}]},
+				"image": {"id": "img"}},
+				{"id": "3", "image": ""}]}`)
+		case "/compute/flavors":
+			fmt.Fprint(w, `{"flavors": [{"id": "f1", "name": "m1.small"}]}`)
+		case "/compute/servers":
+			fmt.Fprint(w, `{"server": {"id": "2"}}`)
+		case "/network/v2.0/floatingips/fip":
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}
+	mux.HandleFunc("/compute/", handle)
+	mux.HandleFunc("/network/", handle)
+
+	srv = httptest.NewServer(mux)
+	return srv, &tokens
+}
+
+func writeConfig(srvURL, password string) {
+	util.AppFs = afero.NewMemMapFs()
+	config := fmt.Sprintf(`{"AuthURL": "%s/identity", "Username": "user",
+		"Password": %q, "ProjectName": "proj", "DomainName": "Default",
+		"Image": "img", "Network": "net", "Regions": ["RegionOne"]}`,
+		srvURL, password)
+	util.WriteFile(filepath.Join(os.Getenv("HOME"), ".openstack", "kelda.json"),
+		[]byte(config), 0644)
+}
+
+func TestClient(t *testing.T) {
+	var requests []string
+	srv, tokens := newTestServer(t, &requests, time.Now().Add(time.Hour))
+	defer srv.Close()
+
+	writeConfig(srv.URL, "secret")
+	clnt, err := New("RegionOne")
+	assert.NoError(t, err)
+
+	servers, err := clnt.ListServers()
+	assert.NoError(t, err)
+	assert.Equal(t, []Server{{
+		ID:       "1",
+		Name:     "a",
+		Metadata: map[string]string{"kelda": "ns"},
+		Addresses: map[string][]Address{
+			"net": {{Addr: "10.0.0.2", Type: "fixed"}},
+		},
+		Image: "img",
+	}, {ID: "3"}}, servers)
+
+	server, err := clnt.CreateServer(CreateServerOpts{
+		Name:          "b",
+		Flavor:        "m1.small",
+		UserData:      "boot",
+		SecurityGroup: "sg",
+		Metadata:      map[string]string{"kelda": "ns"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &Server{ID: "2"}, server)
+
+	_, err = clnt.CreateServer(CreateServerOpts{Flavor: "m1.small",
+		Image: "debian"})
+	assert.NoError(t, err)
+
+	_, err = clnt.CreateServer(CreateServerOpts{Flavor: "m1.huge"})
+	assert.EqualError(t, err, "unknown flavor: m1.huge")
+
+	assert.NoError(t, clnt.UpdateFloatingIP("fip", ""))
+
+	err = clnt.DeleteServer("3")
+	assert.EqualError(t, err, "DELETE /servers/3: 404 Not Found: not found")
+
+	assert.Equal(t, []string{
+		"GET /compute/servers/detail token1 ",
+		"GET /compute/flavors token1 ",
+		`POST /compute/servers token1 {"server":{"flavorRef":"f1",` +
+			`"imageRef":"img","metadata":{"kelda":"ns"},"name":"b",` +
+			`"networks":[{"uuid":"net"}],"security_groups":[{"name":"sg"}],` +
+			`"user_data":"Ym9vdA=="}}` + "\n",
+		`POST /compute/servers token1 {"server":{"flavorRef":"f1",` +
+			`"imageRef":"debian","metadata":null,"name":"",` +
+			`"networks":[{"uuid":"net"}],"security_groups":[{"name":""}],` +
+			`"user_data":""}}` + "\n",
+		"GET /compute/flavors token1 ",
+		`PUT /network/v2.0/floatingips/fip token1 {"floatingip":` +
+			`{"port_id":null}}` + "\n",
+		"DELETE /compute/servers/3 token1 ",
+	}, requests)
+	assert.Equal(t, 1, *tokens)
+}
+
+func TestTokenRefresh(t *testing.T) {
+	var requests []string
+	srv, tokens := newTestServer(t, &requests, time.Now())
+	defer srv.Close()
+
+	// Tokens that are about to expire should be replaced.
+	writeConfig(srv.URL, "secret")
+	clnt, err := New("RegionOne")
+	assert.NoError(t, err)
+
+	_, err = clnt.ListServers()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *tokens)
+	assert.Equal(t, []string{"GET /compute/servers/detail token2 "}, requests)
+}
+
+func TestAuthenticateErrors(t *testing.T) {
+	var requests []string
+	srv, _ := newTestServer(t, &requests, time.Now().Add(time.Hour))
+	defer srv.Close()
+
+	writeConfig(srv.URL, "wrong")
+	_, err := New("RegionOne")
+	assert.EqualError(t, err, `authenticate: 401 Unauthorized: `+
+		`{"error": "bad password"}`)
+
+	writeConfig(srv.URL, "secret")
+	_, err = New("RegionTwo")
+	assert.EqualError(t, err, "authenticate: no network endpoint in region "+
+		"RegionTwo")
+
+	util.AppFs = afero.NewMemMapFs()
+	_, err = New("RegionOne")
+	assert.Error(t, err)
+}
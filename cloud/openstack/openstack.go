@@ -0,0 +1,360 @@
+package openstack
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/kelda/kelda/cloud/acl"
+	"github.com/kelda/kelda/cloud/cfg"
+	"github.com/kelda/kelda/cloud/openstack/client"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/join"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultRegion is the region used when the configuration doesn't list any.
+const DefaultRegion = "RegionOne"
+
+// The metadata keys used to tag the servers booted by Kelda.
+const (
+	namespaceKey = "kelda-namespace"
+	sizeKey      = "kelda-size"
+)
+
+// The Provider object represents a connection to an OpenStack cloud.
+type Provider struct {
+	client.Client
+
+	namespace     string
+	region        string
+	securityGroup string
+}
+
+// Regions returns the regions listed in ~/.openstack/kelda.json, or
+// DefaultRegion if none are listed.
+func Regions() []string {
+	config, err := client.ReadConfig()
+	if err != nil || len(config.Regions) == 0 {
+		return []string{DefaultRegion}
+	}
+	return config.Regions
+}
+
+// New creates a new OpenStack provider for `region`.
+func New(namespace, region string) (*Provider, error) {
+	clnt, err := newClient(region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize OpenStack client: %s",
+			err)
+	}
+
+	return &Provider{
+		Client:        clnt,
+		namespace:     namespace,
+		region:        region,
+		securityGroup: fmt.Sprintf("kelda-%s", namespace),
+	}, nil
+}
+
+// List the servers in the namespace.
+func (prvdr *Provider) List() ([]db.Machine, error) {
+	servers, err := prvdr.ListServers()
+	if err != nil {
+		return nil, err
+	}
+
+	var machines []db.Machine
+	for _, server := range servers {
+		if server.Metadata[namespaceKey] != prvdr.namespace {
+			continue
+		}
+
+		var privateIP, floatingIP string
+		for _, addrs := range server.Addresses {
+			for _, addr := range addrs {
+				switch addr.Type {
+				case "fixed":
+					privateIP = addr.Addr
+				case "floating":
+					floatingIP = addr.Addr
+				}
+			}
+		}
+
+		// Servers without a floating IP are only reachable at their fixed
+		// IP, so the daemon must be able to route to the servers' network.
+		publicIP := floatingIP
+		if publicIP == "" {
+			publicIP = privateIP
+		}
+
+		machines = append(machines, db.Machine{
+			Provider:   db.OpenStack,
+			Region:     prvdr.region,
+			CloudID:    server.ID,
+			PublicIP:   publicIP,
+			PrivateIP:  privateIP,
+			FloatingIP: floatingIP,
+			Size:       server.Metadata[sizeKey],
+			Image:      server.Image,
+		})
+	}
+	return machines, nil
+}
+
+// Boot creates a server for each machine in `bootSet`.
+func (prvdr *Provider) Boot(bootSet []db.Machine) ([]string, error) {
+	for _, m := range bootSet {
+		if m.Preemptible {
+			return nil, errors.New(
+				"openstack does not support preemptible instances")
+		}
+	}
+
+	if _, err := prvdr.getCreateSecurityGroup(); err != nil {
+		return nil, fmt.Errorf("create security group: %s", err)
+	}
+
+	type bootResult struct {
+		id  string
+		err error
+	}
+
+	results := make(chan bootResult)
+	for _, m := range bootSet {
+		go func(m db.Machine) {
+			server, err := prvdr.CreateServer(client.CreateServerOpts{
+				Name:          randName(),
+				Flavor:        m.Size,
+				Image:         m.Image,
+				UserData:      cfg.BootScript(m, ""),
+				SecurityGroup: prvdr.securityGroup,
+				Metadata: map[string]string{
+					namespaceKey: prvdr.namespace,
+					sizeKey:      m.Size,
+				},
+			})
+			if err != nil {
+				results <- bootResult{err: err}
+				return
+			}
+			results <- bootResult{id: server.ID}
+		}(m)
+	}
+
+	var ids []string
+	var err error
+	for range bootSet {
+		res := <-results
+		if res.err != nil {
+			err = res.err
+		} else {
+			ids = append(ids, res.id)
+		}
+	}
+	return ids, err
+}
+
+// Stop deletes the servers of `machines`.
+func (prvdr *Provider) Stop(machines []db.Machine) error {
+	errChan := make(chan error)
+	for _, m := range machines {
+		go func(m db.Machine) {
+			errChan <- prvdr.DeleteServer(m.CloudID)
+		}(m)
+	}
+
+	var err error
+	for range machines {
+		if stopErr := <-errChan; stopErr != nil {
+			err = stopErr
+		}
+	}
+	return err
+}
+
+// SetACLs updates the namespace's security group so that its ingress rules
+// match `acls`.  Servers in the namespace may always reach each other.
+func (prvdr *Provider) SetACLs(acls []acl.ACL) error {
+	group, err := prvdr.getCreateSecurityGroup()
+	if err != nil {
+		return err
+	}
+
+	// Allow all traffic between the servers in the namespace.
+	rules := []client.SecurityGroupRule{
+		{EtherType: "IPv4", RemoteGroupID: group.ID}}
+	for _, a := range acls {
+		min := a.MinPort
+		if min == 0 {
+			min = 1
+		}
+
+		etherType, icmp := "IPv4", "icmp"
+		if acl.IsIPv6(a.CidrIP) {
+			etherType, icmp = "IPv6", "ipv6-icmp"
+		}
+
+		for _, proto := range a.PortProtocols() {
+			rules = append(rules, client.SecurityGroupRule{
+				EtherType:      etherType,
+				Protocol:       proto,
+				PortRangeMin:   min,
+				PortRangeMax:   a.MaxPort,
+				RemoteIPPrefix: a.CidrIP,
+			})
+		}
+		rules = append(rules, client.SecurityGroupRule{
+			EtherType:      etherType,
+			Protocol:       icmp,
+			RemoteIPPrefix: a.CidrIP,
+		})
+	}
+
+	var current []client.SecurityGroupRule
+	for _, rule := range group.Rules {
+		// Egress rules are created by OpenStack to allow all outbound
+		// traffic, and aren't managed by Kelda.
+		if rule.Direction == "ingress" {
+			current = append(current, rule)
+		}
+	}
+
+	key := func(intf interface{}) interface{} {
+		rule := intf.(client.SecurityGroupRule)
+		rule.ID, rule.SecurityGroupID, rule.Direction = "", "", ""
+		return rule
+	}
+	_, adds, removes := join.HashJoin(ruleSlice(rules), ruleSlice(current),
+		key, key)
+
+	for _, intf := range removes {
+		rule := intf.(client.SecurityGroupRule)
+		log.WithField("rule", rule).Debug("OpenStack Remove ACL")
+		if err := prvdr.DeleteSecurityGroupRule(rule.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, intf := range adds {
+		rule := intf.(client.SecurityGroupRule)
+		rule.SecurityGroupID = group.ID
+		rule.Direction = "ingress"
+		log.WithField("rule", rule).Debug("OpenStack Add ACL")
+		if _, err := prvdr.CreateSecurityGroupRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateFloatingIPs associates the floating IPs of `machines` with their
+// servers, and disassociates any floating IPs they should no longer have.
+func (prvdr *Provider) UpdateFloatingIPs(machines []db.Machine) error {
+	fips, err := prvdr.ListFloatingIPs()
+	if err != nil {
+		return fmt.Errorf("list floating IPs: %s", err)
+	}
+
+	for _, m := range machines {
+		ports, err := prvdr.ListPorts(m.CloudID)
+		if err != nil {
+			return fmt.Errorf("list ports: %s", err)
+		} else if len(ports) == 0 {
+			return fmt.Errorf("server %s has no ports", m.CloudID)
+		}
+		port := ports[0].ID
+
+		var desired *client.FloatingIP
+		for i, fip := range fips {
+			if fip.FloatingIPAddress == m.FloatingIP {
+				desired = &fips[i]
+			}
+		}
+
+		if m.FloatingIP != "" {
+			if desired == nil {
+				return fmt.Errorf("%s is not reserved", m.FloatingIP)
+			} else if desired.PortID != "" && desired.PortID != port {
+				return fmt.Errorf("%s is already assigned", m.FloatingIP)
+			}
+		}
+
+		for _, fip := range fips {
+			if fip.PortID == port && fip.FloatingIPAddress != m.FloatingIP {
+				if err := prvdr.UpdateFloatingIP(fip.ID, ""); err != nil {
+					return err
+				}
+			}
+		}
+
+		if m.FloatingIP != "" && desired.PortID != port {
+			if err := prvdr.UpdateFloatingIP(desired.ID, port); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateLabels is a noop because OpenStack resources aren't labeled.
+func (prvdr *Provider) UpdateLabels([]db.Machine) error {
+	return nil
+}
+
+// SetLabels is a noop because OpenStack resources aren't labeled.
+func (prvdr *Provider) SetLabels(map[string]string) error {
+	return nil
+}
+
+// Cleanup removes the namespace's security group.  It's intended to be called
+// when there are no servers running or expected to be running soon.
+func (prvdr *Provider) Cleanup() error {
+	groups, err := prvdr.ListSecurityGroups(prvdr.securityGroup)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		log.WithField("name", group.Name).Debug(
+			"OpenStack Delete Security Group")
+		if err := prvdr.DeleteSecurityGroup(group.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (prvdr *Provider) getCreateSecurityGroup() (*client.SecurityGroup, error) {
+	groups, err := prvdr.ListSecurityGroups(prvdr.securityGroup)
+	if err != nil {
+		return nil, err
+	} else if len(groups) > 0 {
+		return &groups[0], nil
+	}
+
+	log.WithField("name", prvdr.securityGroup).Debug(
+		"OpenStack Create Security Group")
+	return prvdr.CreateSecurityGroup(prvdr.securityGroup,
+		fmt.Sprintf("Kelda namespace %s", prvdr.namespace))
+}
+
+type ruleSlice []client.SecurityGroupRule
+
+func (s ruleSlice) Get(i int) interface{} { return s[i] }
+
+func (s ruleSlice) Len() int { return len(s) }
+
+var randName = func() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // This really shouldn't ever happen.
+	}
+	return "kelda-" + hex.EncodeToString(b)
+}
+
+// Stored in a variable so it may be mocked out for unit tests.
+var newClient = client.New
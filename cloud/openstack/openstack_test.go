@@ -0,0 +1,322 @@
+package openstack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/kelda/kelda/cloud/acl"
+	"github.com/kelda/kelda/cloud/cfg"
+	"github.com/kelda/kelda/cloud/openstack/client"
+	"github.com/kelda/kelda/cloud/openstack/client/mocks"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+func newTestProvider() (*Provider, *mocks.Client) {
+	mc := new(mocks.Client)
+	return &Provider{
+		Client:        mc,
+		namespace:     "ns",
+		region:        "RegionOne",
+		securityGroup: "kelda-ns",
+	}, mc
+}
+
+func TestRegions(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	assert.Equal(t, []string{DefaultRegion}, Regions())
+
+	path := filepath.Join(os.Getenv("HOME"), ".openstack", "kelda.json")
+	util.WriteFile(path, []byte(`{"Regions": ["a", "b"]}`), 0644)
+	assert.Equal(t, []string{"a", "b"}, Regions())
+}
+
+func TestNew(t *testing.T) {
+	mc := new(mocks.Client)
+	newClient = func(region string) (client.Client, error) {
+		assert.Equal(t, "RegionOne", region)
+		return mc, nil
+	}
+	defer func() { newClient = client.New }()
+
+	prvdr, err := New("ns", "RegionOne")
+	assert.NoError(t, err)
+	assert.Equal(t, &Provider{
+		Client:        mc,
+		namespace:     "ns",
+		region:        "RegionOne",
+		securityGroup: "kelda-ns",
+	}, prvdr)
+
+	newClient = func(string) (client.Client, error) {
+		return nil, errors.New("err")
+	}
+	_, err = New("ns", "RegionOne")
+	assert.EqualError(t, err, "failed to initialize OpenStack client: err")
+}
+
+func TestList(t *testing.T) {
+	prvdr, mc := newTestProvider()
+	mc.On("ListServers").Return([]client.Server{
+		{
+			ID: "1",
+			Metadata: map[string]string{
+				namespaceKey: "ns",
+				sizeKey:      "m1.small",
+			},
+			Addresses: map[string][]client.Address{"net": {
+				{Addr: "10.0.0.2", Type: "fixed"},
+				{Addr: "8.8.8.8", Type: "floating"},
+			}},
+			Image: "img",
+		},
+		{
+			ID: "2",
+			Metadata: map[string]string{
+				namespaceKey: "ns",
+				sizeKey:      "m1.large",
+			},
+			Addresses: map[string][]client.Address{"net": {
+				{Addr: "10.0.0.3", Type: "fixed"},
+			}},
+		},
+		{
+			ID:       "3",
+			Metadata: map[string]string{namespaceKey: "other"},
+		},
+		{ID: "4"},
+	}, nil).Once()
+
+	machines, err := prvdr.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []db.Machine{
+		{
+			Provider:   db.OpenStack,
+			Region:     "RegionOne",
+			CloudID:    "1",
+			PublicIP:   "8.8.8.8",
+			PrivateIP:  "10.0.0.2",
+			FloatingIP: "8.8.8.8",
+			Size:       "m1.small",
+			Image:      "img",
+		},
+		{
+			Provider:  db.OpenStack,
+			Region:    "RegionOne",
+			CloudID:   "2",
+			PublicIP:  "10.0.0.3",
+			PrivateIP: "10.0.0.3",
+			Size:      "m1.large",
+		},
+	}, machines)
+
+	mc.On("ListServers").Return(nil, errors.New("err")).Once()
+	_, err = prvdr.List()
+	assert.EqualError(t, err, "err")
+}
+
+func TestBoot(t *testing.T) {
+	randName = func() string { return "name" }
+	prvdr, mc := newTestProvider()
+
+	_, err := prvdr.Boot([]db.Machine{{Preemptible: true}})
+	assert.EqualError(t, err, "openstack does not support preemptible instances")
+
+	// The security group should be created if it doesn't exist.
+	mc.On("ListSecurityGroups", "kelda-ns").Return(nil, nil).Once()
+	mc.On("CreateSecurityGroup", "kelda-ns", "Kelda namespace ns").Return(
+		&client.SecurityGroup{ID: "sg"}, nil).Once()
+
+	m := db.Machine{Size: "m1.small", Role: db.Master, Image: "debian",
+		OS: db.Debian}
+	mc.On("CreateServer", client.CreateServerOpts{
+		Name:          "name",
+		Flavor:        "m1.small",
+		Image:         "debian",
+		UserData:      cfg.BootScript(m, ""),
+		SecurityGroup: "kelda-ns",
+		Metadata:      map[string]string{namespaceKey: "ns", sizeKey: "m1.small"},
+	}).Return(&client.Server{ID: "1"}, nil).Once()
+
+	ids, err := prvdr.Boot([]db.Machine{m})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, ids)
+
+	mc.On("ListSecurityGroups", "kelda-ns").Return(
+		[]client.SecurityGroup{{ID: "sg"}}, nil)
+	mc.On("CreateServer", mock.Anything).Return(nil, errors.New("quota")).Once()
+	_, err = prvdr.Boot([]db.Machine{m})
+	assert.EqualError(t, err, "quota")
+	mc.AssertExpectations(t)
+}
+
+func TestStop(t *testing.T) {
+	prvdr, mc := newTestProvider()
+	mc.On("DeleteServer", "1").Return(nil)
+	mc.On("DeleteServer", "2").Return(errors.New("err"))
+
+	assert.NoError(t, prvdr.Stop([]db.Machine{{CloudID: "1"}}))
+	assert.EqualError(t, prvdr.Stop([]db.Machine{{CloudID: "1"},
+		{CloudID: "2"}}), "err")
+}
+
+func TestSetACLs(t *testing.T) {
+	prvdr, mc := newTestProvider()
+	mc.On("ListSecurityGroups", "kelda-ns").Return([]client.SecurityGroup{{
+		ID: "sg",
+		Rules: []client.SecurityGroupRule{
+			// Egress rules should be left alone.
+			{ID: "egress", Direction: "egress", EtherType: "IPv4"},
+			{ID: "group", SecurityGroupID: "sg", Direction: "ingress",
+				EtherType: "IPv4", RemoteGroupID: "sg"},
+			{ID: "stale", SecurityGroupID: "sg", Direction: "ingress",
+				EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 22,
+				PortRangeMax: 22, RemoteIPPrefix: "1.1.1.1/32"},
+			{ID: "icmp", SecurityGroupID: "sg", Direction: "ingress",
+				EtherType: "IPv4", Protocol: "icmp",
+				RemoteIPPrefix: "8.8.8.8/32"},
+		},
+	}}, nil)
+
+	mc.On("DeleteSecurityGroupRule", "stale").Return(nil).Once()
+	for _, proto := range []string{"tcp", "udp"} {
+		mc.On("CreateSecurityGroupRule", client.SecurityGroupRule{
+			SecurityGroupID: "sg",
+			Direction:       "ingress",
+			EtherType:       "IPv4",
+			Protocol:        proto,
+			PortRangeMin:    1,
+			PortRangeMax:    65535,
+			RemoteIPPrefix:  "8.8.8.8/32",
+		}).Return(&client.SecurityGroupRule{}, nil).Once()
+	}
+
+	err := prvdr.SetACLs([]acl.ACL{{CidrIP: "8.8.8.8/32", MaxPort: 65535}})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+}
+
+func TestSetACLsProtocol(t *testing.T) {
+	prvdr, mc := newTestProvider()
+	mc.On("ListSecurityGroups", "kelda-ns").Return([]client.SecurityGroup{{
+		ID: "sg",
+		Rules: []client.SecurityGroupRule{
+			{ID: "group", SecurityGroupID: "sg", Direction: "ingress",
+				EtherType: "IPv4", RemoteGroupID: "sg"},
+			{ID: "icmp", SecurityGroupID: "sg", Direction: "ingress",
+				EtherType: "IPv4", Protocol: "icmp",
+				RemoteIPPrefix: "8.8.8.8/32"},
+		},
+	}}, nil)
+
+	mc.On("CreateSecurityGroupRule", client.SecurityGroupRule{
+		SecurityGroupID: "sg",
+		Direction:       "ingress",
+		EtherType:       "IPv4",
+		Protocol:        "tcp",
+		PortRangeMin:    443,
+		PortRangeMax:    443,
+		RemoteIPPrefix:  "8.8.8.8/32",
+	}).Return(&client.SecurityGroupRule{}, nil).Once()
+
+	err := prvdr.SetACLs([]acl.ACL{
+		{CidrIP: "8.8.8.8/32", MinPort: 443, MaxPort: 443, Protocol: "tcp"},
+		{CidrIP: "8.8.8.8/32", Protocol: "icmp"},
+	})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+}
+
+func TestSetACLsIPv6(t *testing.T) {
+	prvdr, mc := newTestProvider()
+	mc.On("ListSecurityGroups", "kelda-ns").Return([]client.SecurityGroup{{
+		ID: "sg",
+		Rules: []client.SecurityGroupRule{
+			{ID: "group", SecurityGroupID: "sg", Direction: "ingress",
+				EtherType: "IPv4", RemoteGroupID: "sg"},
+			// The same range with the wrong ether type must be replaced.
+			{ID: "ipv4", SecurityGroupID: "sg", Direction: "ingress",
+				EtherType: "IPv4", Protocol: "ipv6-icmp",
+				RemoteIPPrefix: "::/0"},
+			{ID: "icmp", SecurityGroupID: "sg", Direction: "ingress",
+				EtherType: "IPv6", Protocol: "ipv6-icmp",
+				RemoteIPPrefix: "::/0"},
+		},
+	}}, nil)
+
+	mc.On("DeleteSecurityGroupRule", "ipv4").Return(nil).Once()
+	for _, proto := range []string{"tcp", "udp"} {
+		mc.On("CreateSecurityGroupRule", client.SecurityGroupRule{
+			SecurityGroupID: "sg",
+			Direction:       "ingress",
+			EtherType:       "IPv6",
+			Protocol:        proto,
+			PortRangeMin:    80,
+			PortRangeMax:    80,
+			RemoteIPPrefix:  "::/0",
+		}).Return(&client.SecurityGroupRule{}, nil).Once()
+	}
+
+	err := prvdr.SetACLs([]acl.ACL{{CidrIP: "::/0", MinPort: 80, MaxPort: 80}})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+}
+
+func TestUpdateFloatingIPs(t *testing.T) {
+	prvdr, mc := newTestProvider()
+	mc.On("ListFloatingIPs").Return([]client.FloatingIP{
+		{ID: "fip1", FloatingIPAddress: "1.1.1.1", PortID: "port1"},
+		{ID: "fip2", FloatingIPAddress: "2.2.2.2"},
+		{ID: "fip3", FloatingIPAddress: "3.3.3.3", PortID: "port3"},
+	}, nil)
+	mc.On("ListPorts", "server1").Return([]client.Port{{ID: "port1"}}, nil)
+
+	// Moving a server to a new floating IP should release the old one.
+	mc.On("UpdateFloatingIP", "fip1", "").Return(nil).Once()
+	mc.On("UpdateFloatingIP", "fip2", "port1").Return(nil).Once()
+	err := prvdr.UpdateFloatingIPs([]db.Machine{
+		{CloudID: "server1", FloatingIP: "2.2.2.2"},
+	})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+
+	// Floating IPs that are already associated shouldn't be touched.
+	err = prvdr.UpdateFloatingIPs([]db.Machine{
+		{CloudID: "server1", FloatingIP: "1.1.1.1"},
+	})
+	assert.NoError(t, err)
+
+	mc.On("UpdateFloatingIP", "fip1", "").Return(nil).Once()
+	err = prvdr.UpdateFloatingIPs([]db.Machine{{CloudID: "server1"}})
+	assert.NoError(t, err)
+
+	err = prvdr.UpdateFloatingIPs([]db.Machine{
+		{CloudID: "server1", FloatingIP: "4.4.4.4"},
+	})
+	assert.EqualError(t, err, "4.4.4.4 is not reserved")
+
+	err = prvdr.UpdateFloatingIPs([]db.Machine{
+		{CloudID: "server1", FloatingIP: "3.3.3.3"},
+	})
+	assert.EqualError(t, err, "3.3.3.3 is already assigned")
+
+	mc.On("ListPorts", "server2").Return(nil, nil)
+	err = prvdr.UpdateFloatingIPs([]db.Machine{{CloudID: "server2"}})
+	assert.EqualError(t, err, "server server2 has no ports")
+	mc.AssertExpectations(t)
+}
+
+func TestCleanup(t *testing.T) {
+	prvdr, mc := newTestProvider()
+	mc.On("ListSecurityGroups", "kelda-ns").Return(
+		[]client.SecurityGroup{{ID: "sg", Name: "kelda-ns"}}, nil)
+	mc.On("DeleteSecurityGroup", "sg").Return(nil).Once()
+	assert.NoError(t, prvdr.Cleanup())
+	mc.AssertExpectations(t)
+}
@@ -0,0 +1,376 @@
+package cloud
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/digitalocean/godo"
+	"google.golang.org/api/googleapi"
+
+	"github.com/kelda/kelda/cloud/acl"
+	"github.com/kelda/kelda/db"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// errorClass categorizes the errors returned by the cloud providers.
+type errorClass int
+
+const (
+	// The provider rejected the request because too many have been made.
+	throttled errorClass = iota
+
+	// The request failed for a reason that's likely to go away on its own,
+	// such as a timeout or an internal error in the provider.
+	transient
+
+	// Retrying the request won't help, e.g. because the credentials are
+	// invalid or the request is malformed.
+	permanent
+)
+
+func (class errorClass) String() string {
+	switch class {
+	case throttled:
+		return "Throttled"
+	case transient:
+		return "Transient Error"
+	default:
+		return "Permanent Error"
+	}
+}
+
+// The number of times a failing call is attempted, and the bounds on how long
+// to wait between attempts.
+const (
+	maxAttempts = 5
+	minBackoff  = time.Second
+	maxBackoff  = 30 * time.Second
+)
+
+// The maximum rate of calls to each provider, shared by all of its regions.
+// A single call may make several API requests, so the limits are well below
+// the providers' published API limits.  Providers that aren't listed aren't
+// limited.
+var providerRates = map[db.ProviderName]struct {
+	interval time.Duration
+	burst    int
+}{
+	db.Amazon:       {interval: 200 * time.Millisecond, burst: 10},
+	db.Google:       {interval: 200 * time.Millisecond, burst: 10},
+	db.DigitalOcean: {interval: time.Second, burst: 5},
+	db.OpenStack:    {interval: 200 * time.Millisecond, burst: 10},
+}
+
+var limiters = struct {
+	sync.Mutex
+	byProvider map[db.ProviderName]*rateLimiter
+}{byProvider: map[db.ProviderName]*rateLimiter{}}
+
+// rateLimiter allows `burst` calls at once, and one call every `interval`
+// after that.  When the provider throttles a call, the limiter is paused so
+// that every region backs off together.
+type rateLimiter struct {
+	sync.Mutex
+	interval time.Duration
+	burst    int
+
+	// The earliest time at which the next call may be made, ignoring the
+	// burst.
+	next time.Time
+}
+
+func getLimiter(p db.ProviderName) *rateLimiter {
+	limiters.Lock()
+	defer limiters.Unlock()
+
+	if limiter, ok := limiters.byProvider[p]; ok {
+		return limiter
+	}
+
+	var limiter *rateLimiter
+	if rate, ok := providerRates[p]; ok {
+		limiter = &rateLimiter{interval: rate.interval, burst: rate.burst}
+	}
+	limiters.byProvider[p] = limiter
+	return limiter
+}
+
+// wait blocks until the caller may make a call to the provider.
+func (l *rateLimiter) wait() {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	current := now()
+	earliest := current.Add(-time.Duration(l.burst-1) * l.interval)
+	if l.next.Before(earliest) {
+		l.next = earliest
+	}
+	delay := l.next.Sub(current)
+	l.next = l.next.Add(l.interval)
+	l.Unlock()
+
+	if delay > 0 {
+		sleep(delay)
+	}
+}
+
+// pause delays all calls to the provider for at least `d`.
+func (l *rateLimiter) pause(d time.Duration) {
+	if l == nil {
+		sleep(d)
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if resume := now().Add(d); l.next.Before(resume) {
+		l.next = resume
+	}
+}
+
+// retryProvider wraps a provider with the provider's shared rate limiter, and
+// retries the calls that fail because of throttling or transient errors.  It
+// remembers the calls that failed anyways, so that they can be shown to the
+// user.
+type retryProvider struct {
+	wrapped provider
+
+	name    db.ProviderName
+	limiter *rateLimiter
+
+	// The error returned by the most recent call to each method during the
+	// current sync pass, if it failed.
+	failures map[string]error
+}
+
+// machineMethods are the calls that act on particular machines.  Their failures
+// are only shown on the machines that they were called with.
+var machineMethods = map[string]bool{
+	"boot":                true,
+	"stop":                true,
+	"update floating IPs": true,
+	"update labels":       true,
+}
+
+func newRetryProvider(name db.ProviderName, prvdr provider) *retryProvider {
+	return &retryProvider{
+		wrapped:  prvdr,
+		name:     name,
+		limiter:  getLimiter(name),
+		failures: map[string]error{},
+	}
+}
+
+func (p *retryProvider) List() (machines []db.Machine, err error) {
+	err = p.call("list", true, func() (err error) {
+		machines, err = p.wrapped.List()
+		return err
+	})
+	return machines, err
+}
+
+// Boot isn't idempotent, so it's never retried.  Even a throttled Boot may have
+// booted some of the machines before it failed, and booting them again would
+// create duplicates.  Instead, the next sync pass boots the machines that are
+// still missing.
+func (p *retryProvider) Boot(machines []db.Machine) (ids []string, err error) {
+	err = p.call("boot", false, func() (err error) {
+		ids, err = p.wrapped.Boot(machines)
+		return err
+	})
+	return ids, err
+}
+
+func (p *retryProvider) Stop(machines []db.Machine) error {
+	return p.call("stop", true, func() error {
+		return p.wrapped.Stop(machines)
+	})
+}
+
+func (p *retryProvider) SetACLs(acls []acl.ACL) error {
+	return p.call("set ACLs", true, func() error {
+		return p.wrapped.SetACLs(acls)
+	})
+}
+
+func (p *retryProvider) UpdateFloatingIPs(machines []db.Machine) error {
+	return p.call("update floating IPs", true, func() error {
+		return p.wrapped.UpdateFloatingIPs(machines)
+	})
+}
+
+func (p *retryProvider) UpdateLabels(machines []db.Machine) error {
+	return p.call("update labels", true, func() error {
+		return p.wrapped.UpdateLabels(machines)
+	})
+}
+
+func (p *retryProvider) SetLabels(labels map[string]string) error {
+	return p.call("set labels", true, func() error {
+		return p.wrapped.SetLabels(labels)
+	})
+}
+
+func (p *retryProvider) Cleanup() error {
+	return p.call("cleanup", true, func() error {
+		return p.wrapped.Cleanup()
+	})
+}
+
+func (p *retryProvider) call(method string, idempotent bool, fn func() error) error {
+	backoff := minBackoff
+	for attempt := 1; ; attempt++ {
+		p.limiter.wait()
+		err := fn()
+		if err == nil {
+			delete(p.failures, method)
+			return nil
+		}
+
+		class := classifyError(err)
+		c.Inc(fmt.Sprintf("%s %s", p.name, class))
+
+		retry := idempotent && class != permanent
+		if !retry || attempt == maxAttempts {
+			// Even if the call won't be retried, the other regions
+			// should back off from a provider that's throttling.
+			if class == throttled {
+				p.limiter.pause(backoff)
+			}
+
+			c.Inc(fmt.Sprintf("%s Persistent Failure", p.name))
+			p.failures[method] = err
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"provider": p.name,
+			"method":   method,
+			"attempt":  attempt,
+			"error":    err,
+		}).Debugf("Cloud provider call failed. Retrying in %s.", backoff)
+		c.Inc(fmt.Sprintf("%s Retry", p.name))
+
+		if class == throttled {
+			p.limiter.pause(backoff)
+		} else {
+			sleep(backoff)
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// failure describes the calls whose most recent attempt failed, or returns the
+// empty string if there are none.  The failures of the calls in machineMethods
+// are only included if they're listed in `machineCalls`.
+func (p *retryProvider) failure(machineCalls ...string) string {
+	include := map[string]bool{}
+	for _, method := range machineCalls {
+		include[method] = true
+	}
+
+	var failures []string
+	for method, err := range p.failures {
+		if machineMethods[method] && !include[method] {
+			continue
+		}
+
+		// Only the first line, as some errors include request details.
+		msg := strings.SplitN(err.Error(), "\n", 2)[0]
+		failures = append(failures, fmt.Sprintf("%s: %s", method, msg))
+	}
+	sort.Strings(failures)
+	return strings.Join(failures, "; ")
+}
+
+// resetFailures forgets the failed calls.  It's called after every sync pass so
+// that the failures of calls that are no longer being made don't linger.
+func (p *retryProvider) resetFailures() {
+	p.failures = map[string]error{}
+}
+
+// Substrings of the errors whose types were lost because they were wrapped.
+var (
+	throttledMessages = []string{"throttl", "rate limit", "ratelimit",
+		"requestlimitexceeded", "too many requests"}
+	transientMessages = []string{"timeout", "timed out", "connection reset",
+		"connection refused", "internal server error", "internalerror",
+		"service unavailable", "serviceunavailable", "bad gateway",
+		"temporarily unavailable"}
+)
+
+func classifyError(err error) errorClass {
+	switch err := err.(type) {
+	case awserr.Error:
+		if request.IsErrorThrottle(err) {
+			return throttled
+		}
+		if request.IsErrorRetryable(err) {
+			return transient
+		}
+		if reqErr, ok := err.(awserr.RequestFailure); ok &&
+			reqErr.StatusCode() >= 500 {
+			return transient
+		}
+	case *googleapi.Error:
+		return classifyStatus(err.Code, googleRateLimited(err))
+	case *godo.ErrorResponse:
+		if err.Response == nil {
+			return permanent
+		}
+		return classifyStatus(err.Response.StatusCode, false)
+	case net.Error:
+		if err.Timeout() || err.Temporary() {
+			return transient
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, substr := range throttledMessages {
+		if strings.Contains(msg, substr) {
+			return throttled
+		}
+	}
+	for _, substr := range transientMessages {
+		if strings.Contains(msg, substr) {
+			return transient
+		}
+	}
+	return permanent
+}
+
+func classifyStatus(code int, rateLimited bool) errorClass {
+	switch {
+	case code == 429 || rateLimited:
+		return throttled
+	case code >= 500:
+		return transient
+	default:
+		return permanent
+	}
+}
+
+// googleRateLimited returns whether `err` is one of the 403 errors that Google
+// uses for throttling.
+func googleRateLimited(err *googleapi.Error) bool {
+	for _, item := range err.Errors {
+		switch item.Reason {
+		case "rateLimitExceeded", "userRateLimitExceeded":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package cloud
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+)
+
+func TestClassifyError(t *testing.T) {
+	doError := func(code int) error {
+		return &godo.ErrorResponse{
+			Response: &http.Response{
+				StatusCode: code,
+				Request:    &http.Request{},
+			},
+		}
+	}
+
+	tests := []struct {
+		err   error
+		class errorClass
+	}{
+		{awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil),
+			throttled},
+		{awserr.New("InternalError", "", nil), transient},
+		{awserr.NewRequestFailure(awserr.New("Unknown", "", nil), 503, ""),
+			transient},
+		{awserr.New("AuthFailure", "", nil), permanent},
+		{&googleapi.Error{Code: 429}, throttled},
+		{&googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{
+			{Reason: "rateLimitExceeded"}}}, throttled},
+		{&googleapi.Error{Code: 403}, permanent},
+		{&googleapi.Error{Code: 500}, transient},
+		{doError(429), throttled},
+		{doError(502), transient},
+		{doError(404), permanent},
+		{errors.New("list: Throttling: Rate exceeded"), throttled},
+		{errors.New("GET /servers: 503 Service Unavailable: "), transient},
+		{errors.New("dial tcp: i/o timeout"), transient},
+		{errors.New("invalid size"), permanent},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.class, classifyError(test.err), test.err.Error())
+	}
+}
+
+type flakyProvider struct {
+	fakeProvider
+	errs  []error
+	calls int
+}
+
+func (p *flakyProvider) Boot(machines []db.Machine) ([]string, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return p.fakeProvider.Boot(machines)
+}
+
+func (p *flakyProvider) Stop(machines []db.Machine) error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	return p.fakeProvider.Stop(machines)
+}
+
+func (p *flakyProvider) next() (err error) {
+	p.calls++
+	if len(p.errs) > 0 {
+		err, p.errs = p.errs[0], p.errs[1:]
+	}
+	return err
+}
+
+func TestRetryProvider(t *testing.T) {
+	var slept []time.Duration
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleep = time.Sleep }()
+
+	throttle := errors.New("Too Many Requests")
+	timeout := errors.New("request timed out")
+	invalid := errors.New("invalid request")
+
+	// Transient errors are retried with an exponential backoff.
+	flaky := &flakyProvider{errs: []error{timeout, timeout}}
+	rp := newRetryProvider(FakeVagrant, flaky)
+	assert.NoError(t, rp.Stop(nil))
+	assert.Equal(t, 3, flaky.calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, slept)
+	assert.Empty(t, rp.failure())
+
+	// Boot isn't retried, as it may have booted some of the machines before
+	// it failed.
+	flaky.calls = 0
+	flaky.errs = []error{timeout}
+	_, err := rp.Boot(nil)
+	assert.Equal(t, timeout, err)
+	assert.Equal(t, 1, flaky.calls)
+	assert.Equal(t, "boot: request timed out", rp.failure("boot"))
+
+	// Failures of calls that act on particular machines are only described
+	// for those machines.
+	assert.Empty(t, rp.failure())
+	assert.Empty(t, rp.failure("stop"))
+
+	slept = nil
+	flaky.calls = 0
+	flaky.errs = []error{throttle}
+	_, err = rp.Boot(nil)
+	assert.Equal(t, throttle, err)
+	assert.Equal(t, 1, flaky.calls)
+	assert.Equal(t, "boot: Too Many Requests", rp.failure("boot"))
+
+	// The throttled Boot still backs off the provider's other calls.
+	assert.Equal(t, []time.Duration{time.Second}, slept)
+
+	// Permanent errors aren't retried.
+	flaky.calls = 0
+	flaky.errs = []error{invalid}
+	assert.Equal(t, invalid, rp.Stop(nil))
+	assert.Equal(t, 1, flaky.calls)
+	assert.Equal(t, "stop: invalid request", rp.failure("stop"))
+
+	// Calls give up after `maxAttempts`.
+	slept = nil
+	flaky.calls = 0
+	flaky.errs = []error{throttle, throttle, throttle, throttle, throttle, throttle}
+	assert.Equal(t, throttle, rp.Stop(nil))
+	assert.Equal(t, maxAttempts, flaky.calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second,
+		4 * time.Second, 8 * time.Second, 16 * time.Second}, slept)
+	assert.Equal(t, "boot: Too Many Requests; stop: Too Many Requests",
+		rp.failure("boot", "stop"))
+
+	rp.resetFailures()
+	assert.Empty(t, rp.failure("boot", "stop"))
+}
+
+func TestRateLimiter(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	var slept []time.Duration
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleep = time.Sleep }()
+
+	limiter := &rateLimiter{interval: time.Second, burst: 2}
+	limiter.wait()
+	limiter.wait()
+	assert.Empty(t, slept)
+
+	limiter.wait()
+	limiter.wait()
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, slept)
+
+	// After a while, the burst is available again.
+	slept = nil
+	current = current.Add(time.Minute)
+	limiter.wait()
+	limiter.wait()
+	assert.Empty(t, slept)
+
+	// Pausing delays every caller.
+	limiter.pause(10 * time.Second)
+	limiter.wait()
+	assert.Equal(t, []time.Duration{10 * time.Second}, slept)
+
+	// Unlimited providers never wait.
+	slept = nil
+	var unlimited *rateLimiter
+	unlimited.wait()
+	assert.Empty(t, slept)
+
+	// Limiters are shared by all of a provider's regions.
+	assert.True(t, getLimiter(db.Amazon) == getLimiter(db.Amazon))
+	assert.Nil(t, getLimiter(db.Vagrant))
+}
+
+func TestSyncCloudErrors(t *testing.T) {
+	cloudJoin = joinImpl
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	flaky := &flakyProvider{fakeProvider: *cld.provider.(*fakeProvider)}
+	cld.provider = newRetryProvider(FakeAmazon, flaky)
+
+	cld.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		bp.Blueprint.Machines = []blueprint.Machine{{
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			Size:     "m4.large",
+		}}
+		view.Commit(bp)
+		return nil
+	})
+
+	flaky.errs = []error{errors.New("insufficient capacity")}
+	cld.runOnce()
+
+	machines := cld.conn.SelectFromMachine(nil)
+	assert.Len(t, machines, 1)
+	assert.Equal(t, "boot: insufficient capacity", machines[0].CloudError)
+	assert.Equal(t, db.Booting, machines[0].Status)
+
+	cld.runOnce()
+	machines = cld.conn.SelectFromMachine(nil)
+	assert.Len(t, machines, 1)
+	assert.Empty(t, machines[0].CloudError)
+	booted := machines[0]
+
+	// Boot errors are only shown on the machines that failed to boot.
+	setMachines := func(sizes ...string) {
+		cld.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+			bp, _ := view.GetBlueprint("ns")
+			bp.Blueprint.Machines = nil
+			for _, size := range sizes {
+				bp.Blueprint.Machines = append(bp.Blueprint.Machines,
+					blueprint.Machine{
+						Provider: string(FakeAmazon),
+						Region:   testRegion,
+						Size:     size,
+					})
+			}
+			view.Commit(bp)
+			return nil
+		})
+	}
+	setMachines("m4.large", "m4.xlarge")
+
+	flaky.errs = []error{errors.New("insufficient capacity")}
+	cld.runOnce()
+	machines = cld.conn.SelectFromMachine(nil)
+	assert.Len(t, machines, 2)
+	for _, dbm := range machines {
+		if dbm.ID == booted.ID {
+			assert.Empty(t, dbm.CloudError)
+		} else {
+			assert.Equal(t, "boot: insufficient capacity", dbm.CloudError)
+		}
+	}
+
+	// Once the machine is no longer being booted, its error goes away.
+	setMachines("m4.large")
+	cld.runOnce()
+	machines = cld.conn.SelectFromMachine(nil)
+	assert.Len(t, machines, 1)
+	assert.Empty(t, machines[0].CloudError)
+
+	// Failures that affect the whole region are shown on every machine, until
+	// the call stops failing.
+	flaky.listError = errors.New("unauthorized")
+	cld.runOnce()
+	machines = cld.conn.SelectFromMachine(nil)
+	assert.Len(t, machines, 1)
+	assert.Equal(t, "list: unauthorized", machines[0].CloudError)
+
+	flaky.listError = nil
+	cld.runOnce()
+	machines = cld.conn.SelectFromMachine(nil)
+	assert.Len(t, machines, 1)
+	assert.Empty(t, machines[0].CloudError)
+}
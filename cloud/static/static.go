@@ -0,0 +1,447 @@
+package static
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelda/kelda/cloud/acl"
+	"github.com/kelda/kelda/cloud/cfg"
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/scheduler"
+	"github.com/kelda/kelda/minion/supervisor/images"
+	"github.com/kelda/kelda/minion/vault"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// A Host is a machine in the user's inventory that Kelda may be provisioned
+// onto.
+type Host struct {
+	// The address Kelda connects to over SSH.  It's also used as the
+	// machine's public IP.
+	Address string
+
+	// The address other machines in the cluster use to reach the host.
+	// Defaults to Address.
+	PrivateIP string
+
+	// The user and the path to the private key used to log in to the host.
+	// The user must be able to run `sudo` without a password.
+	User string
+	Key  string
+
+	// An arbitrary label that blueprint machines select hosts by.
+	Size string
+}
+
+// The Provider object represents a pool of static hosts.
+type Provider struct {
+	namespace string
+	hosts     []Host
+}
+
+var c = counter.New("Static")
+
+// The inventory of hosts, relative to the user's home directory.
+var inventoryPath = ".kelda/static_hosts.json"
+
+// The file on each host that records which namespace, if any, has claimed it.
+const claimPath = "/etc/kelda/namespace"
+
+// The iptables chain that implements the ACLs on each host.
+const aclChain = "KELDA-ACL"
+
+// The containers that the minion runs on each host, other than the blueprint's
+// containers.
+var systemContainers = []string{"minion", images.Etcd, images.Ovncontroller,
+	images.Ovnnorthd, images.Ovsdb, images.Ovsvswitchd, images.Registry,
+	vault.ContainerName}
+
+// New creates a new static provider with the hosts listed in
+// ~/.kelda/static_hosts.json.
+func New(namespace string) (*Provider, error) {
+	path := filepath.Join(os.Getenv("HOME"), inventoryPath)
+	inventory, err := util.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []Host
+	if err := json.Unmarshal([]byte(inventory), &hosts); err != nil {
+		return nil, fmt.Errorf("parse %s: %s", path, err)
+	}
+
+	for i, h := range hosts {
+		if h.Address == "" || h.User == "" || h.Key == "" {
+			return nil, fmt.Errorf("host %d in %s must specify an "+
+				"Address, User, and Key", i, path)
+		}
+
+		if h.PrivateIP == "" {
+			hosts[i].PrivateIP = h.Address
+		}
+	}
+
+	return &Provider{namespace: namespace, hosts: hosts}, nil
+}
+
+// List returns the hosts that are claimed by the namespace.
+func (prvdr Provider) List() ([]db.Machine, error) {
+	c.Inc("List")
+
+	claims := prvdr.claims()
+	machines := []db.Machine{}
+	for _, h := range prvdr.hosts {
+		if ns, ok := claims[h.Address]; !ok || ns != prvdr.namespace {
+			continue
+		}
+
+		machines = append(machines, db.Machine{
+			Provider:  db.Static,
+			CloudID:   h.Address,
+			PublicIP:  h.Address,
+			PrivateIP: h.PrivateIP,
+			Size:      h.Size,
+		})
+	}
+	return machines, nil
+}
+
+// Boot claims an idle host of the requested size for each machine in
+// `bootSet`, and provisions Kelda onto it.
+func (prvdr Provider) Boot(bootSet []db.Machine) ([]string, error) {
+	c.Inc("Boot")
+
+	for _, m := range bootSet {
+		if m.Preemptible {
+			return nil, errors.New(
+				"static provider does not support preemptible instances")
+		}
+		if m.Image != "" {
+			return nil, errors.New(
+				"static provider does not support custom images")
+		}
+	}
+
+	claims := prvdr.claims()
+	claimed := map[string]struct{}{}
+	pick := func(size string) (Host, bool) {
+		for _, h := range prvdr.hosts {
+			_, taken := claimed[h.Address]
+			if ns, ok := claims[h.Address]; ok && ns == "" &&
+				!taken && h.Size == size {
+				claimed[h.Address] = struct{}{}
+				return h, true
+			}
+		}
+		return Host{}, false
+	}
+
+	// If any of the bootHost() calls fail, errChan will contain exactly one
+	// error for this function to return.
+	errChan := make(chan error, 1)
+	reportErr := func(err error) {
+		select {
+		case errChan <- err:
+		default:
+		}
+	}
+
+	var ids []string
+	var wg sync.WaitGroup
+	for _, m := range bootSet {
+		h, ok := pick(m.Size)
+		if !ok {
+			reportErr(fmt.Errorf("no idle host of size %q", m.Size))
+			continue
+		}
+
+		wg.Add(1)
+		go func(h Host, m db.Machine) {
+			defer wg.Done()
+			if err := prvdr.bootHost(h, m); err != nil {
+				reportErr(fmt.Errorf("boot %s: %s", h.Address, err))
+			}
+		}(h, m)
+		ids = append(ids, h.Address)
+	}
+	wg.Wait()
+
+	var err error
+	select {
+	case err = <-errChan:
+	default:
+	}
+
+	return ids, err
+}
+
+func (prvdr Provider) bootHost(h Host, m db.Machine) error {
+	// Claim the host before provisioning it, so that the host is listed
+	// while the (slow) boot script runs.
+	// The claim file is created with noclobber, so that only one
+	// namespace's claim succeeds if several claim the host at once.
+	claimCmd := fmt.Sprintf("sh -c 'mkdir -p %s && set -o noclobber && "+
+		"cat > %s'", filepath.Dir(claimPath), claimPath)
+	if _, err := run(h, claimCmd, prvdr.namespace); err != nil {
+		return fmt.Errorf("claim: %s", err)
+	}
+
+	if _, err := run(h, "bash -s", cfg.BootScript(m, "")); err != nil {
+		if _, releaseErr := run(h, "bash -s", teardownScript); releaseErr != nil {
+			log.WithError(releaseErr).WithField("host", h.Address).Warn(
+				"Failed to release host after a failed boot")
+		}
+		return fmt.Errorf("provision: %s", err)
+	}
+	return nil
+}
+
+// Stop tears Kelda down on `machines`, and returns their hosts to the pool.
+func (prvdr Provider) Stop(machines []db.Machine) error {
+	c.Inc("Stop")
+
+	for _, m := range machines {
+		h, ok := prvdr.host(m.CloudID)
+		if !ok {
+			return fmt.Errorf("unknown host: %s", m.CloudID)
+		}
+
+		if _, err := run(h, "bash -s", teardownScript); err != nil {
+			return fmt.Errorf("stop %s: %s", h.Address, err)
+		}
+	}
+	return nil
+}
+
+// SetACLs configures the firewall of each host claimed by the namespace so
+// that it only accepts traffic allowed by `acls`, and traffic from the other
+// machines in the cluster.
+func (prvdr Provider) SetACLs(acls []acl.ACL) error {
+	c.Inc("SetACLs")
+
+	machines, err := prvdr.List()
+	if err != nil {
+		return err
+	}
+
+	script := aclScript(acls, machines)
+	for _, m := range machines {
+		h, _ := prvdr.host(m.CloudID)
+		if _, err := run(h, "bash -s", script); err != nil {
+			return fmt.Errorf("set ACLs on %s: %s", h.Address, err)
+		}
+	}
+	return nil
+}
+
+// UpdateFloatingIPs is not supported.
+func (prvdr *Provider) UpdateFloatingIPs([]db.Machine) error {
+	return errors.New("static provider does not support floating IPs")
+}
+
+// UpdateLabels is a noop because static hosts aren't labeled.
+func (prvdr *Provider) UpdateLabels([]db.Machine) error {
+	return nil
+}
+
+// SetLabels is a noop because static hosts aren't labeled.
+func (prvdr *Provider) SetLabels(map[string]string) error {
+	return nil
+}
+
+// Cleanup is a noop because idle hosts have nothing to clean up.
+func (prvdr *Provider) Cleanup() error {
+	return nil
+}
+
+// claims returns the namespace that each reachable host is claimed by.  Idle
+// hosts map to the empty string, and unreachable hosts are omitted.
+func (prvdr Provider) claims() map[string]string {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	claims := map[string]string{}
+	for _, h := range prvdr.hosts {
+		wg.Add(1)
+		go func(h Host) {
+			defer wg.Done()
+			ns, err := run(h, fmt.Sprintf("cat %s 2>/dev/null || true",
+				claimPath), "")
+			if err != nil {
+				log.WithError(err).WithField("host", h.Address).Debug(
+					"Failed to query static host")
+				return
+			}
+
+			lock.Lock()
+			claims[h.Address] = strings.TrimSpace(ns)
+			lock.Unlock()
+		}(h)
+	}
+	wg.Wait()
+	return claims
+}
+
+func (prvdr Provider) host(address string) (Host, bool) {
+	for _, h := range prvdr.hosts {
+		if h.Address == address {
+			return h, true
+		}
+	}
+	return Host{}, false
+}
+
+// aclScript returns a shell script that replaces the host's ACL chains with ones
+// that implement `acls`, using iptables for IPv4 and ip6tables for IPv6.  SSH is
+// always allowed so that Kelda never loses access to the hosts it manages.  The
+// ip6tables chain is only installed if an ACL or machine uses IPv6, so that
+// hosts in IPv4 clusters keep accepting IPv6 traffic.
+func aclScript(acls []acl.ACL, machines []db.Machine) string {
+	base := []string{
+		"-i lo -j ACCEPT",
+		"-i kelda-int -j ACCEPT",
+		"-m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT",
+		"-p tcp --dport 22 -j ACCEPT",
+	}
+	rules := append([]string{}, base...)
+	// ICMPv6 carries neighbor discovery, without which IPv6 doesn't work.
+	rules6 := append(base, "-p ipv6-icmp -j ACCEPT")
+	ipv6 := false
+
+	for _, m := range machines {
+		for _, ip := range []string{m.PublicIP, m.PrivateIP} {
+			rule := fmt.Sprintf("-s %s -j ACCEPT", ip)
+			if acl.IsIPv6(ip) {
+				rules6 = append(rules6, rule)
+				ipv6 = true
+			} else {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	for _, a := range acls {
+		ports := fmt.Sprintf("%d:%d", a.MinPort, a.MaxPort)
+		var cidrRules []string
+		for _, proto := range a.PortProtocols() {
+			rule := fmt.Sprintf("-s %s -p %s --dport %s -j ACCEPT",
+				a.CidrIP, proto, ports)
+			cidrRules = append(cidrRules, rule)
+		}
+
+		if acl.IsIPv6(a.CidrIP) {
+			rules6 = append(rules6, cidrRules...)
+			ipv6 = true
+		} else {
+			rules = append(rules, cidrRules...)
+			rules = append(rules, fmt.Sprintf("-s %s -p icmp -j ACCEPT",
+				a.CidrIP))
+		}
+	}
+	rules = append(rules, "-j DROP")
+	rules6 = append(rules6, "-j DROP")
+
+	var script bytes.Buffer
+	fmt.Fprintf(&script, "set -e\n")
+	writeChain(&script, "iptables", rules)
+	if ipv6 {
+		writeChain(&script, "ip6tables", rules6)
+	} else {
+		removeChain(&script, "ip6tables")
+	}
+	return script.String()
+}
+
+// writeChain writes the commands that replace the ACL chain of `iptables` with
+// `rules`, and jump to it from the INPUT chain.
+func writeChain(script *bytes.Buffer, iptables string, rules []string) {
+	fmt.Fprintf(script, "%[1]s -N %[2]s 2>/dev/null || %[1]s -F %[2]s\n",
+		iptables, aclChain)
+	for _, rule := range rules {
+		fmt.Fprintf(script, "%s -A %s %s\n", iptables, aclChain, rule)
+	}
+	fmt.Fprintf(script, "%[1]s -C INPUT -j %[2]s 2>/dev/null || "+
+		"%[1]s -I INPUT -j %[2]s\n", iptables, aclChain)
+}
+
+// removeChain writes the commands that remove the ACL chain of `iptables`, if
+// there is one.
+func removeChain(script *bytes.Buffer, iptables string) {
+	fmt.Fprintf(script, "%[1]s -D INPUT -j %[2]s 2>/dev/null || true\n"+
+		"%[1]s -F %[2]s 2>/dev/null || true\n"+
+		"%[1]s -X %[2]s 2>/dev/null || true\n", iptables, aclChain)
+}
+
+// teardownScript stops Kelda, removes its containers and state, and releases
+// the host's claim so that it may be booted again.  Containers that Kelda
+// didn't start are left alone.
+var teardownScript = fmt.Sprintf(`
+systemctl disable --now minion.service ovs.service
+docker ps -aq --filter label=%[3]s | xargs -r docker rm -f
+docker rm -f %[4]s 2>/dev/null || true
+rm -rf /var/lib/etcd /var/lib/kelda /home/kelda/.kelda/tls
+iptables -D INPUT -j %[1]s 2>/dev/null
+iptables -F %[1]s 2>/dev/null
+iptables -X %[1]s 2>/dev/null
+ip6tables -D INPUT -j %[1]s 2>/dev/null
+ip6tables -F %[1]s 2>/dev/null
+ip6tables -X %[1]s 2>/dev/null
+rm -f %[2]s
+`, aclChain, claimPath, scheduler.LabelPair, strings.Join(systemContainers, " "))
+
+// runImpl runs `cmd` as root on `h`, with `stdin` as its standard input, and
+// returns its standard output.
+func runImpl(h Host, cmd, stdin string) (string, error) {
+	key, err := util.ReadFile(h.Key)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(key))
+	if err != nil {
+		return "", fmt.Errorf("parse key: %s", err)
+	}
+
+	sshConfig := &ssh.ClientConfig{
+		User:    h.User,
+		Auth:    []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		Timeout: 5 * time.Second,
+		// XXX: Like the credentials installer, we don't track the host keys
+		// of machines, so we can't check them.
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	client, err := ssh.Dial("tcp", net.JoinHostPort(h.Address, "22"), sshConfig)
+	if err != nil {
+		return "", fmt.Errorf("dial: %s", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("session: %s", err)
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = strings.NewReader(stdin)
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run("sudo " + cmd); err != nil {
+		return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// Stored in a variable so it may be mocked out for unit tests.
+var run = runImpl
@@ -0,0 +1,288 @@
+package static
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/cloud/acl"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+// fakeHosts simulates the claim files and command history of a set of hosts.
+type fakeHosts struct {
+	sync.Mutex
+	claims      map[string]string
+	unreachable map[string]bool
+	failBoot    map[string]bool
+	scripts     map[string][]string
+}
+
+func newFakeHosts() *fakeHosts {
+	return &fakeHosts{
+		claims:      map[string]string{},
+		unreachable: map[string]bool{},
+		failBoot:    map[string]bool{},
+		scripts:     map[string][]string{},
+	}
+}
+
+func (fh *fakeHosts) run(h Host, cmd, stdin string) (string, error) {
+	fh.Lock()
+	defer fh.Unlock()
+
+	if fh.unreachable[h.Address] {
+		return "", errors.New("dial: timeout")
+	}
+
+	switch {
+	case strings.HasPrefix(cmd, "cat "):
+		return fh.claims[h.Address] + "\n", nil
+	case strings.HasPrefix(cmd, "sh -c"):
+		_, claimed := fh.claims[h.Address]
+		if claimed && strings.Contains(cmd, "set -o noclobber") {
+			return "", errors.New("cannot overwrite existing file")
+		}
+		fh.claims[h.Address] = stdin
+	case cmd == "bash -s":
+		fh.scripts[h.Address] = append(fh.scripts[h.Address], stdin)
+		if stdin == teardownScript {
+			delete(fh.claims, h.Address)
+		} else if fh.failBoot[h.Address] {
+			return "", errors.New("exit status 1")
+		}
+	default:
+		return "", errors.New("unexpected command: " + cmd)
+	}
+	return "", nil
+}
+
+func TestNew(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	path := filepath.Join(os.Getenv("HOME"), inventoryPath)
+
+	_, err := New("ns")
+	assert.Error(t, err)
+
+	util.WriteFile(path, []byte(`[
+		{"Address": "8.8.8.8", "User": "ubuntu", "Key": "/key",
+		 "Size": "large"},
+		{"Address": "9.9.9.9", "PrivateIP": "10.1.1.1", "User": "ubuntu",
+		 "Key": "/key"}
+	]`), 0644)
+	prvdr, err := New("ns")
+	assert.NoError(t, err)
+	assert.Equal(t, &Provider{namespace: "ns", hosts: []Host{
+		{Address: "8.8.8.8", PrivateIP: "8.8.8.8", User: "ubuntu", Key: "/key",
+			Size: "large"},
+		{Address: "9.9.9.9", PrivateIP: "10.1.1.1", User: "ubuntu", Key: "/key"},
+	}}, prvdr)
+
+	util.WriteFile(path, []byte(`[{"Address": "8.8.8.8"}]`), 0644)
+	_, err = New("ns")
+	assert.EqualError(t, err, "host 0 in "+path+" must specify an Address, "+
+		"User, and Key")
+
+	util.WriteFile(path, []byte(`{`), 0644)
+	_, err = New("ns")
+	assert.EqualError(t, err, "parse "+path+": unexpected end of JSON input")
+}
+
+func TestBootListStop(t *testing.T) {
+	fh := newFakeHosts()
+	run = fh.run
+	defer func() { run = runImpl }()
+
+	prvdr := Provider{namespace: "ns", hosts: []Host{
+		{Address: "1.1.1.1", PrivateIP: "10.0.0.1", Size: "small"},
+		{Address: "2.2.2.2", PrivateIP: "10.0.0.2", Size: "small"},
+		{Address: "3.3.3.3", PrivateIP: "10.0.0.3", Size: "large"},
+		{Address: "4.4.4.4", PrivateIP: "10.0.0.4", Size: "large"},
+	}}
+
+	// Hosts claimed by other namespaces, or that are unreachable, shouldn't
+	// be listed or booted.
+	fh.claims["1.1.1.1"] = "other"
+	fh.unreachable["3.3.3.3"] = true
+
+	machines, err := prvdr.List()
+	assert.NoError(t, err)
+	assert.Empty(t, machines)
+
+	ids, err := prvdr.Boot([]db.Machine{
+		{Size: "small", Role: db.Master},
+		{Size: "large", Role: db.Worker},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2.2.2.2", "4.4.4.4"}, ids)
+	assert.Equal(t, "ns", fh.claims["2.2.2.2"])
+	assert.Equal(t, "ns", fh.claims["4.4.4.4"])
+	assert.Len(t, fh.scripts["2.2.2.2"], 1)
+	assert.Contains(t, fh.scripts["2.2.2.2"][0], `--role "Master"`)
+	assert.Contains(t, fh.scripts["4.4.4.4"][0], `--role "Worker"`)
+
+	machines, err = prvdr.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []db.Machine{
+		{Provider: db.Static, CloudID: "2.2.2.2", PublicIP: "2.2.2.2",
+			PrivateIP: "10.0.0.2", Size: "small"},
+		{Provider: db.Static, CloudID: "4.4.4.4", PublicIP: "4.4.4.4",
+			PrivateIP: "10.0.0.4", Size: "large"},
+	}, machines)
+
+	// There are no idle hosts left.
+	ids, err = prvdr.Boot([]db.Machine{{Size: "small"}})
+	assert.EqualError(t, err, `no idle host of size "small"`)
+	assert.Empty(t, ids)
+
+	assert.NoError(t, prvdr.Stop([]db.Machine{{CloudID: "2.2.2.2"}}))
+	assert.Equal(t, teardownScript, fh.scripts["2.2.2.2"][1])
+	_, claimed := fh.claims["2.2.2.2"]
+	assert.False(t, claimed)
+
+	// Stopped hosts are returned to the pool.
+	machines, err = prvdr.List()
+	assert.NoError(t, err)
+	assert.Len(t, machines, 1)
+
+	ids, err = prvdr.Boot([]db.Machine{{Size: "small"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2.2.2.2"}, ids)
+
+	err = prvdr.Stop([]db.Machine{{CloudID: "5.5.5.5"}})
+	assert.EqualError(t, err, "unknown host: 5.5.5.5")
+}
+
+func TestClaimRace(t *testing.T) {
+	fh := newFakeHosts()
+	run = fh.run
+	defer func() { run = runImpl }()
+
+	// Another namespace claims the host after it was listed as idle.
+	h := Host{Address: "1.1.1.1"}
+	fh.claims["1.1.1.1"] = "other"
+	prvdr := Provider{namespace: "ns", hosts: []Host{h}}
+	err := prvdr.bootHost(h, db.Machine{})
+	assert.EqualError(t, err, "claim: cannot overwrite existing file")
+	assert.Equal(t, "other", fh.claims["1.1.1.1"])
+	assert.Empty(t, fh.scripts["1.1.1.1"])
+}
+
+func TestTeardownScript(t *testing.T) {
+	// Only the containers that Kelda started are removed.
+	assert.NotContains(t, teardownScript, "docker ps -aq |")
+	assert.Contains(t, teardownScript,
+		"docker ps -aq --filter label=kelda=scheduler | xargs -r docker rm -f\n")
+	assert.Contains(t, teardownScript, "docker rm -f minion etcd "+
+		"ovn-controller ovn-northd ovsdb-server ovs-vswitchd registry vault "+
+		"2>/dev/null || true\n")
+}
+
+func TestBootErrors(t *testing.T) {
+	fh := newFakeHosts()
+	run = fh.run
+	defer func() { run = runImpl }()
+
+	prvdr := Provider{namespace: "ns", hosts: []Host{{Address: "1.1.1.1"}}}
+
+	_, err := prvdr.Boot([]db.Machine{{Preemptible: true}})
+	assert.EqualError(t, err, "static provider does not support preemptible "+
+		"instances")
+
+	_, err = prvdr.Boot([]db.Machine{{Image: "image"}})
+	assert.EqualError(t, err, "static provider does not support custom images")
+
+	// Hosts that fail to provision should be released.
+	fh.failBoot["1.1.1.1"] = true
+	ids, err := prvdr.Boot([]db.Machine{{}})
+	assert.EqualError(t, err, "boot 1.1.1.1: provision: exit status 1")
+	assert.Equal(t, []string{"1.1.1.1"}, ids)
+	assert.Equal(t, teardownScript, fh.scripts["1.1.1.1"][1])
+	_, claimed := fh.claims["1.1.1.1"]
+	assert.False(t, claimed)
+}
+
+func TestSetACLs(t *testing.T) {
+	fh := newFakeHosts()
+	run = fh.run
+	defer func() { run = runImpl }()
+
+	prvdr := Provider{namespace: "ns", hosts: []Host{
+		{Address: "1.1.1.1", PrivateIP: "10.0.0.1"},
+		{Address: "2.2.2.2", PrivateIP: "10.0.0.2"},
+	}}
+	fh.claims["1.1.1.1"] = "ns"
+	fh.claims["2.2.2.2"] = "other"
+
+	err := prvdr.SetACLs([]acl.ACL{
+		{CidrIP: "8.8.8.8/32", MinPort: 80, MaxPort: 81},
+		{CidrIP: "9.9.9.9/32", MinPort: 53, MaxPort: 53, Protocol: "udp"},
+		{CidrIP: "2001:db8::/32", MinPort: 443, MaxPort: 443},
+	})
+	assert.NoError(t, err)
+
+	exp := "set -e\n" +
+		"iptables -N KELDA-ACL 2>/dev/null || iptables -F KELDA-ACL\n" +
+		"iptables -A KELDA-ACL -i lo -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -i kelda-int -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -m conntrack --ctstate ESTABLISHED,RELATED " +
+		"-j ACCEPT\n" +
+		"iptables -A KELDA-ACL -p tcp --dport 22 -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -s 1.1.1.1 -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -s 10.0.0.1 -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -s 8.8.8.8/32 -p tcp --dport 80:81 -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -s 8.8.8.8/32 -p udp --dport 80:81 -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -s 8.8.8.8/32 -p icmp -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -s 9.9.9.9/32 -p udp --dport 53:53 -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -s 9.9.9.9/32 -p icmp -j ACCEPT\n" +
+		"iptables -A KELDA-ACL -j DROP\n" +
+		"iptables -C INPUT -j KELDA-ACL 2>/dev/null || " +
+		"iptables -I INPUT -j KELDA-ACL\n" +
+		"ip6tables -N KELDA-ACL 2>/dev/null || ip6tables -F KELDA-ACL\n" +
+		"ip6tables -A KELDA-ACL -i lo -j ACCEPT\n" +
+		"ip6tables -A KELDA-ACL -i kelda-int -j ACCEPT\n" +
+		"ip6tables -A KELDA-ACL -m conntrack --ctstate ESTABLISHED,RELATED " +
+		"-j ACCEPT\n" +
+		"ip6tables -A KELDA-ACL -p tcp --dport 22 -j ACCEPT\n" +
+		"ip6tables -A KELDA-ACL -p ipv6-icmp -j ACCEPT\n" +
+		"ip6tables -A KELDA-ACL -s 2001:db8::/32 -p tcp --dport 443:443 " +
+		"-j ACCEPT\n" +
+		"ip6tables -A KELDA-ACL -s 2001:db8::/32 -p udp --dport 443:443 " +
+		"-j ACCEPT\n" +
+		"ip6tables -A KELDA-ACL -j DROP\n" +
+		"ip6tables -C INPUT -j KELDA-ACL 2>/dev/null || " +
+		"ip6tables -I INPUT -j KELDA-ACL\n"
+	assert.Equal(t, []string{exp}, fh.scripts["1.1.1.1"])
+
+	// Hosts in other namespaces shouldn't be touched.
+	assert.Empty(t, fh.scripts["2.2.2.2"])
+
+	// Without IPv6 ACLs or machines, the ip6tables chain is removed rather
+	// than installed, so that it doesn't drop the host's IPv6 traffic.
+	fh.scripts = map[string][]string{}
+	err = prvdr.SetACLs([]acl.ACL{{CidrIP: "8.8.8.8/32", MinPort: 80, MaxPort: 80}})
+	assert.NoError(t, err)
+
+	script := fh.scripts["1.1.1.1"][0]
+	assert.NotContains(t, script, "ip6tables -N")
+	assert.NotContains(t, script, "ip6tables -A")
+	assert.Contains(t, script,
+		"ip6tables -D INPUT -j KELDA-ACL 2>/dev/null || true\n"+
+			"ip6tables -F KELDA-ACL 2>/dev/null || true\n"+
+			"ip6tables -X KELDA-ACL 2>/dev/null || true\n")
+
+	// IPv6 machines get the ip6tables chain even without IPv6 ACLs.
+	fh.scripts = map[string][]string{}
+	prvdr.hosts[0].PrivateIP = "fd00::1"
+	err = prvdr.SetACLs([]acl.ACL{{CidrIP: "8.8.8.8/32", MinPort: 80, MaxPort: 80}})
+	assert.NoError(t, err)
+	assert.Contains(t, fh.scripts["1.1.1.1"][0],
+		"ip6tables -A KELDA-ACL -s fd00::1 -j ACCEPT\n")
+}
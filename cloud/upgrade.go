@@ -0,0 +1,200 @@
+package cloud
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/kelda/kelda/cloud/cfg"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/version"
+)
+
+// How long to wait for a restarted minion to reconnect with the new version
+// before restarting it again.
+const upgradeTimeout = 10 * time.Minute
+
+// How long a machine whose minion failed to restart is left alone before it's
+// tried again.  The other machines are upgraded in the meantime.
+const upgradeRetryInterval = 5 * time.Minute
+
+// SyncUpgrades upgrades the minions of the machines marked by `kelda upgrade` to
+// the daemon's version, until `stop` is closed.  Machines are upgraded one at a
+// time, masters first, so that the rest of the cluster keeps running while each
+// minion restarts.  Only the minion is restarted, so the containers it started
+// keep running.
+func SyncUpgrades(conn db.Conn, sshKey ssh.Signer, stop <-chan struct{}) {
+	trigg := conn.TriggerTick(30, db.MachineTable)
+	defer trigg.Stop()
+
+	u := upgrader{conn: conn, sshKey: sshKey}
+	for {
+		select {
+		case <-trigg.C:
+		case <-stop:
+			return
+		}
+		u.runOnce()
+	}
+}
+
+type upgrader struct {
+	conn   db.Conn
+	sshKey ssh.Signer
+
+	// The cloud ID of the machine whose minion was last restarted, and when.
+	current string
+	started time.Time
+
+	// When each machine, keyed by cloud ID, last failed to restart.
+	failed map[string]time.Time
+}
+
+func (u *upgrader) runOnce() {
+	var pending []db.Machine
+	u.conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for _, m := range view.SelectFromMachine(func(m db.Machine) bool {
+			return m.Upgrading
+		}) {
+			upgraded := m.Version == version.Version &&
+				m.Status == db.Connected
+			if upgraded {
+				log.WithField("machine", m).Info("Upgraded minion")
+			}
+
+			if upgraded || m.Status == db.Stopping {
+				m.Upgrading = false
+				view.Commit(m)
+				continue
+			}
+			pending = append(pending, m)
+		}
+		return nil
+	})
+
+	for _, m := range pending {
+		if m.CloudID == u.current && time.Since(u.started) < upgradeTimeout {
+			// Wait for the restarted minion to reconnect.
+			return
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Role != pending[j].Role {
+			return pending[i].Role == db.Master
+		}
+		return pending[i].CloudID < pending[j].CloudID
+	})
+
+	// Forget the failures of machines that are no longer being upgraded.
+	failed := map[string]time.Time{}
+	for _, m := range pending {
+		if t, ok := u.failed[m.CloudID]; ok {
+			failed[m.CloudID] = t
+		}
+	}
+	u.failed = failed
+
+	for _, m := range pending {
+		// Disconnected machines are skipped until they reconnect, and
+		// machines that failed to restart are skipped for a while, so that
+		// they don't hold up the rest of the upgrade.
+		if m.Status != db.Connected || m.PublicIP == "" {
+			continue
+		}
+
+		if failed, ok := u.failed[m.CloudID]; ok &&
+			time.Since(failed) < upgradeRetryInterval {
+			continue
+		}
+
+		c.Inc("Upgrade Minion")
+		log.WithField("machine", m).Infof("Upgrading minion to %s", cfg.Image())
+		if err := restartMinion(m.PublicIP, u.sshKey, cfg.Image()); err != nil {
+			c.Inc("Upgrade Minion Error")
+			log.WithError(err).WithField("machine", m).Warnf(
+				"Failed to upgrade minion. Retrying in %s.",
+				upgradeRetryInterval)
+			u.failed[m.CloudID] = time.Now()
+			continue
+		}
+
+		delete(u.failed, m.CloudID)
+		u.current = m.CloudID
+		u.started = time.Now()
+		return
+	}
+}
+
+// upgradeScript points the services that boot the minion at a new Kelda image,
+// and restarts the minion, which pulls the image.  OVS picks up the new image
+// the next time the machine boots, so that the network stays up.  Local
+// machines don't run systemd; their boot script restarts the minion with the
+// image named in a file instead.
+const upgradeScript = `
+if [ -f /etc/systemd/system/minion.service ]; then
+	for unit in minion ovs; do
+		if [ -f /etc/systemd/system/$unit.service ]; then
+			sed -i 's|%[1]s:[^ ]*|%[2]s|g' /etc/systemd/system/$unit.service
+		fi
+	done
+
+	# Machines booted before the cluster state was snapshotted don't mount the
+	# directory the minion persists its state in.
+	if ! grep -q /var/lib/kelda /etc/systemd/system/minion.service; then
+		mkdir -p /var/lib/kelda
+		sed -i '/^-v \/var\/log\//a -v /var/lib/kelda:/var/lib/kelda:rw \\' \
+			/etc/systemd/system/minion.service
+	fi
+	systemctl daemon-reload
+	systemctl restart minion.service
+elif [ -f /var/lib/kelda/minion-image ]; then
+	docker pull %[2]s
+	echo %[2]s > /var/lib/kelda/minion-image
+	docker rm -f minion
+else
+	echo "The machine doesn't support upgrades. Recreate it instead." >&2
+	exit 1
+fi
+`
+
+// restartMinionImpl restarts the minion on `host` so that it runs `image`.
+func restartMinionImpl(host string, sshKey ssh.Signer, image string) error {
+	sshConfig := &ssh.ClientConfig{
+		User:    "kelda",
+		Auth:    []ssh.AuthMethod{ssh.PublicKeys(sshKey)},
+		Timeout: 5 * time.Second,
+		// XXX: Like the credentials installer, we don't track the host keys
+		// of machines, so we can't check them.
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	client, err := ssh.Dial("tcp", net.JoinHostPort(host, "22"), sshConfig)
+	if err != nil {
+		return fmt.Errorf("dial: %s", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("session: %s", err)
+	}
+	defer session.Close()
+
+	repo := strings.SplitN(image, ":", 2)[0]
+	var stderr bytes.Buffer
+	session.Stdin = strings.NewReader(fmt.Sprintf(upgradeScript, repo, image))
+	session.Stderr = &stderr
+	if err := session.Run("sudo sh -e"); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Stored in a variable so it may be mocked out for unit tests.
+var restartMinion = restartMinionImpl
@@ -0,0 +1,123 @@
+package cloud
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/kelda/kelda/cloud/cfg"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/version"
+)
+
+func TestUpgrade(t *testing.T) {
+	var restarted []string
+	var failHost string
+	restartMinion = func(host string, _ ssh.Signer, image string) error {
+		assert.Equal(t, cfg.Image(), image)
+		restarted = append(restarted, host)
+		if host == failHost {
+			return errors.New("ssh error")
+		}
+		return nil
+	}
+
+	conn := db.New()
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for _, m := range []db.Machine{
+			{CloudID: "worker", Role: db.Worker, PublicIP: "1.1.1.1"},
+			{CloudID: "master", Role: db.Master, PublicIP: "2.2.2.2"},
+			{CloudID: "disconnected", Role: db.Master, PublicIP: "3.3.3.3",
+				Status: db.Reconnecting},
+			{CloudID: "stopping", Role: db.Worker, PublicIP: "4.4.4.4",
+				Status: db.Stopping},
+			{CloudID: "upgraded", Role: db.Worker, PublicIP: "5.5.5.5",
+				Version: version.Version},
+		} {
+			dbm := view.InsertMachine()
+			m.ID = dbm.ID
+			if m.Version == "" {
+				m.Version = "0.1.0"
+			}
+			if m.Status == "" {
+				m.Status = db.Connected
+			}
+			m.Upgrading = true
+			view.Commit(m)
+		}
+		return nil
+	})
+	upgrading := func() []string {
+		var ids []string
+		for _, m := range db.SortMachines(conn.SelectFromMachine(nil)) {
+			if m.Upgrading {
+				ids = append(ids, m.CloudID)
+			}
+		}
+		return ids
+	}
+
+	// The master is restarted first, and the disconnected master is skipped.
+	u := upgrader{conn: conn}
+	u.runOnce()
+	assert.Equal(t, []string{"2.2.2.2"}, restarted)
+	assert.Equal(t, []string{"master", "disconnected", "worker"}, upgrading())
+
+	// Nothing else is restarted until the master reconnects with the new
+	// version.
+	u.runOnce()
+	assert.Equal(t, []string{"2.2.2.2"}, restarted)
+
+	setVersion(conn, "master", version.Version)
+	u.runOnce()
+	assert.Equal(t, []string{"2.2.2.2", "1.1.1.1"}, restarted)
+	assert.Equal(t, []string{"disconnected", "worker"}, upgrading())
+
+	// Minions that don't reconnect in time are restarted again.
+	u.started = time.Now().Add(-upgradeTimeout)
+	u.runOnce()
+	assert.Equal(t, []string{"2.2.2.2", "1.1.1.1", "1.1.1.1"}, restarted)
+
+	// Machines that fail to restart are skipped until the retry interval
+	// passes, so that they don't block the others.
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		m := view.SelectFromMachine(func(m db.Machine) bool {
+			return m.CloudID == "disconnected"
+		})[0]
+		m.Status = db.Connected
+		view.Commit(m)
+		return nil
+	})
+	failHost = "3.3.3.3"
+	u.started = time.Now().Add(-upgradeTimeout)
+	u.runOnce()
+	assert.Equal(t, []string{"2.2.2.2", "1.1.1.1", "1.1.1.1", "3.3.3.3",
+		"1.1.1.1"}, restarted)
+	assert.Equal(t, []string{"disconnected", "worker"}, upgrading())
+
+	restarted = nil
+	failHost = ""
+	setVersion(conn, "worker", version.Version)
+	u.runOnce()
+	assert.Empty(t, restarted)
+	assert.Equal(t, []string{"disconnected"}, upgrading())
+
+	u.failed["disconnected"] = time.Now().Add(-upgradeRetryInterval)
+	u.runOnce()
+	assert.Equal(t, []string{"3.3.3.3"}, restarted)
+	assert.Empty(t, u.failed)
+}
+
+func setVersion(conn db.Conn, cloudID, version string) {
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		m := view.SelectFromMachine(func(m db.Machine) bool {
+			return m.CloudID == cloudID
+		})[0]
+		m.Version = version
+		view.Commit(m)
+		return nil
+	})
+}
@@ -33,6 +33,10 @@ func (prvdr Provider) Boot(bootSet []db.Machine) ([]string, error) {
 			return nil, errors.New(
 				"vagrant does not support preemptible instances")
 		}
+		if m.Image != "" {
+			return nil, errors.New(
+				"vagrant does not support custom images")
+		}
 	}
 
 	// If any of the boot.Machine() calls fail, errChan will contain exactly one
@@ -67,7 +71,7 @@ func (prvdr Provider) Boot(bootSet []db.Machine) ([]string, error) {
 }
 
 func bootMachine(id string, m db.Machine) error {
-	err := initMachine(cfg.Ubuntu(m, inboundPublicInterface), m.Size, id)
+	err := initMachine(cfg.BootScript(m, inboundPublicInterface), m.Size, id)
 	if err == nil {
 		err = up(id)
 	}
@@ -133,6 +137,16 @@ func (prvdr *Provider) UpdateFloatingIPs([]db.Machine) error {
 	return errors.New("vagrant provider does not support floating IPs")
 }
 
+// UpdateLabels is a noop because Vagrant machines aren't labeled.
+func (prvdr *Provider) UpdateLabels([]db.Machine) error {
+	return nil
+}
+
+// SetLabels is a noop because Vagrant machines aren't labeled.
+func (prvdr *Provider) SetLabels(map[string]string) error {
+	return nil
+}
+
 // Cleanup removes unnecessary detritus from this provider.  It's intended to be called
 // when there are no VMs running or expected to be running soon.
 func (prvdr *Provider) Cleanup() error {
@@ -17,3 +17,9 @@ func TestPreemptibleError(t *testing.T) {
 	assert.EqualError(t, err, "vagrant does not support preemptible instances")
 	assert.Nil(t, ids)
 }
+
+func TestImageError(t *testing.T) {
+	ids, err := Provider{}.Boot([]db.Machine{{Image: "box"}})
+	assert.EqualError(t, err, "vagrant does not support custom images")
+	assert.Nil(t, ids)
+}
@@ -3,6 +3,9 @@
 package io
 
 import (
+	goTLS "crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -21,6 +24,8 @@ const (
 	caKeyFilename      = "certificate_authority.key"
 	signedCertFilename = "kelda.crt"
 	signedKeyFilename  = "kelda.key"
+	etcdCertFilename   = "etcd.crt"
+	etcdKeyFilename    = "etcd.key"
 )
 
 // File represents a file to be written to the filesystem.
@@ -65,6 +70,41 @@ func ReadCA(dir string) (rsa.KeyPair, error) {
 	return rsa.New(caCert, caKey)
 }
 
+// ReadEtcdConfig reads the etcd certificate and the certificate authority
+// contained within the directory, and returns a TLS configuration for
+// connecting to etcd.
+func ReadEtcdConfig(dir string) (*goTLS.Config, error) {
+	caCert, err := util.ReadFile(CACertPath(dir))
+	if err != nil {
+		return nil, fmt.Errorf("read CA: %s", err)
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM([]byte(caCert)) {
+		return nil, errors.New("malformed CA")
+	}
+
+	etcdCert, err := util.ReadFile(EtcdCertPath(dir))
+	if err != nil {
+		return nil, fmt.Errorf("read etcd cert: %s", err)
+	}
+
+	etcdKey, err := util.ReadFile(EtcdKeyPath(dir))
+	if err != nil {
+		return nil, fmt.Errorf("read etcd key: %s", err)
+	}
+
+	keyPair, err := goTLS.X509KeyPair([]byte(etcdCert), []byte(etcdKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse etcd cert: %s", err)
+	}
+
+	return &goTLS.Config{
+		Certificates: []goTLS.Certificate{keyPair},
+		RootCAs:      caPool,
+	}, nil
+}
+
 // MinionFiles defines how files should be written to disk for installation on
 // minions.
 func MinionFiles(dir string, ca, signed rsa.KeyPair) []File {
@@ -76,6 +116,15 @@ func MinionFiles(dir string, ca, signed rsa.KeyPair) []File {
 	}
 }
 
+// EtcdFiles defines how the certificate that etcd uses for both its peer and
+// client traffic should be written to disk on minions.
+func EtcdFiles(dir string, etcd rsa.KeyPair) []File {
+	return []File{
+		{Path: EtcdCertPath(dir), Content: etcd.CertString(), Mode: 0644},
+		{Path: EtcdKeyPath(dir), Content: etcd.PrivateKeyString(), Mode: 0600},
+	}
+}
+
 // DaemonFiles defines how files should be written to disk for use by the daemon.
 func DaemonFiles(dir string, ca, signed rsa.KeyPair) []File {
 	return append(MinionFiles(dir, ca, signed),
@@ -101,3 +150,13 @@ func SignedCertPath(dir string) string {
 func SignedKeyPath(dir string) string {
 	return filepath.Join(dir, signedKeyFilename)
 }
+
+// EtcdCertPath defines where to write the certificate used by etcd.
+func EtcdCertPath(dir string) string {
+	return filepath.Join(dir, etcdCertFilename)
+}
+
+// EtcdKeyPath defines where to write the private key used by etcd.
+func EtcdKeyPath(dir string) string {
+	return filepath.Join(dir, etcdKeyFilename)
+}
@@ -1,6 +1,7 @@
 package io
 
 import (
+	"net"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -127,3 +128,29 @@ func setupFilesystem(files []File) {
 		util.WriteFile(f.Path, []byte(f.Content), f.Mode)
 	}
 }
+
+func TestWriteAndReadEtcdCerts(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	ca, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	etcd, err := rsa.NewSigned(ca, net.ParseIP("127.0.0.1"))
+	assert.NoError(t, err)
+
+	testDir := "/tls"
+	util.Mkdir(testDir, 0755)
+
+	_, err = ReadEtcdConfig(testDir)
+	assert.Error(t, err)
+
+	util.WriteFile(CACertPath(testDir), []byte(ca.CertString()), 0644)
+	for _, f := range EtcdFiles(testDir, etcd) {
+		util.WriteFile(f.Path, []byte(f.Content), f.Mode)
+	}
+
+	config, err := ReadEtcdConfig(testDir)
+	assert.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+	assert.NotNil(t, config.RootCAs)
+}
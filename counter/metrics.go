@@ -0,0 +1,308 @@
+package counter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelda/kelda/api/pb"
+	"golang.org/x/sync/syncmap"
+)
+
+// LatencyBuckets are the upper bounds, in seconds, of the buckets used by every
+// histogram.  They're chosen to span everything from a database transaction
+// to a cloud provider booting a batch of machines.
+var LatencyBuckets = []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 30, 60, 300}
+
+type gauge struct {
+	sync.Mutex
+	label  string
+	values map[string]float64
+}
+
+type histogram struct {
+	sync.Mutex
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+var gauges = syncmap.Map{}
+var histograms = syncmap.Map{}
+
+type key struct{ p, n string }
+
+// SetGauge sets the gauge `name` under the provided package to `value`.
+func (p Package) SetGauge(name string, value float64) {
+	p.SetLabeledGauge(name, "", map[string]float64{"": value})
+}
+
+// SetLabeledGauge replaces the values of the gauge `name` under the provided
+// package.  `values` maps the value of `label` to the value of the gauge for
+// that label, so label values that are missing from `values` are dropped from
+// the gauge.
+func (p Package) SetLabeledGauge(name, label string, values map[string]float64) {
+	cpy := map[string]float64{}
+	for l, v := range values {
+		cpy[l] = v
+	}
+
+	g, _ := gauges.LoadOrStore(key{p.name, name}, &gauge{})
+	g.(*gauge).Lock()
+	g.(*gauge).label = label
+	g.(*gauge).values = cpy
+	g.(*gauge).Unlock()
+}
+
+// Observe records `d` in the latency histogram `name` under the provided
+// package.
+func (p Package) Observe(name string, d time.Duration) {
+	h, _ := histograms.LoadOrStore(key{p.name, name},
+		&histogram{buckets: make([]uint64, len(LatencyBuckets))})
+
+	hist := h.(*histogram)
+	seconds := d.Seconds()
+
+	hist.Lock()
+	defer hist.Unlock()
+	for i, bound := range LatencyBuckets {
+		if seconds <= bound {
+			hist.buckets[i]++
+		}
+	}
+	hist.count++
+	hist.sum += seconds
+}
+
+// ObserveSince records the time elapsed since `start` in the latency histogram
+// `name` under the provided package.
+func (p Package) ObserveSince(name string, start time.Time) {
+	p.Observe(name, time.Since(start))
+}
+
+// Handler returns an http.Handler that serves all counters, gauges, and
+// histograms in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WritePrometheus(w)
+	})
+}
+
+// ServeMetrics serves Handler at `/metrics` on the given TCP address.  It only
+// returns if the server fails.
+func ServeMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return http.ListenAndServe(addr, mux)
+}
+
+// A series is the value of one counter, gauge, or histogram.
+type series struct {
+	key
+	typ string
+
+	// The suffix of the metric name that denotes the series' type.
+	suffix string
+
+	// lines returns the sample lines of the series when exported as `name`.
+	lines func(name string) []string
+}
+
+// WritePrometheus writes all counters, gauges, and histograms to `w` in the
+// Prometheus text exposition format.  Unlike Dump, it doesn't affect the
+// PrevValue of the counters.
+func WritePrometheus(w io.Writer) error {
+	var all []series
+	for _, s := range []func() []series{counterSeries, gaugeSeries,
+		histogramSeries} {
+		all = append(all, s()...)
+	}
+
+	// Order the series deterministically so that series whose names collide
+	// get the same suffixes on every scrape.
+	sort.Slice(all, func(i, j int) bool {
+		l, r := all[i], all[j]
+		switch {
+		case l.p != r.p:
+			return l.p < r.p
+		case l.n != r.n:
+			return l.n < r.n
+		default:
+			return l.typ < r.typ
+		}
+	})
+
+	var names []string
+	metrics := map[string]series{}
+	for _, s := range all {
+		name := uniqueName(metrics, metricName(s.p, s.n), s.suffix)
+		metrics[name] = s
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := metrics[name]
+		_, err := fmt.Fprintf(w, "# TYPE %s %s\n%s\n", name, s.typ,
+			strings.Join(s.lines(name), "\n"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueName returns `base` followed by `suffix`.  Package and counter names that
+// only differ in punctuation sanitize to the same metric name, so if the name is
+// already in `taken`, a number is added to `base` to keep the series apart.
+func uniqueName(taken map[string]series, base, suffix string) string {
+	name := base + suffix
+	for i := 2; ; i++ {
+		if _, ok := taken[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d%s", base, i, suffix)
+	}
+}
+
+func counterSeries() []series {
+	var res []series
+	all.Range(func(_, value interface{}) bool {
+		counter := value.(*pb.Counter)
+		val := atomic.LoadUint64(&counter.Value)
+		res = append(res, series{
+			key:    key{counter.Pkg, counter.Name},
+			typ:    "counter",
+			suffix: "_total",
+			lines: func(name string) []string {
+				return []string{fmt.Sprintf("%s %d", name, val)}
+			},
+		})
+		return true
+	})
+	return res
+}
+
+func gaugeSeries() []series {
+	var res []series
+	gauges.Range(func(k, value interface{}) bool {
+		g := value.(*gauge)
+
+		g.Lock()
+		label := g.label
+		values := map[string]float64{}
+		for l, v := range g.values {
+			values[l] = v
+		}
+		g.Unlock()
+
+		res = append(res, series{
+			key: k.(key),
+			typ: "gauge",
+			lines: func(name string) []string {
+				var lines []string
+				for l, v := range values {
+					labels := ""
+					if label != "" {
+						labels = formatLabels(sanitize(label), l)
+					}
+					lines = append(lines, fmt.Sprintf("%s%s %s", name,
+						labels, formatFloat(v)))
+				}
+				sort.Strings(lines)
+				return lines
+			},
+		})
+		return true
+	})
+	return res
+}
+
+func histogramSeries() []series {
+	var res []series
+	histograms.Range(func(k, value interface{}) bool {
+		h := value.(*histogram)
+
+		h.Lock()
+		buckets := append([]uint64{}, h.buckets...)
+		count, sum := h.count, h.sum
+		h.Unlock()
+
+		res = append(res, series{
+			key:    k.(key),
+			typ:    "histogram",
+			suffix: "_seconds",
+			lines: func(name string) []string {
+				return histogramLines(name, buckets, count, sum)
+			},
+		})
+		return true
+	})
+	return res
+}
+
+func histogramLines(name string, buckets []uint64, count uint64,
+	sum float64) []string {
+
+	var lines []string
+	for i, bound := range LatencyBuckets {
+		lines = append(lines, fmt.Sprintf("%s_bucket%s %d", name,
+			formatLabels("le", formatFloat(bound)), buckets[i]))
+	}
+	return append(lines,
+		fmt.Sprintf("%s_bucket%s %d", name, formatLabels("le", "+Inf"), count),
+		fmt.Sprintf("%s_sum %s", name, formatFloat(sum)),
+		fmt.Sprintf("%s_count %d", name, count))
+}
+
+// metricName converts the human readable package and counter names into a
+// valid Prometheus metric name.  For example, "Database Commit" and
+// "db.Machine" become "kelda_database_commit_db_machine".
+func metricName(pkg, name string) string {
+	parts := []string{"kelda"}
+	for _, part := range []string{sanitize(pkg), sanitize(name)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// sanitize lowercases `s` and collapses every run of characters that aren't
+// allowed in Prometheus names into a single underscore.
+func sanitize(s string) string {
+	var sanitized []rune
+	lastUnderscore := true
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sanitized = append(sanitized, r)
+			lastUnderscore = false
+		} else if !lastUnderscore {
+			sanitized = append(sanitized, '_')
+			lastUnderscore = true
+		}
+	}
+	return strings.TrimSuffix(string(sanitized), "_")
+}
+
+func formatLabels(label, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+	return fmt.Sprintf(`{%s="%s"}`, label, value)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return fmt.Sprintf("%g", f)
+}
@@ -0,0 +1,137 @@
+package counter
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/syncmap"
+)
+
+func TestGauge(t *testing.T) {
+	resetMetrics()
+
+	p := New("Cluster")
+	p.SetGauge("Leader", 1)
+	p.SetLabeledGauge("Machines", "Status", map[string]float64{
+		"connected": 2,
+		"booting":   1,
+	})
+
+	exp := `# TYPE kelda_cluster_leader gauge
+kelda_cluster_leader 1
+# TYPE kelda_cluster_machines gauge
+kelda_cluster_machines{status="booting"} 1
+kelda_cluster_machines{status="connected"} 2
+`
+	assert.Equal(t, exp, writeString())
+
+	// Label values missing from the new values should be dropped.
+	p.SetLabeledGauge("Machines", "Status", map[string]float64{"connected": 3})
+	exp = `# TYPE kelda_cluster_leader gauge
+kelda_cluster_leader 1
+# TYPE kelda_cluster_machines gauge
+kelda_cluster_machines{status="connected"} 3
+`
+	assert.Equal(t, exp, writeString())
+}
+
+func TestHistogram(t *testing.T) {
+	resetMetrics()
+
+	p := New("Cloud")
+	p.Observe("Boot", 3*time.Millisecond)
+	p.Observe("Boot", 2*time.Second)
+	p.Observe("Boot", time.Hour)
+
+	exp := `# TYPE kelda_cloud_boot_seconds histogram
+kelda_cloud_boot_seconds_bucket{le="0.001"} 0
+kelda_cloud_boot_seconds_bucket{le="0.005"} 1
+kelda_cloud_boot_seconds_bucket{le="0.01"} 1
+kelda_cloud_boot_seconds_bucket{le="0.05"} 1
+kelda_cloud_boot_seconds_bucket{le="0.1"} 1
+kelda_cloud_boot_seconds_bucket{le="0.5"} 1
+kelda_cloud_boot_seconds_bucket{le="1"} 1
+kelda_cloud_boot_seconds_bucket{le="5"} 2
+kelda_cloud_boot_seconds_bucket{le="10"} 2
+kelda_cloud_boot_seconds_bucket{le="30"} 2
+kelda_cloud_boot_seconds_bucket{le="60"} 2
+kelda_cloud_boot_seconds_bucket{le="300"} 2
+kelda_cloud_boot_seconds_bucket{le="+Inf"} 3
+kelda_cloud_boot_seconds_sum 3602.003
+kelda_cloud_boot_seconds_count 3
+`
+	assert.Equal(t, exp, writeString())
+}
+
+func TestPrometheusCounters(t *testing.T) {
+	resetMetrics()
+
+	p := New("Database Commit")
+	p.Inc("db.Machine")
+	p.Inc("db.Machine")
+	New("Docker").Inc("Is Running?")
+
+	// Dump shouldn't affect the values exported to Prometheus.
+	Dump()
+
+	exp := `# TYPE kelda_database_commit_db_machine_total counter
+kelda_database_commit_db_machine_total 2
+# TYPE kelda_docker_is_running_total counter
+kelda_docker_is_running_total 1
+`
+	assert.Equal(t, exp, writeString())
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, exp, recorder.Body.String())
+}
+
+func TestPrometheusNameCollision(t *testing.T) {
+	resetMetrics()
+
+	// Both counters sanitize to kelda_docker_is_running.
+	New("Docker").Inc("Is Running?")
+	New("Docker").Inc("Is Running?")
+	New("Docker").Inc("Is-Running")
+	New("Docker").SetGauge("Is Running", 1)
+
+	exp := `# TYPE kelda_docker_is_running gauge
+kelda_docker_is_running 1
+# TYPE kelda_docker_is_running_2_total counter
+kelda_docker_is_running_2_total 1
+# TYPE kelda_docker_is_running_total counter
+kelda_docker_is_running_total 2
+`
+	assert.Equal(t, exp, writeString())
+
+	// The suffixes don't change from one scrape to the next.
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, exp, writeString())
+	}
+}
+
+func TestMetricName(t *testing.T) {
+	assert.Equal(t, "kelda_minion", metricName("Minion", ""))
+	assert.Equal(t, "kelda_foreman_new_minion_client",
+		metricName("Foreman", "New Minion Client"))
+	assert.Equal(t, "kelda_cloud_amazon_update_floating_ips",
+		metricName("Cloud", "Amazon Update Floating IPs"))
+	assert.Equal(t, "kelda_docker_is_running",
+		metricName("Docker", "--Is Running?"))
+}
+
+func writeString() string {
+	var buf bytes.Buffer
+	WritePrometheus(&buf)
+	return buf.String()
+}
+
+func resetMetrics() {
+	all = syncmap.Map{}
+	gauges = syncmap.Map{}
+	histograms = syncmap.Map{}
+}
@@ -1,8 +1,9 @@
 package db
 
 import (
-	"errors"
+	"fmt"
 	"log"
+	"sort"
 
 	"github.com/kelda/kelda/blueprint"
 )
@@ -42,37 +43,31 @@ func (conn Conn) SelectFromBlueprint(check func(Blueprint) bool) []Blueprint {
 	return blueprints
 }
 
-// GetBlueprint gets the blueprint from the database. There should only ever be a single
-// blueprint.
-func (db Database) GetBlueprint() (Blueprint, error) {
-	blueprints := db.SelectFromBlueprint(nil)
+// GetBlueprint gets the blueprint of the deployment in `namespace` from the
+// database.  There should only ever be a single blueprint per namespace.
+func (db Database) GetBlueprint(namespace string) (Blueprint, error) {
+	blueprints := db.SelectFromBlueprint(func(bp Blueprint) bool {
+		return bp.Namespace == namespace
+	})
 	numBlueprints := len(blueprints)
 	if numBlueprints == 1 {
 		return blueprints[0], nil
 	} else if numBlueprints > 1 {
-		log.Panicf("Found %d blueprints, there should be 1", numBlueprints)
+		log.Panicf("Found %d blueprints in namespace %s, there should be 1",
+			numBlueprints, namespace)
 	}
-	return Blueprint{}, errors.New("no blueprints found")
+	return Blueprint{}, fmt.Errorf("no blueprint in namespace %q", namespace)
 }
 
-// GetBlueprintNamespace returns the namespace of the single blueprint object in the
-// blueprint table.  Otherwise it returns an error.
-func (db Database) GetBlueprintNamespace() (string, error) {
-	clst, err := db.GetBlueprint()
-	if err != nil {
-		return "", err
+// GetBlueprintNamespaces returns the sorted namespaces of the blueprints in the
+// blueprint table.
+func (conn Conn) GetBlueprintNamespaces() []string {
+	var namespaces []string
+	for _, bp := range conn.SelectFromBlueprint(nil) {
+		namespaces = append(namespaces, bp.Namespace)
 	}
-	return clst.Namespace, nil
-}
-
-// GetBlueprintNamespace returns the namespace of the single blueprint object in the
-// blueprint table.  Otherwise it returns an error.
-func (conn Conn) GetBlueprintNamespace() (namespace string, err error) {
-	conn.Txn(BlueprintTable).Run(func(db Database) error {
-		namespace, err = db.GetBlueprintNamespace()
-		return nil
-	})
-	return
+	sort.Strings(namespaces)
+	return namespaces
 }
 
 func (b Blueprint) getID() int {
@@ -90,3 +85,26 @@ func (b Blueprint) String() string {
 func (b Blueprint) less(r row) bool {
 	return b.ID < r.(Blueprint).ID
 }
+
+// BlueprintSlice is an alias for []Blueprint to allow for joins
+type BlueprintSlice []Blueprint
+
+// Get returns the value contained at the given index
+func (bs BlueprintSlice) Get(i int) interface{} {
+	return bs[i]
+}
+
+// Len returns the number of items in the slice
+func (bs BlueprintSlice) Len() int {
+	return len(bs)
+}
+
+// Less implements less than for sort.Interface.
+func (bs BlueprintSlice) Less(i, j int) bool {
+	return bs[i].less(bs[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (bs BlueprintSlice) Swap(i, j int) {
+	bs[i], bs[j] = bs[j], bs[i]
+}
@@ -9,23 +9,36 @@ import (
 func TestBlueprint(t *testing.T) {
 	conn := New()
 
-	ns, err := conn.GetBlueprintNamespace()
-	assert.NotNil(t, err)
-	assert.Exactly(t, ns, "")
+	assert.Empty(t, conn.GetBlueprintNamespaces())
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		_, err := view.GetBlueprint("test")
+		assert.EqualError(t, err, `no blueprint in namespace "test"`)
+		return nil
+	})
 
 	conn.Txn(AllTables...).Run(func(view Database) error {
 		bp := view.InsertBlueprint()
 		bp.Namespace = "test"
 		view.Commit(bp)
+
+		bp = view.InsertBlueprint()
+		bp.Namespace = "other"
+		view.Commit(bp)
 		return nil
 	})
 
-	ns, err = conn.GetBlueprintNamespace()
-	assert.NoError(t, err)
-	assert.Exactly(t, ns, "test")
+	assert.Equal(t, []string{"other", "test"}, conn.GetBlueprintNamespaces())
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		bp, err := view.GetBlueprint("test")
+		assert.NoError(t, err)
+		assert.Equal(t, "test", bp.Namespace)
+		return nil
+	})
 
-	bps := conn.SelectFromBlueprint(nil)
-	assert.Len(t, bps, 1)
+	assert.Len(t, conn.SelectFromBlueprint(nil), 2)
+	bps := conn.SelectFromBlueprint(func(bp Blueprint) bool {
+		return bp.Namespace == "test"
+	})
 
 	assert.Equal(t, BlueprintTable, bps[0].tt())
 	assert.True(t, bps[0].less(Blueprint{ID: bps[0].ID + 1}))
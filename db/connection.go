@@ -3,18 +3,21 @@ package db
 import (
 	"fmt"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/util/str"
 )
 
 // A Connection allows two hostnames to speak to each other on the port
-// range [MinPort, MaxPort] inclusive.
+// range [MinPort, MaxPort] inclusive, using Protocol.  Connections without a
+// protocol allow both TCP and UDP.
 type Connection struct {
 	ID int `json:"-"`
 
-	From    []string
-	To      []string
-	MinPort int
-	MaxPort int
+	From     []string
+	To       []string
+	MinPort  int
+	MaxPort  int
+	Protocol string `json:",omitempty"`
 }
 
 // InsertConnection creates a new connection row and inserts it into the database.
@@ -52,12 +55,8 @@ func (conn Conn) SelectFromConnection(check func(Connection) bool) []Connection
 }
 
 func (c Connection) String() string {
-	port := fmt.Sprintf("%d", c.MinPort)
-	if c.MaxPort != c.MinPort {
-		port += fmt.Sprintf("-%d", c.MaxPort)
-	}
-
-	return fmt.Sprintf("Connection-%d{%s->%s:%s}", c.ID, c.From, c.To, port)
+	return fmt.Sprintf("Connection-%d{%s->%s:%s}", c.ID, c.From, c.To,
+		blueprint.PortsString(c.MinPort, c.MaxPort, c.Protocol))
 }
 
 func (c Connection) less(r row) bool {
@@ -74,6 +73,8 @@ func (c Connection) less(r row) bool {
 		return c.MaxPort < o.MaxPort
 	case c.MinPort != o.MinPort:
 		return c.MinPort < o.MinPort
+	case c.Protocol != o.Protocol:
+		return c.Protocol < o.Protocol
 	default:
 		return c.ID < o.ID
 	}
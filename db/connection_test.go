@@ -30,7 +30,11 @@ func TestConnection(t *testing.T) {
 
 	connection.MaxPort = 3
 	assert.Equal(t, "Connection-1{[foo]->[]:0-3}", connection.String())
-	connection.MaxPort = 0
+	connection.Protocol = "udp"
+	assert.Equal(t, "Connection-1{[foo]->[]:0-3/udp}", connection.String())
+	connection.MaxPort, connection.Protocol = 0, "icmp"
+	assert.Equal(t, "Connection-1{[foo]->[]:icmp}", connection.String())
+	connection.Protocol = ""
 
 	assert.Equal(t, connection, connections.Get(0))
 
@@ -39,6 +43,8 @@ func TestConnection(t *testing.T) {
 		To: []string{"a"}}))
 	assert.True(t, connection.less(Connection{From: []string{"foo"}, MaxPort: 1}))
 	assert.True(t, connection.less(Connection{From: []string{"foo"}, MinPort: 100}))
+	assert.True(t, connection.less(Connection{From: []string{"foo"},
+		Protocol: "tcp"}))
 	assert.True(t, connection.less(Connection{From: []string{"foo"}, ID: id + 1}))
 
 	assert.True(t, connection.less(Connection{From: []string{"foo", "bar"}}))
@@ -3,6 +3,7 @@ package db
 //The Role within the cluster each machine assumes.
 import (
 	"errors"
+	"fmt"
 
 	"github.com/kelda/kelda/minion/pb"
 )
@@ -63,8 +64,17 @@ const (
 	// DigitalOcean implements Digital Ocean Droplets.
 	DigitalOcean ProviderName = "DigitalOcean"
 
+	// OpenStack implements OpenStack Nova instances.
+	OpenStack ProviderName = "OpenStack"
+
 	// Vagrant implements local virtual machines.
 	Vagrant ProviderName = "Vagrant"
+
+	// Local implements machines as containers on the local Docker daemon.
+	Local ProviderName = "Local"
+
+	// Static implements a user-supplied pool of existing machines.
+	Static ProviderName = "Static"
 )
 
 // AllProviders lists all of the providers that Kelda supports.
@@ -72,7 +82,37 @@ var AllProviders = []ProviderName{
 	Amazon,
 	Google,
 	DigitalOcean,
+	OpenStack,
 	Vagrant,
+	Local,
+	Static,
+}
+
+// OS describes the operating system of a machine's image.  Kelda generates a
+// different boot script for each.
+type OS string
+
+const (
+	// Ubuntu is Ubuntu 16.04, the OS of the providers' default images.
+	Ubuntu OS = "Ubuntu"
+
+	// Debian is Debian 9.
+	Debian OS = "Debian"
+
+	// Prebaked is an Ubuntu or Debian image that already has Docker
+	// installed, so booting it skips the installation.
+	Prebaked OS = "Prebaked"
+)
+
+// ParseOS returns the OS represented by the string `os`, or an error.  The
+// empty string represents the default, Ubuntu.
+func ParseOS(os string) (OS, error) {
+	switch OS(os) {
+	case "", Ubuntu, Debian, Prebaked:
+		return OS(os), nil
+	default:
+		return "", fmt.Errorf("unknown OS: %s", os)
+	}
 }
 
 // ParseRole returns the Role represented by the string 'role', or an error.
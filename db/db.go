@@ -83,6 +83,8 @@ func (cn Conn) Txn(tables ...TableType) Transaction {
 // database without conflicting with other transactions.
 func (tr Transaction) Run(do func(db Database) error) error {
 	c.Inc("Transact")
+	defer c.ObserveSince("Transact", time.Now())
+
 	tr.lockTables()
 	defer tr.unlockTables()
 
@@ -464,6 +464,19 @@ func TestRole(t *testing.T) {
 	assert.Equal(t, Role(None), r)
 }
 
+func TestParseOS(t *testing.T) {
+	t.Parallel()
+
+	for _, exp := range []OS{"", Ubuntu, Debian, Prebaked} {
+		os, err := ParseOS(string(exp))
+		assert.NoError(t, err)
+		assert.Equal(t, exp, os)
+	}
+
+	_, err := ParseOS("Windows")
+	assert.EqualError(t, err, "unknown OS: Windows")
+}
+
 func TestRowSlice(t *testing.T) {
 	t.Parallel()
 
@@ -13,6 +13,8 @@ type Etcd struct {
 
 	Leader   bool   // True if this Minion is the leader.
 	LeaderIP string // IP address of the current leader, or ""
+
+	TLS bool // True once the cluster encrypts etcd traffic with TLS.
 }
 
 func (e Etcd) String() string {
@@ -35,7 +35,8 @@ func TestEtcd(t *testing.T) {
 	assert.Equal(t, "foo", etcd.LeaderIP)
 	assert.Equal(t, id, etcd.getID())
 
-	assert.Equal(t, "Etcd-1{EtcdIPs=[], Leader=false, LeaderIP=foo}", etcd.String())
+	assert.Equal(t, "Etcd-1{EtcdIPs=[], Leader=false, LeaderIP=foo, TLS=false}",
+		etcd.String())
 
 	assert.True(t, etcd.less(Etcd{ID: id + 1}))
 
@@ -11,6 +11,9 @@ import (
 type Machine struct {
 	ID int //Database ID
 
+	// The namespace of the deployment that the machine belongs to.
+	Namespace string
+
 	Role        Role
 	Provider    ProviderName
 	Region      string
@@ -20,6 +23,25 @@ type Machine struct {
 	FloatingIP  string
 	Preemptible bool
 
+	// The most, in US dollars per hour, that the machine may cost if it's
+	// preemptible, or zero for the provider's default.
+	MaxPrice float64
+
+	// The provider-specific image that the machine boots, or the empty string
+	// for the provider's default Ubuntu image.
+	Image string
+
+	// The operating system of `Image`, which determines the boot script.
+	OS OS
+
+	// User-defined labels that the cloud provider applies to the machine and
+	// its disk, e.g. as AWS tags.
+	Labels map[string]string
+
+	// The namespace's container subnet, or the empty string for the default.
+	// The machine's Docker daemon trusts registries in it without TLS.
+	ContainerSubnet string `json:"-" rowStringer:"omit"`
+
 	/* Populated by the cloud provider. */
 	CloudID   string //Cloud Provider ID
 	PublicIP  string
@@ -28,10 +50,34 @@ type Machine struct {
 	/* Populated by the cluster. */
 	Status string
 
+	// The errors from the cloud provider's API, if its calls for the machine's
+	// region keep failing even after being retried.
+	CloudError string
+
 	// The public key that has been installed on this machine.
 	PublicKey string
+
+	// Set by `kelda cordon` and `kelda drain` to keep new containers from
+	// being scheduled on the machine.
+	Unschedulable bool
+
+	// Set by `kelda drain` to move the machine's containers to other
+	// workers.
+	Draining bool
+
+	// The Kelda version that the machine's minion runs, as reported to the
+	// foreman.
+	Version string
+
+	// Set by `kelda upgrade` until the machine's minion runs the daemon's
+	// version.
+	Upgrading bool
 }
 
+// DefaultDiskSize is the size in GB of the disks of machines that don't specify
+// one.
+const DefaultDiskSize = 32
+
 const (
 	// Stopping represents a machine that is being stopped by a cloud provider.
 	Stopping = "stopping"
@@ -50,6 +96,14 @@ const (
 	// Connected represents that we are currently connected to the machine's
 	// minion.
 	Connected = "connected"
+
+	// Terminating represents that the cloud provider has announced that it
+	// will reclaim the machine, e.g. because a spot instance was outbid.
+	Terminating = "terminating"
+
+	// Draining represents that the machine's containers are being moved to
+	// other workers before the machine is stopped.
+	Draining = "draining"
 )
 
 // InsertMachine creates a new Machine and inserts it into 'db'.
@@ -101,6 +155,10 @@ func (m Machine) String() string {
 		tags = append(tags, m.CloudID)
 	}
 
+	if m.Namespace != "" {
+		tags = append(tags, "Namespace="+m.Namespace)
+	}
+
 	if m.PublicIP != "" {
 		tags = append(tags, "PublicIP="+m.PublicIP)
 	}
@@ -117,10 +175,37 @@ func (m Machine) String() string {
 		tags = append(tags, fmt.Sprintf("Disk=%dGB", m.DiskSize))
 	}
 
+	if m.MaxPrice != 0 {
+		tags = append(tags, fmt.Sprintf("MaxPrice=$%g", m.MaxPrice))
+	}
+
+	if m.Image != "" {
+		tags = append(tags, "Image="+m.Image)
+	}
+
+	if m.OS != "" {
+		tags = append(tags, "OS="+string(m.OS))
+	}
+
 	if m.Status != "" {
 		tags = append(tags, m.Status)
 	}
 
+	switch {
+	case m.Draining:
+		tags = append(tags, "Draining")
+	case m.Unschedulable:
+		tags = append(tags, "Unschedulable")
+	}
+
+	if m.Version != "" {
+		tags = append(tags, "Version="+m.Version)
+	}
+
+	if m.Upgrading {
+		tags = append(tags, "Upgrading")
+	}
+
 	return fmt.Sprintf("Machine-%d{%s}", m.ID, strings.Join(tags, ", "))
 }
 
@@ -166,3 +251,13 @@ func (ms MachineSlice) Get(ii int) interface{} {
 func (ms MachineSlice) Len() int {
 	return len(ms)
 }
+
+// Less implements less than for sort.Interface.
+func (ms MachineSlice) Less(i, j int) bool {
+	return ms[i].less(ms[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (ms MachineSlice) Swap(i, j int) {
+	ms[i], ms[j] = ms[j], ms[i]
+}
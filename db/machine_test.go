@@ -131,6 +131,50 @@ func TestMachineString(t *testing.T) {
 	if got != exp {
 		t.Errorf("\nGot: %s\nExp: %s", got, exp)
 	}
+
+	m = Machine{Provider: "Amazon", Image: "ami-1234", OS: Debian}
+	got = m.String()
+	exp = "Machine-0{Amazon  , Image=ami-1234, OS=Debian}"
+	if got != exp {
+		t.Errorf("\nGot: %s\nExp: %s", got, exp)
+	}
+
+	m = Machine{Provider: "Amazon", Preemptible: true, MaxPrice: 0.05}
+	got = m.String()
+	exp = "Machine-0{Amazon   preemptible, MaxPrice=$0.05}"
+	if got != exp {
+		t.Errorf("\nGot: %s\nExp: %s", got, exp)
+	}
+
+	m = Machine{Role: Worker, CloudID: "1", Status: Connected,
+		Unschedulable: true}
+	got = m.String()
+	exp = "Machine-0{Worker,   , 1, connected, Unschedulable}"
+	if got != exp {
+		t.Errorf("\nGot: %s\nExp: %s", got, exp)
+	}
+
+	m.Draining = true
+	got = m.String()
+	exp = "Machine-0{Worker,   , 1, connected, Draining}"
+	if got != exp {
+		t.Errorf("\nGot: %s\nExp: %s", got, exp)
+	}
+
+	m = Machine{Provider: "Amazon", CloudID: "1", Namespace: "prod"}
+	got = m.String()
+	exp = "Machine-0{Amazon  , 1, Namespace=prod}"
+	if got != exp {
+		t.Errorf("\nGot: %s\nExp: %s", got, exp)
+	}
+
+	m = Machine{CloudID: "1", Status: Connected, Version: "0.7.0",
+		Upgrading: true}
+	got = m.String()
+	exp = "Machine-0{  , 1, connected, Version=0.7.0, Upgrading}"
+	if got != exp {
+		t.Errorf("\nGot: %s\nExp: %s", got, exp)
+	}
 }
 
 func SelectMachineCheck(db Database, do func(Machine) bool, expected []Machine) error {
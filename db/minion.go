@@ -11,6 +11,13 @@ type Minion struct {
 	MinionIPToPublicKey map[string]string `json:"-" rowStringer:"omit"`
 	PublicIP            string            `json:"-"`
 
+	// The container network configured in the blueprint.
+	ContainerSubnet string `json:"-" rowStringer:"omit"`
+	GatewayIP       string `json:"-" rowStringer:"omit"`
+	LoadBalancerIP  string `json:"-" rowStringer:"omit"`
+
+	ContainerSubnetIPv6 string `json:"-" rowStringer:"omit"`
+
 	// Below fields are included in the JSON encoding.
 	Role        Role
 	PrivateIP   string
@@ -19,6 +26,16 @@ type Minion struct {
 	Region      string
 	FloatingIP  string
 	HostSubnets []string
+
+	// Set when the cloud provider has announced that it will soon reclaim
+	// the minion's machine.
+	Terminating bool
+
+	// Set when new containers shouldn't be scheduled on the minion.
+	Unschedulable bool
+
+	// Set when the minion's containers should be moved to other workers.
+	Draining bool
 }
 
 // InsertMinion creates a new Minion and inserts it into 'db'.
@@ -45,7 +45,10 @@ func CheckPublicConnections(t *testing.T, machines []db.Machine,
 	// Map of hostname to its publicly exposed ports.
 	pubConns := map[string][]int{}
 	for _, conn := range connections {
-		if str.SliceContains(conn.From, "public") {
+		// Only TCP connections can be checked with HTTP.
+		if str.SliceContains(conn.From, "public") &&
+			str.SliceContains(blueprint.PortProtocols(conn.Protocol),
+				blueprint.TCP) {
 			for port := conn.MinPort; port <= conn.MaxPort; port++ {
 				for _, to := range conn.To {
 					pubConns[to] = append(pubConns[to], port)
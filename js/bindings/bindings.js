@@ -12,12 +12,16 @@ let fs = require('fs'); // eslint-disable-line prefer-const
 const googleDescriptions = require('./googleDescriptions');
 const amazonDescriptions = require('./amazonDescriptions');
 const digitalOceanDescriptions = require('./digitalOceanDescriptions');
+const openStackDescriptions = require('./openStackDescriptions');
 
 const providerDefaultRegions = {
   Amazon: 'us-west-1',
   Google: 'us-east1-b',
   DigitalOcean: 'sfo1',
+  OpenStack: 'RegionOne',
   Vagrant: '',
+  Local: '',
+  Static: '',
 };
 
 const githubCache = {};
@@ -46,11 +50,46 @@ class Infrastructure {
    *   another machine to access the deployed machines (e.g., to SSH into a machine),
    *   add its IP address here.  These IP addresses must be in CIDR notation; e.g.,
    *   to allow access from 1.2.3.4, set adminACL to ["1.2.3.4/32"]. To allow access
-   *   from all IP addresses, set adminACL to ["0.0.0.0/0"].
+   *   from all IP addresses, set adminACL to ["0.0.0.0/0"]. IPv6 CIDRs such as
+   *   "2001:db8::/32" are also accepted.
+   * @param {LogSink} [opts.logSink] - Where the minions should forward the
+   *   logs written by containers.  If undefined, logs are only kept by Docker
+   *   on the machine running the container.
+   * @param {Object.<string, string>} [opts.labels] - Labels to apply to all
+   *   of the cloud resources in the namespace, such as AWS tags or GCE labels.
+   *   A machine's own labels take precedence.
+   * @param {string} [opts.containerSubnet=10.0.0.0/8] - The IPv4 subnet, in
+   *   CIDR notation, in which containers and load balancers are given IP
+   *   addresses.  It must not overlap the subnets of the machines' networks,
+   *   such as the VPC or a VPN.  The subnet can't be changed while the
+   *   namespace's machines are running.
+   * @param {string} [opts.gatewayIP] - The address in the container subnet of
+   *   the gateway, which containers use as their default route and DNS
+   *   server.  Defaults to the first address in the subnet.
+   * @param {string} [opts.loadBalancerIP] - The address in the container
+   *   subnet reserved for the load balancer router.  Defaults to the second
+   *   address in the subnet.
+   * @param {string} [opts.containerSubnetIPv6] - An IPv6 subnet, in CIDR
+   *   notation, in which containers and load balancers are also given
+   *   addresses.  Each address has the same host bits as its IPv4 address,
+   *   so the prefix can be at most 96 bits long.  IPv6 is disabled if it's
+   *   not set, and it can't be changed while the namespace's machines are
+   *   running.
    */
   constructor(masters, workers, opts = {}) {
     this.namespace = opts.namespace || 'kelda';
     this.adminACL = getStringArray('adminACL', opts.adminACL);
+    this.labels = getStringMap('labels', opts.labels);
+    this.containerSubnet = getString('containerSubnet', opts.containerSubnet);
+    this.gatewayIP = getString('gatewayIP', opts.gatewayIP);
+    this.loadBalancerIP = getString('loadBalancerIP', opts.loadBalancerIP);
+    this.containerSubnetIPv6 = getString('containerSubnetIPv6',
+      opts.containerSubnetIPv6);
+    this.logSink = opts.logSink;
+    if (this.logSink !== undefined && !(this.logSink instanceof LogSink)) {
+      throw new Error('logSink must be a LogSink ' +
+        `(was: ${stringify(this.logSink)})`);
+    }
 
     checkExtraKeys(opts, this);
 
@@ -79,6 +118,12 @@ class Infrastructure {
     boxedWorkers.forEach(worker =>
       this.machines.push(machineWithRole(worker, 'Worker')));
 
+    // The infrastructure's labels apply to every machine.
+    this.machines.forEach((machine) => {
+      checkLabels(machine.provider, this.labels);
+      checkLabels(machine.provider, machine.labels);
+    });
+
     if (_keldaInfrastructure !== undefined) {
       throw new Error('the Infrastructure constructor has already been called once ' +
         '(each Kelda blueprint can only define one Infrastructure).');
@@ -127,7 +172,15 @@ class Infrastructure {
 
       namespace: this.namespace,
       adminACL: this.adminACL,
+      labels: this.labels,
+      containerSubnet: this.containerSubnet,
+      gatewayIP: this.gatewayIP,
+      loadBalancerIP: this.loadBalancerIP,
+      containerSubnetIPv6: this.containerSubnetIPv6,
     };
+    if (this.logSink !== undefined) {
+      keldaInfrastructure.logSink = this.logSink.toKeldaRepresentation();
+    }
     vet(keldaInfrastructure);
     return keldaInfrastructure;
   }
@@ -205,6 +258,10 @@ function baseInfrastructure() {
 // we need a special label for it).
 const publicInternetLabel = 'public';
 
+// The protocols that connections may be restricted to. Connections without a
+// protocol allow both TCP and UDP.
+const connectionProtocols = ['tcp', 'udp', 'icmp'];
+
 // Global unique ID counter.
 let uniqueIDCounter = 0;
 
@@ -320,6 +377,128 @@ function vet(infrastructure) {
   });
 }
 
+const logSinkTypes = ['syslog', 'file', 'http'];
+
+// The operating systems that Kelda can boot machines with.
+const machineOSes = ['Ubuntu', 'Debian', 'Prebaked'];
+
+// The labels accepted by the providers that restrict them. DigitalOcean labels
+// become tags of the form "key:value".
+const labelRules = {
+  Google: {
+    key: /^[a-z][a-z0-9_-]{0,62}$/,
+    value: /^[a-z0-9_-]{0,63}$/,
+    description: 'keys must start with a lowercase letter, and keys and ' +
+      'values may only contain up to 63 lowercase letters, numbers, ' +
+      'dashes, and underscores',
+  },
+  DigitalOcean: {
+    key: /^[a-zA-Z0-9_-]+$/,
+    value: /^[a-zA-Z0-9_:-]*$/,
+    maxLength: 255,
+    description: 'keys may only contain letters, numbers, dashes, and ' +
+      'underscores, values may also contain colons, and each key and ' +
+      'value may be at most 254 characters combined',
+  },
+};
+
+/**
+ * Throws an error if `provider` can't apply `labels`.
+ * @private
+ *
+ * @param {string} provider - The provider of the labeled machine.
+ * @param {Object.<string, string>} labels - The labels to check.
+ * @returns {void}
+ */
+function checkLabels(provider, labels) {
+  const rules = labelRules[provider];
+  if (rules === undefined) {
+    return;
+  }
+
+  Object.keys(labels).forEach((key) => {
+    const value = labels[key];
+    const tooLong = rules.maxLength !== undefined &&
+      key.length + value.length + 1 > rules.maxLength;
+    if (!rules.key.test(key) || !rules.value.test(value) || tooLong) {
+      throw new Error(`invalid ${provider} label ${stringify(key)}: ` +
+        `${stringify(value)} (${rules.description})`);
+    }
+  });
+}
+
+class LogSink {
+  /**
+   * Creates a new LogSink, which describes where the minions forward the
+   * stdout and stderr of containers.  Each line is annotated with the
+   * hostname and ID of the container that wrote it, as well as the role,
+   * provider, region, size, and IP addresses of the machine running the
+   * container.
+   * @constructor
+   *
+   * @example <caption>Forward logs to a syslog server over TCP.</caption>
+   * const sink = new LogSink({ type: 'syslog', address: 'tcp://10.0.0.1:514' });
+   * const infra = new Infrastructure(machine, machine, { logSink: sink });
+   *
+   * @example <caption>Append logs to /var/log/kelda/containers.log on the
+   * lead master.</caption>
+   * const sink = new LogSink({ type: 'file', file: 'containers.log' });
+   *
+   * @example <caption>POST logs to an HTTP endpoint as JSON arrays.</caption>
+   * const sink = new LogSink({ type: 'http', address: 'https://example.com/logs' });
+   *
+   * @param {Object} opts - The sink's configuration.
+   * @param {string} opts.type - Either 'syslog', 'file', or 'http'.
+   * @param {string} [opts.address] - For syslog sinks, the address of the
+   *   syslog server (e.g., 'udp://10.0.0.1:514').  For HTTP sinks, the URL to
+   *   POST batches of log lines to.
+   * @param {string} [opts.file=containers.log] - For file sinks, the name of
+   *   the file to write within /var/log/kelda on the lead master.
+   * @param {number} [opts.bufferSize] - The maximum number of lines each
+   *   machine buffers while waiting for a slow sink.  Once the buffer is full,
+   *   the machine stops reading logs from Docker until the sink catches up, so
+   *   containers are never blocked.
+   */
+  constructor(opts) {
+    this.type = getString('type', opts.type);
+    this.address = getString('address', opts.address);
+    this.file = getString('file', opts.file);
+    this.bufferSize = getNumber('bufferSize', opts.bufferSize);
+
+    checkExtraKeys(opts, this);
+
+    if (!logSinkTypes.includes(this.type)) {
+      throw new Error(`logSink type must be one of ${logSinkTypes} ` +
+        `(was: ${stringify(this.type)})`);
+    }
+    if (this.type !== 'file' && this.address === '') {
+      throw new Error(`${this.type} log sinks require an address`);
+    }
+    if (this.type === 'file' && this.file === '') {
+      this.file = 'containers.log';
+    }
+    if (this.file.includes('/')) {
+      throw new Error(`file must be a file name, not a path (was: ${this.file})`);
+    }
+    if (this.bufferSize < 0) {
+      throw new Error('bufferSize must not be negative');
+    }
+  }
+
+  /**
+   * @private
+   * @returns {Object} A map describing the LogSink, in a format that can be
+   *   converted to JSON and interpreted by the Kelda Go code.
+   */
+  toKeldaRepresentation() {
+    return {
+      type: this.type,
+      address: this.address,
+      file: this.file,
+      bufferSize: this.bufferSize,
+    };
+  }
+}
 
 class LoadBalancer {
   /**
@@ -367,9 +546,11 @@ class LoadBalancer {
    *   connections to this load balancer.
    * @param {int|Port|PortRange} portRange - The ports on which containers can
    *   open connections.
+   * @param {string} [protocol] - The protocol that the connections may use:
+   *   'tcp', 'udp', or 'icmp'. Both TCP and UDP are allowed if it's undefined.
    * @returns {void}
    */
-  allowFrom(srcArg, portRange) {
+  allowFrom(srcArg, portRange, protocol) {
     let src;
     try {
       src = boxObjects(srcArg, Container);
@@ -380,7 +561,7 @@ class LoadBalancer {
     }
 
     this.allowedInboundConnections.push(
-      new Connection(src, boxRange(portRange)));
+      new Connection(src, boxRange(portRange), protocol));
   }
 
   /**
@@ -389,12 +570,8 @@ class LoadBalancer {
    *   a format that can be converted to JSON and sent to the Kelda Go code.
    */
   getKeldaConnections() {
-    return this.allowedInboundConnections.map(conn => ({
-      from: conn.from.map(f => f.hostname),
-      to: [this.name],
-      minPort: conn.minPort,
-      maxPort: conn.maxPort,
-    }));
+    return this.allowedInboundConnections.map(conn => keldaConnection(
+      conn.from.map(f => f.hostname), [this.name], conn));
   }
 }
 
@@ -410,9 +587,11 @@ const publicInternet = {
    *   that should be allowed to connect to the public internet.
    * @param {number|Range|PortRange} portRange - A port or range of ports that the
    *   given container(s) are allowed to connect to the public internet on.
+   * @param {string} [protocol] - The protocol that the connections may use:
+   *   'tcp' or 'udp'. Both TCP and UDP are allowed if it's undefined.
    * @returns {void}
    */
-  allowFrom(srcArg, portRange) {
+  allowFrom(srcArg, portRange, protocol) {
     let src;
     try {
       src = boxObjects(srcArg, Container);
@@ -423,7 +602,7 @@ const publicInternet = {
     }
 
     src.forEach((c) => {
-      c.allowOutboundPublic(portRange);
+      c.allowOutboundPublic(portRange, protocol);
     });
   },
 };
@@ -614,6 +793,31 @@ function getSecretOrStringMap(argName, arg) {
   return arg;
 }
 
+/**
+ * @private
+ * @param {string} argName - The name of `arg` (for logging).
+ * @param {Object.<string, string>} arg - The map of strings to strings.
+ * @returns {Object.<string, string>} An empty object if `arg` is not defined,
+ *   and otherwise ensures that `arg` is an object with string values and then
+ *   returns it.
+ */
+function getStringMap(argName, arg) {
+  if (arg === undefined) {
+    return {};
+  }
+  if (typeof arg !== 'object' || arg === null || Array.isArray(arg)) {
+    throw new Error(`${argName} must be a map of strings to strings ` +
+            `(was: ${stringify(arg)})`);
+  }
+  Object.keys(arg).forEach((k) => {
+    if (typeof arg[k] !== 'string') {
+      throw new Error(`${argName} must be a map of strings to strings ` +
+        `(value ${stringify(arg[k])} associated with ${k} is not a string)`);
+    }
+  });
+  return arg;
+}
+
 /**
  * Verifies `arg` is an array of strings or undefined.
  * @private
@@ -690,7 +894,7 @@ class Machine {
    *   Only 'provider' is required; the remaining options are optional.
    * @param {string} opts.provider - The cloud provider that the machine
    *   should be launched in. Accepted values are Amazon, DigitalOcean, Google,
-   *   and Vagrant.
+   *   OpenStack, Vagrant, Local, and Static.
    * @param {string} [opts.region] - The region the machine will run-in
    *   (provider-specific; e.g., for Amazon, this could be 'us-west-2').
    * @param {string} [opts.size] - The instance type (provider-specific).
@@ -703,6 +907,21 @@ class Machine {
    *   in to the machine and containers running on it.
    * @param {boolean} [opts.preemptible=false] - Whether the machine
    *   should be preemptible. Only supported on the Amazon provider.
+   * @param {number|string} [opts.maxPrice] - The most that a preemptible
+   *   machine may cost, either in US dollars per hour, or as a percentage of
+   *   the on-demand price of its size (e.g. '50%'). Defaults to the on-demand
+   *   price. Only supported on the Amazon provider.
+   * @param {string} [opts.image] - The provider-specific image to boot, such
+   *   as an AMI ID for Amazon. Images are specific to a region, so the region
+   *   should also be given. Defaults to the provider's Ubuntu 16.04 image.
+   *   Not supported on the Vagrant, Local, and Static providers.
+   * @param {string} [opts.os] - The operating system of the image, which
+   *   determines how the machine is set up. Accepted values are Ubuntu,
+   *   Debian, and Prebaked (an Ubuntu or Debian image that already has Docker
+   *   installed). Defaults to Ubuntu.
+   * @param {Object.<string, string>} [opts.labels] - Labels to apply to the
+   *   machine and its disk, such as AWS tags or GCE labels. Only supported on
+   *   the Amazon, Google, and DigitalOcean providers.
    */
   constructor(opts) {
     this._refID = uniqueID();
@@ -710,7 +929,7 @@ class Machine {
     this.provider = getString('provider', opts.provider);
     if (this.provider === '') {
       throw new Error('Machine must specify a provider (accepted values are Amazon, ' +
-        'DigitalOcean, Google, and Vagrant');
+        'DigitalOcean, Google, OpenStack, Vagrant, Local, and Static');
     }
     this.role = getString('role', opts.role);
     this.region = getString('region', opts.region);
@@ -719,9 +938,18 @@ class Machine {
     this.diskSize = getNumber('diskSize', opts.diskSize);
     this.sshKeys = getStringArray('sshKeys', opts.sshKeys);
     this.preemptible = getBoolean('preemptible', opts.preemptible);
+    this.image = getString('image', opts.image);
+    this.os = getString('os', opts.os);
+    if (this.os !== '' && !machineOSes.includes(this.os)) {
+      throw new Error(`os must be one of ${machineOSes} ` +
+        `(was: ${stringify(this.os)})`);
+    }
+    this.labels = getStringMap('labels', opts.labels);
+    checkLabels(this.provider, this.labels);
 
-    this.chooseSize(boxRange(opts.cpu), boxRange(opts.ram));
     this.chooseRegion();
+    const description = this.chooseSize(boxRange(opts.cpu), boxRange(opts.ram));
+    this.maxPrice = this.chooseMaxPrice(opts.maxPrice, description);
 
     // Check for extra keys after calling chooseSize, which sets the machine size,
     // CPU, and RAM.
@@ -737,12 +965,18 @@ class Machine {
    * @private
    * @param {Range} cpu - The desired number of CPUs.
    * @param {Range} ram - The desired amount of RAM in GiB.
-   * @returns {void}
+   * @returns {Object|undefined} - The description of the machine that will be
+   *   launched by the cloud provider, if the provider has descriptions.
    */
   chooseSize(cpu, ram) {
-    if (this.provider === 'Vagrant') {
+    if (this.provider === 'Vagrant' || this.provider === 'Local') {
       this.vagrantSize(cpu, ram);
-      return;
+      return undefined;
+    }
+    // Static hosts are selected by the size labels in the user's inventory,
+    // so the size is used as is.
+    if (this.provider === 'Static') {
+      return undefined;
     }
     let providerDescriptions;
     switch (this.provider) {
@@ -755,9 +989,26 @@ class Machine {
       case 'Google':
         providerDescriptions = googleDescriptions.Descriptions;
         break;
+      case 'OpenStack':
+        providerDescriptions = openStackDescriptions.Descriptions;
+        break;
       default:
         throw new Error(`Unknown Cloud Provider: ${this.provider}`);
     }
+
+    // Prices and availability differ between regions, so only consider the
+    // descriptions for the machine's region. Regions without any descriptions
+    // use those of the provider's default region rather than every region's,
+    // which would include regions such as us-gov-west-1 that Kelda can't use.
+    // Descriptions without a region apply to every region.
+    const inRegion = region => providerDescriptions.filter(
+      description => !description.Region || description.Region === region);
+    let regionDescriptions = inRegion(this.region);
+    if (regionDescriptions.length === 0) {
+      regionDescriptions = inRegion(providerDefaultRegions[this.provider]);
+    }
+    providerDescriptions = regionDescriptions;
+
     let machineDescription;
     if (this.size !== '') {
       machineDescription = this.verifySize(providerDescriptions, cpu, ram);
@@ -770,6 +1021,42 @@ class Machine {
     this.size = machineDescription.Size;
     this.ram = machineDescription.RAM;
     this.cpu = machineDescription.CPU;
+    return machineDescription;
+  }
+
+  /**
+   * Converts the user's maxPrice option into US dollars per hour. Throws an
+   * error if the machine can't have a max price, or the option is malformed.
+   * @private
+   * @param {number|string|undefined} maxPrice - The maxPrice option.
+   * @param {Object|undefined} description - The description of the machine
+   *   that will be launched by the cloud provider.
+   * @returns {number} The max price, or 0 if there isn't one.
+   */
+  chooseMaxPrice(maxPrice, description) {
+    if (maxPrice === undefined || maxPrice === 0) {
+      return 0;
+    }
+    if (!this.preemptible) {
+      throw new Error('maxPrice is only supported on preemptible machines');
+    }
+    if (this.provider !== 'Amazon') {
+      throw new Error('maxPrice is only supported on the Amazon provider');
+    }
+
+    if (typeof maxPrice === 'number' && maxPrice > 0) {
+      return maxPrice;
+    }
+
+    const percent = typeof maxPrice === 'string' && maxPrice.match(/^(\d+(\.\d+)?)%$/);
+    if (!percent || Number(percent[1]) === 0) {
+      throw new Error('maxPrice must be a positive number or a percentage of ' +
+        `the on-demand price (was: ${stringify(maxPrice)})`);
+    }
+
+    // Round up to the hundredth of a cent so that small prices don't become 0.
+    const price = (description.Price * Number(percent[1])) / 100;
+    return Math.ceil(price * 10000) / 10000;
   }
 
   /**
@@ -858,7 +1145,8 @@ class Machine {
   }
 
   /**
-   * Rounds up RAM and CPU requirements to be at least one for Vagrant.
+   * Rounds up RAM and CPU requirements to be at least one for Vagrant and
+   * Local machines.
    * @private
    * @param {Range} cpuRange - The desired number of CPUs.
    * @param {Range} ramRange - The desired amount of RAM in GiB.
@@ -880,10 +1168,13 @@ class Machine {
    * @returns {Machine} A new machine with the same attributes.
    */
   clone() {
-    // _.clone only creates a shallow copy, so we must clone sshKeys ourselves.
+    // _.clone only creates a shallow copy, so we must clone sshKeys and labels
+    // ourselves.
     const keyClone = _.clone(this.sshKeys);
+    const labelsClone = _.clone(this.labels);
     const cloned = _.clone(this);
     cloned.sshKeys = keyClone;
+    cloned.labels = labelsClone;
     return new Machine(cloned);
   }
 
@@ -916,6 +1207,10 @@ class Machine {
       floatingIp: this.floatingIp,
       diskSize: this.diskSize,
       preemptible: this.preemptible,
+      maxPrice: this.maxPrice,
+      image: this.image,
+      os: this.os,
+      labels: this.labels,
     });
   }
 
@@ -1166,11 +1461,14 @@ class Container {
    *   be allowed to connect to this Container.
    * @param {number|Range|PortRange} portRange - A port or range of ports that the
    *  given Container(s) are allowed to connect to this Container on.
+   * @param {string} [protocol] - The protocol that the connections may use:
+   *   'tcp', 'udp', or 'icmp'. Both TCP and UDP are allowed if it's undefined.
+   *   ICMP connections don't have ports, so `portRange` must be undefined.
    * @returns {void}
    */
-  allowFrom(srcArg, portRange) {
+  allowFrom(srcArg, portRange, protocol) {
     if (srcArg === publicInternet) {
-      this.allowFromPublic(portRange);
+      this.allowFromPublic(portRange, protocol);
       return;
     }
 
@@ -1184,7 +1482,7 @@ class Container {
     }
 
     this.allowedInboundConnections.push(
-      new Connection(src, boxRange(portRange)));
+      new Connection(src, boxRange(portRange), protocol));
   }
 
   /**
@@ -1194,15 +1492,22 @@ class Container {
    *
    * @param {number|Range} r - A port or port range that this Container should be allowed
    *   to initiate outbound connections to the public internet on.
+   * @param {string} [protocol] - The protocol that the connections may use.
+   *   ICMP isn't supported.
    * @returns {void}
    */
-  allowOutboundPublic(r) {
+  allowOutboundPublic(r, protocol) {
+    if (protocol === 'icmp') {
+      throw new Error('icmp connections to the public internet are not ' +
+              'supported');
+    }
+
     const range = boxRange(r);
     if (range.min !== range.max) {
       throw new Error('public internet can only connect to single ports ' +
               'and not to port ranges');
     }
-    this.outgoingPublic.push(range);
+    this.outgoingPublic.push(new Connection([this], range, protocol));
   }
 
   /**
@@ -1212,15 +1517,16 @@ class Container {
    *
    * @param {number|Range} r - A port or port range that this Container should accept
    *   inbound connetions from the public internet on.
+   * @param {string} [protocol] - The protocol that the connections may use.
    * @returns {void}
    */
-  allowFromPublic(r) {
+  allowFromPublic(r, protocol) {
     const range = boxRange(r);
     if (range.min !== range.max) {
       throw new Error('public internet can only connect to single ports ' +
               'and not to port ranges');
     }
-    this.incomingPublic.push(range);
+    this.incomingPublic.push(new Connection([], range, protocol));
   }
 
   /**
@@ -1242,30 +1548,18 @@ class Container {
     const connections = [];
 
     this.allowedInboundConnections.forEach((conn) => {
-      connections.push({
-        from: conn.from.map(f => f.hostname),
-        to: [this.hostname],
-        minPort: conn.minPort,
-        maxPort: conn.maxPort,
-      });
+      connections.push(keldaConnection(
+        conn.from.map(f => f.hostname), [this.hostname], conn));
     });
 
-    this.outgoingPublic.forEach((rng) => {
-      connections.push({
-        from: [this.hostname],
-        to: [publicInternetLabel],
-        minPort: rng.min,
-        maxPort: rng.max,
-      });
+    this.outgoingPublic.forEach((conn) => {
+      connections.push(keldaConnection(
+        [this.hostname], [publicInternetLabel], conn));
     });
 
-    this.incomingPublic.forEach((rng) => {
-      connections.push({
-        from: [publicInternetLabel],
-        to: [this.hostname],
-        minPort: rng.min,
-        maxPort: rng.max,
-      });
+    this.incomingPublic.forEach((conn) => {
+      connections.push(keldaConnection(
+        [publicInternetLabel], [this.hostname], conn));
     });
 
     return connections;
@@ -1379,9 +1673,11 @@ class Connectable {
    *
    * @param {Container} src - The container that can initiate connections.
    * @param {int|Port|PortRange} port - The ports to allow traffic on.
+   * @param {string} [protocol] - The protocol to allow: 'tcp', 'udp', or
+   *   'icmp'. Both TCP and UDP are allowed if it's undefined.
    * @returns {void}
    */
-  allowFrom(src, port) { // eslint-disable-line
+  allowFrom(src, port, protocol) { // eslint-disable-line
     throw new Error('not implemented');
   }
 }
@@ -1407,11 +1703,14 @@ function isConnectable(x) {
  *   Examples of connectable objects are Containers, LoadBalancers, publicInternet,
  *   and user-defined objects that implement allowFrom.
  * @param {int|Port|PortRange} port - The ports that traffic is allowed on.
+ * @param {string} [protocol] - The protocol that traffic may use: 'tcp', 'udp',
+ *   or 'icmp'. Both TCP and UDP are allowed if it's undefined. ICMP connections
+ *   don't have ports, so `port` must be undefined.
  * @returns {void}
  */
-function allow(src, dst, port) {
+function allow(src, dst, port, protocol) {
   boxConnectable(dst).forEach((c) => {
-    c.allowFrom(src, port);
+    c.allowFrom(src, port, protocol);
   });
 }
 
@@ -1422,14 +1721,49 @@ class Connection {
    *
    * @param {string[]} from - A list of hosts that allow connections.
    * @param {PortRange} ports - The port numbers which are allowed.
+   * @param {string} [protocol] - The protocol which is allowed. Both TCP and
+   *   UDP are allowed if it's undefined.
    */
-  constructor(from, ports) {
+  constructor(from, ports, protocol) {
+    if (protocol !== undefined) {
+      if (!connectionProtocols.includes(protocol)) {
+        throw new Error('protocol must be one of ' +
+          `${connectionProtocols} (was: ${stringify(protocol)})`);
+      }
+      if (protocol === 'icmp' && (ports.min !== 0 || ports.max !== 0)) {
+        throw new Error('icmp connections cannot have ports');
+      }
+    }
+
     this.minPort = ports.min;
     this.maxPort = ports.max;
+    this.protocol = protocol;
     this.from = from;
   }
 }
 
+/**
+ * Converts a Connection to the JSON format expected by the Kelda Go code.
+ * @private
+ *
+ * @param {string[]} from - The hostnames that can initiate connections.
+ * @param {string[]} to - The hostnames that can receive connections.
+ * @param {Connection} conn - The connection's ports and protocol.
+ * @returns {Object} A map that can be converted to JSON.
+ */
+function keldaConnection(from, to, conn) {
+  const res = {
+    from,
+    to,
+    minPort: conn.minPort,
+    maxPort: conn.maxPort,
+  };
+  if (conn.protocol !== undefined) {
+    res.protocol = conn.protocol;
+  }
+  return res;
+}
+
 class Range {
   /**
    * Creates a Range object.
@@ -1512,6 +1846,7 @@ module.exports = {
   Container,
   Infrastructure,
   Image,
+  LogSink,
   Machine,
   Port,
   PortRange,
@@ -112,7 +112,8 @@ describe('Bindings', () => {
     });
     it('throws error when no Provider specified', () => {
       expect(() => new b.Machine({})).to.throw('Machine must specify a provider ' +
-        '(accepted values are Amazon, DigitalOcean, Google, and Vagrant');
+        '(accepted values are Amazon, DigitalOcean, Google, OpenStack, Vagrant, ' +
+        'Local, and Static');
     });
     it('chooses size when provided ram and cpu', () => {
       const machine = new b.Machine({
@@ -172,15 +173,59 @@ describe('Bindings', () => {
       const machine = new b.Machine({
         role: 'Worker',
         provider: 'Amazon',
+        region: 'us-east-1',
         cpu: new b.Range(2, 2),
         sshKeys: ['key1', 'key2'],
       });
       infra = new b.Infrastructure(machine, machine);
       checkMachines([{
         provider: 'Amazon',
+        region: 'us-east-1',
         size: 'c4.large',
       }]);
     });
+    it('chooses size based on the prices in the machine\'s region', () => {
+      // c3.large is the cheapest size with two CPUs in us-west-1, but London
+      // doesn't offer it, and m4.large is cheaper than c4.large there.
+      const west = new b.Machine({
+        role: 'Worker',
+        provider: 'Amazon',
+        cpu: new b.Range(2, 2),
+        sshKeys: ['key1', 'key2'],
+      });
+      const london = new b.Machine({
+        role: 'Worker',
+        provider: 'Amazon',
+        region: 'eu-west-2',
+        cpu: new b.Range(2, 2),
+        sshKeys: ['key1', 'key2'],
+      });
+      infra = new b.Infrastructure([west, london], [west, london]);
+      checkMachines([
+        { provider: 'Amazon', region: 'us-west-1', size: 'c3.large' },
+        { provider: 'Amazon', region: 'eu-west-2', size: 'm4.large' },
+        { provider: 'Amazon', region: 'us-west-1', size: 'c3.large' },
+        { provider: 'Amazon', region: 'eu-west-2', size: 'm4.large' },
+      ]);
+    });
+    it('chooses size for regions without size descriptions', () => {
+      // Regions without descriptions use the prices of the default region,
+      // rather than those of regions that Kelda can't deploy to, such as
+      // us-gov-west-1.
+      const machine = new b.Machine({
+        role: 'Worker',
+        provider: 'Amazon',
+        region: 'ca-central-1',
+        cpu: new b.Range(2, 2),
+        sshKeys: ['key1', 'key2'],
+      });
+      infra = new b.Infrastructure(machine, machine);
+      checkMachines([{
+        provider: 'Amazon',
+        region: 'ca-central-1',
+        size: 'c3.large',
+      }]);
+    });
     it('chooses size when only ram is specifed', () => {
       const machine = new b.Machine({
         role: 'Worker',
@@ -304,6 +349,44 @@ describe('Bindings', () => {
         region: '',
       }]);
     });
+    it('uses empty string as region and RAM,CPU as size for Local', () => {
+      const machine = new b.Machine({
+        provider: 'Local',
+        cpu: 2,
+        ram: 4,
+      });
+      infra = new b.Infrastructure(machine, machine);
+      checkMachines([{
+        provider: 'Local',
+        region: '',
+        size: '4,2',
+      }]);
+    });
+    it('uses the smallest flavor that fits for OpenStack', () => {
+      const machine = new b.Machine({
+        provider: 'OpenStack',
+        cpu: 2,
+        ram: 3,
+      });
+      infra = new b.Infrastructure(machine, machine);
+      checkMachines([{
+        provider: 'OpenStack',
+        region: 'RegionOne',
+        size: 'm1.medium',
+      }]);
+    });
+    it('uses empty string as region for Static', () => {
+      const machine = new b.Machine({
+        provider: 'Static',
+        size: 'large',
+      });
+      infra = new b.Infrastructure(machine, machine);
+      checkMachines([{
+        provider: 'Static',
+        region: '',
+        size: 'large',
+      }]);
+    });
     it('uses provided region when region is provided', () => {
       const machine = new b.Machine({
         provider: 'Amazon',
@@ -437,6 +520,107 @@ describe('Bindings', () => {
         preemptible: true,
       }]);
     });
+    it('max price in dollars', () => {
+      const machine = new b.Machine({
+        provider: 'Amazon',
+        preemptible: true,
+        maxPrice: 0.02,
+      });
+      infra = new b.Infrastructure(machine, machine);
+      checkMachines([{
+        provider: 'Amazon',
+        preemptible: true,
+        maxPrice: 0.02,
+      }]);
+    });
+    it('max price relative to the on-demand price', () => {
+      // The default size is m3.medium, which costs $0.077 in us-west-1.
+      const machine = new b.Machine({
+        provider: 'Amazon',
+        preemptible: true,
+        maxPrice: '50%',
+      });
+      expect(machine.maxPrice).to.equal(0.0385);
+
+      // Cloned machines keep the resolved price.
+      infra = new b.Infrastructure(machine, machine.replicate(2));
+      checkMachines([{ maxPrice: 0.0385 }, { maxPrice: 0.0385 }]);
+    });
+    it('errors on invalid max prices', () => {
+      expect(() => new b.Machine({ provider: 'Amazon', maxPrice: 0.1 }))
+        .to.throw('maxPrice is only supported on preemptible machines');
+      expect(() => new b.Machine({
+        provider: 'Google', preemptible: true, maxPrice: 0.1,
+      })).to.throw('maxPrice is only supported on the Amazon provider');
+      expect(() => new b.Machine({
+        provider: 'Amazon', preemptible: true, maxPrice: 'cheap',
+      })).to.throw('maxPrice must be a positive number or a percentage of ' +
+        'the on-demand price (was: "cheap")');
+      expect(() => new b.Machine({
+        provider: 'Amazon', preemptible: true, maxPrice: -1,
+      })).to.throw('maxPrice must be a positive number');
+    });
+    it('image and OS attributes', () => {
+      const machine = new b.Machine({
+        provider: 'Amazon',
+        region: 'us-west-2',
+        image: 'ami-1234',
+        os: 'Debian',
+      });
+      infra = new b.Infrastructure(machine, machine);
+      checkMachines([{
+        provider: 'Amazon',
+        region: 'us-west-2',
+        image: 'ami-1234',
+        os: 'Debian',
+      }]);
+    });
+    it('errors on unknown OSes', () => {
+      expect(() => new b.Machine({ provider: 'Amazon', os: 'Windows' }))
+        .to.throw('os must be one of Ubuntu,Debian,Prebaked (was: "Windows")');
+    });
+    it('labels', () => {
+      const machine = new b.Machine({
+        provider: 'Amazon',
+        labels: { team: 'infra' },
+      });
+      const workers = machine.replicate(2);
+      workers[0].labels.env = 'prod';
+      infra = new b.Infrastructure(machine, workers);
+      checkMachines([
+        { role: 'Master', labels: { team: 'infra' } },
+        { role: 'Worker', labels: { team: 'infra', env: 'prod' } },
+        { role: 'Worker', labels: { team: 'infra' } },
+      ]);
+    });
+    it('errors on invalid labels', () => {
+      expect(() => new b.Machine({ provider: 'Amazon', labels: ['infra'] }))
+        .to.throw('labels must be a map of strings to strings ' +
+          '(was: ["infra"])');
+      expect(() => new b.Machine({ provider: 'Amazon', labels: { team: 1 } }))
+        .to.throw('labels must be a map of strings to strings ' +
+          '(value 1 associated with team is not a string)');
+    });
+    it('errors on labels the provider rejects', () => {
+      expect(() => new b.Machine({ provider: 'Google', labels: { Team: 'a' } }))
+        .to.throw('invalid Google label "Team": "a" (keys must start with a ' +
+          'lowercase letter, and keys and values may only contain up to 63 ' +
+          'lowercase letters, numbers, dashes, and underscores)');
+      expect(() => new b.Machine({
+        provider: 'DigitalOcean',
+        labels: { owner: 'jane doe' },
+      })).to.throw('invalid DigitalOcean label "owner": "jane doe"');
+
+      // Other providers accept any labels.
+      expect(() => new b.Machine({ provider: 'Amazon', labels: { Team: 'a b' } }))
+        .to.not.throw();
+
+      // The infrastructure's labels are checked against every machine.
+      const machine = new b.Machine({ provider: 'Google' });
+      expect(() => new b.Infrastructure(machine, machine, {
+        labels: { Team: 'infra' },
+      })).to.throw('invalid Google label "Team": "infra"');
+    });
   });
 
   describe('Container', () => {
@@ -808,6 +992,58 @@ describe('Bindings', () => {
         maxPort: 80,
       }]);
     });
+    it('restrict connection to a protocol', () => {
+      bar.allowFrom(foo, 53, 'udp');
+      checkConnections([{
+        from: ['foo'],
+        to: ['bar'],
+        minPort: 53,
+        maxPort: 53,
+        protocol: 'udp',
+      }]);
+    });
+    it('allow icmp from publicInternet', () => {
+      foo.allowFrom(b.publicInternet, undefined, 'icmp');
+      checkConnections([{
+        from: ['public'],
+        to: ['foo'],
+        minPort: 0,
+        maxPort: 0,
+        protocol: 'icmp',
+      }]);
+    });
+    it('restrict publicInternet connection to a protocol', () => {
+      b.publicInternet.allowFrom(foo, 443, 'tcp');
+      checkConnections([{
+        from: ['foo'],
+        to: ['public'],
+        minPort: 443,
+        maxPort: 443,
+        protocol: 'tcp',
+      }]);
+    });
+    it('restrict LoadBalancer connection to a protocol', () => {
+      fooLoadBalancer.allowFrom(bar, 80, 'tcp');
+      checkConnections([{
+        from: ['bar'],
+        to: ['fooLoadBalancer'],
+        minPort: 80,
+        maxPort: 80,
+        protocol: 'tcp',
+      }]);
+    });
+    it('invalid protocol', () => {
+      expect(() => bar.allowFrom(foo, 80, 'sctp')).to
+        .throw('protocol must be one of tcp,udp,icmp (was: "sctp")');
+    });
+    it('icmp to publicInternet', () => {
+      expect(() => b.publicInternet.allowFrom(foo, undefined, 'icmp')).to
+        .throw('icmp connections to the public internet are not supported');
+    });
+    it('icmp with ports', () => {
+      expect(() => bar.allowFrom(foo, 80, 'icmp')).to
+        .throw('icmp connections cannot have ports');
+    });
     it('connect to publicInternet port range', () => {
       expect(() =>
         b.publicInternet.allowFrom(foo, new b.PortRange(80, 81))).to
@@ -1076,6 +1312,107 @@ describe('Bindings', () => {
       createBasicInfra();
       expect(infra.toKeldaRepresentation().adminACL).to.eql([]);
     });
+    it('log sink', () => {
+      infra = new b.Infrastructure(machine, machine, {
+        logSink: new b.LogSink({ type: 'syslog', address: 'udp://10.0.0.1:514' }),
+      });
+      expect(infra.toKeldaRepresentation().logSink).to.eql({
+        type: 'syslog',
+        address: 'udp://10.0.0.1:514',
+        file: '',
+        bufferSize: 0,
+      });
+    });
+    it('labels', () => {
+      infra = new b.Infrastructure(machine, machine, {
+        labels: { team: 'infra' },
+      });
+      expect(infra.toKeldaRepresentation().labels).to.eql({ team: 'infra' });
+    });
+    it('default labels', () => {
+      createBasicInfra();
+      expect(infra.toKeldaRepresentation().labels).to.eql({});
+    });
+    it('container network', () => {
+      infra = new b.Infrastructure(machine, machine, {
+        containerSubnet: '172.20.0.0/16',
+        gatewayIP: '172.20.0.10',
+        loadBalancerIP: '172.20.0.11',
+        containerSubnetIPv6: 'fd00::/64',
+      });
+      const repr = infra.toKeldaRepresentation();
+      expect(repr.containerSubnet).to.equal('172.20.0.0/16');
+      expect(repr.gatewayIP).to.equal('172.20.0.10');
+      expect(repr.loadBalancerIP).to.equal('172.20.0.11');
+      expect(repr.containerSubnetIPv6).to.equal('fd00::/64');
+    });
+    it('default container network', () => {
+      createBasicInfra();
+      const repr = infra.toKeldaRepresentation();
+      expect(repr.containerSubnet).to.equal('');
+      expect(repr.gatewayIP).to.equal('');
+      expect(repr.loadBalancerIP).to.equal('');
+      expect(repr.containerSubnetIPv6).to.equal('');
+    });
+    it('container subnet must be a string', () => {
+      expect(() => new b.Infrastructure(machine, machine, {
+        containerSubnet: 16,
+      })).to.throw('containerSubnet must be a string (was: 16)');
+    });
+    it('default log sink', () => {
+      createBasicInfra();
+      expect(infra.toKeldaRepresentation()).to.not.have.property('logSink');
+    });
+    it('log sink must be a LogSink', () => {
+      expect(() => new b.Infrastructure(machine, machine, {
+        logSink: { type: 'file' },
+      })).to.throw('logSink must be a LogSink (was: {"type":"file"})');
+    });
+  });
+  describe('LogSink', () => {
+    it('defaults the file name for file sinks', () => {
+      const sink = new b.LogSink({ type: 'file' });
+      expect(sink.toKeldaRepresentation()).to.eql({
+        type: 'file',
+        address: '',
+        file: 'containers.log',
+        bufferSize: 0,
+      });
+    });
+    it('passes through all options', () => {
+      const sink = new b.LogSink({
+        type: 'http', address: 'https://example.com/logs', bufferSize: 10,
+      });
+      expect(sink.toKeldaRepresentation()).to.eql({
+        type: 'http',
+        address: 'https://example.com/logs',
+        file: '',
+        bufferSize: 10,
+      });
+    });
+    it('rejects unknown types', () => {
+      expect(() => new b.LogSink({ type: 'kafka', address: 'foo' })).to.throw(
+        'logSink type must be one of syslog,file,http (was: "kafka")');
+    });
+    it('requires an address for syslog and http sinks', () => {
+      expect(() => new b.LogSink({ type: 'syslog' })).to.throw(
+        'syslog log sinks require an address');
+      expect(() => new b.LogSink({ type: 'http' })).to.throw(
+        'http log sinks require an address');
+    });
+    it('rejects file paths', () => {
+      expect(() => new b.LogSink({ type: 'file', file: '../etc/passwd' })).to.throw(
+        'file must be a file name, not a path (was: ../etc/passwd)');
+    });
+    it('rejects negative buffer sizes', () => {
+      expect(() => new b.LogSink({
+        type: 'file', bufferSize: -1,
+      })).to.throw('bufferSize must not be negative');
+    });
+    it('rejects unknown options', () => {
+      expect(() => new b.LogSink({ type: 'file', foo: 'bar' })).to.throw(
+        'Unrecognized keys passed to LogSink constructor: foo');
+    });
   });
   describe('githubKeys()', () => {});
   describe('baseInfrastructure()', () => {
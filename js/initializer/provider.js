@@ -29,6 +29,18 @@ let providerConfig = { // eslint-disable-line prefer-const
       key: 'DigitalOcean account token',
     },
   },
+  OpenStack: {
+    credsTemplate: 'openstack_creds_template',
+    credsKeys: {
+      authURL: 'Keystone authentication URL',
+      username: 'OpenStack username',
+      password: 'OpenStack password',
+      projectName: 'OpenStack project name',
+      domainName: 'OpenStack domain name',
+      image: 'ID of the Ubuntu image to boot',
+      network: 'ID of the network to attach machines to',
+    },
+  },
   Vagrant: {},
 };
 
@@ -0,0 +1,115 @@
+package minion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/kelda/kelda/cloud"
+	"github.com/kelda/kelda/cloud/foreman"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/connection/tls/rsa"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/vault"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The functions that manage the cluster's machines once it's self-managed. They
+// are stored in variables so that they can be mocked out by the unit tests.
+var runForeman = foreman.Run
+var runSyncCredentials = cloud.SyncCredentials
+var runCloud = cloud.Run
+var newVaultClient = vault.New
+
+// runControlPlane manages the cluster's machines, in place of the daemon, once
+// the cluster has been handed off with `kelda handoff`.  It only runs on the
+// Etcd leader, so when leadership changes, the new leader takes over from where
+// the old one left off using the blueprint and machines synced through Etcd.
+func runControlPlane(conn db.Conn, creds connection.Credentials) {
+	var stop chan struct{}
+	for range conn.TriggerTick(30, db.EtcdTable, db.BlueprintTable).C {
+		stop = updateControlPlane(conn, creds, stop)
+	}
+}
+
+func updateControlPlane(conn db.Conn, creds connection.Credentials,
+	stop chan struct{}) chan struct{} {
+
+	selfManaged := len(conn.SelectFromBlueprint(nil)) > 0
+	switch {
+	case conn.EtcdLeader() && selfManaged && stop == nil:
+		sshKey, ca, err := readControlPlaneSecrets(conn)
+		if err != nil {
+			log.WithError(err).Warn("Failed to read control plane secrets")
+			return nil
+		}
+
+		log.Info("Start managing the cluster's machines")
+		c.Inc("Start Control Plane")
+		stop = make(chan struct{})
+		adminKey := strings.TrimSpace(string(
+			ssh.MarshalAuthorizedKey(sshKey.PublicKey())))
+		go runForeman(conn, creds, stop)
+		go runSyncCredentials(conn, sshKey, ca, stop)
+		go runCloud(conn, adminKey, cloud.DefaultHealthPolicy, stop)
+	case (!conn.EtcdLeader() || !selfManaged) && stop != nil:
+		log.Info("Stop managing the cluster's machines")
+		c.Inc("Stop Control Plane")
+		close(stop)
+		stop = nil
+	}
+	return stop
+}
+
+// readControlPlaneSecrets reads the secrets written by `kelda handoff` out of
+// Vault.  The cloud provider credentials are installed where the providers
+// expect them, and the SSH key and certificate authority are returned.
+func readControlPlaneSecrets(conn db.Conn) (ssh.Signer, rsa.KeyPair, error) {
+	store, err := newVaultClient(conn.MinionSelf().PrivateIP)
+	if err != nil {
+		return nil, rsa.KeyPair{}, fmt.Errorf("connect to Vault: %s", err)
+	}
+
+	for name, file := range cloud.CredentialFiles {
+		value, err := store.Read(name)
+		if err == vault.ErrSecretDoesNotExist {
+			// The deployment doesn't use this provider.
+			continue
+		} else if err != nil {
+			return nil, rsa.KeyPair{}, fmt.Errorf("read %s: %s", name, err)
+		}
+
+		path := filepath.Join(os.Getenv("HOME"), file)
+		if err := util.AppFs.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, rsa.KeyPair{}, fmt.Errorf("mkdir: %s", err)
+		}
+		if err := util.WriteFile(path, []byte(value), 0600); err != nil {
+			return nil, rsa.KeyPair{}, fmt.Errorf("write %s: %s", path, err)
+		}
+	}
+
+	values := map[string]string{}
+	for _, name := range []string{cloud.SSHKeySecret, cloud.CACertSecret,
+		cloud.CAKeySecret} {
+		values[name], err = store.Read(name)
+		if err != nil {
+			return nil, rsa.KeyPair{}, fmt.Errorf("read %s: %s", name, err)
+		}
+	}
+
+	sshKey, err := ssh.ParsePrivateKey([]byte(values[cloud.SSHKeySecret]))
+	if err != nil {
+		return nil, rsa.KeyPair{}, fmt.Errorf("parse SSH key: %s", err)
+	}
+
+	ca, err := rsa.New(values[cloud.CACertSecret], values[cloud.CAKeySecret])
+	if err != nil {
+		return nil, rsa.KeyPair{}, fmt.Errorf("parse CA: %s", err)
+	}
+	return sshKey, ca, nil
+}
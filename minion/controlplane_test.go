@@ -0,0 +1,175 @@
+package minion
+
+import (
+	"crypto/rand"
+	goRSA "crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/kelda/kelda/cloud"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/connection/tls/rsa"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/vault"
+	"github.com/kelda/kelda/minion/vault/mocks"
+	"github.com/kelda/kelda/util"
+)
+
+func TestUpdateControlPlane(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	store := &mocks.SecretStore{}
+	newVaultClient = func(ip string) (vault.SecretStore, error) {
+		assert.Equal(t, "10.0.0.1", ip)
+		return store, nil
+	}
+
+	started := make(chan string, 10)
+	stopped := make(chan string, 10)
+	track := func(name string, stop <-chan struct{}) {
+		started <- name
+		<-stop
+		stopped <- name
+	}
+	runForeman = func(_ db.Conn, _ connection.Credentials, stop <-chan struct{}) {
+		track("foreman", stop)
+	}
+	runSyncCredentials = func(_ db.Conn, _ ssh.Signer, _ rsa.KeyPair,
+		stop <-chan struct{}) {
+		track("credentials", stop)
+	}
+	adminKeys := make(chan string, 10)
+	runCloud = func(_ db.Conn, key string, _ cloud.HealthPolicy,
+		stop <-chan struct{}) {
+		adminKeys <- key
+		track("cloud", stop)
+	}
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.PrivateIP = "10.0.0.1"
+		view.Commit(self)
+
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+		return nil
+	})
+
+	// The cluster hasn't been handed off yet, so the daemon still manages it.
+	stop := updateControlPlane(conn, nil, nil)
+	assert.Nil(t, stop)
+
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		view.Commit(bp)
+		return nil
+	})
+
+	// Reading the secrets failed.
+	store.On("Read", "kelda-control-plane/amazon-credentials").Return(
+		"", errors.New("err"))
+	store.On("Read", "kelda-control-plane/google-credentials").Return(
+		"", vault.ErrSecretDoesNotExist)
+	store.On("Read", "kelda-control-plane/digitalocean-credentials").Return(
+		"", vault.ErrSecretDoesNotExist)
+	store.On("Read", "kelda-control-plane/openstack-credentials").Return(
+		"", vault.ErrSecretDoesNotExist)
+	store.On("Read", "kelda-control-plane/static-hosts").Return(
+		"", vault.ErrSecretDoesNotExist)
+	stop = updateControlPlane(conn, nil, nil)
+	assert.Nil(t, stop)
+	assert.Empty(t, started)
+
+	sshKey, sshKeyPEM := newTestSSHKey(t)
+	ca, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	store.ExpectedCalls = nil
+	store.On("Read", "kelda-control-plane/amazon-credentials").Return("aws", nil)
+	store.On("Read", "kelda-control-plane/google-credentials").Return(
+		"", vault.ErrSecretDoesNotExist)
+	store.On("Read", "kelda-control-plane/digitalocean-credentials").Return(
+		"do", nil)
+	store.On("Read", "kelda-control-plane/openstack-credentials").Return(
+		"", vault.ErrSecretDoesNotExist)
+	store.On("Read", "kelda-control-plane/static-hosts").Return(
+		"", vault.ErrSecretDoesNotExist)
+	store.On("Read", cloud.SSHKeySecret).Return(sshKeyPEM, nil)
+	store.On("Read", cloud.CACertSecret).Return(ca.CertString(), nil)
+	store.On("Read", cloud.CAKeySecret).Return(ca.PrivateKeyString(), nil)
+
+	stop = updateControlPlane(conn, nil, nil)
+	assert.NotNil(t, stop)
+	assert.Equal(t, []string{"cloud", "credentials", "foreman"},
+		receive(t, started, 3))
+	assert.Equal(t, strings.TrimSpace(string(
+		ssh.MarshalAuthorizedKey(sshKey.PublicKey()))), <-adminKeys)
+
+	home := os.Getenv("HOME")
+	awsCreds, err := util.ReadFile(filepath.Join(home, ".aws/credentials"))
+	assert.NoError(t, err)
+	assert.Equal(t, "aws", awsCreds)
+	doCreds, err := util.ReadFile(filepath.Join(home, ".digitalocean/key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "do", doCreds)
+
+	// Nothing changes while this minion remains the leader.
+	assert.Equal(t, stop, updateControlPlane(conn, nil, stop))
+	assert.Empty(t, started)
+
+	// Another master was elected, so it takes over.
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.Leader = false
+		view.Commit(etcd)
+		return nil
+	})
+	stop = updateControlPlane(conn, nil, stop)
+	assert.Nil(t, stop)
+	assert.Equal(t, []string{"cloud", "credentials", "foreman"},
+		receive(t, stopped, 3))
+}
+
+// receive returns, sorted, the first `n` names sent on `names` by the control
+// plane's goroutines.
+func receive(t *testing.T, names chan string, n int) []string {
+	var received []string
+	for len(received) < n {
+		select {
+		case name := <-names:
+			received = append(received, name)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	sort.Strings(received)
+	return received
+}
+
+func newTestSSHKey(t *testing.T) (ssh.Signer, string) {
+	key, err := goRSA.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+	signer, err := ssh.ParsePrivateKey([]byte(keyPEM))
+	assert.NoError(t, err)
+	return signer, keyPEM
+}
@@ -1,10 +1,13 @@
 package docker
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +19,7 @@ import (
 
 	dkc "github.com/fsouza/go-dockerclient"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
 )
 
 var pullCacheTimeout = time.Minute
@@ -47,6 +51,49 @@ type Container struct {
 // ContainerSlice is an alias for []Container to allow for joins
 type ContainerSlice []Container
 
+// A LogLine is a single line written by a container to stdout or stderr.
+type LogLine struct {
+	Time   time.Time
+	Stderr bool
+	Text   string
+}
+
+// LogsOptions changes the behavior of the Logs function.
+type LogsOptions struct {
+	Context context.Context
+	Follow  bool
+
+	// If non-zero, only lines logged in the window [Since, Until] are returned.
+	Since time.Time
+	Until time.Time
+
+	// If greater than zero, only the last Tail lines are returned.
+	Tail int
+}
+
+// A TerminalSize is the dimensions of a pseudo-terminal, in characters.
+type TerminalSize struct {
+	Height int
+	Width  int
+}
+
+// ExecOptions changes the behavior of the Exec function.
+type ExecOptions struct {
+	Context context.Context
+
+	// If Tty is true, the command is run in a pseudo-terminal, and everything
+	// it writes is sent to Stdout.
+	Tty bool
+
+	// If Stdin is nil, the command's stdin is closed immediately.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// The pseudo-terminal is resized to each size received on Resize.
+	Resize <-chan TerminalSize
+}
+
 // A Client to the local docker daemon.
 type Client struct {
 	client
@@ -94,6 +141,11 @@ type client interface {
 	CreateContainer(dkc.CreateContainerOptions) (*dkc.Container, error)
 	CreateNetwork(dkc.CreateNetworkOptions) (*dkc.Network, error)
 	ListNetworks() ([]dkc.Network, error)
+	Logs(opts dkc.LogsOptions) error
+	CreateExec(opts dkc.CreateExecOptions) (*dkc.Exec, error)
+	StartExec(id string, opts dkc.StartExecOptions) error
+	ResizeExecTTY(id string, height, width int) error
+	InspectExec(id string) (*dkc.ExecInspect, error)
 }
 
 var c = counter.New("Docker")
@@ -118,6 +170,7 @@ func New(sock string) Client {
 // Run creates and starts a new container in accordance RunOptions.
 func (dk Client) Run(opts RunOptions) (string, error) {
 	c.Inc("Run")
+	defer c.ObserveSince("Run", time.Now())
 
 	env := []string{}
 	for k, v := range opts.Env {
@@ -142,6 +195,7 @@ func (dk Client) Run(opts RunOptions) (string, error) {
 				plugin.NetworkName: {
 					IPAMConfig: &dkc.EndpointIPAMConfig{
 						IPv4Address: opts.IP,
+						IPv6Address: ipdef.IPv6Str(opts.IP),
 					},
 				},
 			},
@@ -175,15 +229,22 @@ func (dk Client) ConfigureNetwork(driver string) error {
 		}
 	}
 
+	ipam := []dkc.IPAMConfig{{
+		Subnet:  ipdef.KeldaSubnet.String(),
+		Gateway: ipdef.GatewayIP.String(),
+	}}
+	if ipdef.IPv6Enabled() {
+		ipam = append(ipam, dkc.IPAMConfig{
+			Subnet:  ipdef.KeldaSubnet6.String(),
+			Gateway: ipdef.GatewayIP6.String(),
+		})
+	}
+
 	_, err = dk.CreateNetwork(dkc.CreateNetworkOptions{
-		Name:   driver,
-		Driver: driver,
-		IPAM: dkc.IPAMOptions{
-			Config: []dkc.IPAMConfig{{
-				Subnet:  ipdef.KeldaSubnet.String(),
-				Gateway: ipdef.GatewayIP.String(),
-			}},
-		},
+		Name:       driver,
+		Driver:     driver,
+		EnableIPv6: ipdef.IPv6Enabled(),
+		IPAM:       dkc.IPAMOptions{Config: ipam},
 	})
 
 	return err
@@ -202,6 +263,8 @@ func (dk Client) Remove(name string) error {
 // RemoveID stops and deletes the container with the given ID.
 func (dk Client) RemoveID(id string) error {
 	c.Inc("Remove")
+	defer c.ObserveSince("Remove", time.Now())
+
 	err := dk.RemoveContainer(dkc.RemoveContainerOptions{ID: id, Force: true})
 	if err != nil {
 		return err
@@ -214,6 +277,8 @@ func (dk Client) RemoveID(id string) error {
 // ID of the resulting image.
 func (dk Client) Build(name, dockerfile string, useCache bool) (id string, err error) {
 	c.Inc("Build")
+	defer c.ObserveSince("Build", time.Now())
+
 	tarBuf, err := util.ToTar("Dockerfile", 0644, dockerfile)
 	if err != nil {
 		return "", err
@@ -244,6 +309,8 @@ func (dk Client) Build(name, dockerfile string, useCache bool) (id string, err e
 // If no tag is specified, then the "latest" tag is applied.
 func (dk Client) Pull(image string) error {
 	c.Inc("Pull")
+	defer c.ObserveSince("Pull", time.Now())
+
 	repo, tag := dkc.ParseRepositoryTag(image)
 	if tag == "" {
 		tag = "latest"
@@ -287,6 +354,8 @@ func (dk Client) getCacheEntry(repo, tag string) *cacheEntry {
 // Push pushes the given image to the registry.
 func (dk Client) Push(registry, image string) error {
 	c.Inc("Push")
+	defer c.ObserveSince("Push", time.Now())
+
 	repo, tag := dkc.ParseRepositoryTag(image)
 	return dk.PushImage(dkc.PushImageOptions{
 		Registry: registry,
@@ -299,6 +368,8 @@ func (dk Client) Push(registry, image string) error {
 // supplied `filters` map.
 func (dk Client) List(filters map[string][]string) ([]Container, error) {
 	c.Inc("List")
+	defer c.ObserveSince("List", time.Now())
+
 	return dk.list(filters, false)
 }
 
@@ -324,9 +395,211 @@ func (dk Client) list(filters map[string][]string, all bool) ([]Container, error
 	return containers, nil
 }
 
+// Logs calls `handle` on each line logged by the container with the given ID
+// or name, in the order they were logged.  If `handle` returns an error, Logs
+// stops and returns it.
+func (dk Client) Logs(id string, opts LogsOptions, handle func(LogLine) error) error {
+	c.Inc("Logs")
+
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var lock sync.Mutex
+	var handleErr error
+
+	// Whether stderr, or stdout, has logged a line after Until.  The streams
+	// are parsed concurrently, so one stream may pass Until while lines that
+	// the other logged before Until are still being parsed.
+	pastUntil := map[bool]bool{}
+	parseLines := func(r io.Reader, stderr bool) {
+		reader := bufio.NewReader(r)
+		for {
+			text, err := reader.ReadString('\n')
+			if text != "" {
+				line, parseErr := parseLogLine(text, stderr)
+				if parseErr != nil {
+					log.WithError(parseErr).Warn(
+						"Failed to parse log line")
+					continue
+				}
+
+				lock.Lock()
+				switch {
+				case handleErr != nil || pastUntil[stderr]:
+				case line.Time.Before(opts.Since):
+					// Docker truncates Since to whole seconds.
+				case !opts.Until.IsZero() && line.Time.After(opts.Until):
+					// Docker doesn't support an upper bound on
+					// the logs, so we stop once we see a line
+					// logged after Until.  Lines that Docker
+					// already sent on the other stream are still
+					// handled.
+					pastUntil[stderr] = true
+					cancel()
+				default:
+					if handleErr = handle(line); handleErr != nil {
+						cancel()
+					}
+				}
+				lock.Unlock()
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		parseLines(stdoutReader, false)
+		io.Copy(ioutil.Discard, stdoutReader)
+		wg.Done()
+	}()
+	go func() {
+		parseLines(stderrReader, true)
+		io.Copy(ioutil.Discard, stderrReader)
+		wg.Done()
+	}()
+
+	logsOpts := dkc.LogsOptions{
+		Context:      ctx,
+		Container:    id,
+		OutputStream: stdoutWriter,
+		ErrorStream:  stderrWriter,
+		Follow:       opts.Follow,
+		Stdout:       true,
+		Stderr:       true,
+		Timestamps:   true,
+	}
+	if !opts.Since.IsZero() {
+		logsOpts.Since = opts.Since.Unix()
+	}
+	if opts.Tail > 0 {
+		logsOpts.Tail = strconv.Itoa(opts.Tail)
+	}
+
+	err := dk.client.Logs(logsOpts)
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	wg.Wait()
+
+	switch {
+	case handleErr != nil:
+		return handleErr
+	case pastUntil[false] || pastUntil[true]:
+		return nil
+	}
+	return err
+}
+
+// Exec runs `cmd` in the container with the given ID or name, and returns its
+// exit code once it exits.
+func (dk Client) Exec(id string, cmd []string, opts ExecOptions) (int, error) {
+	c.Inc("Exec")
+
+	exec, err := dk.CreateExec(dkc.CreateExecOptions{
+		Context:      opts.Context,
+		Container:    id,
+		Cmd:          cmd,
+		Tty:          opts.Tty,
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	stdout, stderr := opts.Stdout, opts.Stderr
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	if stderr == nil {
+		stderr = ioutil.Discard
+	}
+
+	// Docker only accepts resize requests once the command is running, which
+	// StartExec signals over `success`.
+	success := make(chan struct{})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	defer wg.Wait()
+	defer close(done)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-success:
+		case <-done:
+			return
+		}
+		success <- struct{}{}
+
+		for {
+			select {
+			case size := <-opts.Resize:
+				err := dk.ResizeExecTTY(exec.ID, size.Height, size.Width)
+				if err != nil {
+					log.WithError(err).Debug(
+						"Failed to resize exec TTY")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = dk.StartExec(exec.ID, dkc.StartExecOptions{
+		Context:      opts.Context,
+		InputStream:  opts.Stdin,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		Tty:          opts.Tty,
+		RawTerminal:  opts.Tty,
+		Success:      success,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	inspect, err := dk.InspectExec(exec.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
+
+// parseLogLine parses a line of Docker logs that were requested with
+// timestamps, e.g. "2017-10-18T17:55:00.123456789Z foo".
+func parseLogLine(text string, stderr bool) (LogLine, error) {
+	text = strings.TrimSuffix(text, "\n")
+	parts := strings.SplitN(text, " ", 2)
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return LogLine{}, err
+	}
+
+	line := LogLine{Time: timestamp, Stderr: stderr}
+	if len(parts) == 2 {
+		line.Text = parts[1]
+	}
+	return line, nil
+}
+
 // Get returns a Container corresponding to the supplied ID.
 func (dk Client) Get(id string) (Container, error) {
 	c.Inc("Get")
+	defer c.ObserveSince("Get", time.Now())
+
 	dkc, err := dk.InspectContainer(id)
 	if err != nil {
 		return Container{}, err
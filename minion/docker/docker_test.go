@@ -1,6 +1,8 @@
 package docker
 
 import (
+	"bytes"
+	"io"
 	"testing"
 	"time"
 
@@ -288,6 +290,32 @@ func TestConfigureNetwork(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestIPv6(t *testing.T) {
+	defaultNetwork, _ := ipdef.ParseContainerNetwork("", "", "", "")
+	network, _ := ipdef.ParseContainerNetwork("", "", "", "fd00::/64")
+	ipdef.Configure(network)
+	defer ipdef.Configure(defaultNetwork)
+
+	md, dk := NewMock()
+	assert.NoError(t, dk.ConfigureNetwork("kelda"))
+	exp := &dkc.Network{
+		Name:       "kelda",
+		Driver:     "kelda",
+		EnableIPv6: true,
+		IPAM: dkc.IPAMOptions{
+			Config: []dkc.IPAMConfig{
+				{Subnet: "10.0.0.0/8", Gateway: "10.0.0.1"},
+				{Subnet: "fd00::/64", Gateway: "fd00::1"}}}}
+	assert.Equal(t, exp, md.Networks["kelda"])
+
+	id, err := dk.Run(RunOptions{Name: "name", IP: "10.0.0.5"})
+	assert.NoError(t, err)
+	container, err := md.InspectContainer(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", container.NetworkSettings.IPAddress)
+	assert.Equal(t, "fd00::5", container.NetworkSettings.GlobalIPv6Address)
+}
+
 func TestRemove(t *testing.T) {
 	t.Parallel()
 	md, dk := NewMock()
@@ -371,3 +399,139 @@ func cacheKeys(cache map[string]*cacheEntry) map[string]struct{} {
 	}
 	return res
 }
+
+func TestLogs(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	md.StdoutLogs["id"] = "2017-10-18T17:55:00.000000001Z foo\n" +
+		"2017-10-18T17:56:00Z bar baz\n" +
+		"2017-10-18T17:58:00Z qux"
+	md.StderrLogs["id"] = "2017-10-18T17:57:00Z error\n" + "malformed\n"
+
+	var lines []LogLine
+	collect := func(l LogLine) error {
+		lines = append(lines, l)
+		return nil
+	}
+
+	since := time.Date(2017, 10, 18, 17, 50, 0, 0, time.UTC)
+	err := dk.Logs("id", LogsOptions{Follow: true, Since: since, Tail: 10}, collect)
+	assert.NoError(t, err)
+
+	// Lines are only ordered within a stream.
+	byTime := map[time.Time]LogLine{}
+	for _, l := range lines {
+		byTime[l.Time] = l
+	}
+	assert.Len(t, lines, 4)
+	assert.Equal(t, LogLine{
+		Time: time.Date(2017, 10, 18, 17, 55, 0, 1, time.UTC),
+		Text: "foo",
+	}, byTime[time.Date(2017, 10, 18, 17, 55, 0, 1, time.UTC)])
+	assert.Equal(t, "bar baz",
+		byTime[time.Date(2017, 10, 18, 17, 56, 0, 0, time.UTC)].Text)
+	assert.Equal(t, LogLine{
+		Time:   time.Date(2017, 10, 18, 17, 57, 0, 0, time.UTC),
+		Stderr: true,
+		Text:   "error",
+	}, byTime[time.Date(2017, 10, 18, 17, 57, 0, 0, time.UTC)])
+	assert.Equal(t, "qux",
+		byTime[time.Date(2017, 10, 18, 17, 58, 0, 0, time.UTC)].Text)
+
+	req := md.LogRequests["id"]
+	assert.True(t, req.Follow)
+	assert.True(t, req.Timestamps)
+	assert.Equal(t, since.Unix(), req.Since)
+	assert.Equal(t, "10", req.Tail)
+
+	// Lines logged after Until should be dropped.
+	lines = nil
+	until := time.Date(2017, 10, 18, 17, 56, 30, 0, time.UTC)
+	md.StderrLogs["id"] = ""
+	assert.NoError(t, dk.Logs("id", LogsOptions{Until: until}, collect))
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "", md.LogRequests["id"].Tail)
+
+	// A stream passing Until doesn't drop the other stream's earlier lines.
+	lines = nil
+	until = time.Date(2017, 10, 18, 17, 57, 30, 0, time.UTC)
+	md.StdoutLogs["id"] = "2017-10-18T17:56:00Z bar\n2017-10-18T17:58:00Z qux\n"
+	md.StderrLogs["id"] = "2017-10-18T17:57:00Z error\n2017-10-18T17:59:00Z late\n"
+	assert.NoError(t, dk.Logs("id", LogsOptions{Until: until}, collect))
+	var texts []string
+	for _, l := range lines {
+		texts = append(texts, l.Text)
+	}
+	assert.Len(t, texts, 2)
+	assert.Contains(t, texts, "bar")
+	assert.Contains(t, texts, "error")
+
+	// Docker only takes Since in whole seconds, so earlier lines within the
+	// second are dropped.
+	lines = nil
+	since = time.Date(2017, 10, 18, 17, 56, 0, 500, time.UTC)
+	md.StdoutLogs["id"] = "2017-10-18T17:56:00.000000499Z early\n" +
+		"2017-10-18T17:56:00.0000005Z on time\n"
+	md.StderrLogs["id"] = ""
+	assert.NoError(t, dk.Logs("id", LogsOptions{Since: since}, collect))
+	assert.Equal(t, []LogLine{{Time: since, Text: "on time"}}, lines)
+	assert.Equal(t, since.Unix(), md.LogRequests["id"].Since)
+
+	// Errors returned by the handler should be propagated.
+	err = dk.Logs("id", LogsOptions{}, func(LogLine) error {
+		return assert.AnError
+	})
+	assert.Equal(t, assert.AnError, err)
+
+	md.LogsError = true
+	assert.EqualError(t, dk.Logs("id", LogsOptions{}, collect), "logs error")
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	id, err := dk.Run(RunOptions{Name: "name"})
+	assert.NoError(t, err)
+
+	md.ExecStdout[id] = "out"
+	md.ExecStderr[id] = "err"
+	md.ExecExitCodes[id] = 3
+
+	var stdout, stderr bytes.Buffer
+	stdinReader, stdinWriter := io.Pipe()
+	resize := make(chan TerminalSize)
+	go func() {
+		resize <- TerminalSize{Height: 24, Width: 80}
+		// The second resize is only received once the first was handled.
+		resize <- TerminalSize{Height: 48, Width: 160}
+		stdinWriter.Write([]byte("in"))
+		stdinWriter.Close()
+	}()
+
+	exitCode, err := dk.Exec(id, []string{"sh", "-c", "exit 3"}, ExecOptions{
+		Tty:    true,
+		Stdin:  stdinReader,
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Resize: resize,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "out", stdout.String())
+	assert.Equal(t, "err", stderr.String())
+
+	md.Lock()
+	assert.Equal(t, []string{"sh -c exit 3"}, md.Executions[id])
+	assert.Equal(t, "in", md.ExecStdin[id])
+	assert.Contains(t, md.ExecResizes[id], TerminalSize{Height: 24, Width: 80})
+	md.Unlock()
+
+	md.StartExecError = true
+	_, err = dk.Exec(id, []string{"ls"}, ExecOptions{})
+	assert.EqualError(t, err, "start exec error")
+
+	_, err = dk.Exec("missing", []string{"ls"}, ExecOptions{})
+	assert.EqualError(t, err, "unknown container")
+}
@@ -45,6 +45,19 @@ type MockClient struct {
 	createdExecs map[string]dkc.CreateExecOptions
 	Executions   map[string][]string
 
+	// Maps container IDs to the output and exit code of the commands executed
+	// in them, and to the input and terminal sizes the commands received.
+	ExecStdout    map[string]string
+	ExecStderr    map[string]string
+	ExecExitCodes map[string]int
+	ExecStdin     map[string]string
+	ExecResizes   map[string][]TerminalSize
+
+	// Maps container IDs to the raw log output returned by Logs.
+	StdoutLogs  map[string]string
+	StderrLogs  map[string]string
+	LogRequests map[string]dkc.LogsOptions
+
 	CreateError           bool
 	CreateNetworkError    bool
 	ListNetworksError     bool
@@ -52,6 +65,7 @@ type MockClient struct {
 	InspectContainerError bool
 	InspectImageError     bool
 	ListError             bool
+	LogsError             bool
 	BuildError            bool
 	PullError             bool
 	PushError             bool
@@ -75,6 +89,15 @@ func NewMock() (*MockClient, Client) {
 		Images:       map[string]*dkc.Image{},
 		createdExecs: map[string]dkc.CreateExecOptions{},
 		Executions:   map[string][]string{},
+
+		ExecStdout:    map[string]string{},
+		ExecStderr:    map[string]string{},
+		ExecExitCodes: map[string]int{},
+		ExecStdin:     map[string]string{},
+		ExecResizes:   map[string][]TerminalSize{},
+		StdoutLogs:    map[string]string{},
+		StderrLogs:    map[string]string{},
+		LogRequests:   map[string]dkc.LogsOptions{},
 	}
 	return md, Client{md, &sync.Mutex{}, map[string]*cacheEntry{}}
 }
@@ -233,6 +256,26 @@ func (dk MockClient) ListContainers(opts dkc.ListContainersOptions) ([]dkc.APICo
 	return apics, nil
 }
 
+// Logs writes the mocked logs of the requested container to the output streams.
+func (dk MockClient) Logs(opts dkc.LogsOptions) error {
+	dk.Lock()
+	if dk.LogsError {
+		dk.Unlock()
+		return errors.New("logs error")
+	}
+
+	dk.LogRequests[opts.Container] = opts
+	stdout := dk.StdoutLogs[opts.Container]
+	stderr := dk.StderrLogs[opts.Container]
+	dk.Unlock()
+
+	if _, err := io.WriteString(opts.OutputStream, stdout); err != nil {
+		return err
+	}
+	_, err := io.WriteString(opts.ErrorStream, stderr)
+	return err
+}
+
 // CreateNetwork creates a network according to opts.
 func (dk MockClient) CreateNetwork(opts dkc.CreateNetworkOptions) (*dkc.Network, error) {
 	dk.Lock()
@@ -243,9 +286,10 @@ func (dk MockClient) CreateNetwork(opts dkc.CreateNetworkOptions) (*dkc.Network,
 	}
 
 	network := &dkc.Network{
-		Name:   opts.Name,
-		Driver: opts.Driver,
-		IPAM:   opts.IPAM,
+		Name:       opts.Name,
+		Driver:     opts.Driver,
+		EnableIPv6: opts.EnableIPv6,
+		IPAM:       opts.IPAM,
 	}
 	dk.Networks[opts.Driver] = network
 	return network, nil
@@ -310,10 +354,11 @@ func (dk *MockClient) CreateContainer(opts dkc.CreateContainerOptions) (*dkc.Con
 
 	id := uuid.NewV4().String()
 
-	var ip string
+	var ip, ip6 string
 	if opts.NetworkingConfig != nil {
-		ip = opts.NetworkingConfig.EndpointsConfig[plugin.NetworkName].
-			IPAMConfig.IPv4Address
+		ipam := opts.NetworkingConfig.EndpointsConfig[plugin.NetworkName].
+			IPAMConfig
+		ip, ip6 = ipam.IPv4Address, ipam.IPv6Address
 	}
 	container := &dkc.Container{
 		ID:         id,
@@ -322,7 +367,8 @@ func (dk *MockClient) CreateContainer(opts dkc.CreateContainerOptions) (*dkc.Con
 		Config:     opts.Config,
 		HostConfig: opts.HostConfig,
 		NetworkSettings: &dkc.NetworkSettings{
-			IPAddress: ip,
+			IPAddress:         ip,
+			GlobalIPv6Address: ip6,
 		},
 	}
 	if img, ok := dk.Images[image]; ok {
@@ -350,21 +396,80 @@ func (dk MockClient) CreateExec(opts dkc.CreateExecOptions) (*dkc.Exec, error) {
 	return &dkc.Exec{ID: id}, nil
 }
 
-// StartExec starts the supplied execution object.
+// StartExec starts the supplied execution object.  The command's input is
+// recorded in ExecStdin, and its output is read from ExecStdout and ExecStderr.
 func (dk MockClient) StartExec(id string, opts dkc.StartExecOptions) error {
 	dk.Lock()
-	defer dk.Unlock()
-
 	if dk.StartExecError {
+		dk.Unlock()
 		return errors.New("start exec error")
 	}
 
 	exec, _ := dk.createdExecs[id]
 	dk.Executions[exec.Container] = append(dk.Executions[exec.Container],
 		strings.Join(exec.Cmd, " "))
+	stdout := dk.ExecStdout[exec.Container]
+	stderr := dk.ExecStderr[exec.Container]
+	dk.Unlock()
+
+	if opts.Success != nil {
+		opts.Success <- struct{}{}
+		<-opts.Success
+	}
+
+	if opts.InputStream != nil {
+		stdin, err := ioutil.ReadAll(opts.InputStream)
+		if err != nil {
+			return err
+		}
+
+		dk.Lock()
+		dk.ExecStdin[exec.Container] += string(stdin)
+		dk.Unlock()
+	}
+
+	if opts.OutputStream != nil {
+		if _, err := io.WriteString(opts.OutputStream, stdout); err != nil {
+			return err
+		}
+	}
+	if opts.ErrorStream != nil {
+		if _, err := io.WriteString(opts.ErrorStream, stderr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResizeExecTTY records the new size of the supplied execution's terminal.
+func (dk MockClient) ResizeExecTTY(id string, height, width int) error {
+	dk.Lock()
+	defer dk.Unlock()
+
+	exec, ok := dk.createdExecs[id]
+	if !ok {
+		return errors.New("unknown exec")
+	}
+
+	dk.ExecResizes[exec.Container] = append(dk.ExecResizes[exec.Container],
+		TerminalSize{Height: height, Width: width})
 	return nil
 }
 
+// InspectExec returns the exit code of the supplied execution.
+func (dk MockClient) InspectExec(id string) (*dkc.ExecInspect, error) {
+	dk.Lock()
+	defer dk.Unlock()
+
+	exec, ok := dk.createdExecs[id]
+	if !ok {
+		return nil, errors.New("unknown exec")
+	}
+
+	return &dkc.ExecInspect{ID: id, ContainerID: exec.Container,
+		ExitCode: dk.ExecExitCodes[exec.Container]}, nil
+}
+
 // ResetExec clears the list of created and started executions, for use by the unit
 // tests.
 func (dk *MockClient) ResetExec() {
@@ -57,7 +57,9 @@ func portPlacements(connections []db.Connection, containers []db.Container) (
 
 	ports := make(map[int][]string)
 	for _, conn := range connections {
-		if !str.SliceContains(conn.From, blueprint.PublicInternetLabel) {
+		// ICMP connections don't use ports, so they can't conflict.
+		if !str.SliceContains(conn.From, blueprint.PublicInternetLabel) ||
+			conn.Protocol == blueprint.ICMP {
 			continue
 		}
 
@@ -195,10 +197,11 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 		for _, to := range c.To {
 			if lb, ok := loadBalancers[to]; ok {
 				scs = append(scs, blueprint.Connection{
-					From:    c.From,
-					To:      lb.Hostnames,
-					MinPort: c.MinPort,
-					MaxPort: c.MaxPort,
+					From:     c.From,
+					To:       lb.Hostnames,
+					MinPort:  c.MinPort,
+					MaxPort:  c.MaxPort,
+					Protocol: c.Protocol,
 				})
 			}
 		}
@@ -206,12 +209,14 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 
 	dbcKey := func(val interface{}) interface{} {
 		c := val.(db.Connection)
-		return fmt.Sprintf("%s %s %d %d", c.From, c.To, c.MinPort, c.MaxPort)
+		return fmt.Sprintf("%s %s %d %d %s", c.From, c.To, c.MinPort, c.MaxPort,
+			c.Protocol)
 	}
 
 	bpKey := func(val interface{}) interface{} {
 		c := val.(blueprint.Connection)
-		return fmt.Sprintf("%s %s %d %d", c.From, c.To, c.MinPort, c.MaxPort)
+		return fmt.Sprintf("%s %s %d %d %s", c.From, c.To, c.MinPort, c.MaxPort,
+			c.Protocol)
 	}
 
 	vcs := view.SelectFromConnection(nil)
@@ -235,6 +240,7 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 		dbc.To = blueprintc.To
 		dbc.MinPort = blueprintc.MinPort
 		dbc.MaxPort = blueprintc.MaxPort
+		dbc.Protocol = blueprintc.Protocol
 		view.Commit(dbc)
 	}
 }
@@ -299,6 +299,18 @@ func TestConnectionTxn(t *testing.T) {
 	testConnectionTxn(t, conn, bp)
 	assert.False(t, fired(trigg))
 
+	// Changing only the protocol replaces the connection.
+	bp.Connections = []blueprint.Connection{
+		{From: []string{"b"}, To: []string{"a"}, MinPort: 90, MaxPort: 90,
+			Protocol: blueprint.TCP},
+		{From: []string{"b"}, To: []string{"a"}, Protocol: blueprint.ICMP},
+	}
+	testConnectionTxn(t, conn, bp)
+	assert.True(t, fired(trigg))
+
+	testConnectionTxn(t, conn, bp)
+	assert.False(t, fired(trigg))
+
 	bp.Connections = nil
 	testConnectionTxn(t, conn, bp)
 	assert.True(t, fired(trigg))
@@ -320,7 +332,8 @@ func testConnectionTxn(t *testing.T, conn db.Conn, bp blueprint.Blueprint) {
 		found := false
 		for i, c := range connections {
 			if str.SliceEq(e.From, c.From) && str.SliceEq(e.To, c.To) &&
-				e.MinPort == c.MinPort && e.MaxPort == c.MaxPort {
+				e.MinPort == c.MinPort && e.MaxPort == c.MaxPort &&
+				e.Protocol == c.Protocol {
 				connections = append(
 					connections[:i], connections[i+1:]...)
 				found = true
@@ -423,6 +436,15 @@ func TestPlacementTxn(t *testing.T) {
 	}
 	checkPlacement(bp)
 
+	// ICMP connections have no ports, so they don't conflict.
+	bp.Connections = []blueprint.Connection{
+		{From: []string{blueprint.PublicInternetLabel},
+			To: []string{fooHostname}, Protocol: blueprint.ICMP},
+		{From: []string{blueprint.PublicInternetLabel},
+			To: []string{barHostname}, Protocol: blueprint.ICMP},
+	}
+	checkPlacement(bp)
+
 	bp.Connections = []blueprint.Connection{
 		{From: []string{blueprint.PublicInternetLabel},
 			To: []string{fooHostname}, MinPort: 80, MaxPort: 80},
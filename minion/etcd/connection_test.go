@@ -75,4 +75,18 @@ func TestRunConnectionOnce(t *testing.T) {
 	conns[0].ID = 0
 	assert.Equal(t, db.Connection{From: []string{"a"}, To: []string{"b"},
 		MinPort: 80, MaxPort: 8080}, conns[0])
+
+	// Connections that differ only in their protocol are replaced.
+	err = store.Set(connectionPath, `[{"From": ["a"], "To": ["b"], `+
+		`"MinPort": 80, "MaxPort": 8080, "Protocol": "udp"}]`, 0)
+	assert.NoError(t, err)
+
+	err = runConnectionOnce(conn, store)
+	assert.NoError(t, err)
+
+	conns = conn.SelectFromConnection(nil)
+	assert.Len(t, conns, 1)
+	conns[0].ID = 0
+	assert.Equal(t, db.Connection{From: []string{"a"}, To: []string{"b"},
+		MinPort: 80, MaxPort: 8080, Protocol: "udp"}, conns[0])
 }
@@ -0,0 +1,121 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/join"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The blueprint and machines of a cluster that manages itself are written to
+// Etcd by the leader, so that whichever master is elected next can pick up
+// where it left off.
+const (
+	blueprintPath = "/blueprints"
+	machinePath   = "/machines"
+)
+
+func runControlPlane(conn db.Conn, store Store) {
+	etcdWatch := util.JoinNotifiers(store.Watch(blueprintPath, 1*time.Second),
+		store.Watch(machinePath, 1*time.Second))
+	trigg := conn.TriggerTick(60, db.BlueprintTable, db.MachineTable)
+	for range util.JoinNotifiers(trigg.C, etcdWatch) {
+		if err := runControlPlaneOnce(conn, store); err != nil {
+			log.WithError(err).Warn("Failed to sync control plane with Etcd")
+		}
+	}
+}
+
+func runControlPlaneOnce(conn db.Conn, store Store) error {
+	etcdBlueprints, err := readEtcdNode(store, blueprintPath)
+	if err != nil {
+		return fmt.Errorf("etcd read error: %s", err)
+	}
+
+	etcdMachines, err := readEtcdNode(store, machinePath)
+	if err != nil {
+		return fmt.Errorf("etcd read error: %s", err)
+	}
+
+	if conn.EtcdLeader() {
+		c.Inc("Run Control Plane Leader")
+		blueprints := db.BlueprintSlice(conn.SelectFromBlueprint(nil))
+		err := writeEtcdSlice(store, blueprintPath, etcdBlueprints, blueprints)
+		if err != nil {
+			return fmt.Errorf("etcd write error: %s", err)
+		}
+
+		machines := db.MachineSlice(conn.SelectFromMachine(nil))
+		err = writeEtcdSlice(store, machinePath, etcdMachines, machines)
+		if err != nil {
+			return fmt.Errorf("etcd write error: %s", err)
+		}
+	} else {
+		c.Inc("Run Control Plane Worker")
+		var blueprints []db.Blueprint
+		json.Unmarshal([]byte(etcdBlueprints), &blueprints)
+
+		var machines []db.Machine
+		json.Unmarshal([]byte(etcdMachines), &machines)
+
+		conn.Txn(db.BlueprintTable, db.MachineTable).Run(
+			func(view db.Database) error {
+				joinBlueprints(view, blueprints)
+				joinMachines(view, machines)
+				return nil
+			})
+	}
+
+	return nil
+}
+
+func joinBlueprints(view db.Database, etcdBlueprints []db.Blueprint) {
+	// Blueprints contain slices and maps, so they can't be used as keys
+	// directly.
+	key := func(iface interface{}) interface{} {
+		bp := iface.(db.Blueprint)
+		bp.ID = 0
+		str, _ := json.Marshal(bp)
+		return string(str)
+	}
+	_, dbIfaces, etcdIfaces := join.HashJoin(
+		db.BlueprintSlice(view.SelectFromBlueprint(nil)),
+		db.BlueprintSlice(etcdBlueprints), key, key)
+
+	for _, iface := range dbIfaces {
+		view.Remove(iface.(db.Blueprint))
+	}
+
+	for _, iface := range etcdIfaces {
+		etcdBlueprint := iface.(db.Blueprint)
+		etcdBlueprint.ID = view.InsertBlueprint().ID
+		view.Commit(etcdBlueprint)
+	}
+}
+
+func joinMachines(view db.Database, etcdMachines []db.Machine) {
+	key := func(iface interface{}) interface{} {
+		m := iface.(db.Machine)
+		m.ID = 0
+		str, _ := json.Marshal(m)
+		return string(str)
+	}
+	_, dbIfaces, etcdIfaces := join.HashJoin(
+		db.MachineSlice(view.SelectFromMachine(nil)),
+		db.MachineSlice(etcdMachines), key, key)
+
+	for _, iface := range dbIfaces {
+		view.Remove(iface.(db.Machine))
+	}
+
+	for _, iface := range etcdIfaces {
+		etcdMachine := iface.(db.Machine)
+		etcdMachine.ID = view.InsertMachine().ID
+		view.Commit(etcdMachine)
+	}
+}
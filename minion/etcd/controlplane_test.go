@@ -0,0 +1,103 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunControlPlaneOnce(t *testing.T) {
+	t.Parallel()
+
+	store := newTestMock()
+	conn := db.New()
+
+	err := runControlPlaneOnce(conn, store)
+	assert.Error(t, err)
+
+	assert.NoError(t, store.Set(blueprintPath, "", 0))
+	err = runControlPlaneOnce(conn, store)
+	assert.Error(t, err)
+
+	assert.NoError(t, store.Set(machinePath, "", 0))
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+
+		bp := view.InsertBlueprint()
+		bp.Blueprint = blueprint.Blueprint{
+			Namespace: "ns",
+			AdminACL:  []string{"1.2.3.4/32"},
+		}
+		view.Commit(bp)
+
+		m := view.InsertMachine()
+		m.Namespace = "ns"
+		m.Role = db.Master
+		m.CloudID = "id"
+		m.SSHKeys = []string{"key"}
+		view.Commit(m)
+		return nil
+	})
+	expBlueprints := conn.SelectFromBlueprint(nil)
+	expMachines := conn.SelectFromMachine(nil)
+
+	err = runControlPlaneOnce(conn, store)
+	assert.NoError(t, err)
+
+	str, err := store.Get(blueprintPath)
+	assert.NoError(t, err)
+	assert.Contains(t, str, `"Namespace": "ns"`)
+
+	str, err = store.Get(machinePath)
+	assert.NoError(t, err)
+	assert.Contains(t, str, `"CloudID": "id"`)
+
+	// Once another master is elected, this one should follow the blueprint
+	// and machines in Etcd.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.Leader = false
+		view.Commit(etcd)
+
+		for _, m := range view.SelectFromMachine(nil) {
+			view.Remove(m)
+		}
+
+		bp := view.InsertBlueprint()
+		bp.Namespace = "stale"
+		view.Commit(bp)
+		return nil
+	})
+
+	err = runControlPlaneOnce(conn, store)
+	assert.NoError(t, err)
+	checkControlPlane(t, conn, expBlueprints, expMachines)
+
+	// Running again shouldn't change anything.
+	err = runControlPlaneOnce(conn, store)
+	assert.NoError(t, err)
+	checkControlPlane(t, conn, expBlueprints, expMachines)
+}
+
+func checkControlPlane(t *testing.T, conn db.Conn, expBlueprints []db.Blueprint,
+	expMachines []db.Machine) {
+
+	blueprints := conn.SelectFromBlueprint(nil)
+	assert.Len(t, blueprints, len(expBlueprints))
+	for i := range blueprints {
+		blueprints[i].ID = expBlueprints[i].ID
+	}
+	assert.Equal(t, expBlueprints, blueprints)
+
+	machines := conn.SelectFromMachine(nil)
+	assert.Len(t, machines, len(expMachines))
+	for i := range machines {
+		machines[i].ID = expMachines[i].ID
+	}
+	assert.Equal(t, expMachines, machines)
+}
@@ -1,12 +1,15 @@
 package etcd
 
 import (
+	"net"
+	"net/http"
 	"strings"
 	"time"
 
 	"golang.org/x/net/context"
 
 	"github.com/coreos/etcd/client"
+	tlsIO "github.com/kelda/kelda/connection/tls/io"
 	"github.com/kelda/kelda/counter"
 	log "github.com/sirupsen/logrus"
 )
@@ -35,11 +38,17 @@ func NewStore() Store {
 	c.Inc("NewStore")
 	var etcd client.Client
 	for {
-		var err error
-		etcd, err = client.New(client.Config{
-			Endpoints: []string{"http://127.0.0.1:2379"},
-			Transport: client.DefaultTransport,
-		})
+		// The etcd certificate might not be installed yet, in which case
+		// we retry until it is.  etcd serves plain HTTP until the cluster
+		// switches to TLS, so both schemes are tried.
+		transport, err := Transport()
+		if err == nil {
+			etcd, err = client.New(client.Config{
+				Endpoints: []string{"https://127.0.0.1:2379",
+					"http://127.0.0.1:2379"},
+				Transport: transport,
+			})
+		}
 		if err != nil {
 			log.WithError(err).Warning("Failed to connect to ETCD.")
 			time.Sleep(30 * time.Second)
@@ -52,6 +61,25 @@ func NewStore() Store {
 	return store{client.NewKeysAPI(etcd)}
 }
 
+// Transport returns an HTTP transport that authenticates to etcd with the
+// minion's etcd certificate.  It's configured like client.DefaultTransport.
+func Transport() (*http.Transport, error) {
+	tlsConfig, err := tlsIO.ReadEtcdConfig(tlsIO.MinionTLSDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConfig,
+	}, nil
+}
+
 func (s store) Watch(path string, rateLimit time.Duration) chan struct{} {
 	chn := make(chan struct{})
 	go func() {
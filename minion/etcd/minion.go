@@ -89,11 +89,13 @@ func diffMinion(dbMinions, storeMinions []db.Minion) (del, add []db.Minion) {
 	key := func(iface interface{}) interface{} {
 		m := iface.(db.Minion)
 		return struct {
-			Role, PrivateIP, HostSubnets       string
-			Provider, Size, Region, FloatingIP string
+			Role, PrivateIP, HostSubnets         string
+			Provider, Size, Region, FloatingIP   string
+			Terminating, Unschedulable, Draining bool
 		}{
 			string(m.Role), m.PrivateIP, strings.Join(m.HostSubnets, " "),
-			m.Provider, m.Size, m.Region, m.FloatingIP,
+			m.Provider, m.Size, m.Region, m.FloatingIP, m.Terminating,
+			m.Unschedulable, m.Draining,
 		}
 	}
 
@@ -56,7 +56,10 @@ func TestWriteMinion(t *testing.T) {
     "HostSubnets": [
         "foo",
         "bar"
-    ]
+    ],
+    "Terminating": false,
+    "Unschedulable": false,
+    "Draining": false
 }`
 	assert.Equal(t, expVal, val)
 }
@@ -103,6 +106,21 @@ func TestReadDiff(t *testing.T) {
 	del, add = diffMinion(append(dbms, sharedDbm), append(etcd, sharedEtcd))
 	assert.Equal(t, dbms, del)
 	assert.Equal(t, etcd, add)
+
+	// Minions that start terminating should be updated.
+	terminating := sharedEtcd
+	terminating.Terminating = true
+	del, add = diffMinion([]db.Minion{sharedDbm}, []db.Minion{terminating})
+	assert.Equal(t, []db.Minion{sharedDbm}, del)
+	assert.Equal(t, []db.Minion{terminating}, add)
+
+	// So should minions that start draining.
+	draining := sharedEtcd
+	draining.Unschedulable = true
+	draining.Draining = true
+	del, add = diffMinion([]db.Minion{sharedDbm}, []db.Minion{draining})
+	assert.Equal(t, []db.Minion{sharedDbm}, del)
+	assert.Equal(t, []db.Minion{draining}, add)
 }
 
 func TestFilter(t *testing.T) {
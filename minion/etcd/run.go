@@ -18,6 +18,8 @@ func Run(conn db.Conn) {
 	go runConnection(conn, store)
 	go runContainer(conn, store)
 	go runHostname(conn, store)
+	go runControlPlane(conn, store)
+	go runSnapshot(conn, store)
 	runMinionSync(conn, store)
 }
 
@@ -0,0 +1,176 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	snapshotKey = "/snapshot"
+
+	// SnapshotPath is where masters store the latest snapshot of the cluster
+	// state.  It's on the host's disk, so it survives restarts of the minion
+	// and of etcd.
+	SnapshotPath = "/var/lib/kelda/etcd-snapshot.json"
+
+	// How often the leader takes a snapshot.
+	snapshotInterval = 10 * time.Minute
+)
+
+// The keys that hold the cluster state.  The other keys are rebuilt from the
+// blueprint or by the minions.
+var snapshotKeys = []string{containerPath, connectionPath, hostnamePath}
+
+// A Snapshot is a copy of the cluster state stored in etcd.
+type Snapshot struct {
+	// When the snapshot was taken.
+	Time time.Time
+
+	// The values of the keys that hold the cluster state, keyed by path.
+	Keys map[string]string
+}
+
+// runSnapshot periodically snapshots the cluster state on the leader, and stores
+// the snapshot in etcd so that every master keeps a copy of the latest one on
+// its disk.
+func runSnapshot(conn db.Conn, store Store) {
+	trigg := conn.TriggerTick(int(snapshotInterval.Seconds()), db.EtcdTable)
+	for range trigg.C {
+		if conn.MinionSelf().Role != db.Master {
+			continue
+		}
+
+		if err := runSnapshotOnce(conn, store); err != nil {
+			log.WithError(err).Warn("Failed to snapshot the cluster state")
+		}
+	}
+}
+
+func runSnapshotOnce(conn db.Conn, store Store) error {
+	if conn.EtcdLeader() {
+		c.Inc("Take Snapshot")
+		snapshot, err := takeSnapshot(store)
+		if err != nil {
+			return err
+		}
+
+		snapshotJSON, err := jsonMarshal(snapshot)
+		if err != nil {
+			return err
+		}
+
+		if err := store.Set(snapshotKey, string(snapshotJSON), 0); err != nil {
+			return fmt.Errorf("store snapshot: %s", err)
+		}
+	}
+
+	snapshotJSON, err := readEtcdNode(store, snapshotKey)
+	if err != nil || snapshotJSON == "" {
+		return err
+	}
+
+	if current, err := util.ReadFile(SnapshotPath); err == nil &&
+		current == snapshotJSON {
+		return nil
+	}
+
+	if err := util.AppFs.MkdirAll(filepath.Dir(SnapshotPath), 0755); err != nil {
+		return fmt.Errorf("create snapshot directory: %s", err)
+	}
+	return util.WriteFile(SnapshotPath, []byte(snapshotJSON), 0600)
+}
+
+func takeSnapshot(store Store) (Snapshot, error) {
+	snapshot := Snapshot{Time: time.Now(), Keys: map[string]string{}}
+	for _, key := range snapshotKeys {
+		value, err := readEtcdNode(store, key)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("read %s: %s", key, err)
+		}
+		snapshot.Keys[key] = value
+	}
+	return snapshot, nil
+}
+
+// ReadSnapshot returns the latest snapshot stored on this master.
+func ReadSnapshot() (string, error) {
+	snapshot, err := util.ReadFile(SnapshotPath)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no snapshot has been taken yet")
+	}
+	return snapshot, err
+}
+
+// RestoreSnapshot restores the IP allocations and container placements in
+// `snapshotJSON` into the leader's database, from which they're written to etcd.
+// Containers are matched by their blueprint ID, and load balancers by their
+// hostname.  Containers and load balancers that aren't in the snapshot lose
+// their IP if it's restored to another, so that they're allocated a new one.
+func RestoreSnapshot(conn db.Conn, snapshotJSON string) error {
+	var snapshot Snapshot
+	if err := json.Unmarshal([]byte(snapshotJSON), &snapshot); err != nil {
+		return fmt.Errorf("parse snapshot: %s", err)
+	}
+
+	var containers []db.Container
+	var hostnames []db.Hostname
+	for key, value := range map[string]interface{}{
+		containerPath: &containers,
+		hostnamePath:  &hostnames,
+	} {
+		if snapshot.Keys[key] == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(snapshot.Keys[key]), value); err != nil {
+			return fmt.Errorf("parse %s: %s", key, err)
+		}
+	}
+
+	restoredIPs := map[string]struct{}{}
+	containerMap := map[string]db.Container{}
+	for _, dbc := range containers {
+		containerMap[dbc.BlueprintID] = dbc
+		restoredIPs[dbc.IP] = struct{}{}
+	}
+
+	// Load balancers are only in the snapshot through their hostnames.
+	hostnameIPs := map[string]string{}
+	for _, hostname := range hostnames {
+		hostnameIPs[hostname.Hostname] = hostname.IP
+		restoredIPs[hostname.IP] = struct{}{}
+	}
+	delete(restoredIPs, "")
+
+	c.Inc("Restore Snapshot")
+	conn.Txn(db.ContainerTable, db.LoadBalancerTable).Run(
+		func(view db.Database) error {
+			for _, dbc := range view.SelectFromContainer(nil) {
+				if snapshotDBC, ok := containerMap[dbc.BlueprintID]; ok {
+					dbc.IP = snapshotDBC.IP
+					dbc.Minion = snapshotDBC.Minion
+				} else if _, ok := restoredIPs[dbc.IP]; ok {
+					dbc.IP = ""
+				}
+				view.Commit(dbc)
+			}
+
+			for _, lb := range view.SelectFromLoadBalancer(nil) {
+				if ip, ok := hostnameIPs[lb.Name]; ok {
+					lb.IP = ip
+				} else if _, ok := restoredIPs[lb.IP]; ok {
+					lb.IP = ""
+				}
+				view.Commit(lb)
+			}
+			return nil
+		})
+	return nil
+}
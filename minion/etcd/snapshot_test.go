@@ -0,0 +1,146 @@
+package etcd
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+func TestRunSnapshotOnce(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	store := newTestMock()
+	conn := db.New()
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		view.Commit(view.InsertEtcd())
+		return nil
+	})
+
+	// No snapshot has been taken yet.
+	assert.Error(t, runSnapshotOnce(conn, store))
+	_, err := ReadSnapshot()
+	assert.EqualError(t, err, "no snapshot has been taken yet")
+
+	assert.NoError(t, store.Set(containerPath, "containers", 0))
+	assert.NoError(t, store.Set(connectionPath, "connections", 0))
+	assert.NoError(t, store.Set(hostnamePath, "hostnames", 0))
+	assert.NoError(t, store.Set(minionPath, "minions", 0))
+	assert.NoError(t, store.Set(snapshotKey, "", 0))
+
+	// Only the leader takes snapshots.
+	assert.NoError(t, runSnapshotOnce(conn, store))
+	_, err = ReadSnapshot()
+	assert.Error(t, err)
+
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.Leader = true
+		view.Commit(etcd)
+		return nil
+	})
+	assert.NoError(t, runSnapshotOnce(conn, store))
+
+	snapshotJSON, err := ReadSnapshot()
+	assert.NoError(t, err)
+	etcdSnapshot, err := store.Get(snapshotKey)
+	assert.NoError(t, err)
+	assert.Equal(t, etcdSnapshot, snapshotJSON)
+
+	var snapshot Snapshot
+	assert.NoError(t, json.Unmarshal([]byte(snapshotJSON), &snapshot))
+	assert.False(t, snapshot.Time.IsZero())
+	assert.Equal(t, map[string]string{
+		containerPath:  "containers",
+		connectionPath: "connections",
+		hostnamePath:   "hostnames",
+	}, snapshot.Keys)
+
+	// Other masters copy the leader's snapshot.
+	util.AppFs = afero.NewMemMapFs()
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.Leader = false
+		view.Commit(etcd)
+		return nil
+	})
+	assert.NoError(t, runSnapshotOnce(conn, store))
+
+	copied, err := ReadSnapshot()
+	assert.NoError(t, err)
+	assert.Equal(t, snapshotJSON, copied)
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, dbc := range []db.Container{
+			{BlueprintID: "restored", IP: "10.0.0.5", Minion: "1.1.1.1"},
+			{BlueprintID: "unallocated"},
+			{BlueprintID: "conflicting", IP: "10.0.0.2", Minion: "1.1.1.1"},
+			{BlueprintID: "new", IP: "10.0.0.3", Minion: "1.1.1.1"},
+		} {
+			dbc.ID = view.InsertContainer().ID
+			view.Commit(dbc)
+		}
+
+		for _, lb := range []db.LoadBalancer{
+			{Name: "lb", IP: "10.0.0.6"},
+			{Name: "other", IP: "10.0.0.4"},
+		} {
+			lb.ID = view.InsertLoadBalancer().ID
+			view.Commit(lb)
+		}
+		return nil
+	})
+
+	assert.Error(t, RestoreSnapshot(conn, "malformed"))
+
+	containers, _ := json.Marshal([]db.Container{
+		{BlueprintID: "restored", IP: "10.0.0.1", Minion: "2.2.2.2"},
+		{BlueprintID: "unallocated", IP: "10.0.0.2", Minion: "2.2.2.2"},
+		{BlueprintID: "removed", IP: "10.0.0.7", Minion: "2.2.2.2"},
+	})
+	hostnames, _ := json.Marshal([]db.Hostname{
+		{Hostname: "restored", IP: "10.0.0.1"},
+		{Hostname: "lb", IP: "10.0.0.4"},
+	})
+	snapshot, _ := json.Marshal(Snapshot{Keys: map[string]string{
+		containerPath: string(containers),
+		hostnamePath:  string(hostnames),
+	}})
+	assert.NoError(t, RestoreSnapshot(conn, string(snapshot)))
+
+	// Rows that conflict with the restored IPs lose their IP.
+	assert.Equal(t, []db.Container{
+		{BlueprintID: "restored", IP: "10.0.0.1", Minion: "2.2.2.2"},
+		{BlueprintID: "unallocated", IP: "10.0.0.2", Minion: "2.2.2.2"},
+		{BlueprintID: "conflicting", Minion: "1.1.1.1"},
+		{BlueprintID: "new", IP: "10.0.0.3", Minion: "1.1.1.1"},
+	}, stripContainerIDs(conn.SelectFromContainer(nil)))
+
+	lbs := conn.SelectFromLoadBalancer(nil)
+	sort.Slice(lbs, func(i, j int) bool { return lbs[i].ID < lbs[j].ID })
+	for i := range lbs {
+		lbs[i].ID = 0
+	}
+	assert.Equal(t, []db.LoadBalancer{
+		{Name: "lb", IP: "10.0.0.4"},
+		{Name: "other"},
+	}, lbs)
+}
+
+func stripContainerIDs(dbcs []db.Container) []db.Container {
+	sort.Slice(dbcs, func(i, j int) bool { return dbcs[i].ID < dbcs[j].ID })
+	for i := range dbcs {
+		dbcs[i].ID = 0
+	}
+	return dbcs
+}
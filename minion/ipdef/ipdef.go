@@ -6,6 +6,10 @@ import (
 	"syscall"
 )
 
+// DefaultSubnet is the subnet under which containers and load balancers are
+// given IP addresses if the blueprint doesn't configure one.
+const DefaultSubnet = "10.0.0.0/8"
+
 var (
 	// KeldaSubnet is the subnet under which Kelda containers and load balancers
 	// are given IP addresses.
@@ -29,6 +33,15 @@ var (
 	// LoadBalancerMac is the MAC address of the load balancer router.
 	LoadBalancerMac = IPToMac(LoadBalancerIP)
 
+	// KeldaSubnet6 is the IPv6 subnet in which containers and load balancers
+	// are given addresses if the blueprint enables IPv6.  Its IP is nil
+	// otherwise.
+	KeldaSubnet6 net.IPNet
+
+	// GatewayIP6 is the IPv6 address of the border router, or nil if IPv6 is
+	// disabled.
+	GatewayIP6 net.IP
+
 	// KeldaBridge is the Open vSwitch bridge controlled by the Kelda minion.
 	KeldaBridge = "kelda-int"
 
@@ -36,6 +49,166 @@ var (
 	OvnBridge = "br-int"
 )
 
+// ContainerNetwork describes the addresses of the logical network in which
+// containers and load balancers are given IP addresses.
+type ContainerNetwork struct {
+	Subnet         net.IPNet
+	GatewayIP      net.IP
+	LoadBalancerIP net.IP
+
+	// Subnet6 is the IPv6 subnet, if any.  Each address in Subnet has a
+	// corresponding address in Subnet6 with the same host bits.
+	Subnet6 net.IPNet
+}
+
+// ParseContainerNetwork parses and validates the container network configured
+// in a blueprint.  An empty `subnet` defaults to DefaultSubnet, and an empty
+// `gateway` and `loadBalancer` default to the first and second addresses in the
+// subnet.  An empty `subnet6` disables IPv6.
+func ParseContainerNetwork(subnet, gateway, loadBalancer, subnet6 string) (
+	ContainerNetwork, error) {
+
+	if subnet == "" {
+		subnet = DefaultSubnet
+	}
+
+	ip, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil || ip.To4() == nil {
+		return ContainerNetwork{}, fmt.Errorf(
+			"container subnet %q must be an IPv4 CIDR", subnet)
+	}
+
+	if !ip.Equal(ipNet.IP) {
+		return ContainerNetwork{}, fmt.Errorf("container subnet %q has host "+
+			"bits set (did you mean %s?)", subnet, ipNet)
+	}
+
+	// Besides the gateway and load balancer, the subnet must have room for
+	// at least a few containers.
+	if ones, bits := ipNet.Mask.Size(); bits-ones < 3 {
+		return ContainerNetwork{}, fmt.Errorf(
+			"container subnet %s is too small", subnet)
+	}
+
+	network := ContainerNetwork{Subnet: *ipNet}
+	network.GatewayIP, err = parseSubnetIP(*ipNet, "gateway", gateway, 1)
+	if err != nil {
+		return ContainerNetwork{}, err
+	}
+
+	network.LoadBalancerIP, err = parseSubnetIP(*ipNet, "load balancer",
+		loadBalancer, 2)
+	if err != nil {
+		return ContainerNetwork{}, err
+	}
+
+	if network.GatewayIP.Equal(network.LoadBalancerIP) {
+		return ContainerNetwork{}, fmt.Errorf("the gateway and load balancer "+
+			"can't share the IP %s", network.GatewayIP)
+	}
+
+	if subnet6 != "" {
+		network.Subnet6, err = parseSubnet6(subnet6)
+		if err != nil {
+			return ContainerNetwork{}, err
+		}
+	}
+	return network, nil
+}
+
+// parseSubnet6 parses the IPv6 container subnet.  IPv6 addresses are derived
+// from the 32 host bits of IPv4 addresses, so the prefix can be at most 96 bits
+// long.
+func parseSubnet6(subnet6 string) (net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(subnet6)
+	if err != nil || ip.To4() != nil {
+		return net.IPNet{}, fmt.Errorf(
+			"IPv6 container subnet %q must be an IPv6 CIDR", subnet6)
+	}
+
+	if !ip.Equal(ipNet.IP) {
+		return net.IPNet{}, fmt.Errorf("IPv6 container subnet %q has host "+
+			"bits set (did you mean %s?)", subnet6, ipNet)
+	}
+
+	if ones, _ := ipNet.Mask.Size(); ones > 96 {
+		return net.IPNet{}, fmt.Errorf("IPv6 container subnet %s is too "+
+			"small (the prefix can be at most 96 bits)", subnet6)
+	}
+	return *ipNet, nil
+}
+
+// parseSubnetIP parses `ipStr`, which must be a host address in `subnet`.  An
+// empty `ipStr` defaults to the address at `offset` in the subnet.
+func parseSubnetIP(subnet net.IPNet, name, ipStr string, offset byte) (
+	net.IP, error) {
+
+	if ipStr == "" {
+		ip := make(net.IP, net.IPv4len)
+		copy(ip, subnet.IP.To4())
+		ip[3] += offset
+		return ip, nil
+	}
+
+	ip := net.ParseIP(ipStr).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("%s IP %q must be an IPv4 address", name, ipStr)
+	}
+
+	if !subnet.Contains(ip) || ip.Equal(subnet.IP.To4()) {
+		return nil, fmt.Errorf("%s IP %s must be a host address in the "+
+			"container subnet %s", name, ip, subnet.String())
+	}
+	return ip, nil
+}
+
+// Configure sets the container network used by the minion.  It must be called
+// before the modules that use the network's addresses start.
+func Configure(network ContainerNetwork) {
+	KeldaSubnet = network.Subnet
+	GatewayIP = network.GatewayIP
+	GatewayMac = IPToMac(GatewayIP)
+	LoadBalancerIP = network.LoadBalancerIP
+	LoadBalancerMac = IPToMac(LoadBalancerIP)
+	KeldaSubnet6 = network.Subnet6
+	GatewayIP6 = IPv6(GatewayIP)
+}
+
+// IPv6Enabled returns whether containers and load balancers are given IPv6
+// addresses.
+func IPv6Enabled() bool {
+	return KeldaSubnet6.IP != nil
+}
+
+// IPv6 returns the IPv6 address that corresponds to the IPv4 address `ip` in the
+// container network.  It has the same host bits in KeldaSubnet6 as `ip` does in
+// KeldaSubnet.  It returns nil if IPv6 is disabled, or if `ip` isn't in
+// KeldaSubnet.
+func IPv6(ip net.IP) net.IP {
+	ip = ip.To4()
+	if !IPv6Enabled() || ip == nil || !KeldaSubnet.Contains(ip) {
+		return nil
+	}
+
+	mask := net.IP(KeldaSubnet.Mask).To4()
+	ip6 := make(net.IP, net.IPv6len)
+	copy(ip6, KeldaSubnet6.IP.To16())
+	for i := 0; i < net.IPv4len; i++ {
+		ip6[net.IPv6len-net.IPv4len+i] |= ip[i] &^ mask[i]
+	}
+	return ip6
+}
+
+// IPv6Str is like IPv6, but operates on strings.  It returns the empty string if
+// there's no corresponding IPv6 address.
+func IPv6Str(ipStr string) string {
+	ip6 := IPv6(net.ParseIP(ipStr))
+	if ip6 == nil {
+		return ""
+	}
+	return ip6.String()
+}
+
 // IPStrToMac converts the given IP address string into a MAC address.
 func IPStrToMac(ipStr string) string {
 	parsedIP := net.ParseIP(ipStr)
@@ -45,9 +218,14 @@ func IPStrToMac(ipStr string) string {
 	return IPToMac(parsedIP)
 }
 
-// IPToMac converts the given IP address into a MAC address.
+// IPToMac converts the given IP address into a MAC address.  IPv6 addresses are
+// converted using their last four bytes.
 func IPToMac(ip net.IP) string {
-	ip = ip.To4()
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else {
+		ip = ip.To16()[net.IPv6len-net.IPv4len:]
+	}
 	return fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", ip[0], ip[1], ip[2], ip[3])
 }
 
@@ -17,6 +17,9 @@ func TestToMac(t *testing.T) {
 		exp := fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", a, b, c, d)
 		assert.Equal(t, exp, IPStrToMac(addr.String()))
 	}
+
+	assert.Equal(t, "02:00:00:01:00:05", IPStrToMac("fd00::1:5"))
+	assert.Equal(t, "", IPStrToMac("bad"))
 }
 
 func TestIFName(t *testing.T) {
@@ -27,3 +30,108 @@ func TestIFName(t *testing.T) {
 	assert.Equal(t, IFName("1"), "1")
 	assert.Equal(t, IFName(""), "")
 }
+
+func TestParseContainerNetwork(t *testing.T) {
+	network, err := ParseContainerNetwork("", "", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, KeldaSubnet.String(), network.Subnet.String())
+	assert.True(t, GatewayIP.Equal(network.GatewayIP))
+	assert.True(t, LoadBalancerIP.Equal(network.LoadBalancerIP))
+
+	network, err = ParseContainerNetwork("172.20.0.0/16", "", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.20.0.0/16", network.Subnet.String())
+	assert.Equal(t, "172.20.0.1", network.GatewayIP.String())
+	assert.Equal(t, "172.20.0.2", network.LoadBalancerIP.String())
+
+	network, err = ParseContainerNetwork("172.20.0.0/16", "172.20.255.254",
+		"172.20.255.253", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.20.255.254", network.GatewayIP.String())
+	assert.Equal(t, "172.20.255.253", network.LoadBalancerIP.String())
+
+	assert.Nil(t, network.Subnet6.IP)
+
+	network, err = ParseContainerNetwork("172.20.0.0/16", "", "", "fd00::/64")
+	assert.NoError(t, err)
+	assert.Equal(t, "fd00::/64", network.Subnet6.String())
+
+	for _, test := range []struct {
+		subnet, gateway, lb, subnet6, err string
+	}{
+		{"bad", "", "", "", `container subnet "bad" must be an IPv4 CIDR`},
+		{"fd00::/64", "", "", "", `container subnet "fd00::/64" must be an ` +
+			"IPv4 CIDR"},
+		{"172.20.1.0/16", "", "", "", `container subnet "172.20.1.0/16" has ` +
+			"host bits set (did you mean 172.20.0.0/16?)"},
+		{"172.20.0.0/30", "", "", "", "container subnet 172.20.0.0/30 is " +
+			"too small"},
+		{"172.20.0.0/16", "bad", "", "", `gateway IP "bad" must be an IPv4 ` +
+			"address"},
+		{"172.20.0.0/16", "10.0.0.1", "", "", "gateway IP 10.0.0.1 must be a " +
+			"host address in the container subnet 172.20.0.0/16"},
+		{"172.20.0.0/16", "", "172.20.0.0", "", "load balancer IP 172.20.0.0 " +
+			"must be a host address in the container subnet 172.20.0.0/16"},
+		{"172.20.0.0/16", "", "172.20.0.1", "", "the gateway and load balancer " +
+			"can't share the IP 172.20.0.1"},
+		{"172.20.0.0/16", "", "", "bad", `IPv6 container subnet "bad" ` +
+			"must be an IPv6 CIDR"},
+		{"172.20.0.0/16", "", "", "10.0.0.0/8", "IPv6 container subnet " +
+			`"10.0.0.0/8" must be an IPv6 CIDR`},
+		{"172.20.0.0/16", "", "", "fd00::1/64", "IPv6 container subnet " +
+			`"fd00::1/64" has host bits set (did you mean fd00::/64?)`},
+		{"172.20.0.0/16", "", "", "fd00::/112", "IPv6 container subnet " +
+			"fd00::/112 is too small (the prefix can be at most 96 bits)"},
+	} {
+		_, err := ParseContainerNetwork(test.subnet, test.gateway, test.lb,
+			test.subnet6)
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defaultNetwork, _ := ParseContainerNetwork("", "", "", "")
+	defer Configure(defaultNetwork)
+
+	network, err := ParseContainerNetwork("172.20.0.0/16", "", "", "")
+	assert.NoError(t, err)
+	Configure(network)
+
+	assert.Equal(t, "172.20.0.0/16", KeldaSubnet.String())
+	assert.Equal(t, "172.20.0.1", GatewayIP.String())
+	assert.Equal(t, "02:00:ac:14:00:01", GatewayMac)
+	assert.Equal(t, "172.20.0.2", LoadBalancerIP.String())
+	assert.Equal(t, "02:00:ac:14:00:02", LoadBalancerMac)
+	assert.False(t, IPv6Enabled())
+	assert.Nil(t, GatewayIP6)
+
+	network, err = ParseContainerNetwork("172.20.0.0/16", "", "", "fd00::/64")
+	assert.NoError(t, err)
+	Configure(network)
+
+	assert.True(t, IPv6Enabled())
+	assert.Equal(t, "fd00::/64", KeldaSubnet6.String())
+	assert.Equal(t, "fd00::1", GatewayIP6.String())
+}
+
+func TestIPv6(t *testing.T) {
+	defaultNetwork, _ := ParseContainerNetwork("", "", "", "")
+	defer Configure(defaultNetwork)
+
+	Configure(defaultNetwork)
+	assert.Nil(t, IPv6(net.ParseIP("10.0.0.5")))
+	assert.Equal(t, "", IPv6Str("10.0.0.5"))
+
+	network, _ := ParseContainerNetwork("172.20.0.0/16", "", "",
+		"fd00:0:0:1::/64")
+	Configure(network)
+	assert.Equal(t, "fd00:0:0:1::105", IPv6Str("172.20.1.5"))
+	assert.Equal(t, "fd00:0:0:1::fffe", IPv6Str("172.20.255.254"))
+	assert.Equal(t, "", IPv6Str("10.0.0.5"))
+	assert.Equal(t, "", IPv6Str("fd00::5"))
+	assert.Equal(t, "", IPv6Str("bad"))
+
+	network, _ = ParseContainerNetwork("10.0.0.0/8", "", "", "fd00::/96")
+	Configure(network)
+	assert.Equal(t, "fd00::1:203", IPv6Str("10.1.2.3"))
+}
@@ -0,0 +1,362 @@
+// Package logship forwards the logs written by the containers on a machine to
+// the LogSink declared in the blueprint.
+//
+// Each container's logs are read from Docker by a follower goroutine and
+// pushed onto a bounded buffer, which is drained by a single writer goroutine
+// that sends batches of lines to the sink.  If the sink is slow or
+// unavailable, the buffer fills and the followers stop reading from Docker
+// until it drains.  Docker keeps writing the logs to disk in the meantime, so
+// containers are never blocked by the sink.
+package logship
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// The name of the Docker container running the minion.  Its logs are shipped
+// along with the logs of the blueprint's containers.
+const minionContainer = "minion"
+
+const defaultBufferSize = 10000
+
+// The maximum number of lines sent to the sink in a single write.
+const batchSize = 500
+
+// How long to wait before following the logs of a container again after they
+// end, e.g. because the container exited.
+var followRetryInterval = 10 * time.Second
+
+// The bounds of the exponential backoff used when writes to the sink fail.
+var minWriteRetryInterval = time.Second
+var maxWriteRetryInterval = 30 * time.Second
+
+var c = counter.New("Log Ship")
+
+// An Entry is a single line written by a container, along with metadata
+// describing where it came from.
+type Entry struct {
+	Time        time.Time
+	Stderr      bool `json:",omitempty"`
+	Line        string
+	Hostname    string `json:",omitempty"`
+	BlueprintID string `json:",omitempty"`
+	DockerID    string
+
+	// Labels describing the machine running the container, e.g. its role and
+	// provider.
+	Labels map[string]string `json:",omitempty"`
+}
+
+// A source is a container whose logs are shipped.
+type source struct {
+	dockerID    string
+	hostname    string
+	blueprintID string
+}
+
+type shipper struct {
+	conn  db.Conn
+	dk    docker.Client
+	creds connection.Credentials
+
+	sinkConfig *blueprint.LogSink
+	buffer     chan Entry
+	stop       chan struct{}
+	followers  map[string]context.CancelFunc
+
+	// The time of the last line buffered from each stream of each container,
+	// so that followers can resume where they left off.
+	shippedLock sync.Mutex
+	shipped     map[stream]time.Time
+}
+
+// A stream is the stdout, or stderr, of a container.
+type stream struct {
+	dockerID string
+	stderr   bool
+}
+
+// Run forwards the logs of the containers on this machine to the blueprint's
+// LogSink.
+func Run(conn db.Conn, dk docker.Client, creds connection.Credentials) {
+	s := newShipper(conn, dk, creds)
+	for range conn.TriggerTick(30, db.MinionTable, db.ContainerTable).C {
+		s.runOnce()
+	}
+}
+
+func newShipper(conn db.Conn, dk docker.Client,
+	creds connection.Credentials) *shipper {
+
+	return &shipper{
+		conn:      conn,
+		dk:        dk,
+		creds:     creds,
+		followers: map[string]context.CancelFunc{},
+		shipped:   map[stream]time.Time{},
+	}
+}
+
+func (s *shipper) runOnce() {
+	self := s.conn.MinionSelf()
+
+	sinkConfig := getSinkConfig(self.Blueprint)
+	if !reflect.DeepEqual(sinkConfig, s.sinkConfig) {
+		s.stopShipping()
+		s.sinkConfig = sinkConfig
+
+		if sinkConfig != nil {
+			snk, err := newSink(*sinkConfig, s.conn, s.creds)
+			if err != nil {
+				log.WithError(err).Error("Invalid log sink")
+				return
+			}
+			s.startShipping(snk, sinkConfig.BufferSize)
+		}
+	}
+
+	if s.buffer == nil {
+		return
+	}
+
+	sources := s.sources(self)
+	for id, cancel := range s.followers {
+		if _, ok := sources[id]; !ok {
+			cancel()
+			delete(s.followers, id)
+			s.forgetShipped(id)
+		}
+	}
+
+	labels := minionLabels(self)
+	for id, src := range sources {
+		if _, ok := s.followers[id]; ok {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		s.followers[id] = cancel
+		go s.follow(ctx, src, labels, s.buffer)
+	}
+}
+
+func (s *shipper) startShipping(snk sink, bufferSize int) {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	s.buffer = make(chan Entry, bufferSize)
+	s.stop = make(chan struct{})
+	go write(snk, s.buffer, s.stop)
+}
+
+// stopShipping stops all followers and the writer.  Lines that are still
+// buffered are dropped.
+func (s *shipper) stopShipping() {
+	for id, cancel := range s.followers {
+		cancel()
+		delete(s.followers, id)
+	}
+
+	if s.stop != nil {
+		close(s.stop)
+	}
+	s.buffer = nil
+	s.stop = nil
+}
+
+// sources returns the containers running on this machine, keyed by Docker ID.
+func (s *shipper) sources(self db.Minion) map[string]source {
+	sources := map[string]source{
+		minionContainer: {dockerID: minionContainer, hostname: "minion"},
+	}
+
+	for _, dbc := range s.conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.DockerID != "" && dbc.Minion == self.PrivateIP
+	}) {
+		sources[dbc.DockerID] = source{
+			dockerID:    dbc.DockerID,
+			hostname:    dbc.Hostname,
+			blueprintID: dbc.BlueprintID,
+		}
+	}
+	return sources
+}
+
+// follow pushes the logs of `src` onto `buffer` until `ctx` is cancelled.
+func (s *shipper) follow(ctx context.Context, src source, labels map[string]string,
+	buffer chan<- Entry) {
+
+	for {
+		stdout := stream{dockerID: src.dockerID}
+		stderr := stream{dockerID: src.dockerID, stderr: true}
+		shipped := map[bool]time.Time{
+			false: s.getShipped(stdout),
+			true:  s.getShipped(stderr),
+		}
+
+		// The streams are read together, so they're read from the last line
+		// shipped from the stream that's furthest behind.
+		since := shipped[false]
+		if shipped[true].Before(since) {
+			since = shipped[true]
+		}
+
+		opts := docker.LogsOptions{Context: ctx, Follow: true, Since: since}
+		err := s.dk.Logs(src.dockerID, opts, func(line docker.LogLine) error {
+			// Skip the lines that were already shipped from the line's
+			// stream.
+			if !line.Time.After(shipped[line.Stderr]) {
+				return nil
+			}
+
+			entry := Entry{
+				Time:        line.Time,
+				Stderr:      line.Stderr,
+				Line:        line.Text,
+				Hostname:    src.hostname,
+				BlueprintID: src.blueprintID,
+				DockerID:    src.dockerID,
+				Labels:      labels,
+			}
+
+			select {
+			case buffer <- entry:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			s.setShipped(stream{src.dockerID, line.Stderr}, line.Time)
+			return nil
+		})
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			log.WithError(err).WithField("container", src.dockerID).Warn(
+				"Failed to follow container logs")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(followRetryInterval):
+		}
+	}
+}
+
+func (s *shipper) getShipped(strm stream) time.Time {
+	s.shippedLock.Lock()
+	defer s.shippedLock.Unlock()
+	return s.shipped[strm]
+}
+
+// setShipped records that the line logged to `strm` at time `t` was buffered.
+func (s *shipper) setShipped(strm stream, t time.Time) {
+	s.shippedLock.Lock()
+	defer s.shippedLock.Unlock()
+	if t.After(s.shipped[strm]) {
+		s.shipped[strm] = t
+	}
+}
+
+func (s *shipper) forgetShipped(id string) {
+	s.shippedLock.Lock()
+	defer s.shippedLock.Unlock()
+	delete(s.shipped, stream{dockerID: id})
+	delete(s.shipped, stream{dockerID: id, stderr: true})
+}
+
+// write sends the lines in `buffer` to `snk` in batches until `stop` is closed.
+// Failed writes are retried with an exponential backoff, during which
+// `buffer` fills and the followers stop reading from Docker.
+func write(snk sink, buffer <-chan Entry, stop <-chan struct{}) {
+	for {
+		var batch []Entry
+		select {
+		case entry := <-buffer:
+			batch = append(batch, entry)
+		case <-stop:
+			return
+		}
+
+	fill:
+		for len(batch) < batchSize {
+			select {
+			case entry := <-buffer:
+				batch = append(batch, entry)
+			default:
+				break fill
+			}
+		}
+		c.SetGauge("Buffered Lines", float64(len(buffer)))
+
+		retryInterval := minWriteRetryInterval
+		for {
+			start := time.Now()
+			err := snk.write(batch)
+			c.ObserveSince("Write", start)
+			if err == nil {
+				break
+			}
+
+			c.Inc("Write Error")
+			log.WithError(err).WithField("lines", len(batch)).Warn(
+				"Failed to write logs to sink")
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(retryInterval):
+			}
+
+			retryInterval *= 2
+			if retryInterval > maxWriteRetryInterval {
+				retryInterval = maxWriteRetryInterval
+			}
+		}
+	}
+}
+
+func getSinkConfig(bpJSON string) *blueprint.LogSink {
+	if bpJSON == "" {
+		return nil
+	}
+
+	bp, err := blueprint.FromJSON(bpJSON)
+	if err != nil {
+		log.WithError(err).Debug("Failed to parse blueprint")
+		return nil
+	}
+	return bp.LogSink
+}
+
+func minionLabels(self db.Minion) map[string]string {
+	labels := map[string]string{}
+	for key, value := range map[string]string{
+		"role":      string(self.Role),
+		"provider":  self.Provider,
+		"region":    self.Region,
+		"size":      self.Size,
+		"privateIP": self.PrivateIP,
+		"publicIP":  self.PublicIP,
+	} {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
@@ -0,0 +1,296 @@
+package logship
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+)
+
+func init() {
+	followRetryInterval = 10 * time.Millisecond
+	minWriteRetryInterval = time.Millisecond
+	maxWriteRetryInterval = 2 * time.Millisecond
+}
+
+type mockSink struct {
+	sync.Mutex
+	failures int
+	entries  []Entry
+}
+
+func (s *mockSink) write(entries []Entry) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("write error")
+	}
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *mockSink) get() []Entry {
+	s.Lock()
+	defer s.Unlock()
+	return append([]Entry{}, s.entries...)
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	snk := &mockSink{failures: 2}
+	buffer := make(chan Entry, 10)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	exp := []Entry{{Line: "foo"}, {Line: "bar"}}
+	for _, entry := range exp {
+		buffer <- entry
+	}
+	go write(snk, buffer, stop)
+
+	// Failed writes should be retried.
+	assert.True(t, eventually(func() bool { return len(snk.get()) == 2 }))
+	assert.Equal(t, exp, snk.get())
+
+	buffer <- Entry{Line: "baz"}
+	assert.True(t, eventually(func() bool { return len(snk.get()) == 3 }))
+}
+
+func TestWriteStop(t *testing.T) {
+	t.Parallel()
+
+	snk := &mockSink{failures: 1000000}
+	buffer := make(chan Entry, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	buffer <- Entry{Line: "foo"}
+	go func() {
+		write(snk, buffer, stop)
+		close(done)
+	}()
+
+	// The writer should stop even if the sink never recovers.
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer didn't stop")
+	}
+}
+
+func TestFollow(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	md.StdoutLogs["id"] = "2017-10-18T17:55:00.5Z foo\n" +
+		"2017-10-18T17:56:00Z bar\n"
+	md.StderrLogs["id"] = "2017-10-18T17:57:00Z baz\n"
+
+	s := newShipper(db.New(), dk, nil)
+	buffer := make(chan Entry)
+	ctx, cancel := context.WithCancel(context.Background())
+	src := source{dockerID: "id", hostname: "host", blueprintID: "bp"}
+	labels := map[string]string{"role": "Worker"}
+
+	done := make(chan struct{})
+	go func() {
+		s.follow(ctx, src, labels, buffer)
+		close(done)
+	}()
+
+	var entries []Entry
+	for i := 0; i < 3; i++ {
+		entries = append(entries, <-buffer)
+	}
+	assert.Contains(t, entries, Entry{
+		Time:        time.Date(2017, 10, 18, 17, 55, 0, 5e8, time.UTC),
+		Line:        "foo",
+		Hostname:    "host",
+		BlueprintID: "bp",
+		DockerID:    "id",
+		Labels:      labels,
+	})
+	assert.Contains(t, entries, Entry{
+		Time:        time.Date(2017, 10, 18, 17, 57, 0, 0, time.UTC),
+		Stderr:      true,
+		Line:        "baz",
+		Hostname:    "host",
+		BlueprintID: "bp",
+		DockerID:    "id",
+		Labels:      labels,
+	})
+
+	// When the logs are followed again, lines that were already shipped
+	// should be skipped.
+	md.Lock()
+	md.StdoutLogs["id"] += "2017-10-18T17:58:00Z qux\n"
+	md.Unlock()
+	assert.Equal(t, "qux", (<-buffer).Line)
+
+	md.Lock()
+	assert.True(t, md.LogRequests["id"].Follow)
+	assert.NotZero(t, md.LogRequests["id"].Since)
+	md.Unlock()
+
+	// Each stream resumes from its own last shipped line, so the logs are
+	// read from the last stderr line, and a line logged to stderr before the
+	// last stdout line is still shipped.
+	time.Sleep(5 * followRetryInterval)
+	md.Lock()
+	assert.Equal(t, time.Date(2017, 10, 18, 17, 57, 0, 0, time.UTC).Unix(),
+		md.LogRequests["id"].Since)
+	md.StderrLogs["id"] += "2017-10-18T17:57:30Z late\n"
+	md.Unlock()
+	late := <-buffer
+	assert.Equal(t, "late", late.Line)
+	assert.True(t, late.Stderr)
+
+	// Followers should exit once cancelled, even if the buffer is full.
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("follower didn't stop")
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	var received []Entry
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var entries []Entry
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&entries))
+			lock.Lock()
+			received = append(received, entries...)
+			lock.Unlock()
+		}))
+	defer server.Close()
+
+	md, dk := docker.NewMock()
+	md.StdoutLogs["minion"] = "2017-10-18T17:55:00Z minion\n"
+	md.StdoutLogs["mine"] = "2017-10-18T17:55:00Z mine\n"
+	md.StdoutLogs["other"] = "2017-10-18T17:55:00Z other\n"
+
+	conn := db.New()
+	setBlueprint := func(sink *blueprint.LogSink) {
+		conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			self := view.MinionSelf()
+			self.Blueprint = blueprint.Blueprint{LogSink: sink}.String()
+			view.Commit(self)
+			return nil
+		})
+	}
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.Role = db.Worker
+		self.PrivateIP = "10.0.0.1"
+		view.Commit(self)
+
+		dbc := view.InsertContainer()
+		dbc.DockerID = "mine"
+		dbc.Hostname = "mine-host"
+		dbc.Minion = "10.0.0.1"
+		view.Commit(dbc)
+
+		dbc = view.InsertContainer()
+		dbc.DockerID = "other"
+		dbc.Minion = "10.0.0.2"
+		view.Commit(dbc)
+		return nil
+	})
+	setBlueprint(&blueprint.LogSink{Type: blueprint.HTTPSink,
+		Address: server.URL})
+
+	s := newShipper(conn, dk, nil)
+	s.runOnce()
+	assert.Len(t, s.followers, 2)
+	assert.NotNil(t, s.followers["minion"])
+	assert.NotNil(t, s.followers["mine"])
+
+	getLines := func() map[string]string {
+		lock.Lock()
+		defer lock.Unlock()
+		lines := map[string]string{}
+		for _, entry := range received {
+			lines[entry.Line] = entry.Hostname
+		}
+		return lines
+	}
+	assert.True(t, eventually(func() bool { return len(getLines()) == 2 }))
+	assert.Equal(t, map[string]string{"minion": "minion", "mine": "mine-host"},
+		getLines())
+
+	// Containers that are no longer running shouldn't be followed.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromContainer(nil) {
+			view.Remove(dbc)
+		}
+		return nil
+	})
+	s.runOnce()
+	assert.Len(t, s.followers, 1)
+
+	// Removing the sink should stop shipping.
+	setBlueprint(nil)
+	s.runOnce()
+	assert.Empty(t, s.followers)
+	assert.Nil(t, s.buffer)
+
+	// Invalid sinks are ignored.
+	setBlueprint(&blueprint.LogSink{Type: "kafka"})
+	s.runOnce()
+	assert.Empty(t, s.followers)
+	assert.Nil(t, s.buffer)
+}
+
+func TestGetSinkConfig(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, getSinkConfig(""))
+	assert.Nil(t, getSinkConfig("{"))
+	assert.Nil(t, getSinkConfig(blueprint.Blueprint{}.String()))
+
+	sink := &blueprint.LogSink{Type: blueprint.FileSink, File: "foo"}
+	assert.Equal(t, sink, getSinkConfig(blueprint.Blueprint{LogSink: sink}.String()))
+}
+
+func TestMinionLabels(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, map[string]string{
+		"role":      "Worker",
+		"provider":  "Amazon",
+		"privateIP": "10.0.0.1",
+	}, minionLabels(db.Minion{
+		Role:      db.Worker,
+		Provider:  "Amazon",
+		PrivateIP: "10.0.0.1",
+	}))
+}
+
+func eventually(check func() bool) bool {
+	for i := 0; i < 500; i++ {
+		if check() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
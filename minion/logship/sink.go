@@ -0,0 +1,331 @@
+package logship
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/pb"
+	"github.com/kelda/kelda/util"
+
+	"golang.org/x/net/context"
+)
+
+// LogDir is the directory on the masters in which the FileSink writes.
+const LogDir = "/var/log/kelda"
+
+const defaultLogFile = "containers.log"
+
+const sinkTimeout = 30 * time.Second
+
+type sink interface {
+	write([]Entry) error
+}
+
+func newSink(cfg blueprint.LogSink, conn db.Conn, creds connection.Credentials) (
+	sink, error) {
+
+	switch cfg.Type {
+	case blueprint.SyslogSink:
+		return newSyslogSink(cfg.Address)
+	case blueprint.HTTPSink:
+		return newHTTPSink(cfg.Address)
+	case blueprint.FileSink:
+		if _, err := FilePath(cfg); err != nil {
+			return nil, err
+		}
+		return &fileSink{conn: conn, creds: creds}, nil
+	default:
+		return nil, fmt.Errorf("unknown log sink type: %q", cfg.Type)
+	}
+}
+
+// The facility and severities used for syslog messages, as defined by RFC 5424.
+const (
+	syslogFacilityLocal0 = 16
+	syslogSeverityError  = 3
+	syslogSeverityInfo   = 6
+)
+
+// syslogSink sends each line to a syslog server using the RFC 5424 format.
+type syslogSink struct {
+	network, addr string
+	conn          net.Conn
+}
+
+func newSyslogSink(address string) (*syslogSink, error) {
+	network, addr := "udp", address
+	if parts := strings.SplitN(address, "://", 2); len(parts) == 2 {
+		network, addr = parts[0], parts[1]
+	}
+
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unsupported syslog protocol: %q", network)
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid syslog address %q: %s", address, err)
+	}
+	return &syslogSink{network: network, addr: addr}, nil
+}
+
+func (s *syslogSink) write(entries []Entry) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, sinkTimeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	for _, entry := range entries {
+		msg := formatSyslog(entry)
+		if s.network == "tcp" {
+			// Use octet counting to frame messages sent over TCP (RFC 6587).
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+		if _, err := io.WriteString(s.conn, msg); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func formatSyslog(entry Entry) string {
+	severity := syslogSeverityInfo
+	if entry.Stderr {
+		severity = syslogSeverityError
+	}
+
+	host := entry.Labels["privateIP"]
+	if host == "" {
+		host = "-"
+	}
+
+	appName := entry.Hostname
+	if appName == "" {
+		appName = entry.DockerID
+	}
+	if len(appName) > 48 {
+		appName = appName[:48]
+	}
+
+	params := map[string]string{"dockerID": entry.DockerID}
+	if entry.BlueprintID != "" {
+		params["blueprintID"] = entry.BlueprintID
+	}
+	for key, value := range entry.Labels {
+		params[key] = value
+	}
+
+	var keys []string
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	structuredData := "[kelda@32473"
+	for _, key := range keys {
+		structuredData += fmt.Sprintf(` %s="%s"`, key,
+			escaper.Replace(params[key]))
+	}
+	structuredData += "]"
+
+	return fmt.Sprintf("<%d>1 %s %s %s - - %s %s\n",
+		syslogFacilityLocal0*8+severity,
+		entry.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		host, appName, structuredData, entry.Line)
+}
+
+// httpSink POSTs each batch of lines as a JSON array.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPSink(address string) (*httpSink, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log sink URL %q: %s", address, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("log sink URL %q must use http or https",
+			address)
+	}
+	return &httpSink{url: address, client: &http.Client{Timeout: sinkTimeout}},
+		nil
+}
+
+func (s *httpSink) write(entries []Entry) error {
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return nil
+}
+
+// fileSink sends each batch of lines to the lead master, which appends them to
+// a file in LogDir.
+type fileSink struct {
+	conn  db.Conn
+	creds connection.Credentials
+
+	leaderIP string
+	client   pb.MinionClient
+	closer   io.Closer
+}
+
+func (s *fileSink) write(entries []Entry) error {
+	var leaderIP string
+	s.conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		if etcdRow, err := view.GetEtcd(); err == nil {
+			leaderIP = etcdRow.LeaderIP
+		}
+		return nil
+	})
+
+	if leaderIP == "" {
+		return errors.New("no lead master")
+	}
+
+	if s.client == nil || leaderIP != s.leaderIP {
+		if s.closer != nil {
+			s.closer.Close()
+		}
+
+		client, closer, err := newMinionClient(leaderIP, s.creds)
+		if err != nil {
+			s.client, s.closer = nil, nil
+			return err
+		}
+		s.leaderIP, s.client, s.closer = leaderIP, client, closer
+	}
+
+	msg := &pb.LogEntries{}
+	for _, entry := range entries {
+		msg.Entries = append(msg.Entries, entry.toPB())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sinkTimeout)
+	defer cancel()
+	_, err := s.client.WriteLogs(ctx, msg)
+	return err
+}
+
+func newMinionClientImpl(ip string, creds connection.Credentials) (
+	pb.MinionClient, io.Closer, error) {
+
+	cc, err := connection.Client("tcp", ip+":9999", creds.ClientOpts())
+	if err != nil {
+		return nil, nil, err
+	}
+	return pb.NewMinionClient(cc), cc, nil
+}
+
+// Stored in a variable so that it can be mocked out for unit tests.
+var newMinionClient = newMinionClientImpl
+
+// FilePath returns the path of the file written by the FileSink described by
+// `cfg`.
+func FilePath(cfg blueprint.LogSink) (string, error) {
+	name := cfg.File
+	if name == "" {
+		name = defaultLogFile
+	}
+
+	if strings.ContainsRune(name, '/') || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid log file name: %q", name)
+	}
+	return filepath.Join(LogDir, name), nil
+}
+
+var appendLock sync.Mutex
+
+// AppendToFile appends `entries` to the file at `path`, one JSON object per
+// line.
+func AppendToFile(path string, entries []Entry) error {
+	appendLock.Lock()
+	defer appendLock.Unlock()
+
+	if err := util.AppFs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := util.AppFs.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	_, err = f.Write(buf.Bytes())
+	return err
+}
+
+func (entry Entry) toPB() *pb.LogEntry {
+	return &pb.LogEntry{
+		Timestamp:   entry.Time.UnixNano(),
+		Stderr:      entry.Stderr,
+		Line:        entry.Line,
+		Hostname:    entry.Hostname,
+		BlueprintID: entry.BlueprintID,
+		DockerID:    entry.DockerID,
+		Labels:      entry.Labels,
+	}
+}
+
+// EntriesFromPB converts the protobuf representation of log entries into
+// Entries.
+func EntriesFromPB(pbEntries []*pb.LogEntry) []Entry {
+	var entries []Entry
+	for _, pbEntry := range pbEntries {
+		entries = append(entries, Entry{
+			Time:        time.Unix(0, pbEntry.Timestamp).UTC(),
+			Stderr:      pbEntry.Stderr,
+			Line:        pbEntry.Line,
+			Hostname:    pbEntry.Hostname,
+			BlueprintID: pbEntry.BlueprintID,
+			DockerID:    pbEntry.DockerID,
+			Labels:      pbEntry.Labels,
+		})
+	}
+	return entries
+}
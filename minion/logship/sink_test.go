@@ -0,0 +1,285 @@
+package logship
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/pb"
+	"github.com/kelda/kelda/util"
+)
+
+var testEntry = Entry{
+	Time:        time.Date(2017, 10, 18, 17, 55, 0, 123456789, time.UTC),
+	Line:        "foo bar",
+	Hostname:    "spark-ms",
+	BlueprintID: "bp",
+	DockerID:    "docker",
+	Labels:      map[string]string{"privateIP": "10.0.0.1", "role": "Worker"},
+}
+
+func TestNewSink(t *testing.T) {
+	t.Parallel()
+
+	snk, err := newSink(blueprint.LogSink{Type: blueprint.SyslogSink,
+		Address: "10.0.0.1:514"}, db.New(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &syslogSink{network: "udp", addr: "10.0.0.1:514"}, snk)
+
+	snk, err = newSink(blueprint.LogSink{Type: blueprint.SyslogSink,
+		Address: "tcp://10.0.0.1:514"}, db.New(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &syslogSink{network: "tcp", addr: "10.0.0.1:514"}, snk)
+
+	_, err = newSink(blueprint.LogSink{Type: blueprint.SyslogSink,
+		Address: "unix:///dev/log"}, db.New(), nil)
+	assert.EqualError(t, err, `unsupported syslog protocol: "unix"`)
+
+	_, err = newSink(blueprint.LogSink{Type: blueprint.SyslogSink,
+		Address: "10.0.0.1"}, db.New(), nil)
+	assert.Error(t, err)
+
+	snk, err = newSink(blueprint.LogSink{Type: blueprint.HTTPSink,
+		Address: "https://example.com/logs"}, db.New(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/logs", snk.(*httpSink).url)
+
+	_, err = newSink(blueprint.LogSink{Type: blueprint.HTTPSink,
+		Address: "ftp://example.com"}, db.New(), nil)
+	assert.EqualError(t, err,
+		`log sink URL "ftp://example.com" must use http or https`)
+
+	snk, err = newSink(blueprint.LogSink{Type: blueprint.FileSink}, db.New(), nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &fileSink{}, snk)
+
+	_, err = newSink(blueprint.LogSink{Type: blueprint.FileSink, File: "../foo"},
+		db.New(), nil)
+	assert.EqualError(t, err, `invalid log file name: "../foo"`)
+
+	_, err = newSink(blueprint.LogSink{Type: "kafka"}, db.New(), nil)
+	assert.EqualError(t, err, `unknown log sink type: "kafka"`)
+}
+
+func TestFormatSyslog(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `<134>1 2017-10-18T17:55:00.123456Z 10.0.0.1 spark-ms - - `+
+		`[kelda@32473 blueprintID="bp" dockerID="docker" privateIP="10.0.0.1" `+
+		`role="Worker"] foo bar`+"\n", formatSyslog(testEntry))
+
+	entry := Entry{
+		Time:     testEntry.Time,
+		Stderr:   true,
+		Line:     "error",
+		DockerID: `a"b]c\`,
+	}
+	assert.Equal(t, `<131>1 2017-10-18T17:55:00.123456Z - a"b]c\ - - `+
+		`[kelda@32473 dockerID="a\"b\]c\\"] error`+"\n", formatSyslog(entry))
+}
+
+func TestSyslogSink(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	snk, err := newSyslogSink("tcp://" + listener.Addr().String())
+	assert.NoError(t, err)
+
+	received := make(chan string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			lengthStr, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+
+			length, err := strconv.Atoi(strings.TrimSuffix(lengthStr, " "))
+			if err != nil {
+				return
+			}
+
+			msg := make([]byte, length)
+			if _, err := io.ReadFull(reader, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	assert.NoError(t, snk.write([]Entry{testEntry, testEntry}))
+	assert.Equal(t, formatSyslog(testEntry), <-received)
+	assert.Equal(t, formatSyslog(testEntry), <-received)
+}
+
+func TestHTTPSink(t *testing.T) {
+	t.Parallel()
+
+	status := http.StatusOK
+	var received []Entry
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(body, &received))
+			w.WriteHeader(status)
+		}))
+	defer server.Close()
+
+	snk, err := newHTTPSink(server.URL)
+	assert.NoError(t, err)
+
+	assert.NoError(t, snk.write([]Entry{testEntry}))
+	assert.Equal(t, []Entry{testEntry}, received)
+
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, snk.write([]Entry{testEntry}),
+		"unexpected response: 503 Service Unavailable")
+}
+
+type mockMinionClient struct {
+	pb.MinionClient
+	received []*pb.LogEntries
+	err      error
+}
+
+func (c *mockMinionClient) WriteLogs(ctx context.Context, in *pb.LogEntries,
+	opts ...grpc.CallOption) (*pb.Reply, error) {
+	c.received = append(c.received, in)
+	return &pb.Reply{}, c.err
+}
+
+type mockCloser struct {
+	closed bool
+}
+
+func (c *mockCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestFileSink(t *testing.T) {
+	conn := db.New()
+	setLeader := func(ip string) {
+		conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+			etcdRow, err := view.GetEtcd()
+			if err != nil {
+				etcdRow = view.InsertEtcd()
+			}
+			etcdRow.LeaderIP = ip
+			view.Commit(etcdRow)
+			return nil
+		})
+	}
+
+	clients := map[string]*mockMinionClient{}
+	closers := map[string]*mockCloser{}
+	newMinionClient = func(ip string, _ connection.Credentials) (
+		pb.MinionClient, io.Closer, error) {
+		if ip == "bad" {
+			return nil, nil, errors.New("dial error")
+		}
+		clients[ip] = &mockMinionClient{}
+		closers[ip] = &mockCloser{}
+		return clients[ip], closers[ip], nil
+	}
+	defer func() { newMinionClient = newMinionClientImpl }()
+
+	snk := &fileSink{conn: conn}
+	assert.EqualError(t, snk.write([]Entry{testEntry}), "no lead master")
+
+	setLeader("leader1")
+	assert.NoError(t, snk.write([]Entry{testEntry}))
+	assert.Equal(t, []*pb.LogEntries{{Entries: []*pb.LogEntry{testEntry.toPB()}}},
+		clients["leader1"].received)
+
+	// The client should be reused until the leader changes.
+	assert.NoError(t, snk.write([]Entry{testEntry}))
+	assert.Len(t, clients["leader1"].received, 2)
+
+	setLeader("leader2")
+	assert.NoError(t, snk.write([]Entry{testEntry}))
+	assert.True(t, closers["leader1"].closed)
+	assert.Len(t, clients["leader2"].received, 1)
+
+	setLeader("bad")
+	assert.EqualError(t, snk.write([]Entry{testEntry}), "dial error")
+
+	setLeader("leader3")
+	clientErr := errors.New("rpc error")
+	assert.NoError(t, snk.write(nil))
+	clients["leader3"].err = clientErr
+	assert.Equal(t, clientErr, snk.write([]Entry{testEntry}))
+}
+
+func TestFilePath(t *testing.T) {
+	t.Parallel()
+
+	path, err := FilePath(blueprint.LogSink{Type: blueprint.FileSink})
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/log/kelda/containers.log", path)
+
+	path, err = FilePath(blueprint.LogSink{Type: blueprint.FileSink, File: "a.log"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/log/kelda/a.log", path)
+
+	for _, name := range []string{"/etc/passwd", "..", ".", "a/b"} {
+		_, err := FilePath(blueprint.LogSink{Type: blueprint.FileSink,
+			File: name})
+		assert.Error(t, err)
+	}
+}
+
+func TestAppendToFile(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	defer func() { util.AppFs = afero.NewOsFs() }()
+
+	path := "/var/log/kelda/containers.log"
+	assert.NoError(t, AppendToFile(path, []Entry{testEntry}))
+	assert.NoError(t, AppendToFile(path, []Entry{{Line: "second"}}))
+
+	contents, err := util.ReadFile(path)
+	assert.NoError(t, err)
+
+	testJSON, err := json.Marshal(testEntry)
+	assert.NoError(t, err)
+	secondJSON, err := json.Marshal(Entry{Line: "second"})
+	assert.NoError(t, err)
+	assert.Equal(t, string(testJSON)+"\n"+string(secondJSON)+"\n", contents)
+}
+
+func TestEntriesFromPB(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []Entry{testEntry},
+		EntriesFromPB([]*pb.LogEntry{testEntry.toPB()}))
+}
@@ -0,0 +1,66 @@
+package minion
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/kelda/kelda/api"
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/db"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var clusterC = counter.New("Cluster")
+
+// serveMetrics serves the minion's metrics on its private IP.  The endpoint
+// isn't authenticated, so it mustn't be reachable from the public ranges that
+// the admin ACLs allow.
+func serveMetrics(conn db.Conn) {
+	addr := net.JoinHostPort(waitForPrivateIP(conn),
+		strconv.Itoa(api.DefaultMetricsPort))
+	if err := counter.ServeMetrics(addr); err != nil {
+		log.WithError(err).Error("Failed to serve metrics")
+	}
+}
+
+// waitForPrivateIP blocks until the foreman tells the minion its private IP.
+func waitForPrivateIP(conn db.Conn) string {
+	trigg := conn.Trigger(db.MinionTable)
+	defer trigg.Stop()
+
+	for {
+		if ip := conn.MinionSelf().PrivateIP; ip != "" {
+			return ip
+		}
+		<-trigg.C
+	}
+}
+
+// syncContainerMetrics keeps the container gauges up to date with the
+// container table.  On the leader, the table holds every container in the
+// cluster, while workers only track the containers scheduled on them.
+func syncContainerMetrics(conn db.Conn) {
+	for range conn.Trigger(db.ContainerTable).C {
+		clusterC.SetLabeledGauge("Containers", "Status",
+			containersByStatus(conn.SelectFromContainer(nil)))
+	}
+}
+
+func containersByStatus(dbcs []db.Container) map[string]float64 {
+	statuses := map[string]float64{}
+	for _, dbc := range dbcs {
+		// Only workers know the status reported by Docker, so fall back to the
+		// scheduling state if it's missing.
+		status := dbc.Status
+		switch {
+		case status != "":
+		case dbc.Minion != "":
+			status = "scheduled"
+		default:
+			status = "pending"
+		}
+		statuses[status]++
+	}
+	return statuses
+}
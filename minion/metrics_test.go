@@ -0,0 +1,48 @@
+package minion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/db"
+)
+
+func TestContainersByStatus(t *testing.T) {
+	assert.Empty(t, containersByStatus(nil))
+
+	dbcs := []db.Container{
+		{Status: "running", Minion: "1.2.3.4"},
+		{Status: "running", Minion: "1.2.3.4"},
+		{Status: "exited", Minion: "1.2.3.5"},
+		{Minion: "1.2.3.5"},
+		{},
+	}
+	assert.Equal(t, map[string]float64{
+		"running":   2,
+		"exited":    1,
+		"scheduled": 1,
+		"pending":   1,
+	}, containersByStatus(dbcs))
+}
+
+func TestWaitForPrivateIP(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		view.Commit(self)
+		return nil
+	})
+
+	ipChan := make(chan string)
+	go func() { ipChan <- waitForPrivateIP(conn) }()
+
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		self := view.MinionSelf()
+		self.PrivateIP = "10.1.2.3"
+		view.Commit(self)
+		return nil
+	})
+	assert.Equal(t, "10.1.2.3", <-ipChan)
+}
@@ -0,0 +1,102 @@
+package minion
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/ipdef"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// containerNetworkPath is where the minion stores the container network that
+// it was first configured with.  The network can't change once the minion has
+// set it up, and after a restart the minion may not be configured again until
+// its control plane is running, so the minion reuses the stored network.
+const containerNetworkPath = "/var/lib/kelda/container-network.json"
+
+// containerNetwork is the container network as configured in the blueprint.
+type containerNetwork struct {
+	Subnet         string `json:",omitempty"`
+	GatewayIP      string `json:",omitempty"`
+	LoadBalancerIP string `json:",omitempty"`
+	SubnetIPv6     string `json:",omitempty"`
+}
+
+// configureContainerNetwork sets the addresses in ipdef to the container network
+// configured in the blueprint.  If the minion hasn't stored a network yet, it
+// blocks until it's configured for the first time.
+func configureContainerNetwork(conn db.Conn) error {
+	config, err := readContainerNetwork()
+	if err != nil {
+		config = waitForContainerNetwork(conn)
+		if err := writeContainerNetwork(config); err != nil {
+			log.WithError(err).Warn("Failed to store the container network")
+		}
+	}
+
+	network, err := ipdef.ParseContainerNetwork(config.Subnet,
+		config.GatewayIP, config.LoadBalancerIP, config.SubnetIPv6)
+	if err != nil {
+		return err
+	}
+
+	ipdef.Configure(network)
+	log.WithFields(log.Fields{
+		"subnet":       network.Subnet.String(),
+		"gateway":      network.GatewayIP,
+		"loadBalancer": network.LoadBalancerIP,
+		"subnetIPv6":   network.Subnet6.String(),
+	}).Info("Configured the container network")
+	return nil
+}
+
+// waitForContainerNetwork blocks until the minion receives its first config, and
+// returns the container network in it.
+func waitForContainerNetwork(conn db.Conn) containerNetwork {
+	trigg := conn.Trigger(db.MinionTable)
+	defer trigg.Stop()
+
+	for {
+		// The private IP is always set in the minion's config.
+		self := conn.MinionSelf()
+		if self.PrivateIP != "" {
+			return containerNetwork{
+				Subnet:         self.ContainerSubnet,
+				GatewayIP:      self.GatewayIP,
+				LoadBalancerIP: self.LoadBalancerIP,
+				SubnetIPv6:     self.ContainerSubnetIPv6,
+			}
+		}
+		<-trigg.C
+	}
+}
+
+func readContainerNetwork() (containerNetwork, error) {
+	var config containerNetwork
+	configJSON, err := util.ReadFile(containerNetworkPath)
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return config, fmt.Errorf("parse %s: %s", containerNetworkPath, err)
+	}
+	return config, nil
+}
+
+func writeContainerNetwork(config containerNetwork) error {
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(containerNetworkPath)
+	if err := util.AppFs.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create %s: %s", dir, err)
+	}
+	return util.WriteFile(containerNetworkPath, configJSON, 0644)
+}
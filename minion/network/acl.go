@@ -9,6 +9,7 @@ import (
 	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/join"
+	"github.com/kelda/kelda/minion/ipdef"
 	"github.com/kelda/kelda/minion/ovsdb"
 	"github.com/kelda/kelda/util/str"
 
@@ -25,8 +26,12 @@ type connection struct {
 	from string
 	to   string
 
-	minPort int
-	maxPort int
+	minPort  int
+	maxPort  int
+	protocol string
+
+	// Whether `from` and `to` contain IPv6 addresses rather than IPv4.
+	ipv6 bool
 }
 
 func updateACLs(client ovsdb.Client, dbConns []db.Connection,
@@ -58,10 +63,27 @@ func resolveConnections(dbConns []db.Connection, hostnameToIP map[string]string)
 		}
 
 		conns = append(conns, connection{
-			minPort: dbConn.MinPort,
-			maxPort: dbConn.MaxPort,
-			from:    endpointName(from, addressSets),
-			to:      endpointName(to, addressSets),
+			minPort:  dbConn.MinPort,
+			maxPort:  dbConn.MaxPort,
+			protocol: dbConn.Protocol,
+			from:     endpointName(from, addressSets),
+			to:       endpointName(to, addressSets),
+		})
+
+		// The containers' IPv6 addresses may communicate in the same way
+		// as their IPv4 addresses.
+		from6, to6 := toIPv6(from), toIPv6(to)
+		if len(from6) == 0 || len(to6) == 0 {
+			continue
+		}
+
+		conns = append(conns, connection{
+			minPort:  dbConn.MinPort,
+			maxPort:  dbConn.MaxPort,
+			protocol: dbConn.Protocol,
+			from:     endpointName(from6, addressSets),
+			to:       endpointName(to6, addressSets),
+			ipv6:     true,
 		})
 	}
 
@@ -89,6 +111,18 @@ func resolveHostnames(hostnames []string, hostnameToIP map[string]string) []stri
 	return res
 }
 
+// toIPv6 returns the IPv6 addresses that correspond to `ips`, or nil if IPv6 is
+// disabled.
+func toIPv6(ips []string) []string {
+	var res []string
+	for _, ip := range ips {
+		if ip6 := ipdef.IPv6Str(ip); ip6 != "" {
+			res = append(res, ip6)
+		}
+	}
+	return res
+}
+
 func syncAddressSets(ovsdbClient ovsdb.Client, expSets []ovsdb.AddressSet) {
 	sets, err := ovsdbClient.ListAddressSets()
 	if err != nil {
@@ -161,17 +195,20 @@ func syncACLs(ovsdbClient ovsdb.Client, connections []connection) {
 
 	icmpMatches := map[string]struct{}{}
 	for _, conn := range connections {
-		matchStr := getMatchString(conn)
-		expACLs = append(expACLs, directedACLs(
-			ovsdb.ACL{
-				Core: ovsdb.ACLCore{
-					Action:   "allow",
-					Match:    matchStr,
-					Priority: 1,
-				},
-			})...)
-
-		icmpMatch := and(from(conn.from), to(conn.to), "icmp")
+		// ICMP connections don't have any ports to allow, and all
+		// connections allow ICMP so that the containers can ping each other.
+		if conn.protocol != blueprint.ICMP {
+			expACLs = append(expACLs, directedACLs(
+				ovsdb.ACL{
+					Core: ovsdb.ACLCore{
+						Action:   "allow",
+						Match:    getMatchString(conn),
+						Priority: 1,
+					},
+				})...)
+		}
+
+		icmpMatch := and(from(conn, conn.from), to(conn, conn.to), "icmp")
 		if _, ok := icmpMatches[icmpMatch]; !ok {
 			icmpMatches[icmpMatch] = struct{}{}
 			expACLs = append(expACLs, directedACLs(
@@ -225,24 +262,37 @@ func getMatchString(conn connection) string {
 	// corresponding source port.
 	return or(
 		and(
-			from(conn.from), to(conn.to),
-			portConstraint(conn.minPort, conn.maxPort, "dst")),
+			from(conn, conn.from), to(conn, conn.to),
+			portConstraint(conn, "dst")),
 		and(
-			from(conn.to), to(conn.from),
-			portConstraint(conn.minPort, conn.maxPort, "src")))
+			from(conn, conn.to), to(conn, conn.from),
+			portConstraint(conn, "src")))
+}
+
+func portConstraint(conn connection, direction string) string {
+	var constraints []string
+	for _, protocol := range blueprint.PortProtocols(conn.protocol) {
+		constraints = append(constraints, fmt.Sprintf("%d <= %s.%s <= %d",
+			conn.minPort, protocol, direction, conn.maxPort))
+	}
+	return or(constraints...)
 }
 
-func portConstraint(minPort, maxPort int, direction string) string {
-	return fmt.Sprintf("(%[1]d <= udp.%[2]s <= %[3]d || "+
-		"%[1]d <= tcp.%[2]s <= %[3]d)", minPort, direction, maxPort)
+func from(conn connection, ip string) string {
+	return fmt.Sprintf("%s.src == %s", ipField(conn), ip)
 }
 
-func from(ip string) string {
-	return fmt.Sprintf("ip4.src == %s", ip)
+func to(conn connection, ip string) string {
+	return fmt.Sprintf("%s.dst == %s", ipField(conn), ip)
 }
 
-func to(ip string) string {
-	return fmt.Sprintf("ip4.dst == %s", ip)
+// ipField returns the OVN match field for the addresses in `conn`.  Note that
+// the "icmp" match covers both ICMPv4 and ICMPv6.
+func ipField(conn connection) string {
+	if conn.ipv6 {
+		return "ip6"
+	}
+	return "ip4"
 }
 
 func or(predicates ...string) string {
@@ -2,6 +2,7 @@ package network
 
 import (
 	"errors"
+	"sort"
 	"testing"
 
 	"github.com/kelda/kelda/db"
@@ -95,6 +96,53 @@ func TestResolveConenctions(t *testing.T) {
 
 }
 
+func TestResolveConnectionsIPv6(t *testing.T) {
+	defer enableIPv6()()
+
+	connections, addressSets := resolveConnections([]db.Connection{{
+		From:    []string{"a"},
+		To:      []string{"b"},
+		MinPort: 80,
+		MaxPort: 80,
+	}, {
+		From:    []string{"a", "b"},
+		To:      []string{"external"},
+		MinPort: 80,
+		MaxPort: 80,
+	}}, map[string]string{
+		"a":        "10.0.0.3",
+		"b":        "10.0.0.4",
+		"external": "8.8.8.8",
+	})
+
+	assert.Len(t, addressSets, 1)
+	assert.Equal(t, []connection{{
+		from:    "10.0.0.3",
+		to:      "10.0.0.4",
+		minPort: 80,
+		maxPort: 80,
+	}, {
+		from:    "fd00::3",
+		to:      "fd00::4",
+		minPort: 80,
+		maxPort: 80,
+		ipv6:    true,
+	}, {
+		// Addresses outside of the container network have no IPv6
+		// address.
+		from:    connections[2].from,
+		to:      "8.8.8.8",
+		minPort: 80,
+		maxPort: 80,
+	}}, connections)
+
+	assert.Equal(t, "((ip6.src == fd00::3 && ip6.dst == fd00::4 && "+
+		"(80 <= tcp.dst <= 80 || 80 <= udp.dst <= 80)) || "+
+		"(ip6.src == fd00::4 && ip6.dst == fd00::3 && "+
+		"(80 <= tcp.src <= 80 || 80 <= udp.src <= 80)))",
+		getMatchString(connections[1]))
+}
+
 func TestSyncAddressSets(t *testing.T) {
 	t.Parallel()
 
@@ -157,6 +205,8 @@ func TestSyncACLs(t *testing.T) {
 		}
 	}
 
+	icmpMatch := and(from(conns[0], "8.8.8.8"), to(conns[0], "9.9.9.9"), "icmp")
+	assert.Equal(t, "(ip4.src == 8.8.8.8 && ip4.dst == 9.9.9.9 && icmp)", icmpMatch)
 	expACLs := []ovsdb.ACLCore{{
 		Priority:  0,
 		Direction: "from-lport",
@@ -170,32 +220,32 @@ func TestSyncACLs(t *testing.T) {
 	}, {
 		Priority:  1,
 		Direction: "from-lport",
-		Match:     and(from("8.8.8.8"), to("9.9.9.9"), "icmp"),
+		Match:     icmpMatch,
 		Action:    "allow-related",
 	}, {
 		Priority:  1,
 		Direction: "to-lport",
-		Match:     and(from("8.8.8.8"), to("9.9.9.9"), "icmp"),
+		Match:     icmpMatch,
 		Action:    "allow-related",
 	}, {
 		Priority:  1,
 		Direction: "from-lport",
-		Match:     getMatchString(connection{"8.8.8.8", "9.9.9.9", 80, 80}),
+		Match:     getMatchString(conns[0]),
 		Action:    "allow",
 	}, {
 		Priority:  1,
 		Direction: "to-lport",
-		Match:     getMatchString(connection{"8.8.8.8", "9.9.9.9", 80, 80}),
+		Match:     getMatchString(conns[0]),
 		Action:    "allow",
 	}, {
 		Priority:  1,
 		Direction: "from-lport",
-		Match:     getMatchString(connection{"8.8.8.8", "9.9.9.9", 8080, 8080}),
+		Match:     getMatchString(conns[1]),
 		Action:    "allow",
 	}, {
 		Priority:  1,
 		Direction: "to-lport",
-		Match:     getMatchString(connection{"8.8.8.8", "9.9.9.9", 8080, 8080}),
+		Match:     getMatchString(conns[1]),
 		Action:    "allow",
 	}}
 
@@ -211,3 +261,43 @@ func TestSyncACLs(t *testing.T) {
 	syncACLs(client, conns)
 	client.AssertCalled(t, "ListACLs")
 }
+
+func TestMatchStringProtocol(t *testing.T) {
+	t.Parallel()
+
+	conns, _ := resolveConnections([]db.Connection{{
+		From:     []string{"a"},
+		To:       []string{"b"},
+		MinPort:  53,
+		MaxPort:  53,
+		Protocol: "udp",
+	}}, map[string]string{"a": "10.0.0.2", "b": "10.0.0.3"})
+	assert.Len(t, conns, 1)
+	assert.Equal(t, "((ip4.src == 10.0.0.2 && ip4.dst == 10.0.0.3 && "+
+		"(53 <= udp.dst <= 53)) || "+
+		"(ip4.src == 10.0.0.3 && ip4.dst == 10.0.0.2 && "+
+		"(53 <= udp.src <= 53)))", getMatchString(conns[0]))
+}
+
+func TestSyncACLsICMP(t *testing.T) {
+	t.Parallel()
+	client := new(mocks.Client)
+	client.On("ListACLs").Return(nil, nil)
+	client.On("CreateACLs", "kelda", mock.Anything).Return(nil)
+
+	conn := connection{from: "8.8.8.8", to: "9.9.9.9", protocol: "icmp"}
+	syncACLs(client, []connection{conn})
+
+	// ICMP connections only allow ICMP.
+	icmpMatch := "(ip4.src == 8.8.8.8 && ip4.dst == 9.9.9.9 && icmp)"
+	client.AssertCalled(t, "CreateACLs", "kelda", mock.MatchedBy(
+		func(acls []ovsdb.ACLCore) bool {
+			var matches []string
+			for _, acl := range acls {
+				matches = append(matches, acl.Match)
+			}
+			sort.Strings(matches)
+			return assert.ObjectsAreEqual([]string{
+				icmpMatch, icmpMatch, "ip", "ip"}, matches)
+		}))
+}
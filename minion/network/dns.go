@@ -162,17 +162,29 @@ func (table *dnsTable) genResponse(req *dns.Msg) *dns.Msg {
 	}
 
 	q := req.Question[0]
-	// If the request is for an IPv6 address, simply return an empty answer to
+	// If there are no IPv6 addresses, simply return an empty answer to
 	// indicate that there may be answers for other query types, such as IPv4.
 	if q.Qtype == dns.TypeAAAA {
-		return resp.SetReply(req)
+		resp.SetReply(req)
+		for _, ip := range table.lookup(q.Name, true) {
+			resp.Answer = append(resp.Answer, &dns.AAAA{
+				Hdr: dns.RR_Header{
+					Name:   q.Name,
+					Rrtype: dns.TypeAAAA,
+					Class:  dns.ClassINET,
+					Ttl:    dnsTTL,
+				},
+				AAAA: ip,
+			})
+		}
+		return resp
 	}
 
 	if q.Qclass != dns.ClassINET || q.Qtype != dns.TypeA {
 		return resp.SetRcode(req, dns.RcodeNotImplemented)
 	}
 
-	ips := table.lookupA(q.Name)
+	ips := table.lookup(q.Name, false)
 	if len(ips) == 0 {
 		// Even though the client asked for a hostname within `.q` that we know
 		// nothing about, it's possible we'll learn about it in the future.  For
@@ -202,19 +214,30 @@ func (table *dnsTable) genResponse(req *dns.Msg) *dns.Msg {
 	return resp
 }
 
-func (table *dnsTable) lookupA(name string) []net.IP {
+// lookup returns the IPv4 addresses of `name`, or its IPv6 addresses if `ipv6` is
+// set.
+func (table *dnsTable) lookup(name string, ipv6 bool) []net.IP {
 	name = strings.ToLower(name)
 	if strings.HasSuffix(name, ".q.") {
 		dnsC.Inc("Lookup Internal")
 		table.recordLock.Lock()
 		ip := table.records[name]
 		table.recordLock.Unlock()
+		if ipv6 {
+			ip = ipdef.IPv6(ip)
+		}
 		if ip == nil {
 			return nil
 		}
 		return []net.IP{ip}
 	}
 
+	// Containers can only reach external IPv6 addresses if they have IPv6
+	// addresses of their own.
+	if ipv6 && !ipdef.IPv6Enabled() {
+		return nil
+	}
+
 	dnsC.Inc("Lookup External")
 	ipStrs, err := lookupHost(strings.TrimRight(name, "."))
 	if err != nil {
@@ -224,7 +247,8 @@ func (table *dnsTable) lookupA(name string) []net.IP {
 
 	var ips []net.IP
 	for _, ipStr := range ipStrs {
-		if ip := net.ParseIP(ipStr); ip != nil && ip.To4() != nil {
+		ip := net.ParseIP(ipStr)
+		if ip != nil && (ip.To4() == nil) == ipv6 {
 			ips = append(ips, ip)
 		}
 	}
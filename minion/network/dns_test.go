@@ -78,33 +78,73 @@ func TestGenResponse(t *testing.T) {
 
 }
 
-func TestLookupA(t *testing.T) {
+func TestLookup(t *testing.T) {
 	t.Parallel()
 
 	table := makeTable(map[string]net.IP{
 		"a.q.": net.IPv4(1, 2, 3, 4),
 	})
 
-	assert.Empty(t, table.lookupA("bad.q."))
-	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookupA("a.q."))
-	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookupA("A.Q."))
+	assert.Empty(t, table.lookup("bad.q.", false))
+	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookup("a.q.", false))
+	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookup("A.Q.", false))
 
 	lookupHost = func(string) ([]string, error) { return nil, assert.AnError }
-	assert.Empty(t, table.lookupA("kelda.io."))
+	assert.Empty(t, table.lookup("kelda.io.", false))
 
 	lookupHost = func(string) ([]string, error) { return []string{"bad"}, nil }
-	assert.Empty(t, table.lookupA("kelda.io."))
+	assert.Empty(t, table.lookup("kelda.io.", false))
 
 	lookupHost = func(string) ([]string, error) {
 		return []string{"2601:644:380:cde:fc06:2533:adf9:2891"}, nil
 	}
-	assert.Empty(t, table.lookupA("kelda.io."))
+	assert.Empty(t, table.lookup("kelda.io.", false))
 
 	lookupHost = func(string) ([]string, error) {
 		return []string{"1.2.3.4", "5.6.7.8"}, nil
 	}
 	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4), net.IPv4(5, 6, 7, 8)},
-		table.lookupA("kelda.io."))
+		table.lookup("kelda.io.", false))
+
+	// There are no IPv6 addresses unless IPv6 is enabled.
+	assert.Empty(t, table.lookup("a.q.", true))
+	assert.Empty(t, table.lookup("kelda.io.", true))
+}
+
+func TestLookupIPv6(t *testing.T) {
+	defer enableIPv6()()
+	defer func() { lookupHost = net.LookupHost }()
+
+	table := makeTable(map[string]net.IP{
+		"a.q.":        net.IPv4(10, 0, 0, 5),
+		"external.q.": net.IPv4(1, 2, 3, 4),
+	})
+	assert.Equal(t, []net.IP{net.ParseIP("fd00::5")}, table.lookup("a.q.", true))
+	assert.Empty(t, table.lookup("external.q.", true))
+	assert.Empty(t, table.lookup("bad.q.", true))
+
+	lookupHost = func(string) ([]string, error) {
+		return []string{"1.2.3.4", "2601:644:380:cde:fc06:2533:adf9:2891"}, nil
+	}
+	assert.Equal(t, []net.IP{net.ParseIP("2601:644:380:cde:fc06:2533:adf9:2891")},
+		table.lookup("kelda.io.", true))
+
+	req := &dns.Msg{}
+	req.SetQuestion("a.q.", dns.TypeAAAA)
+	resp := table.genResponse(req)
+	exp := *req
+	exp.Response = true
+	exp.Rcode = dns.RcodeSuccess
+	exp.Answer = []dns.RR{&dns.AAAA{
+		Hdr: dns.RR_Header{
+			Name:   "a.q.",
+			Rrtype: dns.TypeAAAA,
+			Class:  dns.ClassINET,
+			Ttl:    dnsTTL,
+		},
+		AAAA: net.ParseIP("fd00::5"),
+	}}
+	assert.Equal(t, &exp, resp)
 }
 
 func TestMakeTable(t *testing.T) {
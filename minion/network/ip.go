@@ -112,7 +112,9 @@ func updateIPsOnce(view db.Database) error {
 
 // makeSubnetBlacklist returns all subnets that are governed by routes in a
 // worker machine's network stack, and intersect with the Kelda container
-// subnet.
+// subnet.  It fails if a worker's subnet contains the whole container subnet, or
+// the gateway or load balancer IPs, because the container network can't work
+// alongside it.
 func makeSubnetBlacklist(view db.Database) ([]net.IPNet, error) {
 	isWorker := func(m db.Minion) bool {
 		return m.Role == db.Worker
@@ -132,9 +134,21 @@ func makeSubnetBlacklist(view db.Database) ([]net.IPNet, error) {
 			return nil, fmt.Errorf("parse subnet %s: %s", subnetStr, err)
 		}
 
-		if subnetIntersects(ipdef.KeldaSubnet, *subnet) {
-			subnetBlacklist = append(subnetBlacklist, *subnet)
+		if !subnetIntersects(ipdef.KeldaSubnet, *subnet) {
+			continue
+		}
+
+		kOnes, _ := ipdef.KeldaSubnet.Mask.Size()
+		sOnes, _ := subnet.Mask.Size()
+		if sOnes <= kOnes || subnet.Contains(ipdef.GatewayIP) ||
+			subnet.Contains(ipdef.LoadBalancerIP) {
+			return nil, fmt.Errorf("the container subnet %s, with "+
+				"gateway %s and load balancer %s, conflicts with the "+
+				"subnet %s of a worker; configure a different container "+
+				"subnet in the blueprint", ipdef.KeldaSubnet.String(),
+				ipdef.GatewayIP, ipdef.LoadBalancerIP, subnet)
 		}
+		subnetBlacklist = append(subnetBlacklist, *subnet)
 	}
 	return subnetBlacklist, nil
 }
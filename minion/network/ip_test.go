@@ -67,6 +67,28 @@ func TestMakeSubnetBlacklist(t *testing.T) {
 	})
 }
 
+func TestMakeSubnetBlacklistConflict(t *testing.T) {
+	t.Parallel()
+
+	expErr := "the container subnet 10.0.0.0/8, with gateway 10.0.0.1 and " +
+		"load balancer 10.0.0.2, conflicts with the subnet %s of a worker; " +
+		"configure a different container subnet in the blueprint"
+	for _, subnet := range []string{"10.0.0.0/8", "0.0.0.0/1", "10.0.0.0/24",
+		"10.0.0.2/32"} {
+		conn := db.New()
+		conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			m := view.InsertMinion()
+			m.Role = db.Worker
+			m.HostSubnets = []string{"10.0.1.0/24", subnet}
+			view.Commit(m)
+
+			_, err := makeSubnetBlacklist(view)
+			assert.EqualError(t, err, fmt.Sprintf(expErr, subnet))
+			return nil
+		})
+	}
+}
+
 func TestMakeIPContext(t *testing.T) {
 	t.Parallel()
 
@@ -61,21 +61,27 @@ func updateLoadBalancerIPs(client ovsdb.Client, loadBalancers []db.LoadBalancer,
 
 	var target []ovsdb.LoadBalancer
 	for _, lb := range loadBalancers {
-		var ips []string
+		var ips, ip6s []string
 		for _, hostname := range lb.Hostnames {
 			ip := hostnameToIP[hostname]
 			if ip != "" {
 				ips = append(ips, ip)
 			}
+			if ip6 := ipdef.IPv6Str(ip); ip6 != "" {
+				ip6s = append(ip6s, ip6)
+			}
 		}
 		// Ignore the ip order.
 		sort.Strings(ips)
+		sort.Strings(ip6s)
 
+		vips := map[string]string{lb.IP: strings.Join(ips, ",")}
+		if ip6 := ipdef.IPv6Str(lb.IP); ip6 != "" {
+			vips[ip6] = strings.Join(ip6s, ",")
+		}
 		target = append(target, ovsdb.LoadBalancer{
 			Name: lb.Name,
-			VIPs: map[string]string{
-				lb.IP: strings.Join(ips, ","),
-			},
+			VIPs: vips,
 		})
 	}
 
@@ -111,7 +117,8 @@ func updateLoadBalancerIPs(client ovsdb.Client, loadBalancers []db.LoadBalancer,
 
 // updateLoadBalancerARP updates the `addresses` field of the logical switch
 // port attached to the load balancer router. This is necessary so that the
-// switch port synthesizes ARP responses to load balanced VIPs.
+// switch port synthesizes ARP and neighbor discovery responses to load balanced
+// VIPs.
 func updateLoadBalancerARP(client ovsdb.Client, loadBalancers []db.LoadBalancer) {
 	curr, err := client.ListSwitchPort(loadBalancerSwitchPort)
 	if err != nil {
@@ -121,7 +128,7 @@ func updateLoadBalancerARP(client ovsdb.Client, loadBalancers []db.LoadBalancer)
 
 	var loadBalancedIPs []string
 	for _, lb := range loadBalancers {
-		loadBalancedIPs = append(loadBalancedIPs, lb.IP)
+		loadBalancedIPs = append(loadBalancedIPs, dualStack(lb.IP)...)
 	}
 	// Ignore the order of `loadBalancers`.
 	sort.Strings(loadBalancedIPs)
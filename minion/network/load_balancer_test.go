@@ -55,6 +55,26 @@ func TestUpdateLoadBalancerIPs(t *testing.T) {
 	client.AssertExpectations(t)
 }
 
+func TestUpdateLoadBalancerIPsIPv6(t *testing.T) {
+	defer enableIPv6()()
+
+	client := new(mocks.Client)
+	client.On("ListLoadBalancers").Return(nil, nil).Once()
+	client.On("CreateLoadBalancer", lSwitch, "red", map[string]string{
+		"10.0.0.10": "10.0.0.3,10.0.0.4",
+		"fd00::a":   "fd00::3,fd00::4",
+	}).Return(nil)
+	updateLoadBalancerIPs(client, []db.LoadBalancer{{
+		Name:      "red",
+		IP:        "10.0.0.10",
+		Hostnames: []string{"red", "blue"},
+	}}, map[string]string{
+		"red":  "10.0.0.4",
+		"blue": "10.0.0.3",
+	})
+	client.AssertExpectations(t)
+}
+
 func TestUpdateLoadBalancerARP(t *testing.T) {
 	client := new(mocks.Client)
 
@@ -87,3 +107,15 @@ func TestUpdateLoadBalancerARP(t *testing.T) {
 	client.AssertNotCalled(t, "UpdateSwitchPortAddresses",
 		mock.Anything, mock.Anything)
 }
+
+func TestUpdateLoadBalancerARPIPv6(t *testing.T) {
+	defer enableIPv6()()
+
+	client := new(mocks.Client)
+	client.On("ListSwitchPort", mock.Anything).Return(
+		ovsdb.SwitchPort{}, nil).Once()
+	client.On("UpdateSwitchPortAddresses", loadBalancerSwitchPort,
+		[]string{ipdef.LoadBalancerMac + " 10.0.0.10 fd00::a"}).Return(nil)
+	updateLoadBalancerARP(client, []db.LoadBalancer{{IP: "10.0.0.10"}})
+	client.AssertExpectations(t)
+}
@@ -3,13 +3,18 @@ package network
 import (
 	"errors"
 	"fmt"
+	"net"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/cloud/acl"
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/join"
+	"github.com/kelda/kelda/minion/ipdef"
 	"github.com/kelda/kelda/minion/nl"
 	"github.com/kelda/kelda/util/str"
 
@@ -51,9 +56,38 @@ func runNat(conn db.Conn, inboundPubIntf, outboundPubIntf string) {
 		if err != nil {
 			log.WithError(err).Error("Failed to update NAT rules")
 		}
+
+		if !ipdef.IPv6Enabled() {
+			continue
+		}
+
+		ip6t, err := iptables.NewWithProtocol(iptables.ProtocolIPv6)
+		if err != nil {
+			log.WithError(err).Error("Failed to get ip6tables handle")
+			continue
+		}
+
+		err = updateNAT(ip6t, ipv6Containers(containers), connections,
+			inboundPubIntf, outboundPubIntf)
+		if err != nil {
+			log.WithError(err).Error("Failed to update IPv6 NAT rules")
+		}
 	}
 }
 
+// ipv6Containers returns copies of `containers` with their IPv6 addresses in
+// place of their IPv4 addresses, so that the same NAT rules can be generated for
+// ip6tables.
+func ipv6Containers(containers []db.Container) []db.Container {
+	var res []db.Container
+	for _, dbc := range containers {
+		if dbc.IP = ipdef.IPv6Str(dbc.IP); dbc.IP != "" {
+			res = append(res, dbc)
+		}
+	}
+	return res
+}
+
 // pickIntfs converts the command line arguments for NAT interfaces to the names
 // that should actually be used in the iptables rules.
 // If an interface is not specificied (i.e. the empty string is supplied), we use
@@ -197,7 +231,7 @@ func preroutingRules(publicInterface string, containers []db.Container,
 
 	// Map each hostname to all ports on which it can receive packets
 	// from the public internet.
-	portsFromWeb := make(map[string]map[int]struct{})
+	portsFromWeb := make(map[string]map[publicPort]struct{})
 	for _, conn := range connections {
 		if !str.SliceContains(conn.From, blueprint.PublicInternetLabel) {
 			continue
@@ -205,23 +239,25 @@ func preroutingRules(publicInterface string, containers []db.Container,
 
 		for _, to := range conn.To {
 			if _, ok := portsFromWeb[to]; !ok {
-				portsFromWeb[to] = make(map[int]struct{})
+				portsFromWeb[to] = make(map[publicPort]struct{})
 			}
 
-			portsFromWeb[to][conn.MinPort] = struct{}{}
+			for _, protocol := range blueprint.PortProtocols(conn.Protocol) {
+				port := publicPort{conn.MinPort, protocol}
+				portsFromWeb[to][port] = struct{}{}
+			}
 		}
 	}
 
 	// Map the container's port to the same port of the host.
 	for _, dbc := range containers {
-		for port := range portsFromWeb[dbc.Hostname] {
-			for _, protocol := range []string{"tcp", "udp"} {
-				rules = append(rules, fmt.Sprintf(
-					"-i %[1]s -p %[2]s -m %[2]s "+
-						"--dport %[3]d -j DNAT "+
-						"--to-destination %[4]s:%[3]d",
-					publicInterface, protocol, port, dbc.IP))
-			}
+		for _, port := range sortPorts(portsFromWeb[dbc.Hostname]) {
+			dest := net.JoinHostPort(dbc.IP, strconv.Itoa(port.port))
+			rules = append(rules, fmt.Sprintf(
+				"-i %[1]s -p %[2]s -m %[2]s "+
+					"--dport %[3]d -j DNAT "+
+					"--to-destination %[4]s",
+				publicInterface, port.protocol, port.port, dest))
 		}
 	}
 
@@ -233,7 +269,7 @@ func postroutingRules(publicInterface string, containers []db.Container,
 
 	// Map each hostname to all ports on which it can send packets
 	// to the public internet.
-	portsToWeb := make(map[string]map[int]struct{})
+	portsToWeb := make(map[string]map[publicPort]struct{})
 	for _, conn := range connections {
 		for _, to := range conn.To {
 			if to != blueprint.PublicInternetLabel {
@@ -243,29 +279,54 @@ func postroutingRules(publicInterface string, containers []db.Container,
 
 		for _, from := range conn.From {
 			if _, ok := portsToWeb[from]; !ok {
-				portsToWeb[from] = make(map[int]struct{})
+				portsToWeb[from] = make(map[publicPort]struct{})
 			}
 
-			portsToWeb[from][conn.MinPort] = struct{}{}
+			for _, protocol := range blueprint.PortProtocols(conn.Protocol) {
+				port := publicPort{conn.MinPort, protocol}
+				portsToWeb[from][port] = struct{}{}
+			}
 		}
 	}
 
 	for _, dbc := range containers {
-		for port := range portsToWeb[dbc.Hostname] {
-			for _, protocol := range []string{"tcp", "udp"} {
-				rules = append(rules, fmt.Sprintf(
-					"-s %[1]s/32 -p %[2]s -m %[2]s "+
-						"--dport %[3]d -o %[4]s "+
-						"-j MASQUERADE",
-					dbc.IP, protocol, port, publicInterface,
-				))
-			}
+		for _, port := range sortPorts(portsToWeb[dbc.Hostname]) {
+			rules = append(rules, fmt.Sprintf(
+				"-s %[1]s -p %[2]s -m %[2]s "+
+					"--dport %[3]d -o %[4]s "+
+					"-j MASQUERADE",
+				acl.HostCIDR(dbc.IP), port.protocol, port.port,
+				publicInterface,
+			))
 		}
 	}
 
 	return rules
 }
 
+// A publicPort is a port and protocol on which a container may communicate with
+// the public internet.
+type publicPort struct {
+	port     int
+	protocol string
+}
+
+// sortPorts returns the ports in `set`, sorted by port number and then protocol so
+// that the generated rules are deterministic.
+func sortPorts(set map[publicPort]struct{}) []publicPort {
+	var ports []publicPort
+	for port := range set {
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].port != ports[j].port {
+			return ports[i].port < ports[j].port
+		}
+		return ports[i].protocol < ports[j].protocol
+	})
+	return ports
+}
+
 type rule struct {
 	table  string
 	chain  string
@@ -130,6 +130,77 @@ func TestPostroutingRules(t *testing.T) {
 	assert.Equal(t, exp, actual)
 }
 
+func TestNATRulesProtocol(t *testing.T) {
+	t.Parallel()
+
+	containers := []db.Container{{IP: "8.8.8.8", Hostname: "red"}}
+	connections := []db.Connection{
+		{
+			From:     []string{blueprint.PublicInternetLabel},
+			To:       []string{"red"},
+			MinPort:  53,
+			Protocol: blueprint.UDP,
+		},
+		{
+			From:     []string{blueprint.PublicInternetLabel},
+			To:       []string{"red"},
+			Protocol: blueprint.ICMP,
+		},
+		{
+			From:     []string{"red"},
+			To:       []string{blueprint.PublicInternetLabel},
+			MinPort:  443,
+			Protocol: blueprint.TCP,
+		},
+	}
+
+	assert.Equal(t, []string{
+		"-i eth0 -p udp -m udp --dport 53 -j DNAT --to-destination 8.8.8.8:53",
+	}, preroutingRules("eth0", containers, connections))
+	assert.Equal(t, []string{
+		"-s 8.8.8.8/32 -p tcp -m tcp --dport 443 -o eth0 -j MASQUERADE",
+	}, postroutingRules("eth0", containers, connections))
+}
+
+func TestIPv6NATRules(t *testing.T) {
+	defer enableIPv6()()
+
+	containers := ipv6Containers([]db.Container{
+		{IP: "10.0.0.5", Hostname: "red"},
+		{Hostname: "unallocated"},
+	})
+	assert.Equal(t, []db.Container{{IP: "fd00::5", Hostname: "red"}}, containers)
+
+	connections := []db.Connection{{
+		From:    []string{blueprint.PublicInternetLabel},
+		To:      []string{"red"},
+		MinPort: 80,
+	}, {
+		From:    []string{"red"},
+		To:      []string{blueprint.PublicInternetLabel},
+		MinPort: 443,
+	}}
+
+	actual := preroutingRules("eth0", containers, connections)
+	sort.Strings(actual)
+	assert.Equal(t, []string{
+		"-i eth0 -p tcp -m tcp --dport 80 -j DNAT --to-destination [fd00::5]:80",
+		"-i eth0 -p udp -m udp --dport 80 -j DNAT --to-destination [fd00::5]:80",
+	}, actual)
+
+	actual = postroutingRules("eth0", containers, connections)
+	sort.Strings(actual)
+	assert.Equal(t, []string{
+		"-s fd00::5/128 -p tcp -m tcp --dport 443 -o eth0 -j MASQUERADE",
+		"-s fd00::5/128 -p udp -m udp --dport 443 -o eth0 -j MASQUERADE",
+	}, actual)
+
+	// Without IPv6, there are no IPv6 rules.
+	ipdef.Configure(ipdef.ContainerNetwork{Subnet: ipdef.KeldaSubnet,
+		GatewayIP: ipdef.GatewayIP, LoadBalancerIP: ipdef.LoadBalancerIP})
+	assert.Empty(t, ipv6Containers([]db.Container{{IP: "10.0.0.5"}}))
+}
+
 func TestGetRules(t *testing.T) {
 	ipt := &mocks.IPTables{}
 	ipt.On("List", "nat", "PREROUTING").Return([]string{
@@ -6,6 +6,8 @@
 package network
 
 import (
+	"strings"
+
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/join"
@@ -111,11 +113,13 @@ func updateLogicalSwitch(ovsdbClient ovsdb.Client, containers []db.Container) {
 		},
 	}
 	for _, dbc := range containers {
+		addresses := append([]string{ipdef.IPStrToMac(dbc.IP)},
+			dualStack(dbc.IP)...)
 		expPorts = append(expPorts, ovsdb.SwitchPort{
 			Name: dbc.IP,
 			// OVN represents network interfaces with the empty string.
 			Type:      "",
-			Addresses: []string{ipdef.IPStrToMac(dbc.IP) + " " + dbc.IP},
+			Addresses: []string{strings.Join(addresses, " ")},
 		})
 	}
 
@@ -169,11 +173,15 @@ func updateLoadBalancerRouter(ovsdbClient ovsdb.Client) {
 		return
 	}
 
+	networks := []string{ipdef.KeldaSubnet.String()}
+	if ipdef.IPv6Enabled() {
+		networks = append(networks, ipdef.KeldaSubnet6.String())
+	}
 	expPorts := []ovsdb.RouterPort{
 		{
 			Name:     loadBalancerRouterPort,
 			MAC:      ipdef.LoadBalancerMac,
-			Networks: []string{ipdef.KeldaSubnet.String()},
+			Networks: networks,
 		},
 	}
 
@@ -205,3 +213,11 @@ func updateLoadBalancerRouter(ovsdbClient ovsdb.Client) {
 		}
 	}
 }
+
+// dualStack returns `ip` followed by its IPv6 address, if IPv6 is enabled.
+func dualStack(ip string) []string {
+	if ip6 := ipdef.IPv6Str(ip); ip6 != "" {
+		return []string{ip, ip6}
+	}
+	return []string{ip}
+}
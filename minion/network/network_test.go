@@ -54,6 +54,21 @@ func TestUpdateLogicalSwitch(t *testing.T) {
 	client.AssertExpectations(t)
 }
 
+func TestUpdateLogicalSwitchIPv6(t *testing.T) {
+	defer enableIPv6()()
+
+	client := new(mocks.Client)
+	client.On("LogicalSwitchExists", lSwitch).Return(true, nil)
+	client.On("ListSwitchPorts").Return([]ovsdb.SwitchPort{
+		{Name: loadBalancerSwitchPort}}, nil)
+	client.On("CreateSwitchPort", lSwitch, ovsdb.SwitchPort{
+		Name:      "10.0.1.4",
+		Addresses: []string{"02:00:0a:00:01:04 10.0.1.4 fd00::104"},
+	}).Return(nil).Once()
+	updateLogicalSwitch(client, []db.Container{{IP: "10.0.1.4"}})
+	client.AssertExpectations(t)
+}
+
 func TestCreateLogicalSwitch(t *testing.T) {
 	t.Parallel()
 
@@ -125,3 +140,28 @@ func TestUpdateLoadBalancerRouter(t *testing.T) {
 	updateLoadBalancerRouter(client)
 	client.AssertExpectations(t)
 }
+
+func TestUpdateLoadBalancerRouterIPv6(t *testing.T) {
+	defer enableIPv6()()
+
+	client := new(mocks.Client)
+	client.On("LogicalRouterExists", loadBalancerRouter).Return(true, nil)
+	client.On("ListRouterPorts").Return(nil, nil)
+	client.On("CreateRouterPort", loadBalancerRouter, ovsdb.RouterPort{
+		Name:     loadBalancerRouterPort,
+		MAC:      ipdef.LoadBalancerMac,
+		Networks: []string{"10.0.0.0/8", "fd00::/64"},
+	}).Return(nil).Once()
+	updateLoadBalancerRouter(client)
+	client.AssertExpectations(t)
+}
+
+// enableIPv6 configures the default container network with the IPv6 subnet
+// fd00::/64, and returns a function that disables IPv6 again.  Tests that call
+// it must not run in parallel.
+func enableIPv6() func() {
+	defaultNetwork, _ := ipdef.ParseContainerNetwork("", "", "", "")
+	network, _ := ipdef.ParseContainerNetwork("", "", "", "fd00::/64")
+	ipdef.Configure(network)
+	return func() { ipdef.Configure(defaultNetwork) }
+}
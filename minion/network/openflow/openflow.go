@@ -68,6 +68,11 @@ Table_1 {
 		output:LOCAL,reg0
 	}
 
+	// Likewise for IPv6 neighbor discovery, if IPv6 is enabled.
+	if icmp6,dl_dst=33:33:00:00:00:00/ff:ff:00:00:00:00 {
+		output:LOCAL,reg0
+	}
+
 	// Send packets from the veth to the gateway.
 	if dl_dst=gwMac {
 		goto Table_3
@@ -79,8 +84,9 @@ Table_1 {
 
 // Table_2 forwards packets coming from the LOCAL port.
 Table_2 {
-	// If the gateway sends a broadcast, send it to all veths.
-	if dl_dst=ff:ff:ff:ff:ff:ff {
+	// If the gateway sends a broadcast, or an IPv6 multicast, send it to all
+	// veths.
+	if dl_dst=ff:ff:ff:ff:ff:ff || dl_dst=33:33:00:00:00:00/ff:ff:00:00:00:00 {
 		output:veth{1..n}
 	}
 
@@ -95,6 +101,15 @@ Table_2 {
 			output:veth
 		}
 
+		// If IPv6 is enabled, the same goes for neighbor discovery and
+		// packets from the gateway's IPv6 address.
+		if icmp6 && dl_dst=dbc.mac {
+			output:veth
+		}
+		if ipv6 && dl_dst=dbc.mac && ipv6_src=gwIP6 {
+			output:veth
+		}
+
 		for each toPub {
 			// Response packets have toPub as the source port.
 			[tcp|udp],dl_dst=dbc.mac,ip_dst=dbc.ip,tp_src=toPub,
@@ -123,6 +138,15 @@ Table_3 {
 		output:LOCAL
 	}
 
+	// If IPv6 is enabled, the same goes for the gateway's IPv6 address, and
+	// neighbor discovery.
+	if ipv6 && ipv6_dst=gwIP6 {
+		output:LOCAL
+	}
+	if icmp6 && icmp_type=135 || icmp6 && icmp_type=136 {
+		output:LOCAL
+	}
+
 	for each db.Container {
 		for each toPub {
 			// Outbound packets have fromPub as the destination port.
@@ -138,6 +162,9 @@ Table_3 {
 	}
 }
 
+The public port flows in Table_2 and Table_3 are repeated for the containers' IPv6
+addresses, matching [tcp6|udp6] and ipv6_src or ipv6_dst.
+
 */
 
 // A Container that needs OpenFlow rules installed for it.
@@ -161,21 +188,45 @@ type container struct {
 
 var c = counter.New("OpenFlow")
 
-var staticFlows = []string{
-	// Table 0
-	"table=0,priority=1000,in_port=LOCAL,actions=resubmit(,2)",
-
-	// Table 1
-	"table=1,priority=1000,arp,dl_dst=ff:ff:ff:ff:ff:ff," +
-		"actions=output:LOCAL,output:NXM_NX_REG0[]",
-	fmt.Sprintf("table=1,priority=900,dl_dst=%s,actions=resubmit(,3)",
-		ipdef.GatewayMac),
-	"table=1,priority=800,actions=output:NXM_NX_REG0[]",
-
-	// Table 3
-	fmt.Sprintf("table=3,priority=1000,ip,nw_dst=%s,actions=output:LOCAL",
-		ipdef.GatewayIP),
-	"table=3,priority=900,arp,actions=output:LOCAL",
+// ipv6Multicast matches the Ethernet addresses of IPv6 multicast packets, which
+// include neighbor solicitations.
+const ipv6Multicast = "33:33:00:00:00:00/ff:ff:00:00:00:00"
+
+// staticFlows returns the flows that don't depend on the containers.  They're
+// built on each call because the gateway isn't known until the container
+// network is configured.
+func staticFlows() []string {
+	flows := []string{
+		// Table 0
+		"table=0,priority=1000,in_port=LOCAL,actions=resubmit(,2)",
+
+		// Table 1
+		"table=1,priority=1000,arp,dl_dst=ff:ff:ff:ff:ff:ff," +
+			"actions=output:LOCAL,output:NXM_NX_REG0[]",
+		fmt.Sprintf("table=1,priority=900,dl_dst=%s,actions=resubmit(,3)",
+			ipdef.GatewayMac),
+		"table=1,priority=800,actions=output:NXM_NX_REG0[]",
+
+		// Table 3
+		fmt.Sprintf("table=3,priority=1000,ip,nw_dst=%s,actions=output:LOCAL",
+			ipdef.GatewayIP),
+		"table=3,priority=900,arp,actions=output:LOCAL",
+	}
+
+	if !ipdef.IPv6Enabled() {
+		return flows
+	}
+
+	return append(flows,
+		// Table 1
+		fmt.Sprintf("table=1,priority=1000,icmp6,dl_dst=%s,"+
+			"actions=output:LOCAL,output:NXM_NX_REG0[]", ipv6Multicast),
+
+		// Table 3
+		fmt.Sprintf("table=3,priority=1000,ipv6,ipv6_dst=%s,"+
+			"actions=output:LOCAL", ipdef.GatewayIP6),
+		"table=3,priority=900,icmp6,icmp_type=135,actions=output:LOCAL",
+		"table=3,priority=900,icmp6,icmp_type=136,actions=output:LOCAL")
 }
 
 // ReplaceFlows adds flows associated with the provided containers, and removes all
@@ -270,6 +321,48 @@ func containerFlows(c container) []string {
 			fmt.Sprintf(table3, "udp", c.Mac, c.IP, from))
 	}
 
+	if ip6 := ipdef.IPv6Str(c.IP); ip6 != "" {
+		flows = append(flows, ipv6ContainerFlows(c, ip6)...)
+	}
+	return flows
+}
+
+// ipv6ContainerFlows returns the flows for the IPv6 address `ip6` of `c`.  They
+// mirror the IPv4 flows, with neighbor discovery in place of ARP.
+func ipv6ContainerFlows(c container, ip6 string) []string {
+	flows := []string{
+		// Table 2
+		fmt.Sprintf("table=2,priority=900,icmp6,dl_dst=%s,action=output:%d",
+			c.Mac, c.vethPort),
+		fmt.Sprintf("table=2,priority=800,ipv6,dl_dst=%s,ipv6_src=%s,"+
+			"action=output:%d", c.Mac, ipdef.GatewayIP6, c.vethPort),
+	}
+
+	table2 := "table=2,priority=500,%s,dl_dst=%s,ipv6_dst=%s,tp_src=%d," +
+		"actions=output:%d"
+	table3 := "table=3,priority=500,%s,dl_src=%s,ipv6_src=%s,tp_dst=%d," +
+		"actions=output:LOCAL"
+	for to := range c.Container.ToPub {
+		flows = append(flows,
+			fmt.Sprintf(table2, "tcp6", c.Mac, ip6, to, c.vethPort),
+			fmt.Sprintf(table2, "udp6", c.Mac, ip6, to, c.vethPort),
+
+			fmt.Sprintf(table3, "tcp6", c.Mac, ip6, to),
+			fmt.Sprintf(table3, "udp6", c.Mac, ip6, to))
+	}
+
+	table2 = "table=2,priority=500,%s,dl_dst=%s,ipv6_dst=%s,tp_dst=%d," +
+		"actions=output:%d"
+	table3 = "table=3,priority=500,%s,dl_src=%s,ipv6_src=%s,tp_src=%d," +
+		"actions=output:LOCAL"
+	for from := range c.Container.FromPub {
+		flows = append(flows,
+			fmt.Sprintf(table2, "tcp6", c.Mac, ip6, from, c.vethPort),
+			fmt.Sprintf(table2, "udp6", c.Mac, ip6, from, c.vethPort),
+
+			fmt.Sprintf(table3, "tcp6", c.Mac, ip6, from),
+			fmt.Sprintf(table3, "udp6", c.Mac, ip6, from))
+	}
 	return flows
 }
 
@@ -280,9 +373,15 @@ func allFlows(containers []container) []string {
 			fmt.Sprintf("output:%d", c.vethPort))
 	}
 
-	flows := append(staticFlows, allContainerFlows(containers)...)
-	return append(flows, "table=2,priority=1000,dl_dst=ff:ff:ff:ff:ff:ff,actions="+
+	flows := append(staticFlows(), allContainerFlows(containers)...)
+	flows = append(flows, "table=2,priority=1000,dl_dst=ff:ff:ff:ff:ff:ff,actions="+
 		strings.Join(gatewayBroadcastActions, ","))
+	if ipdef.IPv6Enabled() {
+		flows = append(flows, fmt.Sprintf("table=2,priority=1000,dl_dst=%s,"+
+			"actions=%s", ipv6Multicast,
+			strings.Join(gatewayBroadcastActions, ",")))
+	}
+	return flows
 }
 
 func resolveContainers(portMap map[string]int, containers []Container) []container {
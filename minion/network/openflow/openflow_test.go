@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/kelda/kelda/minion/ipdef"
 	"github.com/kelda/kelda/minion/ovsdb"
 	"github.com/kelda/kelda/minion/ovsdb/mocks"
 	"github.com/stretchr/testify/assert"
@@ -83,7 +84,7 @@ func TestAllFlows(t *testing.T) {
 			IP:      "9.8.7.6",
 			Mac:     "99:99:99:99:99:99",
 			FromPub: map[int]struct{}{8: {}}}}})
-	exp := append(staticFlows,
+	exp := append(staticFlows(),
 		"table=0,in_port=5,dl_src=66:66:66:66:66:66,"+
 			"actions=load:0x4->NXM_NX_REG0[],resubmit(,1)",
 		"table=0,in_port=4,actions=output:5",
@@ -117,6 +118,47 @@ func TestAllFlows(t *testing.T) {
 	assert.Equal(t, exp, flows)
 }
 
+func TestAllFlowsIPv6(t *testing.T) {
+	defaultNetwork, _ := ipdef.ParseContainerNetwork("", "", "", "")
+	network, _ := ipdef.ParseContainerNetwork("", "", "", "fd00::/64")
+	ipdef.Configure(network)
+	defer ipdef.Configure(defaultNetwork)
+
+	flows := allFlows([]container{{
+		patchPort: 4,
+		vethPort:  5,
+		Container: Container{
+			IP:      "10.0.0.9",
+			Mac:     "66:66:66:66:66:66",
+			ToPub:   map[int]struct{}{5: {}},
+			FromPub: map[int]struct{}{8: {}}},
+	}})
+
+	for _, flow := range []string{
+		"table=1,priority=1000,icmp6,dl_dst=33:33:00:00:00:00/" +
+			"ff:ff:00:00:00:00,actions=output:LOCAL,output:NXM_NX_REG0[]",
+		"table=3,priority=1000,ipv6,ipv6_dst=fd00::1,actions=output:LOCAL",
+		"table=3,priority=900,icmp6,icmp_type=135,actions=output:LOCAL",
+		"table=3,priority=900,icmp6,icmp_type=136,actions=output:LOCAL",
+		"table=2,priority=900,icmp6,dl_dst=66:66:66:66:66:66,action=output:5",
+		"table=2,priority=800,ipv6,dl_dst=66:66:66:66:66:66,ipv6_src=fd00::1," +
+			"action=output:5",
+		"table=2,priority=500,tcp6,dl_dst=66:66:66:66:66:66,ipv6_dst=fd00::9," +
+			"tp_src=5,actions=output:5",
+		"table=3,priority=500,udp6,dl_src=66:66:66:66:66:66,ipv6_src=fd00::9," +
+			"tp_dst=5,actions=output:LOCAL",
+		"table=2,priority=500,tcp6,dl_dst=66:66:66:66:66:66,ipv6_dst=fd00::9," +
+			"tp_dst=8,actions=output:5",
+		"table=3,priority=500,udp6,dl_src=66:66:66:66:66:66,ipv6_src=fd00::9," +
+			"tp_src=8,actions=output:LOCAL",
+		"table=2,priority=1000,dl_dst=33:33:00:00:00:00/ff:ff:00:00:00:00," +
+			"actions=output:5",
+	} {
+		assert.Contains(t, flows, flow)
+	}
+	assert.Len(t, flows, len(staticFlows())+4+2+8+8+2)
+}
+
 func TestResolveContainers(t *testing.T) {
 	t.Parallel()
 
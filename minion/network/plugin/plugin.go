@@ -169,6 +169,9 @@ func (d driver) Join(req *dnet.JoinRequest) (*dnet.JoinResponse, error) {
 	inner := ipdef.IFName("tmp_" + req.EndpointID)
 	resp := &dnet.JoinResponse{}
 	resp.Gateway = ipdef.GatewayIP.String()
+	if ipdef.IPv6Enabled() {
+		resp.GatewayIPv6 = ipdef.GatewayIP6.String()
+	}
 	resp.InterfaceName = dnet.InterfaceName{SrcName: inner, DstPrefix: ifacePrefix}
 	return resp, nil
 }
@@ -180,6 +180,19 @@ func TestJoin(t *testing.T) {
 		Gateway: "10.0.0.1"}, resp)
 }
 
+func TestJoinIPv6(t *testing.T) {
+	defaultNetwork, _ := ipdef.ParseContainerNetwork("", "", "", "")
+	network, _ := ipdef.ParseContainerNetwork("", "", "", "fd00::/64")
+	ipdef.Configure(network)
+	defer ipdef.Configure(defaultNetwork)
+
+	d := driver{}
+	resp, err := d.Join(&dnet.JoinRequest{EndpointID: zero})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", resp.Gateway)
+	assert.Equal(t, "fd00::1", resp.GatewayIPv6)
+}
+
 func TestLeave(t *testing.T) {
 	d := driver{}
 	_, err := d.Join(&dnet.JoinRequest{EndpointID: zero, SandboxKey: "/test/docker0"})
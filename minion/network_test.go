@@ -0,0 +1,93 @@
+package minion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/ipdef"
+	"github.com/kelda/kelda/util"
+)
+
+func TestConfigureContainerNetwork(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	defaultNetwork, _ := ipdef.ParseContainerNetwork("", "", "", "")
+	defer ipdef.Configure(defaultNetwork)
+
+	conn := newSelfConn()
+
+	// The minion waits until it's configured.
+	done := make(chan error)
+	go func() { done <- configureContainerNetwork(conn) }()
+	select {
+	case <-done:
+		t.Fatal("configured the container network before receiving a config")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	setContainerNetwork(conn, "172.20.0.0/16", "172.20.0.10", "fd00::/64")
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("didn't configure the container network")
+	}
+	assert.Equal(t, "172.20.0.0/16", ipdef.KeldaSubnet.String())
+	assert.Equal(t, "172.20.0.10", ipdef.GatewayIP.String())
+	assert.Equal(t, "172.20.0.2", ipdef.LoadBalancerIP.String())
+	assert.Equal(t, "fd00::/64", ipdef.KeldaSubnet6.String())
+	assert.Equal(t, "fd00::a", ipdef.GatewayIP6.String())
+
+	// After a restart, the minion uses the network it was first configured
+	// with.
+	conn = newSelfConn()
+	ipdef.Configure(defaultNetwork)
+	assert.NoError(t, configureContainerNetwork(conn))
+	assert.Equal(t, "172.20.0.0/16", ipdef.KeldaSubnet.String())
+	assert.Equal(t, "172.20.0.10", ipdef.GatewayIP.String())
+	assert.Equal(t, "fd00::/64", ipdef.KeldaSubnet6.String())
+
+	// Blueprints that don't configure the network use the default one.
+	util.AppFs = afero.NewMemMapFs()
+	setContainerNetwork(conn, "", "", "")
+	assert.NoError(t, configureContainerNetwork(conn))
+	assert.Equal(t, ipdef.DefaultSubnet, ipdef.KeldaSubnet.String())
+	assert.Equal(t, "10.0.0.1", ipdef.GatewayIP.String())
+	assert.False(t, ipdef.IPv6Enabled())
+
+	util.AppFs = afero.NewMemMapFs()
+	setContainerNetwork(conn, "bad", "", "")
+	assert.EqualError(t, configureContainerNetwork(conn),
+		`container subnet "bad" must be an IPv4 CIDR`)
+
+	util.AppFs = afero.NewMemMapFs()
+	setContainerNetwork(conn, "", "", "bad")
+	assert.EqualError(t, configureContainerNetwork(conn),
+		`IPv6 container subnet "bad" must be an IPv6 CIDR`)
+}
+
+func setContainerNetwork(conn db.Conn, subnet, gateway, subnet6 string) {
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.PrivateIP = "1.2.3.4"
+		m.ContainerSubnet = subnet
+		m.GatewayIP = gateway
+		m.ContainerSubnetIPv6 = subnet6
+		view.Commit(m)
+		return nil
+	})
+}
+
+func newSelfConn() db.Conn {
+	conn := db.New()
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		m := view.InsertMinion()
+		m.Self = true
+		view.Commit(m)
+		return nil
+	})
+	return conn
+}
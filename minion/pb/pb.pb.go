@@ -9,6 +9,8 @@ It is generated from these files:
 
 It has these top-level messages:
 	MinionConfig
+	LogEntry
+	LogEntries
 	Reply
 	Request
 */
@@ -71,6 +73,30 @@ type MinionConfig struct {
 	EtcdMembers         []string          `protobuf:"bytes,10,rep,name=EtcdMembers" json:"EtcdMembers,omitempty"`
 	AuthorizedKeys      []string          `protobuf:"bytes,11,rep,name=AuthorizedKeys" json:"AuthorizedKeys,omitempty"`
 	MinionIPToPublicKey map[string]string `protobuf:"bytes,12,rep,name=MinionIPToPublicKey" json:"MinionIPToPublicKey,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Set by the minion when the cloud provider has announced that it will
+	// reclaim the machine.
+	Terminating bool `protobuf:"varint,13,opt,name=Terminating" json:"Terminating,omitempty"`
+	// Whether new containers may be scheduled on the minion, and whether its
+	// containers should be moved to other workers.
+	Unschedulable bool `protobuf:"varint,14,opt,name=Unschedulable" json:"Unschedulable,omitempty"`
+	Draining      bool `protobuf:"varint,15,opt,name=Draining" json:"Draining,omitempty"`
+	// Set by the minion when it's draining, and Docker no longer runs any of
+	// its containers.
+	Drained bool `protobuf:"varint,16,opt,name=Drained" json:"Drained,omitempty"`
+	// The container network configured in the blueprint. Empty fields take
+	// their default values.
+	ContainerSubnet     string `protobuf:"bytes,17,opt,name=ContainerSubnet" json:"ContainerSubnet,omitempty"`
+	GatewayIP           string `protobuf:"bytes,18,opt,name=GatewayIP" json:"GatewayIP,omitempty"`
+	LoadBalancerIP      string `protobuf:"bytes,19,opt,name=LoadBalancerIP" json:"LoadBalancerIP,omitempty"`
+	ContainerSubnetIPv6 string `protobuf:"bytes,20,opt,name=ContainerSubnetIPv6" json:"ContainerSubnetIPv6,omitempty"`
+	// Whether etcd encrypts its traffic with TLS.  It's set once every master
+	// has an etcd certificate, and then stays set.
+	EtcdTLS bool `protobuf:"varint,21,opt,name=EtcdTLS" json:"EtcdTLS,omitempty"`
+	// Set by workers when every container scheduled on them is running, and
+	// by masters when every container is scheduled on a minion that isn't
+	// draining.  A draining worker is only drained once the rest of the
+	// cluster has settled, so that its containers are running elsewhere.
+	Settled bool `protobuf:"varint,22,opt,name=Settled" json:"Settled,omitempty"`
 }
 
 func (m *MinionConfig) Reset()                    { *m = MinionConfig{} }
@@ -162,13 +188,164 @@ func (m *MinionConfig) GetMinionIPToPublicKey() map[string]string {
 	return nil
 }
 
+func (m *MinionConfig) GetTerminating() bool {
+	if m != nil {
+		return m.Terminating
+	}
+	return false
+}
+
+func (m *MinionConfig) GetUnschedulable() bool {
+	if m != nil {
+		return m.Unschedulable
+	}
+	return false
+}
+
+func (m *MinionConfig) GetDraining() bool {
+	if m != nil {
+		return m.Draining
+	}
+	return false
+}
+
+func (m *MinionConfig) GetDrained() bool {
+	if m != nil {
+		return m.Drained
+	}
+	return false
+}
+
+func (m *MinionConfig) GetContainerSubnet() string {
+	if m != nil {
+		return m.ContainerSubnet
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetGatewayIP() string {
+	if m != nil {
+		return m.GatewayIP
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetLoadBalancerIP() string {
+	if m != nil {
+		return m.LoadBalancerIP
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetContainerSubnetIPv6() string {
+	if m != nil {
+		return m.ContainerSubnetIPv6
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetEtcdTLS() bool {
+	if m != nil {
+		return m.EtcdTLS
+	}
+	return false
+}
+
+func (m *MinionConfig) GetSettled() bool {
+	if m != nil {
+		return m.Settled
+	}
+	return false
+}
+
+type LogEntry struct {
+	// Unix time in nanoseconds.
+	Timestamp   int64             `protobuf:"varint,1,opt,name=Timestamp" json:"Timestamp,omitempty"`
+	Stderr      bool              `protobuf:"varint,2,opt,name=Stderr" json:"Stderr,omitempty"`
+	Line        string            `protobuf:"bytes,3,opt,name=Line" json:"Line,omitempty"`
+	Hostname    string            `protobuf:"bytes,4,opt,name=Hostname" json:"Hostname,omitempty"`
+	BlueprintID string            `protobuf:"bytes,5,opt,name=BlueprintID" json:"BlueprintID,omitempty"`
+	DockerID    string            `protobuf:"bytes,6,opt,name=DockerID" json:"DockerID,omitempty"`
+	Labels      map[string]string `protobuf:"bytes,7,rep,name=Labels" json:"Labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *LogEntry) Reset()                    { *m = LogEntry{} }
+func (m *LogEntry) String() string            { return proto.CompactTextString(m) }
+func (*LogEntry) ProtoMessage()               {}
+func (*LogEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *LogEntry) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *LogEntry) GetStderr() bool {
+	if m != nil {
+		return m.Stderr
+	}
+	return false
+}
+
+func (m *LogEntry) GetLine() string {
+	if m != nil {
+		return m.Line
+	}
+	return ""
+}
+
+func (m *LogEntry) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+func (m *LogEntry) GetBlueprintID() string {
+	if m != nil {
+		return m.BlueprintID
+	}
+	return ""
+}
+
+func (m *LogEntry) GetDockerID() string {
+	if m != nil {
+		return m.DockerID
+	}
+	return ""
+}
+
+func (m *LogEntry) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+type LogEntries struct {
+	Entries []*LogEntry `protobuf:"bytes,1,rep,name=Entries" json:"Entries,omitempty"`
+}
+
+func (m *LogEntries) Reset()                    { *m = LogEntries{} }
+func (m *LogEntries) String() string            { return proto.CompactTextString(m) }
+func (*LogEntries) ProtoMessage()               {}
+func (*LogEntries) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *LogEntries) GetEntries() []*LogEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
 type Reply struct {
 }
 
 func (m *Reply) Reset()                    { *m = Reply{} }
 func (m *Reply) String() string            { return proto.CompactTextString(m) }
 func (*Reply) ProtoMessage()               {}
-func (*Reply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*Reply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 type Request struct {
 }
@@ -176,10 +353,12 @@ type Request struct {
 func (m *Request) Reset()                    { *m = Request{} }
 func (m *Request) String() string            { return proto.CompactTextString(m) }
 func (*Request) ProtoMessage()               {}
-func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func init() {
 	proto.RegisterType((*MinionConfig)(nil), "MinionConfig")
+	proto.RegisterType((*LogEntry)(nil), "LogEntry")
+	proto.RegisterType((*LogEntries)(nil), "LogEntries")
 	proto.RegisterType((*Reply)(nil), "Reply")
 	proto.RegisterType((*Request)(nil), "Request")
 	proto.RegisterEnum("MinionConfig_Role", MinionConfig_Role_name, MinionConfig_Role_value)
@@ -198,6 +377,9 @@ const _ = grpc.SupportPackageIsVersion4
 type MinionClient interface {
 	SetMinionConfig(ctx context.Context, in *MinionConfig, opts ...grpc.CallOption) (*Reply, error)
 	GetMinionConfig(ctx context.Context, in *Request, opts ...grpc.CallOption) (*MinionConfig, error)
+	// Appends the entries to the log file on the master.  Used by the file
+	// log sink.
+	WriteLogs(ctx context.Context, in *LogEntries, opts ...grpc.CallOption) (*Reply, error)
 }
 
 type minionClient struct {
@@ -226,11 +408,23 @@ func (c *minionClient) GetMinionConfig(ctx context.Context, in *Request, opts ..
 	return out, nil
 }
 
+func (c *minionClient) WriteLogs(ctx context.Context, in *LogEntries, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := grpc.Invoke(ctx, "/Minion/WriteLogs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Minion service
 
 type MinionServer interface {
 	SetMinionConfig(context.Context, *MinionConfig) (*Reply, error)
 	GetMinionConfig(context.Context, *Request) (*MinionConfig, error)
+	// Appends the entries to the log file on the master.  Used by the file
+	// log sink.
+	WriteLogs(context.Context, *LogEntries) (*Reply, error)
 }
 
 func RegisterMinionServer(s *grpc.Server, srv MinionServer) {
@@ -273,6 +467,24 @@ func _Minion_GetMinionConfig_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Minion_WriteLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogEntries)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MinionServer).WriteLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Minion/WriteLogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MinionServer).WriteLogs(ctx, req.(*LogEntries))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Minion_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "Minion",
 	HandlerType: (*MinionServer)(nil),
@@ -285,6 +497,10 @@ var _Minion_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetMinionConfig",
 			Handler:    _Minion_GetMinionConfig_Handler,
 		},
+		{
+			MethodName: "WriteLogs",
+			Handler:    _Minion_WriteLogs_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "minion/pb/pb.proto",
@@ -293,31 +509,50 @@ var _Minion_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("minion/pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 410 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x92, 0x51, 0x6b, 0xdb, 0x30,
-	0x10, 0x80, 0x63, 0xc7, 0x71, 0xe2, 0x4b, 0x97, 0x86, 0xdb, 0x18, 0x22, 0x8c, 0x61, 0xfc, 0x10,
-	0xcc, 0x18, 0x2e, 0x64, 0x2f, 0x63, 0x6f, 0xdd, 0xea, 0x0e, 0x13, 0xd2, 0x1a, 0xa5, 0xb0, 0xbd,
-	0xc6, 0xcd, 0x2d, 0x13, 0x73, 0x2d, 0x4f, 0x91, 0x03, 0xee, 0x6f, 0xd9, 0x8f, 0x1d, 0x91, 0x4d,
-	0x97, 0x94, 0xec, 0xed, 0xee, 0xbb, 0xfb, 0x84, 0xa4, 0x3b, 0xc0, 0x07, 0x51, 0x08, 0x59, 0x5c,
-	0x94, 0xd9, 0x45, 0x99, 0x45, 0xa5, 0x92, 0x5a, 0x06, 0x7f, 0x1c, 0x38, 0x5b, 0x18, 0xfc, 0x45,
-	0x16, 0x3f, 0xc4, 0x06, 0x47, 0x60, 0x27, 0x57, 0xcc, 0xf2, 0xad, 0xd0, 0xe3, 0x76, 0x72, 0x85,
-	0x53, 0x70, 0x94, 0xcc, 0x89, 0xd9, 0xbe, 0x15, 0x8e, 0x66, 0x18, 0x1d, 0x36, 0x47, 0x5c, 0xe6,
-	0xc4, 0x4d, 0x1d, 0xdf, 0x80, 0x97, 0x2a, 0xb1, 0x5b, 0x69, 0x4a, 0x52, 0xd6, 0x35, 0xfa, 0x3f,
-	0x80, 0x13, 0x18, 0xa4, 0x55, 0x96, 0x8b, 0xfb, 0x24, 0x65, 0x8e, 0x29, 0x3e, 0xe5, 0x7b, 0xf3,
-	0x73, 0x5e, 0x51, 0xa9, 0x44, 0xa1, 0x59, 0xaf, 0x31, 0x9f, 0x80, 0x31, 0x95, 0xdc, 0x89, 0x35,
-	0x29, 0xe6, 0xb6, 0x66, 0x9b, 0x23, 0x82, 0xb3, 0x14, 0x8f, 0xc4, 0xfa, 0x86, 0x9b, 0x18, 0x5f,
-	0x83, 0xcb, 0x69, 0x23, 0x64, 0xc1, 0x06, 0x86, 0xb6, 0x19, 0xbe, 0x05, 0xb8, 0xce, 0xe5, 0x4a,
-	0x8b, 0x62, 0x93, 0xa4, 0xcc, 0x33, 0xb5, 0x03, 0x82, 0x3e, 0x0c, 0x63, 0x7d, 0xbf, 0x5e, 0xd0,
-	0x43, 0x46, 0x6a, 0xcb, 0xc0, 0xef, 0x86, 0x1e, 0x3f, 0x44, 0x38, 0x85, 0xd1, 0x65, 0xa5, 0x7f,
-	0x4a, 0x25, 0x1e, 0x69, 0x3d, 0xa7, 0x7a, 0xcb, 0x86, 0xa6, 0xe9, 0x19, 0xc5, 0xef, 0xf0, 0xb2,
-	0xf9, 0xa4, 0x24, 0xbd, 0x93, 0xcd, 0x2b, 0xe7, 0x54, 0xb3, 0x33, 0xbf, 0x1b, 0x0e, 0x67, 0xd3,
-	0xe3, 0x0f, 0x3c, 0xd1, 0x18, 0x17, 0x5a, 0xd5, 0xfc, 0xd4, 0x11, 0x93, 0x6b, 0x60, 0xff, 0x13,
-	0x70, 0x0c, 0xdd, 0x5f, 0x54, 0xb7, 0x83, 0xdb, 0x87, 0xf8, 0x0a, 0x7a, 0xbb, 0x55, 0x5e, 0x35,
-	0xa3, 0xf3, 0x78, 0x93, 0x7c, 0xb2, 0x3f, 0x5a, 0x41, 0x08, 0xce, 0x7e, 0x72, 0x38, 0x00, 0xe7,
-	0xe6, 0xf6, 0x26, 0x1e, 0x77, 0x10, 0xc0, 0xfd, 0x76, 0xcb, 0xe7, 0x31, 0x1f, 0x5b, 0xfb, 0x78,
-	0x71, 0xb9, 0xbc, 0x8b, 0xf9, 0xd8, 0x0e, 0xfa, 0xd0, 0xe3, 0x54, 0xe6, 0x75, 0xe0, 0x41, 0x9f,
-	0xd3, 0xef, 0x8a, 0xb6, 0x7a, 0x96, 0x81, 0xdb, 0xdc, 0x02, 0xdf, 0xc1, 0xf9, 0x92, 0xf4, 0xd1,
-	0xfa, 0xbc, 0x38, 0x7a, 0xdf, 0xc4, 0x8d, 0x1a, 0xbd, 0x83, 0xef, 0xe1, 0xfc, 0xeb, 0xb3, 0xde,
-	0x41, 0xd4, 0x1e, 0x39, 0x39, 0xb6, 0x82, 0x4e, 0xe6, 0x9a, 0xed, 0xfc, 0xf0, 0x37, 0x00, 0x00,
-	0xff, 0xff, 0xdc, 0x84, 0xc2, 0xfb, 0xb3, 0x02, 0x00, 0x00,
+	// 705 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xdd, 0x6e, 0xea, 0x46,
+	0x10, 0x8e, 0x81, 0x18, 0x3c, 0x24, 0x40, 0x37, 0x3f, 0x5a, 0xa1, 0xa8, 0x42, 0xb4, 0x8a, 0x50,
+	0xd5, 0x3a, 0x6d, 0x2a, 0x55, 0x6d, 0xef, 0x92, 0x40, 0x52, 0x2b, 0x24, 0xb1, 0x16, 0xaa, 0xf4,
+	0xd6, 0xc6, 0x53, 0xb2, 0x8a, 0xf1, 0xd2, 0xf5, 0x42, 0x45, 0x1e, 0xe0, 0x3c, 0xc3, 0x79, 0xcc,
+	0xf3, 0x08, 0x47, 0xbb, 0x36, 0xbf, 0xca, 0xb9, 0x38, 0x77, 0xf3, 0x7d, 0xf3, 0xb3, 0xe3, 0x99,
+	0xf9, 0x0c, 0x64, 0xc2, 0x13, 0x2e, 0x92, 0x8b, 0x69, 0x78, 0x31, 0x0d, 0xdd, 0xa9, 0x14, 0x4a,
+	0xb4, 0x3f, 0xd9, 0x70, 0xf0, 0x60, 0xe8, 0x1b, 0x91, 0xfc, 0xcb, 0xc7, 0xa4, 0x06, 0x05, 0xaf,
+	0x4b, 0xad, 0x96, 0xd5, 0x71, 0x58, 0xc1, 0xeb, 0x92, 0x73, 0x28, 0x49, 0x11, 0x23, 0x2d, 0xb4,
+	0xac, 0x4e, 0xed, 0x92, 0xb8, 0x9b, 0xc1, 0x2e, 0x13, 0x31, 0x32, 0xe3, 0x27, 0x67, 0xe0, 0xf8,
+	0x92, 0xcf, 0x03, 0x85, 0x9e, 0x4f, 0x8b, 0x26, 0x7d, 0x4d, 0x90, 0x26, 0x54, 0xfc, 0x59, 0x18,
+	0xf3, 0x91, 0xe7, 0xd3, 0x92, 0x71, 0xae, 0xb0, 0xce, 0xbc, 0x8e, 0x67, 0x38, 0x95, 0x3c, 0x51,
+	0x74, 0x3f, 0xcb, 0x5c, 0x11, 0x26, 0x53, 0x8a, 0x39, 0x8f, 0x50, 0x52, 0x3b, 0xcf, 0xcc, 0x31,
+	0x21, 0x50, 0x1a, 0xf0, 0x37, 0xa4, 0x65, 0xc3, 0x1b, 0x9b, 0x9c, 0x82, 0xcd, 0x70, 0xcc, 0x45,
+	0x42, 0x2b, 0x86, 0xcd, 0x11, 0xf9, 0x16, 0xe0, 0x36, 0x16, 0x81, 0xe2, 0xc9, 0xd8, 0xf3, 0xa9,
+	0x63, 0x7c, 0x1b, 0x0c, 0x69, 0x41, 0xb5, 0xa7, 0x46, 0xd1, 0x03, 0x4e, 0x42, 0x94, 0x29, 0x85,
+	0x56, 0xb1, 0xe3, 0xb0, 0x4d, 0x8a, 0x9c, 0x43, 0xed, 0x6a, 0xa6, 0x5e, 0x84, 0xe4, 0x6f, 0x18,
+	0xdd, 0xe3, 0x22, 0xa5, 0x55, 0x13, 0xb4, 0xc3, 0x92, 0x7f, 0xe0, 0x28, 0x1b, 0x92, 0xe7, 0x0f,
+	0x45, 0xf6, 0x95, 0xf7, 0xb8, 0xa0, 0x07, 0xad, 0x62, 0xa7, 0x7a, 0x79, 0xbe, 0x3d, 0xc0, 0x77,
+	0x02, 0x7b, 0x89, 0x92, 0x0b, 0xf6, 0x5e, 0x09, 0xdd, 0xe3, 0x10, 0xe5, 0x84, 0x27, 0xa6, 0x69,
+	0x7a, 0xd8, 0xb2, 0x3a, 0x15, 0xb6, 0x49, 0x91, 0xef, 0xe1, 0xf0, 0xef, 0x24, 0x1d, 0xbd, 0x60,
+	0x34, 0x8b, 0x83, 0x30, 0x46, 0x5a, 0x33, 0x31, 0xdb, 0xa4, 0x9e, 0x69, 0x57, 0x06, 0x3c, 0xd1,
+	0x45, 0xea, 0x26, 0x60, 0x85, 0x09, 0x85, 0xb2, 0xb1, 0x31, 0xa2, 0x0d, 0xe3, 0x5a, 0x42, 0xd2,
+	0x81, 0xfa, 0x8d, 0x48, 0x94, 0x06, 0x72, 0x30, 0x0b, 0x13, 0x54, 0xf4, 0x1b, 0x33, 0xc6, 0x5d,
+	0x5a, 0x6f, 0xf4, 0x2e, 0x50, 0xf8, 0x7f, 0xb0, 0xf0, 0x7c, 0x4a, 0xb2, 0x8d, 0xae, 0x08, 0x3d,
+	0xc7, 0xbe, 0x08, 0xa2, 0xeb, 0x20, 0x0e, 0x92, 0x11, 0x4a, 0xcf, 0xa7, 0x47, 0x26, 0x64, 0x87,
+	0x25, 0x3f, 0xc3, 0xd1, 0x4e, 0x61, 0xcf, 0x9f, 0xff, 0x46, 0x8f, 0x4d, 0xf0, 0x7b, 0x2e, 0xdd,
+	0xbb, 0x5e, 0xd8, 0xb0, 0x3f, 0xa0, 0x27, 0x59, 0xef, 0x39, 0xd4, 0x9e, 0x01, 0x2a, 0x15, 0x63,
+	0x44, 0x4f, 0x33, 0x4f, 0x0e, 0x9b, 0xb7, 0x40, 0xbf, 0xb4, 0x04, 0xd2, 0x80, 0xe2, 0x2b, 0x2e,
+	0x72, 0x31, 0x68, 0x93, 0x1c, 0xc3, 0xfe, 0x3c, 0x88, 0x67, 0x99, 0x1c, 0x1c, 0x96, 0x81, 0x3f,
+	0x0b, 0xbf, 0x5b, 0xed, 0x0e, 0x94, 0xb4, 0x1a, 0x48, 0x05, 0x4a, 0x8f, 0x4f, 0x8f, 0xbd, 0xc6,
+	0x1e, 0x01, 0xb0, 0x9f, 0x9f, 0xd8, 0x7d, 0x8f, 0x35, 0x2c, 0x6d, 0x3f, 0x5c, 0x0d, 0x86, 0x3d,
+	0xd6, 0x28, 0xb4, 0x3f, 0x16, 0xa0, 0xd2, 0x17, 0xe3, 0xec, 0x89, 0x33, 0x70, 0x86, 0x7c, 0x82,
+	0xa9, 0x0a, 0x26, 0x53, 0xf3, 0x50, 0x91, 0xad, 0x09, 0x7d, 0xcc, 0x03, 0x15, 0xa1, 0x94, 0xe6,
+	0xbd, 0x0a, 0xcb, 0x91, 0x3e, 0xfc, 0x3e, 0x4f, 0x30, 0xd7, 0x99, 0xb1, 0xf5, 0x52, 0xff, 0x12,
+	0xa9, 0x4a, 0x82, 0x09, 0x2e, 0x25, 0xb6, 0xc4, 0xfa, 0x70, 0x56, 0x8a, 0xf2, 0xba, 0xb9, 0xc8,
+	0x36, 0x29, 0x73, 0x12, 0x62, 0xf4, 0x8a, 0xd2, 0xeb, 0x2e, 0x65, 0xb6, 0xc4, 0xe4, 0x27, 0xb0,
+	0xfb, 0x41, 0x88, 0x71, 0x4a, 0xcb, 0xe6, 0x86, 0x4f, 0xdc, 0x65, 0xfb, 0x6e, 0xc6, 0x1b, 0x9b,
+	0xe5, 0x41, 0xcd, 0x3f, 0xa0, 0xba, 0x41, 0x7f, 0xd5, 0x10, 0x7f, 0x01, 0xc8, 0x4b, 0x73, 0x4c,
+	0xc9, 0x77, 0x50, 0xce, 0x4d, 0x6a, 0x99, 0x87, 0x9d, 0xd5, 0xc3, 0x6c, 0xe9, 0x69, 0x97, 0x61,
+	0x9f, 0xe1, 0x34, 0x5e, 0xb4, 0x1d, 0x28, 0x33, 0xfc, 0x6f, 0x86, 0xa9, 0xba, 0xfc, 0x60, 0x81,
+	0x9d, 0x2d, 0x95, 0xfc, 0x00, 0xf5, 0x01, 0xaa, 0xad, 0x3f, 0xdc, 0xe1, 0x96, 0x04, 0x9b, 0xb6,
+	0x9b, 0xe5, 0xef, 0x91, 0x1f, 0xa1, 0x7e, 0xb7, 0x13, 0x5b, 0x71, 0xf3, 0x9a, 0xcd, 0xed, 0xac,
+	0xf6, 0x1e, 0x69, 0x83, 0xf3, 0x2c, 0xb9, 0xc2, 0xbe, 0x18, 0xa7, 0xa4, 0xea, 0xae, 0xfb, 0x5e,
+	0x57, 0x0c, 0x6d, 0xf3, 0x93, 0xfd, 0xf5, 0xf3, 0x00, 0x2b, 0x6a, 0xc7, 0xfe, 0x7a, 0x05, 0x00,
+	0x00,
 }
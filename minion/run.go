@@ -14,6 +14,7 @@ import (
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/docker"
 	"github.com/kelda/kelda/minion/etcd"
+	"github.com/kelda/kelda/minion/logship"
 	"github.com/kelda/kelda/minion/network"
 	"github.com/kelda/kelda/minion/network/plugin"
 	"github.com/kelda/kelda/minion/pprofile"
@@ -54,15 +55,9 @@ func Run(role db.Role, inboundPubIntf, outboundPubIntf string) {
 		go network.WriteSubnets(conn)
 	}
 
-	// Not in a goroutine, want the plugin to start before the scheduler
-	plugin.Run()
-
-	supervisor.Run(conn, dk, role)
-
-	go network.Run(conn, inboundPubIntf, outboundPubIntf)
-	go registry.Run(conn, dk)
-	go etcd.Run(conn)
 	go syncAuthorizedKeys(conn)
+	go serveMetrics(conn)
+	go watchTermination(conn)
 
 	// Block until the credentials are in place on the local filesystem. We
 	// can't simply fail if the first read fails because the daemon might still
@@ -83,17 +78,39 @@ func Run(role db.Role, inboundPubIntf, outboundPubIntf string) {
 		return
 	}
 
-	go minionServerRun(conn, creds)
+	go minionServerRun(conn, dk, creds)
 	go apiServer.Run(conn, fmt.Sprintf("tcp://0.0.0.0:%d", api.DefaultRemotePort),
 		false, creds)
 
+	// The container network is configured through the minion server, and must
+	// be known before the modules that set up the network start.
+	if err := configureContainerNetwork(conn); err != nil {
+		log.WithError(err).Error("Failed to configure the container network")
+		return
+	}
+
+	// Not in a goroutine, want the plugin to start before the scheduler
+	plugin.Run()
+
+	supervisor.Run(conn, dk, role)
+
+	go network.Run(conn, inboundPubIntf, outboundPubIntf)
+	go registry.Run(conn, dk)
+	go etcd.Run(conn)
+	go syncContainerMetrics(conn)
+
 	go syncPolicy(conn)
+	go logship.Run(conn, dk, creds)
 
 	// Vault must be started after the credentials are resolved because it also
 	// makes use of the credentials.
 	if role == db.Master {
 		vaultClient := vault.Start(conn, dk)
 		go vault.Run(conn, dk, vaultClient)
+
+		// The control plane reads its credentials out of Vault, so it too
+		// must wait for Vault to start.
+		go runControlPlane(conn, creds)
 	}
 
 	// Don't start scheduling containers until after Vault has booted (i.e.
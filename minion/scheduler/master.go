@@ -125,6 +125,17 @@ func canBeColocated(constraint db.Placement, toPlace db.Container,
 func validPlacement(constraints []db.Placement, m minion, peers []*db.Container,
 	dbc *db.Container) bool {
 
+	// Drain minions that the cloud provider is about to reclaim, or that are
+	// being drained before they're stopped or maintained.
+	if m.Terminating || m.Draining {
+		return false
+	}
+
+	// Cordoned minions keep their containers, but don't accept new ones.
+	if m.Unschedulable && dbc.Minion != m.PrivateIP {
+		return false
+	}
+
 	for _, constraint := range constraints {
 		if constraint.OtherContainer != "" {
 			if !canBeColocated(constraint, *dbc, peers) {
@@ -98,6 +98,50 @@ func TestCleanup(t *testing.T) {
 	assert.Equal(t, expChanged, ctx.changed)
 }
 
+func TestCleanupTerminating(t *testing.T) {
+	t.Parallel()
+
+	containers := []db.Container{{ID: 1, Hostname: "1", Minion: "1"}}
+	minions := []db.Minion{
+		{PrivateIP: "1", Role: db.Worker, Terminating: true},
+		{PrivateIP: "2", Role: db.Worker},
+	}
+
+	// Containers on terminating minions should be moved to other workers.
+	ctx := makeContext(minions, nil, containers, nil)
+	cleanupPlacements(ctx)
+	placeUnassigned(ctx)
+
+	assert.Equal(t, "2", containers[0].Minion)
+	assert.Empty(t, ctx.minions[0].containers)
+}
+
+func TestCleanupUnschedulable(t *testing.T) {
+	t.Parallel()
+
+	containers := []db.Container{
+		{ID: 1, Hostname: "1", Minion: "1"},
+		{ID: 2, Hostname: "2", Minion: "2"},
+		{ID: 3, Hostname: "3"},
+	}
+	minions := []db.Minion{
+		{PrivateIP: "1", Role: db.Worker, Unschedulable: true},
+		{PrivateIP: "2", Role: db.Worker, Unschedulable: true,
+			Draining: true},
+		{PrivateIP: "3", Role: db.Worker},
+	}
+
+	// Cordoned minions keep their containers, but draining minions don't,
+	// and neither accepts new ones.
+	ctx := makeContext(minions, nil, containers, nil)
+	cleanupPlacements(ctx)
+	placeUnassigned(ctx)
+
+	assert.Equal(t, "1", containers[0].Minion)
+	assert.Equal(t, "3", containers[1].Minion)
+	assert.Equal(t, "3", containers[2].Minion)
+}
+
 func TestCleanupContainerRule(t *testing.T) {
 	t.Parallel()
 
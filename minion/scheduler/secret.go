@@ -37,6 +37,12 @@ func resolveSecrets(client vault.SecretStore, dbcs []db.Container) map[string]st
 				continue
 			}
 
+			// The reserved secrets are never given to containers, so they're
+			// treated as missing.
+			if blueprint.IsReservedSecret(name) {
+				continue
+			}
+
 			secretVal, err := getSecret(client, name)
 			if err == nil {
 				secretMap[name] = secretVal
@@ -23,7 +23,11 @@ import (
 
 const labelKey = "kelda"
 const labelValue = "scheduler"
-const labelPair = labelKey + "=" + labelValue
+
+// LabelPair is the Docker label filter that matches the containers started by
+// the scheduler.
+const LabelPair = labelKey + "=" + labelValue
+
 const filesKey = "files"
 const concurrencyLimit = 32
 
@@ -72,7 +76,7 @@ func runWorker(conn db.Conn, dk docker.Client, myPrivIP, myPubIP string) {
 		updateOpenflow(conn, myPrivIP)
 	})
 
-	filter := map[string][]string{"label": {labelPair}}
+	filter := map[string][]string{"label": {LabelPair}}
 
 	var toBoot, toKill []interface{}
 	for i := 0; i < 2; i++ {
@@ -284,6 +288,11 @@ func openflowContainers(dbcs []db.Container,
 	fromPubPorts := map[string][]int{}
 	toPubPorts := map[string][]int{}
 	for _, conn := range conns {
+		// ICMP connections don't have ports to expose.
+		if conn.Protocol == blueprint.ICMP {
+			continue
+		}
+
 		for _, from := range conn.From {
 			for _, to := range conn.To {
 				if from != blueprint.PublicInternetLabel &&
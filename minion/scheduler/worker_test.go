@@ -346,7 +346,9 @@ func TestOpenFlowContainers(t *testing.T) {
 		{MinPort: 3, MaxPort: 3, To: []string{blueprint.PublicInternetLabel},
 			From: []string{"red"}},
 		{MinPort: 4, MaxPort: 4, To: []string{blueprint.PublicInternetLabel},
-			From: []string{"blue"}}}
+			From: []string{"blue"}},
+		{Protocol: blueprint.ICMP, From: []string{blueprint.PublicInternetLabel},
+			To: []string{"red"}}}
 
 	res := openflowContainers([]db.Container{
 		{EndpointID: "f", IP: "1.2.3.4", Hostname: "red"}},
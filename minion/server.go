@@ -1,12 +1,17 @@
 package minion
 
 import (
+	"errors"
 	"sort"
 	"strings"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/connection"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/logship"
 	"github.com/kelda/kelda/minion/pb"
+	"github.com/kelda/kelda/minion/scheduler"
 
 	"golang.org/x/net/context"
 
@@ -15,11 +20,12 @@ import (
 
 type server struct {
 	db.Conn
+	dk docker.Client
 }
 
-func minionServerRun(conn db.Conn, creds connection.Credentials) {
+func minionServerRun(conn db.Conn, dk docker.Client, creds connection.Credentials) {
 	sock, s := connection.Server("tcp", ":9999", creds.ServerOpts())
-	server := server{conn}
+	server := server{conn, dk}
 	pb.RegisterMinionServer(s, server)
 	s.Serve(sock)
 }
@@ -41,10 +47,24 @@ func (s server) GetMinionConfig(cts context.Context,
 	cfg.Region = m.Region
 	cfg.AuthorizedKeys = strings.Split(m.AuthorizedKeys, "\n")
 	cfg.MinionIPToPublicKey = m.MinionIPToPublicKey
+	cfg.Terminating = m.Terminating
+	cfg.Unschedulable = m.Unschedulable
+	cfg.Draining = m.Draining
+	cfg.ContainerSubnet = m.ContainerSubnet
+	cfg.GatewayIP = m.GatewayIP
+	cfg.LoadBalancerIP = m.LoadBalancerIP
+	cfg.ContainerSubnetIPv6 = m.ContainerSubnetIPv6
+
+	if m.Role == db.Master {
+		cfg.Settled = s.placementSettled()
+	} else {
+		cfg.Settled, cfg.Drained = s.workerSettled(m.Draining)
+	}
 
 	s.Txn(db.EtcdTable).Run(func(view db.Database) error {
 		if etcdRow, err := view.GetEtcd(); err == nil {
 			cfg.EtcdMembers = etcdRow.EtcdIPs
+			cfg.EtcdTLS = etcdRow.TLS
 		}
 		return nil
 	})
@@ -52,6 +72,50 @@ func (s server) GetMinionConfig(cts context.Context,
 	return &cfg, nil
 }
 
+// placementSettled returns whether every container is scheduled on a minion
+// that isn't draining.
+func (s server) placementSettled() bool {
+	draining := map[string]bool{}
+	for _, m := range s.SelectFromMinion(nil) {
+		if m.Terminating || m.Draining {
+			draining[m.PrivateIP] = true
+		}
+	}
+
+	for _, dbc := range s.SelectFromContainer(nil) {
+		if dbc.Minion == "" || draining[dbc.Minion] {
+			return false
+		}
+	}
+	return true
+}
+
+// workerSettled returns whether every container scheduled on the worker is
+// running, and, if the worker is draining, whether Docker has stopped all of
+// the containers that were moved off of it.  Workers only track the containers
+// scheduled on them, so the containers that were moved are only found by
+// asking Docker.
+func (s server) workerSettled(draining bool) (settled, drained bool) {
+	dbcs := s.SelectFromContainer(nil)
+	settled = true
+	for _, dbc := range dbcs {
+		if dbc.DockerID == "" {
+			settled = false
+		}
+	}
+
+	if !draining || len(dbcs) != 0 {
+		return settled, false
+	}
+
+	dkcs, err := s.dk.List(map[string][]string{"label": {scheduler.LabelPair}})
+	if err != nil {
+		log.WithError(err).Warn("Failed to list containers")
+		return settled, false
+	}
+	return settled, len(dkcs) == 0
+}
+
 func (s server) SetMinionConfig(ctx context.Context,
 	msg *pb.MinionConfig) (*pb.Reply, error) {
 
@@ -67,6 +131,12 @@ func (s server) SetMinionConfig(ctx context.Context,
 		minion.FloatingIP = msg.FloatingIP
 		minion.AuthorizedKeys = strings.Join(msg.AuthorizedKeys, "\n")
 		minion.MinionIPToPublicKey = msg.MinionIPToPublicKey
+		minion.Unschedulable = msg.Unschedulable
+		minion.Draining = msg.Draining
+		minion.ContainerSubnet = msg.ContainerSubnet
+		minion.GatewayIP = msg.GatewayIP
+		minion.LoadBalancerIP = msg.LoadBalancerIP
+		minion.ContainerSubnetIPv6 = msg.ContainerSubnetIPv6
 		minion.Self = true
 		view.Commit(minion)
 
@@ -78,6 +148,9 @@ func (s server) SetMinionConfig(ctx context.Context,
 
 		etcdRow.EtcdIPs = msg.EtcdMembers
 		sort.Strings(etcdRow.EtcdIPs)
+
+		// Once etcd switches to TLS, it never switches back.
+		etcdRow.TLS = etcdRow.TLS || msg.EtcdTLS
 		view.Commit(etcdRow)
 
 		return nil
@@ -85,3 +158,32 @@ func (s server) SetMinionConfig(ctx context.Context,
 
 	return &pb.Reply{}, nil
 }
+
+// WriteLogs appends log entries sent by the minions to the file configured by
+// the blueprint's FileSink.
+func (s server) WriteLogs(ctx context.Context, msg *pb.LogEntries) (
+	*pb.Reply, error) {
+
+	c.Inc("WriteLogs")
+	self := s.MinionSelf()
+	if self.Role != db.Master {
+		return nil, errors.New("only masters write logs")
+	}
+
+	bp, err := blueprint.FromJSON(self.Blueprint)
+	if err != nil {
+		return nil, err
+	}
+
+	if bp.LogSink == nil || bp.LogSink.Type != blueprint.FileSink {
+		return nil, errors.New("no file log sink")
+	}
+
+	path, err := logship.FilePath(*bp.LogSink)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Reply{}, logship.AppendToFile(path,
+		logship.EntriesFromPB(msg.Entries))
+}
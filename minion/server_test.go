@@ -1,19 +1,25 @@
 package minion
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/logship"
 	"github.com/kelda/kelda/minion/pb"
+	"github.com/kelda/kelda/util"
 )
 
 func TestSetMinionConfig(t *testing.T) {
 	t.Parallel()
-	s := server{db.New()}
+	s := server{Conn: db.New()}
 
 	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		m := view.InsertMinion()
@@ -59,6 +65,39 @@ func TestSetMinionConfig(t *testing.T) {
 	checkEtcdEquals(t, s.Conn, db.Etcd{
 		EtcdIPs: []string{"etcd3"},
 	})
+
+	// Once etcd switches to TLS, it stays switched.
+	cfg.EtcdTLS = true
+	_, err = s.SetMinionConfig(nil, &cfg)
+	assert.NoError(t, err)
+	checkEtcdEquals(t, s.Conn, db.Etcd{EtcdIPs: []string{"etcd3"}, TLS: true})
+
+	cfg.EtcdTLS = false
+	_, err = s.SetMinionConfig(nil, &cfg)
+	assert.NoError(t, err)
+	checkEtcdEquals(t, s.Conn, db.Etcd{EtcdIPs: []string{"etcd3"}, TLS: true})
+
+	// Drain the minion.
+	cfg.Unschedulable = true
+	cfg.Draining = true
+	expMinion.Unschedulable = true
+	expMinion.Draining = true
+	_, err = s.SetMinionConfig(nil, &cfg)
+	assert.NoError(t, err)
+	checkMinionEquals(t, s.Conn, expMinion)
+
+	// Configure the container network.
+	cfg.ContainerSubnet = "172.20.0.0/16"
+	cfg.GatewayIP = "172.20.0.10"
+	cfg.LoadBalancerIP = "172.20.0.11"
+	cfg.ContainerSubnetIPv6 = "fd00::/64"
+	expMinion.ContainerSubnet = "172.20.0.0/16"
+	expMinion.GatewayIP = "172.20.0.10"
+	expMinion.LoadBalancerIP = "172.20.0.11"
+	expMinion.ContainerSubnetIPv6 = "fd00::/64"
+	_, err = s.SetMinionConfig(nil, &cfg)
+	assert.NoError(t, err)
+	checkMinionEquals(t, s.Conn, expMinion)
 }
 
 func checkMinionEquals(t *testing.T, conn db.Conn, exp db.Minion) {
@@ -104,7 +143,8 @@ func checkEtcdEquals(t *testing.T, conn db.Conn, exp db.Etcd) {
 
 func TestGetMinionConfig(t *testing.T) {
 	t.Parallel()
-	s := server{db.New()}
+	md, dk := docker.NewMock()
+	s := server{db.New(), dk}
 
 	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		m := view.InsertMinion()
@@ -144,12 +184,14 @@ func TestGetMinionConfig(t *testing.T) {
 		Size:           "selfsize",
 		Region:         "selfregion",
 		AuthorizedKeys: []string{"key1", "key2"},
+		Settled:        true,
 	}, *cfg)
 
 	// Test returning a full config.
 	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		etcd := view.InsertEtcd()
 		etcd.EtcdIPs = []string{"etcd1", "etcd2"}
+		etcd.TLS = true
 		view.Commit(etcd)
 		return nil
 	})
@@ -163,6 +205,179 @@ func TestGetMinionConfig(t *testing.T) {
 		Size:           "selfsize",
 		Region:         "selfregion",
 		EtcdMembers:    []string{"etcd1", "etcd2"},
+		EtcdTLS:        true,
 		AuthorizedKeys: []string{"key1", "key2"},
+		Settled:        true,
 	}, *cfg)
+
+	// The minion reports when its machine is about to be reclaimed.
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.Terminating = true
+		view.Commit(m)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Terminating)
+
+	// Masters are settled once every container is scheduled on a minion that
+	// isn't draining.
+	var dbc db.Container
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		draining := view.InsertMinion()
+		draining.PrivateIP = "draining"
+		draining.Draining = true
+		view.Commit(draining)
+
+		dbc = view.InsertContainer()
+		view.Commit(dbc)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.False(t, cfg.Settled)
+
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc.Minion = "draining"
+		view.Commit(dbc)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.False(t, cfg.Settled)
+
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc.Minion = "worker"
+		view.Commit(dbc)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Settled)
+
+	// Workers are settled once their containers are running.  Draining
+	// workers report that they're drained once the containers scheduled on
+	// them are gone from Docker, and not just from the database.
+	dkID, err := dk.Run(docker.RunOptions{Name: "moved",
+		Labels: map[string]string{"kelda": "scheduler"}})
+	assert.NoError(t, err)
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.Role = db.Worker
+		m.Unschedulable = true
+		m.Draining = true
+		view.Commit(m)
+
+		dbc.DockerID = ""
+		view.Commit(dbc)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Unschedulable)
+	assert.True(t, cfg.Draining)
+	assert.False(t, cfg.Settled)
+	assert.False(t, cfg.Drained)
+
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc.DockerID = dkID
+		view.Commit(dbc)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Settled)
+	assert.False(t, cfg.Drained)
+
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		view.Remove(dbc)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Settled)
+	assert.False(t, cfg.Drained)
+
+	md.ListError = true
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.False(t, cfg.Drained)
+	md.ListError = false
+
+	assert.NoError(t, dk.RemoveID(dkID))
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Drained)
+
+	// The container network is reported so that the foreman doesn't resend
+	// it.
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.ContainerSubnet = "172.20.0.0/16"
+		m.GatewayIP = "172.20.0.10"
+		m.LoadBalancerIP = "172.20.0.11"
+		m.ContainerSubnetIPv6 = "fd00::/64"
+		view.Commit(m)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.Equal(t, "172.20.0.0/16", cfg.ContainerSubnet)
+	assert.Equal(t, "172.20.0.10", cfg.GatewayIP)
+	assert.Equal(t, "172.20.0.11", cfg.LoadBalancerIP)
+	assert.Equal(t, "fd00::/64", cfg.ContainerSubnetIPv6)
+}
+
+func TestWriteLogs(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	defer func() { util.AppFs = afero.NewOsFs() }()
+
+	s := server{Conn: db.New()}
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMinion()
+		m.Self = true
+		view.Commit(m)
+		return nil
+	})
+
+	setMinion := func(role db.Role, sink *blueprint.LogSink) {
+		s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			m := view.MinionSelf()
+			m.Role = role
+			m.Blueprint = blueprint.Blueprint{LogSink: sink}.String()
+			view.Commit(m)
+			return nil
+		})
+	}
+
+	entry := logship.Entry{
+		Time:     time.Unix(0, 10).UTC(),
+		Line:     "foo",
+		DockerID: "id",
+	}
+	msg := &pb.LogEntries{Entries: []*pb.LogEntry{{
+		Timestamp: 10,
+		Line:      "foo",
+		DockerID:  "id",
+	}}}
+
+	fileSink := &blueprint.LogSink{Type: blueprint.FileSink, File: "a.log"}
+	setMinion(db.Worker, fileSink)
+	_, err := s.WriteLogs(nil, msg)
+	assert.EqualError(t, err, "only masters write logs")
+
+	setMinion(db.Master, &blueprint.LogSink{Type: blueprint.SyslogSink})
+	_, err = s.WriteLogs(nil, msg)
+	assert.EqualError(t, err, "no file log sink")
+
+	setMinion(db.Master, fileSink)
+	_, err = s.WriteLogs(nil, msg)
+	assert.NoError(t, err)
+
+	contents, err := util.ReadFile("/var/log/kelda/a.log")
+	assert.NoError(t, err)
+	entryJSON, err := json.Marshal(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, string(entryJSON)+"\n", contents)
 }
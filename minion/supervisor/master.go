@@ -1,12 +1,21 @@
 package supervisor
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 
+	"golang.org/x/net/context"
+
+	"github.com/coreos/etcd/client"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/etcd"
 	"github.com/kelda/kelda/minion/supervisor/images"
 	"github.com/kelda/kelda/util"
 	"github.com/kelda/kelda/util/str"
+
+	log "github.com/sirupsen/logrus"
 )
 
 func runMaster() {
@@ -17,7 +26,10 @@ func runMaster() {
 
 func runMasterSystem() {
 	loopLog := util.NewEventTimer("Supervisor")
-	for range conn.Trigger(db.MinionTable, db.EtcdTable).C {
+	// The trigger ticks so that etcd starts once its certificate is installed,
+	// and so that masters that are waiting to be added to the etcd cluster
+	// retry.
+	for range conn.TriggerTick(30, db.MinionTable, db.EtcdTable).C {
 		loopLog.LogStart()
 		runMasterOnce()
 		loopLog.LogEnd()
@@ -36,27 +48,31 @@ func runMasterOnce() {
 	etcdIPs := etcdRow.EtcdIPs
 	leader := etcdRow.Leader
 
-	if oldIP != IP || !str.SliceEq(oldEtcdIPs, etcdIPs) {
+	// Changes to the other masters are handled by changing the membership of
+	// the running etcd cluster, so etcd is only restarted if this master's IP
+	// changes.
+	if oldIP != IP {
 		c.Inc("Reset Etcd")
 		Remove(images.Etcd)
 	}
-
-	oldEtcdIPs = etcdIPs
 	oldIP = IP
 
 	if IP == "" || len(etcdIPs) == 0 {
 		return
 	}
 
-	run(images.Etcd, "etcd", fmt.Sprintf("--name=master-%s", IP),
-		fmt.Sprintf("--initial-cluster=%s", initialClusterString(etcdIPs)),
-		fmt.Sprintf("--advertise-client-urls=http://%s:2379", IP),
-		fmt.Sprintf("--listen-peer-urls=http://%s:2380", IP),
-		fmt.Sprintf("--initial-advertise-peer-urls=http://%s:2380", IP),
-		"--listen-client-urls=http://0.0.0.0:2379",
-		"--heartbeat-interval="+etcdHeartbeatInterval,
-		"--initial-cluster-state=new",
-		"--election-timeout="+etcdElectionTimeout)
+	// Once the cluster switches to TLS, the leader moves the members' peer URLs
+	// to https one at a time, and each master restarts etcd with TLS once its
+	// own member has moved.
+	if etcdRow.TLS && !etcdRunningTLS && etcdMemberMovedToTLS(IP, etcdIPs) {
+		c.Inc("Restart Etcd With TLS")
+		Remove(images.Etcd)
+	}
+
+	runEtcd(IP, etcdIPs, etcdRow.TLS)
+	if leader {
+		syncEtcdMembers(IP, etcdIPs, etcdRow.TLS)
+	}
 
 	run(images.Ovsdb, "ovsdb-server")
 	run(images.Registry)
@@ -70,3 +86,273 @@ func runMasterOnce() {
 		Remove(images.Ovnnorthd)
 	}
 }
+
+// runEtcd starts etcd if it isn't running.  If none of the other masters run
+// etcd, the masters bootstrap a new cluster, which uses TLS if `tls` is set.
+// Otherwise, this master joins the existing cluster once the leader has added it
+// as a member, and uses TLS if its member's peer URL is https.  Because etcd's
+// data directory persists on the host, a restarted etcd rejoins the cluster with
+// its previous state, and ignores the initial cluster flags.
+func runEtcd(IP string, etcdIPs []string, tls bool) {
+	if isRunning, err := dk.IsRunning(images.Etcd); err == nil && isRunning {
+		return
+	}
+
+	if !etcdCertsReady() {
+		return
+	}
+
+	state := "new"
+	initialCluster := initialClusterString(etcdIPs, tls)
+
+	var peers []string
+	for _, ip := range etcdIPs {
+		if ip != IP {
+			peers = append(peers, ip)
+		}
+	}
+
+	if members, err := listEtcdMembers(etcdClientURLs(peers...)); err == nil {
+		if !str.SliceContains(etcdMemberIPs(members), IP) {
+			log.Debug("Waiting to be added to the etcd cluster")
+			return
+		}
+		state = "existing"
+		initialCluster = membersClusterString(members)
+		tls = memberUsesTLS(members, IP)
+	}
+
+	args := []string{"etcd", fmt.Sprintf("--name=%s", nodeName(IP)),
+		fmt.Sprintf("--initial-cluster=%s", initialCluster),
+		fmt.Sprintf("--advertise-client-urls=%s", etcdClientURL(IP, tls)),
+		fmt.Sprintf("--listen-peer-urls=%s", etcdPeerURL(IP, tls)),
+		fmt.Sprintf("--initial-advertise-peer-urls=%s", etcdPeerURL(IP, tls)),
+		fmt.Sprintf("--listen-client-urls=%s", etcdClientURL("0.0.0.0", tls)),
+		"--heartbeat-interval=" + etcdHeartbeatInterval,
+		"--initial-cluster-state=" + state,
+		"--election-timeout=" + etcdElectionTimeout}
+	if tls {
+		args = append(args, etcdTLSArgs()...)
+	}
+	run(images.Etcd, args...)
+	etcdRunningTLS = tls
+}
+
+// etcdMemberMovedToTLS returns whether the leader has moved the peer URL of this
+// master's etcd member to https.  The other masters are asked first, because
+// this master's etcd might not hear about the change once its peers contact it
+// over TLS.
+func etcdMemberMovedToTLS(IP string, etcdIPs []string) bool {
+	var peers []string
+	for _, ip := range etcdIPs {
+		if ip != IP {
+			peers = append(peers, ip)
+		}
+	}
+
+	members, err := listEtcdMembers(append(etcdClientURLs(peers...),
+		etcdClientURLs(IP)...))
+	if err != nil {
+		log.WithError(err).Debug("Failed to list etcd members")
+		return false
+	}
+	return memberUsesTLS(members, IP)
+}
+
+// syncEtcdMembers makes the members of the etcd cluster match `etcdIPs`.  It runs
+// on the leader.  Masters are added one at a time, and only once every existing
+// member has started, so that the cluster keeps a quorum while new masters boot.
+func syncEtcdMembers(IP string, etcdIPs []string, tls bool) {
+	membersAPI, err := newEtcdMembers(etcdClientURLs(IP))
+	if err != nil {
+		log.WithError(err).Warn("Failed to connect to etcd")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	members, err := membersAPI.List(ctx)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list etcd members")
+		return
+	}
+
+	allStarted := true
+	removed := false
+	var memberIPs []string
+	for _, member := range members {
+		ips := etcdMemberIPs([]client.Member{member})
+		memberIPs = append(memberIPs, ips...)
+
+		// The leader doesn't remove itself.  The next leader removes it once
+		// it's elected.
+		if len(ips) == 0 || str.SliceContains(etcdIPs, ips[0]) ||
+			ips[0] == IP {
+			allStarted = allStarted && member.Name != ""
+			continue
+		}
+
+		c.Inc("Remove Etcd Member")
+		log.WithField("member", ips[0]).Info("Removing etcd member")
+		if err := membersAPI.Remove(ctx, member.ID); err != nil {
+			log.WithError(err).Warn("Failed to remove etcd member")
+		}
+		removed = true
+	}
+
+	if !allStarted || removed {
+		return
+	}
+
+	for _, ip := range etcdIPs {
+		if str.SliceContains(memberIPs, ip) {
+			continue
+		}
+
+		c.Inc("Add Etcd Member")
+		log.WithField("member", ip).Info("Adding etcd member")
+		if _, err := membersAPI.Add(ctx, etcdPeerURL(ip, tls)); err != nil {
+			log.WithError(err).Warn("Failed to add etcd member")
+		}
+		return
+	}
+
+	if tls {
+		moveEtcdMemberToTLS(ctx, membersAPI, members, IP)
+	}
+}
+
+// moveEtcdMemberToTLS moves the peer URL of one etcd member to https, once every
+// member that was moved before it serves TLS.  The member's master then restarts
+// etcd with TLS.  Moving the members one at a time keeps the cluster's quorum,
+// because the peers of a moved member can't reach it until it restarts.  The
+// leader moves its own member last.
+func moveEtcdMemberToTLS(ctx context.Context, membersAPI client.MembersAPI,
+	members []client.Member, IP string) {
+
+	var toMove, self []client.Member
+	for _, member := range members {
+		ips := etcdMemberIPs([]client.Member{member})
+		if len(ips) == 0 {
+			continue
+		}
+
+		if !memberUsesTLS([]client.Member{member}, ips[0]) {
+			if ips[0] == IP {
+				self = append(self, member)
+			} else {
+				toMove = append(toMove, member)
+			}
+			continue
+		}
+
+		if ips[0] == IP {
+			continue
+		}
+
+		_, err := listEtcdMembers([]string{etcdClientURL(ips[0], true)})
+		if err != nil {
+			log.WithError(err).WithField("member", ips[0]).Debug(
+				"Waiting for etcd member to restart with TLS")
+			return
+		}
+	}
+
+	toMove = append(toMove, self...)
+	if len(toMove) == 0 {
+		return
+	}
+
+	ip := etcdMemberIPs(toMove[:1])[0]
+
+	c.Inc("Move Etcd Member To TLS")
+	log.WithField("member", ip).Info("Moving etcd member to TLS")
+	err := membersAPI.Update(ctx, toMove[0].ID, []string{etcdPeerURL(ip, true)})
+	if err != nil {
+		log.WithError(err).Warn("Failed to move etcd member to TLS")
+	}
+}
+
+// listEtcdMembers lists the members of the etcd cluster reachable at any of
+// `endpoints`.
+func listEtcdMembers(endpoints []string) ([]client.Member, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no etcd endpoints")
+	}
+
+	membersAPI, err := newEtcdMembers(endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	return membersAPI.List(ctx)
+}
+
+// etcdMemberIPs returns the IPs of the peer URLs of `members`.
+func etcdMemberIPs(members []client.Member) []string {
+	var ips []string
+	for _, member := range members {
+		for _, peerURL := range member.PeerURLs {
+			u, err := url.Parse(peerURL)
+			if err != nil {
+				log.WithError(err).WithField("url", peerURL).Warn(
+					"Malformed etcd peer URL")
+				continue
+			}
+			ips = append(ips, u.Hostname())
+		}
+	}
+	return ips
+}
+
+// memberUsesTLS returns whether the peer URL of the member at `IP` is https.
+func memberUsesTLS(members []client.Member, IP string) bool {
+	for _, member := range members {
+		for _, peerURL := range member.PeerURLs {
+			u, err := url.Parse(peerURL)
+			if err == nil && u.Hostname() == IP {
+				return u.Scheme == "https"
+			}
+		}
+	}
+	return false
+}
+
+// membersClusterString returns the initial cluster that matches the peer URLs
+// of the existing cluster's `members`.
+func membersClusterString(members []client.Member) string {
+	var cluster []string
+	for _, member := range members {
+		for _, peerURL := range member.PeerURLs {
+			u, err := url.Parse(peerURL)
+			if err != nil {
+				continue
+			}
+			cluster = append(cluster, fmt.Sprintf("%s=%s",
+				nodeName(u.Hostname()), peerURL))
+		}
+	}
+	return strings.Join(cluster, ",")
+}
+
+func newEtcdMembersImpl(endpoints []string) (client.MembersAPI, error) {
+	transport, err := etcd.Transport()
+	if err != nil {
+		return nil, err
+	}
+
+	etcdClient, err := client.New(client.Config{
+		Endpoints: endpoints,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client.NewMembersAPI(etcdClient), nil
+}
+
+// Stored in a variable so it may be mocked out by the unit tests.
+var newEtcdMembers = newEtcdMembersImpl
@@ -1,9 +1,14 @@
 package supervisor
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"testing"
 
+	"golang.org/x/net/context"
+
+	"github.com/coreos/etcd/client"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/supervisor/images"
 
@@ -27,7 +32,7 @@ func TestMaster(t *testing.T) {
 	runMasterOnce()
 
 	exp := map[string][]string{
-		images.Etcd:     etcdArgsMaster(ip, etcdIPs),
+		images.Etcd:     etcdArgsMaster(ip, etcdIPs, "new", false),
 		images.Ovsdb:    {"ovsdb-server"},
 		images.Registry: nil,
 	}
@@ -51,7 +56,7 @@ func TestMaster(t *testing.T) {
 	runMasterOnce()
 
 	exp = map[string][]string{
-		images.Etcd:      etcdArgsMaster(ip, etcdIPs),
+		images.Etcd:      etcdArgsMaster(ip, etcdIPs, "new", false),
 		images.Ovsdb:     {"ovsdb-server"},
 		images.Ovnnorthd: {"ovn-northd"},
 		images.Registry:  nil,
@@ -69,7 +74,7 @@ func TestMaster(t *testing.T) {
 	runMasterOnce()
 
 	exp = map[string][]string{
-		images.Etcd:     etcdArgsMaster(ip, etcdIPs),
+		images.Etcd:     etcdArgsMaster(ip, etcdIPs, "new", false),
 		images.Ovsdb:    {"ovsdb-server"},
 		images.Registry: nil,
 	}
@@ -81,101 +86,280 @@ func TestEtcdAdd(t *testing.T) {
 	ctx := initTest()
 	ip := "1.2.3.4"
 	etcdIPs := []string{ip, "5.6.7.8"}
-	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
-		m := view.MinionSelf()
-		e := view.SelectFromEtcd(nil)[0]
-		m.Role = db.Master
-		m.PrivateIP = ip
-		e.EtcdIPs = etcdIPs
-		view.Commit(m)
-		view.Commit(e)
-		return nil
-	})
+	setEtcd(ctx, ip, etcdIPs, true)
+
+	// The master restarts, and rejoins the existing cluster.
+	members := &fakeMembers{}
+	members.setStarted(etcdIPs...)
+	newEtcdMembers = func(endpoints []string) (client.MembersAPI, error) {
+		return members, nil
+	}
 	runMasterOnce()
 
 	exp := map[string][]string{
-		images.Etcd:     etcdArgsMaster(ip, etcdIPs),
-		images.Ovsdb:    {"ovsdb-server"},
-		images.Registry: nil,
+		images.Etcd:      etcdArgsMaster(ip, etcdIPs, "existing", false),
+		images.Ovsdb:     {"ovsdb-server"},
+		images.Ovnnorthd: {"ovn-northd"},
+		images.Registry:  nil,
 	}
 	assert.Equal(t, exp, ctx.fd.running())
 
-	// Add a new master
-	etcdIPs = append(etcdIPs, "9.10.11.12")
-	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
-		m := view.MinionSelf()
-		e := view.SelectFromEtcd(nil)[0]
-		m.Role = db.Master
-		e.EtcdIPs = etcdIPs
-		view.Commit(m)
-		view.Commit(e)
-		return nil
-	})
+	// Adding masters doesn't restart etcd.  The leader adds them as members
+	// one at a time.
+	etcdIPs = append(etcdIPs, "9.10.11.12", "13.14.15.16")
+	setEtcd(ctx, ip, etcdIPs, true)
 	runMasterOnce()
+	assert.Equal(t, exp, ctx.fd.running())
+	assert.Equal(t, []string{ip, "5.6.7.8", "9.10.11.12"}, members.ips())
 
-	exp = map[string][]string{
-		images.Etcd:     etcdArgsMaster(ip, etcdIPs),
-		images.Ovsdb:    {"ovsdb-server"},
-		images.Registry: nil,
-	}
+	// The next master isn't added until the new member starts.
+	runMasterOnce()
+	assert.Equal(t, []string{ip, "5.6.7.8", "9.10.11.12"}, members.ips())
+
+	members.setStarted("9.10.11.12")
+	runMasterOnce()
+	assert.Equal(t, etcdIPs, members.ips())
 	assert.Equal(t, exp, ctx.fd.running())
 }
 
 func TestEtcdRemove(t *testing.T) {
 	ctx := initTest()
 	ip := "1.2.3.4"
-	etcdIPs := []string{ip, "5.6.7.8"}
+	etcdIPs := []string{ip, "5.6.7.8", "9.10.11.12"}
+	setEtcd(ctx, ip, etcdIPs, true)
+	runMasterOnce()
+
+	exp := map[string][]string{
+		images.Etcd:      etcdArgsMaster(ip, etcdIPs, "new", false),
+		images.Ovsdb:     {"ovsdb-server"},
+		images.Ovnnorthd: {"ovn-northd"},
+		images.Registry:  nil,
+	}
+	assert.Equal(t, exp, ctx.fd.running())
+
+	members := &fakeMembers{}
+	members.setStarted(etcdIPs...)
+	newEtcdMembers = func(endpoints []string) (client.MembersAPI, error) {
+		assert.Equal(t, []string{"https://1.2.3.4:2379",
+			"http://1.2.3.4:2379"}, endpoints)
+		return members, nil
+	}
+
+	// Removing a master doesn't restart etcd, and the leader removes it from
+	// the cluster.
+	etcdIPs = []string{ip, "9.10.11.12"}
+	setEtcd(ctx, ip, etcdIPs, true)
+	runMasterOnce()
+	assert.Equal(t, exp, ctx.fd.running())
+	assert.Equal(t, etcdIPs, members.ips())
+
+	// The leader doesn't remove itself.
+	setEtcd(ctx, ip, []string{"9.10.11.12"}, true)
+	runMasterOnce()
+	assert.Equal(t, etcdIPs, members.ips())
+
+	// Only the leader changes the membership.
+	setEtcd(ctx, ip, []string{"9.10.11.12", "13.14.15.16"}, false)
+	runMasterOnce()
+	assert.Equal(t, etcdIPs, members.ips())
+}
+
+func TestEtcdJoin(t *testing.T) {
+	ctx := initTest()
+	ip := "9.10.11.12"
+	etcdIPs := []string{"1.2.3.4", "5.6.7.8", ip}
+	setEtcd(ctx, ip, etcdIPs, false)
+
+	members := &fakeMembers{}
+	members.setStarted("1.2.3.4", "5.6.7.8")
+	newEtcdMembers = func(endpoints []string) (client.MembersAPI, error) {
+		assert.Equal(t, []string{"https://1.2.3.4:2379", "http://1.2.3.4:2379",
+			"https://5.6.7.8:2379", "http://5.6.7.8:2379"}, endpoints)
+		return members, nil
+	}
+
+	// The cluster exists, so the master waits to be added to it.
+	runMasterOnce()
+	_, ok := ctx.fd.running()[images.Etcd]
+	assert.False(t, ok)
+
+	members.Add(nil, etcdPeerURL(ip, false))
+	runMasterOnce()
+	assert.Equal(t, etcdArgsMaster(ip, etcdIPs, "existing", false),
+		ctx.fd.running()[images.Etcd])
+}
+
+func TestEtcdMoveToTLS(t *testing.T) {
+	ctx := initTest()
+	ip := "1.2.3.4"
+	etcdIPs := []string{ip, "5.6.7.8", "9.10.11.12"}
+	setEtcd(ctx, ip, etcdIPs, true)
+	runMasterOnce()
+
+	exp := etcdArgsMaster(ip, etcdIPs, "new", false)
+	assert.Equal(t, exp, ctx.fd.running()[images.Etcd])
+
+	// Only the masters in `servingTLS` answer over https.
+	servingTLS := map[string]bool{}
+	members := &fakeMembers{}
+	members.setStarted(etcdIPs...)
+	newEtcdMembers = func(endpoints []string) (client.MembersAPI, error) {
+		for _, endpoint := range endpoints {
+			u, err := url.Parse(endpoint)
+			assert.NoError(t, err)
+			if u.Scheme == "http" || servingTLS[u.Hostname()] {
+				return members, nil
+			}
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	// The leader moves the other members to TLS first.
+	setEtcdTLS(ctx)
+	runMasterOnce()
+	assert.Equal(t, exp, ctx.fd.running()[images.Etcd])
+	assert.Equal(t, []string{"http://1.2.3.4:2380", "https://5.6.7.8:2380",
+		"http://9.10.11.12:2380"}, members.peerURLs())
+
+	// The next member isn't moved until the moved member restarts with TLS.
+	runMasterOnce()
+	assert.Equal(t, []string{"http://1.2.3.4:2380", "https://5.6.7.8:2380",
+		"http://9.10.11.12:2380"}, members.peerURLs())
+
+	servingTLS["5.6.7.8"] = true
+	runMasterOnce()
+	assert.Equal(t, []string{"http://1.2.3.4:2380", "https://5.6.7.8:2380",
+		"https://9.10.11.12:2380"}, members.peerURLs())
+
+	// The leader moves its own member last.
+	servingTLS["9.10.11.12"] = true
+	runMasterOnce()
+	assert.Equal(t, []string{"https://1.2.3.4:2380", "https://5.6.7.8:2380",
+		"https://9.10.11.12:2380"}, members.peerURLs())
+	assert.Equal(t, exp, ctx.fd.running()[images.Etcd])
+
+	// Once its member has moved, the master restarts etcd with TLS.
+	runMasterOnce()
+	assert.Equal(t, etcdArgsMaster(ip, etcdIPs, "existing", true),
+		ctx.fd.running()[images.Etcd])
+	assert.True(t, etcdRunningTLS)
+}
+
+func setEtcd(ctx *testCtx, ip string, etcdIPs []string, leader bool) {
 	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		m := view.MinionSelf()
 		e := view.SelectFromEtcd(nil)[0]
 		m.Role = db.Master
 		m.PrivateIP = ip
 		e.EtcdIPs = etcdIPs
+		e.Leader = leader
 		view.Commit(m)
 		view.Commit(e)
 		return nil
 	})
-	runMasterOnce()
-
-	exp := map[string][]string{
-		images.Etcd:     etcdArgsMaster(ip, etcdIPs),
-		images.Ovsdb:    {"ovsdb-server"},
-		images.Registry: nil,
-	}
-	assert.Equal(t, exp, ctx.fd.running())
+}
 
-	// Remove a master
-	etcdIPs = etcdIPs[1:]
+func setEtcdTLS(ctx *testCtx) {
 	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
-		m := view.MinionSelf()
 		e := view.SelectFromEtcd(nil)[0]
-		m.Role = db.Master
-		e.EtcdIPs = etcdIPs
-		view.Commit(m)
+		e.TLS = true
 		view.Commit(e)
 		return nil
 	})
-	runMasterOnce()
+}
 
-	exp = map[string][]string{
-		images.Etcd:     etcdArgsMaster(ip, etcdIPs),
-		images.Ovsdb:    {"ovsdb-server"},
-		images.Registry: nil,
+// fakeMembers is an in-memory etcd cluster membership.
+type fakeMembers struct {
+	client.MembersAPI
+	members []client.Member
+
+	// Whether the members started by setStarted use TLS.
+	tls bool
+}
+
+func (f *fakeMembers) List(_ context.Context) ([]client.Member, error) {
+	return f.members, nil
+}
+
+func (f *fakeMembers) Add(_ context.Context, peerURL string) (*client.Member,
+	error) {
+	member := client.Member{ID: peerURL, PeerURLs: []string{peerURL}}
+	f.members = append(f.members, member)
+	return &member, nil
+}
+
+func (f *fakeMembers) Remove(_ context.Context, id string) error {
+	var members []client.Member
+	for _, member := range f.members {
+		if member.ID != id {
+			members = append(members, member)
+		}
 	}
-	assert.Equal(t, exp, ctx.fd.running())
+	f.members = members
+	return nil
 }
 
-func etcdArgsMaster(ip string, etcdIPs []string) []string {
-	return []string{
+func (f *fakeMembers) Update(_ context.Context, id string,
+	peerURLs []string) error {
+	for i := range f.members {
+		if f.members[i].ID == id {
+			f.members[i].PeerURLs = peerURLs
+		}
+	}
+	return nil
+}
+
+// setStarted adds the masters at `ips` as members that have started.
+func (f *fakeMembers) setStarted(ips ...string) {
+	for _, ip := range ips {
+		f.Remove(nil, etcdPeerURL(ip, f.tls))
+		f.members = append(f.members, client.Member{ID: etcdPeerURL(ip, f.tls),
+			Name: nodeName(ip), PeerURLs: []string{etcdPeerURL(ip, f.tls)}})
+	}
+}
+
+func (f *fakeMembers) peerURLs() []string {
+	var urls []string
+	for _, member := range f.members {
+		urls = append(urls, member.PeerURLs...)
+	}
+	return urls
+}
+
+func (f *fakeMembers) ips() []string {
+	return etcdMemberIPs(f.members)
+}
+
+func etcdArgsMaster(ip string, etcdIPs []string, state string, tls bool) []string {
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+
+	args := []string{
 		"etcd",
 		fmt.Sprintf("--name=master-%s", ip),
-		fmt.Sprintf("--initial-cluster=%s", initialClusterString(etcdIPs)),
-		fmt.Sprintf("--advertise-client-urls=http://%s:2379", ip),
-		fmt.Sprintf("--listen-peer-urls=http://%s:2380", ip),
-		fmt.Sprintf("--initial-advertise-peer-urls=http://%s:2380", ip),
-		"--listen-client-urls=http://0.0.0.0:2379",
+		fmt.Sprintf("--initial-cluster=%s", initialClusterString(etcdIPs, tls)),
+		fmt.Sprintf("--advertise-client-urls=%s://%s:2379", scheme, ip),
+		fmt.Sprintf("--listen-peer-urls=%s://%s:2380", scheme, ip),
+		fmt.Sprintf("--initial-advertise-peer-urls=%s://%s:2380", scheme, ip),
+		fmt.Sprintf("--listen-client-urls=%s://0.0.0.0:2379", scheme),
 		"--heartbeat-interval=500",
-		"--initial-cluster-state=new",
+		"--initial-cluster-state=" + state,
 		"--election-timeout=5000",
 	}
+	if !tls {
+		return args
+	}
+
+	return append(args,
+		"--cert-file=/home/kelda/.kelda/tls/etcd.crt",
+		"--key-file=/home/kelda/.kelda/tls/etcd.key",
+		"--trusted-ca-file=/home/kelda/.kelda/tls/certificate_authority.crt",
+		"--client-cert-auth",
+		"--peer-cert-file=/home/kelda/.kelda/tls/etcd.crt",
+		"--peer-key-file=/home/kelda/.kelda/tls/etcd.key",
+		"--peer-trusted-ca-file=/home/kelda/.kelda/tls/certificate_authority.crt",
+		"--peer-client-cert-auth",
+	)
 }
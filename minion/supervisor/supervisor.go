@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 
+	tlsIO "github.com/kelda/kelda/connection/tls/io"
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/docker"
 	"github.com/kelda/kelda/minion/supervisor/images"
+	"github.com/kelda/kelda/util"
 
 	dkc "github.com/fsouza/go-dockerclient"
 	log "github.com/sirupsen/logrus"
@@ -32,6 +35,9 @@ var imageMap = map[string]string{
 const etcdHeartbeatInterval = "500"
 const etcdElectionTimeout = "5000"
 
+// How long to wait for etcd to respond to membership requests.
+const etcdTimeout = 5 * time.Second
+
 var c = counter.New("Supervisor")
 
 var conn db.Conn
@@ -39,6 +45,9 @@ var dk docker.Client
 var oldEtcdIPs []string
 var oldIP string
 
+// Whether the etcd that this master started serves TLS.
+var etcdRunningTLS bool
+
 // Run blocks implementing the supervisor module.
 func Run(_conn db.Conn, _dk docker.Client, role db.Role) {
 	conn = _conn
@@ -117,11 +126,13 @@ func Remove(name string) {
 	}
 }
 
-func initialClusterString(etcdIPs []string) string {
+// initialClusterString returns the initial cluster of a new etcd cluster made up
+// of the masters at `etcdIPs`.
+func initialClusterString(etcdIPs []string, tls bool) string {
 	var initialCluster []string
 	for _, ip := range etcdIPs {
 		initialCluster = append(initialCluster,
-			fmt.Sprintf("%s=http://%s:2380", nodeName(ip), ip))
+			fmt.Sprintf("%s=%s", nodeName(ip), etcdPeerURL(ip, tls)))
 	}
 	return strings.Join(initialCluster, ",")
 }
@@ -130,6 +141,61 @@ func nodeName(IP string) string {
 	return fmt.Sprintf("master-%s", IP)
 }
 
+func etcdScheme(tls bool) string {
+	if tls {
+		return "https"
+	}
+	return "http"
+}
+
+func etcdPeerURL(IP string, tls bool) string {
+	return fmt.Sprintf("%s://%s:2380", etcdScheme(tls), IP)
+}
+
+func etcdClientURL(IP string, tls bool) string {
+	return fmt.Sprintf("%s://%s:2379", etcdScheme(tls), IP)
+}
+
+// etcdClientURLs returns the client URLs of the etcd servers at `IPs` with both
+// schemes, because it isn't known whether they've switched to TLS yet.
+func etcdClientURLs(IPs ...string) []string {
+	var urls []string
+	for _, ip := range IPs {
+		urls = append(urls, etcdClientURL(ip, true), etcdClientURL(ip, false))
+	}
+	return urls
+}
+
+// etcdTLSArgs returns the arguments that make etcd encrypt its peer and client
+// traffic with the minion's etcd certificate, and only accept connections with
+// certificates signed by Kelda's certificate authority.
+func etcdTLSArgs() []string {
+	cert := tlsIO.EtcdCertPath(tlsIO.MinionTLSDir)
+	key := tlsIO.EtcdKeyPath(tlsIO.MinionTLSDir)
+	ca := tlsIO.CACertPath(tlsIO.MinionTLSDir)
+	return []string{
+		"--cert-file=" + cert,
+		"--key-file=" + key,
+		"--trusted-ca-file=" + ca,
+		"--client-cert-auth",
+		"--peer-cert-file=" + cert,
+		"--peer-key-file=" + key,
+		"--peer-trusted-ca-file=" + ca,
+		"--peer-client-cert-auth",
+	}
+}
+
+// etcdCertsReady returns whether the daemon has installed the etcd certificate,
+// without which etcd can't start.
+func etcdCertsReady() bool {
+	_, err := util.AppFs.Stat(tlsIO.EtcdKeyPath(tlsIO.MinionTLSDir))
+	if err != nil {
+		log.WithError(err).Debug("etcd certificate not ready yet")
+		return false
+	}
+	return true
+}
+
 // execRun() is a global variable so that it can be mocked out by the unit tests.
 var execRun = func(name string, arg ...string) error {
 	c.Inc(name)
@@ -1,10 +1,15 @@
 package supervisor
 
 import (
+	"errors"
 	"net"
 
+	"github.com/coreos/etcd/client"
+	tlsIO "github.com/kelda/kelda/connection/tls/io"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/util"
+	"github.com/spf13/afero"
 )
 
 type testCtx struct {
@@ -15,6 +20,11 @@ type testCtx struct {
 }
 
 func initTest() *testCtx {
+	util.AppFs = afero.NewMemMapFs()
+	util.WriteFile(tlsIO.EtcdKeyPath(tlsIO.MinionTLSDir), []byte("key"), 0600)
+
+	etcdRunningTLS = false
+
 	conn = db.New()
 	md, _dk := docker.NewMock()
 	ctx := testCtx{fakeDocker{_dk, md}, nil, conn}
@@ -34,6 +44,11 @@ func initTest() *testCtx {
 		return nil
 	}
 
+	// By default, none of the other masters run etcd.
+	newEtcdMembers = func(endpoints []string) (client.MembersAPI, error) {
+		return nil, errors.New("connection refused")
+	}
+
 	cfgGateway = func(name string, ip net.IPNet) error {
 		execRun("cfgGateway", ip.String())
 		return nil
@@ -33,20 +33,29 @@ func setupWorker() {
 		time.Sleep(5 * time.Second)
 	}
 
-	ip := net.IPNet{IP: ipdef.GatewayIP, Mask: ipdef.KeldaSubnet.Mask}
-	for {
-		err := cfgGateway(ipdef.KeldaBridge, ip)
-		if err == nil {
-			break
+	ips := []net.IPNet{{IP: ipdef.GatewayIP, Mask: ipdef.KeldaSubnet.Mask}}
+	if ipdef.IPv6Enabled() {
+		ips = append(ips, net.IPNet{
+			IP: ipdef.GatewayIP6, Mask: ipdef.KeldaSubnet6.Mask})
+	}
+
+	for _, ip := range ips {
+		for {
+			err := cfgGateway(ipdef.KeldaBridge, ip)
+			if err == nil {
+				break
+			}
+			log.WithError(err).Errorf("Failed to configure %s.",
+				ipdef.KeldaBridge)
+			time.Sleep(5 * time.Second)
 		}
-		log.WithError(err).Errorf("Failed to configure %s.", ipdef.KeldaBridge)
-		time.Sleep(5 * time.Second)
 	}
 }
 
 func runWorkerSystem() {
 	loopLog := util.NewEventTimer("Supervisor")
-	for range conn.Trigger(db.MinionTable, db.EtcdTable).C {
+	// The trigger ticks so that etcd starts once its certificate is installed.
+	for range conn.TriggerTick(30, db.MinionTable, db.EtcdTable).C {
 		loopLog.LogStart()
 		runWorkerOnce()
 		loopLog.LogEnd()
@@ -72,11 +81,22 @@ func runWorkerOnce() {
 
 	oldEtcdIPs = etcdIPs
 
-	run(images.Etcd, "etcd",
-		fmt.Sprintf("--initial-cluster=%s", initialClusterString(etcdIPs)),
-		"--heartbeat-interval="+etcdHeartbeatInterval,
-		"--election-timeout="+etcdElectionTimeout,
-		"--proxy=on")
+	if etcdCertsReady() {
+		// The proxy may reach the masters over either scheme, because they
+		// switch to TLS one at a time.
+		initialCluster := initialClusterString(etcdIPs, false)
+		if len(etcdIPs) > 0 {
+			initialCluster += "," + initialClusterString(etcdIPs, true)
+		}
+
+		args := []string{"etcd",
+			"--initial-cluster=" + initialCluster,
+			"--heartbeat-interval=" + etcdHeartbeatInterval,
+			"--election-timeout=" + etcdElectionTimeout,
+			"--listen-client-urls=https://127.0.0.1:2379",
+			"--proxy=on"}
+		run(images.Etcd, append(args, etcdTLSArgs()...)...)
+	}
 
 	run(images.Ovsdb, "ovsdb-server")
 	run(images.Ovsvswitchd, "ovs-vswitchd")
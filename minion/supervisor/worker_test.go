@@ -79,6 +79,19 @@ func TestSetupWorker(t *testing.T) {
 	assert.Equal(t, setupArgs(), ctx.execs)
 }
 
+func TestSetupWorkerIPv6(t *testing.T) {
+	defaultNetwork, _ := ipdef.ParseContainerNetwork("", "", "", "")
+	network, _ := ipdef.ParseContainerNetwork("", "", "", "fd00::/64")
+	ipdef.Configure(network)
+	defer ipdef.Configure(defaultNetwork)
+
+	ctx := initTest()
+	setupWorker()
+
+	exp := append(setupArgs(), []string{"cfgGateway", "fd00::1/64"})
+	assert.Equal(t, exp, ctx.execs)
+}
+
 func TestCfgGateway(t *testing.T) {
 	mk := new(nlmock.I)
 	nl.N = mk
@@ -142,9 +155,20 @@ func ovsExecArgs(ip, leader string) [][]string {
 func etcdArgsWorker(etcdIPs []string) []string {
 	return []string{
 		"etcd",
-		fmt.Sprintf("--initial-cluster=%s", initialClusterString(etcdIPs)),
+		fmt.Sprintf("--initial-cluster=%s,%s",
+			initialClusterString(etcdIPs, false),
+			initialClusterString(etcdIPs, true)),
 		"--heartbeat-interval=500",
 		"--election-timeout=5000",
+		"--listen-client-urls=https://127.0.0.1:2379",
 		"--proxy=on",
+		"--cert-file=/home/kelda/.kelda/tls/etcd.crt",
+		"--key-file=/home/kelda/.kelda/tls/etcd.key",
+		"--trusted-ca-file=/home/kelda/.kelda/tls/certificate_authority.crt",
+		"--client-cert-auth",
+		"--peer-cert-file=/home/kelda/.kelda/tls/etcd.crt",
+		"--peer-key-file=/home/kelda/.kelda/tls/etcd.key",
+		"--peer-trusted-ca-file=/home/kelda/.kelda/tls/certificate_authority.crt",
+		"--peer-client-cert-auth",
 	}
 }
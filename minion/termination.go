@@ -0,0 +1,114 @@
+package minion
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kelda/kelda/db"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The instance metadata that announces that the cloud provider is about to
+// reclaim the machine.  Amazon responds with a 404 until the spot instance is
+// marked for interruption, and Google reports whether a preemptible instance
+// has been preempted.
+var (
+	amazonTerminationURL = "http://169.254.169.254/latest/meta-data/spot/" +
+		"instance-action"
+	googleTerminationURL = "http://metadata.google.internal/computeMetadata/" +
+		"v1/instance/preempted"
+)
+
+var metadataClient = &http.Client{Timeout: 5 * time.Second}
+
+// watchTermination marks the minion as terminating once the cloud provider
+// announces that it will reclaim the machine.  Amazon gives two minutes of
+// notice and Google only thirty seconds, so the metadata is polled often.
+func watchTermination(conn db.Conn) {
+	for range time.Tick(5 * time.Second) {
+		if checkTermination(conn) {
+			return
+		}
+	}
+}
+
+func checkTermination(conn db.Conn) bool {
+	terminating, err := terminationNotice(conn.MinionSelf().Provider)
+	if err != nil {
+		log.WithError(err).Debug("Failed to check for a termination notice")
+		return false
+	}
+
+	if !terminating {
+		return false
+	}
+
+	c.Inc("Termination Notice")
+	log.Warn("The cloud provider is reclaiming this machine. " +
+		"Draining its containers.")
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		self := view.MinionSelf()
+		self.Terminating = true
+		view.Commit(self)
+		return nil
+	})
+	return true
+}
+
+func terminationNotice(provider string) (bool, error) {
+	switch db.ProviderName(provider) {
+	case db.Amazon:
+		status, _, err := getMetadata(amazonTerminationURL, nil)
+		if err != nil {
+			return false, err
+		}
+		switch status {
+		case http.StatusOK:
+			return true, nil
+		case http.StatusNotFound:
+			return false, nil
+		default:
+			return false, fmt.Errorf("unexpected status: %d", status)
+		}
+	case db.Google:
+		status, body, err := getMetadata(googleTerminationURL,
+			map[string]string{"Metadata-Flavor": "Google"})
+		if err != nil {
+			return false, err
+		}
+		if status != http.StatusOK {
+			return false, fmt.Errorf("unexpected status: %d", status)
+		}
+		return strings.TrimSpace(body) == "TRUE", nil
+	}
+
+	// The other providers don't reclaim machines.
+	return false, nil
+}
+
+func getMetadata(url string, headers map[string]string) (int, string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, "", err
+	}
+
+	for key, val := range headers {
+		req.Header.Set(key, val)
+	}
+
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, string(body), nil
+}
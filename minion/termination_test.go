@@ -0,0 +1,74 @@
+package minion
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/db"
+)
+
+func TestCheckTermination(t *testing.T) {
+	amazonStatus := http.StatusNotFound
+	amazon := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(amazonStatus)
+		}))
+	defer amazon.Close()
+
+	googlePreempted := "FALSE"
+	google := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, googlePreempted)
+		}))
+	defer google.Close()
+
+	amazonTerminationURL = amazon.URL
+	googleTerminationURL = google.URL
+
+	conn := db.New()
+	setProvider := func(provider db.ProviderName) {
+		conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+			self := view.MinionSelf()
+			self.Provider = string(provider)
+			self.Terminating = false
+			view.Commit(self)
+			return nil
+		})
+	}
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		view.Commit(self)
+		return nil
+	})
+
+	// Providers that don't reclaim machines are never terminating.
+	setProvider(db.Vagrant)
+	assert.False(t, checkTermination(conn))
+
+	setProvider(db.Amazon)
+	assert.False(t, checkTermination(conn))
+	assert.False(t, conn.MinionSelf().Terminating)
+
+	amazonStatus = http.StatusInternalServerError
+	assert.False(t, checkTermination(conn))
+
+	amazonStatus = http.StatusOK
+	assert.True(t, checkTermination(conn))
+	assert.True(t, conn.MinionSelf().Terminating)
+
+	setProvider(db.Google)
+	assert.False(t, checkTermination(conn))
+
+	googlePreempted = "TRUE"
+	assert.True(t, checkTermination(conn))
+	assert.True(t, conn.MinionSelf().Terminating)
+}
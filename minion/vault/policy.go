@@ -3,6 +3,7 @@ package vault
 import (
 	"encoding/json"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/join"
 
@@ -78,8 +79,12 @@ func joinPolicies(client APIClient, desiredPolicies, currentPolicies vaultPolicy
 // rescheduled, or new masters are booted.
 func getDesiredPolicies(conn db.Conn) (desiredPolicies []vaultPolicy) {
 	// Assign all master minions a policy that allows them to write to any
-	// secret.
-	writeAllPolicy := policyString(map[string]string{"*": "write"})
+	// secret, including the control plane secrets.
+	controlPlaneSecrets := blueprint.ControlPlaneSecretPrefix + "*"
+	writeAllPolicy := policyString(map[string]string{
+		"*":                 "write",
+		controlPlaneSecrets: "write",
+	})
 	for _, m := range conn.SelectFromMinion(nil) {
 		if m.Role == db.Master {
 			desiredPolicies = append(desiredPolicies, vaultPolicy{
@@ -103,6 +108,11 @@ func getDesiredPolicies(conn db.Conn) (desiredPolicies []vaultPolicy) {
 	for worker, secretNames := range workerToSecrets {
 		acls := map[string]string{}
 		for _, secretName := range secretNames {
+			// Deploy rejects containers that reference reserved secrets,
+			// but never grant access to them regardless.
+			if blueprint.IsReservedSecret(secretName) {
+				continue
+			}
 			acls[secretName] = "read"
 		}
 		desiredPolicies = append(desiredPolicies, vaultPolicy{
@@ -106,6 +106,12 @@ func TestGetDesiredPolicies(t *testing.T) {
 		dbc.Minion = scheduledMinion
 		dbc.Env = map[string]blueprint.ContainerValue{
 			"aKey": blueprint.NewSecret(secretName),
+
+			// Containers are never granted the reserved secrets.
+			"reserved": blueprint.NewSecret(
+				blueprint.ControlPlaneSecretPrefix + "ssh-key"),
+			"escape": blueprint.NewSecret(
+				"../kelda-control-plane/ssh-key"),
 		}
 		view.Commit(dbc)
 
@@ -125,8 +131,9 @@ func TestGetDesiredPolicies(t *testing.T) {
 			secretName),
 	})
 	assert.Contains(t, desiredPolicies, vaultPolicy{
-		name:   masterIP,
-		policy: `{"path":{"/secret/kelda/*":{"policy":"write"}}}`,
+		name: masterIP,
+		policy: `{"path":{"/secret/kelda-control-plane/*":{"policy":` +
+			`"write"},"/secret/kelda/*":{"policy":"write"}}}`,
 	})
 }
 
@@ -5,6 +5,9 @@ package vault
 import (
 	"errors"
 	"path"
+	"strings"
+
+	"github.com/kelda/kelda/blueprint"
 )
 
 const (
@@ -12,6 +15,12 @@ const (
 	// stored.
 	secretStorePath = "/secret/kelda"
 
+	// controlPlaneStorePath is the root Vault path under which the control
+	// plane secrets are stored.  It's outside of secretStorePath so that the
+	// policies that grant workers access to their containers' secrets can
+	// never match it.
+	controlPlaneStorePath = "/secret/kelda-control-plane"
+
 	// secretKey is the key used to store the secret's value. A key is necessary
 	// because a Vault path is a map of key-value pairs -- not just a single
 	// value.
@@ -85,5 +94,9 @@ func (client secretStoreImpl) Write(name, value string) error {
 }
 
 func pathForSecret(name string) string {
+	if strings.HasPrefix(name, blueprint.ControlPlaneSecretPrefix) {
+		return path.Join(controlPlaneStorePath,
+			strings.TrimPrefix(name, blueprint.ControlPlaneSecretPrefix))
+	}
 	return path.Join(secretStorePath, name)
 }
@@ -61,3 +61,9 @@ func TestReadAndWrite(t *testing.T) {
 	assert.Equal(t, secretValue, actualValue)
 	mockClient.AssertExpectations(t)
 }
+
+func TestPathForSecret(t *testing.T) {
+	assert.Equal(t, "/secret/kelda/password", pathForSecret("password"))
+	assert.Equal(t, "/secret/kelda-control-plane/ssh-key",
+		pathForSecret("kelda-control-plane/ssh-key"))
+}
@@ -64,6 +64,17 @@ func MapEq(x, y map[string]string) bool {
 	return true
 }
 
+// MapContains returns true if every key in 'sub' maps to the same value in 'm'.
+// Other keys in 'm' are ignored.
+func MapContains(m, sub map[string]string) bool {
+	for k, v := range sub {
+		if mVal, ok := m[k]; !ok || v != mVal {
+			return false
+		}
+	}
+	return true
+}
+
 // MapAsString creates a deterministic string representing the given map.
 func MapAsString(m map[string]string) string {
 	var strs []string
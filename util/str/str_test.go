@@ -63,6 +63,18 @@ func TestMapEqual(t *testing.T) {
 	assert.False(t, MapEq(a, b))
 }
 
+func TestMapContains(t *testing.T) {
+	t.Parallel()
+
+	m := map[string]string{"1": "1", "2": "2"}
+	assert.True(t, MapContains(m, nil))
+	assert.True(t, MapContains(m, map[string]string{"1": "1"}))
+	assert.True(t, MapContains(m, m))
+	assert.False(t, MapContains(m, map[string]string{"1": "2"}))
+	assert.False(t, MapContains(m, map[string]string{"3": "3"}))
+	assert.False(t, MapContains(nil, map[string]string{"1": "1"}))
+}
+
 func TestMapString(t *testing.T) {
 	t.Parallel()
 
@@ -1,4 +1,39 @@
 package version
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Version is the Kelda version number.
 const Version = "dev"
+
+// Compatible returns whether a daemon running version `daemon` may manage
+// minions running version `minion`.  Releases are compatible if they share a
+// major and minor version, e.g. 0.7.0 and 0.7.3.  Other builds, such as "dev",
+// are only compatible with themselves.
+func Compatible(daemon, minion string) bool {
+	if daemon == minion {
+		return true
+	}
+
+	daemonRelease, daemonOK := majorMinor(daemon)
+	minionRelease, minionOK := majorMinor(minion)
+	return daemonOK && minionOK && daemonRelease == minionRelease
+}
+
+// majorMinor returns the major and minor version of the release `version`, or
+// false if `version` isn't a release.
+func majorMinor(version string) (string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	for _, part := range parts[:2] {
+		if _, err := strconv.Atoi(part); err != nil {
+			return "", false
+		}
+	}
+	return parts[0] + "." + parts[1], true
+}
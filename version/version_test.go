@@ -0,0 +1,22 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompatible(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, Compatible("dev", "dev"))
+	assert.True(t, Compatible("0.7.0", "0.7.0"))
+	assert.True(t, Compatible("0.7.0", "0.7.3"))
+	assert.True(t, Compatible("v0.7.1", "0.7.0-rc1"))
+
+	assert.False(t, Compatible("0.7.0", "0.6.2"))
+	assert.False(t, Compatible("1.7.0", "0.7.0"))
+	assert.False(t, Compatible("dev", "0.7.0"))
+	assert.False(t, Compatible("0.7", "0.7.0"))
+	assert.False(t, Compatible("0.x.0", "0.x.1"))
+}